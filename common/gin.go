@@ -95,6 +95,18 @@ func GetBodyStorage(c *gin.Context) (BodyStorage, error) {
 	return bs, nil
 }
 
+// ReplaceBodyStorage 用新内容替换缓存的请求体（如内容审核脱敏后），后续解析与转发均使用新内容
+func ReplaceBodyStorage(c *gin.Context, data []byte) error {
+	storage, err := CreateBodyStorage(data)
+	if err != nil {
+		return err
+	}
+	CleanupBodyStorage(c)
+	c.Set(KeyBodyStorage, storage)
+	c.Request.ContentLength = int64(len(data))
+	return nil
+}
+
 // CleanupBodyStorage 清理请求体存储（应在请求结束时调用）
 func CleanupBodyStorage(c *gin.Context) {
 	if storage, exists := c.Get(KeyBodyStorage); exists && storage != nil {
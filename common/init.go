@@ -19,13 +19,15 @@ var (
 	PrintVersion = flag.Bool("version", false, "print version and exit")
 	PrintHelp    = flag.Bool("help", false, "print help and exit")
 	LogDir       = flag.String("log-dir", "./logs", "specify the log directory")
+
+	EncryptSecrets = flag.Bool("encrypt-secrets", false, "encrypt existing plaintext secrets with SECRET_ENCRYPTION_KEY and exit")
 )
 
 func printHelp() {
 	fmt.Println("NewAPI(Based OneAPI) " + Version + " - The next-generation LLM gateway and AI asset management system supports multiple languages.")
 	fmt.Println("Original Project: OneAPI by JustSong - https://github.com/songquanpeng/one-api")
 	fmt.Println("Maintainer: QuantumNous - https://github.com/QuantumNous/new-api")
-	fmt.Println("Usage: newapi [--port <port>] [--log-dir <log directory>] [--version] [--help]")
+	fmt.Println("Usage: newapi [--port <port>] [--log-dir <log directory>] [--encrypt-secrets] [--version] [--help]")
 }
 
 func InitEnv() {
@@ -175,4 +177,6 @@ func initConstantEnv() {
 	constant.TrustedRedirectDomains = trustedDomains
 
 	constant.FeishuAlertWebhook = GetEnvOrDefaultString("FEISHU_ALERT_WEBHOOK", "")
+
+	constant.MetricsToken = GetEnvOrDefaultString("METRICS_TOKEN", "")
 }
@@ -0,0 +1,54 @@
+-- 用量限流器（RPM / TPM / 并发数）
+-- RPM 与 TPM 使用滑动窗口计数：当前窗口计数 + 上一窗口计数 × 上一窗口剩余占比
+-- KEYS[1]: 当前窗口请求数
+-- KEYS[2]: 上一窗口请求数
+-- KEYS[3]: 当前窗口 token 数
+-- KEYS[4]: 上一窗口 token 数
+-- KEYS[5]: 进行中的请求数
+-- ARGV[1]: RPM 限制，0 表示不限制
+-- ARGV[2]: TPM 限制，0 表示不限制
+-- ARGV[3]: 并发数限制，0 表示不限制
+-- ARGV[4]: 上一窗口权重 (0~1)
+-- ARGV[5]: 窗口计数过期时间（秒）
+-- ARGV[6]: 并发计数过期时间（秒）
+-- ARGV[7]: 模式 0=检查并占用 1=仅检查 2=仅占用（不检查）
+-- 返回: 0=允许 1=超出RPM 2=超出TPM 3=超出并发数
+
+local rpmLimit = tonumber(ARGV[1])
+local tpmLimit = tonumber(ARGV[2])
+local inFlightLimit = tonumber(ARGV[3])
+local prevWeight = tonumber(ARGV[4])
+local windowTTL = tonumber(ARGV[5])
+local inFlightTTL = tonumber(ARGV[6])
+local mode = tonumber(ARGV[7])
+
+local function windowUsage(cur, prev)
+    local curCount = tonumber(redis.call('GET', cur) or '0')
+    local prevCount = tonumber(redis.call('GET', prev) or '0')
+    return curCount + prevCount * prevWeight
+end
+
+if mode ~= 2 then
+    if rpmLimit > 0 and windowUsage(KEYS[1], KEYS[2]) >= rpmLimit then
+        return 1
+    end
+    if tpmLimit > 0 and windowUsage(KEYS[3], KEYS[4]) >= tpmLimit then
+        return 2
+    end
+    if inFlightLimit > 0 and tonumber(redis.call('GET', KEYS[5]) or '0') >= inFlightLimit then
+        return 3
+    end
+end
+
+if mode ~= 1 then
+    if rpmLimit > 0 then
+        redis.call('INCR', KEYS[1])
+        redis.call('EXPIRE', KEYS[1], windowTTL)
+    end
+    if inFlightLimit > 0 then
+        redis.call('INCR', KEYS[5])
+        redis.call('EXPIRE', KEYS[5], inFlightTTL)
+    end
+end
+
+return 0
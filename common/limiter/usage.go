@@ -0,0 +1,278 @@
+package limiter
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/go-redis/redis/v8"
+)
+
+//go:embed lua/usage_limit.lua
+var usageLimitScriptSource string
+
+var usageLimitScript = redis.NewScript(usageLimitScriptSource)
+
+// 释放并发计数，计数归零时删除 key
+var usageReleaseScript = redis.NewScript(`
+local v = redis.call('DECR', KEYS[1])
+if v <= 0 then
+    redis.call('DEL', KEYS[1])
+end
+return v
+`)
+
+const (
+	usageWindowSeconds = 60
+	// 并发计数在每次占用时续期，用于兜底实例异常退出后未释放的计数
+	usageInFlightTTLSeconds = 30 * 60
+)
+
+const (
+	usageModeAcquire = iota
+	usageModeCheck
+	usageModeTrack
+)
+
+// UsageLimits 用量限制，各项为 0 表示不限制
+type UsageLimits struct {
+	RPM         int // 每分钟请求数
+	TPM         int // 每分钟 token 数（输入 + 输出）
+	MaxInFlight int // 最大并发请求数
+}
+
+func (l UsageLimits) Enabled() bool {
+	return l.RPM > 0 || l.TPM > 0 || l.MaxInFlight > 0
+}
+
+// UsageExceeded 超出的限制类型
+type UsageExceeded int
+
+const (
+	UsageAllowed UsageExceeded = iota
+	UsageRPMExceeded
+	UsageTPMExceeded
+	UsageInFlightExceeded
+)
+
+func (e UsageExceeded) String() string {
+	switch e {
+	case UsageRPMExceeded:
+		return "rpm"
+	case UsageTPMExceeded:
+		return "tpm"
+	case UsageInFlightExceeded:
+		return "max_in_flight"
+	default:
+		return ""
+	}
+}
+
+// AcquireUsage 检查用量限制并占用一次请求，允许时返回的 release 用于在请求结束后释放并发计数
+func AcquireUsage(ctx context.Context, key string, limits UsageLimits) (release func(), exceeded UsageExceeded) {
+	exceeded = evalUsage(ctx, key, limits, usageModeAcquire)
+	if exceeded != UsageAllowed {
+		return func() {}, exceeded
+	}
+	return usageReleaser(ctx, key, limits), UsageAllowed
+}
+
+// CheckUsage 仅检查是否仍有余量，不占用
+func CheckUsage(ctx context.Context, key string, limits UsageLimits) UsageExceeded {
+	return evalUsage(ctx, key, limits, usageModeCheck)
+}
+
+// TrackUsage 不检查限制，仅计入请求数与并发数（用于已在选择阶段检查过的渠道）
+func TrackUsage(ctx context.Context, key string, limits UsageLimits) (release func()) {
+	evalUsage(ctx, key, limits, usageModeTrack)
+	return usageReleaser(ctx, key, limits)
+}
+
+// AddUsageTokens 计入实际消耗的 token 数，请求结束后调用
+func AddUsageTokens(ctx context.Context, key string, tokens int) {
+	if tokens <= 0 {
+		return
+	}
+	windowStart := currentUsageWindow()
+	if common.RedisEnabled {
+		tokenKey := usageRedisKey(key, "tpm", windowStart)
+		pipe := common.RDB.TxPipeline()
+		pipe.IncrBy(ctx, tokenKey, int64(tokens))
+		pipe.Expire(ctx, tokenKey, usageWindowTTL())
+		if _, err := pipe.Exec(ctx); err != nil {
+			common.SysLog(fmt.Sprintf("usage limit add tokens failed: %v", err))
+		}
+		return
+	}
+	memoryUsage.addTokens(key, windowStart, tokens)
+}
+
+func usageReleaser(ctx context.Context, key string, limits UsageLimits) func() {
+	if limits.MaxInFlight <= 0 {
+		return func() {}
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if common.RedisEnabled {
+				err := usageReleaseScript.Run(context.WithoutCancel(ctx), common.RDB, []string{usageInFlightKey(key)}).Err()
+				if err != nil {
+					common.SysLog(fmt.Sprintf("usage limit release failed: %v", err))
+				}
+				return
+			}
+			memoryUsage.release(key)
+		})
+	}
+}
+
+func evalUsage(ctx context.Context, key string, limits UsageLimits, mode int) UsageExceeded {
+	if !limits.Enabled() {
+		return UsageAllowed
+	}
+	now := time.Now()
+	windowStart := currentUsageWindow()
+	prevWeight := 1 - float64(now.Unix()-windowStart)/usageWindowSeconds
+	if common.RedisEnabled {
+		result, err := usageLimitScript.Run(ctx, common.RDB,
+			[]string{
+				usageRedisKey(key, "rpm", windowStart),
+				usageRedisKey(key, "rpm", windowStart-usageWindowSeconds),
+				usageRedisKey(key, "tpm", windowStart),
+				usageRedisKey(key, "tpm", windowStart-usageWindowSeconds),
+				usageInFlightKey(key),
+			},
+			limits.RPM, limits.TPM, limits.MaxInFlight,
+			strconv.FormatFloat(prevWeight, 'f', 4, 64),
+			int(usageWindowTTL().Seconds()), usageInFlightTTLSeconds, mode,
+		).Int()
+		if err != nil {
+			// Redis 异常时放行，避免限流组件故障导致服务不可用
+			common.SysLog(fmt.Sprintf("usage limit check failed: %v", err))
+			return UsageAllowed
+		}
+		return UsageExceeded(result)
+	}
+	return memoryUsage.eval(key, limits, windowStart, prevWeight, mode)
+}
+
+func currentUsageWindow() int64 {
+	now := time.Now().Unix()
+	return now - now%usageWindowSeconds
+}
+
+func usageWindowTTL() time.Duration {
+	return (2*usageWindowSeconds + 10) * time.Second
+}
+
+// 使用 hash tag 保证同一对象的计数位于同一 slot
+func usageRedisKey(key string, kind string, windowStart int64) string {
+	return fmt.Sprintf("usageLimit:{%s}:%s:%d", key, kind, windowStart)
+}
+
+func usageInFlightKey(key string) string {
+	return fmt.Sprintf("usageLimit:{%s}:inflight", key)
+}
+
+// 未启用 Redis 时的内存实现，仅在当前实例内生效
+type usageCounter struct {
+	windowStart  int64
+	requests     int64
+	prevRequests int64
+	tokens       int64
+	prevTokens   int64
+	inFlight     int
+}
+
+// rotate 切换到 windowStart 所在窗口
+func (u *usageCounter) rotate(windowStart int64) {
+	switch u.windowStart {
+	case windowStart:
+		return
+	case windowStart - usageWindowSeconds:
+		u.prevRequests, u.prevTokens = u.requests, u.tokens
+	default:
+		u.prevRequests, u.prevTokens = 0, 0
+	}
+	u.requests, u.tokens = 0, 0
+	u.windowStart = windowStart
+}
+
+type memoryUsageStore struct {
+	mu       sync.Mutex
+	counters map[string]*usageCounter
+	once     sync.Once
+}
+
+var memoryUsage = &memoryUsageStore{counters: make(map[string]*usageCounter)}
+
+func (s *memoryUsageStore) get(key string, windowStart int64) *usageCounter {
+	s.once.Do(func() {
+		go s.clearExpired()
+	})
+	counter, ok := s.counters[key]
+	if !ok {
+		counter = &usageCounter{windowStart: windowStart}
+		s.counters[key] = counter
+	}
+	counter.rotate(windowStart)
+	return counter
+}
+
+func (s *memoryUsageStore) eval(key string, limits UsageLimits, windowStart int64, prevWeight float64, mode int) UsageExceeded {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counter := s.get(key, windowStart)
+	if mode != usageModeTrack {
+		if limits.RPM > 0 && float64(counter.requests)+float64(counter.prevRequests)*prevWeight >= float64(limits.RPM) {
+			return UsageRPMExceeded
+		}
+		if limits.TPM > 0 && float64(counter.tokens)+float64(counter.prevTokens)*prevWeight >= float64(limits.TPM) {
+			return UsageTPMExceeded
+		}
+		if limits.MaxInFlight > 0 && counter.inFlight >= limits.MaxInFlight {
+			return UsageInFlightExceeded
+		}
+	}
+	if mode != usageModeCheck {
+		if limits.RPM > 0 {
+			counter.requests++
+		}
+		if limits.MaxInFlight > 0 {
+			counter.inFlight++
+		}
+	}
+	return UsageAllowed
+}
+
+func (s *memoryUsageStore) addTokens(key string, windowStart int64, tokens int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.get(key, windowStart).tokens += int64(tokens)
+}
+
+func (s *memoryUsageStore) release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if counter, ok := s.counters[key]; ok && counter.inFlight > 0 {
+		counter.inFlight--
+	}
+}
+
+func (s *memoryUsageStore) clearExpired() {
+	for {
+		time.Sleep(usageWindowSeconds * time.Second)
+		s.mu.Lock()
+		expired := currentUsageWindow() - usageWindowSeconds
+		for key, counter := range s.counters {
+			if counter.windowStart < expired && counter.inFlight == 0 {
+				delete(s.counters, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
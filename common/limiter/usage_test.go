@@ -0,0 +1,50 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryUsageLimits(t *testing.T) {
+	common.RedisEnabled = false
+	ctx := context.Background()
+
+	release1, exceeded := AcquireUsage(ctx, "test:inflight", UsageLimits{MaxInFlight: 1})
+	require.Equal(t, UsageAllowed, exceeded)
+	_, exceeded = AcquireUsage(ctx, "test:inflight", UsageLimits{MaxInFlight: 1})
+	require.Equal(t, UsageInFlightExceeded, exceeded)
+	release1()
+	release1() // 重复释放不应多减
+	release2, exceeded := AcquireUsage(ctx, "test:inflight", UsageLimits{MaxInFlight: 1})
+	require.Equal(t, UsageAllowed, exceeded)
+	release2()
+
+	for i := 0; i < 2; i++ {
+		_, exceeded = AcquireUsage(ctx, "test:rpm", UsageLimits{RPM: 2})
+		require.Equal(t, UsageAllowed, exceeded)
+	}
+	_, exceeded = AcquireUsage(ctx, "test:rpm", UsageLimits{RPM: 2})
+	require.Equal(t, UsageRPMExceeded, exceeded)
+
+	require.Equal(t, UsageAllowed, CheckUsage(ctx, "test:tpm", UsageLimits{TPM: 100}))
+	AddUsageTokens(ctx, "test:tpm", 100)
+	require.Equal(t, UsageTPMExceeded, CheckUsage(ctx, "test:tpm", UsageLimits{TPM: 100}))
+	// 仅检查不占用
+	require.Equal(t, UsageAllowed, CheckUsage(ctx, "test:check", UsageLimits{RPM: 1}))
+	require.Equal(t, UsageAllowed, CheckUsage(ctx, "test:check", UsageLimits{RPM: 1}))
+}
+
+func TestUsageCounterRotate(t *testing.T) {
+	counter := &usageCounter{windowStart: 60, requests: 5, tokens: 100}
+	counter.rotate(120)
+	require.Equal(t, int64(5), counter.prevRequests)
+	require.Equal(t, int64(100), counter.prevTokens)
+	require.Zero(t, counter.requests)
+
+	counter.rotate(300)
+	require.Zero(t, counter.prevRequests)
+	require.Zero(t, counter.prevTokens)
+}
@@ -20,6 +20,9 @@ const (
 	ContextKeyTokenModelLimit        ContextKey = "token_model_limit"
 	ContextKeyTokenCrossGroupRetry   ContextKey = "token_cross_group_retry"
 	ContextKeyTokenGroups            ContextKey = "token_groups" // []string 有序分组列表（新多分组逻辑）
+	ContextKeyTokenUsageLimits       ContextKey = "token_usage_limits"
+	ContextKeyTokenBudget            ContextKey = "token_budget" // *model.Token 配置了预算的令牌
+	ContextKeyTokenOrganizationId    ContextKey = "token_organization_id"
 
 	/* channel related keys */
 	ContextKeyChannelId                ContextKey = "channel_id"
@@ -38,6 +41,9 @@ const (
 	ContextKeyChannelIsMultiKey        ContextKey = "channel_is_multi_key"
 	ContextKeyChannelMultiKeyIndex     ContextKey = "channel_multi_key_index"
 	ContextKeyChannelKey               ContextKey = "channel_key"
+	ContextKeyChannelUsageLimits       ContextKey = "channel_usage_limits"
+	// ContextKeyChannelUsageLimitKey 当前渠道的用量计数键，渠道设置了 TPM 限制时才有值
+	ContextKeyChannelUsageLimitKey ContextKey = "channel_usage_limit_key"
 
 	ContextKeyAutoGroup           ContextKey = "auto_group"
 	ContextKeyAutoGroupIndex      ContextKey = "auto_group_index"
@@ -53,6 +59,9 @@ const (
 	ContextKeyUsingGroup  ContextKey = "group"
 	ContextKeyUserName    ContextKey = "username"
 
+	// ContextKeyUsageLimitKeys 需要在请求结束后计入 token 消耗的令牌 / 用户用量计数键（[]string）
+	ContextKeyUsageLimitKeys ContextKey = "usage_limit_keys"
+
 	ContextKeyLocalCountTokens ContextKey = "local_count_tokens"
 
 	ContextKeySystemPromptOverride ContextKey = "system_prompt_override"
@@ -67,4 +76,7 @@ const (
 	// ContextKeyLanguage stores the user's language preference for i18n
 	ContextKeyLanguage ContextKey = "language"
 	ContextKeyIsStream ContextKey = "is_stream"
+
+	// ContextKeyStreamFailover stores the *service.StreamFailoverWriter shared by all relay attempts of a streaming request
+	ContextKeyStreamFailover ContextKey = "stream_failover"
 )
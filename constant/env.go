@@ -28,3 +28,7 @@ var TrustedRedirectDomains []string
 // FeishuAlertWebhook is the Feishu bot webhook URL for relay error alerts.
 // Set via FEISHU_ALERT_WEBHOOK env var. Leave empty to disable.
 var FeishuAlertWebhook string
+
+// MetricsToken is the bearer token required by the Prometheus /metrics endpoint.
+// Set via METRICS_TOKEN env var. Leave empty to disable the endpoint.
+var MetricsToken string
@@ -3,6 +3,8 @@ package constant
 type MultiKeyMode string
 
 const (
-	MultiKeyModeRandom  MultiKeyMode = "random"  // 随机
-	MultiKeyModePolling MultiKeyMode = "polling" // 轮询
+	MultiKeyModeRandom            MultiKeyMode = "random"              // 随机
+	MultiKeyModePolling           MultiKeyMode = "polling"             // 轮询
+	MultiKeyModeLeastRecentlyUsed MultiKeyMode = "least_recently_used" // 最久未使用优先
+	MultiKeyModeLeastSpent        MultiKeyMode = "least_spent"         // 当日消耗最少优先
 )
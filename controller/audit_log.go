@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAuditLogs 分页查看管理操作审计记录，可按操作人、操作、目标、请求 ID 与时间范围过滤
+func GetAuditLogs(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	filter := model.AuditLogFilter{
+		ActorId:        common.String2Int(c.Query("actor_id")),
+		ActorName:      c.Query("actor_name"),
+		Action:         c.Query("action"),
+		TargetType:     c.Query("target_type"),
+		TargetId:       c.Query("target_id"),
+		RequestId:      c.Query("request_id"),
+		StartTimestamp: startTimestamp,
+		EndTimestamp:   endTimestamp,
+	}
+	logs, total, err := model.GetAuditLogs(filter, pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(logs)
+	common.ApiSuccess(c, pageInfo)
+}
+
+func GetAuditLog(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	log, err := model.GetAuditLogById(id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, log)
+}
@@ -0,0 +1,220 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func optionalTimestamp(ts int64) *int64 {
+	if ts == 0 {
+		return nil
+	}
+	return &ts
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func toOpenAIBatch(batch *model.Batch) dto.OpenAIBatch {
+	b := dto.OpenAIBatch{
+		Id:               batch.BatchId,
+		Object:           "batch",
+		Endpoint:         batch.Endpoint,
+		InputFileId:      batch.InputFileId,
+		CompletionWindow: batch.CompletionWindow,
+		Status:           batch.Status,
+		OutputFileId:     optionalString(batch.OutputFileId),
+		ErrorFileId:      optionalString(batch.ErrorFileId),
+		CreatedAt:        batch.CreatedAt,
+		InProgressAt:     optionalTimestamp(batch.InProgressAt),
+		ExpiresAt:        optionalTimestamp(batch.ExpiresAt),
+		FinalizingAt:     optionalTimestamp(batch.FinalizingAt),
+		CompletedAt:      optionalTimestamp(batch.CompletedAt),
+		FailedAt:         optionalTimestamp(batch.FailedAt),
+		ExpiredAt:        optionalTimestamp(batch.ExpiredAt),
+		CancellingAt:     optionalTimestamp(batch.CancellingAt),
+		CancelledAt:      optionalTimestamp(batch.CancelledAt),
+		RequestCounts: dto.OpenAIBatchRequestCount{
+			Total:     batch.TotalCount,
+			Completed: batch.CompletedCount,
+			Failed:    batch.FailedCount,
+		},
+	}
+	if batch.Errors != "" {
+		var errs dto.OpenAIBatchErrors
+		if err := common.UnmarshalJsonStr(batch.Errors, &errs); err == nil {
+			b.Errors = &errs
+		}
+	}
+	if batch.Metadata != "" {
+		_ = common.UnmarshalJsonStr(batch.Metadata, &b.Metadata)
+	}
+	return b
+}
+
+func checkBatchApiEnabled(c *gin.Context) bool {
+	if !operation_setting.GetBatchSetting().Enabled || !operation_setting.GetFileSetting().Enabled {
+		fileApiError(c, http.StatusNotImplemented, "nice_api_error", "Batch API is not enabled")
+		return false
+	}
+	return true
+}
+
+func getUserBatch(c *gin.Context) (*model.Batch, bool) {
+	batchId := c.Param("id")
+	batch, err := model.GetUserBatchById(c.GetInt("id"), batchId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			fileApiError(c, http.StatusNotFound, "invalid_request_error", fmt.Sprintf("No batch found with id '%s'.", batchId))
+		} else {
+			fileApiError(c, http.StatusInternalServerError, "server_error", err.Error())
+		}
+		return nil, false
+	}
+	return batch, true
+}
+
+// CreateBatch POST /v1/batches
+func CreateBatch(c *gin.Context) {
+	if !checkBatchApiEnabled(c) {
+		return
+	}
+	var req dto.OpenAIBatchCreateRequest
+	if err := common.UnmarshalBodyReusable(c, &req); err != nil {
+		fileApiError(c, http.StatusBadRequest, "invalid_request_error", "Invalid request body: "+err.Error())
+		return
+	}
+	batch, err := service.CreateBatch(c.GetInt("id"), c.GetInt("token_id"), c.ClientIP(), &req)
+	if err != nil {
+		fileApiError(c, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, toOpenAIBatch(batch))
+}
+
+// RetrieveBatch GET /v1/batches/:id
+func RetrieveBatch(c *gin.Context) {
+	if !checkBatchApiEnabled(c) {
+		return
+	}
+	batch, ok := getUserBatch(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, toOpenAIBatch(batch))
+}
+
+// CancelBatch POST /v1/batches/:id/cancel
+func CancelBatch(c *gin.Context) {
+	if !checkBatchApiEnabled(c) {
+		return
+	}
+	batch, ok := getUserBatch(c)
+	if !ok {
+		return
+	}
+	if err := service.CancelBatch(batch); err != nil {
+		fileApiError(c, http.StatusConflict, "invalid_request_error", err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, toOpenAIBatch(batch))
+}
+
+// ListBatches GET /v1/batches
+func ListBatches(c *gin.Context) {
+	if !checkBatchApiEnabled(c) {
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	batches, hasMore, err := model.ListUserBatches(c.GetInt("id"), c.Query("after"), limit)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			fileApiError(c, http.StatusBadRequest, "invalid_request_error", "Invalid 'after' cursor")
+			return
+		}
+		fileApiError(c, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	resp := dto.OpenAIBatchList{
+		Object:  "list",
+		Data:    make([]dto.OpenAIBatch, 0, len(batches)),
+		HasMore: hasMore,
+	}
+	for _, batch := range batches {
+		resp.Data = append(resp.Data, toOpenAIBatch(batch))
+	}
+	if len(resp.Data) > 0 {
+		resp.FirstId = resp.Data[0].Id
+		resp.LastId = resp.Data[len(resp.Data)-1].Id
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func getBatchQueryParams(c *gin.Context) model.BatchQueryParams {
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	return model.BatchQueryParams{
+		BatchId:        c.Query("batch_id"),
+		UserId:         c.Query("user_id"),
+		Status:         c.Query("status"),
+		StartTimestamp: startTimestamp,
+		EndTimestamp:   endTimestamp,
+	}
+}
+
+// GetAllBatch 管理员查看所有批次
+func GetAllBatch(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	items, total, err := model.GetAllBatches(0, pageInfo.GetStartIdx(), pageInfo.GetPageSize(), getBatchQueryParams(c))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	userIds := types.NewSet[int]()
+	for _, item := range items {
+		userIds.Add(item.UserId)
+	}
+	for _, userId := range userIds.Items() {
+		if user, err := model.GetUserCache(userId); err == nil {
+			for _, item := range items {
+				if item.UserId == userId {
+					item.Username = user.Username
+				}
+			}
+		}
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(items)
+	common.ApiSuccess(c, pageInfo)
+}
+
+// GetUserBatch 用户查看自己的批次
+func GetUserBatch(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	queryParams := getBatchQueryParams(c)
+	queryParams.UserId = ""
+	items, total, err := model.GetAllBatches(c.GetInt("id"), pageInfo.GetStartIdx(), pageInfo.GetPageSize(), queryParams)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(items)
+	common.ApiSuccess(c, pageInfo)
+}
@@ -950,7 +950,10 @@ func UpdateChannel(c *gin.Context) {
 				channel.Key = strings.Join(allKeys, "\n")
 			}
 		case "replace":
-			// 覆盖模式：直接使用新密钥（默认行为，不需要特殊处理）
+			// 覆盖模式：直接使用新密钥，原有密钥的元数据不再适用
+			if channel.Key != "" {
+				channel.ChannelInfo.MultiKeyMeta = nil
+			}
 		}
 	}
 	err = channel.Update()
@@ -1210,11 +1213,15 @@ func CopyChannel(c *gin.Context) {
 // MultiKeyManageRequest represents the request for multi-key management operations
 type MultiKeyManageRequest struct {
 	ChannelId int    `json:"channel_id"`
-	Action    string `json:"action"`              // "disable_key", "enable_key", "delete_key", "delete_disabled_keys", "get_key_status"
-	KeyIndex  *int   `json:"key_index,omitempty"` // for disable_key, enable_key, and delete_key actions
+	Action    string `json:"action"`              // "disable_key", "enable_key", "delete_key", "delete_disabled_keys", "get_key_status", "set_key_meta"
+	KeyIndex  *int   `json:"key_index,omitempty"` // for disable_key, enable_key, delete_key and set_key_meta actions
 	Page      int    `json:"page,omitempty"`      // for get_key_status pagination
 	PageSize  int    `json:"page_size,omitempty"` // for get_key_status pagination
 	Status    *int   `json:"status,omitempty"`    // for get_key_status filtering: 1=enabled, 2=manual_disabled, 3=auto_disabled, nil=all
+	// for set_key_meta, nil fields are left unchanged
+	ExpiresAt     *int64  `json:"expires_at,omitempty"`
+	DailySpendCap *int64  `json:"daily_spend_cap,omitempty"`
+	Note          *string `json:"note,omitempty"`
 }
 
 // MultiKeyStatusResponse represents the response for key status query
@@ -1236,6 +1243,12 @@ type KeyStatus struct {
 	DisabledTime int64  `json:"disabled_time,omitempty"`
 	Reason       string `json:"reason,omitempty"`
 	KeyPreview   string `json:"key_preview"` // first 10 chars of key for identification
+	// per-key metadata and usage
+	ExpiresAt      int64  `json:"expires_at,omitempty"`
+	DailySpendCap  int64  `json:"daily_spend_cap,omitempty"`
+	Note           string `json:"note,omitempty"`
+	TodayUsedQuota int64  `json:"today_used_quota"`
+	LastUsedAt     int64  `json:"last_used_at,omitempty"`
 }
 
 // ManageMultiKeys handles multi-key management operations
@@ -1285,6 +1298,8 @@ func ManageMultiKeys(c *gin.Context) {
 		// Statistics for all keys (unchanged by filtering)
 		var enabledCount, manualDisabledCount, autoDisabledCount int
 
+		keyUsage := model.GetChannelKeyUsage(channel)
+
 		// Build all key status data first
 		var allKeyStatusList []KeyStatus
 		for i, key := range keys {
@@ -1323,12 +1338,18 @@ func ManageMultiKeys(c *gin.Context) {
 				keyPreview = key[:10] + "..."
 			}
 
+			meta := channel.ChannelInfo.GetMultiKeyMeta(i)
 			allKeyStatusList = append(allKeyStatusList, KeyStatus{
-				Index:        i,
-				Status:       status,
-				DisabledTime: disabledTime,
-				Reason:       reason,
-				KeyPreview:   keyPreview,
+				Index:          i,
+				Status:         status,
+				DisabledTime:   disabledTime,
+				Reason:         reason,
+				KeyPreview:     keyPreview,
+				ExpiresAt:      meta.ExpiresAt,
+				DailySpendCap:  meta.DailySpendCap,
+				Note:           meta.Note,
+				TodayUsedQuota: keyUsage[i].UsedQuota,
+				LastUsedAt:     keyUsage[i].LastUsedAt,
 			})
 		}
 
@@ -1562,6 +1583,7 @@ func ManageMultiKeys(c *gin.Context) {
 		var newStatusList = make(map[int]int)
 		var newDisabledTime = make(map[int]int64)
 		var newDisabledReason = make(map[int]string)
+		var newMeta = make(map[int]model.MultiKeyMeta)
 
 		newIndex := 0
 		for i, key := range keys {
@@ -1588,6 +1610,9 @@ func ManageMultiKeys(c *gin.Context) {
 					newDisabledReason[newIndex] = r
 				}
 			}
+			if meta, exists := channel.ChannelInfo.MultiKeyMeta[i]; exists {
+				newMeta[newIndex] = meta
+			}
 			newIndex++
 		}
 
@@ -1605,6 +1630,7 @@ func ManageMultiKeys(c *gin.Context) {
 		channel.ChannelInfo.MultiKeyStatusList = newStatusList
 		channel.ChannelInfo.MultiKeyDisabledTime = newDisabledTime
 		channel.ChannelInfo.MultiKeyDisabledReason = newDisabledReason
+		channel.ChannelInfo.MultiKeyMeta = newMeta
 
 		err = channel.Update()
 		if err != nil {
@@ -1626,6 +1652,7 @@ func ManageMultiKeys(c *gin.Context) {
 		var newStatusList = make(map[int]int)
 		var newDisabledTime = make(map[int]int64)
 		var newDisabledReason = make(map[int]string)
+		var newMeta = make(map[int]model.MultiKeyMeta)
 
 		newIndex := 0
 		for i, key := range keys {
@@ -1641,6 +1668,9 @@ func ManageMultiKeys(c *gin.Context) {
 				deletedCount++
 			} else {
 				remainingKeys = append(remainingKeys, key)
+				if meta, exists := channel.ChannelInfo.MultiKeyMeta[i]; exists {
+					newMeta[newIndex] = meta
+				}
 				// 保留非自动禁用密钥的状态信息，重新索引
 				if status != 1 {
 					newStatusList[newIndex] = status
@@ -1673,6 +1703,7 @@ func ManageMultiKeys(c *gin.Context) {
 		channel.ChannelInfo.MultiKeyStatusList = newStatusList
 		channel.ChannelInfo.MultiKeyDisabledTime = newDisabledTime
 		channel.ChannelInfo.MultiKeyDisabledReason = newDisabledReason
+		channel.ChannelInfo.MultiKeyMeta = newMeta
 
 		err = channel.Update()
 		if err != nil {
@@ -1688,6 +1719,68 @@ func ManageMultiKeys(c *gin.Context) {
 		})
 		return
 
+	case "set_key_meta":
+		if request.KeyIndex == nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "未指定要设置的密钥索引",
+			})
+			return
+		}
+
+		keyIndex := *request.KeyIndex
+		if keyIndex < 0 || keyIndex >= channel.ChannelInfo.MultiKeySize {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "密钥索引超出范围",
+			})
+			return
+		}
+		if (request.ExpiresAt != nil && *request.ExpiresAt < 0) || (request.DailySpendCap != nil && *request.DailySpendCap < 0) {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "过期时间和每日消耗上限不能为负数",
+			})
+			return
+		}
+
+		meta := channel.ChannelInfo.GetMultiKeyMeta(keyIndex)
+		if request.ExpiresAt != nil {
+			meta.ExpiresAt = *request.ExpiresAt
+		}
+		if request.DailySpendCap != nil {
+			meta.DailySpendCap = *request.DailySpendCap
+		}
+		if request.Note != nil {
+			meta.Note = strings.TrimSpace(*request.Note)
+		}
+		channel.ChannelInfo.SetMultiKeyMeta(keyIndex, meta)
+
+		// 延长有效期或提高上限后，立即恢复因此被自动禁用的密钥
+		if channel.ChannelInfo.MultiKeyStatusList[keyIndex] == common.ChannelStatusAutoDisabled {
+			reason := channel.ChannelInfo.MultiKeyDisabledReason[keyIndex]
+			usage := model.GetChannelKeyUsage(channel)[keyIndex]
+			if (reason == model.MultiKeyReasonExpired && (meta.ExpiresAt == 0 || meta.ExpiresAt > common.GetTimestamp())) ||
+				(reason == model.MultiKeyReasonDailySpendCap && (meta.DailySpendCap == 0 || usage.UsedQuota < meta.DailySpendCap)) {
+				delete(channel.ChannelInfo.MultiKeyStatusList, keyIndex)
+				delete(channel.ChannelInfo.MultiKeyDisabledTime, keyIndex)
+				delete(channel.ChannelInfo.MultiKeyDisabledReason, keyIndex)
+			}
+		}
+
+		err = channel.Update()
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+
+		model.InitChannelCache()
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "密钥信息已更新",
+		})
+		return
+
 	default:
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
@@ -0,0 +1,33 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/pkg/channelhealth"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetChannelHealth 查看当前实例的渠道健康度统计与熔断状态
+func GetChannelHealth(c *gin.Context) {
+	cfg := operation_setting.GetChannelSelectionSetting().HealthConfig()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    channelhealth.Snapshot(cfg),
+	})
+}
+
+// ResetChannelHealth 清除指定渠道的健康度统计，立即解除熔断
+func ResetChannelHealth(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	channelhealth.Reset(id)
+	common.ApiSuccess(c, nil)
+}
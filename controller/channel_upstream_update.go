@@ -622,6 +622,23 @@ func runChannelUpstreamModelUpdateTaskOnce() {
 				removeModelSamples,
 			),
 		)
+		channelEvents := make([]map[string]interface{}, 0, len(channelSummaries))
+		for _, summary := range channelSummaries {
+			channelEvents = append(channelEvents, map[string]interface{}{
+				"channel_name": summary.ChannelName,
+				"add_count":    summary.AddCount,
+				"remove_count": summary.RemoveCount,
+			})
+		}
+		service.PublishWebhookEvent(model.WebhookEventUpstreamModelUpdateDetected, 0, map[string]interface{}{
+			"checked_channels":   checkedChannels,
+			"changed_channels":   changedChannels,
+			"failed_channel_ids": failedChannelIDs,
+			"auto_added_models":  autoAddedModels,
+			"channels":           channelEvents,
+			"added_models":       addModelSamples,
+			"removed_models":     removeModelSamples,
+		})
 	}
 }
 
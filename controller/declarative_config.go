@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/i18n"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DeclarativeConfigRequest struct {
+	// Content 为空时使用 DECLARATIVE_CONFIG_FILE 指定的配置文件
+	Content string `json:"content"`
+}
+
+func loadDeclarativeConfig(c *gin.Context) (*service.DeclarativeConfig, bool) {
+	var req DeclarativeConfigRequest
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil && !errors.Is(err, io.EOF) {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return nil, false
+	}
+	var (
+		cfg *service.DeclarativeConfig
+		err error
+	)
+	if strings.TrimSpace(req.Content) != "" {
+		cfg, err = service.ParseDeclarativeConfig([]byte(req.Content), "", false)
+	} else {
+		path := service.GetDeclarativeConfigPath()
+		if path == "" {
+			common.ApiErrorI18n(c, i18n.MsgDeclarativeConfigNotConfigured)
+			return nil, false
+		}
+		cfg, err = service.LoadDeclarativeConfigFile(path)
+	}
+	if err != nil {
+		common.ApiError(c, err)
+		return nil, false
+	}
+	return cfg, true
+}
+
+// PlanDeclarativeConfig 返回声明式配置与数据库之间的差异，不做任何写入
+func PlanDeclarativeConfig(c *gin.Context) {
+	cfg, ok := loadDeclarativeConfig(c)
+	if !ok {
+		return
+	}
+	plan, err := service.PlanDeclarativeConfig(cfg)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, plan)
+}
+
+// ApplyDeclarativeConfig 将数据库收敛到声明式配置描述的状态
+func ApplyDeclarativeConfig(c *gin.Context) {
+	cfg, ok := loadDeclarativeConfig(c)
+	if !ok {
+		return
+	}
+	plan, err := service.ApplyDeclarativeConfig(cfg)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, plan)
+}
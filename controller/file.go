@@ -0,0 +1,290 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func fileApiError(c *gin.Context, status int, errType, message string) {
+	c.JSON(status, gin.H{
+		"error": gin.H{
+			"message": message,
+			"type":    errType,
+		},
+	})
+}
+
+func toOpenAIFile(file *model.File) dto.OpenAIFile {
+	f := dto.OpenAIFile{
+		Id:        file.FileId,
+		Object:    "file",
+		Bytes:     file.Bytes,
+		CreatedAt: file.CreatedAt,
+		Filename:  file.Filename,
+		Purpose:   file.Purpose,
+		Status:    file.Status,
+	}
+	if file.ExpiresAt > 0 {
+		expiresAt := file.ExpiresAt
+		f.ExpiresAt = &expiresAt
+	}
+	return f
+}
+
+// getUserFile 获取当前用户的文件，失败时已写入错误响应
+func getUserFile(c *gin.Context) (*model.File, bool) {
+	fileId := c.Param("id")
+	file, err := model.GetUserFileById(c.GetInt("id"), fileId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			fileApiError(c, http.StatusNotFound, "invalid_request_error", fmt.Sprintf("No such File object: %s", fileId))
+		} else {
+			fileApiError(c, http.StatusInternalServerError, "server_error", err.Error())
+		}
+		return nil, false
+	}
+	if file.IsExpired() {
+		fileApiError(c, http.StatusNotFound, "invalid_request_error", fmt.Sprintf("File %s has expired", fileId))
+		return nil, false
+	}
+	return file, true
+}
+
+func checkFileApiEnabled(c *gin.Context) bool {
+	if !operation_setting.GetFileSetting().Enabled {
+		fileApiError(c, http.StatusNotImplemented, "nice_api_error", "Files API is not enabled")
+		return false
+	}
+	return true
+}
+
+// UploadFile POST /v1/files
+func UploadFile(c *gin.Context) {
+	if !checkFileApiEnabled(c) {
+		return
+	}
+	fileSetting := operation_setting.GetFileSetting()
+	maxBytes := int64(fileSetting.MaxFileSizeMB) << 20
+	if maxBytes > 0 {
+		// multipart 额外开销预留 1MB
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+(1<<20))
+	}
+
+	purpose := c.PostForm("purpose")
+	if !operation_setting.IsFilePurposeAllowed(purpose) {
+		fileApiError(c, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("Invalid purpose: '%s'", purpose))
+		return
+	}
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		fileApiError(c, http.StatusBadRequest, "invalid_request_error", "Missing or invalid file: "+err.Error())
+		return
+	}
+	if maxBytes > 0 && fileHeader.Size > maxBytes {
+		fileApiError(c, http.StatusRequestEntityTooLarge, "invalid_request_error",
+			fmt.Sprintf("File is too large, maximum size is %d MB", fileSetting.MaxFileSizeMB))
+		return
+	}
+
+	userId := c.GetInt("id")
+	count, totalBytes, err := model.GetUserFileUsage(userId)
+	if err != nil {
+		fileApiError(c, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	if fileSetting.UserMaxFiles > 0 && count >= int64(fileSetting.UserMaxFiles) {
+		fileApiError(c, http.StatusForbidden, "invalid_request_error",
+			fmt.Sprintf("File count limit exceeded, maximum is %d", fileSetting.UserMaxFiles))
+		return
+	}
+	if fileSetting.UserMaxStorageMB > 0 && totalBytes+fileHeader.Size > int64(fileSetting.UserMaxStorageMB)<<20 {
+		fileApiError(c, http.StatusForbidden, "invalid_request_error",
+			fmt.Sprintf("File storage limit exceeded, maximum is %d MB", fileSetting.UserMaxStorageMB))
+		return
+	}
+
+	src, err := fileHeader.Open()
+	if err != nil {
+		fileApiError(c, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	defer src.Close()
+
+	contentType := fileHeader.Header.Get("Content-Type")
+	file := &model.File{
+		UserId:      userId,
+		TokenId:     c.GetInt("token_id"),
+		Filename:    fileHeader.Filename,
+		Purpose:     purpose,
+		Bytes:       fileHeader.Size,
+		ContentType: contentType,
+		Status:      model.FileStatusProcessed,
+		CreatedAt:   common.GetTimestamp(),
+	}
+	if fileSetting.DefaultExpireHours > 0 {
+		file.ExpiresAt = file.CreatedAt + int64(fileSetting.DefaultExpireHours)*int64(time.Hour/time.Second)
+	}
+
+	if fileSetting.PassThroughEnabled {
+		channel, err := service.SelectFileUpstreamChannel(c)
+		if err != nil {
+			fileApiError(c, http.StatusServiceUnavailable, "new_api_error", "No available upstream channel for files: "+err.Error())
+			return
+		}
+		key, keyIndex, apiErr := channel.GetNextEnabledKey()
+		if apiErr != nil {
+			fileApiError(c, http.StatusServiceUnavailable, "new_api_error", "No available upstream channel for files: "+apiErr.Error())
+			return
+		}
+		upstreamFile, err := service.UploadFileToUpstream(c.Request.Context(), channel, key, fileHeader.Filename, purpose, contentType, src)
+		if err != nil {
+			logger.LogError(c, fmt.Sprintf("upload file to channel #%d failed: %s", channel.Id, err.Error()))
+			fileApiError(c, http.StatusBadGateway, "upstream_error", "Failed to upload file to upstream")
+			return
+		}
+		file.FileId = upstreamFile.Id
+		file.StorageType = model.FileStorageUpstream
+		file.ChannelId = channel.Id
+		file.ChannelKeyIndex = keyIndex
+		if upstreamFile.Status != "" {
+			file.Status = upstreamFile.Status
+		}
+		if upstreamFile.ExpiresAt != nil && *upstreamFile.ExpiresAt > 0 {
+			file.ExpiresAt = *upstreamFile.ExpiresAt
+		}
+	} else {
+		storage, err := service.GetFileStorage()
+		if err != nil {
+			fileApiError(c, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		file.FileId = model.GenerateFileId()
+		file.StorageType = storage.Type()
+		file.StorageKey = strconv.Itoa(userId) + "/" + file.FileId
+		if storage.Type() == operation_setting.FileStorageS3 {
+			file.StorageKey = fileSetting.S3Prefix + file.StorageKey
+		}
+		if err = storage.Put(c.Request.Context(), file.StorageKey, src, fileHeader.Size, contentType); err != nil {
+			logger.LogError(c, "save file failed: "+err.Error())
+			fileApiError(c, http.StatusInternalServerError, "server_error", "Failed to save file")
+			return
+		}
+	}
+
+	if err = file.Insert(); err != nil {
+		fileApiError(c, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, toOpenAIFile(file))
+}
+
+// ListFiles GET /v1/files
+func ListFiles(c *gin.Context) {
+	if !checkFileApiEnabled(c) {
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10000"))
+	order := c.DefaultQuery("order", "desc")
+	files, hasMore, err := model.ListUserFiles(c.GetInt("id"), c.Query("purpose"), c.Query("after"), limit, order)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			fileApiError(c, http.StatusBadRequest, "invalid_request_error", "Invalid 'after' cursor")
+			return
+		}
+		fileApiError(c, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	resp := dto.OpenAIFileList{
+		Object:  "list",
+		Data:    make([]dto.OpenAIFile, 0, len(files)),
+		HasMore: hasMore,
+	}
+	for _, file := range files {
+		resp.Data = append(resp.Data, toOpenAIFile(file))
+	}
+	if len(resp.Data) > 0 {
+		resp.FirstId = resp.Data[0].Id
+		resp.LastId = resp.Data[len(resp.Data)-1].Id
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// RetrieveFile GET /v1/files/:id
+func RetrieveFile(c *gin.Context) {
+	if !checkFileApiEnabled(c) {
+		return
+	}
+	file, ok := getUserFile(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, toOpenAIFile(file))
+}
+
+// DeleteFile DELETE /v1/files/:id
+func DeleteFile(c *gin.Context) {
+	if !checkFileApiEnabled(c) {
+		return
+	}
+	file, ok := getUserFile(c)
+	if !ok {
+		return
+	}
+	if err := service.DeleteFileObject(c.Request.Context(), file); err != nil {
+		logger.LogError(c, fmt.Sprintf("delete file %s failed: %s", file.FileId, err.Error()))
+		fileApiError(c, http.StatusInternalServerError, "server_error", "Failed to delete file")
+		return
+	}
+	c.JSON(http.StatusOK, dto.OpenAIFileDeleteResponse{
+		Id:      file.FileId,
+		Object:  "file",
+		Deleted: true,
+	})
+}
+
+// fileContentDisposition 生成下载响应头，文件名中的引号等字符会被转义，非 ASCII 文件名按 RFC 2231 编码
+func fileContentDisposition(filename string) string {
+	if disposition := mime.FormatMediaType("attachment", map[string]string{"filename": filename}); disposition != "" {
+		return disposition
+	}
+	return "attachment"
+}
+
+// GetFileContent GET /v1/files/:id/content
+func GetFileContent(c *gin.Context) {
+	if !checkFileApiEnabled(c) {
+		return
+	}
+	file, ok := getUserFile(c)
+	if !ok {
+		return
+	}
+
+	reader, contentType, err := service.OpenFileContent(c.Request.Context(), file)
+	if err != nil {
+		logger.LogError(c, fmt.Sprintf("read file %s failed: %s", file.FileId, err.Error()))
+		fileApiError(c, http.StatusBadGateway, "server_error", "Failed to read file content")
+		return
+	}
+	defer reader.Close()
+
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.Header("Content-Disposition", fileContentDisposition(file.Filename))
+	c.DataFromReader(http.StatusOK, -1, contentType, reader, nil)
+}
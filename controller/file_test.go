@@ -0,0 +1,191 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupFileControllerTest(t *testing.T) *gin.Engine {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	common.UsingSQLite = true
+	common.UsingMySQL = false
+	common.UsingPostgreSQL = false
+	common.RedisEnabled = false
+	originalMemoryCache := common.MemoryCacheEnabled
+	common.MemoryCacheEnabled = false
+	service.InitHttpClient()
+
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", strings.ReplaceAll(t.Name(), "/", "_"))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	model.DB = db
+	model.LOG_DB = db
+	require.NoError(t, db.AutoMigrate(&model.File{}, &model.Channel{}))
+
+	fileSetting := operation_setting.GetFileSetting()
+	original := *fileSetting
+	fileSetting.Enabled = true
+	fileSetting.StorageType = operation_setting.FileStorageLocal
+	fileSetting.LocalPath = t.TempDir()
+	t.Cleanup(func() {
+		*fileSetting = original
+		common.MemoryCacheEnabled = originalMemoryCache
+		sqlDB, err := db.DB()
+		if err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("id", 1)
+		c.Next()
+	})
+	router.POST("/v1/files", UploadFile)
+	router.GET("/v1/files", ListFiles)
+	router.GET("/v1/files/:id", RetrieveFile)
+	router.GET("/v1/files/:id/content", GetFileContent)
+	router.DELETE("/v1/files/:id", DeleteFile)
+	return router
+}
+
+func uploadTestFile(t *testing.T, router *gin.Engine, filename string, content string) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	require.NoError(t, writer.WriteField("purpose", "batch"))
+	part, err := writer.CreateFormFile("file", filename)
+	require.NoError(t, err)
+	_, err = part.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/files", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func serveFileRequest(router *gin.Engine, method string, target string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(method, target, nil))
+	return recorder
+}
+
+func TestFilesApiLocalStorage(t *testing.T) {
+	router := setupFileControllerTest(t)
+
+	filename := `训练 "data".jsonl`
+	recorder := uploadTestFile(t, router, filename, `{"prompt":"hi"}`)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	var uploaded dto.OpenAIFile
+	require.NoError(t, common.Unmarshal(recorder.Body.Bytes(), &uploaded))
+	require.True(t, strings.HasPrefix(uploaded.Id, "file-"))
+	require.Equal(t, filename, uploaded.Filename)
+	require.EqualValues(t, len(`{"prompt":"hi"}`), uploaded.Bytes)
+
+	recorder = serveFileRequest(router, http.MethodGet, "/v1/files?purpose=batch")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var list dto.OpenAIFileList
+	require.NoError(t, common.Unmarshal(recorder.Body.Bytes(), &list))
+	require.Len(t, list.Data, 1)
+	require.Equal(t, uploaded.Id, list.FirstId)
+
+	recorder = serveFileRequest(router, http.MethodGet, "/v1/files/"+uploaded.Id+"/content")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, `{"prompt":"hi"}`, recorder.Body.String())
+	// 文件名中的引号与非 ASCII 字符被正确编码，解析后与原文件名一致
+	disposition, params, err := mime.ParseMediaType(recorder.Header().Get("Content-Disposition"))
+	require.NoError(t, err)
+	require.Equal(t, "attachment", disposition)
+	require.Equal(t, filename, params["filename"])
+
+	recorder = serveFileRequest(router, http.MethodDelete, "/v1/files/"+uploaded.Id)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	recorder = serveFileRequest(router, http.MethodGet, "/v1/files/"+uploaded.Id)
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestFileContentDispositionEscapesFilename(t *testing.T) {
+	for _, filename := range []string{`a"; filename="evil.exe`, "a\r\nX-Injected: 1.txt", `dir\name.txt`, "plain.jsonl"} {
+		header := fileContentDisposition(filename)
+		require.NotContains(t, header, "\r")
+		require.NotContains(t, header, "\n")
+		_, params, err := mime.ParseMediaType(header)
+		require.NoError(t, err)
+		require.Equal(t, filename, params["filename"])
+	}
+}
+
+func TestFilesApiPassThroughContentAndDelete(t *testing.T) {
+	router := setupFileControllerTest(t)
+
+	deleted := false
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Bearer sk-upstream", r.Header.Get("Authorization"))
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/files/file-upstream123456/content":
+			w.Header().Set("Content-Type", "application/jsonl")
+			_, _ = w.Write([]byte("upstream content"))
+		case r.Method == http.MethodDelete && r.URL.Path == "/v1/files/file-upstream123456":
+			deleted = true
+			_, _ = w.Write([]byte(`{"id":"file-upstream123456","deleted":true}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer upstream.Close()
+
+	channel := &model.Channel{
+		Type:    constant.ChannelTypeOpenAI,
+		Key:     "sk-upstream",
+		Name:    "files",
+		Status:  common.ChannelStatusEnabled,
+		BaseURL: &upstream.URL,
+	}
+	require.NoError(t, model.DB.Create(channel).Error)
+	// 透传文件只在本地保存元数据，内容与删除均转发到所属渠道
+	require.NoError(t, (&model.File{
+		FileId:      "file-upstream123456",
+		UserId:      1,
+		Filename:    "input.jsonl",
+		Purpose:     "batch",
+		Status:      model.FileStatusProcessed,
+		StorageType: model.FileStorageUpstream,
+		ChannelId:   channel.Id,
+	}).Insert())
+
+	recorder := serveFileRequest(router, http.MethodGet, "/v1/files/file-upstream123456/content")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	body, err := io.ReadAll(recorder.Body)
+	require.NoError(t, err)
+	require.Equal(t, "upstream content", string(body))
+	require.Equal(t, "application/jsonl", recorder.Header().Get("Content-Type"))
+
+	recorder = serveFileRequest(router, http.MethodDelete, "/v1/files/file-upstream123456")
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	require.True(t, deleted)
+	_, err = model.GetUserFileById(1, "file-upstream123456")
+	require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
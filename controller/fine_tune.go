@@ -0,0 +1,158 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay/channel/task/finetune"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func checkFineTuneEnabled(c *gin.Context) bool {
+	if !operation_setting.GetFineTuneSetting().Enabled {
+		fileApiError(c, http.StatusNotImplemented, "nice_api_error", "Fine-tuning API is not enabled")
+		return false
+	}
+	return true
+}
+
+// toOpenAIFineTuneJob 将任务中保存的上游任务对象转换为对外展示的格式
+func toOpenAIFineTuneJob(task *model.Task) json.RawMessage {
+	data, err := finetune.ConvertToOpenAIJob(task.Data, task.TaskID, task.Properties.OriginModelName)
+	if err != nil {
+		return task.Data
+	}
+	return data
+}
+
+// getUserFineTuneTask 获取当前用户的微调任务，失败时已写入错误响应
+func getUserFineTuneTask(c *gin.Context) (*model.Task, bool) {
+	jobId := c.Param("id")
+	task, exist, err := model.GetByTaskId(c.GetInt("id"), jobId)
+	if err != nil {
+		fileApiError(c, http.StatusInternalServerError, "server_error", err.Error())
+		return nil, false
+	}
+	if !exist || task.Platform != constant.TaskPlatformFineTune {
+		fileApiError(c, http.StatusNotFound, "invalid_request_error", fmt.Sprintf("No fine-tuning job found with id '%s'.", jobId))
+		return nil, false
+	}
+	return task, true
+}
+
+// proxyFineTuneJobRequest 将请求转发到任务所属的上游渠道，返回上游响应体
+func proxyFineTuneJobRequest(c *gin.Context, task *model.Task, method string, subPath string) (int, []byte, bool) {
+	resp, err := service.DoFineTuneJobRequest(c.Request.Context(), task, method, subPath, c.Request.URL.RawQuery)
+	if err != nil {
+		logger.LogError(c, fmt.Sprintf("request fine-tuning job %s failed: %s", task.TaskID, err.Error()))
+		fileApiError(c, http.StatusBadGateway, "upstream_error", "Failed to request upstream fine-tuning job")
+		return 0, nil, false
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		fileApiError(c, http.StatusBadGateway, "upstream_error", err.Error())
+		return 0, nil, false
+	}
+	return resp.StatusCode, body, true
+}
+
+// ListFineTuneJobs GET /v1/fine_tuning/jobs
+func ListFineTuneJobs(c *gin.Context) {
+	if !checkFineTuneEnabled(c) {
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	tasks, hasMore, err := model.ListUserTasksByPlatform(c.GetInt("id"), constant.TaskPlatformFineTune, c.Query("after"), limit)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			fileApiError(c, http.StatusBadRequest, "invalid_request_error", "Invalid 'after' cursor")
+			return
+		}
+		fileApiError(c, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	resp := dto.OpenAIFineTuneJobList{
+		Object:  "list",
+		Data:    make([]json.RawMessage, 0, len(tasks)),
+		HasMore: hasMore,
+	}
+	for _, task := range tasks {
+		resp.Data = append(resp.Data, toOpenAIFineTuneJob(task))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// RetrieveFineTuneJob GET /v1/fine_tuning/jobs/:id
+func RetrieveFineTuneJob(c *gin.Context) {
+	if !checkFineTuneEnabled(c) {
+		return
+	}
+	task, ok := getUserFineTuneTask(c)
+	if !ok {
+		return
+	}
+	c.Data(http.StatusOK, "application/json", toOpenAIFineTuneJob(task))
+}
+
+// CancelFineTuneJob POST /v1/fine_tuning/jobs/:id/cancel
+// 取消后任务状态由轮询同步，失败的任务会退还预扣额度
+func CancelFineTuneJob(c *gin.Context) {
+	if !checkFineTuneEnabled(c) {
+		return
+	}
+	task, ok := getUserFineTuneTask(c)
+	if !ok {
+		return
+	}
+	status, body, ok := proxyFineTuneJobRequest(c, task, http.MethodPost, "/cancel")
+	if !ok {
+		return
+	}
+	if status != http.StatusOK {
+		c.Data(status, "application/json", body)
+		return
+	}
+	data, err := finetune.ConvertToOpenAIJob(body, task.TaskID, task.Properties.OriginModelName)
+	if err != nil {
+		data = body
+	}
+	c.Data(http.StatusOK, "application/json", data)
+}
+
+// ListFineTuneJobEvents GET /v1/fine_tuning/jobs/:id/events
+func ListFineTuneJobEvents(c *gin.Context) {
+	proxyFineTuneJobSubResource(c, "/events")
+}
+
+// ListFineTuneJobCheckpoints GET /v1/fine_tuning/jobs/:id/checkpoints
+func ListFineTuneJobCheckpoints(c *gin.Context) {
+	proxyFineTuneJobSubResource(c, "/checkpoints")
+}
+
+func proxyFineTuneJobSubResource(c *gin.Context, subPath string) {
+	if !checkFineTuneEnabled(c) {
+		return
+	}
+	task, ok := getUserFineTuneTask(c)
+	if !ok {
+		return
+	}
+	status, body, ok := proxyFineTuneJobRequest(c, task, http.MethodGet, subPath)
+	if !ok {
+		return
+	}
+	c.Data(status, "application/json", body)
+}
@@ -174,6 +174,9 @@ func UpdateMidjourneyTaskBulk() {
 					}
 				}
 				won, err := task.UpdateWithStatus(preStatus)
+				if err == nil && won && preStatus != task.Status && (task.Status == "SUCCESS" || task.Status == "FAILURE") {
+					service.PublishMidjourneyFinishedEvent(task)
+				}
 				if err != nil {
 					logger.LogError(ctx, "UpdateMidjourneyTask task error: "+err.Error())
 				} else if won && shouldReturnQuota {
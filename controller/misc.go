@@ -145,6 +145,8 @@ func GetStatus(c *gin.Context) {
 		"star_user_system_enabled":    common.StarUserSystemEnabled && common.StarBackendAddress != "",
 		"star_wechat_enabled":         common.StarWeChatEnabled,
 		"checkin_enabled":             operation_setting.GetCheckinSetting().Enabled,
+		"webhook_event_enabled":       operation_setting.GetWebhookEventSetting().Enabled,
+		"webhook_event_user_enabled":  operation_setting.GetWebhookEventSetting().UserEnabled,
 		"_qn":                         "new-api",
 	}
 
@@ -0,0 +1,487 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/i18n"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ---- Shared types ----
+
+type OrganizationRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type OrganizationMemberRequest struct {
+	Username   string `json:"username"`
+	Role       string `json:"role"`
+	QuotaLimit int    `json:"quota_limit"`
+	// ResetUsedQuota 清零成员已用额度，用于按周期重新分配子额度
+	ResetUsedQuota bool `json:"reset_used_quota"`
+}
+
+type OrganizationTransferRequest struct {
+	Quota int `json:"quota"`
+}
+
+type OrganizationDetail struct {
+	*model.Organization
+	Role             string `json:"role"`
+	MemberQuotaLimit int    `json:"member_quota_limit"`
+	MemberUsedQuota  int    `json:"member_used_quota"`
+}
+
+func validateOrganizationRequest(c *gin.Context, req *OrganizationRequest) bool {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		common.ApiErrorI18n(c, i18n.MsgOrganizationNameEmpty)
+		return false
+	}
+	if len(req.Name) > 64 || len(req.Description) > 255 {
+		common.ApiErrorI18n(c, i18n.MsgOrganizationNameTooLong)
+		return false
+	}
+	return true
+}
+
+// getOrganizationMembership 读取路由中的组织 ID，并校验当前用户是组织成员
+func getOrganizationMembership(c *gin.Context) (*model.Organization, *model.OrganizationMember, bool) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	org, err := model.GetOrganizationById(id)
+	if err != nil {
+		if errors.Is(err, model.ErrOrganizationNotFound) {
+			common.ApiErrorI18n(c, i18n.MsgOrganizationNotFound)
+		} else {
+			common.ApiError(c, err)
+		}
+		return nil, nil, false
+	}
+	member, err := model.GetOrganizationMember(org.Id, c.GetInt("id"))
+	if err != nil {
+		if errors.Is(err, model.ErrOrganizationNotMember) {
+			common.ApiErrorI18n(c, i18n.MsgOrganizationNotFound)
+		} else {
+			common.ApiError(c, err)
+		}
+		return nil, nil, false
+	}
+	return org, member, true
+}
+
+// getOrganizationManager 在 getOrganizationMembership 基础上要求当前用户为所有者或管理员
+func getOrganizationManager(c *gin.Context) (*model.Organization, *model.OrganizationMember, bool) {
+	org, member, ok := getOrganizationMembership(c)
+	if !ok {
+		return nil, nil, false
+	}
+	if !member.CanManage() {
+		common.ApiErrorI18n(c, i18n.MsgOrganizationPermissionDenied)
+		return nil, nil, false
+	}
+	return org, member, true
+}
+
+// ---- User APIs ----
+
+func GetSelfOrganizations(c *gin.Context) {
+	orgs, err := model.GetUserOrganizations(c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, orgs)
+}
+
+func CreateOrganization(c *gin.Context) {
+	var req OrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return
+	}
+	if !validateOrganizationRequest(c, &req) {
+		return
+	}
+	userId := c.GetInt("id")
+	org := &model.Organization{
+		Name:        req.Name,
+		Description: req.Description,
+	}
+	if err := model.CreateOrganization(org, userId); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	model.RecordOrganizationLog(org.Id, userId, model.LogTypeManage, fmt.Sprintf("创建组织 %s", org.Name))
+	common.ApiSuccess(c, org)
+}
+
+func GetOrganization(c *gin.Context) {
+	org, member, ok := getOrganizationMembership(c)
+	if !ok {
+		return
+	}
+	common.ApiSuccess(c, OrganizationDetail{
+		Organization:     org,
+		Role:             member.Role,
+		MemberQuotaLimit: member.QuotaLimit,
+		MemberUsedQuota:  member.UsedQuota,
+	})
+}
+
+func UpdateOrganization(c *gin.Context) {
+	org, _, ok := getOrganizationManager(c)
+	if !ok {
+		return
+	}
+	var req OrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return
+	}
+	if !validateOrganizationRequest(c, &req) {
+		return
+	}
+	org.Name = req.Name
+	org.Description = req.Description
+	if err := org.Update(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, org)
+}
+
+func DeleteOrganization(c *gin.Context) {
+	org, member, ok := getOrganizationMembership(c)
+	if !ok {
+		return
+	}
+	if member.Role != model.OrganizationRoleOwner {
+		common.ApiErrorI18n(c, i18n.MsgOrganizationPermissionDenied)
+		return
+	}
+	// 共享钱包仍有余额时不允许所有者自行解散，避免额度丢失
+	if org.Quota > 0 {
+		common.ApiErrorI18n(c, i18n.MsgOrganizationQuotaNotEmpty)
+		return
+	}
+	if err := model.DeleteOrganization(org.Id); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	model.RecordLog(c.GetInt("id"), model.LogTypeManage, fmt.Sprintf("解散组织 %s", org.Name))
+	common.ApiSuccess(c, nil)
+}
+
+func GetOrganizationMembers(c *gin.Context) {
+	org, _, ok := getOrganizationManager(c)
+	if !ok {
+		return
+	}
+	members, err := model.GetOrganizationMembers(org.Id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, members)
+}
+
+func AddOrganizationMember(c *gin.Context) {
+	org, me, ok := getOrganizationManager(c)
+	if !ok {
+		return
+	}
+	var req OrganizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return
+	}
+	if req.Role == "" {
+		req.Role = model.OrganizationRoleMember
+	}
+	// 所有者只能通过转让产生；管理员只能添加普通成员
+	if !model.IsValidOrganizationRole(req.Role) || req.Role == model.OrganizationRoleOwner {
+		common.ApiErrorI18n(c, i18n.MsgOrganizationInvalidRole)
+		return
+	}
+	if req.Role == model.OrganizationRoleAdmin && me.Role != model.OrganizationRoleOwner {
+		common.ApiErrorI18n(c, i18n.MsgOrganizationPermissionDenied)
+		return
+	}
+	if req.QuotaLimit < 0 {
+		common.ApiErrorI18n(c, i18n.MsgOrganizationQuotaLimitNegative)
+		return
+	}
+	userId, err := model.GetUserIdByUsername(strings.TrimSpace(req.Username))
+	if err != nil || userId == 0 {
+		common.ApiErrorI18n(c, i18n.MsgUserNotExists)
+		return
+	}
+	member := &model.OrganizationMember{
+		OrganizationId: org.Id,
+		UserId:         userId,
+		Role:           req.Role,
+		QuotaLimit:     req.QuotaLimit,
+	}
+	if err := model.AddOrganizationMember(member); err != nil {
+		if errors.Is(err, model.ErrOrganizationMemberExists) {
+			common.ApiErrorI18n(c, i18n.MsgOrganizationMemberExists)
+		} else {
+			common.ApiError(c, err)
+		}
+		return
+	}
+	model.RecordOrganizationLog(org.Id, c.GetInt("id"), model.LogTypeManage,
+		fmt.Sprintf("添加组织成员 %s（角色 %s，额度上限 %s）", req.Username, req.Role, logger.LogQuota(req.QuotaLimit)))
+	common.ApiSuccess(c, member)
+}
+
+func UpdateOrganizationMember(c *gin.Context) {
+	org, me, ok := getOrganizationManager(c)
+	if !ok {
+		return
+	}
+	targetUserId, _ := strconv.Atoi(c.Param("user_id"))
+	target, err := model.GetOrganizationMember(org.Id, targetUserId)
+	if err != nil {
+		common.ApiErrorI18n(c, i18n.MsgOrganizationMemberNotFound)
+		return
+	}
+	if !me.CanManageMember(target) {
+		common.ApiErrorI18n(c, i18n.MsgOrganizationPermissionDenied)
+		return
+	}
+	var req OrganizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return
+	}
+	if req.Role == "" {
+		req.Role = target.Role
+	}
+	if !model.IsValidOrganizationRole(req.Role) {
+		common.ApiErrorI18n(c, i18n.MsgOrganizationInvalidRole)
+		return
+	}
+	if req.QuotaLimit < 0 {
+		common.ApiErrorI18n(c, i18n.MsgOrganizationQuotaLimitNegative)
+		return
+	}
+	if req.Role != target.Role {
+		// 只有所有者可以调整角色；设为所有者即转让所有权
+		if me.Role != model.OrganizationRoleOwner {
+			common.ApiErrorI18n(c, i18n.MsgOrganizationPermissionDenied)
+			return
+		}
+		if req.Role == model.OrganizationRoleOwner {
+			if err := model.TransferOrganizationOwnership(org.Id, me.UserId, target.UserId); err != nil {
+				common.ApiError(c, err)
+				return
+			}
+			model.RecordOrganizationLog(org.Id, me.UserId, model.LogTypeManage,
+				fmt.Sprintf("将组织所有权转让给用户 %d", target.UserId))
+		} else if target.Role == model.OrganizationRoleOwner {
+			// 所有者不能直接降级，需先转让所有权
+			common.ApiErrorI18n(c, i18n.MsgOrganizationInvalidRole)
+			return
+		}
+	}
+	target.Role = req.Role
+	target.QuotaLimit = req.QuotaLimit
+	if err := target.Update(req.ResetUsedQuota); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	model.RecordOrganizationLog(org.Id, me.UserId, model.LogTypeManage,
+		fmt.Sprintf("更新组织成员 %d（角色 %s，额度上限 %s）", target.UserId, req.Role, logger.LogQuota(req.QuotaLimit)))
+	common.ApiSuccess(c, nil)
+}
+
+func RemoveOrganizationMember(c *gin.Context) {
+	org, me, ok := getOrganizationMembership(c)
+	if !ok {
+		return
+	}
+	targetUserId, _ := strconv.Atoi(c.Param("user_id"))
+	target, err := model.GetOrganizationMember(org.Id, targetUserId)
+	if err != nil {
+		common.ApiErrorI18n(c, i18n.MsgOrganizationMemberNotFound)
+		return
+	}
+	if target.Role == model.OrganizationRoleOwner {
+		common.ApiErrorI18n(c, i18n.MsgOrganizationOwnerCannotLeave)
+		return
+	}
+	// 成员可以主动退出；移除他人需要管理权限
+	if target.UserId != me.UserId && !me.CanManageMember(target) {
+		common.ApiErrorI18n(c, i18n.MsgOrganizationPermissionDenied)
+		return
+	}
+	if err := model.RemoveOrganizationMember(org.Id, target.UserId); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	model.RecordOrganizationLog(org.Id, me.UserId, model.LogTypeManage,
+		fmt.Sprintf("移除组织成员 %d", target.UserId))
+	common.ApiSuccess(c, nil)
+}
+
+// TransferQuotaToOrganization 成员将个人钱包额度转入组织共享钱包
+func TransferQuotaToOrganization(c *gin.Context) {
+	org, me, ok := getOrganizationMembership(c)
+	if !ok {
+		return
+	}
+	if org.Status != model.OrganizationStatusEnabled {
+		common.ApiErrorI18n(c, i18n.MsgOrganizationDisabled)
+		return
+	}
+	var req OrganizationTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return
+	}
+	if req.Quota <= 0 {
+		common.ApiErrorI18n(c, i18n.MsgOrganizationTransferInvalid)
+		return
+	}
+	if err := model.TransferUserQuotaToOrganization(me.UserId, org.Id, req.Quota); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	model.RecordOrganizationLog(org.Id, me.UserId, model.LogTypeManage,
+		fmt.Sprintf("向组织 %s 转入额度 %s", org.Name, logger.LogQuota(req.Quota)))
+	common.ApiSuccess(c, nil)
+}
+
+func GetOrganizationLogs(c *gin.Context) {
+	org, _, ok := getOrganizationManager(c)
+	if !ok {
+		return
+	}
+	pageInfo := common.GetPageQuery(c)
+	logType, _ := strconv.Atoi(c.Query("type"))
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	logs, total, err := model.GetOrganizationLogs(org.Id, logType, startTimestamp, endTimestamp,
+		c.Query("model_name"), c.Query("username"), c.Query("token_name"), pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(logs)
+	common.ApiSuccess(c, pageInfo)
+}
+
+func GetOrganizationQuotaData(c *gin.Context) {
+	org, _, ok := getOrganizationManager(c)
+	if !ok {
+		return
+	}
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	// 与个人数据看板一致，时间跨度不超过 1 个月
+	if endTimestamp-startTimestamp > 2592000 {
+		common.ApiErrorMsg(c, "时间跨度不能超过 1 个月")
+		return
+	}
+	dates, err := model.GetQuotaDataByOrganizationId(org.Id, startTimestamp, endTimestamp)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, dates)
+}
+
+// ---- Admin APIs ----
+
+func AdminGetAllOrganizations(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	orgs, total, err := model.GetAllOrganizations(c.Query("keyword"), pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(orgs)
+	common.ApiSuccess(c, pageInfo)
+}
+
+func AdminGetOrganizationMembers(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	members, err := model.GetOrganizationMembers(id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, members)
+}
+
+// AdminManageOrganization 管理员启用/禁用/删除组织或调整组织钱包额度，参数与 ManageUser 一致
+func AdminManageOrganization(c *gin.Context) {
+	var req ManageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return
+	}
+	org, err := model.GetOrganizationById(req.Id)
+	if err != nil {
+		common.ApiErrorI18n(c, i18n.MsgOrganizationNotFound)
+		return
+	}
+	adminId := c.GetInt("id")
+	adminName := c.GetString("username")
+	switch req.Action {
+	case "disable":
+		err = model.UpdateOrganizationStatus(org.Id, model.OrganizationStatusDisabled)
+	case "enable":
+		err = model.UpdateOrganizationStatus(org.Id, model.OrganizationStatusEnabled)
+	case "delete":
+		err = model.DeleteOrganization(org.Id)
+	case "add_quota":
+		switch req.Mode {
+		case "add":
+			if req.Value <= 0 {
+				common.ApiErrorI18n(c, i18n.MsgUserQuotaChangeZero)
+				return
+			}
+			if err = model.IncreaseOrganizationQuota(org.Id, req.Value); err == nil {
+				model.RecordOrganizationLog(org.Id, adminId, model.LogTypeManage,
+					fmt.Sprintf("管理员(%s)增加组织 %s 额度 %s", adminName, org.Name, logger.LogQuota(req.Value)))
+			}
+		case "subtract":
+			if req.Value <= 0 {
+				common.ApiErrorI18n(c, i18n.MsgUserQuotaChangeZero)
+				return
+			}
+			if err = model.IncreaseOrganizationQuota(org.Id, -req.Value); err == nil {
+				model.RecordOrganizationLog(org.Id, adminId, model.LogTypeManage,
+					fmt.Sprintf("管理员(%s)减少组织 %s 额度 %s", adminName, org.Name, logger.LogQuota(req.Value)))
+			}
+		case "override":
+			if err = model.OverrideOrganizationQuota(org.Id, req.Value); err == nil {
+				model.RecordOrganizationLog(org.Id, adminId, model.LogTypeManage,
+					fmt.Sprintf("管理员(%s)覆盖组织 %s 额度从 %s 为 %s", adminName, org.Name, logger.LogQuota(org.Quota), logger.LogQuota(req.Value)))
+			}
+		default:
+			common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+			return
+		}
+	default:
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return
+	}
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
@@ -0,0 +1,32 @@
+package controller
+
+import (
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetPayloadLogs 管理员分页查看载荷记录列表，可按用户、请求 ID 与模型过滤
+func GetPayloadLogs(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	userId := common.String2Int(c.Query("user_id"))
+	payloadLogs, total, err := model.GetPayloadLogs(userId, c.Query("request_id"), c.Query("model_name"), pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(payloadLogs)
+	common.ApiSuccess(c, pageInfo)
+}
+
+// GetPayloadLogsByRequestId 管理员按请求 ID 查看请求/响应载荷，重试时每次尝试各一条
+func GetPayloadLogsByRequestId(c *gin.Context) {
+	payloadLogs, err := model.GetPayloadLogsByRequestId(c.Param("request_id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, payloadLogs)
+}
@@ -64,6 +64,7 @@ func completeWalletTopUpFromVerifyInfo(verifyInfo *epay.VerifyRes) error {
 	}
 
 	model.RecordLog(topUp.UserId, model.LogTypeTopup, fmt.Sprintf("使用在线充值成功，充值金额: %v，支付金额：%f", logger.LogQuota(quotaToAdd), topUp.Money))
+	model.PublishTopupEvent(topUp.UserId, topUp.PaymentMethod, quotaToAdd, topUp.Money, topUp.TradeNo)
 	log.Printf("易支付订单完成成功 %v", topUp)
 	return nil
 }
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/service"
 	"github.com/gin-gonic/gin"
 )
 
@@ -149,6 +150,19 @@ func ClearDiskCache(c *gin.Context) {
 	})
 }
 
+// ClearResponseCache 清空对话补全响应缓存
+func ClearResponseCache(c *gin.Context) {
+	if err := service.PurgeResponseCache(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "响应缓存已清空",
+	})
+}
+
 // ResetPerformanceStats 重置性能统计
 func ResetPerformanceStats(c *gin.Context) {
 	common.ResetDiskCacheStats()
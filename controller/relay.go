@@ -15,6 +15,8 @@ import (
 	"github.com/QuantumNous/new-api/logger"
 	"github.com/QuantumNous/new-api/middleware"
 	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/pkg/metrics"
+	"github.com/QuantumNous/new-api/pkg/tracing"
 	"github.com/QuantumNous/new-api/relay"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
 	relayconstant "github.com/QuantumNous/new-api/relay/constant"
@@ -29,6 +31,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
 func relayHandler(c *gin.Context, info *relaycommon.RelayInfo) *types.NewAPIError {
@@ -123,7 +126,12 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 		return
 	}
 
-	needSensitiveCheck := setting.ShouldCheckPromptSensitive()
+	// 内容审核发起的内部请求不再重复检查
+	contentCheckEnabled := !relaycommon.IsModerationRequest(c.Request.Context())
+	moderationEnabled := contentCheckEnabled && service.IsModerationEnabled()
+	// 内容审核未包含敏感词提供方时保留原有的敏感词检查
+	legacySensitiveCheck := contentCheckEnabled && setting.ShouldCheckPromptSensitive() && !service.IsModerationCheckingSensitiveWords()
+	needSensitiveCheck := legacySensitiveCheck || moderationEnabled
 	needCountToken := constant.CountToken
 	// Avoid building huge CombineText (strings.Join) when token counting and sensitive check are both disabled.
 	var meta *types.TokenCountMeta
@@ -133,7 +141,7 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 		meta = fastTokenCountMetaForPricing(request)
 	}
 
-	if needSensitiveCheck && meta != nil {
+	if legacySensitiveCheck && meta != nil {
 		contains, words := service.CheckSensitiveText(meta.CombineText)
 		if contains {
 			logger.LogWarn(c, fmt.Sprintf("user sensitive words detected: %s", strings.Join(words, ", ")))
@@ -142,7 +150,27 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 		}
 	}
 
+	if moderationEnabled {
+		// 内容审核启用时按配置的提供方审核，脱敏后按新的请求体重新解析
+		redacted, moderationErr := service.ModeratePrompt(c, relayInfo, meta)
+		if moderationErr != nil {
+			newAPIError = moderationErr
+			return
+		}
+		if redacted {
+			request, err = helper.GetAndValidateRequest(c, relayFormat)
+			if err != nil {
+				newAPIError = types.NewError(err, types.ErrorCodeInvalidRequest)
+				return
+			}
+			relayInfo.Request = request
+			meta = request.GetTokenCountMeta()
+		}
+	}
+
+	_, estimateSpan := tracing.StartSpan(c.Request.Context(), "estimate_request_token")
 	tokens, err := service.EstimateRequestToken(c, meta, relayInfo)
+	tracing.EndSpan(estimateSpan, err)
 	if err != nil {
 		newAPIError = types.NewError(err, types.ErrorCodeCountTokenFailed)
 		return
@@ -178,6 +206,15 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 		}
 	}()
 
+	// 审核模型输出，需在写回错误响应前恢复原始的 ResponseWriter
+	if moderationWriter := service.StartCompletionModeration(c, relayInfo); moderationWriter != nil {
+		defer moderationWriter.Finish()
+	}
+
+	// 流式续传：输出中途中断时切换渠道续写，同样需在写回错误响应前恢复 ResponseWriter
+	streamFailover := service.StartStreamFailover(c, relayInfo)
+	defer streamFailover.Restore()
+
 	retryParam := &service.RetryParam{
 		Ctx:        c,
 		TokenGroup: relayInfo.TokenGroup,
@@ -187,7 +224,8 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 	relayInfo.RetryIndex = 0
 	relayInfo.LastError = nil
 
-	for ; retryParam.GetRetry() <= common.RetryTimes; retryParam.IncreaseRetry() {
+	// 每次续传额外增加一次尝试，不占用普通重试次数
+	for ; retryParam.GetRetry() <= common.RetryTimes+streamFailover.Failovers(); retryParam.IncreaseRetry() {
 		relayInfo.RetryIndex = retryParam.GetRetry()
 		channel, channelErr := getChannel(c, relayInfo, retryParam)
 		if channelErr != nil {
@@ -217,15 +255,12 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 		}
 		c.Request.Body = io.NopCloser(bodyStorage)
 
-		switch relayFormat {
-		case types.RelayFormatOpenAIRealtime:
-			newAPIError = relay.WssHelper(c, relayInfo)
-		case types.RelayFormatClaude:
-			newAPIError = relay.ClaudeHelper(c, relayInfo)
-		case types.RelayFormatGemini:
-			newAPIError = geminiRelayHandler(c, relayInfo)
-		default:
-			newAPIError = relayHandler(c, relayInfo)
+		attemptStart := time.Now()
+		newAPIError = relayAttempt(c, relayInfo, relayFormat, channel)
+		// 命中响应缓存时未请求上游，不计入渠道指标与健康度
+		if !relayInfo.ResponseCacheHit {
+			recordRelayMetrics(c, relayInfo, channel, newAPIError, attemptStart)
+			service.RecordChannelOutcome(relayInfo, channel, newAPIError, attemptStart)
 		}
 
 		if newAPIError == nil {
@@ -238,9 +273,10 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 
 		processChannelError(c, *types.NewChannelError(channel.Id, channel.Type, channel.Name, channel.ChannelInfo.IsMultiKey, common.GetContextKeyString(c, constant.ContextKeyChannelKey), channel.GetAutoBan()), newAPIError)
 
-		if !shouldRetry(c, newAPIError, common.RetryTimes-retryParam.GetRetry()) {
+		if !shouldRetry(c, newAPIError, common.RetryTimes+streamFailover.Failovers()-retryParam.GetRetry()) {
 			break
 		}
+		metrics.IncRelayRetry(relayMetricLabels(c, relayInfo, channel))
 	}
 
 	useChannel := c.GetStringSlice("use_channel")
@@ -251,6 +287,10 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 
 	if newAPIError != nil {
 		service.FeishuRelayErrorAlert(relayInfo, newAPIError, useChannel)
+		// 已向客户端输出部分内容时以错误事件结束流并按已产生的用量计费
+		if relay.FinishStreamFailover(c, relayInfo, newAPIError) {
+			newAPIError = nil
+		}
 	}
 }
 
@@ -261,6 +301,72 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
+// relayAttempt 在独立的 span 中执行一次上游转发尝试
+func relayAttempt(c *gin.Context, relayInfo *relaycommon.RelayInfo, relayFormat types.RelayFormat, channel *model.Channel) (newAPIError *types.NewAPIError) {
+	parentCtx := c.Request.Context()
+	ctx, span := tracing.StartSpan(parentCtx, "relay_attempt",
+		tracing.AttrRequestId.String(c.GetString(common.RequestIdKey)),
+		tracing.AttrRetryIndex.Int(relayInfo.RetryIndex),
+		tracing.AttrChannelId.Int(channel.Id),
+		tracing.AttrChannelType.Int(channel.Type),
+		tracing.AttrModel.String(relayInfo.OriginModelName),
+		tracing.AttrGroup.String(common.GetContextKeyString(c, constant.ContextKeyUsingGroup)),
+		tracing.AttrRelayFormat.String(string(relayFormat)),
+		tracing.AttrIsStream.Bool(relayInfo.IsStream),
+	)
+	c.Request = c.Request.WithContext(ctx)
+	relayInfo.TraceContext = ctx
+	releaseChannelUsage := service.TrackChannelUsage(c, channel.Id)
+	defer func() {
+		releaseChannelUsage()
+		c.Request = c.Request.WithContext(parentCtx)
+		relayInfo.TraceContext = parentCtx
+		if newAPIError != nil {
+			span.SetAttributes(semconv.HTTPResponseStatusCode(newAPIError.StatusCode))
+			tracing.EndSpan(span, newAPIError)
+			return
+		}
+		span.End()
+	}()
+
+	switch relayFormat {
+	case types.RelayFormatOpenAIRealtime:
+		return relay.WssHelper(c, relayInfo)
+	case types.RelayFormatClaude:
+		return relay.ClaudeHelper(c, relayInfo)
+	case types.RelayFormatGemini:
+		return geminiRelayHandler(c, relayInfo)
+	default:
+		return relayHandler(c, relayInfo)
+	}
+}
+
+func relayMetricLabels(c *gin.Context, info *relaycommon.RelayInfo, channel *model.Channel) metrics.RelayLabels {
+	return metrics.RelayLabels{
+		ChannelId:   channel.Id,
+		ChannelType: channel.Type,
+		Model:       info.OriginModelName,
+		Group:       common.GetContextKeyString(c, constant.ContextKeyUsingGroup),
+		RelayFormat: string(info.RelayFormat),
+	}
+}
+
+// recordRelayMetrics 记录单次转发尝试的请求数、上游耗时与流式首字耗时
+func recordRelayMetrics(c *gin.Context, info *relaycommon.RelayInfo, channel *model.Channel, apiErr *types.NewAPIError, attemptStart time.Time) {
+	labels := relayMetricLabels(c, info, channel)
+	statusCode := http.StatusOK
+	if apiErr != nil {
+		statusCode = apiErr.StatusCode
+	}
+	metrics.ObserveRelayAttempt(labels, statusCode, time.Since(attemptStart))
+	if apiErr == nil && info.IsStream {
+		// 首字耗时从本次尝试开始计算，不包含之前失败的尝试
+		if ttft, ok := info.FirstResponseSince(attemptStart); ok {
+			metrics.ObserveFirstToken(labels, ttft)
+		}
+	}
+}
+
 func addUsedChannel(c *gin.Context, channelId int) {
 	useChannel := c.GetStringSlice("use_channel")
 	useChannel = append(useChannel, fmt.Sprintf("%d", channelId))
@@ -341,6 +447,10 @@ func shouldRetry(c *gin.Context, openaiErr *types.NewAPIError, retryTimes int) b
 	if types.IsSkipRetryError(openaiErr) {
 		return false
 	}
+	// 是否还能续传已在返回该错误前检查
+	if openaiErr.GetErrorCode() == types.ErrorCodeStreamInterrupted {
+		return true
+	}
 	if retryTimes <= 0 {
 		return false
 	}
@@ -593,6 +703,7 @@ func RelayTask(c *gin.Context) {
 		task.PrivateData.UpstreamTaskID = result.UpstreamTaskID
 		task.PrivateData.BillingSource = relayInfo.BillingSource
 		task.PrivateData.SubscriptionId = relayInfo.SubscriptionId
+		task.PrivateData.OrganizationId = relayInfo.OrganizationId
 		task.PrivateData.TokenId = relayInfo.TokenId
 		task.PrivateData.BillingContext = &model.TaskBillingContext{
 			ModelPrice:      relayInfo.PriceData.ModelPrice,
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/pkg/metrics"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/types"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func scrapeMetrics(t *testing.T) string {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	return recorder.Body.String()
+}
+
+func TestRecordRelayMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	common.SetContextKey(c, constant.ContextKeyUsingGroup, "vip")
+
+	now := time.Now()
+	info := &relaycommon.RelayInfo{
+		OriginModelName:   "gpt-4o-metrics",
+		RelayFormat:       types.RelayFormatOpenAI,
+		IsStream:          true,
+		StartTime:         now.Add(-10 * time.Second),
+		FirstResponseTime: now.Add(-time.Second),
+	}
+	labels := `channel_id="8101",channel_type="1",group="vip",model="gpt-4o-metrics",relay_format="openai"`
+
+	// 首字耗时从本次尝试开始计算，不包含之前失败的尝试
+	recordRelayMetrics(c, info, &model.Channel{Id: 8101, Type: 1}, nil, now.Add(-3*time.Second))
+	body := scrapeMetrics(t)
+	require.Contains(t, body, `newapi_relay_requests_total{`+labels+`,status_code="200"} 1`)
+	require.Contains(t, body, `newapi_relay_upstream_latency_seconds_count{`+labels+`} 1`)
+	require.Contains(t, body, `newapi_relay_time_to_first_token_seconds_count{`+labels+`} 1`)
+	require.Contains(t, body, `newapi_relay_time_to_first_token_seconds_sum{`+labels+`} 2`)
+
+	// 失败的尝试只记录状态码与耗时，不记录首字耗时
+	apiErr := types.NewErrorWithStatusCode(http.ErrHandlerTimeout, types.ErrorCodeBadResponse, http.StatusTooManyRequests)
+	recordRelayMetrics(c, info, &model.Channel{Id: 8101, Type: 1}, apiErr, now.Add(-3*time.Second))
+	// 首个响应早于本次尝试（由之前的尝试发出）时不记录首字耗时
+	recordRelayMetrics(c, info, &model.Channel{Id: 8101, Type: 1}, nil, now)
+	body = scrapeMetrics(t)
+	require.Contains(t, body, `newapi_relay_requests_total{`+labels+`,status_code="429"} 1`)
+	require.Contains(t, body, `newapi_relay_requests_total{`+labels+`,status_code="200"} 2`)
+	require.Contains(t, body, `newapi_relay_upstream_latency_seconds_count{`+labels+`} 3`)
+	require.Contains(t, body, `newapi_relay_time_to_first_token_seconds_count{`+labels+`} 1`)
+}
@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/middleware"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/pkg/tracing"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func setupTracingTest(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	originalProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		_ = provider.Shutdown(t.Context())
+		otel.SetTracerProvider(originalProvider)
+	})
+	return exporter
+}
+
+func spanByName(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	t.Helper()
+	for _, span := range spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	require.Failf(t, "span not found", "name: %s", name)
+	return tracetest.SpanStub{}
+}
+
+func spanAttributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value, len(span.Attributes))
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestRelayAttemptSpanTree(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service.InitHttpClient()
+	exporter := setupTracingTest(t)
+
+	var upstreamTraceparent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamTraceparent = r.Header.Get("traceparent")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"error":{"message":"upstream broken","type":"server_error"}}`))
+	}))
+	defer upstream.Close()
+
+	channel := &model.Channel{Id: 8201, Type: constant.ChannelTypeOpenAI}
+	var relayErr *types.NewAPIError
+	router := gin.New()
+	router.POST("/v1/chat/completions", func(c *gin.Context) {
+		c.Set(common.RequestIdKey, "req-trace-1")
+		c.Next()
+	}, middleware.Tracing(), func(c *gin.Context) {
+		common.SetContextKey(c, constant.ContextKeyUsingGroup, "vip")
+		common.SetContextKey(c, constant.ContextKeyOriginalModel, "gpt-4o-trace")
+		common.SetContextKey(c, constant.ContextKeyChannelId, channel.Id)
+		common.SetContextKey(c, constant.ContextKeyChannelType, channel.Type)
+		common.SetContextKey(c, constant.ContextKeyChannelBaseUrl, upstream.URL)
+		common.SetContextKey(c, constant.ContextKeyChannelKey, "sk-trace")
+		common.SetContextKey(c, constant.ContextKeyChannelSetting, dto.ChannelSettings{PropagateTraceContext: true})
+
+		request := &dto.GeneralOpenAIRequest{
+			Model:    "gpt-4o-trace",
+			Messages: []dto.Message{{Role: "user", Content: "hi"}},
+		}
+		relayInfo, err := relaycommon.GenRelayInfo(c, types.RelayFormatOpenAI, request, nil)
+		require.NoError(t, err)
+		relayInfo.RetryIndex = 1
+		relayErr = relayAttempt(c, relayInfo, types.RelayFormatOpenAI, channel)
+		c.Status(relayErr.StatusCode)
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil))
+	require.NotNil(t, relayErr)
+	require.Equal(t, http.StatusInternalServerError, relayErr.StatusCode)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 3)
+	server := spanByName(t, spans, "POST /v1/chat/completions")
+	attempt := spanByName(t, spans, "relay_attempt")
+	upstreamSpan := spanByName(t, spans, "upstream_request")
+
+	// 根 span -> relay_attempt -> upstream_request，同属一条 trace
+	require.False(t, server.Parent.IsValid())
+	require.Equal(t, trace.SpanKindServer, server.SpanKind)
+	require.Equal(t, server.SpanContext.SpanID(), attempt.Parent.SpanID())
+	require.Equal(t, attempt.SpanContext.SpanID(), upstreamSpan.Parent.SpanID())
+	require.Equal(t, trace.SpanKindClient, upstreamSpan.SpanKind)
+	traceId := server.SpanContext.TraceID()
+	require.Equal(t, traceId, attempt.SpanContext.TraceID())
+	require.Equal(t, traceId, upstreamSpan.SpanContext.TraceID())
+
+	attrs := spanAttributes(attempt)
+	require.Equal(t, "req-trace-1", attrs[tracing.AttrRequestId].AsString())
+	require.Equal(t, int64(1), attrs[tracing.AttrRetryIndex].AsInt64())
+	require.Equal(t, int64(channel.Id), attrs[tracing.AttrChannelId].AsInt64())
+	require.Equal(t, int64(channel.Type), attrs[tracing.AttrChannelType].AsInt64())
+	require.Equal(t, "gpt-4o-trace", attrs[tracing.AttrModel].AsString())
+	require.Equal(t, "vip", attrs[tracing.AttrGroup].AsString())
+	require.Equal(t, string(types.RelayFormatOpenAI), attrs[tracing.AttrRelayFormat].AsString())
+	require.False(t, attrs[tracing.AttrIsStream].AsBool())
+	require.Equal(t, int64(http.StatusInternalServerError), attrs["http.response.status_code"].AsInt64())
+	require.Equal(t, codes.Error, attempt.Status.Code)
+
+	upstreamAttrs := spanAttributes(upstreamSpan)
+	require.Equal(t, int64(channel.Id), upstreamAttrs[tracing.AttrChannelId].AsInt64())
+	require.Equal(t, int64(http.StatusInternalServerError), upstreamAttrs["http.response.status_code"].AsInt64())
+	require.Equal(t, "/v1/chat/completions", upstreamAttrs["url.path"].AsString())
+
+	serverAttrs := spanAttributes(server)
+	require.Equal(t, "req-trace-1", serverAttrs[tracing.AttrRequestId].AsString())
+	require.Equal(t, int64(http.StatusInternalServerError), serverAttrs["http.response.status_code"].AsInt64())
+	require.Equal(t, codes.Error, server.Status.Code)
+
+	// 开启透传后上游收到的 traceparent 指向 upstream_request span
+	require.Equal(t, "00-"+traceId.String()+"-"+upstreamSpan.SpanContext.SpanID().String()+"-01", upstreamTraceparent)
+}
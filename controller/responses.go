@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 以下接口仅能访问网关为桥接渠道保存的响应，原生支持 Responses API 的渠道由上游自行保存
+
+func checkResponsesStoreEnabled(c *gin.Context) bool {
+	if !operation_setting.GetResponsesStoreSetting().Enabled {
+		fileApiError(c, http.StatusNotImplemented, "nice_api_error", "Responses store is not enabled")
+		return false
+	}
+	return true
+}
+
+func getUserResponseRecord(c *gin.Context) (*model.ResponseRecord, bool) {
+	responseId := c.Param("id")
+	record, err := service.GetResponseRecord(responseId, c.GetInt("id"), c.GetInt("token_id"))
+	if err != nil {
+		if errors.Is(err, service.ErrResponseNotFound) {
+			fileApiError(c, http.StatusNotFound, "invalid_request_error", fmt.Sprintf("No response found with id '%s'.", responseId))
+		} else {
+			fileApiError(c, http.StatusInternalServerError, "server_error", err.Error())
+		}
+		return nil, false
+	}
+	return record, true
+}
+
+// RetrieveResponse GET /v1/responses/:id
+func RetrieveResponse(c *gin.Context) {
+	if !checkResponsesStoreEnabled(c) {
+		return
+	}
+	record, ok := getUserResponseRecord(c)
+	if !ok {
+		return
+	}
+	c.Data(http.StatusOK, "application/json", []byte(record.Response))
+}
+
+// DeleteResponse DELETE /v1/responses/:id
+func DeleteResponse(c *gin.Context) {
+	if !checkResponsesStoreEnabled(c) {
+		return
+	}
+	record, ok := getUserResponseRecord(c)
+	if !ok {
+		return
+	}
+	if _, err := service.DeleteResponseRecord(record.ResponseId, record.UserId, record.TokenId); err != nil {
+		fileApiError(c, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"id":      record.ResponseId,
+		"object":  "response",
+		"deleted": true,
+	})
+}
+
+// ListResponseInputItems GET /v1/responses/:id/input_items
+func ListResponseInputItems(c *gin.Context) {
+	if !checkResponsesStoreEnabled(c) {
+		return
+	}
+	record, ok := getUserResponseRecord(c)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	order := c.DefaultQuery("order", "desc")
+	if order != "asc" && order != "desc" {
+		fileApiError(c, http.StatusBadRequest, "invalid_request_error", "Invalid 'order': expected 'asc' or 'desc'")
+		return
+	}
+
+	items, err := service.ResponseConversationInputItems(record)
+	if err != nil {
+		fileApiError(c, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	ids := make([]string, len(items))
+	for i, item := range items {
+		var v struct {
+			Id string `json:"id"`
+		}
+		_ = common.Unmarshal(item, &v)
+		ids[i] = v.Id
+	}
+	if order == "desc" {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+			ids[i], ids[j] = ids[j], ids[i]
+		}
+	}
+
+	start := 0
+	if after := c.Query("after"); after != "" {
+		start = -1
+		for i, id := range ids {
+			if id == after {
+				start = i + 1
+				break
+			}
+		}
+		if start < 0 {
+			fileApiError(c, http.StatusBadRequest, "invalid_request_error", "Invalid 'after' cursor")
+			return
+		}
+	}
+	end := start + limit
+	if end > len(items) {
+		end = len(items)
+	}
+
+	resp := dto.ResponsesInputItemList{
+		Object:  "list",
+		Data:    items[start:end],
+		HasMore: end < len(items),
+	}
+	if len(resp.Data) > 0 {
+		resp.FirstId = ids[start]
+		resp.LastId = ids[end-1]
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -77,6 +77,31 @@ func GetToken(c *gin.Context) {
 	common.ApiSuccess(c, buildMaskedTokenResponse(token))
 }
 
+// GetTokenBudgetUsage 获取令牌当前各预算周期的使用情况
+func GetTokenBudgetUsage(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	userId := c.GetInt("id")
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	token, err := model.GetTokenByIds(id, userId)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	budgets, err := model.GetTokenBudgetStatuses(token)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"token_id":   token.Id,
+		"used_quota": token.UsedQuota,
+		"budgets":    budgets,
+	})
+}
+
 func GetTokenKey(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	userId := c.GetInt("id")
@@ -147,6 +172,12 @@ func GetTokenUsage(c *gin.Context) {
 		expiredAt = 0
 	}
 
+	budgets, err := model.GetTokenBudgetStatuses(token)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"code":    true,
 		"message": "ok",
@@ -160,6 +191,7 @@ func GetTokenUsage(c *gin.Context) {
 			"model_limits":         token.GetModelLimitsMap(),
 			"model_limits_enabled": token.ModelLimitsEnabled,
 			"expires_at":           expiredAt,
+			"budgets":              budgets,
 		},
 	})
 }
@@ -175,6 +207,14 @@ func AddToken(c *gin.Context) {
 		common.ApiErrorI18n(c, i18n.MsgTokenNameTooLong)
 		return
 	}
+	if token.RpmLimit < 0 || token.TpmLimit < 0 || token.MaxInFlight < 0 {
+		common.ApiErrorI18n(c, i18n.MsgTokenUsageLimitNegative)
+		return
+	}
+	if token.DailyQuotaLimit < 0 || token.WeeklyQuotaLimit < 0 || token.MonthlyQuotaLimit < 0 {
+		common.ApiErrorI18n(c, i18n.MsgTokenBudgetNegative)
+		return
+	}
 	// 非无限额度时，检查额度值是否超出有效范围
 	if !token.UnlimitedQuota {
 		if token.RemainQuota < 0 {
@@ -187,6 +227,12 @@ func AddToken(c *gin.Context) {
 			return
 		}
 	}
+	if token.OrganizationId > 0 {
+		if err := model.CheckOrganizationTokenAccess(token.OrganizationId, c.GetInt("id")); err != nil {
+			common.ApiErrorI18n(c, i18n.MsgOrganizationTokenAccessDenied)
+			return
+		}
+	}
 	// 检查用户令牌数量是否已达上限
 	maxTokens := operation_setting.GetMaxUserTokens()
 	count, err := model.CountUserTokens(c.GetInt("id"))
@@ -208,20 +254,28 @@ func AddToken(c *gin.Context) {
 		return
 	}
 	cleanToken := model.Token{
-		UserId:             c.GetInt("id"),
-		Name:               token.Name,
-		Key:                key,
-		CreatedTime:        common.GetTimestamp(),
-		AccessedTime:       common.GetTimestamp(),
-		ExpiredTime:        token.ExpiredTime,
-		RemainQuota:        token.RemainQuota,
-		UnlimitedQuota:     token.UnlimitedQuota,
-		ModelLimitsEnabled: token.ModelLimitsEnabled,
-		ModelLimits:        token.ModelLimits,
-		AllowIps:           token.AllowIps,
-		Group:              token.Group,
-		CrossGroupRetry:    token.CrossGroupRetry,
-		Groups:             token.Groups,
+		UserId:              c.GetInt("id"),
+		Name:                token.Name,
+		Key:                 key,
+		CreatedTime:         common.GetTimestamp(),
+		AccessedTime:        common.GetTimestamp(),
+		ExpiredTime:         token.ExpiredTime,
+		RemainQuota:         token.RemainQuota,
+		UnlimitedQuota:      token.UnlimitedQuota,
+		ModelLimitsEnabled:  token.ModelLimitsEnabled,
+		ModelLimits:         token.ModelLimits,
+		AllowIps:            token.AllowIps,
+		Group:               token.Group,
+		CrossGroupRetry:     token.CrossGroupRetry,
+		Groups:              token.Groups,
+		RpmLimit:            token.RpmLimit,
+		TpmLimit:            token.TpmLimit,
+		MaxInFlight:         token.MaxInFlight,
+		DailyQuotaLimit:     token.DailyQuotaLimit,
+		WeeklyQuotaLimit:    token.WeeklyQuotaLimit,
+		MonthlyQuotaLimit:   token.MonthlyQuotaLimit,
+		BudgetNotifyEnabled: token.BudgetNotifyEnabled,
+		OrganizationId:      token.OrganizationId,
 	}
 	err = cleanToken.Insert()
 	if err != nil {
@@ -261,6 +315,14 @@ func UpdateToken(c *gin.Context) {
 		common.ApiErrorI18n(c, i18n.MsgTokenNameTooLong)
 		return
 	}
+	if token.RpmLimit < 0 || token.TpmLimit < 0 || token.MaxInFlight < 0 {
+		common.ApiErrorI18n(c, i18n.MsgTokenUsageLimitNegative)
+		return
+	}
+	if token.DailyQuotaLimit < 0 || token.WeeklyQuotaLimit < 0 || token.MonthlyQuotaLimit < 0 {
+		common.ApiErrorI18n(c, i18n.MsgTokenBudgetNegative)
+		return
+	}
 	if !token.UnlimitedQuota {
 		if token.RemainQuota < 0 {
 			common.ApiErrorI18n(c, i18n.MsgTokenQuotaNegative)
@@ -301,6 +363,20 @@ func UpdateToken(c *gin.Context) {
 		cleanToken.Group = token.Group
 		cleanToken.CrossGroupRetry = token.CrossGroupRetry
 		cleanToken.Groups = token.Groups
+		cleanToken.RpmLimit = token.RpmLimit
+		cleanToken.TpmLimit = token.TpmLimit
+		cleanToken.MaxInFlight = token.MaxInFlight
+		cleanToken.DailyQuotaLimit = token.DailyQuotaLimit
+		cleanToken.WeeklyQuotaLimit = token.WeeklyQuotaLimit
+		cleanToken.MonthlyQuotaLimit = token.MonthlyQuotaLimit
+		cleanToken.BudgetNotifyEnabled = token.BudgetNotifyEnabled
+		if token.OrganizationId != cleanToken.OrganizationId && token.OrganizationId > 0 {
+			if err := model.CheckOrganizationTokenAccess(token.OrganizationId, userId); err != nil {
+				common.ApiErrorI18n(c, i18n.MsgOrganizationTokenAccessDenied)
+				return
+			}
+		}
+		cleanToken.OrganizationId = token.OrganizationId
 	}
 	err = cleanToken.Update()
 	if err != nil {
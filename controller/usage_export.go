@@ -0,0 +1,250 @@
+package controller
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/i18n"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	usageExportFormatCSV   = "csv"
+	usageExportFormatJSONL = "jsonl"
+)
+
+var logExportHeader = []string{
+	"id", "created_at", "type", "user_id", "username", "token_id", "token_name", "model_name", "channel_id",
+	"group", "quota", "prompt_tokens", "completion_tokens", "use_time", "is_stream", "request_id", "organization_id", "content", "other",
+}
+
+var quotaDataExportHeader = []string{
+	"id", "created_at", "user_id", "username", "model_name", "organization_id", "count", "token_used", "quota",
+}
+
+// usageExportWriter 以 CSV 或 NDJSON 流式写出导出数据，每批写完后立即刷新到客户端
+type usageExportWriter struct {
+	c      *gin.Context
+	format string
+	buf    *bufio.Writer
+	csv    *csv.Writer
+}
+
+func newUsageExportWriter(c *gin.Context, name string) (*usageExportWriter, bool) {
+	format := c.DefaultQuery("format", usageExportFormatCSV)
+	if format == "ndjson" {
+		format = usageExportFormatJSONL
+	}
+	if format != usageExportFormatCSV && format != usageExportFormatJSONL {
+		common.ApiErrorI18n(c, i18n.MsgUsageExportInvalidFormat)
+		return nil, false
+	}
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().Format("20060102150405"), format)
+	contentType := "text/csv; charset=utf-8"
+	if format == usageExportFormatJSONL {
+		contentType = "application/x-ndjson; charset=utf-8"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Header("Cache-Control", "no-cache")
+	c.Status(http.StatusOK)
+	w := &usageExportWriter{c: c, format: format, buf: bufio.NewWriter(c.Writer)}
+	if format == usageExportFormatCSV {
+		// 写入 BOM，便于 Excel 正确识别 UTF-8
+		_, _ = w.buf.WriteString("\xEF\xBB\xBF")
+		w.csv = csv.NewWriter(w.buf)
+	}
+	return w, true
+}
+
+func (w *usageExportWriter) writeHeader(header []string) error {
+	if w.csv == nil {
+		return nil
+	}
+	return w.csv.Write(header)
+}
+
+func (w *usageExportWriter) writeRow(record []string, item any) error {
+	if w.csv != nil {
+		return w.csv.Write(record)
+	}
+	data, err := common.Marshal(item)
+	if err != nil {
+		return err
+	}
+	if _, err = w.buf.Write(data); err != nil {
+		return err
+	}
+	return w.buf.WriteByte('\n')
+}
+
+func (w *usageExportWriter) flush() error {
+	if w.csv != nil {
+		w.csv.Flush()
+		if err := w.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	w.c.Writer.Flush()
+	return nil
+}
+
+func logExportRecord(log *model.Log) []string {
+	return []string{
+		strconv.Itoa(log.Id),
+		time.Unix(log.CreatedAt, 0).Format(time.RFC3339),
+		strconv.Itoa(log.Type),
+		strconv.Itoa(log.UserId),
+		log.Username,
+		strconv.Itoa(log.TokenId),
+		log.TokenName,
+		log.ModelName,
+		strconv.Itoa(log.ChannelId),
+		log.Group,
+		strconv.Itoa(log.Quota),
+		strconv.Itoa(log.PromptTokens),
+		strconv.Itoa(log.CompletionTokens),
+		strconv.Itoa(log.UseTime),
+		strconv.FormatBool(log.IsStream),
+		log.RequestId,
+		strconv.Itoa(log.OrganizationId),
+		log.Content,
+		log.Other,
+	}
+}
+
+func quotaDataExportRecord(data *model.QuotaData) []string {
+	return []string{
+		strconv.Itoa(data.Id),
+		time.Unix(data.CreatedAt, 0).Format(time.RFC3339),
+		strconv.Itoa(data.UserID),
+		data.Username,
+		data.ModelName,
+		strconv.Itoa(data.OrganizationId),
+		strconv.Itoa(data.Count),
+		strconv.Itoa(data.TokenUsed),
+		strconv.Itoa(data.Quota),
+	}
+}
+
+func parseLogExportFilter(c *gin.Context) model.LogExportFilter {
+	filter := model.LogExportFilter{
+		Username:  c.Query("username"),
+		TokenName: c.Query("token_name"),
+		ModelName: c.Query("model_name"),
+		Group:     c.Query("group"),
+	}
+	filter.UserId, _ = strconv.Atoi(c.Query("user_id"))
+	filter.TokenId, _ = strconv.Atoi(c.Query("token_id"))
+	filter.ChannelId, _ = strconv.Atoi(c.Query("channel"))
+	filter.LogType, _ = strconv.Atoi(c.Query("type"))
+	filter.StartTimestamp, _ = strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	filter.EndTimestamp, _ = strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	return filter
+}
+
+func streamLogExport(c *gin.Context, filter model.LogExportFilter, selfOnly bool) {
+	if err := filter.Validate(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	w, ok := newUsageExportWriter(c, "logs")
+	if !ok {
+		return
+	}
+	if err := w.writeHeader(logExportHeader); err != nil {
+		return
+	}
+	export := model.ExportLogs
+	if selfOnly {
+		// 与用户日志接口一致，不向用户暴露渠道与管理员字段，也不暴露真实日志 ID
+		export = model.ExportUserLogs
+	}
+	err := export(filter, func(logs []*model.Log) error {
+		for _, log := range logs {
+			if err := w.writeRow(logExportRecord(log), log); err != nil {
+				return err
+			}
+		}
+		return w.flush()
+	})
+	if err == nil {
+		err = w.flush()
+	}
+	if err != nil {
+		logger.LogError(c, "failed to export logs: "+err.Error())
+	}
+}
+
+func streamQuotaDataExport(c *gin.Context, filter model.QuotaDataExportFilter) {
+	w, ok := newUsageExportWriter(c, "usage")
+	if !ok {
+		return
+	}
+	if err := w.writeHeader(quotaDataExportHeader); err != nil {
+		return
+	}
+	err := model.ExportQuotaData(filter, func(data []*model.QuotaData) error {
+		for _, item := range data {
+			if err := w.writeRow(quotaDataExportRecord(item), item); err != nil {
+				return err
+			}
+		}
+		return w.flush()
+	})
+	if err == nil {
+		err = w.flush()
+	}
+	if err != nil {
+		logger.LogError(c, "failed to export quota data: "+err.Error())
+	}
+}
+
+// ExportAllLogs 管理员按用户、令牌、模型、渠道和分组导出日志
+func ExportAllLogs(c *gin.Context) {
+	streamLogExport(c, parseLogExportFilter(c), false)
+}
+
+// ExportUserLogs 用户导出自己的日志
+func ExportUserLogs(c *gin.Context) {
+	filter := parseLogExportFilter(c)
+	filter.UserId = c.GetInt("id")
+	filter.Username = ""
+	filter.ChannelId = 0
+	streamLogExport(c, filter, true)
+}
+
+func parseQuotaDataExportFilter(c *gin.Context) model.QuotaDataExportFilter {
+	filter := model.QuotaDataExportFilter{
+		Username:  c.Query("username"),
+		ModelName: c.Query("model_name"),
+	}
+	filter.UserId, _ = strconv.Atoi(c.Query("user_id"))
+	filter.StartTimestamp, _ = strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	filter.EndTimestamp, _ = strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	return filter
+}
+
+// ExportAllQuotaData 管理员导出按小时聚合的用量统计
+func ExportAllQuotaData(c *gin.Context) {
+	streamQuotaDataExport(c, parseQuotaDataExportFilter(c))
+}
+
+// ExportUserQuotaData 用户导出自己的用量统计
+func ExportUserQuotaData(c *gin.Context) {
+	filter := parseQuotaDataExportFilter(c)
+	filter.UserId = c.GetInt("id")
+	filter.Username = ""
+	streamQuotaDataExport(c, filter)
+}
@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupUsageExportTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	common.UsingSQLite = true
+	common.UsingMySQL = false
+	common.UsingPostgreSQL = false
+	common.RedisEnabled = false
+
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", strings.ReplaceAll(t.Name(), "/", "_"))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	model.DB = db
+	model.LOG_DB = db
+	require.NoError(t, db.AutoMigrate(&model.Log{}))
+	t.Cleanup(func() {
+		sqlDB, err := db.DB()
+		if err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func exportLogsForTest(t *testing.T, handler gin.HandlerFunc, userId int, query string) (int, []map[string]any) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/log/export?format=jsonl&"+query, nil)
+	c.Set("id", userId)
+	handler(c)
+
+	var rows []map[string]any
+	if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "application/x-ndjson") {
+		return recorder.Code, nil
+	}
+	scanner := bufio.NewScanner(recorder.Body)
+	for scanner.Scan() {
+		var row map[string]any
+		require.NoError(t, common.Unmarshal(scanner.Bytes(), &row))
+		rows = append(rows, row)
+	}
+	return recorder.Code, rows
+}
+
+func TestExportUserLogsUsesUserLogFormatting(t *testing.T) {
+	db := setupUsageExportTestDB(t)
+	logs := []*model.Log{
+		{Id: 11, UserId: 1, Type: model.LogTypeConsume, ModelName: "gpt-4o", ChannelId: 3, Other: `{"admin_info":{"use_channel":[3]},"reject_reason":"x","model_ratio":1}`},
+		{Id: 15, UserId: 2, Type: model.LogTypeConsume, ModelName: "gpt-4o", ChannelId: 3},
+		{Id: 20, UserId: 1, Type: model.LogTypeConsume, ModelName: "gpt-4o-mini", ChannelId: 4},
+		{Id: 27, UserId: 1, Type: model.LogTypeConsume, ModelName: "gptx4o", ChannelId: 4},
+		{Id: 31, UserId: 1, Type: model.LogTypeConsume, ModelName: "claude-3", ChannelId: 5},
+	}
+	require.NoError(t, db.Create(&logs).Error)
+
+	// 用户导出与用户日志接口一致：重新编号、隐藏渠道与管理员字段
+	code, rows := exportLogsForTest(t, ExportUserLogs, 1, "model_name=gpt%25")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, rows, 3)
+	for i, row := range rows {
+		require.EqualValues(t, i+1, row["id"])
+		require.EqualValues(t, 0, row["channel"])
+	}
+	require.Equal(t, []any{"gpt-4o", "gpt-4o-mini", "gptx4o"}, []any{rows[0]["model_name"], rows[1]["model_name"], rows[2]["model_name"]})
+	require.NotContains(t, rows[0]["other"], "admin_info")
+	require.NotContains(t, rows[0]["other"], "reject_reason")
+	require.Contains(t, rows[0]["other"], "model_ratio")
+
+	// 下划线按字面匹配，不作为单字符通配符
+	_, rows = exportLogsForTest(t, ExportUserLogs, 1, "model_name=gpt_4o")
+	require.Empty(t, rows)
+
+	// 管理员导出保留真实 ID 与渠道
+	code, rows = exportLogsForTest(t, ExportAllLogs, 0, "model_name=%25-4o%25")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, rows, 3)
+	require.EqualValues(t, 11, rows[0]["id"])
+	require.EqualValues(t, 3, rows[0]["channel"])
+	require.Contains(t, rows[0]["other"], "admin_info")
+
+	// 非法的模糊模式在写出任何数据前返回错误
+	code, rows = exportLogsForTest(t, ExportUserLogs, 1, "model_name=%25a%25b%25")
+	require.Equal(t, http.StatusOK, code)
+	require.Nil(t, rows)
+}
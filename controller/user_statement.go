@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"errors"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/i18n"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetSelfStatements 获取当前用户的月度账单列表
+func GetSelfStatements(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	statements, total, err := model.GetUserStatements(c.GetInt("id"), pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(statements)
+	common.ApiSuccess(c, pageInfo)
+}
+
+// GetSelfStatement 获取当前用户指定周期的账单明细
+func GetSelfStatement(c *gin.Context) {
+	getStatement(c, c.GetInt("id"), c.Param("period"))
+}
+
+func getStatement(c *gin.Context, userId int, period string) {
+	if _, _, err := model.StatementPeriodRange(period); err != nil {
+		common.ApiErrorI18n(c, i18n.MsgStatementInvalidPeriod)
+		return
+	}
+	statement, err := model.GetUserStatement(userId, period)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			common.ApiErrorI18n(c, i18n.MsgStatementNotFound)
+			return
+		}
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, statement)
+}
+
+// AdminGetStatements 管理员获取账单列表，可按用户过滤
+func AdminGetStatements(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	userId := common.String2Int(c.Query("user_id"))
+	statements, total, err := model.GetUserStatements(userId, pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(statements)
+	common.ApiSuccess(c, pageInfo)
+}
+
+// AdminGetStatement 管理员获取指定用户指定周期的账单明细
+func AdminGetStatement(c *gin.Context) {
+	getStatement(c, common.String2Int(c.Query("user_id")), c.Param("period"))
+}
+
+type generateStatementRequest struct {
+	UserId    int    `json:"user_id"`
+	Period    string `json:"period"`
+	SendEmail bool   `json:"send_email"`
+}
+
+// AdminGenerateStatement 管理员手动生成（或重新生成）用户账单，周期为空时生成上个自然月
+func AdminGenerateStatement(c *gin.Context) {
+	var req generateStatementRequest
+	if err := common.DecodeJson(c.Request.Body, &req); err != nil || req.UserId <= 0 {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return
+	}
+	if req.Period == "" {
+		now := time.Now()
+		req.Period = model.StatementPeriodOf(now.AddDate(0, 0, -now.Day()))
+	}
+	if _, _, err := model.StatementPeriodRange(req.Period); err != nil {
+		common.ApiErrorI18n(c, i18n.MsgStatementInvalidPeriod)
+		return
+	}
+	statement, err := service.GenerateUserStatement(req.UserId, req.Period, req.SendEmail)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, statement)
+}
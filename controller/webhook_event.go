@@ -0,0 +1,267 @@
+package controller
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/i18n"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type WebhookEndpointRequest struct {
+	Name   string   `json:"name"`
+	Url    string   `json:"url"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+	// Enabled 为空时视为启用
+	Enabled  *bool `json:"enabled"`
+	AllUsers bool  `json:"all_users"`
+}
+
+type WebhookEventTypeItem struct {
+	Type      string `json:"type"`
+	AdminOnly bool   `json:"admin_only"`
+}
+
+func isWebhookAdmin(c *gin.Context) bool {
+	return c.GetInt("role") >= common.RoleAdminUser
+}
+
+// checkWebhookAvailable 校验 Webhook 订阅是否开启，普通用户还需开启用户订阅
+func checkWebhookAvailable(c *gin.Context) bool {
+	setting := operation_setting.GetWebhookEventSetting()
+	if !setting.Enabled || (!setting.UserEnabled && !isWebhookAdmin(c)) {
+		common.ApiErrorI18n(c, i18n.MsgWebhookDisabled)
+		return false
+	}
+	return true
+}
+
+func validateWebhookEndpointRequest(c *gin.Context, req *WebhookEndpointRequest) bool {
+	req.Name = strings.TrimSpace(req.Name)
+	req.Url = strings.TrimSpace(req.Url)
+	req.Secret = strings.TrimSpace(req.Secret)
+	if len(req.Name) > 128 {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return false
+	}
+	if parsed, err := url.ParseRequestURI(req.Url); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || len(req.Url) > 1024 {
+		common.ApiErrorI18n(c, i18n.MsgWebhookUrlInvalid)
+		return false
+	}
+	if len(req.Secret) > 128 {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return false
+	}
+	if len(req.Events) == 0 {
+		common.ApiErrorI18n(c, i18n.MsgWebhookEventsEmpty)
+		return false
+	}
+	isAdmin := isWebhookAdmin(c)
+	for _, event := range req.Events {
+		if event == model.WebhookEventAll {
+			continue
+		}
+		if !slices.Contains(model.WebhookEventTypes, event) {
+			common.ApiErrorI18n(c, i18n.MsgWebhookEventInvalid, map[string]any{"Event": event})
+			return false
+		}
+		if !isAdmin && model.IsAdminOnlyWebhookEvent(event) {
+			common.ApiErrorI18n(c, i18n.MsgWebhookEventAdminOnly, map[string]any{"Event": event})
+			return false
+		}
+	}
+	if !isAdmin {
+		req.AllUsers = false
+	}
+	return true
+}
+
+func getWebhookEndpointParam(c *gin.Context) (*model.WebhookEndpoint, bool) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	endpoint, err := model.GetWebhookEndpointById(id, c.GetInt("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			common.ApiErrorI18n(c, i18n.MsgWebhookNotFound)
+		} else {
+			common.ApiError(c, err)
+		}
+		return nil, false
+	}
+	return endpoint, true
+}
+
+// GetWebhookEventTypes 返回当前用户可订阅的事件类型
+func GetWebhookEventTypes(c *gin.Context) {
+	isAdmin := isWebhookAdmin(c)
+	items := make([]WebhookEventTypeItem, 0, len(model.WebhookEventTypes))
+	for _, eventType := range model.WebhookEventTypes {
+		adminOnly := model.IsAdminOnlyWebhookEvent(eventType)
+		if adminOnly && !isAdmin {
+			continue
+		}
+		items = append(items, WebhookEventTypeItem{Type: eventType, AdminOnly: adminOnly})
+	}
+	common.ApiSuccess(c, items)
+}
+
+func GetSelfWebhookEndpoints(c *gin.Context) {
+	endpoints, err := model.GetUserWebhookEndpoints(c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, endpoints)
+}
+
+func CreateWebhookEndpoint(c *gin.Context) {
+	if !checkWebhookAvailable(c) {
+		return
+	}
+	var req WebhookEndpointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return
+	}
+	if !validateWebhookEndpointRequest(c, &req) {
+		return
+	}
+	userId := c.GetInt("id")
+	maxEndpoints := operation_setting.GetWebhookEventSetting().MaxEndpointsPerUser
+	if maxEndpoints > 0 {
+		count, err := model.CountUserWebhookEndpoints(userId)
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		if count >= int64(maxEndpoints) {
+			common.ApiErrorI18n(c, i18n.MsgWebhookEndpointLimit, map[string]any{"Max": maxEndpoints})
+			return
+		}
+	}
+	if req.Secret == "" {
+		req.Secret = "whsec_" + common.GetRandomString(32)
+	}
+	endpoint := &model.WebhookEndpoint{
+		UserId:   userId,
+		Name:     req.Name,
+		Url:      req.Url,
+		Secret:   req.Secret,
+		Enabled:  req.Enabled == nil || *req.Enabled,
+		AllUsers: req.AllUsers,
+	}
+	endpoint.SetEvents(req.Events)
+	if err := endpoint.Insert(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, endpoint)
+}
+
+func UpdateWebhookEndpoint(c *gin.Context) {
+	if !checkWebhookAvailable(c) {
+		return
+	}
+	endpoint, ok := getWebhookEndpointParam(c)
+	if !ok {
+		return
+	}
+	var req WebhookEndpointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiErrorI18n(c, i18n.MsgInvalidParams)
+		return
+	}
+	if !validateWebhookEndpointRequest(c, &req) {
+		return
+	}
+	endpoint.Name = req.Name
+	endpoint.Url = req.Url
+	// 未填写密钥时保留原密钥
+	if req.Secret != "" {
+		endpoint.Secret = req.Secret
+	}
+	if req.Enabled != nil {
+		endpoint.Enabled = *req.Enabled
+	}
+	endpoint.AllUsers = req.AllUsers
+	endpoint.SetEvents(req.Events)
+	if err := endpoint.Update(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, endpoint)
+}
+
+func DeleteWebhookEndpoint(c *gin.Context) {
+	endpoint, ok := getWebhookEndpointParam(c)
+	if !ok {
+		return
+	}
+	if err := model.DeleteWebhookEndpoint(endpoint.Id, endpoint.UserId); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
+
+// TestWebhookEndpoint 立即向端点发送一条测试事件并返回投递结果
+func TestWebhookEndpoint(c *gin.Context) {
+	if !checkWebhookAvailable(c) {
+		return
+	}
+	endpoint, ok := getWebhookEndpointParam(c)
+	if !ok {
+		return
+	}
+	delivery, err := service.SendWebhookTestEvent(endpoint)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, delivery)
+}
+
+func GetSelfWebhookDeliveries(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	endpointId, _ := strconv.Atoi(c.Query("endpoint_id"))
+	deliveries, total, err := model.GetWebhookDeliveries(
+		c.GetInt("id"),
+		endpointId,
+		c.Query("event_type"),
+		c.Query("status"),
+		pageInfo.GetStartIdx(),
+		pageInfo.GetPageSize(),
+	)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(deliveries)
+	common.ApiSuccess(c, pageInfo)
+}
+
+// RetryWebhookDelivery 将失败或等待重试的投递重新放回队列
+func RetryWebhookDelivery(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	delivery, err := model.RetryWebhookDelivery(id, c.GetInt("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			common.ApiErrorI18n(c, i18n.MsgWebhookDeliveryNotFound)
+		} else {
+			common.ApiError(c, err)
+		}
+		return
+	}
+	service.WakeWebhookDelivery()
+	common.ApiSuccess(c, delivery)
+}
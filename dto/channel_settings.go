@@ -7,6 +7,7 @@ type ChannelSettings struct {
 	PassThroughBodyEnabled bool   `json:"pass_through_body_enabled,omitempty"`
 	SystemPrompt           string `json:"system_prompt,omitempty"`
 	SystemPromptOverride   bool   `json:"system_prompt_override,omitempty"`
+	PropagateTraceContext  bool   `json:"propagate_trace_context,omitempty"` // 向上游透传 W3C traceparent
 }
 
 type VertexKeyType string
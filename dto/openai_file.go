@@ -0,0 +1,27 @@
+package dto
+
+// OpenAIFile OpenAI Files API 文件对象
+type OpenAIFile struct {
+	Id        string `json:"id"`
+	Object    string `json:"object"`
+	Bytes     int64  `json:"bytes"`
+	CreatedAt int64  `json:"created_at"`
+	ExpiresAt *int64 `json:"expires_at,omitempty"`
+	Filename  string `json:"filename"`
+	Purpose   string `json:"purpose"`
+	Status    string `json:"status,omitempty"`
+}
+
+type OpenAIFileList struct {
+	Object  string       `json:"object"`
+	Data    []OpenAIFile `json:"data"`
+	FirstId string       `json:"first_id,omitempty"`
+	LastId  string       `json:"last_id,omitempty"`
+	HasMore bool         `json:"has_more"`
+}
+
+type OpenAIFileDeleteResponse struct {
+	Id      string `json:"id"`
+	Object  string `json:"object"`
+	Deleted bool   `json:"deleted"`
+}
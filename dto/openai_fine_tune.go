@@ -0,0 +1,10 @@
+package dto
+
+import "encoding/json"
+
+// OpenAIFineTuneJobList GET /v1/fine_tuning/jobs 的列表响应，任务对象保持上游原始结构
+type OpenAIFineTuneJobList struct {
+	Object  string            `json:"object"`
+	Data    []json.RawMessage `json:"data"`
+	HasMore bool              `json:"has_more"`
+}
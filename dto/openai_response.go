@@ -225,13 +225,14 @@ type Usage struct {
 	TotalTokens          int `json:"total_tokens"`
 	PromptCacheHitTokens int `json:"prompt_cache_hit_tokens,omitempty"`
 
-	PromptTokensDetails    InputTokenDetails  `json:"prompt_tokens_details"`
-	CompletionTokenDetails OutputTokenDetails `json:"completion_tokens_details"`
-	InputTokens            int                `json:"input_tokens"`
-	OutputTokens           int                `json:"output_tokens"`
-	InputTokensDetails     *InputTokenDetails `json:"input_tokens_details"`
-	UsageSemantic          string             `json:"usage_semantic,omitempty"`
-	UsageSource            string             `json:"usage_source,omitempty"`
+	PromptTokensDetails    InputTokenDetails   `json:"prompt_tokens_details"`
+	CompletionTokenDetails OutputTokenDetails  `json:"completion_tokens_details"`
+	InputTokens            int                 `json:"input_tokens"`
+	OutputTokens           int                 `json:"output_tokens"`
+	InputTokensDetails     *InputTokenDetails  `json:"input_tokens_details"`
+	OutputTokensDetails    *OutputTokenDetails `json:"output_tokens_details,omitempty"`
+	UsageSemantic          string              `json:"usage_semantic,omitempty"`
+	UsageSource            string              `json:"usage_source,omitempty"`
 
 	// claude cache 1h
 	ClaudeCacheCreation5mTokens int `json:"claude_cache_creation_5_m_tokens"`
@@ -331,8 +332,18 @@ func (o *OpenAIResponsesResponse) GetSize() string {
 	return ""
 }
 
+// ResponsesInputItemList GET /v1/responses/{id}/input_items 的返回结构
+type ResponsesInputItemList struct {
+	Object  string            `json:"object"`
+	Data    []json.RawMessage `json:"data"`
+	FirstId string            `json:"first_id,omitempty"`
+	LastId  string            `json:"last_id,omitempty"`
+	HasMore bool              `json:"has_more"`
+}
+
 type IncompleteDetails struct {
 	Reasoning string `json:"reasoning"`
+	Reason    string `json:"reason,omitempty"`
 }
 
 type ResponsesOutput struct {
@@ -346,6 +357,8 @@ type ResponsesOutput struct {
 	CallId    string                   `json:"call_id,omitempty"`
 	Name      string                   `json:"name,omitempty"`
 	Arguments string                   `json:"arguments,omitempty"`
+	// reasoning 条目的推理摘要
+	Summary []ResponsesReasoningSummaryPart `json:"summary,omitempty"`
 }
 
 type ResponsesOutputContent struct {
@@ -386,6 +399,12 @@ type ResponsesStreamResponse struct {
 	SummaryIndex *int                           `json:"summary_index,omitempty"`
 	ItemID       string                         `json:"item_id,omitempty"`
 	Part         *ResponsesReasoningSummaryPart `json:"part,omitempty"`
+	// - response.output_text.done
+	// - response.reasoning_summary_text.done
+	Text      string `json:"text,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+	// 事件序号，按事件发出顺序递增
+	SequenceNumber int `json:"sequence_number,omitempty"`
 }
 
 // GetOpenAIError 从动态错误类型中提取OpenAIError结构
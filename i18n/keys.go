@@ -54,6 +54,12 @@ const (
 	MsgTokenExhausted            = "token.exhausted"
 	MsgTokenStatusUnavailable    = "token.status_unavailable"
 	MsgTokenDbError              = "token.db_error"
+	MsgTokenUsageLimitNegative   = "token.usage_limit_negative"
+	MsgTokenBudgetNegative       = "token.budget_negative"
+	MsgTokenBudgetExceeded       = "token.budget_exceeded"
+	MsgTokenBudgetPeriodDaily    = "token.budget_period_daily"
+	MsgTokenBudgetPeriodWeekly   = "token.budget_period_weekly"
+	MsgTokenBudgetPeriodMonthly  = "token.budget_period_monthly"
 )
 
 // Redemption related messages
@@ -139,6 +145,47 @@ const (
 	MsgSubscriptionInvalidUserId    = "subscription.invalid_user_id"
 )
 
+// Organization related messages
+const (
+	MsgOrganizationNotFound           = "organization.not_found"
+	MsgOrganizationDisabled           = "organization.disabled"
+	MsgOrganizationNameEmpty          = "organization.name_empty"
+	MsgOrganizationNameTooLong        = "organization.name_too_long"
+	MsgOrganizationPermissionDenied   = "organization.permission_denied"
+	MsgOrganizationInvalidRole        = "organization.invalid_role"
+	MsgOrganizationMemberExists       = "organization.member_exists"
+	MsgOrganizationMemberNotFound     = "organization.member_not_found"
+	MsgOrganizationOwnerCannotLeave   = "organization.owner_cannot_leave"
+	MsgOrganizationQuotaNotEmpty      = "organization.quota_not_empty"
+	MsgOrganizationQuotaLimitNegative = "organization.quota_limit_negative"
+	MsgOrganizationTransferInvalid    = "organization.transfer_invalid"
+	MsgOrganizationTokenAccessDenied  = "organization.token_access_denied"
+)
+
+// Usage export and statement related messages
+const (
+	MsgUsageExportInvalidFormat = "usage_export.invalid_format"
+	MsgStatementInvalidPeriod   = "statement.invalid_period"
+	MsgStatementNotFound        = "statement.not_found"
+)
+
+// Webhook event subscription messages
+const (
+	MsgWebhookDisabled         = "webhook.disabled"
+	MsgWebhookNotFound         = "webhook.not_found"
+	MsgWebhookUrlInvalid       = "webhook.url_invalid"
+	MsgWebhookEventsEmpty      = "webhook.events_empty"
+	MsgWebhookEventInvalid     = "webhook.event_invalid"
+	MsgWebhookEventAdminOnly   = "webhook.event_admin_only"
+	MsgWebhookEndpointLimit    = "webhook.endpoint_limit"
+	MsgWebhookDeliveryNotFound = "webhook.delivery_not_found"
+)
+
+// Declarative config messages
+const (
+	MsgDeclarativeConfigNotConfigured = "declarative_config.not_configured"
+)
+
 // Payment related messages
 const (
 	MsgPaymentNotConfigured    = "payment.not_configured"
@@ -229,8 +276,11 @@ const (
 
 // Rate limit related messages
 const (
-	MsgRateLimitReached      = "rate_limit.reached"
-	MsgRateLimitTotalReached = "rate_limit.total_reached"
+	MsgRateLimitReached         = "rate_limit.reached"
+	MsgRateLimitTotalReached    = "rate_limit.total_reached"
+	MsgRateLimitRPMReached      = "rate_limit.rpm_reached"
+	MsgRateLimitTPMReached      = "rate_limit.tpm_reached"
+	MsgRateLimitInFlightReached = "rate_limit.in_flight_reached"
 )
 
 // Setting related messages
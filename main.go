@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"log"
@@ -21,6 +22,7 @@ import (
 	"github.com/QuantumNous/new-api/middleware"
 	"github.com/QuantumNous/new-api/model"
 	"github.com/QuantumNous/new-api/oauth"
+	"github.com/QuantumNous/new-api/pkg/tracing"
 	"github.com/QuantumNous/new-api/relay"
 	"github.com/QuantumNous/new-api/router"
 	"github.com/QuantumNous/new-api/service"
@@ -157,6 +159,15 @@ func main() {
 		return
 	}
 
+	if *common.EncryptSecrets {
+		result, err := model.EncryptExistingSecrets()
+		if err != nil {
+			common.FatalLog("failed to encrypt existing secrets: " + err.Error())
+		}
+		common.SysLog(fmt.Sprintf("encrypted existing secrets: %v", result))
+		os.Exit(0)
+	}
+
 	common.SysLog("New API " + common.Version + " started")
 	if os.Getenv("GIN_MODE") != "debug" {
 		gin.SetMode(gin.ReleaseMode)
@@ -220,6 +231,27 @@ func main() {
 	// Subscription quota reset task (daily/weekly/monthly/custom)
 	service.StartSubscriptionQuotaResetTask()
 
+	// Expired file cleanup task (/v1/files)
+	service.StartFileCleanupTask()
+
+	// Monthly user statement task
+	service.StartUserStatementTask()
+
+	// Payload log retention cleanup task
+	service.StartPayloadLogCleanupTask()
+
+	// Webhook event delivery queue task
+	service.StartWebhookDeliveryTask()
+
+	// Multi-key channel key expiry and daily spend cap task
+	service.StartChannelKeyScheduleTask()
+
+	// Audit log retention cleanup task
+	service.StartAuditLogCleanupTask()
+
+	// Expired Responses API response record cleanup task
+	service.StartResponseRecordCleanupTask()
+
 	// Wire task polling adaptor factory (breaks service -> relay import cycle)
 	service.GetTaskAdaptorFunc = func(platform constant.TaskPlatform) service.TaskPollingAdaptor {
 		a := relay.GetTaskAdaptor(platform)
@@ -229,6 +261,15 @@ func main() {
 		return a
 	}
 
+	// Wire token budget notifier (model cannot import service)
+	model.TokenBudgetNotifier = service.NotifyTokenBudget
+
+	// Wire webhook event publisher (model cannot import service)
+	model.WebhookEventPublisher = service.PublishWebhookEvent
+
+	// Declarative config plan/apply on startup and optional file watcher
+	service.StartDeclarativeConfigTask()
+
 	// Channel upstream model update check task
 	controller.StartChannelUpstreamModelUpdateTask()
 
@@ -259,6 +300,13 @@ func main() {
 		common.SysError(fmt.Sprintf("start pyroscope error : %v", err))
 	}
 
+	shutdownTracing, err := tracing.Start(context.Background())
+	if err != nil {
+		common.SysError(fmt.Sprintf("start tracing error : %v", err))
+	} else {
+		defer shutdownTracing(context.Background())
+	}
+
 	// Initialize HTTP server
 	server := gin.New()
 	server.Use(gin.CustomRecovery(func(c *gin.Context, err any) {
@@ -274,6 +322,7 @@ func main() {
 	// This will cause SSE not to work!!!
 	//server.Use(gzip.Gzip(gzip.DefaultCompression))
 	server.Use(middleware.RequestId())
+	server.Use(middleware.Tracing())
 	server.Use(middleware.PoweredBy())
 	server.Use(middleware.I18n())
 	middleware.SetUpLogger(server)
@@ -304,6 +353,12 @@ func main() {
 
 	// 设置路由
 	router.SetRouter(server, getIndexPage, getDocPage)
+
+	// Batch API: 批处理请求通过路由重新分发，完整经过鉴权、分发与计费
+	service.BatchRequestHandler = server.ServeHTTP
+	// 内容审核接口同样通过路由重新分发
+	service.ModerationRequestHandler = server.ServeHTTP
+	service.StartBatchWorker()
 	var port = os.Getenv("PORT")
 	if port == "" {
 		port = strconv.Itoa(*common.Port)
@@ -385,6 +440,13 @@ func InitResources() error {
 		return err
 	}
 
+	// Load secret encryption keys, should before reading channels and options
+	err = model.InitSecretEncryption()
+	if err != nil {
+		common.FatalLog("failed to initialize secret encryption: " + err.Error())
+		return err
+	}
+
 	model.CheckSetup()
 
 	// Initialize options, should after model.InitDB()
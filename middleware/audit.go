@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/QuantumNous/new-api/service"
+	"github.com/gin-gonic/gin"
+)
+
+// AuditLog 记录管理接口的写操作，group 为该路由分组对应的审计目标类型
+func AuditLog(group string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		recorder := service.BeginAudit(c, group)
+		defer recorder.Finish(c)
+		c.Next()
+	}
+}
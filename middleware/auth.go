@@ -13,6 +13,7 @@ import (
 	"github.com/QuantumNous/new-api/i18n"
 	"github.com/QuantumNous/new-api/logger"
 	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
 	"github.com/QuantumNous/new-api/service"
 	"github.com/QuantumNous/new-api/setting/ratio_setting"
 	"github.com/QuantumNous/new-api/types"
@@ -342,6 +343,9 @@ func TokenAuth() func(c *gin.Context) {
 				abortWithOpenAiMessage(c, http.StatusInternalServerError,
 					common.TranslateMessage(c, i18n.MsgDatabaseError))
 			} else {
+				if token != nil && !token.UnlimitedQuota && token.RemainQuota <= 0 {
+					service.PublishTokenExhaustedEvent(token)
+				}
 				abortWithOpenAiMessage(c, http.StatusUnauthorized,
 					common.TranslateMessage(c, i18n.MsgTokenInvalid))
 			}
@@ -364,6 +368,10 @@ func TokenAuth() func(c *gin.Context) {
 			logger.LogDebug(c, "Client IP %s passed the token IP restrictions check", clientIp)
 		}
 
+		if !checkTokenBudget(c, token) {
+			return
+		}
+
 		userCache, err := model.GetUserCache(token.UserId)
 		if err != nil {
 			common.SysLog(fmt.Sprintf("TokenAuth GetUserCache error for user %d: %v", token.UserId, err))
@@ -402,6 +410,11 @@ func TokenAuth() func(c *gin.Context) {
 		if err != nil {
 			return
 		}
+		if group, ok := relaycommon.GetModerationGroup(c.Request.Context()); ok && group != "" {
+			// 内容审核的内部请求在配置的审核分组内选择渠道
+			common.SetContextKey(c, constant.ContextKeyUsingGroup, group)
+			common.SetContextKey(c, constant.ContextKeyTokenGroups, []string{group})
+		}
 		c.Next()
 	}
 }
@@ -425,6 +438,12 @@ func SetupContextForToken(c *gin.Context, token *model.Token, parts ...string) e
 		c.Set("token_model_limit_enabled", false)
 	}
 	common.SetContextKey(c, constant.ContextKeyTokenGroup, token.Group)
+	if token.OrganizationId > 0 {
+		common.SetContextKey(c, constant.ContextKeyTokenOrganizationId, token.OrganizationId)
+	}
+	if limits := token.GetUsageLimits(); limits.Enabled() {
+		common.SetContextKey(c, constant.ContextKeyTokenUsageLimits, limits)
+	}
 	// 多分组令牌默认开启跨分组重试；旧版 "auto" 令牌和无分组令牌（使用系统 autoGroups）也需跨分组重试
 	crossGroupRetry := token.CrossGroupRetry || token.Groups != "" || token.Group == "auto" || token.Group == ""
 	common.SetContextKey(c, constant.ContextKeyTokenCrossGroupRetry, crossGroupRetry)
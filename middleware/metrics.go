@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/QuantumNous/new-api/constant"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MetricsAuth 校验 /metrics 的独立访问令牌，未配置 METRICS_TOKEN 时视为未启用
+func MetricsAuth() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if constant.MetricsToken == "" {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		token := strings.TrimSpace(strings.TrimPrefix(c.Request.Header.Get("Authorization"), "Bearer "))
+		if subtle.ConstantTimeCompare([]byte(token), []byte(constant.MetricsToken)) != 1 {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/i18n"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+func tokenBudgetPeriodName(c *gin.Context, period string) string {
+	switch period {
+	case model.SubscriptionResetDaily:
+		return i18n.T(c, i18n.MsgTokenBudgetPeriodDaily)
+	case model.SubscriptionResetWeekly:
+		return i18n.T(c, i18n.MsgTokenBudgetPeriodWeekly)
+	default:
+		return i18n.T(c, i18n.MsgTokenBudgetPeriodMonthly)
+	}
+}
+
+// checkTokenBudget 检查令牌的每日 / 每周 / 每月预算，已用尽时中止请求并返回 false
+func checkTokenBudget(c *gin.Context, token *model.Token) bool {
+	if !token.HasBudget() {
+		return true
+	}
+	status, err := model.CheckTokenBudget(token)
+	if err != nil {
+		// 预算查询失败时放行，实际消耗仍会计入预算
+		common.SysError("failed to check token budget: " + err.Error())
+	} else if status != nil {
+		abortWithOpenAiMessage(c, http.StatusTooManyRequests, i18n.T(c, i18n.MsgTokenBudgetExceeded, map[string]any{
+			"Period":    tokenBudgetPeriodName(c, status.Period),
+			"ResetTime": time.Unix(status.NextResetTime, 0).Format("2006-01-02 15:04:05"),
+		}), types.ErrorCodeTokenBudgetExceeded)
+		return false
+	}
+	// 保存令牌副本，记录消耗时无需再次查询
+	budgetToken := *token
+	common.SetContextKey(c, constant.ContextKeyTokenBudget, &budgetToken)
+	return true
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/pkg/tracing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Tracing 为每个请求创建根 span，接受客户端传入的 W3C traceparent，需放在 RequestId 之后
+func Tracing() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		ctx, span := tracing.StartServerSpan(c.Request, c.Request.Method+" "+route,
+			semconv.HTTPRequestMethodKey.String(c.Request.Method),
+			semconv.HTTPRoute(route),
+			semconv.URLPath(c.Request.URL.Path),
+			tracing.AttrRequestId.String(c.GetString(common.RequestIdKey)),
+		)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []attribute.KeyValue{semconv.HTTPResponseStatusCode(status)}
+		if userId := c.GetInt("id"); userId != 0 {
+			attrs = append(attrs, tracing.AttrUserId.Int(userId))
+		}
+		if tokenId := c.GetInt("token_id"); tokenId != 0 {
+			attrs = append(attrs, tracing.AttrTokenId.Int(tokenId))
+		}
+		span.SetAttributes(attrs...)
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, fmt.Sprintf("status code %d", status))
+		}
+	}
+}
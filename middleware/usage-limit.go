@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/common/limiter"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/i18n"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+type usageLimitTarget struct {
+	key    string
+	limits limiter.UsageLimits
+}
+
+// usageLimitTargets 收集当前请求需要检查的令牌与用户分组用量限制
+func usageLimitTargets(c *gin.Context) []usageLimitTarget {
+	var targets []usageLimitTarget
+	if limits, ok := common.GetContextKeyType[limiter.UsageLimits](c, constant.ContextKeyTokenUsageLimits); ok {
+		targets = append(targets, usageLimitTarget{key: model.TokenUsageLimitKey(c.GetInt("token_id")), limits: limits})
+	}
+	group := common.GetContextKeyString(c, constant.ContextKeyUsingGroup)
+	if group == "" {
+		group = common.GetContextKeyString(c, constant.ContextKeyUserGroup)
+	}
+	if groupLimit, ok := operation_setting.GetGroupUsageLimit(group); ok {
+		limits := limiter.UsageLimits{
+			RPM:         groupLimit.RPM,
+			TPM:         groupLimit.TPM,
+			MaxInFlight: groupLimit.MaxInFlight,
+		}
+		if limits.Enabled() {
+			targets = append(targets, usageLimitTarget{key: model.UserUsageLimitKey(c.GetInt("id")), limits: limits})
+		}
+	}
+	return targets
+}
+
+func usageExceededMessage(c *gin.Context, exceeded limiter.UsageExceeded, limits limiter.UsageLimits) string {
+	switch exceeded {
+	case limiter.UsageRPMExceeded:
+		return i18n.T(c, i18n.MsgRateLimitRPMReached, map[string]any{"Max": limits.RPM})
+	case limiter.UsageTPMExceeded:
+		return i18n.T(c, i18n.MsgRateLimitTPMReached, map[string]any{"Max": limits.TPM})
+	default:
+		return i18n.T(c, i18n.MsgRateLimitInFlightReached, map[string]any{"Max": limits.MaxInFlight})
+	}
+}
+
+// UsageLimit 令牌与用户分组的 RPM / TPM / 并发数限制
+// TPM 按请求结束后的实际消耗计入，因此并发请求可能短暂超出限制
+func UsageLimit() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		targets := usageLimitTargets(c)
+		if len(targets) == 0 {
+			c.Next()
+			return
+		}
+
+		var releases []func()
+		defer func() {
+			for _, release := range releases {
+				release()
+			}
+		}()
+		var tpmKeys []string
+		for _, target := range targets {
+			release, exceeded := limiter.AcquireUsage(c.Request.Context(), target.key, target.limits)
+			if exceeded != limiter.UsageAllowed {
+				abortWithOpenAiMessage(c, http.StatusTooManyRequests, usageExceededMessage(c, exceeded, target.limits))
+				return
+			}
+			releases = append(releases, release)
+			if target.limits.TPM > 0 {
+				tpmKeys = append(tpmKeys, target.key)
+			}
+		}
+		if len(tpmKeys) > 0 {
+			common.SetContextKey(c, constant.ContextKeyUsageLimitKeys, tpmKeys)
+		}
+		c.Next()
+	}
+}
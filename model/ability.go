@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
 
 	"github.com/samber/lo"
 	"gorm.io/gorm"
@@ -58,12 +59,12 @@ func GetAllEnableAbilities() []Ability {
 	return abilities
 }
 
-func getPriority(group string, model string, retry int) (int, error) {
+func getPriority(group string, model string, retry int, excludedChannelIds []int) (int, error) {
 
 	var priorities []int
-	err := DB.Model(&Ability{}).
+	err := excludeChannels(DB.Model(&Ability{}).
 		Select("DISTINCT(priority)").
-		Where(commonGroupCol+" = ? and model = ? and enabled = ?", group, model, true).
+		Where(commonGroupCol+" = ? and model = ? and enabled = ?", group, model, true), excludedChannelIds).
 		Order("priority DESC").              // 按优先级降序排序
 		Pluck("priority", &priorities).Error // Pluck用于将查询的结果直接扫描到一个切片中
 
@@ -88,11 +89,19 @@ func getPriority(group string, model string, retry int) (int, error) {
 	return priorityToUse, nil
 }
 
-func getChannelQuery(group string, model string, retry int) (*gorm.DB, error) {
-	maxPrioritySubQuery := DB.Model(&Ability{}).Select("MAX(priority)").Where(commonGroupCol+" = ? and model = ? and enabled = ?", group, model, true)
+// excludeChannels 排除指定渠道（例如已超出用量限制的渠道）
+func excludeChannels(query *gorm.DB, channelIds []int) *gorm.DB {
+	if len(channelIds) == 0 {
+		return query
+	}
+	return query.Where("channel_id NOT IN ?", channelIds)
+}
+
+func getChannelQuery(group string, model string, retry int, excludedChannelIds []int) (*gorm.DB, error) {
+	maxPrioritySubQuery := excludeChannels(DB.Model(&Ability{}).Select("MAX(priority)").Where(commonGroupCol+" = ? and model = ? and enabled = ?", group, model, true), excludedChannelIds)
 	channelQuery := DB.Where(commonGroupCol+" = ? and model = ? and enabled = ? and priority = (?)", group, model, true, maxPrioritySubQuery)
 	if retry != 0 {
-		priority, err := getPriority(group, model, retry)
+		priority, err := getPriority(group, model, retry, excludedChannelIds)
 		if err != nil {
 			return nil, err
 		} else {
@@ -100,14 +109,22 @@ func getChannelQuery(group string, model string, retry int) (*gorm.DB, error) {
 		}
 	}
 
-	return channelQuery, nil
+	return excludeChannels(channelQuery, excludedChannelIds), nil
 }
 
 func GetChannel(group string, model string, retry int) (*Channel, error) {
 	var abilities []Ability
 
 	var err error = nil
-	channelQuery, err := getChannelQuery(group, model, retry)
+	// 跳过已超出 RPM / TPM / 并发限制的渠道
+	excludedChannelIds, err := overUsageAbilityChannelIds(group, model)
+	if err != nil {
+		return nil, err
+	}
+	if len(excludedChannelIds) > 0 && !hasAvailableAbility(group, model, excludedChannelIds) {
+		return nil, nil
+	}
+	channelQuery, err := getChannelQuery(group, model, retry, excludedChannelIds)
 	if err != nil {
 		return nil, err
 	}
@@ -120,6 +137,12 @@ func GetChannel(group string, model string, retry int) (*Channel, error) {
 		return nil, err
 	}
 	channel := Channel{}
+	if len(abilities) > 0 && operation_setting.IsAdaptiveSelectionEnabled(group, model) {
+		if ability := pickAdaptiveAbility(abilities); ability != nil {
+			err = DB.First(&channel, "id = ?", ability.ChannelId).Error
+			return &channel, err
+		}
+	}
 	if len(abilities) > 0 {
 		// Randomly choose one
 		weightSum := uint(0)
@@ -0,0 +1,114 @@
+package model
+
+import (
+	"context"
+	"errors"
+)
+
+// AuditLog 管理操作审计记录，保存操作人、来源、目标及变更前后的快照（敏感字段已脱敏）
+type AuditLog struct {
+	Id        int    `json:"id"`
+	CreatedAt int64  `json:"created_at" gorm:"bigint;index"`
+	ActorId   int    `json:"actor_id" gorm:"index"`
+	ActorName string `json:"actor_name" gorm:"type:varchar(64);default:''"`
+	ActorRole int    `json:"actor_role"`
+	Ip        string `json:"ip" gorm:"type:varchar(64);default:''"`
+	RequestId string `json:"request_id" gorm:"type:varchar(64);index;default:''"`
+	// Action 请求方法与路由，如 PUT /api/channel/
+	Action     string `json:"action" gorm:"type:varchar(255);index"`
+	TargetType string `json:"target_type" gorm:"type:varchar(32);index:idx_audit_log_target,priority:1"`
+	TargetId   string `json:"target_id" gorm:"type:varchar(255);index:idx_audit_log_target,priority:2;default:''"`
+	// Before/After 变更前后的目标快照；无法确定单个目标时 After 为脱敏后的请求体
+	Before string `json:"before" gorm:"type:text"`
+	After  string `json:"after" gorm:"type:text"`
+	// Diff 字段级差异，JSON 数组
+	Diff    string `json:"diff" gorm:"type:text"`
+	Success bool   `json:"success"`
+	Message string `json:"message" gorm:"type:text"`
+}
+
+// AuditLogFilter 审计记录查询条件，零值表示不过滤
+type AuditLogFilter struct {
+	ActorId        int
+	ActorName      string
+	Action         string
+	TargetType     string
+	TargetId       string
+	RequestId      string
+	StartTimestamp int64
+	EndTimestamp   int64
+}
+
+func (log *AuditLog) Insert() error {
+	return DB.Create(log).Error
+}
+
+// GetAuditLogs 分页查询审计记录，Action 按前缀匹配
+func GetAuditLogs(filter AuditLogFilter, startIdx int, num int) (logs []*AuditLog, total int64, err error) {
+	tx := DB.Model(&AuditLog{})
+	if filter.ActorId != 0 {
+		tx = tx.Where("actor_id = ?", filter.ActorId)
+	}
+	if filter.ActorName != "" {
+		tx = tx.Where("actor_name = ?", filter.ActorName)
+	}
+	if filter.Action != "" {
+		tx = tx.Where("action LIKE ?", filter.Action+"%")
+	}
+	if filter.TargetType != "" {
+		tx = tx.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetId != "" {
+		tx = tx.Where("target_id = ?", filter.TargetId)
+	}
+	if filter.RequestId != "" {
+		tx = tx.Where("request_id = ?", filter.RequestId)
+	}
+	if filter.StartTimestamp != 0 {
+		tx = tx.Where("created_at >= ?", filter.StartTimestamp)
+	}
+	if filter.EndTimestamp != 0 {
+		tx = tx.Where("created_at <= ?", filter.EndTimestamp)
+	}
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Order("id desc").Limit(num).Offset(startIdx).Find(&logs).Error
+	return logs, total, err
+}
+
+func GetAuditLogById(id int) (*AuditLog, error) {
+	if id == 0 {
+		return nil, errors.New("id 为空！")
+	}
+	log := &AuditLog{}
+	err := DB.First(log, "id = ?", id).Error
+	return log, err
+}
+
+// DeleteOldAuditLogs 分批删除早于指定时间的审计记录
+func DeleteOldAuditLogs(ctx context.Context, targetTimestamp int64, limit int) (int64, error) {
+	var total int64 = 0
+	for {
+		if nil != ctx.Err() {
+			return total, ctx.Err()
+		}
+		var ids []int
+		err := DB.Model(&AuditLog{}).Where("created_at < ?", targetTimestamp).Limit(limit).Pluck("id", &ids).Error
+		if err != nil {
+			return total, err
+		}
+		if len(ids) == 0 {
+			break
+		}
+		result := DB.Where("id IN ?", ids).Delete(&AuditLog{})
+		if nil != result.Error {
+			return total, result.Error
+		}
+		total += result.RowsAffected
+		if len(ids) < limit {
+			break
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,185 @@
+package model
+
+import (
+	"errors"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"gorm.io/gorm"
+)
+
+// 批次状态，与 OpenAI Batch API 保持一致
+const (
+	BatchStatusValidating = "validating"
+	BatchStatusFailed     = "failed"
+	BatchStatusInProgress = "in_progress"
+	BatchStatusFinalizing = "finalizing"
+	BatchStatusCompleted  = "completed"
+	BatchStatusExpired    = "expired"
+	BatchStatusCancelling = "cancelling"
+	BatchStatusCancelled  = "cancelled"
+)
+
+// Batch OpenAI Batch API 批次
+type Batch struct {
+	Id               int    `json:"-" gorm:"primaryKey"`
+	BatchId          string `json:"batch_id" gorm:"type:varchar(128);uniqueIndex"`
+	UserId           int    `json:"user_id" gorm:"index"`
+	Username         string `json:"username,omitempty" gorm:"-:all"`
+	TokenId          int    `json:"-" gorm:"index"`
+	ClientIp         string `json:"-" gorm:"type:varchar(64)"`
+	Endpoint         string `json:"endpoint" gorm:"type:varchar(64)"`
+	InputFileId      string `json:"input_file_id" gorm:"type:varchar(128)"`
+	OutputFileId     string `json:"output_file_id" gorm:"type:varchar(128)"`
+	ErrorFileId      string `json:"error_file_id" gorm:"type:varchar(128)"`
+	CompletionWindow string `json:"completion_window" gorm:"type:varchar(16)"`
+	Status           string `json:"status" gorm:"type:varchar(20);index"`
+	Errors           string `json:"errors" gorm:"type:text"`   // 批次级错误（JSON 数组）
+	Metadata         string `json:"metadata" gorm:"type:text"` // 用户自定义 metadata（JSON 对象）
+	TotalCount       int    `json:"total_count"`
+	CompletedCount   int    `json:"completed_count"`
+	FailedCount      int    `json:"failed_count"`
+	Quota            int    `json:"quota"` // 批次已消耗额度
+	CreatedAt        int64  `json:"created_at" gorm:"bigint;index"`
+	InProgressAt     int64  `json:"in_progress_at" gorm:"bigint"`
+	FinalizingAt     int64  `json:"finalizing_at" gorm:"bigint"`
+	CompletedAt      int64  `json:"completed_at" gorm:"bigint"`
+	FailedAt         int64  `json:"failed_at" gorm:"bigint"`
+	ExpiredAt        int64  `json:"expired_at" gorm:"bigint"`
+	CancellingAt     int64  `json:"cancelling_at" gorm:"bigint"`
+	CancelledAt      int64  `json:"cancelled_at" gorm:"bigint"`
+	ExpiresAt        int64  `json:"expires_at" gorm:"bigint"`
+}
+
+// GenerateBatchId 生成 batch_xxxx 格式的批次 ID
+func GenerateBatchId() string {
+	key, _ := common.GenerateRandomCharsKey(24)
+	return "batch_" + key
+}
+
+// IsFinished 批次是否已处于终态
+func (b *Batch) IsFinished() bool {
+	switch b.Status {
+	case BatchStatusCompleted, BatchStatusFailed, BatchStatusExpired, BatchStatusCancelled:
+		return true
+	}
+	return false
+}
+
+func (b *Batch) Insert() error {
+	if b.CreatedAt == 0 {
+		b.CreatedAt = common.GetTimestamp()
+	}
+	return DB.Create(b).Error
+}
+
+func (b *Batch) Update() error {
+	return DB.Save(b).Error
+}
+
+// UpdateBatchProgress 更新批次进度计数
+func UpdateBatchProgress(id int, completed int, failed int) error {
+	return DB.Model(&Batch{}).Where("id = ?", id).Updates(map[string]any{
+		"completed_count": completed,
+		"failed_count":    failed,
+	}).Error
+}
+
+// UpdateBatchStatusFrom 仅当批次处于 fromStatus 时更新状态，用于避免并发覆盖（如取消）
+func UpdateBatchStatusFrom(id int, fromStatus string, updates map[string]any) (bool, error) {
+	result := DB.Model(&Batch{}).Where("id = ? AND status = ?", id, fromStatus).Updates(updates)
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetBatchStatus 读取批次最新状态
+func GetBatchStatus(id int) (string, error) {
+	var status string
+	err := DB.Model(&Batch{}).Where("id = ?", id).Select("status").Scan(&status).Error
+	return status, err
+}
+
+// GetUserBatchById 获取用户自己的批次
+func GetUserBatchById(userId int, batchId string) (*Batch, error) {
+	if batchId == "" {
+		return nil, errors.New("batch id is empty")
+	}
+	var batch Batch
+	err := DB.Where("batch_id = ? AND user_id = ?", batchId, userId).First(&batch).Error
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// ListUserBatches 按 OpenAI 的游标分页方式列出用户批次（按创建时间倒序）
+func ListUserBatches(userId int, after string, limit int) (batches []*Batch, hasMore bool, err error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	query := DB.Where("user_id = ?", userId)
+	if after != "" {
+		cursor, err := GetUserBatchById(userId, after)
+		if err != nil {
+			return nil, false, err
+		}
+		query = query.Where("id < ?", cursor.Id)
+	}
+	err = query.Order("id desc").Limit(limit + 1).Find(&batches).Error
+	if err != nil {
+		return nil, false, err
+	}
+	if len(batches) > limit {
+		batches = batches[:limit]
+		hasMore = true
+	}
+	return batches, hasMore, nil
+}
+
+// GetPendingBatches 获取待处理的批次（validating / in_progress / cancelling）
+func GetPendingBatches(limit int) ([]*Batch, error) {
+	var batches []*Batch
+	err := DB.Where("status IN ?", []string{BatchStatusValidating, BatchStatusInProgress, BatchStatusCancelling}).
+		Order("id asc").Limit(limit).Find(&batches).Error
+	return batches, err
+}
+
+type BatchQueryParams struct {
+	BatchId        string
+	UserId         string
+	Status         string
+	StartTimestamp int64
+	EndTimestamp   int64
+}
+
+func buildBatchQuery(userId int, queryParams BatchQueryParams) *gorm.DB {
+	query := DB.Model(&Batch{})
+	if userId > 0 {
+		query = query.Where("user_id = ?", userId)
+	} else if queryParams.UserId != "" {
+		query = query.Where("user_id = ?", queryParams.UserId)
+	}
+	if queryParams.BatchId != "" {
+		query = query.Where("batch_id = ?", queryParams.BatchId)
+	}
+	if queryParams.Status != "" {
+		query = query.Where("status = ?", queryParams.Status)
+	}
+	if queryParams.StartTimestamp != 0 {
+		query = query.Where("created_at >= ?", queryParams.StartTimestamp)
+	}
+	if queryParams.EndTimestamp != 0 {
+		query = query.Where("created_at <= ?", queryParams.EndTimestamp)
+	}
+	return query
+}
+
+// GetAllBatches 控制台分页查询批次，userId 为 0 时查询所有用户
+func GetAllBatches(userId int, startIdx int, num int, queryParams BatchQueryParams) ([]*Batch, int64, error) {
+	var batches []*Batch
+	var total int64
+	if err := buildBatchQuery(userId, queryParams).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := buildBatchQuery(userId, queryParams).Order("id desc").Limit(num).Offset(startIdx).Find(&batches).Error
+	return batches, total, err
+}
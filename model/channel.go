@@ -21,7 +21,8 @@ import (
 type Channel struct {
 	Id                 int     `json:"id"`
 	Type               int     `json:"type" gorm:"default:0"`
-	Key                string  `json:"key" gorm:"not null"`
+	Key                string  `json:"key" gorm:"not null;serializer:secret"`
+	KeyHash            string  `json:"-" gorm:"type:varchar(64);index;default:''"` // 密钥的 HMAC，加密后按密钥检索使用
 	OpenAIOrganization *string `json:"openai_organization"`
 	TestModel          *string `json:"test_model"`
 	Status             int     `json:"status" gorm:"default:1"`
@@ -48,6 +49,9 @@ type Channel struct {
 	ParamOverride     *string `json:"param_override" gorm:"type:text"`
 	HeaderOverride    *string `json:"header_override" gorm:"type:text"`
 	Remark            *string `json:"remark" gorm:"type:varchar(255)" validate:"max=255"`
+	RpmLimit          *int    `json:"rpm_limit" gorm:"default:0"`     // 每分钟请求数限制，0 表示不限制
+	TpmLimit          *int    `json:"tpm_limit" gorm:"default:0"`     // 每分钟 token 数限制，超出后选择渠道时跳过
+	MaxInFlight       *int    `json:"max_in_flight" gorm:"default:0"` // 最大并发请求数，0 表示不限制
 	// add after v0.8.5
 	ChannelInfo ChannelInfo `json:"channel_info" gorm:"type:json"`
 
@@ -65,6 +69,50 @@ type ChannelInfo struct {
 	MultiKeyDisabledTime   map[int]int64         `json:"multi_key_disabled_time,omitempty"`   // key禁用时间列表，key index -> time
 	MultiKeyPollingIndex   int                   `json:"multi_key_polling_index"`             // 多Key模式下轮询的key索引
 	MultiKeyMode           constant.MultiKeyMode `json:"multi_key_mode"`
+	MultiKeyMeta           map[int]MultiKeyMeta  `json:"multi_key_meta,omitempty"` // key元数据，key index -> meta
+}
+
+// MultiKeyMeta 多Key模式下单个key的元数据
+type MultiKeyMeta struct {
+	ExpiresAt     int64  `json:"expires_at,omitempty"`      // 过期时间，0 表示不过期
+	DailySpendCap int64  `json:"daily_spend_cap,omitempty"` // 每日消耗额度上限，0 表示不限制
+	Note          string `json:"note,omitempty"`
+	// ExpiryNotified 已发送临期提醒对应的过期时间，修改过期时间后会重新提醒
+	ExpiryNotified int64 `json:"expiry_notified,omitempty"`
+}
+
+// IsZero 元数据为空时不再保存
+func (meta MultiKeyMeta) IsZero() bool {
+	return meta == MultiKeyMeta{}
+}
+
+// GetMultiKeyMeta 获取key元数据，不存在时返回零值
+func (c *ChannelInfo) GetMultiKeyMeta(idx int) MultiKeyMeta {
+	if c.MultiKeyMeta == nil {
+		return MultiKeyMeta{}
+	}
+	return c.MultiKeyMeta[idx]
+}
+
+// SetMultiKeyMeta 设置key元数据，零值时删除
+func (c *ChannelInfo) SetMultiKeyMeta(idx int, meta MultiKeyMeta) {
+	if meta.IsZero() {
+		delete(c.MultiKeyMeta, idx)
+		return
+	}
+	if c.MultiKeyMeta == nil {
+		c.MultiKeyMeta = make(map[int]MultiKeyMeta)
+	}
+	c.MultiKeyMeta[idx] = meta
+}
+
+func (c *ChannelInfo) hasDailySpendCap() bool {
+	for _, meta := range c.MultiKeyMeta {
+		if meta.DailySpendCap > 0 {
+			return true
+		}
+	}
+	return false
 }
 
 // Value implements driver.Valuer interface
@@ -144,8 +192,24 @@ func (channel *Channel) GetNextEnabledKey() (string, int, *types.NewAPIError) {
 	if len(enabledIdx) == 0 {
 		return "", 0, types.NewError(errors.New("no enabled keys"), types.ErrorCodeChannelNoAvailableKey)
 	}
+	enabledIdx = filterOpenCircuitKeys(channel.Id, enabledIdx)
+
+	mode := channel.ChannelInfo.MultiKeyMode
+	if mode == constant.MultiKeyModeLeastRecentlyUsed || mode == constant.MultiKeyModeLeastSpent || channel.ChannelInfo.hasDailySpendCap() {
+		usage := getChannelKeyUsage(channel.Id, keys, enabledIdx)
+		// 排除当日消耗已达上限、但尚未被自动禁用的key
+		enabledIdx = filterCappedKeys(&channel.ChannelInfo, usage, enabledIdx)
+		if len(enabledIdx) == 0 {
+			return "", 0, types.NewError(errors.New("no enabled keys"), types.ErrorCodeChannelNoAvailableKey)
+		}
+		if mode == constant.MultiKeyModeLeastRecentlyUsed || mode == constant.MultiKeyModeLeastSpent {
+			selectedIdx := pickLeastUsedKey(mode, usage, enabledIdx)
+			touchChannelKeyUsage(channel.Id, keys[selectedIdx])
+			return keys[selectedIdx], selectedIdx, nil
+		}
+	}
 
-	switch channel.ChannelInfo.MultiKeyMode {
+	switch mode {
 	case constant.MultiKeyModeRandom:
 		// Randomly pick one enabled key
 		selectedIdx := enabledIdx[rand.Intn(len(enabledIdx))]
@@ -249,6 +313,22 @@ func (channel *Channel) GetAutoBan() bool {
 	return *channel.AutoBan == 1
 }
 
+// BeforeSave 写入密钥时同步更新检索哈希，密文带随机 nonce 无法直接按密钥匹配
+func (channel *Channel) BeforeSave(tx *gorm.DB) error {
+	if channel.Key != "" {
+		tx.Statement.SetColumn("KeyHash", ChannelKeyHash(channel.Key))
+	}
+	return nil
+}
+
+// channelKeySearchCondition 按完整密钥检索：未加密的渠道直接比较明文，已加密的渠道比较检索哈希
+func channelKeySearchCondition(keyword string) (string, []interface{}) {
+	if hash := ChannelKeyHash(keyword); hash != "" {
+		return commonKeyCol + " = ? OR key_hash = ?", []interface{}{keyword, hash}
+	}
+	return commonKeyCol + " = ?", []interface{}{keyword}
+}
+
 func (channel *Channel) Save() error {
 	return DB.Save(channel).Error
 }
@@ -313,6 +393,7 @@ func SearchChannels(keyword string, group string, model string, idSort bool) ([]
 	baseQuery := DB.Model(&Channel{}).Omit("key")
 
 	// 构造WHERE子句
+	keyCondition, keyArgs := channelKeySearchCondition(keyword)
 	var whereClause string
 	var args []interface{}
 	if group != "" && group != "null" {
@@ -323,11 +404,15 @@ func SearchChannels(keyword string, group string, model string, idSort bool) ([]
 			// sqlite, PostgreSQL
 			groupCondition = `(',' || ` + commonGroupCol + ` || ',') LIKE ?`
 		}
-		whereClause = "(id = ? OR name LIKE ? OR " + commonKeyCol + " = ? OR " + baseURLCol + " LIKE ?) AND " + modelsCol + ` LIKE ? AND ` + groupCondition
-		args = append(args, common.String2Int(keyword), "%"+keyword+"%", keyword, "%"+keyword+"%", "%"+model+"%", "%,"+group+",%")
+		whereClause = "(id = ? OR name LIKE ? OR " + keyCondition + " OR " + baseURLCol + " LIKE ?) AND " + modelsCol + ` LIKE ? AND ` + groupCondition
+		args = append(args, common.String2Int(keyword), "%"+keyword+"%")
+		args = append(args, keyArgs...)
+		args = append(args, "%"+keyword+"%", "%"+model+"%", "%,"+group+",%")
 	} else {
-		whereClause = "(id = ? OR name LIKE ? OR " + commonKeyCol + " = ? OR " + baseURLCol + " LIKE ?) AND " + modelsCol + " LIKE ?"
-		args = append(args, common.String2Int(keyword), "%"+keyword+"%", keyword, "%"+keyword+"%", "%"+model+"%")
+		whereClause = "(id = ? OR name LIKE ? OR " + keyCondition + " OR " + baseURLCol + " LIKE ?) AND " + modelsCol + " LIKE ?"
+		args = append(args, common.String2Int(keyword), "%"+keyword+"%")
+		args = append(args, keyArgs...)
+		args = append(args, "%"+keyword+"%", "%"+model+"%")
 	}
 
 	// 执行查询
@@ -493,6 +578,11 @@ func (channel *Channel) Update() error {
 				}
 			}
 		}
+		for idx := range channel.ChannelInfo.MultiKeyMeta {
+			if idx >= channel.ChannelInfo.MultiKeySize {
+				delete(channel.ChannelInfo.MultiKeyMeta, idx)
+			}
+		}
 	}
 	var err error
 	err = DB.Model(channel).Updates(channel).Error
@@ -504,6 +594,28 @@ func (channel *Channel) Update() error {
 	return err
 }
 
+// UpdateFields 仅更新指定列并重建能力表，避免覆盖已用额度、余额等运行时维护的字段
+func (channel *Channel) UpdateFields(columns []string) error {
+	if channel.Id == 0 {
+		return errors.New("channel ID is 0")
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+	if lo.Contains(columns, "key") && !lo.Contains(columns, "key_hash") {
+		columns = append(columns[:len(columns):len(columns)], "key_hash")
+	}
+	err := DB.Model(channel).Select(columns).Updates(channel).Error
+	if err != nil {
+		return err
+	}
+	err = DB.First(channel, "id = ?", channel.Id).Error
+	if err != nil {
+		return err
+	}
+	return channel.UpdateAbilities(nil)
+}
+
 func (channel *Channel) UpdateResponseTime(responseTime int64) {
 	err := DB.Model(channel).Select("response_time", "test_time").Updates(Channel{
 		TestTime:     common.GetTimestamp(),
@@ -768,6 +880,24 @@ func updateChannelUsedQuota(id int, quota int) {
 	}
 }
 
+// AddChannelModel 向渠道的模型列表追加模型并同步 abilities，模型已存在时返回 false
+func AddChannelModel(id int, modelName string) (bool, error) {
+	channel, err := GetChannelById(id, true)
+	if err != nil {
+		return false, err
+	}
+	models := channel.GetModels()
+	if common.StringsContains(models, modelName) {
+		return false, nil
+	}
+	channel.Models = strings.Join(append(models, modelName), ",")
+	err = DB.Model(&Channel{}).Where("id = ?", id).Update("models", channel.Models).Error
+	if err != nil {
+		return false, err
+	}
+	return true, channel.UpdateAbilities(nil)
+}
+
 func DeleteChannelByStatus(status int64) (int64, error) {
 	result := DB.Where("status = ?", status).Delete(&Channel{})
 	return result.RowsAffected, result.Error
@@ -808,6 +938,7 @@ func SearchTags(keyword string, group string, model string, idSort bool) ([]*str
 	baseQuery := DB.Model(&Channel{}).Omit("key")
 
 	// 构造WHERE子句
+	keyCondition, keyArgs := channelKeySearchCondition(keyword)
 	var whereClause string
 	var args []interface{}
 	if group != "" && group != "null" {
@@ -818,11 +949,15 @@ func SearchTags(keyword string, group string, model string, idSort bool) ([]*str
 			// sqlite, PostgreSQL
 			groupCondition = `(',' || ` + commonGroupCol + ` || ',') LIKE ?`
 		}
-		whereClause = "(id = ? OR name LIKE ? OR " + commonKeyCol + " = ? OR " + baseURLCol + " LIKE ?) AND " + modelsCol + ` LIKE ? AND ` + groupCondition
-		args = append(args, common.String2Int(keyword), "%"+keyword+"%", keyword, "%"+keyword+"%", "%"+model+"%", "%,"+group+",%")
+		whereClause = "(id = ? OR name LIKE ? OR " + keyCondition + " OR " + baseURLCol + " LIKE ?) AND " + modelsCol + ` LIKE ? AND ` + groupCondition
+		args = append(args, common.String2Int(keyword), "%"+keyword+"%")
+		args = append(args, keyArgs...)
+		args = append(args, "%"+keyword+"%", "%"+model+"%", "%,"+group+",%")
 	} else {
-		whereClause = "(id = ? OR name LIKE ? OR " + commonKeyCol + " = ? OR " + baseURLCol + " LIKE ?) AND " + modelsCol + " LIKE ?"
-		args = append(args, common.String2Int(keyword), "%"+keyword+"%", keyword, "%"+keyword+"%", "%"+model+"%")
+		whereClause = "(id = ? OR name LIKE ? OR " + keyCondition + " OR " + baseURLCol + " LIKE ?) AND " + modelsCol + " LIKE ?"
+		args = append(args, common.String2Int(keyword), "%"+keyword+"%")
+		args = append(args, keyArgs...)
+		args = append(args, "%"+keyword+"%", "%"+model+"%")
 	}
 
 	subQuery := baseQuery.Where(whereClause, args...).
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
 	"github.com/QuantumNous/new-api/setting/ratio_setting"
 )
 
@@ -100,7 +102,6 @@ func GetRandomSatisfiedChannel(group string, model string, retry int) (*Channel,
 	}
 
 	channelSyncLock.RLock()
-	defer channelSyncLock.RUnlock()
 
 	// First, try to find channels with the exact model name.
 	channels := group2model2channels[group][model]
@@ -111,6 +112,21 @@ func GetRandomSatisfiedChannel(group string, model string, retry int) (*Channel,
 		channels = group2model2channels[group][normalizedModel]
 	}
 
+	if len(channels) == 0 {
+		channelSyncLock.RUnlock()
+		return nil, nil
+	}
+
+	// 跳过已超出 RPM / TPM / 并发限制的渠道，全部超出时视为无可用渠道。
+	// 用量检查需要访问 Redis，先在锁内复制候选渠道及其限制，释放锁后再过滤
+	// （禁用渠道时会原地修改 group2model2channels 中的切片）
+	if limits := channelUsageLimits(channels); len(limits) > 0 {
+		candidates := slices.Clone(channels)
+		channelSyncLock.RUnlock()
+		channels = filterOverUsageChannels(candidates, limits)
+		channelSyncLock.RLock()
+	}
+	defer channelSyncLock.RUnlock()
 	if len(channels) == 0 {
 		return nil, nil
 	}
@@ -159,6 +175,12 @@ func GetRandomSatisfiedChannel(group string, model string, retry int) (*Channel,
 		return nil, errors.New(fmt.Sprintf("no channel found, group: %s, model: %s, priority: %d", group, model, targetPriority))
 	}
 
+	if operation_setting.IsAdaptiveSelectionEnabled(group, model) {
+		if channel := pickAdaptiveChannel(targetChannels); channel != nil {
+			return channel, nil
+		}
+	}
+
 	// smoothing factor and adjustment
 	smoothingFactor := 1
 	smoothingAdjustment := 0
@@ -0,0 +1,125 @@
+package model
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/pkg/channelhealth"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pickAdaptiveIndex 在同一优先级的渠道中按 静态权重 × 健康度权重比例 随机选择，返回下标；
+// 全部渠道均处于熔断状态时返回 -1，由调用方回退到静态选择
+func pickAdaptiveIndex(channelIds []int, weights []int) int {
+	cfg := operation_setting.GetChannelSelectionSetting().HealthConfig()
+	keys := make([]channelhealth.Key, len(channelIds))
+	for i, id := range channelIds {
+		keys[i] = channelhealth.Key{ChannelId: id, KeyIndex: channelhealth.ChannelLevel}
+	}
+	ratios := channelhealth.WeightRatios(cfg, keys)
+
+	// 所有渠道权重为 0 时视为等权重
+	useEqualWeight := true
+	for _, weight := range weights {
+		if weight > 0 {
+			useEqualWeight = false
+			break
+		}
+	}
+	effective := make([]float64, len(weights))
+	totalWeight := 0.0
+	for i, weight := range weights {
+		base := float64(weight)
+		if useEqualWeight {
+			base = 1
+		}
+		effective[i] = base * ratios[i]
+		totalWeight += effective[i]
+	}
+	if totalWeight <= 0 {
+		return -1
+	}
+	randomWeight := rand.Float64() * totalWeight
+	for i := range effective {
+		randomWeight -= effective[i]
+		if randomWeight < 0 {
+			return i
+		}
+	}
+	return len(effective) - 1
+}
+
+func pickAdaptiveChannel(channels []*Channel) *Channel {
+	ids := make([]int, len(channels))
+	weights := make([]int, len(channels))
+	for i, channel := range channels {
+		ids[i] = channel.Id
+		weights[i] = channel.GetWeight()
+	}
+	if idx := pickAdaptiveIndex(ids, weights); idx >= 0 {
+		return channels[idx]
+	}
+	return nil
+}
+
+func pickAdaptiveAbility(abilities []Ability) *Ability {
+	ids := make([]int, len(abilities))
+	weights := make([]int, len(abilities))
+	for i, ability := range abilities {
+		ids[i] = ability.ChannelId
+		weights[i] = int(ability.Weight)
+	}
+	if idx := pickAdaptiveIndex(ids, weights); idx >= 0 {
+		return &abilities[idx]
+	}
+	return nil
+}
+
+// filterOpenCircuitKeys 多密钥渠道中排除处于熔断状态的密钥，全部熔断时保持原样
+func filterOpenCircuitKeys(channelId int, indexes []int) []int {
+	if !operation_setting.GetChannelSelectionSetting().AdaptiveEnabled {
+		return indexes
+	}
+	cfg := operation_setting.GetChannelSelectionSetting().HealthConfig()
+	healthy := make([]int, 0, len(indexes))
+	for _, idx := range indexes {
+		if !channelhealth.IsOpen(cfg, channelhealth.Key{ChannelId: channelId, KeyIndex: idx}) {
+			healthy = append(healthy, idx)
+		}
+	}
+	if len(healthy) == 0 {
+		return indexes
+	}
+	return healthy
+}
+
+// recordChannelThroughput 按消费日志中的输出 token 数统计渠道吞吐
+func recordChannelThroughput(c *gin.Context, params RecordConsumeLogParams) {
+	if c == nil || params.ChannelId == 0 || params.CompletionTokens <= 0 {
+		return
+	}
+	if !operation_setting.GetChannelSelectionSetting().AdaptiveEnabled {
+		return
+	}
+	startTime := common.GetContextKeyTime(c, constant.ContextKeyRequestStartTime)
+	if startTime.IsZero() {
+		return
+	}
+	duration := time.Since(startTime)
+	// 流式请求扣除首字耗时，只统计生成阶段
+	if frt, ok := params.Other["frt"].(float64); ok && params.IsStream && frt > 0 {
+		if generation := duration - time.Duration(frt)*time.Millisecond; generation > 0 {
+			duration = generation
+		}
+	}
+	cfg := operation_setting.GetChannelSelectionSetting().HealthConfig()
+	channelhealth.RecordThroughput(cfg, channelhealth.Key{ChannelId: params.ChannelId, KeyIndex: channelhealth.ChannelLevel}, params.CompletionTokens, duration)
+	if common.GetContextKeyBool(c, constant.ContextKeyChannelIsMultiKey) {
+		keyIndex := common.GetContextKeyInt(c, constant.ContextKeyChannelMultiKeyIndex)
+		channelhealth.RecordThroughput(cfg, channelhealth.Key{ChannelId: params.ChannelId, KeyIndex: keyIndex}, params.CompletionTokens, duration)
+	}
+}
@@ -0,0 +1,132 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+// MultiKeyScheduleResult 一次多Key定时检查的结果，下标均为key index
+type MultiKeyScheduleResult struct {
+	ChannelId   int
+	ChannelName string
+	Enabled     []int // 次日恢复或延长有效期后重新启用的key
+	Expired     []int // 因过期被禁用的key
+	Expiring    []int // 即将过期、需要发送提醒的key
+	// StatusChanged 渠道因key全部禁用或重新可用而改变了状态
+	StatusChanged bool
+}
+
+func (r *MultiKeyScheduleResult) Changed() bool {
+	return len(r.Enabled) > 0 || len(r.Expired) > 0 || len(r.Expiring) > 0
+}
+
+// GetMultiKeyChannelIds 获取所有多Key渠道的ID
+func GetMultiKeyChannelIds() ([]int, error) {
+	var channels []*Channel
+	if err := DB.Select("id", "channel_info").Find(&channels).Error; err != nil {
+		return nil, err
+	}
+	ids := make([]int, 0)
+	for _, channel := range channels {
+		if channel.ChannelInfo.IsMultiKey {
+			ids = append(ids, channel.Id)
+		}
+	}
+	return ids, nil
+}
+
+// ApplyMultiKeySchedule 检查多Key渠道的key：过期的key自动禁用，因每日上限禁用的key在次日恢复，
+// 在过期前 alertBefore 内的key标记为需要提醒，每个过期时间只提醒一次
+func ApplyMultiKeySchedule(channelId int, now time.Time, alertBefore time.Duration) (*MultiKeyScheduleResult, error) {
+	lock := GetChannelPollingLock(channelId)
+	lock.Lock()
+	defer lock.Unlock()
+
+	channel, err := GetChannelById(channelId, true)
+	if err != nil {
+		return nil, err
+	}
+	result := &MultiKeyScheduleResult{ChannelId: channel.Id, ChannelName: channel.Name}
+	info := &channel.ChannelInfo
+	if !info.IsMultiKey {
+		return result, nil
+	}
+	nowUnix := now.Unix()
+	today := channelKeyUsageDay(now)
+	for idx := 0; idx < info.MultiKeySize; idx++ {
+		meta := info.GetMultiKeyMeta(idx)
+		expired := meta.ExpiresAt > 0 && meta.ExpiresAt <= nowUnix
+		status, ok := info.MultiKeyStatusList[idx]
+		if !ok {
+			status = common.ChannelStatusEnabled
+		}
+		reason := info.MultiKeyDisabledReason[idx]
+		switch {
+		case status == common.ChannelStatusEnabled && expired:
+			info.setMultiKeyDisabled(idx, MultiKeyReasonExpired, nowUnix)
+			result.Expired = append(result.Expired, idx)
+		case status == common.ChannelStatusAutoDisabled && reason == MultiKeyReasonExpired && !expired:
+			info.setMultiKeyEnabled(idx)
+			result.Enabled = append(result.Enabled, idx)
+		case status == common.ChannelStatusAutoDisabled && reason == MultiKeyReasonDailySpendCap && !expired &&
+			channelKeyUsageDay(time.Unix(info.MultiKeyDisabledTime[idx], 0)) != today:
+			info.setMultiKeyEnabled(idx)
+			result.Enabled = append(result.Enabled, idx)
+		}
+		if meta.ExpiresAt > 0 && !expired && alertBefore > 0 && meta.ExpiryNotified != meta.ExpiresAt &&
+			time.Unix(meta.ExpiresAt, 0).Sub(now) <= alertBefore {
+			meta.ExpiryNotified = meta.ExpiresAt
+			info.SetMultiKeyMeta(idx, meta)
+			result.Expiring = append(result.Expiring, idx)
+		}
+	}
+	if !result.Changed() {
+		return result, nil
+	}
+
+	beforeStatus := channel.Status
+	if len(info.MultiKeyStatusList) >= info.MultiKeySize {
+		if channel.Status == common.ChannelStatusEnabled {
+			channel.Status = common.ChannelStatusAutoDisabled
+			otherInfo := channel.GetOtherInfo()
+			otherInfo["status_reason"] = "All keys are disabled"
+			otherInfo["status_time"] = nowUnix
+			channel.SetOtherInfo(otherInfo)
+		}
+	} else if channel.Status == common.ChannelStatusAutoDisabled && channel.GetOtherInfo()["status_reason"] == "All keys are disabled" {
+		channel.Status = common.ChannelStatusEnabled
+	}
+	result.StatusChanged = beforeStatus != channel.Status
+	if err = DB.Model(channel).Select("status", "other_info", "channel_info").Updates(channel).Error; err != nil {
+		return nil, err
+	}
+	if result.StatusChanged {
+		if err = UpdateAbilityStatus(channel.Id, channel.Status == common.ChannelStatusEnabled); err != nil {
+			common.SysLog(fmt.Sprintf("failed to update ability status: channel_id=%d, error=%v", channel.Id, err))
+		}
+	}
+	return result, nil
+}
+
+func (c *ChannelInfo) setMultiKeyEnabled(idx int) {
+	delete(c.MultiKeyStatusList, idx)
+	delete(c.MultiKeyDisabledReason, idx)
+	delete(c.MultiKeyDisabledTime, idx)
+}
+
+func (c *ChannelInfo) setMultiKeyDisabled(idx int, reason string, disabledTime int64) {
+	if c.MultiKeyStatusList == nil {
+		c.MultiKeyStatusList = make(map[int]int)
+	}
+	if c.MultiKeyDisabledReason == nil {
+		c.MultiKeyDisabledReason = make(map[int]string)
+	}
+	if c.MultiKeyDisabledTime == nil {
+		c.MultiKeyDisabledTime = make(map[int]int64)
+	}
+	c.MultiKeyStatusList[idx] = common.ChannelStatusAutoDisabled
+	c.MultiKeyDisabledReason[idx] = reason
+	c.MultiKeyDisabledTime[idx] = disabledTime
+}
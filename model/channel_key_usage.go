@@ -0,0 +1,330 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 多Key自动禁用原因，定时任务据此判断是否可以自动恢复
+const (
+	MultiKeyReasonDailySpendCap = "daily spend cap reached"
+	MultiKeyReasonExpired       = "key expired"
+)
+
+// channelKeyUsageReloadInterval 内存中的key用量定期从数据库刷新，以合并其他节点的消耗
+const channelKeyUsageReloadInterval = time.Minute
+
+// ChannelKeyUsage 多Key渠道中单个key在某一天的消耗，按key指纹记录，删除或重排key后仍能对应
+type ChannelKeyUsage struct {
+	Id           int    `json:"id"`
+	ChannelId    int    `json:"channel_id" gorm:"uniqueIndex:idx_channel_key_usage,priority:1"`
+	KeyHash      string `json:"key_hash" gorm:"type:varchar(16);uniqueIndex:idx_channel_key_usage,priority:2"`
+	Day          string `json:"day" gorm:"type:varchar(10);uniqueIndex:idx_channel_key_usage,priority:3;index"` // 格式 2006-01-02
+	UsedQuota    int64  `json:"used_quota" gorm:"type:bigint;not null;default:0"`
+	RequestCount int    `json:"request_count" gorm:"default:0"`
+	LastUsedAt   int64  `json:"last_used_at" gorm:"bigint;default:0"`
+}
+
+// ChannelKeyUsageState 单个key当日消耗与最近使用时间
+type ChannelKeyUsageState struct {
+	UsedQuota  int64 `json:"used_quota"`
+	LastUsedAt int64 `json:"last_used_at"`
+	day        string
+	// pickedAt 本节点最近一次选中该key的时间（纳秒），区分同一秒内的多次选择
+	pickedAt int64
+}
+
+func (s ChannelKeyUsageState) lastUsedNano() int64 {
+	return max(s.LastUsedAt*int64(time.Second), s.pickedAt)
+}
+
+// channelKeyUsageEntry 单个渠道的内存用量，各渠道独立加锁，互不阻塞
+type channelKeyUsageEntry struct {
+	// mu 保护 loadedAt 与 keys，持有期间不访问数据库
+	mu       sync.Mutex
+	loadedAt time.Time
+	keys     map[string]*ChannelKeyUsageState
+	// loadMu 保证同一渠道同时只有一个请求从数据库加载
+	loadMu sync.Mutex
+}
+
+// channelKeyUsageCache channel id -> *channelKeyUsageEntry
+var channelKeyUsageCache sync.Map
+
+func channelKeyUsageDay(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// channelKeyHash key指纹，避免在用量表中保存明文key
+func channelKeyHash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// loadChannelKeyUsage 从数据库读取渠道各key当日消耗与最近使用时间
+func loadChannelKeyUsage(channelId int, day string) (map[string]*ChannelKeyUsageState, error) {
+	var rows []ChannelKeyUsage
+	err := DB.Model(&ChannelKeyUsage{}).
+		Select("key_hash, MAX(last_used_at) AS last_used_at").
+		Where("channel_id = ?", channelId).
+		Group("key_hash").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	states := make(map[string]*ChannelKeyUsageState, len(rows))
+	for _, row := range rows {
+		states[row.KeyHash] = &ChannelKeyUsageState{LastUsedAt: row.LastUsedAt, day: day}
+	}
+	var today []ChannelKeyUsage
+	if err = DB.Where("channel_id = ? AND day = ?", channelId, day).Find(&today).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range today {
+		if state, ok := states[row.KeyHash]; ok {
+			state.UsedQuota = row.UsedQuota
+		}
+	}
+	return states, nil
+}
+
+func getChannelKeyUsageEntry(channelId int) *channelKeyUsageEntry {
+	if v, ok := channelKeyUsageCache.Load(channelId); ok {
+		return v.(*channelKeyUsageEntry)
+	}
+	v, _ := channelKeyUsageCache.LoadOrStore(channelId, &channelKeyUsageEntry{keys: make(map[string]*ChannelKeyUsageState)})
+	return v.(*channelKeyUsageEntry)
+}
+
+func (e *channelKeyUsageEntry) stale(now time.Time) (stale bool, loaded bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	loaded = !e.loadedAt.IsZero()
+	return !loaded || now.Sub(e.loadedAt) > channelKeyUsageReloadInterval, loaded
+}
+
+// channelKeyUsageEntryFor 获取渠道的内存用量，必要时从数据库加载。
+// 首次加载时其他请求等待加载完成；定期刷新时由一个请求加载，其他请求继续使用旧数据
+func channelKeyUsageEntryFor(channelId int, now time.Time) *channelKeyUsageEntry {
+	entry := getChannelKeyUsageEntry(channelId)
+	stale, loaded := entry.stale(now)
+	if !stale {
+		return entry
+	}
+	if loaded {
+		if !entry.loadMu.TryLock() {
+			return entry
+		}
+	} else {
+		entry.loadMu.Lock()
+	}
+	defer entry.loadMu.Unlock()
+	if stale, _ = entry.stale(now); !stale {
+		return entry
+	}
+
+	states, err := loadChannelKeyUsage(channelId, channelKeyUsageDay(now))
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	entry.loadedAt = now
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to load channel %d key usage: %s", channelId, err.Error()))
+		return entry
+	}
+	// 保留本节点刚选中但尚未落库的使用时间，以及加载期间写入的当日消耗
+	for hash, state := range entry.keys {
+		if loadedState, ok := states[hash]; ok {
+			loadedState.LastUsedAt = max(loadedState.LastUsedAt, state.LastUsedAt)
+			loadedState.pickedAt = state.pickedAt
+			if state.day == loadedState.day {
+				loadedState.UsedQuota = max(loadedState.UsedQuota, state.UsedQuota)
+			}
+		} else {
+			states[hash] = state
+		}
+	}
+	entry.keys = states
+	return entry
+}
+
+// getChannelKeyUsage 返回指定下标key的当日消耗与最近使用时间
+func getChannelKeyUsage(channelId int, keys []string, indexes []int) map[int]ChannelKeyUsageState {
+	now := time.Now()
+	day := channelKeyUsageDay(now)
+	entry := channelKeyUsageEntryFor(channelId, now)
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	result := make(map[int]ChannelKeyUsageState, len(indexes))
+	for _, idx := range indexes {
+		if idx < 0 || idx >= len(keys) {
+			continue
+		}
+		state, ok := entry.keys[channelKeyHash(keys[idx])]
+		if !ok {
+			result[idx] = ChannelKeyUsageState{}
+			continue
+		}
+		usage := *state
+		if usage.day != day {
+			usage.UsedQuota = 0
+		}
+		result[idx] = usage
+	}
+	return result
+}
+
+// GetChannelKeyUsage 返回渠道全部key的当日消耗与最近使用时间，key index -> state
+func GetChannelKeyUsage(channel *Channel) map[int]ChannelKeyUsageState {
+	keys := channel.GetKeys()
+	indexes := make([]int, len(keys))
+	for i := range keys {
+		indexes[i] = i
+	}
+	return getChannelKeyUsage(channel.Id, keys, indexes)
+}
+
+// touchChannelKeyUsage 选中key时记录使用时间，使最久未使用模式下的并发请求分散到不同key
+func touchChannelKeyUsage(channelId int, key string) {
+	now := time.Now()
+	entry := channelKeyUsageEntryFor(channelId, now)
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	hash := channelKeyHash(key)
+	state, ok := entry.keys[hash]
+	if !ok {
+		state = &ChannelKeyUsageState{day: channelKeyUsageDay(now)}
+		entry.keys[hash] = state
+	}
+	state.LastUsedAt = now.Unix()
+	state.pickedAt = now.UnixNano()
+}
+
+// setChannelKeyUsage 用数据库中的最新值覆盖内存用量
+func setChannelKeyUsage(channelId int, usage *ChannelKeyUsage) {
+	v, ok := channelKeyUsageCache.Load(channelId)
+	if !ok {
+		return
+	}
+	entry := v.(*channelKeyUsageEntry)
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	state, ok := entry.keys[usage.KeyHash]
+	if !ok {
+		state = &ChannelKeyUsageState{}
+		entry.keys[usage.KeyHash] = state
+	}
+	state.day = usage.Day
+	state.UsedQuota = usage.UsedQuota
+	state.LastUsedAt = max(state.LastUsedAt, usage.LastUsedAt)
+}
+
+// filterCappedKeys 排除当日消耗已达上限的key
+func filterCappedKeys(info *ChannelInfo, usage map[int]ChannelKeyUsageState, indexes []int) []int {
+	available := make([]int, 0, len(indexes))
+	for _, idx := range indexes {
+		limit := info.GetMultiKeyMeta(idx).DailySpendCap
+		if limit > 0 && usage[idx].UsedQuota >= limit {
+			continue
+		}
+		available = append(available, idx)
+	}
+	return available
+}
+
+// pickLeastUsedKey 按模式选择最久未使用或当日消耗最少的key，消耗相同时优先最久未使用
+func pickLeastUsedKey(mode constant.MultiKeyMode, usage map[int]ChannelKeyUsageState, indexes []int) int {
+	selected := indexes[0]
+	for _, idx := range indexes[1:] {
+		current, best := usage[idx], usage[selected]
+		if mode == constant.MultiKeyModeLeastSpent && current.UsedQuota != best.UsedQuota {
+			if current.UsedQuota < best.UsedQuota {
+				selected = idx
+			}
+			continue
+		}
+		if current.lastUsedNano() < best.lastUsedNano() {
+			selected = idx
+		}
+	}
+	return selected
+}
+
+// IncreaseChannelKeyUsage 将消耗计入key当日用量并返回累计值，quota 为负数时表示退还
+func IncreaseChannelKeyUsage(channelId int, key string, quota int) (*ChannelKeyUsage, error) {
+	now := time.Now()
+	usage := &ChannelKeyUsage{
+		ChannelId:    channelId,
+		KeyHash:      channelKeyHash(key),
+		Day:          channelKeyUsageDay(now),
+		UsedQuota:    int64(common.Max(quota, 0)),
+		RequestCount: 1,
+		LastUsedAt:   now.Unix(),
+	}
+	err := DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "channel_id"}, {Name: "key_hash"}, {Name: "day"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"used_quota":    gorm.Expr("used_quota + ?", quota),
+			"request_count": gorm.Expr("request_count + ?", 1),
+			"last_used_at":  usage.LastUsedAt,
+		}),
+	}).Create(usage).Error
+	if err != nil {
+		return nil, err
+	}
+	err = DB.Where("channel_id = ? AND key_hash = ? AND day = ?", usage.ChannelId, usage.KeyHash, usage.Day).First(usage).Error
+	return usage, err
+}
+
+// recordChannelKeyUsage 异步记录多Key渠道中key的消耗，达到每日上限时自动禁用该key
+func recordChannelKeyUsage(c *gin.Context, params RecordConsumeLogParams) {
+	if c == nil || params.ChannelId == 0 || !common.GetContextKeyBool(c, constant.ContextKeyChannelIsMultiKey) {
+		return
+	}
+	key := common.GetContextKeyString(c, constant.ContextKeyChannelKey)
+	if key == "" {
+		return
+	}
+	keyIndex := common.GetContextKeyInt(c, constant.ContextKeyChannelMultiKeyIndex)
+	channelId := params.ChannelId
+	quota := params.Quota
+	gopool.Go(func() {
+		usage, err := IncreaseChannelKeyUsage(channelId, key, quota)
+		if err != nil {
+			common.SysError(fmt.Sprintf("failed to update channel %d key usage: %s", channelId, err.Error()))
+			return
+		}
+		setChannelKeyUsage(channelId, usage)
+		channel, err := CacheGetChannel(channelId)
+		if err != nil {
+			return
+		}
+		limit := channel.ChannelInfo.GetMultiKeyMeta(keyIndex).DailySpendCap
+		if limit <= 0 || usage.UsedQuota < limit {
+			return
+		}
+		if keys := channel.GetKeys(); keyIndex >= len(keys) || keys[keyIndex] != key {
+			return
+		}
+		if UpdateChannelStatus(channelId, key, common.ChannelStatusAutoDisabled, MultiKeyReasonDailySpendCap) {
+			common.SysLog(fmt.Sprintf("channel #%d key #%d reached daily spend cap %d, disabled until tomorrow", channelId, keyIndex+1, limit))
+		}
+	})
+}
+
+// DeleteOldChannelKeyUsage 删除指定日期之前的key用量记录
+func DeleteOldChannelKeyUsage(beforeDay string) (int64, error) {
+	result := DB.Where("day < ?", beforeDay).Delete(&ChannelKeyUsage{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,138 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetNextEnabledKeyLeastSpent(t *testing.T) {
+	truncateTables(t)
+	t.Cleanup(func() {
+		DB.Exec("DELETE FROM channel_key_usages")
+	})
+
+	channel := &Channel{
+		Key:  "sk-a\nsk-b\nsk-c",
+		Name: "multi",
+		ChannelInfo: ChannelInfo{
+			IsMultiKey:   true,
+			MultiKeySize: 3,
+			MultiKeyMode: constant.MultiKeyModeLeastSpent,
+			MultiKeyMeta: map[int]MultiKeyMeta{1: {DailySpendCap: 100}},
+		},
+	}
+	require.NoError(t, DB.Create(channel).Error)
+
+	_, err := IncreaseChannelKeyUsage(channel.Id, "sk-a", 50)
+	require.NoError(t, err)
+	usage, err := IncreaseChannelKeyUsage(channel.Id, "sk-b", 120)
+	require.NoError(t, err)
+	require.EqualValues(t, 120, usage.UsedQuota)
+	_, err = IncreaseChannelKeyUsage(channel.Id, "sk-c", 80)
+	require.NoError(t, err)
+
+	// sk-b 已达每日上限，其余key中 sk-a 消耗最少
+	key, idx, apiErr := channel.GetNextEnabledKey()
+	require.Nil(t, apiErr)
+	require.Equal(t, "sk-a", key)
+	require.Equal(t, 0, idx)
+
+	channel.ChannelInfo.MultiKeyMode = constant.MultiKeyModeLeastRecentlyUsed
+	seen := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		key, _, apiErr = channel.GetNextEnabledKey()
+		require.Nil(t, apiErr)
+		seen[key] = true
+	}
+	require.Equal(t, map[string]bool{"sk-a": true, "sk-c": true}, seen)
+}
+
+func TestApplyMultiKeySchedule(t *testing.T) {
+	truncateTables(t)
+
+	now := time.Now()
+	yesterday := now.AddDate(0, 0, -1).Unix()
+	channel := &Channel{
+		Key:    "sk-a\nsk-b\nsk-c",
+		Name:   "multi",
+		Status: common.ChannelStatusEnabled,
+		ChannelInfo: ChannelInfo{
+			IsMultiKey:             true,
+			MultiKeySize:           3,
+			MultiKeyStatusList:     map[int]int{1: common.ChannelStatusAutoDisabled},
+			MultiKeyDisabledReason: map[int]string{1: MultiKeyReasonDailySpendCap},
+			MultiKeyDisabledTime:   map[int]int64{1: yesterday},
+			MultiKeyMeta: map[int]MultiKeyMeta{
+				0: {ExpiresAt: now.Add(-time.Minute).Unix()},
+				2: {ExpiresAt: now.Add(48 * time.Hour).Unix(), Note: "trial"},
+			},
+		},
+	}
+	require.NoError(t, DB.Create(channel).Error)
+
+	result, err := ApplyMultiKeySchedule(channel.Id, now, 72*time.Hour)
+	require.NoError(t, err)
+	require.Equal(t, []int{1}, result.Enabled)
+	require.Equal(t, []int{0}, result.Expired)
+	require.Equal(t, []int{2}, result.Expiring)
+
+	saved, err := GetChannelById(channel.Id, true)
+	require.NoError(t, err)
+	require.Equal(t, map[int]int{0: common.ChannelStatusAutoDisabled}, saved.ChannelInfo.MultiKeyStatusList)
+	require.Equal(t, MultiKeyReasonExpired, saved.ChannelInfo.MultiKeyDisabledReason[0])
+	require.Equal(t, saved.ChannelInfo.MultiKeyMeta[2].ExpiresAt, saved.ChannelInfo.MultiKeyMeta[2].ExpiryNotified)
+
+	// 每个过期时间只提醒一次
+	result, err = ApplyMultiKeySchedule(channel.Id, now, 72*time.Hour)
+	require.NoError(t, err)
+	require.False(t, result.Changed())
+}
+
+func TestChannelKeyUsageReloadDoesNotBlock(t *testing.T) {
+	truncateTables(t)
+	t.Cleanup(func() {
+		DB.Exec("DELETE FROM channel_key_usages")
+		channelKeyUsageCache.Delete(9201)
+		channelKeyUsageCache.Delete(9202)
+	})
+
+	_, err := IncreaseChannelKeyUsage(9201, "sk-a", 30)
+	require.NoError(t, err)
+	keys := []string{"sk-a", "sk-b"}
+	require.EqualValues(t, 30, getChannelKeyUsage(9201, keys, []int{0, 1})[0].UsedQuota)
+
+	// 模拟渠道 9201 正在从数据库刷新：其他请求继续使用旧数据，其他渠道不受影响
+	entry := getChannelKeyUsageEntry(9201)
+	entry.mu.Lock()
+	entry.loadedAt = time.Now().Add(-2 * channelKeyUsageReloadInterval)
+	entry.mu.Unlock()
+	entry.loadMu.Lock()
+	done := make(chan map[int]ChannelKeyUsageState, 2)
+	go func() {
+		touchChannelKeyUsage(9201, "sk-b")
+		done <- getChannelKeyUsage(9201, keys, []int{0, 1})
+	}()
+	go func() {
+		done <- getChannelKeyUsage(9202, keys, []int{0})
+	}()
+	for i := 0; i < 2; i++ {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("key usage lookup blocked by another load")
+		}
+	}
+	entry.loadMu.Unlock()
+
+	// 刷新后保留本节点的选中时间与加载期间写入的消耗
+	usage, err := IncreaseChannelKeyUsage(9201, "sk-a", 20)
+	require.NoError(t, err)
+	setChannelKeyUsage(9201, usage)
+	result := getChannelKeyUsage(9201, keys, []int{0, 1})
+	require.EqualValues(t, 50, result[0].UsedQuota)
+	require.NotZero(t, result[1].pickedAt)
+}
@@ -44,7 +44,7 @@ type CustomOAuthProvider struct {
 	Icon                  string `json:"icon" gorm:"type:varchar(128);default:''"`                       // Icon name from @lobehub/icons
 	Enabled               bool   `json:"enabled" gorm:"default:false"`                                   // Whether this provider is enabled
 	ClientId              string `json:"client_id" gorm:"type:varchar(256)"`                             // OAuth client ID
-	ClientSecret          string `json:"-" gorm:"type:varchar(512)"`                                     // OAuth client secret (not returned to frontend)
+	ClientSecret          string `json:"-" gorm:"type:varchar(512);serializer:secret"`                   // OAuth client secret (not returned to frontend, encrypted at rest)
 	AuthorizationEndpoint string `json:"authorization_endpoint" gorm:"type:varchar(512)"`                // Authorization URL
 	TokenEndpoint         string `json:"token_endpoint" gorm:"type:varchar(512)"`                        // Token exchange URL
 	UserInfoEndpoint      string `json:"user_info_endpoint" gorm:"type:varchar(512)"`                    // User info URL
@@ -0,0 +1,161 @@
+package model
+
+import (
+	"errors"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+const (
+	FileStatusUploaded  = "uploaded"
+	FileStatusProcessed = "processed"
+	FileStatusError     = "error"
+
+	// FileStorageUpstream 表示文件透传到上游渠道保存，本地只保留元数据
+	FileStorageUpstream = "upstream"
+)
+
+// File OpenAI Files API 文件元数据，文件内容保存在 StorageType 指定的存储后端
+type File struct {
+	Id          int    `json:"-" gorm:"primaryKey"`
+	FileId      string `json:"id" gorm:"type:varchar(128);uniqueIndex"` // 对外暴露的文件 ID（透传时为上游文件 ID）
+	UserId      int    `json:"-" gorm:"index"`
+	TokenId     int    `json:"-" gorm:"index"`
+	Filename    string `json:"filename" gorm:"type:varchar(255)"`
+	Purpose     string `json:"purpose" gorm:"type:varchar(32);index"`
+	Bytes       int64  `json:"bytes"`
+	ContentType string `json:"-" gorm:"type:varchar(128)"`
+	Status      string `json:"status" gorm:"type:varchar(16)"`
+	StorageType string `json:"-" gorm:"type:varchar(16)"` // local / s3 / upstream
+	StorageKey  string `json:"-" gorm:"type:varchar(512)"`
+	ChannelId   int    `json:"-" gorm:"index"` // 透传时文件所属的上游渠道
+	// 透传时上传所用 key 在多 key 渠道中的序号，后续读取和删除须使用同一个 key
+	ChannelKeyIndex int   `json:"-" gorm:"default:0"`
+	CreatedAt       int64 `json:"created_at" gorm:"bigint;index"`
+	ExpiresAt       int64 `json:"expires_at" gorm:"bigint;index"` // 0 表示不过期
+}
+
+// GenerateFileId 生成对外暴露的 file-xxxx 格式 ID
+func GenerateFileId() string {
+	key, _ := common.GenerateRandomCharsKey(24)
+	return "file-" + key
+}
+
+func (f *File) Insert() error {
+	if f.CreatedAt == 0 {
+		f.CreatedAt = common.GetTimestamp()
+	}
+	return DB.Create(f).Error
+}
+
+func (f *File) Update() error {
+	return DB.Save(f).Error
+}
+
+func (f *File) Delete() error {
+	return DB.Delete(f).Error
+}
+
+// IsExpired 文件是否已过期
+func (f *File) IsExpired() bool {
+	return f.ExpiresAt > 0 && f.ExpiresAt <= common.GetTimestamp()
+}
+
+// GetUserFileById 获取用户自己的文件
+func GetUserFileById(userId int, fileId string) (*File, error) {
+	if fileId == "" {
+		return nil, errors.New("file id is empty")
+	}
+	var file File
+	err := DB.Where("file_id = ? AND user_id = ?", fileId, userId).First(&file).Error
+	if err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// GetFileById 按文件 ID 获取文件（不校验用户，内部使用）
+func GetFileById(fileId string) (*File, error) {
+	var file File
+	err := DB.Where("file_id = ?", fileId).First(&file).Error
+	if err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// ListUserFiles 按 OpenAI 的游标分页方式列出用户文件
+// after 为上一页最后一个文件 ID；order 为 asc 或 desc
+func ListUserFiles(userId int, purpose string, after string, limit int, order string) (files []*File, hasMore bool, err error) {
+	if limit <= 0 || limit > 10000 {
+		limit = 10000
+	}
+	desc := order != "asc"
+	query := DB.Where("user_id = ?", userId)
+	if purpose != "" {
+		query = query.Where("purpose = ?", purpose)
+	}
+	if after != "" {
+		cursor, err := GetUserFileById(userId, after)
+		if err != nil {
+			return nil, false, err
+		}
+		if desc {
+			query = query.Where("id < ?", cursor.Id)
+		} else {
+			query = query.Where("id > ?", cursor.Id)
+		}
+	}
+	if desc {
+		query = query.Order("id desc")
+	} else {
+		query = query.Order("id asc")
+	}
+	err = query.Limit(limit + 1).Find(&files).Error
+	if err != nil {
+		return nil, false, err
+	}
+	if len(files) > limit {
+		files = files[:limit]
+		hasMore = true
+	}
+	return files, hasMore, nil
+}
+
+// GetUserFileUsage 统计用户当前的文件数量和总大小
+func GetUserFileUsage(userId int) (count int64, totalBytes int64, err error) {
+	var result struct {
+		Count int64
+		Total int64
+	}
+	err = DB.Model(&File{}).Select("count(*) as count, coalesce(sum(bytes), 0) as total").
+		Where("user_id = ?", userId).Scan(&result).Error
+	return result.Count, result.Total, err
+}
+
+// GetExpiredFiles 获取已过期的文件，每次最多 limit 条
+func GetExpiredFiles(limit int) ([]*File, error) {
+	var files []*File
+	err := DB.Where("expires_at > 0 AND expires_at <= ?", common.GetTimestamp()).
+		Order("id asc").Limit(limit).Find(&files).Error
+	return files, err
+}
+
+// GetFileChannelIds 返回指定文件 ID 中透传到上游的文件所属渠道
+func GetFileChannelIds(userId int, fileIds []string) (map[string]int, error) {
+	result := make(map[string]int)
+	if len(fileIds) == 0 {
+		return result, nil
+	}
+	var files []*File
+	err := DB.Select("file_id", "channel_id").
+		Where("user_id = ? AND file_id IN ? AND channel_id > 0", userId, fileIds).
+		Find(&files).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		result[f.FileId] = f.ChannelId
+	}
+	return result, nil
+}
@@ -7,7 +7,9 @@ import (
 	"time"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/pkg/metrics"
 	"github.com/QuantumNous/new-api/types"
 
 	"github.com/gin-gonic/gin"
@@ -36,6 +38,7 @@ type Log struct {
 	Group            string `json:"group" gorm:"index"`
 	Ip               string `json:"ip" gorm:"index;default:''"`
 	RequestId        string `json:"request_id,omitempty" gorm:"type:varchar(64);index:idx_logs_request_id;default:''"`
+	OrganizationId   int    `json:"organization_id" gorm:"index;default:0"`
 	Other            string `json:"other"`
 }
 
@@ -48,6 +51,8 @@ const (
 	LogTypeSystem  = 4
 	LogTypeError   = 5
 	LogTypeRefund  = 6
+	// LogTypeModeration 内容审核命中记录
+	LogTypeModeration = 7
 )
 
 func formatUserLogs(logs []*Log, startIdx int) {
@@ -92,6 +97,18 @@ func RecordLog(userId int, logType int, content string) {
 func RecordErrorLog(c *gin.Context, userId int, channelId int, modelName string, tokenName string, content string, tokenId int, useTimeSeconds int,
 	isStream bool, group string, other map[string]interface{}) {
 	logger.LogInfo(c, fmt.Sprintf("record error log: userId=%d, channelId=%d, modelName=%s, tokenName=%s, content=%s", userId, channelId, modelName, tokenName, content))
+	recordRequestLog(c, LogTypeError, userId, channelId, modelName, tokenName, content, tokenId, useTimeSeconds, isStream, group, other)
+}
+
+// RecordModerationLog 记录内容审核命中，命中详情保存在 other.moderation 中
+func RecordModerationLog(c *gin.Context, userId int, channelId int, modelName string, tokenName string, content string, tokenId int,
+	isStream bool, group string, other map[string]interface{}) {
+	logger.LogInfo(c, fmt.Sprintf("record moderation log: userId=%d, modelName=%s, tokenName=%s, content=%s", userId, modelName, tokenName, content))
+	recordRequestLog(c, LogTypeModeration, userId, channelId, modelName, tokenName, content, tokenId, 0, isStream, group, other)
+}
+
+func recordRequestLog(c *gin.Context, logType int, userId int, channelId int, modelName string, tokenName string, content string, tokenId int,
+	useTimeSeconds int, isStream bool, group string, other map[string]interface{}) {
 	username := c.GetString("username")
 	requestId := c.GetString(common.RequestIdKey)
 	otherStr := common.MapToJsonStr(other)
@@ -106,7 +123,7 @@ func RecordErrorLog(c *gin.Context, userId int, channelId int, modelName string,
 		UserId:           userId,
 		Username:         username,
 		CreatedAt:        common.GetTimestamp(),
-		Type:             LogTypeError,
+		Type:             logType,
 		Content:          content,
 		PromptTokens:     0,
 		CompletionTokens: 0,
@@ -124,8 +141,9 @@ func RecordErrorLog(c *gin.Context, userId int, channelId int, modelName string,
 			}
 			return ""
 		}(),
-		RequestId: requestId,
-		Other:     otherStr,
+		RequestId:      requestId,
+		OrganizationId: common.GetContextKeyInt(c, constant.ContextKeyTokenOrganizationId),
+		Other:          otherStr,
 	}
 	err := LOG_DB.Create(log).Error
 	if err != nil {
@@ -149,12 +167,22 @@ type RecordConsumeLogParams struct {
 }
 
 func RecordConsumeLog(c *gin.Context, userId int, params RecordConsumeLogParams) {
+	// 指标与用量统计不受消费日志开关影响
+	metrics.AddQuotaConsumed(params.ChannelId, common.GetContextKeyInt(c, constant.ContextKeyChannelType), params.ModelName, params.Group, params.Quota)
+	recordChannelThroughput(c, params)
+	recordChannelKeyUsage(c, params)
+	recordUsageLimitTokens(c, params)
+	if token, ok := common.GetContextKeyType[*Token](c, constant.ContextKeyTokenBudget); ok && token != nil {
+		recordTokenBudgetUsage(token, params.Quota)
+	}
+	recordUserMonthlyUsage(userId, params.Quota)
 	if !common.LogConsumeEnabled {
 		return
 	}
 	logger.LogInfo(c, fmt.Sprintf("record consume log: userId=%d, params=%s", userId, common.GetJsonString(params)))
 	username := c.GetString("username")
 	requestId := c.GetString(common.RequestIdKey)
+	organizationId := common.GetContextKeyInt(c, constant.ContextKeyTokenOrganizationId)
 	otherStr := common.MapToJsonStr(params.Other)
 	// 判断是否需要记录 IP
 	needRecordIp := false
@@ -185,8 +213,9 @@ func RecordConsumeLog(c *gin.Context, userId int, params RecordConsumeLogParams)
 			}
 			return ""
 		}(),
-		RequestId: requestId,
-		Other:     otherStr,
+		RequestId:      requestId,
+		OrganizationId: organizationId,
+		Other:          otherStr,
 	}
 	err := LOG_DB.Create(log).Error
 	if err != nil {
@@ -194,7 +223,7 @@ func RecordConsumeLog(c *gin.Context, userId int, params RecordConsumeLogParams)
 	}
 	if common.DataExportEnabled {
 		gopool.Go(func() {
-			LogQuotaData(userId, username, params.ModelName, params.Quota, common.GetTimestamp(), params.PromptTokens+params.CompletionTokens)
+			LogQuotaData(userId, username, params.ModelName, params.Quota, common.GetTimestamp(), params.PromptTokens+params.CompletionTokens, organizationId)
 		})
 	}
 }
@@ -209,9 +238,27 @@ type RecordTaskBillingLogParams struct {
 	TokenId   int
 	Group     string
 	Other     map[string]interface{}
+	// OrganizationId 任务由组织令牌发起时记录所属组织
+	OrganizationId int
 }
 
 func RecordTaskBillingLog(params RecordTaskBillingLogParams) {
+	if params.LogType == LogTypeConsume {
+		channelType := 0
+		if channel, err := CacheGetChannel(params.ChannelId); err == nil {
+			channelType = channel.Type
+		}
+		metrics.AddQuotaConsumed(params.ChannelId, channelType, params.ModelName, params.Group, params.Quota)
+	}
+	// 异步任务无请求上下文，由 recordTaskTokenBudgetUsage 自行判断令牌是否配置了预算
+	switch params.LogType {
+	case LogTypeConsume:
+		recordTaskTokenBudgetUsage(params.TokenId, params.Quota)
+		recordUserMonthlyUsage(params.UserId, params.Quota)
+	case LogTypeRefund:
+		recordTaskTokenBudgetUsage(params.TokenId, -params.Quota)
+		recordUserMonthlyUsage(params.UserId, -params.Quota)
+	}
 	if params.LogType == LogTypeConsume && !common.LogConsumeEnabled {
 		return
 	}
@@ -223,18 +270,19 @@ func RecordTaskBillingLog(params RecordTaskBillingLogParams) {
 		}
 	}
 	log := &Log{
-		UserId:    params.UserId,
-		Username:  username,
-		CreatedAt: common.GetTimestamp(),
-		Type:      params.LogType,
-		Content:   params.Content,
-		TokenName: tokenName,
-		ModelName: params.ModelName,
-		Quota:     params.Quota,
-		ChannelId: params.ChannelId,
-		TokenId:   params.TokenId,
-		Group:     params.Group,
-		Other:     common.MapToJsonStr(params.Other),
+		UserId:         params.UserId,
+		Username:       username,
+		CreatedAt:      common.GetTimestamp(),
+		Type:           params.LogType,
+		Content:        params.Content,
+		TokenName:      tokenName,
+		ModelName:      params.ModelName,
+		Quota:          params.Quota,
+		ChannelId:      params.ChannelId,
+		TokenId:        params.TokenId,
+		Group:          params.Group,
+		Other:          common.MapToJsonStr(params.Other),
+		OrganizationId: params.OrganizationId,
 	}
 	err := LOG_DB.Create(log).Error
 	if err != nil {
@@ -373,6 +421,46 @@ func GetUserLogs(userId int, logType int, startTimestamp int64, endTimestamp int
 	return logs, total, err
 }
 
+// GetOrganizationLogs 查询组织令牌产生的日志，供组织所有者与管理员查看
+func GetOrganizationLogs(organizationId int, logType int, startTimestamp int64, endTimestamp int64, modelName string, username string, tokenName string, startIdx int, num int) (logs []*Log, total int64, err error) {
+	tx := LOG_DB.Where("logs.organization_id = ?", organizationId)
+	if logType != LogTypeUnknown {
+		tx = tx.Where("logs.type = ?", logType)
+	}
+	if modelName != "" {
+		modelNamePattern, err := sanitizeLikePattern(modelName)
+		if err != nil {
+			return nil, 0, err
+		}
+		tx = tx.Where("logs.model_name LIKE ? ESCAPE '!'", modelNamePattern)
+	}
+	if username != "" {
+		tx = tx.Where("logs.username = ?", username)
+	}
+	if tokenName != "" {
+		tx = tx.Where("logs.token_name = ?", tokenName)
+	}
+	if startTimestamp != 0 {
+		tx = tx.Where("logs.created_at >= ?", startTimestamp)
+	}
+	if endTimestamp != 0 {
+		tx = tx.Where("logs.created_at <= ?", endTimestamp)
+	}
+	err = tx.Model(&Log{}).Limit(logSearchCountLimit).Count(&total).Error
+	if err != nil {
+		common.SysError("failed to count organization logs: " + err.Error())
+		return nil, 0, errors.New("查询日志失败")
+	}
+	err = tx.Order("logs.id desc").Limit(num).Offset(startIdx).Find(&logs).Error
+	if err != nil {
+		common.SysError("failed to search organization logs: " + err.Error())
+		return nil, 0, errors.New("查询日志失败")
+	}
+
+	formatUserLogs(logs, startIdx)
+	return logs, total, err
+}
+
 type Stat struct {
 	Quota int `json:"quota"`
 	Rpm   int `json:"rpm"`
@@ -478,3 +566,20 @@ func DeleteOldLog(ctx context.Context, targetTimestamp int64, limit int) (int64,
 
 	return total, nil
 }
+
+// SumQuotaByRequestIds 统计指定请求 ID 的消费额度（用于批处理汇总）
+func SumQuotaByRequestIds(requestIds []string) (int, error) {
+	total := 0
+	const chunkSize = 500
+	for start := 0; start < len(requestIds); start += chunkSize {
+		end := min(start+chunkSize, len(requestIds))
+		var quota int
+		err := LOG_DB.Model(&Log{}).Where("type = ? AND request_id IN ?", LogTypeConsume, requestIds[start:end]).
+			Select("coalesce(sum(quota), 0)").Scan(&quota).Error
+		if err != nil {
+			return total, err
+		}
+		total += quota
+	}
+	return total, nil
+}
@@ -264,6 +264,7 @@ func migrateDB() error {
 		&Redemption{},
 		&Ability{},
 		&Log{},
+		&PayloadLog{},
 		&Midjourney{},
 		&TopUp{},
 		&QuotaData{},
@@ -279,9 +280,22 @@ func migrateDB() error {
 		&SubscriptionOrder{},
 		&UserSubscription{},
 		&SubscriptionPreConsumeRecord{},
+		&TokenBudget{},
+		&Organization{},
+		&OrganizationMember{},
+		&UserMonthlyUsage{},
+		&ChannelKeyUsage{},
+		&AuditLog{},
+		&UserStatement{},
 		&CustomOAuthProvider{},
 		&UserOAuthBinding{},
 		&ProxySite{},
+		&File{},
+		&Batch{},
+		&WebhookEndpoint{},
+		&WebhookDelivery{},
+		&EncryptionKey{},
+		&ResponseRecord{},
 	)
 	if err != nil {
 		return err
@@ -314,6 +328,7 @@ func migrateDBFast() error {
 		{&Redemption{}, "Redemption"},
 		{&Ability{}, "Ability"},
 		{&Log{}, "Log"},
+		{&PayloadLog{}, "PayloadLog"},
 		{&Midjourney{}, "Midjourney"},
 		{&TopUp{}, "TopUp"},
 		{&QuotaData{}, "QuotaData"},
@@ -329,9 +344,22 @@ func migrateDBFast() error {
 		{&SubscriptionOrder{}, "SubscriptionOrder"},
 		{&UserSubscription{}, "UserSubscription"},
 		{&SubscriptionPreConsumeRecord{}, "SubscriptionPreConsumeRecord"},
+		{&TokenBudget{}, "TokenBudget"},
+		{&Organization{}, "Organization"},
+		{&OrganizationMember{}, "OrganizationMember"},
+		{&UserMonthlyUsage{}, "UserMonthlyUsage"},
+		{&ChannelKeyUsage{}, "ChannelKeyUsage"},
+		{&AuditLog{}, "AuditLog"},
+		{&UserStatement{}, "UserStatement"},
 		{&CustomOAuthProvider{}, "CustomOAuthProvider"},
 		{&UserOAuthBinding{}, "UserOAuthBinding"},
 		{&ProxySite{}, "ProxySite"},
+		{&File{}, "File"},
+		{&Batch{}, "Batch"},
+		{&WebhookEndpoint{}, "WebhookEndpoint"},
+		{&WebhookDelivery{}, "WebhookDelivery"},
+		{&EncryptionKey{}, "EncryptionKey"},
+		{&ResponseRecord{}, "ResponseRecord"},
 	}
 	// 动态计算migration数量，确保errChan缓冲区足够大
 	errChan := make(chan error, len(migrations))
@@ -371,7 +399,7 @@ func migrateDBFast() error {
 
 func migrateLOGDB() error {
 	var err error
-	if err = LOG_DB.AutoMigrate(&Log{}); err != nil {
+	if err = LOG_DB.AutoMigrate(&Log{}, &PayloadLog{}); err != nil {
 		return err
 	}
 	return nil
@@ -13,6 +13,8 @@ import (
 	"github.com/QuantumNous/new-api/setting/performance_setting"
 	"github.com/QuantumNous/new-api/setting/ratio_setting"
 	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"gorm.io/gorm"
 )
 
 type Option struct {
@@ -20,6 +22,39 @@ type Option struct {
 	Value string `json:"value"`
 }
 
+// IsSecretOptionKey 判断配置项是否为密钥类配置，此类配置不会返回给前端，并在启用加密时加密保存
+func IsSecretOptionKey(key string) bool {
+	return strings.HasSuffix(key, "Token") ||
+		strings.HasSuffix(key, "Secret") ||
+		strings.HasSuffix(key, "Key") ||
+		strings.HasSuffix(key, "secret") ||
+		strings.HasSuffix(key, "api_key")
+}
+
+func (option *Option) BeforeSave(tx *gorm.DB) error {
+	if !IsSecretOptionKey(option.Key) {
+		return nil
+	}
+	value, err := EncryptSecret(option.Value)
+	if err != nil {
+		return err
+	}
+	tx.Statement.SetColumn("Value", value)
+	return nil
+}
+
+func (option *Option) AfterFind(tx *gorm.DB) error {
+	if !IsEncryptedSecret(option.Value) {
+		return nil
+	}
+	value, err := DecryptSecret(option.Value)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt option %s: %w", option.Key, err)
+	}
+	option.Value = value
+	return nil
+}
+
 func AllOption() ([]*Option, error) {
 	var options []*Option
 	var err error
@@ -127,6 +162,7 @@ func InitOptionMap() {
 	common.OptionMap["ImageRatio"] = ratio_setting.ImageRatio2JSONString()
 	common.OptionMap["AudioRatio"] = ratio_setting.AudioRatio2JSONString()
 	common.OptionMap["AudioCompletionRatio"] = ratio_setting.AudioCompletionRatio2JSONString()
+	common.OptionMap["PricingTiers"] = ratio_setting.PricingTiers2JSONString()
 	common.OptionMap["TopUpLink"] = common.TopUpLink
 	//common.OptionMap["ChatLink"] = common.ChatLink
 	//common.OptionMap["ChatLink2"] = common.ChatLink2
@@ -451,6 +487,8 @@ func updateOptionMap(key string, value string) (err error) {
 		err = ratio_setting.UpdateAudioRatioByJSONString(value)
 	case "AudioCompletionRatio":
 		err = ratio_setting.UpdateAudioCompletionRatioByJSONString(value)
+	case "PricingTiers":
+		err = ratio_setting.UpdatePricingTiersByJSONString(value)
 	case "TopUpLink":
 		common.TopUpLink = value
 	//case "ChatLink":
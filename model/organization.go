@@ -0,0 +1,414 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"gorm.io/gorm"
+)
+
+// 组织成员角色
+const (
+	OrganizationRoleOwner  = "owner"
+	OrganizationRoleAdmin  = "admin"
+	OrganizationRoleMember = "member"
+)
+
+const (
+	OrganizationStatusEnabled  = 1
+	OrganizationStatusDisabled = 2
+)
+
+var (
+	ErrOrganizationNotFound            = errors.New("organization not found")
+	ErrOrganizationDisabled            = errors.New("organization is disabled")
+	ErrOrganizationNotMember           = errors.New("user is not a member of the organization")
+	ErrOrganizationQuotaInsufficient   = errors.New("organization quota insufficient")
+	ErrOrganizationMemberQuotaExceeded = errors.New("organization member quota limit exceeded")
+	ErrOrganizationMemberExists        = errors.New("user is already a member of the organization")
+)
+
+// Organization 组织，成员共享同一个钱包，组织令牌从该钱包扣费
+type Organization struct {
+	Id          int            `json:"id"`
+	Name        string         `json:"name" gorm:"type:varchar(64);index"`
+	Description string         `json:"description" gorm:"type:varchar(255);default:''"`
+	OwnerId     int            `json:"owner_id" gorm:"index"`
+	Status      int            `json:"status" gorm:"default:1"`
+	Quota       int            `json:"quota" gorm:"default:0"`      // 共享钱包剩余额度
+	UsedQuota   int            `json:"used_quota" gorm:"default:0"` // 共享钱包已用额度
+	CreatedTime int64          `json:"created_time" gorm:"bigint"`
+	UpdatedTime int64          `json:"updated_time" gorm:"bigint"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+func (o *Organization) BeforeCreate(tx *gorm.DB) error {
+	now := common.GetTimestamp()
+	o.CreatedTime = now
+	o.UpdatedTime = now
+	return nil
+}
+
+func (o *Organization) BeforeUpdate(tx *gorm.DB) error {
+	o.UpdatedTime = common.GetTimestamp()
+	return nil
+}
+
+// OrganizationMember 组织成员，QuotaLimit 为该成员可使用的共享额度上限
+type OrganizationMember struct {
+	Id             int    `json:"id"`
+	OrganizationId int    `json:"organization_id" gorm:"uniqueIndex:idx_org_member,priority:1"`
+	UserId         int    `json:"user_id" gorm:"uniqueIndex:idx_org_member,priority:2;index"`
+	Role           string `json:"role" gorm:"type:varchar(16);default:'member'"`
+	QuotaLimit     int    `json:"quota_limit" gorm:"default:0"` // 0 表示不限制
+	UsedQuota      int    `json:"used_quota" gorm:"default:0"`
+	CreatedTime    int64  `json:"created_time" gorm:"bigint"`
+	Username       string `json:"username" gorm:"->;-:migration"`
+	DisplayName    string `json:"display_name" gorm:"->;-:migration"`
+}
+
+func (m *OrganizationMember) BeforeCreate(tx *gorm.DB) error {
+	m.CreatedTime = common.GetTimestamp()
+	return nil
+}
+
+// CanManage 是否可以管理组织（修改信息、管理成员、查看组织用量）
+func (m *OrganizationMember) CanManage() bool {
+	return m.Role == OrganizationRoleOwner || m.Role == OrganizationRoleAdmin
+}
+
+// CanManageMember 是否可以管理目标成员：所有者可管理所有人，管理员只能管理普通成员
+func (m *OrganizationMember) CanManageMember(target *OrganizationMember) bool {
+	if m.Role == OrganizationRoleOwner {
+		return true
+	}
+	return m.Role == OrganizationRoleAdmin && target.Role == OrganizationRoleMember
+}
+
+func IsValidOrganizationRole(role string) bool {
+	switch role {
+	case OrganizationRoleOwner, OrganizationRoleAdmin, OrganizationRoleMember:
+		return true
+	}
+	return false
+}
+
+// UserOrganization 用户所在的组织及其在组织中的角色与额度
+type UserOrganization struct {
+	Organization
+	Role             string `json:"role"`
+	MemberQuotaLimit int    `json:"member_quota_limit"`
+	MemberUsedQuota  int    `json:"member_used_quota"`
+}
+
+// CreateOrganization 创建组织，创建者成为所有者
+func CreateOrganization(org *Organization, ownerId int) error {
+	org.OwnerId = ownerId
+	org.Status = OrganizationStatusEnabled
+	org.Quota = 0
+	org.UsedQuota = 0
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(org).Error; err != nil {
+			return err
+		}
+		return tx.Create(&OrganizationMember{
+			OrganizationId: org.Id,
+			UserId:         ownerId,
+			Role:           OrganizationRoleOwner,
+		}).Error
+	})
+}
+
+func GetOrganizationById(id int) (*Organization, error) {
+	if id <= 0 {
+		return nil, ErrOrganizationNotFound
+	}
+	org := &Organization{}
+	err := DB.First(org, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrOrganizationNotFound
+	}
+	return org, err
+}
+
+func GetAllOrganizations(keyword string, startIdx int, num int) (orgs []*Organization, total int64, err error) {
+	tx := DB.Model(&Organization{})
+	if keyword = strings.TrimSpace(keyword); keyword != "" {
+		if id, convErr := strconv.Atoi(keyword); convErr == nil {
+			tx = tx.Where("id = ? OR name LIKE ?", id, "%"+keyword+"%")
+		} else {
+			tx = tx.Where("name LIKE ?", "%"+keyword+"%")
+		}
+	}
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Order("id desc").Limit(num).Offset(startIdx).Find(&orgs).Error
+	return orgs, total, err
+}
+
+// GetUserOrganizations 获取用户加入的所有组织
+func GetUserOrganizations(userId int) ([]*UserOrganization, error) {
+	var orgs []*UserOrganization
+	err := DB.Table("organizations").
+		Select("organizations.*, organization_members.role, organization_members.quota_limit AS member_quota_limit, organization_members.used_quota AS member_used_quota").
+		Joins("JOIN organization_members ON organization_members.organization_id = organizations.id").
+		Where("organization_members.user_id = ? AND organizations.deleted_at IS NULL", userId).
+		Order("organizations.id asc").
+		Find(&orgs).Error
+	return orgs, err
+}
+
+func (org *Organization) Update() error {
+	return DB.Model(org).Select("name", "description").Updates(org).Error
+}
+
+func UpdateOrganizationStatus(id int, status int) error {
+	return DB.Model(&Organization{}).Where("id = ?", id).Update("status", status).Error
+}
+
+// DeleteOrganization 删除组织及其成员，绑定到该组织的令牌将无法继续使用
+func DeleteOrganization(id int) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("organization_id = ?", id).Delete(&OrganizationMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Organization{}, "id = ?", id).Error
+	})
+}
+
+func GetOrganizationMember(organizationId int, userId int) (*OrganizationMember, error) {
+	member := &OrganizationMember{}
+	err := DB.Where("organization_id = ? AND user_id = ?", organizationId, userId).First(member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrOrganizationNotMember
+	}
+	return member, err
+}
+
+func GetOrganizationMembers(organizationId int) ([]*OrganizationMember, error) {
+	var members []*OrganizationMember
+	err := DB.Table("organization_members").
+		Select("organization_members.*, users.username, users.display_name").
+		Joins("LEFT JOIN users ON users.id = organization_members.user_id").
+		Where("organization_members.organization_id = ?", organizationId).
+		Order("organization_members.id asc").
+		Find(&members).Error
+	return members, err
+}
+
+func AddOrganizationMember(member *OrganizationMember) error {
+	var count int64
+	if err := DB.Model(&OrganizationMember{}).
+		Where("organization_id = ? AND user_id = ?", member.OrganizationId, member.UserId).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrOrganizationMemberExists
+	}
+	return DB.Create(member).Error
+}
+
+// Update 更新成员角色与额度上限；used_quota 由预扣与结算并发累加，仅在重置时写回
+func (m *OrganizationMember) Update(resetUsedQuota bool) error {
+	columns := []string{"role", "quota_limit"}
+	if resetUsedQuota {
+		m.UsedQuota = 0
+		columns = append(columns, "used_quota")
+	}
+	return DB.Model(m).Select(columns).Updates(m).Error
+}
+
+func RemoveOrganizationMember(organizationId int, userId int) error {
+	return DB.Where("organization_id = ? AND user_id = ?", organizationId, userId).Delete(&OrganizationMember{}).Error
+}
+
+// TransferOrganizationOwnership 转让所有权，原所有者降为管理员
+func TransferOrganizationOwnership(organizationId int, fromUserId int, toUserId int) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&OrganizationMember{}).
+			Where("organization_id = ? AND user_id = ?", organizationId, toUserId).
+			Update("role", OrganizationRoleOwner)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrOrganizationNotMember
+		}
+		if err := tx.Model(&OrganizationMember{}).
+			Where("organization_id = ? AND user_id = ?", organizationId, fromUserId).
+			Update("role", OrganizationRoleAdmin).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Organization{}).Where("id = ?", organizationId).Update("owner_id", toUserId).Error
+	})
+}
+
+// CheckOrganizationTokenAccess 检查用户能否将令牌绑定到组织
+func CheckOrganizationTokenAccess(organizationId int, userId int) error {
+	org, err := GetOrganizationById(organizationId)
+	if err != nil {
+		return err
+	}
+	if org.Status != OrganizationStatusEnabled {
+		return ErrOrganizationDisabled
+	}
+	_, err = GetOrganizationMember(organizationId, userId)
+	return err
+}
+
+// PreConsumeOrganizationQuota 从组织共享钱包预扣额度，同时计入成员用量并检查成员子额度。
+// amount 为 0 时仅校验组织状态与成员身份
+func PreConsumeOrganizationQuota(organizationId int, userId int, amount int) error {
+	if amount < 0 {
+		return errors.New("amount must be >= 0")
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		org := &Organization{}
+		err := tx.First(org, "id = ?", organizationId).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrOrganizationNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if org.Status != OrganizationStatusEnabled {
+			return ErrOrganizationDisabled
+		}
+		member := &OrganizationMember{}
+		err = tx.Where("organization_id = ? AND user_id = ?", organizationId, userId).First(member).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrOrganizationNotMember
+		}
+		if err != nil {
+			return err
+		}
+		if amount == 0 {
+			if org.Quota <= 0 {
+				return ErrOrganizationQuotaInsufficient
+			}
+			if member.QuotaLimit > 0 && member.UsedQuota > member.QuotaLimit {
+				return ErrOrganizationMemberQuotaExceeded
+			}
+			return nil
+		}
+		// 以余额与子额度为条件原子扣减，并发预扣时不会超额
+		result := tx.Model(&Organization{}).
+			Where("id = ? AND quota >= ?", organizationId, amount).
+			Updates(map[string]interface{}{
+				"quota":      gorm.Expr("quota - ?", amount),
+				"used_quota": gorm.Expr("used_quota + ?", amount),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrOrganizationQuotaInsufficient
+		}
+		result = tx.Model(&OrganizationMember{}).
+			Where("organization_id = ? AND user_id = ? AND (quota_limit <= 0 OR used_quota + ? <= quota_limit)", organizationId, userId, amount).
+			Update("used_quota", gorm.Expr("used_quota + ?", amount))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrOrganizationMemberQuotaExceeded
+		}
+		return nil
+	})
+}
+
+// AdjustOrganizationQuota 按差额调整组织钱包与成员用量，delta > 0 表示扣费，delta < 0 表示退还
+func AdjustOrganizationQuota(organizationId int, userId int, delta int) error {
+	if delta == 0 {
+		return nil
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		return adjustOrganizationQuotaTx(tx, organizationId, userId, delta)
+	})
+}
+
+func adjustOrganizationQuotaTx(tx *gorm.DB, organizationId int, userId int, delta int) error {
+	err := tx.Model(&Organization{}).Where("id = ?", organizationId).Updates(map[string]interface{}{
+		"quota":      gorm.Expr("quota - ?", delta),
+		"used_quota": gorm.Expr("used_quota + ?", delta),
+	}).Error
+	if err != nil {
+		return err
+	}
+	// 成员已被移除时不再记录其用量
+	return tx.Model(&OrganizationMember{}).
+		Where("organization_id = ? AND user_id = ?", organizationId, userId).
+		Update("used_quota", gorm.Expr("used_quota + ?", delta)).Error
+}
+
+// IncreaseOrganizationQuota 增加组织钱包额度，quota 为负数时减少
+func IncreaseOrganizationQuota(organizationId int, quota int) error {
+	return DB.Model(&Organization{}).Where("id = ?", organizationId).
+		Update("quota", gorm.Expr("quota + ?", quota)).Error
+}
+
+func OverrideOrganizationQuota(organizationId int, quota int) error {
+	return DB.Model(&Organization{}).Where("id = ?", organizationId).Update("quota", quota).Error
+}
+
+// TransferUserQuotaToOrganization 将用户钱包额度转入组织钱包
+func TransferUserQuotaToOrganization(userId int, organizationId int, quota int) error {
+	if quota <= 0 {
+		return errors.New("转入额度必须大于 0")
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		// 以余额为条件原子扣减，避免并发转入使用户额度为负
+		result := tx.Model(&User{}).Where("id = ? AND quota >= ?", userId, quota).Update("quota", gorm.Expr("quota - ?", quota))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			user := &User{}
+			if err := tx.Select("id", "quota").First(user, "id = ?", userId).Error; err != nil {
+				return err
+			}
+			return fmt.Errorf("用户额度不足, 剩余额度: %s", logger.FormatQuota(user.Quota))
+		}
+		result = tx.Model(&Organization{}).Where("id = ?", organizationId).Update("quota", gorm.Expr("quota + ?", quota))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrOrganizationNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	gopool.Go(func() {
+		if err := cacheDecrUserQuota(userId, int64(quota)); err != nil {
+			common.SysLog("failed to decrease user quota cache: " + err.Error())
+		}
+	})
+	return nil
+}
+
+// RecordOrganizationLog 记录组织相关的管理日志，同时出现在操作用户与组织的日志中
+func RecordOrganizationLog(organizationId int, userId int, logType int, content string) {
+	username, _ := GetUsernameById(userId, false)
+	log := &Log{
+		UserId:         userId,
+		Username:       username,
+		CreatedAt:      common.GetTimestamp(),
+		Type:           logType,
+		Content:        content,
+		OrganizationId: organizationId,
+	}
+	if err := LOG_DB.Create(log).Error; err != nil {
+		common.SysLog("failed to record organization log: " + err.Error())
+	}
+}
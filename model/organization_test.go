@@ -0,0 +1,145 @@
+package model
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreConsumeOrganizationQuota(t *testing.T) {
+	org := &Organization{Name: "acme"}
+	require.NoError(t, CreateOrganization(org, 1))
+	t.Cleanup(func() {
+		DB.Unscoped().Where("id = ?", org.Id).Delete(&Organization{})
+		DB.Where("organization_id = ?", org.Id).Delete(&OrganizationMember{})
+	})
+	require.NoError(t, IncreaseOrganizationQuota(org.Id, 1000))
+	require.NoError(t, AddOrganizationMember(&OrganizationMember{OrganizationId: org.Id, UserId: 2, Role: OrganizationRoleMember, QuotaLimit: 300}))
+	require.ErrorIs(t, AddOrganizationMember(&OrganizationMember{OrganizationId: org.Id, UserId: 2}), ErrOrganizationMemberExists)
+
+	require.ErrorIs(t, PreConsumeOrganizationQuota(org.Id, 3, 0), ErrOrganizationNotMember)
+
+	require.NoError(t, PreConsumeOrganizationQuota(org.Id, 2, 200))
+	// 结算退还差额后成员用量同步减少
+	require.NoError(t, AdjustOrganizationQuota(org.Id, 2, -150))
+	require.ErrorIs(t, PreConsumeOrganizationQuota(org.Id, 2, 260), ErrOrganizationMemberQuotaExceeded)
+	require.NoError(t, PreConsumeOrganizationQuota(org.Id, 2, 250))
+
+	// 所有者不受成员子额度限制，但受组织钱包余额限制
+	require.ErrorIs(t, PreConsumeOrganizationQuota(org.Id, 1, 800), ErrOrganizationQuotaInsufficient)
+
+	org, err := GetOrganizationById(org.Id)
+	require.NoError(t, err)
+	require.Equal(t, 700, org.Quota)
+	require.Equal(t, 300, org.UsedQuota)
+	member, err := GetOrganizationMember(org.Id, 2)
+	require.NoError(t, err)
+	require.Equal(t, 300, member.UsedQuota)
+
+	require.NoError(t, UpdateOrganizationStatus(org.Id, OrganizationStatusDisabled))
+	require.ErrorIs(t, PreConsumeOrganizationQuota(org.Id, 1, 10), ErrOrganizationDisabled)
+}
+
+func TestPreConsumeOrganizationQuotaConcurrent(t *testing.T) {
+	org := &Organization{Name: "concurrent"}
+	require.NoError(t, CreateOrganization(org, 1))
+	t.Cleanup(func() {
+		DB.Unscoped().Where("id = ?", org.Id).Delete(&Organization{})
+		DB.Where("organization_id = ?", org.Id).Delete(&OrganizationMember{})
+	})
+	require.NoError(t, IncreaseOrganizationQuota(org.Id, 1000))
+	require.NoError(t, AddOrganizationMember(&OrganizationMember{OrganizationId: org.Id, UserId: 2, Role: OrganizationRoleMember, QuotaLimit: 500}))
+
+	// 并发预扣不会超出成员子额度与组织余额
+	var memberOk, ownerOk atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if PreConsumeOrganizationQuota(org.Id, 2, 60) == nil {
+				memberOk.Add(1)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if PreConsumeOrganizationQuota(org.Id, 1, 60) == nil {
+				ownerOk.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.EqualValues(t, 8, memberOk.Load())
+	org, err := GetOrganizationById(org.Id)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, org.Quota, 0)
+	require.Equal(t, int(memberOk.Load()+ownerOk.Load())*60, org.UsedQuota)
+	require.Equal(t, 1000, org.Quota+org.UsedQuota)
+	member, err := GetOrganizationMember(org.Id, 2)
+	require.NoError(t, err)
+	require.Equal(t, 480, member.UsedQuota)
+}
+
+func TestTransferUserQuotaToOrganizationConcurrent(t *testing.T) {
+	org := &Organization{Name: "transfer"}
+	require.NoError(t, CreateOrganization(org, 1))
+	user := &User{Id: 9101, Username: "org_transfer_user", Quota: 100}
+	require.NoError(t, DB.Create(user).Error)
+	t.Cleanup(func() {
+		DB.Unscoped().Where("id = ?", org.Id).Delete(&Organization{})
+		DB.Where("organization_id = ?", org.Id).Delete(&OrganizationMember{})
+		DB.Unscoped().Delete(&User{}, user.Id)
+	})
+
+	// 并发转入不会使用户额度为负
+	var succeeded atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if TransferUserQuotaToOrganization(user.Id, org.Id, 30) == nil {
+				succeeded.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.EqualValues(t, 3, succeeded.Load())
+	require.NoError(t, DB.First(user, user.Id).Error)
+	require.Equal(t, 10, user.Quota)
+	org, err := GetOrganizationById(org.Id)
+	require.NoError(t, err)
+	require.Equal(t, 90, org.Quota)
+	require.Error(t, TransferUserQuotaToOrganization(user.Id, org.Id, 30))
+}
+
+func TestOrganizationMemberUpdateKeepsUsedQuota(t *testing.T) {
+	org := &Organization{Name: "member-update"}
+	require.NoError(t, CreateOrganization(org, 1))
+	t.Cleanup(func() {
+		DB.Unscoped().Where("id = ?", org.Id).Delete(&Organization{})
+		DB.Where("organization_id = ?", org.Id).Delete(&OrganizationMember{})
+	})
+	require.NoError(t, IncreaseOrganizationQuota(org.Id, 1000))
+	require.NoError(t, AddOrganizationMember(&OrganizationMember{OrganizationId: org.Id, UserId: 2, Role: OrganizationRoleMember, QuotaLimit: 500}))
+
+	// 读取成员后发生的消耗不会被过期的 UsedQuota 覆盖
+	member, err := GetOrganizationMember(org.Id, 2)
+	require.NoError(t, err)
+	require.NoError(t, PreConsumeOrganizationQuota(org.Id, 2, 120))
+	member.QuotaLimit = 800
+	require.NoError(t, member.Update(false))
+	member, err = GetOrganizationMember(org.Id, 2)
+	require.NoError(t, err)
+	require.Equal(t, 800, member.QuotaLimit)
+	require.Equal(t, 120, member.UsedQuota)
+
+	require.NoError(t, member.Update(true))
+	member, err = GetOrganizationMember(org.Id, 2)
+	require.NoError(t, err)
+	require.Equal(t, 0, member.UsedQuota)
+}
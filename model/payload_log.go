@@ -0,0 +1,73 @@
+package model
+
+import (
+	"context"
+)
+
+// PayloadLog 请求/响应载荷记录，与 logs 表通过 request_id 关联，同一请求重试时每次尝试各一条
+type PayloadLog struct {
+	Id                int    `json:"id"`
+	RequestId         string `json:"request_id" gorm:"type:varchar(64);index"`
+	CreatedAt         int64  `json:"created_at" gorm:"bigint;index"`
+	UserId            int    `json:"user_id" gorm:"index"`
+	TokenId           int    `json:"token_id" gorm:"default:0"`
+	ChannelId         int    `json:"channel_id" gorm:"default:0"`
+	Group             string `json:"group" gorm:"type:varchar(64);default:''"`
+	ModelName         string `json:"model_name" gorm:"type:varchar(255);default:''"`
+	RequestPath       string `json:"request_path" gorm:"type:varchar(255);default:''"`
+	IsStream          bool   `json:"is_stream"`
+	StatusCode        int    `json:"status_code"`
+	RequestBody       string `json:"request_body" gorm:"type:text"`
+	ResponseBody      string `json:"response_body" gorm:"type:text"`
+	RequestTruncated  bool   `json:"request_truncated"`
+	ResponseTruncated bool   `json:"response_truncated"`
+	ErrorMessage      string `json:"error_message" gorm:"type:text"`
+}
+
+func CreatePayloadLog(payloadLog *PayloadLog) error {
+	return LOG_DB.Create(payloadLog).Error
+}
+
+// GetPayloadLogsByRequestId 按请求 ID 获取载荷记录，按尝试顺序排列
+func GetPayloadLogsByRequestId(requestId string) (payloadLogs []*PayloadLog, err error) {
+	err = LOG_DB.Where("request_id = ?", requestId).Order("id asc").Find(&payloadLogs).Error
+	return payloadLogs, err
+}
+
+// GetPayloadLogs 分页获取载荷记录列表，不返回载荷内容
+func GetPayloadLogs(userId int, requestId string, modelName string, startIdx int, num int) (payloadLogs []*PayloadLog, total int64, err error) {
+	tx := LOG_DB.Model(&PayloadLog{})
+	if userId != 0 {
+		tx = tx.Where("user_id = ?", userId)
+	}
+	if requestId != "" {
+		tx = tx.Where("request_id = ?", requestId)
+	}
+	if modelName != "" {
+		tx = tx.Where("model_name = ?", modelName)
+	}
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Omit("request_body", "response_body").Order("id desc").Limit(num).Offset(startIdx).Find(&payloadLogs).Error
+	return payloadLogs, total, err
+}
+
+// DeleteOldPayloadLogs 分批删除早于指定时间的载荷记录
+func DeleteOldPayloadLogs(ctx context.Context, targetTimestamp int64, limit int) (int64, error) {
+	var total int64 = 0
+	for {
+		if nil != ctx.Err() {
+			return total, ctx.Err()
+		}
+		result := LOG_DB.Where("created_at < ?", targetTimestamp).Limit(limit).Delete(&PayloadLog{})
+		if nil != result.Error {
+			return total, result.Error
+		}
+		total += result.RowsAffected
+		if result.RowsAffected < int64(limit) {
+			break
+		}
+	}
+	return total, nil
+}
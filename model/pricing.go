@@ -42,6 +42,8 @@ type Pricing struct {
 	ListTypes      string `json:"list_types,omitempty"`
 	ListTags       string `json:"list_tags,omitempty"`
 	PricingVersion string `json:"pricing_version,omitempty"`
+	// 分档计价规则（长上下文、时段、月用量），按顺序匹配
+	PricingTiers []*ratio_setting.PricingTier `json:"pricing_tiers,omitempty"`
 }
 
 type PricingVendor struct {
@@ -379,6 +381,9 @@ func updatePricing() {
 			audioCompletionRatio := ratio_setting.GetAudioCompletionRatio(model)
 			pricing.AudioCompletionRatio = &audioCompletionRatio
 		}
+		if tiers := ratio_setting.GetPricingTiers(model); len(tiers) > 0 {
+			pricing.PricingTiers = tiers
+		}
 		pricingMap = append(pricingMap, pricing)
 	}
 
@@ -0,0 +1,88 @@
+package model
+
+import (
+	"context"
+	"errors"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+// ResponseRecord 网关保存的 Responses API 响应，仅用于经 Chat Completions 桥接的渠道
+type ResponseRecord struct {
+	Id                 int    `json:"-" gorm:"primaryKey"`
+	ResponseId         string `json:"response_id" gorm:"type:varchar(64);uniqueIndex"`
+	UserId             int    `json:"user_id" gorm:"index"`
+	TokenId            int    `json:"token_id" gorm:"index"`
+	PreviousResponseId string `json:"previous_response_id" gorm:"type:varchar(64)"`
+	ModelName          string `json:"model_name" gorm:"type:varchar(255)"`
+	Input              string `json:"input" gorm:"type:text"`    // 本轮输入条目（JSON 数组），不含历史
+	Response           string `json:"response" gorm:"type:text"` // 完整响应对象（JSON），包含输出条目
+	CreatedAt          int64  `json:"created_at" gorm:"bigint"`
+	ExpiresAt          int64  `json:"expires_at" gorm:"bigint;index"`
+}
+
+func (r *ResponseRecord) Insert() error {
+	if r.CreatedAt == 0 {
+		r.CreatedAt = common.GetTimestamp()
+	}
+	return DB.Create(r).Error
+}
+
+// IsExpired 是否已过期，ExpiresAt 为 0 表示不过期
+func (r *ResponseRecord) IsExpired() bool {
+	return r.ExpiresAt > 0 && r.ExpiresAt <= common.GetTimestamp()
+}
+
+// GetResponseRecord 获取响应记录，仅限创建该响应的用户与令牌访问
+func GetResponseRecord(responseId string, userId int, tokenId int) (*ResponseRecord, error) {
+	if responseId == "" {
+		return nil, errors.New("response id is empty")
+	}
+	var record ResponseRecord
+	err := DB.Where("response_id = ? AND user_id = ? AND token_id = ?", responseId, userId, tokenId).First(&record).Error
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// ResponseRecordExists 响应记录是否仍存在，仅按唯一索引查询，不读取响应内容
+func ResponseRecordExists(responseId string) (bool, error) {
+	var count int64
+	err := DB.Model(&ResponseRecord{}).Where("response_id = ?", responseId).Limit(1).Count(&count).Error
+	return count > 0, err
+}
+
+// DeleteResponseRecord 删除响应记录，返回是否存在并被删除
+func DeleteResponseRecord(responseId string, userId int, tokenId int) (bool, error) {
+	result := DB.Where("response_id = ? AND user_id = ? AND token_id = ?", responseId, userId, tokenId).Delete(&ResponseRecord{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteExpiredResponseRecords 分批删除已过期的响应记录
+func DeleteExpiredResponseRecords(ctx context.Context, limit int) (int64, error) {
+	var total int64 = 0
+	for {
+		if nil != ctx.Err() {
+			return total, ctx.Err()
+		}
+		var ids []int
+		err := DB.Model(&ResponseRecord{}).Where("expires_at > 0 AND expires_at <= ?", common.GetTimestamp()).
+			Limit(limit).Pluck("id", &ids).Error
+		if err != nil {
+			return total, err
+		}
+		if len(ids) == 0 {
+			break
+		}
+		result := DB.Where("id IN ?", ids).Delete(&ResponseRecord{})
+		if nil != result.Error {
+			return total, result.Error
+		}
+		total += result.RowsAffected
+		if len(ids) < limit {
+			break
+		}
+	}
+	return total, nil
+}
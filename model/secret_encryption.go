@@ -0,0 +1,481 @@
+package model
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"gorm.io/gorm/schema"
+)
+
+// 敏感字段的信封加密：业务数据使用数据密钥（DEK）以 AES-256-GCM 加密，
+// 数据密钥再由主密钥（KEK）加密后保存在 encryption_keys 表中。
+// 轮换主密钥时只需重新包装数据密钥，无需改写业务数据。
+//
+// 密文格式：enc:v1:<数据密钥 ID>:<base64(nonce || ciphertext)>
+// 未带前缀的值视为明文，以兼容加密迁移前写入的数据。
+
+const secretCiphertextPrefix = "enc:v1:"
+
+type EncryptionKey struct {
+	Id          int    `json:"id"`
+	MasterKeyId string `json:"master_key_id" gorm:"type:varchar(32);index"`
+	WrappedKey  string `json:"-" gorm:"type:text;not null"`
+	CreatedAt   int64  `json:"created_at" gorm:"bigint"`
+	UpdatedAt   int64  `json:"updated_at" gorm:"bigint"`
+}
+
+type secretKeyring struct {
+	mu              sync.RWMutex
+	masterKeys      map[string][]byte
+	currentMasterId string
+	dataKeys        map[int][]byte
+	activeDataKeyId int
+	// 用于计算渠道密钥检索哈希的 HMAC 密钥，由最早的数据密钥派生，轮换主密钥时保持不变
+	searchHashKey []byte
+}
+
+var keyring = &secretKeyring{}
+
+func init() {
+	schema.RegisterSerializer("secret", secretSerializer{})
+}
+
+// secretSerializer 用于 gorm:"serializer:secret" 标记的字符串字段，写入时加密、读取时解密
+type secretSerializer struct{}
+
+func (secretSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var raw string
+	switch v := dbValue.(type) {
+	case nil:
+	case []byte:
+		raw = string(v)
+	case string:
+		raw = v
+	default:
+		return fmt.Errorf("unsupported secret value type: %T", dbValue)
+	}
+	plaintext, err := DecryptSecret(raw)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt %s.%s: %w", field.Schema.Table, field.DBName, err)
+	}
+	field.ReflectValueOf(ctx, dst).SetString(plaintext)
+	return nil
+}
+
+func (secretSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	plaintext, _ := fieldValue.(string)
+	return EncryptSecret(plaintext)
+}
+
+// parseMasterKey 支持 base64 或 hex 编码的 32 字节密钥，其他字符串经 SHA-256 派生
+func parseMasterKey(raw string) []byte {
+	raw = strings.TrimSpace(raw)
+	if decoded, err := base64.StdEncoding.DecodeString(raw); err == nil && len(decoded) == 32 {
+		return decoded
+	}
+	if decoded, err := hex.DecodeString(raw); err == nil && len(decoded) == 32 {
+		return decoded
+	}
+	sum := sha256.Sum256([]byte(raw))
+	return sum[:]
+}
+
+func masterKeyId(key []byte) string {
+	sum := sha256.Sum256(append([]byte("new-api-master-key:"), key...))
+	return hex.EncodeToString(sum[:8])
+}
+
+// readSecretEnv 读取环境变量，未设置时读取 <name>_FILE 指定的文件
+func readSecretEnv(name string) (string, error) {
+	if value := strings.TrimSpace(os.Getenv(name)); value != "" {
+		return value, nil
+	}
+	path := strings.TrimSpace(os.Getenv(name + "_FILE"))
+	if path == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s_FILE: %w", name, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// InitSecretEncryption 加载主密钥与数据密钥。未配置 SECRET_ENCRYPTION_KEY 时不加密，
+// 主节点发现由旧主密钥包装的数据密钥时会自动用当前主密钥重新包装。
+func InitSecretEncryption() error {
+	current, err := readSecretEnv("SECRET_ENCRYPTION_KEY")
+	if err != nil {
+		return err
+	}
+	if current == "" {
+		return nil
+	}
+	previous, err := readSecretEnv("SECRET_ENCRYPTION_PREVIOUS_KEYS")
+	if err != nil {
+		return err
+	}
+
+	currentKey := parseMasterKey(current)
+	masterKeys := map[string][]byte{masterKeyId(currentKey): currentKey}
+	for _, raw := range strings.FieldsFunc(previous, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		key := parseMasterKey(raw)
+		masterKeys[masterKeyId(key)] = key
+	}
+
+	keyring.mu.Lock()
+	keyring.masterKeys = masterKeys
+	keyring.currentMasterId = masterKeyId(currentKey)
+	keyring.dataKeys = make(map[int][]byte)
+	keyring.activeDataKeyId = 0
+	keyring.searchHashKey = nil
+	keyring.mu.Unlock()
+
+	var rows []EncryptionKey
+	if err := DB.Order("id asc").Find(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if _, err := loadDataKey(&row); err != nil {
+			return err
+		}
+	}
+	if len(rows) == 0 {
+		if _, err := createDataKey(); err != nil {
+			return err
+		}
+	}
+	if err := initSearchHashKey(); err != nil {
+		return err
+	}
+	if common.IsMasterNode {
+		rewrapped, err := RewrapDataKeys()
+		if err != nil {
+			return err
+		}
+		if rewrapped > 0 {
+			common.SysLog(fmt.Sprintf("secret encryption: rewrapped %d data keys with current master key", rewrapped))
+		}
+		filled, err := BackfillChannelKeyHashes()
+		if err != nil {
+			return err
+		}
+		if filled > 0 {
+			common.SysLog(fmt.Sprintf("secret encryption: computed key hash for %d channels", filled))
+		}
+	}
+	common.SysLog("secret encryption enabled, master key id: " + keyring.currentMasterId)
+	return nil
+}
+
+func SecretEncryptionEnabled() bool {
+	keyring.mu.RLock()
+	defer keyring.mu.RUnlock()
+	return keyring.currentMasterId != ""
+}
+
+func IsEncryptedSecret(value string) bool {
+	return strings.HasPrefix(value, secretCiphertextPrefix)
+}
+
+func wrapDataKey(dataKey []byte) (string, string, error) {
+	keyring.mu.RLock()
+	masterId := keyring.currentMasterId
+	masterKey := keyring.masterKeys[masterId]
+	keyring.mu.RUnlock()
+	wrapped, err := common.AesGcmEncrypt(masterKey, dataKey)
+	if err != nil {
+		return "", "", err
+	}
+	return masterId, base64.StdEncoding.EncodeToString(wrapped), nil
+}
+
+func loadDataKey(row *EncryptionKey) ([]byte, error) {
+	keyring.mu.RLock()
+	masterKey, ok := keyring.masterKeys[row.MasterKeyId]
+	keyring.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("data key %d is wrapped by unknown master key %s, add the old key to SECRET_ENCRYPTION_PREVIOUS_KEYS", row.Id, row.MasterKeyId)
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(row.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := common.AesGcmDecrypt(masterKey, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key %d: %w", row.Id, err)
+	}
+	keyring.mu.Lock()
+	keyring.dataKeys[row.Id] = dataKey
+	if row.Id > keyring.activeDataKeyId {
+		keyring.activeDataKeyId = row.Id
+	}
+	keyring.mu.Unlock()
+	return dataKey, nil
+}
+
+func createDataKey() (int, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return 0, err
+	}
+	masterId, wrapped, err := wrapDataKey(dataKey)
+	if err != nil {
+		return 0, err
+	}
+	now := common.GetTimestamp()
+	row := &EncryptionKey{MasterKeyId: masterId, WrappedKey: wrapped, CreatedAt: now, UpdatedAt: now}
+	if err := DB.Create(row).Error; err != nil {
+		return 0, err
+	}
+	keyring.mu.Lock()
+	keyring.dataKeys[row.Id] = dataKey
+	if row.Id > keyring.activeDataKeyId {
+		keyring.activeDataKeyId = row.Id
+	}
+	keyring.mu.Unlock()
+	return row.Id, nil
+}
+
+// getDataKey 优先使用内存缓存，缓存未命中时（例如其他节点新建了数据密钥）从数据库加载
+func getDataKey(id int) ([]byte, error) {
+	keyring.mu.RLock()
+	dataKey, ok := keyring.dataKeys[id]
+	keyring.mu.RUnlock()
+	if ok {
+		return dataKey, nil
+	}
+	var row EncryptionKey
+	if err := DB.First(&row, "id = ?", id).Error; err != nil {
+		return nil, fmt.Errorf("data key %d not found: %w", id, err)
+	}
+	return loadDataKey(&row)
+}
+
+// initSearchHashKey 以 id 最小的数据密钥派生检索哈希密钥，所有节点得到相同结果
+func initSearchHashKey() error {
+	var row EncryptionKey
+	if err := DB.Order("id asc").First(&row).Error; err != nil {
+		return err
+	}
+	dataKey, err := getDataKey(row.Id)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(append([]byte("new-api-channel-key-hash:"), dataKey...))
+	keyring.mu.Lock()
+	keyring.searchHashKey = sum[:]
+	keyring.mu.Unlock()
+	return nil
+}
+
+// ChannelKeyHash 返回渠道密钥的 HMAC，用于在密钥加密后仍能按完整密钥精确检索；未启用加密时返回空串
+func ChannelKeyHash(key string) string {
+	if key == "" {
+		return ""
+	}
+	keyring.mu.RLock()
+	hashKey := keyring.searchHashKey
+	keyring.mu.RUnlock()
+	if hashKey == nil {
+		return ""
+	}
+	return common.GenerateHMACWithKey(hashKey, key)
+}
+
+// BackfillChannelKeyHashes 为启用加密前写入、尚无检索哈希的渠道补齐 key_hash
+func BackfillChannelKeyHashes() (int, error) {
+	if !SecretEncryptionEnabled() {
+		return 0, nil
+	}
+	var channels []*Channel
+	err := DB.Select("id", "key").Where("key_hash = '' OR key_hash IS NULL").Where(commonKeyCol + " <> ''").Find(&channels).Error
+	if err != nil {
+		return 0, err
+	}
+	for _, channel := range channels {
+		err = DB.Model(&Channel{}).Where("id = ?", channel.Id).UpdateColumn("key_hash", ChannelKeyHash(channel.Key)).Error
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(channels), nil
+}
+
+// EncryptSecret 加密敏感字段；未启用加密、空值或已是密文时原样返回
+func EncryptSecret(plaintext string) (string, error) {
+	if plaintext == "" || IsEncryptedSecret(plaintext) {
+		return plaintext, nil
+	}
+	keyring.mu.RLock()
+	enabled := keyring.currentMasterId != ""
+	dataKeyId := keyring.activeDataKeyId
+	dataKey := keyring.dataKeys[dataKeyId]
+	keyring.mu.RUnlock()
+	if !enabled {
+		return plaintext, nil
+	}
+	if dataKey == nil {
+		return "", errors.New("secret encryption data key is not loaded")
+	}
+	ciphertext, err := common.AesGcmEncrypt(dataKey, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return secretCiphertextPrefix + strconv.Itoa(dataKeyId) + ":" + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// DecryptSecret 解密 EncryptSecret 的输出，明文原样返回
+func DecryptSecret(value string) (string, error) {
+	if !IsEncryptedSecret(value) {
+		return value, nil
+	}
+	if !SecretEncryptionEnabled() {
+		return "", errors.New("found encrypted secret but SECRET_ENCRYPTION_KEY is not configured")
+	}
+	idPart, payload, ok := strings.Cut(strings.TrimPrefix(value, secretCiphertextPrefix), ":")
+	if !ok {
+		return "", errors.New("malformed encrypted secret")
+	}
+	dataKeyId, err := strconv.Atoi(idPart)
+	if err != nil {
+		return "", errors.New("malformed encrypted secret")
+	}
+	dataKey, err := getDataKey(dataKeyId)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := common.AesGcmDecrypt(dataKey, ciphertext)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// RewrapDataKeys 用当前主密钥重新包装由旧主密钥包装的数据密钥，业务数据保持不变
+func RewrapDataKeys() (int, error) {
+	if !SecretEncryptionEnabled() {
+		return 0, nil
+	}
+	keyring.mu.RLock()
+	currentMasterId := keyring.currentMasterId
+	keyring.mu.RUnlock()
+
+	var rows []EncryptionKey
+	if err := DB.Where("master_key_id <> ?", currentMasterId).Find(&rows).Error; err != nil {
+		return 0, err
+	}
+	rewrapped := 0
+	for i := range rows {
+		dataKey, err := loadDataKey(&rows[i])
+		if err != nil {
+			return rewrapped, err
+		}
+		masterId, wrapped, err := wrapDataKey(dataKey)
+		if err != nil {
+			return rewrapped, err
+		}
+		// 以旧的主密钥 ID 作为条件，避免多个节点同时轮换时互相覆盖
+		result := DB.Model(&EncryptionKey{}).
+			Where("id = ? AND master_key_id = ?", rows[i].Id, rows[i].MasterKeyId).
+			Updates(map[string]interface{}{
+				"master_key_id": masterId,
+				"wrapped_key":   wrapped,
+				"updated_at":    common.GetTimestamp(),
+			})
+		if result.Error != nil {
+			return rewrapped, result.Error
+		}
+		rewrapped += int(result.RowsAffected)
+	}
+	return rewrapped, nil
+}
+
+type rawSecretRow struct {
+	Id    string
+	Value string
+}
+
+// EncryptExistingSecrets 将加密迁移前写入的明文敏感字段加密，返回各表加密的行数。
+// 直接读写原始列，绕过序列化器，可重复执行。
+func EncryptExistingSecrets() (map[string]int, error) {
+	if !SecretEncryptionEnabled() {
+		return nil, errors.New("SECRET_ENCRYPTION_KEY is not configured")
+	}
+	targets := []struct {
+		table    string
+		idColumn string
+		column   string
+		filter   func(id string) bool
+	}{
+		{"channels", "id", commonKeyCol, nil},
+		{"custom_oauth_providers", "id", "client_secret", nil},
+		{"options", commonKeyCol, "value", IsSecretOptionKey},
+	}
+	result := make(map[string]int, len(targets))
+	for _, target := range targets {
+		var rows []rawSecretRow
+		err := DB.Table(target.table).
+			Select(target.idColumn + " AS id, " + target.column + " AS value").
+			Scan(&rows).Error
+		if err != nil {
+			return result, fmt.Errorf("failed to read %s: %w", target.table, err)
+		}
+		count := 0
+		for _, row := range rows {
+			if row.Value == "" || IsEncryptedSecret(row.Value) {
+				continue
+			}
+			if target.filter != nil && !target.filter(row.Id) {
+				continue
+			}
+			encrypted, err := EncryptSecret(row.Value)
+			if err != nil {
+				return result, err
+			}
+			err = DB.Table(target.table).
+				Where(target.idColumn+" = ?", row.Id).
+				Update(strings.Trim(target.column, "`\""), encrypted).Error
+			if err != nil {
+				return result, fmt.Errorf("failed to encrypt %s %s: %w", target.table, row.Id, err)
+			}
+			count++
+		}
+		result[target.table] = count
+	}
+	if _, err := BackfillChannelKeyHashes(); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// UpdateChannelKey 仅更新渠道密钥列及其检索哈希；按列更新不会经过序列化器，因此需显式加密
+func UpdateChannelKey(id int, key string) error {
+	encrypted, err := EncryptSecret(key)
+	if err != nil {
+		return err
+	}
+	return DB.Model(&Channel{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"key":      encrypted,
+		"key_hash": ChannelKeyHash(key),
+	}).Error
+}
@@ -0,0 +1,157 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/stretchr/testify/require"
+)
+
+func resetSecretKeyring(t *testing.T) {
+	t.Helper()
+	t.Cleanup(func() {
+		keyring.mu.Lock()
+		keyring.masterKeys = nil
+		keyring.currentMasterId = ""
+		keyring.dataKeys = nil
+		keyring.activeDataKeyId = 0
+		keyring.searchHashKey = nil
+		keyring.mu.Unlock()
+		DB.Exec("DELETE FROM encryption_keys")
+		DB.Exec("DELETE FROM channels")
+		DB.Exec("DELETE FROM options")
+	})
+}
+
+func rawChannelKey(t *testing.T, id int) string {
+	t.Helper()
+	var raw string
+	require.NoError(t, DB.Table("channels").Select(commonKeyCol).Where("id = ?", id).Scan(&raw).Error)
+	return raw
+}
+
+func TestSecretEncryptionRoundTripAndRotation(t *testing.T) {
+	initCol()
+	resetSecretKeyring(t)
+	isMasterNode := common.IsMasterNode
+	common.IsMasterNode = true
+	t.Cleanup(func() { common.IsMasterNode = isMasterNode })
+
+	// 未启用加密时按明文写入
+	plain := &Channel{Name: "plain", Key: "sk-plain"}
+	require.NoError(t, DB.Create(plain).Error)
+	require.Equal(t, "sk-plain", rawChannelKey(t, plain.Id))
+
+	t.Setenv("SECRET_ENCRYPTION_KEY", "master-key-1")
+	require.NoError(t, InitSecretEncryption())
+
+	channel := &Channel{Name: "secret", Key: "sk-secret"}
+	require.NoError(t, DB.Create(channel).Error)
+	require.Equal(t, "sk-secret", channel.Key)
+	require.True(t, IsEncryptedSecret(rawChannelKey(t, channel.Id)))
+
+	loaded, err := GetChannelById(channel.Id, true)
+	require.NoError(t, err)
+	require.Equal(t, "sk-secret", loaded.Key)
+
+	// 迁移命令加密历史明文，且可重复执行
+	result, err := EncryptExistingSecrets()
+	require.NoError(t, err)
+	require.Equal(t, 1, result["channels"])
+	require.True(t, IsEncryptedSecret(rawChannelKey(t, plain.Id)))
+	result, err = EncryptExistingSecrets()
+	require.NoError(t, err)
+	require.Equal(t, 0, result["channels"])
+
+	// 敏感配置项加密保存，普通配置项保持明文
+	require.NoError(t, DB.Save(&Option{Key: "StripeApiSecret", Value: "sk_live_x"}).Error)
+	require.NoError(t, DB.Save(&Option{Key: "SystemName", Value: "New API"}).Error)
+	options, err := AllOption()
+	require.NoError(t, err)
+	values := make(map[string]string)
+	for _, option := range options {
+		values[option.Key] = option.Value
+	}
+	require.Equal(t, "sk_live_x", values["StripeApiSecret"])
+	var rawOption string
+	require.NoError(t, DB.Table("options").Select("value").Where(commonKeyCol+" = ?", "StripeApiSecret").Scan(&rawOption).Error)
+	require.True(t, IsEncryptedSecret(rawOption))
+	require.NoError(t, DB.Table("options").Select("value").Where(commonKeyCol+" = ?", "SystemName").Scan(&rawOption).Error)
+	require.Equal(t, "New API", rawOption)
+
+	// 轮换主密钥只重新包装数据密钥，业务密文不变
+	before := rawChannelKey(t, channel.Id)
+	t.Setenv("SECRET_ENCRYPTION_KEY", "master-key-2")
+	t.Setenv("SECRET_ENCRYPTION_PREVIOUS_KEYS", "master-key-1")
+	require.NoError(t, InitSecretEncryption())
+	require.Equal(t, before, rawChannelKey(t, channel.Id))
+	loaded, err = GetChannelById(channel.Id, true)
+	require.NoError(t, err)
+	require.Equal(t, "sk-secret", loaded.Key)
+
+	// 移除旧主密钥后仍可启动
+	t.Setenv("SECRET_ENCRYPTION_PREVIOUS_KEYS", "")
+	require.NoError(t, InitSecretEncryption())
+	require.NoError(t, UpdateChannelKey(channel.Id, "sk-rotated"))
+	require.True(t, IsEncryptedSecret(rawChannelKey(t, channel.Id)))
+	loaded, err = GetChannelById(channel.Id, true)
+	require.NoError(t, err)
+	require.Equal(t, "sk-rotated", loaded.Key)
+
+	// 错误的主密钥无法解包数据密钥
+	t.Setenv("SECRET_ENCRYPTION_KEY", "wrong-key")
+	require.Error(t, InitSecretEncryption())
+}
+
+func TestSearchChannelsByKeyWithEncryption(t *testing.T) {
+	initCol()
+	resetSecretKeyring(t)
+	isMasterNode := common.IsMasterNode
+	common.IsMasterNode = true
+	t.Cleanup(func() { common.IsMasterNode = isMasterNode })
+
+	searchIds := func(keyword string) []int {
+		t.Helper()
+		channels, err := SearchChannels(keyword, "", "", false)
+		require.NoError(t, err)
+		ids := make([]int, 0, len(channels))
+		for _, channel := range channels {
+			ids = append(ids, channel.Id)
+		}
+		return ids
+	}
+
+	// 启用加密前写入的明文渠道，启动时补齐检索哈希
+	plain := &Channel{Name: "plain", Key: "sk-search-plain"}
+	require.NoError(t, DB.Create(plain).Error)
+	require.Equal(t, []int{plain.Id}, searchIds("sk-search-plain"))
+
+	t.Setenv("SECRET_ENCRYPTION_KEY", "master-key-1")
+	require.NoError(t, InitSecretEncryption())
+	result, err := EncryptExistingSecrets()
+	require.NoError(t, err)
+	require.Equal(t, 1, result["channels"])
+	require.Equal(t, []int{plain.Id}, searchIds("sk-search-plain"))
+
+	channel := &Channel{Name: "secret", Key: "sk-search-secret"}
+	require.NoError(t, DB.Create(channel).Error)
+	require.True(t, IsEncryptedSecret(rawChannelKey(t, channel.Id)))
+	require.Equal(t, []int{channel.Id}, searchIds("sk-search-secret"))
+	require.Empty(t, searchIds("sk-search-missing"))
+
+	// 各种更新密钥的方式都同步检索哈希
+	require.NoError(t, UpdateChannelKey(channel.Id, "sk-search-updated"))
+	require.Empty(t, searchIds("sk-search-secret"))
+	require.Equal(t, []int{channel.Id}, searchIds("sk-search-updated"))
+
+	channel.Key = "sk-search-fields"
+	require.NoError(t, DB.Model(channel).Updates(channel).Error)
+	require.Equal(t, []int{channel.Id}, searchIds("sk-search-fields"))
+
+	// 轮换主密钥后检索哈希保持不变
+	t.Setenv("SECRET_ENCRYPTION_KEY", "master-key-2")
+	t.Setenv("SECRET_ENCRYPTION_PREVIOUS_KEYS", "master-key-1")
+	require.NoError(t, InitSecretEncryption())
+	require.Equal(t, []int{channel.Id}, searchIds("sk-search-fields"))
+	require.Equal(t, []int{plain.Id}, searchIds("sk-search-plain"))
+}
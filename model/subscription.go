@@ -309,7 +309,16 @@ func calcNextResetTime(base time.Time, plan *SubscriptionPlan, endUnix int64) in
 	if plan == nil {
 		return 0
 	}
-	period := NormalizeResetPeriod(plan.QuotaResetPeriod)
+	next := calcNextPeriodResetTime(base, plan.QuotaResetPeriod, plan.QuotaResetCustomSeconds)
+	if endUnix > 0 && next > endUnix {
+		return 0
+	}
+	return next
+}
+
+// calcNextPeriodResetTime 计算 base 之后的下一个重置时间点（按自然日 / 周一 / 月初对齐），never 返回 0
+func calcNextPeriodResetTime(base time.Time, period string, customSeconds int64) int64 {
+	period = NormalizeResetPeriod(period)
 	if period == SubscriptionResetNever {
 		return 0
 	}
@@ -333,16 +342,13 @@ func calcNextResetTime(base time.Time, plan *SubscriptionPlan, endUnix int64) in
 		next = time.Date(base.Year(), base.Month(), 1, 0, 0, 0, 0, base.Location()).
 			AddDate(0, 1, 0)
 	case SubscriptionResetCustom:
-		if plan.QuotaResetCustomSeconds <= 0 {
+		if customSeconds <= 0 {
 			return 0
 		}
-		next = base.Add(time.Duration(plan.QuotaResetCustomSeconds) * time.Second)
+		next = base.Add(time.Duration(customSeconds) * time.Second)
 	default:
 		return 0
 	}
-	if endUnix > 0 && next.Unix() > endUnix {
-		return 0
-	}
 	return next.Unix()
 }
 
@@ -697,6 +703,18 @@ func GetAllUserSubscriptions(userId int) ([]SubscriptionSummary, error) {
 	return buildSubscriptionSummaries(subs), nil
 }
 
+// GetUserSubscriptionById returns a single user subscription record.
+func GetUserSubscriptionById(id int) (*UserSubscription, error) {
+	if id <= 0 {
+		return nil, errors.New("invalid userSubscriptionId")
+	}
+	var sub UserSubscription
+	if err := DB.Where("id = ?", id).First(&sub).Error; err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
 func buildSubscriptionSummaries(subs []UserSubscription) []SubscriptionSummary {
 	if len(subs) == 0 {
 		return []SubscriptionSummary{}
@@ -822,13 +840,13 @@ func ExpireDueSubscriptions(limit int) (int, error) {
 		return 0, nil
 	}
 	expiredCount := 0
-	userIds := make(map[int]struct{}, len(subs))
+	userSubs := make(map[int][]UserSubscription, len(subs))
 	for _, sub := range subs {
 		if sub.UserId > 0 {
-			userIds[sub.UserId] = struct{}{}
+			userSubs[sub.UserId] = append(userSubs[sub.UserId], sub)
 		}
 	}
-	for userId := range userIds {
+	for userId := range userSubs {
 		cacheGroup := ""
 		err := DB.Transaction(func(tx *gorm.DB) error {
 			res := tx.Model(&UserSubscription{}).
@@ -888,6 +906,16 @@ func ExpireDueSubscriptions(limit int) (int, error) {
 		if cacheGroup != "" {
 			_ = UpdateUserGroupCache(userId, cacheGroup)
 		}
+		for _, sub := range userSubs[userId] {
+			publishWebhookEvent(WebhookEventSubscriptionExpired, userId, map[string]interface{}{
+				"user_id":         userId,
+				"subscription_id": sub.Id,
+				"plan_id":         sub.PlanId,
+				"amount_total":    sub.AmountTotal,
+				"amount_used":     sub.AmountUsed,
+				"end_time":        sub.EndTime,
+			})
+		}
 	}
 	return expiredCount, nil
 }
@@ -10,6 +10,8 @@ import (
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
 	commonRelay "github.com/QuantumNous/new-api/relay/common"
+
+	"gorm.io/gorm"
 )
 
 type TaskStatus string
@@ -101,8 +103,9 @@ type TaskPrivateData struct {
 	UpstreamTaskID string `json:"upstream_task_id,omitempty"` // 上游真实 task ID
 	ResultURL      string `json:"result_url,omitempty"`       // 任务成功后的结果 URL（视频地址等）
 	// 计费上下文：用于异步退款/差额结算（轮询阶段读取）
-	BillingSource  string              `json:"billing_source,omitempty"`  // "wallet" 或 "subscription"
+	BillingSource  string              `json:"billing_source,omitempty"`  // "wallet"、"subscription" 或 "organization"
 	SubscriptionId int                 `json:"subscription_id,omitempty"` // 订阅 ID，用于订阅退款
+	OrganizationId int                 `json:"organization_id,omitempty"` // 组织 ID，用于组织钱包退款
 	TokenId        int                 `json:"token_id,omitempty"`        // 令牌 ID，用于令牌额度退款
 	BillingContext *TaskBillingContext `json:"billing_context,omitempty"` // 计费参数快照（用于轮询阶段重新计算）
 }
@@ -173,8 +176,10 @@ func InitTask(platform constant.TaskPlatform, relayInfo *commonRelay.RelayInfo)
 	properties := Properties{}
 	privateData := TaskPrivateData{}
 	if relayInfo != nil && relayInfo.ChannelMeta != nil {
+		// 微调任务归属于创建它的 key（组织），后续查询需使用同一 key
 		if relayInfo.ChannelMeta.ChannelType == constant.ChannelTypeGemini ||
-			relayInfo.ChannelMeta.ChannelType == constant.ChannelTypeVertexAi {
+			relayInfo.ChannelMeta.ChannelType == constant.ChannelTypeVertexAi ||
+			platform == constant.TaskPlatformFineTune {
 			privateData.Key = relayInfo.ChannelMeta.ApiKey
 		}
 		if relayInfo.UpstreamModelName != "" {
@@ -293,6 +298,7 @@ func GetTimedOutUnfinishedTasks(cutoffUnix int64, limit int) []*Task {
 	var tasks []*Task
 	err := DB.Where("progress != ?", "100%").
 		Where("status NOT IN ?", []string{TaskStatusFailure, TaskStatusSuccess}).
+		Where("platform <> ?", constant.TaskPlatformFineTune). // 微调任务耗时较长，终态以上游为准
 		Where("submit_time < ?", cutoffUnix).
 		Order("submit_time").
 		Limit(limit).
@@ -506,3 +512,30 @@ func (t *Task) ToOpenAIVideo() *dto.OpenAIVideo {
 	openAIVideo.SetMetadata("url", t.GetResultURL())
 	return openAIVideo
 }
+
+// ListUserTasksByPlatform 按 OpenAI 的游标分页方式列出用户指定平台的任务（按创建时间倒序）
+func ListUserTasksByPlatform(userId int, platform constant.TaskPlatform, after string, limit int) (tasks []*Task, hasMore bool, err error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	query := DB.Where("user_id = ? AND platform = ?", userId, platform)
+	if after != "" {
+		cursor, exist, err := GetByTaskId(userId, after)
+		if err != nil {
+			return nil, false, err
+		}
+		if !exist {
+			return nil, false, gorm.ErrRecordNotFound
+		}
+		query = query.Where("id < ?", cursor.ID)
+	}
+	err = query.Order("id desc").Limit(limit + 1).Find(&tasks).Error
+	if err != nil {
+		return nil, false, err
+	}
+	if len(tasks) > limit {
+		tasks = tasks[:limit]
+		hasMore = true
+	}
+	return tasks, hasMore, nil
+}
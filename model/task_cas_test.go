@@ -33,7 +33,7 @@ func TestMain(m *testing.M) {
 	}
 	sqlDB.SetMaxOpenConns(1)
 
-	if err := db.AutoMigrate(&Task{}, &User{}, &Token{}, &Log{}, &Channel{}); err != nil {
+	if err := db.AutoMigrate(&Task{}, &User{}, &Token{}, &Log{}, &Channel{}, &TokenBudget{}, &Organization{}, &OrganizationMember{}, &UserMonthlyUsage{}, &TopUp{}, &Redemption{}, &SubscriptionOrder{}, &SubscriptionPlan{}, &UserStatement{}, &Option{}, &CustomOAuthProvider{}, &EncryptionKey{}, &ChannelKeyUsage{}); err != nil {
 		panic("failed to migrate: " + err.Error())
 	}
 
@@ -12,24 +12,32 @@ import (
 )
 
 type Token struct {
-	Id                 int            `json:"id"`
-	UserId             int            `json:"user_id" gorm:"index"`
-	Key                string         `json:"key" gorm:"type:char(48);uniqueIndex"`
-	Status             int            `json:"status" gorm:"default:1"`
-	Name               string         `json:"name" gorm:"index" `
-	CreatedTime        int64          `json:"created_time" gorm:"bigint"`
-	AccessedTime       int64          `json:"accessed_time" gorm:"bigint"`
-	ExpiredTime        int64          `json:"expired_time" gorm:"bigint;default:-1"` // -1 means never expired
-	RemainQuota        int            `json:"remain_quota" gorm:"default:0"`
-	UnlimitedQuota     bool           `json:"unlimited_quota"`
-	ModelLimitsEnabled bool           `json:"model_limits_enabled"`
-	ModelLimits        string         `json:"model_limits" gorm:"type:text"`
-	AllowIps           *string        `json:"allow_ips" gorm:"default:''"`
-	UsedQuota          int            `json:"used_quota" gorm:"default:0"` // used quota
-	Group              string         `json:"group" gorm:"default:''"`
-	CrossGroupRetry    bool           `json:"cross_group_retry"`                  // 跨分组重试，仅auto分组有效
-	Groups             string         `json:"groups" gorm:"type:text;default:''"` // 多分组优先级配置，JSON数组，为空时使用系统autoGroups
-	DeletedAt          gorm.DeletedAt `gorm:"index"`
+	Id                  int            `json:"id"`
+	UserId              int            `json:"user_id" gorm:"index"`
+	Key                 string         `json:"key" gorm:"type:char(48);uniqueIndex"`
+	Status              int            `json:"status" gorm:"default:1"`
+	Name                string         `json:"name" gorm:"index" `
+	CreatedTime         int64          `json:"created_time" gorm:"bigint"`
+	AccessedTime        int64          `json:"accessed_time" gorm:"bigint"`
+	ExpiredTime         int64          `json:"expired_time" gorm:"bigint;default:-1"` // -1 means never expired
+	RemainQuota         int            `json:"remain_quota" gorm:"default:0"`
+	UnlimitedQuota      bool           `json:"unlimited_quota"`
+	ModelLimitsEnabled  bool           `json:"model_limits_enabled"`
+	ModelLimits         string         `json:"model_limits" gorm:"type:text"`
+	AllowIps            *string        `json:"allow_ips" gorm:"default:''"`
+	UsedQuota           int            `json:"used_quota" gorm:"default:0"` // used quota
+	Group               string         `json:"group" gorm:"default:''"`
+	CrossGroupRetry     bool           `json:"cross_group_retry"`                      // 跨分组重试，仅auto分组有效
+	Groups              string         `json:"groups" gorm:"type:text;default:''"`     // 多分组优先级配置，JSON数组，为空时使用系统autoGroups
+	RpmLimit            int            `json:"rpm_limit" gorm:"default:0"`             // 每分钟请求数限制，0 表示不限制
+	TpmLimit            int            `json:"tpm_limit" gorm:"default:0"`             // 每分钟 token 数限制，0 表示不限制
+	MaxInFlight         int            `json:"max_in_flight" gorm:"default:0"`         // 最大并发请求数，0 表示不限制
+	DailyQuotaLimit     int            `json:"daily_quota_limit" gorm:"default:0"`     // 每日预算（额度），0 表示不限制
+	WeeklyQuotaLimit    int            `json:"weekly_quota_limit" gorm:"default:0"`    // 每周预算（额度），0 表示不限制
+	MonthlyQuotaLimit   int            `json:"monthly_quota_limit" gorm:"default:0"`   // 每月预算（额度），0 表示不限制
+	BudgetNotifyEnabled bool           `json:"budget_notify_enabled"`                  // 预算用量达到 80% / 100% 时通知用户
+	OrganizationId      int            `json:"organization_id" gorm:"index;default:0"` // 所属组织，非 0 时从组织共享钱包扣费
+	DeletedAt           gorm.DeletedAt `gorm:"index"`
 }
 
 func (token *Token) Clean() {
@@ -296,7 +304,10 @@ func (token *Token) Update() (err error) {
 		}
 	}()
 	err = DB.Model(token).Select("name", "status", "expired_time", "remain_quota", "unlimited_quota",
-		"model_limits_enabled", "model_limits", "allow_ips", "group", "cross_group_retry", "groups").Updates(token).Error
+		"model_limits_enabled", "model_limits", "allow_ips", "group", "cross_group_retry", "groups",
+		"rpm_limit", "tpm_limit", "max_in_flight",
+		"daily_quota_limit", "weekly_quota_limit", "monthly_quota_limit", "budget_notify_enabled",
+		"organization_id").Updates(token).Error
 	return err
 }
 
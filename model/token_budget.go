@@ -0,0 +1,323 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 令牌预算周期，与订阅的重置周期保持一致（按自然日 / 周一 / 月初对齐）
+var tokenBudgetPeriods = []string{
+	SubscriptionResetDaily,
+	SubscriptionResetWeekly,
+	SubscriptionResetMonthly,
+}
+
+// 预算用量达到以下百分比时通知用户
+var tokenBudgetNotifyPercents = []int{80, 100}
+
+// TokenBudget 令牌在某个预算周期内的用量
+type TokenBudget struct {
+	Id              int    `json:"id"`
+	TokenId         int    `json:"token_id" gorm:"uniqueIndex:idx_token_budget_period,priority:1"`
+	Period          string `json:"period" gorm:"type:varchar(16);uniqueIndex:idx_token_budget_period,priority:2"`
+	UsedQuota       int64  `json:"used_quota" gorm:"type:bigint;not null;default:0"`
+	LastResetTime   int64  `json:"last_reset_time" gorm:"bigint"`
+	NextResetTime   int64  `json:"next_reset_time" gorm:"bigint"`
+	NotifiedPercent int    `json:"notified_percent" gorm:"default:0"` // 当前周期已通知的最高百分比
+	UpdatedAt       int64  `json:"updated_at" gorm:"bigint"`
+}
+
+func (b *TokenBudget) BeforeSave(tx *gorm.DB) error {
+	b.UpdatedAt = common.GetTimestamp()
+	return nil
+}
+
+// refresh 周期已过时重置用量，返回是否发生了重置
+func (b *TokenBudget) refresh(now time.Time) bool {
+	if b.NextResetTime > now.Unix() {
+		return false
+	}
+	next := calcNextPeriodResetTime(now, b.Period, 0)
+	b.UsedQuota = 0
+	b.NotifiedPercent = 0
+	b.LastResetTime = tokenBudgetPeriodStart(next, b.Period)
+	b.NextResetTime = next
+	return true
+}
+
+// tokenBudgetPeriodStart 根据下一次重置时间推算当前周期的开始时间
+func tokenBudgetPeriodStart(next int64, period string) int64 {
+	t := time.Unix(next, 0)
+	switch period {
+	case SubscriptionResetDaily:
+		return t.AddDate(0, 0, -1).Unix()
+	case SubscriptionResetWeekly:
+		return t.AddDate(0, 0, -7).Unix()
+	case SubscriptionResetMonthly:
+		return t.AddDate(0, -1, 0).Unix()
+	}
+	return 0
+}
+
+// GetBudgetLimit 获取令牌在指定周期内的预算，0 表示不限制
+func (token *Token) GetBudgetLimit(period string) int {
+	switch period {
+	case SubscriptionResetDaily:
+		return token.DailyQuotaLimit
+	case SubscriptionResetWeekly:
+		return token.WeeklyQuotaLimit
+	case SubscriptionResetMonthly:
+		return token.MonthlyQuotaLimit
+	}
+	return 0
+}
+
+func (token *Token) HasBudget() bool {
+	return token.DailyQuotaLimit > 0 || token.WeeklyQuotaLimit > 0 || token.MonthlyQuotaLimit > 0
+}
+
+// TokenBudgetStatus 令牌当前周期的预算使用情况
+type TokenBudgetStatus struct {
+	Period        string  `json:"period"`
+	QuotaLimit    int     `json:"quota_limit"`
+	UsedQuota     int64   `json:"used_quota"`
+	RemainQuota   int64   `json:"remain_quota"`
+	Percent       float64 `json:"percent"`
+	LastResetTime int64   `json:"last_reset_time"`
+	NextResetTime int64   `json:"next_reset_time"`
+}
+
+func (s *TokenBudgetStatus) Exceeded() bool {
+	return s.UsedQuota >= int64(s.QuotaLimit)
+}
+
+func newTokenBudgetStatus(budget *TokenBudget, limit int) *TokenBudgetStatus {
+	status := &TokenBudgetStatus{
+		Period:        budget.Period,
+		QuotaLimit:    limit,
+		UsedQuota:     budget.UsedQuota,
+		RemainQuota:   int64(limit) - budget.UsedQuota,
+		LastResetTime: budget.LastResetTime,
+		NextResetTime: budget.NextResetTime,
+	}
+	if status.RemainQuota < 0 {
+		status.RemainQuota = 0
+	}
+	if limit > 0 {
+		status.Percent = float64(budget.UsedQuota) * 100 / float64(limit)
+	}
+	return status
+}
+
+// GetTokenBudgetStatuses 获取令牌已配置预算的各周期使用情况
+func GetTokenBudgetStatuses(token *Token) ([]*TokenBudgetStatus, error) {
+	statuses := make([]*TokenBudgetStatus, 0, len(tokenBudgetPeriods))
+	if token == nil || !token.HasBudget() {
+		return statuses, nil
+	}
+	var budgets []TokenBudget
+	if err := DB.Where("token_id = ?", token.Id).Find(&budgets).Error; err != nil {
+		return nil, err
+	}
+	budgetMap := make(map[string]*TokenBudget, len(budgets))
+	for i := range budgets {
+		budgetMap[budgets[i].Period] = &budgets[i]
+	}
+	now := time.Now()
+	for _, period := range tokenBudgetPeriods {
+		limit := token.GetBudgetLimit(period)
+		if limit <= 0 {
+			continue
+		}
+		budget, ok := budgetMap[period]
+		if !ok {
+			budget = &TokenBudget{TokenId: token.Id, Period: period}
+		}
+		// 仅用于展示，过期的周期在下次记录用量时才会写回数据库
+		budget.refresh(now)
+		statuses = append(statuses, newTokenBudgetStatus(budget, limit))
+	}
+	return statuses, nil
+}
+
+// CheckTokenBudget 检查令牌是否已用尽某个周期的预算，用尽时返回对应周期的使用情况
+func CheckTokenBudget(token *Token) (*TokenBudgetStatus, error) {
+	statuses, err := GetTokenBudgetStatuses(token)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range statuses {
+		if status.Exceeded() {
+			return status, nil
+		}
+	}
+	return nil, nil
+}
+
+// TokenBudgetNotification 预算用量达到通知阈值
+type TokenBudgetNotification struct {
+	Token   *Token
+	Status  *TokenBudgetStatus
+	Percent int
+}
+
+// TokenBudgetNotifier 由 service 包注入，用于发送预算通知
+var TokenBudgetNotifier func(notification TokenBudgetNotification)
+
+// getTokenBudgetForUpdate 获取并锁定令牌某个周期的用量记录，不存在时创建
+// SQLite 不支持行锁，用量的变更均使用原子更新，不依赖读取到的值
+func getTokenBudgetForUpdate(tx *gorm.DB, tokenId int, period string) (*TokenBudget, error) {
+	budget := &TokenBudget{}
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_id = ? AND period = ?", tokenId, period).Limit(1).Find(budget)
+	if query.Error != nil {
+		return nil, query.Error
+	}
+	if query.RowsAffected > 0 {
+		return budget, nil
+	}
+	budget = &TokenBudget{TokenId: tokenId, Period: period}
+	budget.refresh(time.Now())
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(budget).Error; err != nil {
+		return nil, err
+	}
+	if budget.Id > 0 {
+		return budget, nil
+	}
+	// 并发创建时由另一请求创建成功，重新读取
+	budget = &TokenBudget{}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_id = ? AND period = ?", tokenId, period).First(budget).Error
+	return budget, err
+}
+
+// tokenBudgetNotifyPercent 返回用量已达到的最高通知阈值，未达到任何阈值时返回 0
+func tokenBudgetNotifyPercent(used int64, limit int) int {
+	reached := 0
+	for _, percent := range tokenBudgetNotifyPercents {
+		if used*100 >= int64(limit)*int64(percent) {
+			reached = percent
+		}
+	}
+	return reached
+}
+
+// addTokenBudgetUsage 原子地将额度变化计入某个周期的用量，返回更新后的记录
+func addTokenBudgetUsage(tx *gorm.DB, budget *TokenBudget, quota int, now time.Time) error {
+	// 周期已过时重置，以原重置时间为条件保证并发请求只重置一次
+	if budget.NextResetTime <= now.Unix() {
+		reset := *budget
+		reset.refresh(now)
+		err := tx.Model(&TokenBudget{}).
+			Where("id = ? AND next_reset_time = ?", budget.Id, budget.NextResetTime).
+			Updates(map[string]interface{}{
+				"used_quota":       0,
+				"notified_percent": 0,
+				"last_reset_time":  reset.LastResetTime,
+				"next_reset_time":  reset.NextResetTime,
+			}).Error
+		if err != nil {
+			return err
+		}
+	}
+	// 退还时用量不低于 0
+	err := tx.Model(&TokenBudget{}).Where("id = ?", budget.Id).Updates(map[string]interface{}{
+		"used_quota": gorm.Expr("CASE WHEN used_quota + ? < 0 THEN 0 ELSE used_quota + ? END", quota, quota),
+		"updated_at": common.GetTimestamp(),
+	}).Error
+	if err != nil {
+		return err
+	}
+	return tx.First(budget, budget.Id).Error
+}
+
+// UpdateTokenBudgetUsage 将额度变化计入令牌的各周期预算，quota 为负数时表示退还
+func UpdateTokenBudgetUsage(token *Token, quota int) error {
+	if token == nil || !token.HasBudget() || quota == 0 {
+		return nil
+	}
+	now := time.Now()
+	var notifications []TokenBudgetNotification
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		for _, period := range tokenBudgetPeriods {
+			limit := token.GetBudgetLimit(period)
+			if limit <= 0 {
+				continue
+			}
+			budget, err := getTokenBudgetForUpdate(tx, token.Id, period)
+			if err != nil {
+				return err
+			}
+			if err := addTokenBudgetUsage(tx, budget, quota, now); err != nil {
+				return err
+			}
+			percent := tokenBudgetNotifyPercent(budget.UsedQuota, limit)
+			if percent <= budget.NotifiedPercent {
+				continue
+			}
+			// 条件更新保证同一阈值只通知一次
+			result := tx.Model(&TokenBudget{}).
+				Where("id = ? AND notified_percent < ?", budget.Id, percent).
+				Update("notified_percent", percent)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				budget.NotifiedPercent = percent
+				notifications = append(notifications, TokenBudgetNotification{
+					Token:   token,
+					Status:  newTokenBudgetStatus(budget, limit),
+					Percent: percent,
+				})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if token.BudgetNotifyEnabled && TokenBudgetNotifier != nil {
+		for _, notification := range notifications {
+			TokenBudgetNotifier(notification)
+		}
+	}
+	return nil
+}
+
+// recordTokenBudgetUsage 异步记录令牌预算用量，token 为请求上下文中已校验过的令牌，失败时仅记录日志
+func recordTokenBudgetUsage(token *Token, quota int) {
+	if token == nil || token.Id <= 0 || quota == 0 {
+		return
+	}
+	gopool.Go(func() {
+		if err := UpdateTokenBudgetUsage(token, quota); err != nil {
+			common.SysError(fmt.Sprintf("failed to update token %d budget usage: %s", token.Id, err.Error()))
+		}
+	})
+}
+
+// recordTaskTokenBudgetUsage 异步记录任务的令牌预算用量，任务无请求上下文，需按 ID 读取令牌
+func recordTaskTokenBudgetUsage(tokenId int, quota int) {
+	if tokenId <= 0 || quota == 0 {
+		return
+	}
+	gopool.Go(func() {
+		token, err := GetTokenById(tokenId)
+		if err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				common.SysError(fmt.Sprintf("failed to get token %d for budget: %s", tokenId, err.Error()))
+			}
+			return
+		}
+		if err := UpdateTokenBudgetUsage(token, quota); err != nil {
+			common.SysError(fmt.Sprintf("failed to update token %d budget usage: %s", tokenId, err.Error()))
+		}
+	})
+}
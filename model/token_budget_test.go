@@ -0,0 +1,109 @@
+package model
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateTokenBudgetUsage(t *testing.T) {
+	token := &Token{Id: 9001, UserId: 1, DailyQuotaLimit: 100, MonthlyQuotaLimit: 1000, BudgetNotifyEnabled: true}
+
+	var notified []TokenBudgetNotification
+	TokenBudgetNotifier = func(notification TokenBudgetNotification) {
+		notified = append(notified, notification)
+	}
+	t.Cleanup(func() {
+		TokenBudgetNotifier = nil
+		DB.Where("token_id = ?", token.Id).Delete(&TokenBudget{})
+	})
+
+	require.NoError(t, UpdateTokenBudgetUsage(token, 50))
+	status, err := CheckTokenBudget(token)
+	require.NoError(t, err)
+	require.Nil(t, status)
+	require.Empty(t, notified)
+
+	require.NoError(t, UpdateTokenBudgetUsage(token, 30))
+	require.Len(t, notified, 1)
+	require.Equal(t, SubscriptionResetDaily, notified[0].Status.Period)
+	require.Equal(t, 80, notified[0].Percent)
+
+	// 退还后再次达到 80% 不重复通知
+	require.NoError(t, UpdateTokenBudgetUsage(token, -10))
+	require.NoError(t, UpdateTokenBudgetUsage(token, 10))
+	require.Len(t, notified, 1)
+
+	require.NoError(t, UpdateTokenBudgetUsage(token, 20))
+	require.Len(t, notified, 2)
+	require.Equal(t, 100, notified[1].Percent)
+
+	status, err = CheckTokenBudget(token)
+	require.NoError(t, err)
+	require.NotNil(t, status)
+	require.Equal(t, SubscriptionResetDaily, status.Period)
+	require.EqualValues(t, 100, status.UsedQuota)
+	require.EqualValues(t, 0, status.RemainQuota)
+
+	statuses, err := GetTokenBudgetStatuses(token)
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	require.Equal(t, SubscriptionResetMonthly, statuses[1].Period)
+	require.EqualValues(t, 100, statuses[1].UsedQuota)
+}
+
+func TestUpdateTokenBudgetUsageConcurrent(t *testing.T) {
+	token := &Token{Id: 9002, UserId: 1, DailyQuotaLimit: 100, BudgetNotifyEnabled: true}
+
+	var mu sync.Mutex
+	notifiedPercents := make(map[int]int)
+	TokenBudgetNotifier = func(notification TokenBudgetNotification) {
+		mu.Lock()
+		defer mu.Unlock()
+		notifiedPercents[notification.Percent]++
+	}
+	t.Cleanup(func() {
+		TokenBudgetNotifier = nil
+		DB.Where("token_id = ?", token.Id).Delete(&TokenBudget{})
+	})
+
+	// 并发记录用量不丢失，每个阈值只通知一次
+	const goroutines = 20
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			defer wg.Done()
+			require.NoError(t, UpdateTokenBudgetUsage(token, 6))
+		}()
+	}
+	wg.Wait()
+
+	var budget TokenBudget
+	require.NoError(t, DB.Where("token_id = ? AND period = ?", token.Id, SubscriptionResetDaily).First(&budget).Error)
+	require.EqualValues(t, goroutines*6, budget.UsedQuota)
+	require.Equal(t, 100, budget.NotifiedPercent)
+	require.Equal(t, map[int]int{80: 1, 100: 1}, notifiedPercents)
+
+	// 退还不会使用量低于 0
+	require.NoError(t, UpdateTokenBudgetUsage(token, -1000))
+	require.NoError(t, DB.First(&budget, budget.Id).Error)
+	require.EqualValues(t, 0, budget.UsedQuota)
+}
+
+func TestTokenBudgetRefresh(t *testing.T) {
+	now := time.Date(2026, 3, 18, 15, 30, 0, 0, time.Local) // 周三
+	budget := &TokenBudget{Period: SubscriptionResetWeekly, UsedQuota: 500, NotifiedPercent: 100, NextResetTime: now.Add(-time.Hour).Unix()}
+
+	require.True(t, budget.refresh(now))
+	require.EqualValues(t, 0, budget.UsedQuota)
+	require.Equal(t, 0, budget.NotifiedPercent)
+	require.Equal(t, time.Date(2026, 3, 16, 0, 0, 0, 0, time.Local).Unix(), budget.LastResetTime)
+	require.Equal(t, time.Date(2026, 3, 23, 0, 0, 0, 0, time.Local).Unix(), budget.NextResetTime)
+
+	budget.UsedQuota = 10
+	require.False(t, budget.refresh(now))
+	require.EqualValues(t, 10, budget.UsedQuota)
+}
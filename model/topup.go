@@ -107,6 +107,7 @@ func Recharge(referenceId string, customerId string) (err error) {
 	}
 
 	RecordLog(topUp.UserId, LogTypeTopup, fmt.Sprintf("使用在线充值成功，充值金额: %v，支付金额：%d", logger.FormatQuota(int(quota)), topUp.Amount))
+	PublishTopupEvent(topUp.UserId, topUp.PaymentMethod, int(quota), topUp.Money, topUp.TradeNo)
 
 	return nil
 }
@@ -311,6 +312,7 @@ func ManualCompleteTopUp(tradeNo string) error {
 
 	// 事务外记录日志，避免阻塞
 	RecordLog(userId, LogTypeTopup, fmt.Sprintf("管理员补单成功，充值金额: %v，支付金额：%f", logger.FormatQuota(quotaToAdd), payMoney))
+	PublishTopupEvent(userId, "manual", quotaToAdd, payMoney, tradeNo)
 	return nil
 }
 func GetTopUpsBySiteId(siteId int, pageInfo *common.PageInfo) (topups []*TopUp, total int64, err error) {
@@ -397,6 +399,7 @@ func RechargeCreem(referenceId string, customerEmail string, customerName string
 	}
 
 	RecordLog(topUp.UserId, LogTypeTopup, fmt.Sprintf("使用Creem充值成功，充值额度: %v，支付金额：%.2f", quota, topUp.Money))
+	PublishTopupEvent(topUp.UserId, topUp.PaymentMethod, int(quota), topUp.Money, topUp.TradeNo)
 
 	return nil
 }
@@ -458,6 +461,7 @@ func RechargeWaffo(tradeNo string) (err error) {
 
 	if quotaToAdd > 0 {
 		RecordLog(topUp.UserId, LogTypeTopup, fmt.Sprintf("Waffo充值成功，充值额度: %v，支付金额: %.2f", logger.FormatQuota(quotaToAdd), topUp.Money))
+		PublishTopupEvent(topUp.UserId, topUp.PaymentMethod, quotaToAdd, topUp.Money, topUp.TradeNo)
 	}
 
 	return nil
@@ -0,0 +1,157 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+// 导出时每批读取的记录数，按主键游标分批读取，避免深分页与一次性加载
+const usageExportBatchSize = 1000
+
+// LogExportFilter 日志导出的过滤条件，零值表示不过滤
+type LogExportFilter struct {
+	UserId         int
+	Username       string
+	TokenId        int
+	TokenName      string
+	ModelName      string
+	ChannelId      int
+	Group          string
+	LogType        int
+	StartTimestamp int64
+	EndTimestamp   int64
+}
+
+// Validate 校验过滤条件，模型名与用户日志查询一样按 LIKE 模式匹配
+func (f *LogExportFilter) Validate() error {
+	if f.ModelName == "" {
+		return nil
+	}
+	_, err := sanitizeLikePattern(f.ModelName)
+	return err
+}
+
+func (f *LogExportFilter) apply(tx *gorm.DB) (*gorm.DB, error) {
+	if f.UserId != 0 {
+		tx = tx.Where("logs.user_id = ?", f.UserId)
+	}
+	if f.Username != "" {
+		tx = tx.Where("logs.username = ?", f.Username)
+	}
+	if f.TokenId != 0 {
+		tx = tx.Where("logs.token_id = ?", f.TokenId)
+	}
+	if f.TokenName != "" {
+		tx = tx.Where("logs.token_name = ?", f.TokenName)
+	}
+	if f.ModelName != "" {
+		modelNamePattern, err := sanitizeLikePattern(f.ModelName)
+		if err != nil {
+			return nil, err
+		}
+		tx = tx.Where("logs.model_name LIKE ? ESCAPE '!'", modelNamePattern)
+	}
+	if f.ChannelId != 0 {
+		tx = tx.Where("logs.channel_id = ?", f.ChannelId)
+	}
+	if f.Group != "" {
+		tx = tx.Where("logs."+logGroupCol+" = ?", f.Group)
+	}
+	if f.LogType != LogTypeUnknown {
+		tx = tx.Where("logs.type = ?", f.LogType)
+	}
+	if f.StartTimestamp != 0 {
+		tx = tx.Where("logs.created_at >= ?", f.StartTimestamp)
+	}
+	if f.EndTimestamp != 0 {
+		tx = tx.Where("logs.created_at <= ?", f.EndTimestamp)
+	}
+	return tx, nil
+}
+
+// ExportLogs 按过滤条件逐批读取日志并交给 fn 处理，fn 返回错误时停止导出
+func ExportLogs(filter LogExportFilter, fn func(logs []*Log) error) error {
+	lastId := 0
+	for {
+		tx, err := filter.apply(LOG_DB.Model(&Log{}))
+		if err != nil {
+			return err
+		}
+		var logs []*Log
+		err = tx.Where("logs.id > ?", lastId).
+			Order("logs.id asc").
+			Limit(usageExportBatchSize).
+			Find(&logs).Error
+		if err != nil {
+			return err
+		}
+		if len(logs) == 0 {
+			return nil
+		}
+		lastId = logs[len(logs)-1].Id
+		if err = fn(logs); err != nil {
+			return err
+		}
+		if len(logs) < usageExportBatchSize {
+			return nil
+		}
+	}
+}
+
+// ExportUserLogs 导出用户自己的日志，与用户日志接口一样清理管理员字段并重新编号，同时隐藏渠道
+func ExportUserLogs(filter LogExportFilter, fn func(logs []*Log) error) error {
+	exported := 0
+	return ExportLogs(filter, func(logs []*Log) error {
+		formatUserLogs(logs, exported)
+		for _, log := range logs {
+			log.ChannelId = 0
+		}
+		exported += len(logs)
+		return fn(logs)
+	})
+}
+
+// QuotaDataExportFilter 用量统计导出的过滤条件，quota_data 按用户、模型和小时聚合，不含令牌、渠道和分组维度
+type QuotaDataExportFilter struct {
+	UserId         int
+	Username       string
+	ModelName      string
+	StartTimestamp int64
+	EndTimestamp   int64
+}
+
+// ExportQuotaData 按过滤条件逐批读取用量统计并交给 fn 处理
+func ExportQuotaData(filter QuotaDataExportFilter, fn func(data []*QuotaData) error) error {
+	lastId := 0
+	for {
+		tx := DB.Table("quota_data").Where("id > ?", lastId)
+		if filter.UserId != 0 {
+			tx = tx.Where("user_id = ?", filter.UserId)
+		}
+		if filter.Username != "" {
+			tx = tx.Where("username = ?", filter.Username)
+		}
+		if filter.ModelName != "" {
+			tx = tx.Where("model_name = ?", filter.ModelName)
+		}
+		if filter.StartTimestamp != 0 {
+			tx = tx.Where("created_at >= ?", filter.StartTimestamp)
+		}
+		if filter.EndTimestamp != 0 {
+			tx = tx.Where("created_at <= ?", filter.EndTimestamp)
+		}
+		var data []*QuotaData
+		if err := tx.Order("id asc").Limit(usageExportBatchSize).Find(&data).Error; err != nil {
+			return err
+		}
+		if len(data) == 0 {
+			return nil
+		}
+		lastId = data[len(data)-1].Id
+		if err := fn(data); err != nil {
+			return err
+		}
+		if len(data) < usageExportBatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package model
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/common/limiter"
+	"github.com/QuantumNous/new-api/constant"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TokenUsageLimitKey(tokenId int) string {
+	return fmt.Sprintf("token:%d", tokenId)
+}
+
+func UserUsageLimitKey(userId int) string {
+	return fmt.Sprintf("user:%d", userId)
+}
+
+func ChannelUsageLimitKey(channelId int) string {
+	return fmt.Sprintf("channel:%d", channelId)
+}
+
+func (token *Token) GetUsageLimits() limiter.UsageLimits {
+	return limiter.UsageLimits{
+		RPM:         token.RpmLimit,
+		TPM:         token.TpmLimit,
+		MaxInFlight: token.MaxInFlight,
+	}
+}
+
+func (channel *Channel) GetUsageLimits() limiter.UsageLimits {
+	limits := limiter.UsageLimits{}
+	if channel.RpmLimit != nil {
+		limits.RPM = *channel.RpmLimit
+	}
+	if channel.TpmLimit != nil {
+		limits.TPM = *channel.TpmLimit
+	}
+	if channel.MaxInFlight != nil {
+		limits.MaxInFlight = *channel.MaxInFlight
+	}
+	return limits
+}
+
+// IsChannelOverUsageLimit 渠道是否已用尽 RPM / TPM / 并发额度
+func IsChannelOverUsageLimit(channelId int, limits limiter.UsageLimits) bool {
+	if !limits.Enabled() {
+		return false
+	}
+	return limiter.CheckUsage(context.Background(), ChannelUsageLimitKey(channelId), limits) != limiter.UsageAllowed
+}
+
+// channelUsageLimits 复制启用了用量限制的渠道限制，调用方需持有 channelSyncLock
+func channelUsageLimits(channelIds []int) map[int]limiter.UsageLimits {
+	limits := make(map[int]limiter.UsageLimits)
+	for _, id := range channelIds {
+		if channel, ok := channelsIDM[id]; ok {
+			if channelLimits := channel.GetUsageLimits(); channelLimits.Enabled() {
+				limits[id] = channelLimits
+			}
+		}
+	}
+	return limits
+}
+
+// filterOverUsageChannels 排除已超出用量限制的渠道，无需持有 channelSyncLock
+func filterOverUsageChannels(channelIds []int, limits map[int]limiter.UsageLimits) []int {
+	available := make([]int, 0, len(channelIds))
+	for _, id := range channelIds {
+		if channelLimits, ok := limits[id]; ok && IsChannelOverUsageLimit(id, channelLimits) {
+			continue
+		}
+		available = append(available, id)
+	}
+	return available
+}
+
+// overUsageAbilityChannelIds 查询分组模型下已超出用量限制的渠道（未启用内存缓存时使用）
+func overUsageAbilityChannelIds(group string, model string) ([]int, error) {
+	var channels []*Channel
+	abilityQuery := DB.Model(&Ability{}).Select("channel_id").Where(commonGroupCol+" = ? and model = ? and enabled = ?", group, model, true)
+	err := DB.Select("id", "rpm_limit", "tpm_limit", "max_in_flight").
+		Where("id IN (?)", abilityQuery).
+		Where("rpm_limit > 0 OR tpm_limit > 0 OR max_in_flight > 0").
+		Find(&channels).Error
+	if err != nil {
+		return nil, err
+	}
+	var ids []int
+	for _, channel := range channels {
+		if IsChannelOverUsageLimit(channel.Id, channel.GetUsageLimits()) {
+			ids = append(ids, channel.Id)
+		}
+	}
+	return ids, nil
+}
+
+// hasAvailableAbility 排除指定渠道后分组模型下是否仍有可用渠道
+func hasAvailableAbility(group string, model string, excludedChannelIds []int) bool {
+	var count int64
+	err := excludeChannels(DB.Model(&Ability{}).Where(commonGroupCol+" = ? and model = ? and enabled = ?", group, model, true), excludedChannelIds).
+		Count(&count).Error
+	return err == nil && count > 0
+}
+
+// recordUsageLimitTokens 将本次请求消耗的 token 计入令牌、用户与渠道的 TPM
+func recordUsageLimitTokens(c *gin.Context, params RecordConsumeLogParams) {
+	if c == nil {
+		return
+	}
+	tokens := params.PromptTokens + params.CompletionTokens
+	if tokens <= 0 {
+		return
+	}
+	keys := append([]string{}, common.GetContextKeyStringSlice(c, constant.ContextKeyUsageLimitKeys)...)
+	if key := common.GetContextKeyString(c, constant.ContextKeyChannelUsageLimitKey); key != "" {
+		keys = append(keys, key)
+	}
+	for _, key := range keys {
+		limiter.AddUsageTokens(context.Background(), key, tokens)
+	}
+}
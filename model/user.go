@@ -1064,6 +1064,13 @@ func GetUsernameById(id int, fromDB bool) (username string, err error) {
 	return username, nil
 }
 
+// GetUserIdByUsername 按用户名查询用户 ID，用户不存在时返回 gorm.ErrRecordNotFound
+func GetUserIdByUsername(username string) (int, error) {
+	user := &User{}
+	err := DB.Select("id").Where("username = ?", username).First(user).Error
+	return user.Id, err
+}
+
 func IsLinuxDOIdAlreadyTaken(linuxDOId string) bool {
 	var user User
 	err := DB.Unscoped().Where("linux_do_id = ?", linuxDOId).First(&user).Error
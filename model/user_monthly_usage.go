@@ -0,0 +1,71 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UserMonthlyUsage 用户在某个自然月内的累计消耗额度，用于按月用量分档计价
+type UserMonthlyUsage struct {
+	Id        int    `json:"id"`
+	UserId    int    `json:"user_id" gorm:"uniqueIndex:idx_user_monthly_usage,priority:1"`
+	Month     string `json:"month" gorm:"type:varchar(7);uniqueIndex:idx_user_monthly_usage,priority:2"` // 格式 2006-01
+	UsedQuota int64  `json:"used_quota" gorm:"type:bigint;not null;default:0"`
+	UpdatedAt int64  `json:"updated_at" gorm:"bigint"`
+}
+
+func userUsageMonth(t time.Time) string {
+	return t.Format("2006-01")
+}
+
+// GetUserMonthlyUsedQuota 获取用户本月累计消耗额度，查询失败时返回 0
+func GetUserMonthlyUsedQuota(userId int) int64 {
+	if userId <= 0 {
+		return 0
+	}
+	var usage UserMonthlyUsage
+	err := DB.Where("user_id = ? AND month = ?", userId, userUsageMonth(time.Now())).Limit(1).Find(&usage).Error
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to get user %d monthly usage: %s", userId, err.Error()))
+		return 0
+	}
+	return usage.UsedQuota
+}
+
+// IncreaseUserMonthlyUsage 将额度变化计入用户本月用量，quota 为负数时表示退还
+func IncreaseUserMonthlyUsage(userId int, quota int) error {
+	if userId <= 0 || quota == 0 {
+		return nil
+	}
+	usage := &UserMonthlyUsage{
+		UserId:    userId,
+		Month:     userUsageMonth(time.Now()),
+		UsedQuota: int64(common.Max(quota, 0)),
+		UpdatedAt: common.GetTimestamp(),
+	}
+	return DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "month"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"used_quota": gorm.Expr("used_quota + ?", quota),
+			"updated_at": usage.UpdatedAt,
+		}),
+	}).Create(usage).Error
+}
+
+// recordUserMonthlyUsage 异步记录用户月用量，失败时仅记录日志
+func recordUserMonthlyUsage(userId int, quota int) {
+	if userId <= 0 || quota == 0 {
+		return
+	}
+	gopool.Go(func() {
+		if err := IncreaseUserMonthlyUsage(userId, quota); err != nil {
+			common.SysError(fmt.Sprintf("failed to update user %d monthly usage: %s", userId, err.Error()))
+		}
+	})
+}
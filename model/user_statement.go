@@ -0,0 +1,308 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UserStatement 用户月度账单，汇总当月充值、兑换、订阅购买与按模型的消费
+type UserStatement struct {
+	Id                int     `json:"id"`
+	UserId            int     `json:"user_id" gorm:"uniqueIndex:idx_user_statement_period,priority:1"`
+	Period            string  `json:"period" gorm:"type:varchar(7);uniqueIndex:idx_user_statement_period,priority:2"` // 格式 2006-01
+	StartTime         int64   `json:"start_time" gorm:"bigint"`
+	EndTime           int64   `json:"end_time" gorm:"bigint"`
+	TopUpCount        int     `json:"topup_count"`
+	TopUpAmount       int64   `json:"topup_amount" gorm:"type:bigint"`
+	TopUpMoney        float64 `json:"topup_money"`
+	RedemptionCount   int     `json:"redemption_count"`
+	RedemptionQuota   int64   `json:"redemption_quota" gorm:"type:bigint"`
+	SubscriptionCount int     `json:"subscription_count"`
+	SubscriptionMoney float64 `json:"subscription_money"`
+	RequestCount      int64   `json:"request_count" gorm:"type:bigint"`
+	ConsumeQuota      int64   `json:"consume_quota" gorm:"type:bigint"`
+	RefundQuota       int64   `json:"refund_quota" gorm:"type:bigint"`
+	Detail            string  `json:"detail" gorm:"type:text"` // StatementDetail 的 JSON
+	EmailSent         bool    `json:"email_sent" gorm:"default:false"`
+	CreatedTime       int64   `json:"created_time" gorm:"bigint"`
+}
+
+// StatementDetail 账单明细
+type StatementDetail struct {
+	TopUps        []StatementTopUp        `json:"topups"`
+	Redemptions   []StatementRedemption   `json:"redemptions"`
+	Subscriptions []StatementSubscription `json:"subscriptions"`
+	Models        []StatementModelUsage   `json:"models"`
+}
+
+type StatementTopUp struct {
+	TradeNo       string  `json:"trade_no"`
+	PaymentMethod string  `json:"payment_method"`
+	Amount        int64   `json:"amount"`
+	Money         float64 `json:"money"`
+	CompleteTime  int64   `json:"complete_time"`
+}
+
+type StatementRedemption struct {
+	Id           int    `json:"id"`
+	Name         string `json:"name"`
+	Quota        int    `json:"quota"`
+	RedeemedTime int64  `json:"redeemed_time"`
+}
+
+type StatementSubscription struct {
+	TradeNo       string  `json:"trade_no"`
+	PlanId        int     `json:"plan_id"`
+	PlanTitle     string  `json:"plan_title"`
+	PaymentMethod string  `json:"payment_method"`
+	Money         float64 `json:"money"`
+	CompleteTime  int64   `json:"complete_time"`
+}
+
+type StatementModelUsage struct {
+	ModelName        string `json:"model_name"`
+	RequestCount     int64  `json:"request_count"`
+	PromptTokens     int64  `json:"prompt_tokens"`
+	CompletionTokens int64  `json:"completion_tokens"`
+	Quota            int64  `json:"quota"`
+	RefundQuota      int64  `json:"refund_quota"`
+}
+
+var ErrInvalidStatementPeriod = errors.New("invalid statement period")
+
+// StatementPeriodRange 返回账单周期（2006-01）的起止时间戳，结束时间为下月第一秒之前
+func StatementPeriodRange(period string) (int64, int64, error) {
+	start, err := time.ParseInLocation("2006-01", period, time.Local)
+	if err != nil {
+		return 0, 0, ErrInvalidStatementPeriod
+	}
+	return start.Unix(), start.AddDate(0, 1, 0).Unix() - 1, nil
+}
+
+// StatementPeriodOf 返回时间所在的账单周期
+func StatementPeriodOf(t time.Time) string {
+	return t.In(time.Local).Format("2006-01")
+}
+
+// BuildUserStatement 根据充值、兑换、订阅订单与消费日志计算用户账单，不写入数据库
+func BuildUserStatement(userId int, period string) (*UserStatement, error) {
+	startTime, endTime, err := StatementPeriodRange(period)
+	if err != nil {
+		return nil, err
+	}
+	statement := &UserStatement{
+		UserId:    userId,
+		Period:    period,
+		StartTime: startTime,
+		EndTime:   endTime,
+	}
+	detail := StatementDetail{
+		TopUps:        []StatementTopUp{},
+		Redemptions:   []StatementRedemption{},
+		Subscriptions: []StatementSubscription{},
+		Models:        []StatementModelUsage{},
+	}
+
+	// 订阅购买也会生成 amount 为 0 的充值记录，单独从订阅订单统计
+	var topUps []TopUp
+	err = DB.Where("user_id = ? AND status = ? AND amount > 0 AND complete_time >= ? AND complete_time <= ?",
+		userId, common.TopUpStatusSuccess, startTime, endTime).Order("complete_time asc").Find(&topUps).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, topUp := range topUps {
+		statement.TopUpCount++
+		statement.TopUpAmount += topUp.Amount
+		statement.TopUpMoney += topUp.Money
+		detail.TopUps = append(detail.TopUps, StatementTopUp{
+			TradeNo:       topUp.TradeNo,
+			PaymentMethod: topUp.PaymentMethod,
+			Amount:        topUp.Amount,
+			Money:         topUp.Money,
+			CompleteTime:  topUp.CompleteTime,
+		})
+	}
+
+	var redemptions []Redemption
+	err = DB.Unscoped().Where("used_user_id = ? AND redeemed_time >= ? AND redeemed_time <= ?", userId, startTime, endTime).
+		Order("redeemed_time asc").Find(&redemptions).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, redemption := range redemptions {
+		statement.RedemptionCount++
+		statement.RedemptionQuota += int64(redemption.Quota)
+		detail.Redemptions = append(detail.Redemptions, StatementRedemption{
+			Id:           redemption.Id,
+			Name:         redemption.Name,
+			Quota:        redemption.Quota,
+			RedeemedTime: redemption.RedeemedTime,
+		})
+	}
+
+	var orders []SubscriptionOrder
+	err = DB.Where("user_id = ? AND status = ? AND complete_time >= ? AND complete_time <= ?",
+		userId, common.TopUpStatusSuccess, startTime, endTime).Order("complete_time asc").Find(&orders).Error
+	if err != nil {
+		return nil, err
+	}
+	planTitles := make(map[int]string)
+	for _, order := range orders {
+		title, ok := planTitles[order.PlanId]
+		if !ok {
+			var plan SubscriptionPlan
+			if err := DB.Select("id", "title").Where("id = ?", order.PlanId).Limit(1).Find(&plan).Error; err == nil {
+				title = plan.Title
+			}
+			planTitles[order.PlanId] = title
+		}
+		statement.SubscriptionCount++
+		statement.SubscriptionMoney += order.Money
+		detail.Subscriptions = append(detail.Subscriptions, StatementSubscription{
+			TradeNo:       order.TradeNo,
+			PlanId:        order.PlanId,
+			PlanTitle:     title,
+			PaymentMethod: order.PaymentMethod,
+			Money:         order.Money,
+			CompleteTime:  order.CompleteTime,
+		})
+	}
+
+	var usages []struct {
+		ModelName        string
+		Type             int
+		RequestCount     int64
+		PromptTokens     int64
+		CompletionTokens int64
+		Quota            int64
+	}
+	err = LOG_DB.Model(&Log{}).
+		Select("model_name, type, count(*) as request_count, sum(prompt_tokens) as prompt_tokens, sum(completion_tokens) as completion_tokens, sum(quota) as quota").
+		Where("user_id = ? AND type IN ? AND created_at >= ? AND created_at <= ?", userId, []int{LogTypeConsume, LogTypeRefund}, startTime, endTime).
+		Group("model_name, type").Order("model_name asc").Scan(&usages).Error
+	if err != nil {
+		return nil, err
+	}
+	modelIndex := make(map[string]int)
+	for _, usage := range usages {
+		idx, ok := modelIndex[usage.ModelName]
+		if !ok {
+			idx = len(detail.Models)
+			modelIndex[usage.ModelName] = idx
+			detail.Models = append(detail.Models, StatementModelUsage{ModelName: usage.ModelName})
+		}
+		item := &detail.Models[idx]
+		if usage.Type == LogTypeRefund {
+			item.RefundQuota += usage.Quota
+			statement.RefundQuota += usage.Quota
+			continue
+		}
+		item.RequestCount += usage.RequestCount
+		item.PromptTokens += usage.PromptTokens
+		item.CompletionTokens += usage.CompletionTokens
+		item.Quota += usage.Quota
+		statement.RequestCount += usage.RequestCount
+		statement.ConsumeQuota += usage.Quota
+	}
+
+	detailBytes, err := common.Marshal(detail)
+	if err != nil {
+		return nil, err
+	}
+	statement.Detail = string(detailBytes)
+	return statement, nil
+}
+
+// IsEmpty 账单周期内没有任何充值、兑换、订阅与消费记录
+func (s *UserStatement) IsEmpty() bool {
+	return s.TopUpCount == 0 && s.RedemptionCount == 0 && s.SubscriptionCount == 0 &&
+		s.RequestCount == 0 && s.RefundQuota == 0
+}
+
+// SaveUserStatement 保存账单，同一用户同一周期重复生成时覆盖原账单（保留邮件发送状态）
+func SaveUserStatement(statement *UserStatement) error {
+	statement.CreatedTime = common.GetTimestamp()
+	return DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "period"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"start_time", "end_time", "top_up_count", "top_up_amount", "top_up_money",
+			"redemption_count", "redemption_quota", "subscription_count", "subscription_money",
+			"request_count", "consume_quota", "refund_quota", "detail", "created_time",
+		}),
+	}).Create(statement).Error
+}
+
+func GetUserStatement(userId int, period string) (*UserStatement, error) {
+	var statement UserStatement
+	err := DB.Where("user_id = ? AND period = ?", userId, period).First(&statement).Error
+	if err != nil {
+		return nil, err
+	}
+	return &statement, nil
+}
+
+// GetUserStatements 分页获取用户账单列表，不返回明细
+func GetUserStatements(userId int, startIdx int, num int) (statements []*UserStatement, total int64, err error) {
+	tx := DB.Model(&UserStatement{})
+	if userId != 0 {
+		tx = tx.Where("user_id = ?", userId)
+	}
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Omit("detail").Order("period desc, id desc").Limit(num).Offset(startIdx).Find(&statements).Error
+	return statements, total, err
+}
+
+func MarkUserStatementEmailSent(id int) error {
+	return DB.Model(&UserStatement{}).Where("id = ?", id).Update("email_sent", true).Error
+}
+
+// GetStatementCandidateUserIds 获取账单周期内有充值、兑换、订阅或消费记录的用户，且尚未生成该周期账单
+func GetStatementCandidateUserIds(period string) ([]int, error) {
+	startTime, endTime, err := StatementPeriodRange(period)
+	if err != nil {
+		return nil, err
+	}
+	userIds := make(map[int]struct{})
+	collect := func(db *gorm.DB, table string, column string, where string, args ...interface{}) error {
+		var ids []int
+		if err := db.Table(table).Distinct(column).Where(where, args...).Pluck(column, &ids).Error; err != nil {
+			return fmt.Errorf("collect statement users from %s: %w", table, err)
+		}
+		for _, id := range ids {
+			if id > 0 {
+				userIds[id] = struct{}{}
+			}
+		}
+		return nil
+	}
+	if err := collect(DB, "top_ups", "user_id", "status = ? AND complete_time >= ? AND complete_time <= ?", common.TopUpStatusSuccess, startTime, endTime); err != nil {
+		return nil, err
+	}
+	if err := collect(DB, "redemptions", "used_user_id", "redeemed_time >= ? AND redeemed_time <= ?", startTime, endTime); err != nil {
+		return nil, err
+	}
+	if err := collect(LOG_DB, "logs", "user_id", "type IN ? AND created_at >= ? AND created_at <= ?", []int{LogTypeConsume, LogTypeRefund}, startTime, endTime); err != nil {
+		return nil, err
+	}
+
+	var existing []int
+	if err := DB.Model(&UserStatement{}).Where("period = ?", period).Pluck("user_id", &existing).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range existing {
+		delete(userIds, id)
+	}
+	result := make([]int, 0, len(userIds))
+	for id := range userIds {
+		result = append(result, id)
+	}
+	return result, nil
+}
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildUserStatement(t *testing.T) {
+	const userId = 9101
+	start, _, err := StatementPeriodRange("2026-03")
+	require.NoError(t, err)
+	inPeriod := start + 3600
+	outOfPeriod := start - 3600
+
+	require.NoError(t, DB.Create(&TopUp{UserId: userId, Amount: 10, Money: 72, TradeNo: "st-1", Status: common.TopUpStatusSuccess, CompleteTime: inPeriod}).Error)
+	require.NoError(t, DB.Create(&TopUp{UserId: userId, Amount: 5, Money: 36, TradeNo: "st-2", Status: common.TopUpStatusPending, CompleteTime: inPeriod}).Error)
+	require.NoError(t, DB.Create(&TopUp{UserId: userId, Amount: 0, Money: 20, TradeNo: "st-3", Status: common.TopUpStatusSuccess, CompleteTime: inPeriod}).Error)
+	require.NoError(t, DB.Create(&Redemption{UserId: 1, Key: "statement-test-key-00000000000001", Name: "gift", Quota: 500, UsedUserId: userId, RedeemedTime: inPeriod}).Error)
+	require.NoError(t, DB.Create(&SubscriptionOrder{UserId: userId, PlanId: 1, Money: 20, TradeNo: "st-sub-1", Status: common.TopUpStatusSuccess, CompleteTime: inPeriod}).Error)
+	require.NoError(t, LOG_DB.Create(&Log{UserId: userId, Type: LogTypeConsume, ModelName: "gpt-4o", Quota: 100, PromptTokens: 10, CompletionTokens: 20, CreatedAt: inPeriod}).Error)
+	require.NoError(t, LOG_DB.Create(&Log{UserId: userId, Type: LogTypeConsume, ModelName: "gpt-4o", Quota: 50, PromptTokens: 5, CompletionTokens: 5, CreatedAt: inPeriod}).Error)
+	require.NoError(t, LOG_DB.Create(&Log{UserId: userId, Type: LogTypeRefund, ModelName: "gpt-4o", Quota: 30, CreatedAt: inPeriod}).Error)
+	require.NoError(t, LOG_DB.Create(&Log{UserId: userId, Type: LogTypeConsume, ModelName: "gpt-4o", Quota: 999, CreatedAt: outOfPeriod}).Error)
+	t.Cleanup(func() {
+		DB.Where("user_id = ?", userId).Delete(&TopUp{})
+		DB.Unscoped().Where("used_user_id = ?", userId).Delete(&Redemption{})
+		DB.Where("user_id = ?", userId).Delete(&SubscriptionOrder{})
+		DB.Where("user_id = ?", userId).Delete(&UserStatement{})
+		LOG_DB.Where("user_id = ?", userId).Delete(&Log{})
+	})
+
+	statement, err := BuildUserStatement(userId, "2026-03")
+	require.NoError(t, err)
+	require.Equal(t, 1, statement.TopUpCount)
+	require.EqualValues(t, 10, statement.TopUpAmount)
+	require.Equal(t, 1, statement.RedemptionCount)
+	require.EqualValues(t, 500, statement.RedemptionQuota)
+	require.Equal(t, 1, statement.SubscriptionCount)
+	require.EqualValues(t, 2, statement.RequestCount)
+	require.EqualValues(t, 150, statement.ConsumeQuota)
+	require.EqualValues(t, 30, statement.RefundQuota)
+
+	var detail StatementDetail
+	require.NoError(t, common.UnmarshalJsonStr(statement.Detail, &detail))
+	require.Len(t, detail.Models, 1)
+	require.EqualValues(t, 150, detail.Models[0].Quota)
+	require.EqualValues(t, 30, detail.Models[0].RefundQuota)
+
+	// 重复保存覆盖原有账单
+	require.NoError(t, SaveUserStatement(statement))
+	statement.ConsumeQuota = 1
+	require.NoError(t, SaveUserStatement(statement))
+	saved, err := GetUserStatement(userId, "2026-03")
+	require.NoError(t, err)
+	require.EqualValues(t, 1, saved.ConsumeQuota)
+
+	ids, err := GetStatementCandidateUserIds("2026-03")
+	require.NoError(t, err)
+	require.NotContains(t, ids, userId)
+}
@@ -0,0 +1,295 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+// 平台事件类型
+const (
+	WebhookEventChannelAutoDisabled         = "channel.auto_disabled"
+	WebhookEventChannelAutoEnabled          = "channel.auto_enabled"
+	WebhookEventChannelKeyExpiring          = "channel.key_expiring"
+	WebhookEventChannelKeyExpired           = "channel.key_expired"
+	WebhookEventTopupCompleted              = "topup.completed"
+	WebhookEventSubscriptionExpired         = "subscription.expired"
+	WebhookEventTokenExhausted              = "token.exhausted"
+	WebhookEventTaskFinished                = "task.finished"
+	WebhookEventUpstreamModelUpdateDetected = "upstream_model.update_detected"
+	WebhookEventAuditLogged                 = "audit.logged"
+	// WebhookEventTest 手动发送的测试事件，仅投递到指定端点
+	WebhookEventTest = "webhook.test"
+	// WebhookEventAll 订阅全部事件
+	WebhookEventAll = "*"
+)
+
+// WebhookEventTypes 可订阅的事件类型
+var WebhookEventTypes = []string{
+	WebhookEventChannelAutoDisabled,
+	WebhookEventChannelAutoEnabled,
+	WebhookEventChannelKeyExpiring,
+	WebhookEventChannelKeyExpired,
+	WebhookEventTopupCompleted,
+	WebhookEventSubscriptionExpired,
+	WebhookEventTokenExhausted,
+	WebhookEventTaskFinished,
+	WebhookEventUpstreamModelUpdateDetected,
+	WebhookEventAuditLogged,
+}
+
+// IsAdminOnlyWebhookEvent 渠道、上游模型与审计事件不属于任何用户，仅管理员可订阅
+func IsAdminOnlyWebhookEvent(eventType string) bool {
+	switch eventType {
+	case WebhookEventChannelAutoDisabled, WebhookEventChannelAutoEnabled, WebhookEventChannelKeyExpiring, WebhookEventChannelKeyExpired,
+		WebhookEventUpstreamModelUpdateDetected, WebhookEventAuditLogged:
+		return true
+	}
+	return false
+}
+
+// 投递状态
+const (
+	WebhookDeliveryStatusPending = "pending"
+	WebhookDeliveryStatusSuccess = "success"
+	WebhookDeliveryStatusFailed  = "failed"
+)
+
+// WebhookEndpoint 用户注册的事件接收端点，请求体使用 Secret 进行 HMAC-SHA256 签名
+type WebhookEndpoint struct {
+	Id     int    `json:"id"`
+	UserId int    `json:"user_id" gorm:"index"`
+	Name   string `json:"name" gorm:"type:varchar(128);default:''"`
+	Url    string `json:"url" gorm:"type:varchar(1024)"`
+	Secret string `json:"secret" gorm:"type:varchar(128);default:''"`
+	// Events 订阅的事件类型，JSON 数组，包含 * 时订阅全部事件
+	Events  string `json:"events" gorm:"type:text"`
+	Enabled bool   `json:"enabled"`
+	// AllUsers 接收所有用户的事件，仅管理员可设置；否则只接收与自己相关的事件
+	AllUsers  bool  `json:"all_users" gorm:"default:false"`
+	CreatedAt int64 `json:"created_at" gorm:"bigint"`
+	UpdatedAt int64 `json:"updated_at" gorm:"bigint"`
+}
+
+// WebhookDelivery 事件投递记录，同时作为持久化投递队列
+type WebhookDelivery struct {
+	Id         int `json:"id"`
+	EndpointId int `json:"endpoint_id" gorm:"index"`
+	// UserId 端点所属用户
+	UserId    int    `json:"user_id" gorm:"index"`
+	EventId   string `json:"event_id" gorm:"type:varchar(64);index"`
+	EventType string `json:"event_type" gorm:"type:varchar(64);index"`
+	Payload   string `json:"payload" gorm:"type:text"`
+	Status    string `json:"status" gorm:"type:varchar(16);index:idx_webhook_delivery_due,priority:1"`
+	Attempts  int    `json:"attempts" gorm:"default:0"`
+	// NextAttemptAt 下次尝试时间，仅 pending 状态有效
+	NextAttemptAt  int64  `json:"next_attempt_at" gorm:"bigint;index:idx_webhook_delivery_due,priority:2"`
+	LastStatusCode int    `json:"last_status_code" gorm:"default:0"`
+	LastError      string `json:"last_error" gorm:"type:text"`
+	CreatedAt      int64  `json:"created_at" gorm:"bigint;index"`
+	DeliveredAt    int64  `json:"delivered_at" gorm:"bigint;default:0"`
+}
+
+// GetEvents 解析订阅的事件类型
+func (endpoint *WebhookEndpoint) GetEvents() []string {
+	var events []string
+	if endpoint.Events == "" {
+		return events
+	}
+	if err := common.UnmarshalJsonStr(endpoint.Events, &events); err != nil {
+		return []string{}
+	}
+	return events
+}
+
+func (endpoint *WebhookEndpoint) SetEvents(events []string) {
+	data, _ := common.Marshal(events)
+	endpoint.Events = string(data)
+}
+
+// Subscribes 判断端点是否订阅了指定事件
+func (endpoint *WebhookEndpoint) Subscribes(eventType string) bool {
+	events := endpoint.GetEvents()
+	return slices.Contains(events, WebhookEventAll) || slices.Contains(events, eventType)
+}
+
+func (endpoint *WebhookEndpoint) Insert() error {
+	now := common.GetTimestamp()
+	endpoint.CreatedAt = now
+	endpoint.UpdatedAt = now
+	return DB.Create(endpoint).Error
+}
+
+func (endpoint *WebhookEndpoint) Update() error {
+	endpoint.UpdatedAt = common.GetTimestamp()
+	return DB.Model(endpoint).Select("name", "url", "secret", "events", "enabled", "all_users", "updated_at").Updates(endpoint).Error
+}
+
+// GetWebhookEndpointById 获取端点，userId 为 0 时不校验归属
+func GetWebhookEndpointById(id int, userId int) (*WebhookEndpoint, error) {
+	if id == 0 {
+		return nil, errors.New("id 为空！")
+	}
+	endpoint := &WebhookEndpoint{}
+	tx := DB.Where("id = ?", id)
+	if userId != 0 {
+		tx = tx.Where("user_id = ?", userId)
+	}
+	err := tx.First(endpoint).Error
+	return endpoint, err
+}
+
+func GetUserWebhookEndpoints(userId int) (endpoints []*WebhookEndpoint, err error) {
+	err = DB.Where("user_id = ?", userId).Order("id desc").Find(&endpoints).Error
+	return endpoints, err
+}
+
+func CountUserWebhookEndpoints(userId int) (total int64, err error) {
+	err = DB.Model(&WebhookEndpoint{}).Where("user_id = ?", userId).Count(&total).Error
+	return total, err
+}
+
+// GetWebhookEndpointsForEvent 获取应接收该事件的启用端点：接收所有用户事件的端点，以及事件所属用户自己的端点
+func GetWebhookEndpointsForEvent(eventType string, userId int) ([]*WebhookEndpoint, error) {
+	var endpoints []*WebhookEndpoint
+	tx := DB.Where("enabled = ?", true)
+	if userId > 0 && !IsAdminOnlyWebhookEvent(eventType) {
+		tx = tx.Where("all_users = ? OR user_id = ?", true, userId)
+	} else {
+		tx = tx.Where("all_users = ?", true)
+	}
+	if err := tx.Find(&endpoints).Error; err != nil {
+		return nil, err
+	}
+	matched := make([]*WebhookEndpoint, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		if endpoint.Subscribes(eventType) {
+			matched = append(matched, endpoint)
+		}
+	}
+	return matched, nil
+}
+
+// DeleteWebhookEndpoint 删除端点及其未完成的投递
+func DeleteWebhookEndpoint(id int, userId int) error {
+	endpoint, err := GetWebhookEndpointById(id, userId)
+	if err != nil {
+		return err
+	}
+	if err = DB.Where("endpoint_id = ? AND status = ?", endpoint.Id, WebhookDeliveryStatusPending).Delete(&WebhookDelivery{}).Error; err != nil {
+		return err
+	}
+	return DB.Delete(endpoint).Error
+}
+
+func CreateWebhookDeliveries(deliveries []*WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return DB.Create(&deliveries).Error
+}
+
+// GetDueWebhookDeliveries 获取已到投递时间的待投递记录
+func GetDueWebhookDeliveries(now int64, limit int) (deliveries []*WebhookDelivery, err error) {
+	err = DB.Where("status = ? AND next_attempt_at <= ?", WebhookDeliveryStatusPending, now).
+		Order("id asc").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+// UpdateAttempt 保存一次投递尝试的结果
+func (delivery *WebhookDelivery) UpdateAttempt() error {
+	return DB.Model(delivery).Select("status", "attempts", "next_attempt_at", "last_status_code", "last_error", "delivered_at").Updates(delivery).Error
+}
+
+func GetWebhookDeliveryById(id int, userId int) (*WebhookDelivery, error) {
+	if id == 0 {
+		return nil, errors.New("id 为空！")
+	}
+	delivery := &WebhookDelivery{}
+	err := DB.Where("id = ? AND user_id = ?", id, userId).First(delivery).Error
+	return delivery, err
+}
+
+// GetWebhookDeliveries 分页获取用户端点的投递记录
+func GetWebhookDeliveries(userId int, endpointId int, eventType string, status string, startIdx int, num int) (deliveries []*WebhookDelivery, total int64, err error) {
+	tx := DB.Model(&WebhookDelivery{}).Where("user_id = ?", userId)
+	if endpointId != 0 {
+		tx = tx.Where("endpoint_id = ?", endpointId)
+	}
+	if eventType != "" {
+		tx = tx.Where("event_type = ?", eventType)
+	}
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Order("id desc").Limit(num).Offset(startIdx).Find(&deliveries).Error
+	return deliveries, total, err
+}
+
+// RetryWebhookDelivery 将投递重新放回队列，立即尝试一次
+func RetryWebhookDelivery(id int, userId int) (*WebhookDelivery, error) {
+	delivery, err := GetWebhookDeliveryById(id, userId)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.Status == WebhookDeliveryStatusSuccess {
+		return nil, errors.New("投递已成功，无需重试")
+	}
+	delivery.Status = WebhookDeliveryStatusPending
+	delivery.NextAttemptAt = common.GetTimestamp()
+	return delivery, DB.Model(delivery).Select("status", "next_attempt_at").Updates(delivery).Error
+}
+
+// DeleteOldWebhookDeliveries 分批删除早于指定时间且已结束的投递记录
+func DeleteOldWebhookDeliveries(ctx context.Context, targetTimestamp int64, limit int) (int64, error) {
+	var total int64 = 0
+	for {
+		if nil != ctx.Err() {
+			return total, ctx.Err()
+		}
+		var ids []int
+		err := DB.Model(&WebhookDelivery{}).Where("created_at < ? AND status <> ?", targetTimestamp, WebhookDeliveryStatusPending).
+			Limit(limit).Pluck("id", &ids).Error
+		if err != nil {
+			return total, err
+		}
+		if len(ids) == 0 {
+			break
+		}
+		result := DB.Where("id IN ?", ids).Delete(&WebhookDelivery{})
+		if nil != result.Error {
+			return total, result.Error
+		}
+		total += result.RowsAffected
+		if len(ids) < limit {
+			break
+		}
+	}
+	return total, nil
+}
+
+// WebhookEventPublisher 由 service 包注入，用于发布平台事件
+var WebhookEventPublisher func(eventType string, userId int, data map[string]interface{})
+
+// publishWebhookEvent 发布平台事件，未注入发布器时忽略
+func publishWebhookEvent(eventType string, userId int, data map[string]interface{}) {
+	if WebhookEventPublisher != nil {
+		WebhookEventPublisher(eventType, userId, data)
+	}
+}
+
+// PublishTopupEvent 发布充值完成事件
+func PublishTopupEvent(userId int, source string, quota int, money float64, tradeNo string) {
+	publishWebhookEvent(WebhookEventTopupCompleted, userId, map[string]interface{}{
+		"user_id":  userId,
+		"source":   source,
+		"quota":    quota,
+		"money":    money,
+		"trade_no": tradeNo,
+	})
+}
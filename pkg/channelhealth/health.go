@@ -0,0 +1,356 @@
+// Package channelhealth 基于真实流量的渠道健康度统计与熔断器。
+//
+// 每个渠道（以及多密钥渠道的每个密钥）维护一个滑动窗口，记录成功率、首字耗时与输出吞吐，
+// 用于在自动禁用（AutoBan）之前降低劣化渠道的权重，或直接熔断；
+// 熔断一段时间后进入半开状态，以较低权重放行少量请求探测，连续成功后逐步恢复。
+// 统计仅保存在当前实例内存中，多实例部署时各自独立统计。
+package channelhealth
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ChannelLevel 渠道整体统计使用的密钥索引
+const ChannelLevel = -1
+
+const bucketCount = 10
+
+type State string
+
+const (
+	StateClosed   State = "closed"
+	StateOpen     State = "open"
+	StateHalfOpen State = "half_open"
+)
+
+// Config 健康度统计与熔断参数
+type Config struct {
+	Window              time.Duration // 滑动窗口长度
+	MinSamples          int           // 窗口内样本数达到该值才参与评估
+	ErrorRateThreshold  float64       // 错误率达到该值时熔断
+	OpenDuration        time.Duration // 熔断持续时间，之后进入半开状态
+	HalfOpenSuccesses   int           // 半开状态下连续成功该次数后恢复
+	HalfOpenWeightRatio float64       // 半开状态的初始权重比例
+	MinWeightRatio      float64       // 劣化渠道的最低权重比例
+}
+
+// Outcome 一次转发尝试的结果
+type Outcome struct {
+	Success bool
+	TTFT    time.Duration // 首字耗时，非流式请求为 0
+}
+
+type Key struct {
+	ChannelId int
+	KeyIndex  int
+}
+
+type bucket struct {
+	start       int64 // 桶起始时间（unix 纳秒）
+	success     int
+	failure     int
+	ttftSum     time.Duration
+	ttftCount   int
+	tokens      int
+	genDuration time.Duration
+}
+
+type entry struct {
+	buckets           [bucketCount]bucket
+	state             State
+	openedAt          time.Time
+	halfOpenSuccesses int
+}
+
+// Stat 健康度快照
+type Stat struct {
+	ChannelId       int     `json:"channel_id"`
+	KeyIndex        int     `json:"key_index"`
+	State           State   `json:"state"`
+	Samples         int     `json:"samples"`
+	SuccessRate     float64 `json:"success_rate"`
+	AvgTTFTMs       int64   `json:"avg_ttft_ms"`
+	TokensPerSecond float64 `json:"tokens_per_second"`
+	WeightRatio     float64 `json:"weight_ratio"`
+	OpenedAt        int64   `json:"opened_at,omitempty"`
+}
+
+var (
+	mu      sync.Mutex
+	entries = make(map[Key]*entry)
+)
+
+func bucketSpan(cfg Config) time.Duration {
+	span := cfg.Window / bucketCount
+	if span <= 0 {
+		span = time.Second
+	}
+	return span
+}
+
+// currentBucket 返回当前时间所在的桶，过期的桶会被清空复用
+func (e *entry) currentBucket(cfg Config, now time.Time) *bucket {
+	span := bucketSpan(cfg)
+	start := now.Truncate(span).UnixNano()
+	b := &e.buckets[(start/int64(span))%bucketCount]
+	if b.start != start {
+		*b = bucket{start: start}
+	}
+	return b
+}
+
+// aggregate 汇总窗口内仍有效的桶
+func (e *entry) aggregate(cfg Config, now time.Time) bucket {
+	var sum bucket
+	oldest := now.Add(-cfg.Window).UnixNano()
+	for i := range e.buckets {
+		b := &e.buckets[i]
+		if b.start == 0 || b.start < oldest {
+			continue
+		}
+		sum.success += b.success
+		sum.failure += b.failure
+		sum.ttftSum += b.ttftSum
+		sum.ttftCount += b.ttftCount
+		sum.tokens += b.tokens
+		sum.genDuration += b.genDuration
+	}
+	return sum
+}
+
+func (e *entry) reset() {
+	e.buckets = [bucketCount]bucket{}
+}
+
+// refreshState 熔断时间到期后转为半开
+func (e *entry) refreshState(cfg Config, now time.Time) {
+	if e.state == StateOpen && now.Sub(e.openedAt) >= cfg.OpenDuration {
+		e.state = StateHalfOpen
+		e.halfOpenSuccesses = 0
+	}
+}
+
+func getEntry(key Key) *entry {
+	e, ok := entries[key]
+	if !ok {
+		e = &entry{state: StateClosed}
+		entries[key] = e
+	}
+	return e
+}
+
+// RecordOutcome 记录一次转发结果，返回是否因此触发熔断
+func RecordOutcome(cfg Config, key Key, outcome Outcome) (opened bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	now := time.Now()
+	e := getEntry(key)
+	e.refreshState(cfg, now)
+
+	b := e.currentBucket(cfg, now)
+	if outcome.Success {
+		b.success++
+		if outcome.TTFT > 0 {
+			b.ttftSum += outcome.TTFT
+			b.ttftCount++
+		}
+	} else {
+		b.failure++
+	}
+
+	switch e.state {
+	case StateHalfOpen:
+		if !outcome.Success {
+			e.state = StateOpen
+			e.openedAt = now
+			return true
+		}
+		e.halfOpenSuccesses++
+		if e.halfOpenSuccesses >= cfg.HalfOpenSuccesses {
+			// 恢复后重新统计，避免熔断前的失败再次触发熔断
+			e.state = StateClosed
+			e.reset()
+		}
+	case StateClosed:
+		if outcome.Success {
+			return false
+		}
+		sum := e.aggregate(cfg, now)
+		total := sum.success + sum.failure
+		if total >= cfg.MinSamples && float64(sum.failure)/float64(total) >= cfg.ErrorRateThreshold {
+			e.state = StateOpen
+			e.openedAt = now
+			return true
+		}
+	}
+	return false
+}
+
+// RecordThroughput 记录一次请求的输出 token 数与生成耗时
+func RecordThroughput(cfg Config, key Key, tokens int, duration time.Duration) {
+	if tokens <= 0 || duration <= 0 {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	b := getEntry(key).currentBucket(cfg, time.Now())
+	b.tokens += tokens
+	b.genDuration += duration
+}
+
+// IsOpen 是否处于熔断状态（半开视为可用）
+func IsOpen(cfg Config, key Key) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	e, ok := entries[key]
+	if !ok {
+		return false
+	}
+	e.refreshState(cfg, time.Now())
+	return e.state == StateOpen
+}
+
+// Reset 清除渠道的全部统计（例如管理员手动启用渠道后）
+func Reset(channelId int) {
+	mu.Lock()
+	defer mu.Unlock()
+	for key := range entries {
+		if key.ChannelId == channelId {
+			delete(entries, key)
+		}
+	}
+}
+
+func (e *entry) stat(cfg Config, key Key, now time.Time) Stat {
+	sum := e.aggregate(cfg, now)
+	stat := Stat{
+		ChannelId:   key.ChannelId,
+		KeyIndex:    key.KeyIndex,
+		State:       e.state,
+		Samples:     sum.success + sum.failure,
+		SuccessRate: 1,
+	}
+	if stat.Samples > 0 {
+		stat.SuccessRate = float64(sum.success) / float64(stat.Samples)
+	}
+	if sum.ttftCount > 0 {
+		stat.AvgTTFTMs = (sum.ttftSum / time.Duration(sum.ttftCount)).Milliseconds()
+	}
+	if sum.genDuration > 0 {
+		stat.TokensPerSecond = float64(sum.tokens) / sum.genDuration.Seconds()
+	}
+	if e.state == StateOpen {
+		stat.OpenedAt = e.openedAt.Unix()
+	}
+	return stat
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Float64s(values)
+	mid := len(values) / 2
+	if len(values)%2 == 0 {
+		return (values[mid-1] + values[mid]) / 2
+	}
+	return values[mid]
+}
+
+func clamp(v, min, max float64) float64 {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+// WeightRatios 计算一组候选渠道的权重比例：
+// 熔断为 0；半开按连续成功次数从 HalfOpenWeightRatio 逐步升至 1；
+// 正常状态按 成功率² × 首字耗时因子 × 吞吐因子 计算，耗时与吞吐以候选渠道的中位数为基准，
+// 样本不足 MinSamples 时视为健康（1）。
+func WeightRatios(cfg Config, keys []Key) []float64 {
+	mu.Lock()
+	defer mu.Unlock()
+	now := time.Now()
+	stats := make([]*Stat, len(keys))
+	var ttfts, tps []float64
+	for i, key := range keys {
+		e, ok := entries[key]
+		if !ok {
+			continue
+		}
+		e.refreshState(cfg, now)
+		stat := e.stat(cfg, key, now)
+		stats[i] = &stat
+		if stat.Samples < cfg.MinSamples {
+			continue
+		}
+		if stat.AvgTTFTMs > 0 {
+			ttfts = append(ttfts, float64(stat.AvgTTFTMs))
+		}
+		if stat.TokensPerSecond > 0 {
+			tps = append(tps, stat.TokensPerSecond)
+		}
+	}
+	medianTTFT := median(ttfts)
+	medianTPS := median(tps)
+
+	ratios := make([]float64, len(keys))
+	for i, key := range keys {
+		ratios[i] = weightRatio(cfg, entries[key], stats[i], medianTTFT, medianTPS)
+	}
+	return ratios
+}
+
+func weightRatio(cfg Config, e *entry, stat *Stat, medianTTFT, medianTPS float64) float64 {
+	if stat == nil {
+		return 1
+	}
+	switch stat.State {
+	case StateOpen:
+		return 0
+	case StateHalfOpen:
+		progress := 0.0
+		if cfg.HalfOpenSuccesses > 0 {
+			progress = float64(e.halfOpenSuccesses) / float64(cfg.HalfOpenSuccesses)
+		}
+		return clamp(cfg.HalfOpenWeightRatio+(1-cfg.HalfOpenWeightRatio)*progress, cfg.HalfOpenWeightRatio, 1)
+	}
+	if stat.Samples < cfg.MinSamples {
+		return 1
+	}
+	ratio := stat.SuccessRate * stat.SuccessRate
+	if medianTTFT > 0 && stat.AvgTTFTMs > 0 {
+		ratio *= clamp(medianTTFT/float64(stat.AvgTTFTMs), 0, 1)
+	}
+	if medianTPS > 0 && stat.TokensPerSecond > 0 {
+		ratio *= clamp(stat.TokensPerSecond/medianTPS, 0, 1)
+	}
+	return clamp(ratio, cfg.MinWeightRatio, 1)
+}
+
+// Snapshot 返回所有已统计渠道的健康度，按渠道与密钥索引排序
+func Snapshot(cfg Config) []Stat {
+	mu.Lock()
+	defer mu.Unlock()
+	now := time.Now()
+	result := make([]Stat, 0, len(entries))
+	for key, e := range entries {
+		e.refreshState(cfg, now)
+		stat := e.stat(cfg, key, now)
+		stat.WeightRatio = weightRatio(cfg, e, &stat, 0, 0)
+		result = append(result, stat)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ChannelId != result[j].ChannelId {
+			return result[i].ChannelId < result[j].ChannelId
+		}
+		return result[i].KeyIndex < result[j].KeyIndex
+	})
+	return result
+}
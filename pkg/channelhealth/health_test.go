@@ -0,0 +1,93 @@
+package channelhealth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testConfig() Config {
+	return Config{
+		Window:              time.Minute,
+		MinSamples:          4,
+		ErrorRateThreshold:  0.5,
+		OpenDuration:        time.Hour,
+		HalfOpenSuccesses:   2,
+		HalfOpenWeightRatio: 0.1,
+		MinWeightRatio:      0.05,
+	}
+}
+
+func TestRecordOutcome_OpensCircuitAfterThreshold(t *testing.T) {
+	cfg := testConfig()
+	key := Key{ChannelId: 1001, KeyIndex: ChannelLevel}
+	t.Cleanup(func() { Reset(key.ChannelId) })
+
+	require.False(t, RecordOutcome(cfg, key, Outcome{Success: true}))
+	require.False(t, RecordOutcome(cfg, key, Outcome{Success: true}))
+	require.False(t, RecordOutcome(cfg, key, Outcome{Success: false}))
+	require.True(t, RecordOutcome(cfg, key, Outcome{Success: false}))
+	require.True(t, IsOpen(cfg, key))
+	require.Equal(t, []float64{0}, WeightRatios(cfg, []Key{key}))
+}
+
+func TestRecordOutcome_HalfOpenRecoversGradually(t *testing.T) {
+	cfg := testConfig()
+	key := Key{ChannelId: 1002, KeyIndex: ChannelLevel}
+	t.Cleanup(func() { Reset(key.ChannelId) })
+
+	for i := 0; i < 4; i++ {
+		RecordOutcome(cfg, key, Outcome{Success: false})
+	}
+	require.True(t, IsOpen(cfg, key))
+
+	// 熔断时间到期后进入半开状态
+	cfg.OpenDuration = 0
+	require.False(t, IsOpen(cfg, key))
+	require.InDelta(t, 0.1, WeightRatios(cfg, []Key{key})[0], 1e-9)
+
+	RecordOutcome(cfg, key, Outcome{Success: true})
+	require.InDelta(t, 0.55, WeightRatios(cfg, []Key{key})[0], 1e-9)
+
+	RecordOutcome(cfg, key, Outcome{Success: true})
+	require.Equal(t, []float64{1}, WeightRatios(cfg, []Key{key}))
+}
+
+func TestRecordOutcome_HalfOpenFailureReopens(t *testing.T) {
+	cfg := testConfig()
+	key := Key{ChannelId: 1003, KeyIndex: ChannelLevel}
+	t.Cleanup(func() { Reset(key.ChannelId) })
+
+	for i := 0; i < 4; i++ {
+		RecordOutcome(cfg, key, Outcome{Success: false})
+	}
+	cfg.OpenDuration = 0
+	require.False(t, IsOpen(cfg, key))
+	require.True(t, RecordOutcome(cfg, key, Outcome{Success: false}))
+
+	cfg.OpenDuration = time.Hour
+	require.True(t, IsOpen(cfg, key))
+}
+
+func TestWeightRatios_DegradesSlowChannel(t *testing.T) {
+	cfg := testConfig()
+	fast := Key{ChannelId: 1004, KeyIndex: ChannelLevel}
+	slow := Key{ChannelId: 1005, KeyIndex: ChannelLevel}
+	fresh := Key{ChannelId: 1006, KeyIndex: ChannelLevel}
+	t.Cleanup(func() {
+		Reset(fast.ChannelId)
+		Reset(slow.ChannelId)
+	})
+
+	for i := 0; i < 4; i++ {
+		RecordOutcome(cfg, fast, Outcome{Success: true, TTFT: 100 * time.Millisecond})
+		RecordOutcome(cfg, fast, Outcome{Success: true, TTFT: 100 * time.Millisecond})
+		RecordOutcome(cfg, slow, Outcome{Success: true, TTFT: 900 * time.Millisecond})
+	}
+	ratios := WeightRatios(cfg, []Key{fast, slow, fresh})
+	require.Equal(t, 1.0, ratios[0])
+	require.InDelta(t, 500.0/900.0, ratios[1], 1e-9)
+	// 没有样本的渠道视为健康
+	require.Equal(t, 1.0, ratios[2])
+}
@@ -0,0 +1,197 @@
+// Package metrics 提供 Prometheus / OpenMetrics 格式的运行指标，
+// 通过 /metrics 暴露给外部监控系统（Grafana、Alertmanager 等）。
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "newapi"
+
+var relayLabels = []string{"channel_id", "channel_type", "model", "group", "relay_format"}
+
+// 延迟分桶覆盖从毫秒级到长时间流式输出
+var latencyBuckets = []float64{0.1, 0.25, 0.5, 1, 2, 4, 8, 15, 30, 60, 120, 300}
+
+var (
+	registry = prometheus.NewRegistry()
+
+	relayRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "relay_requests_total",
+		Help:      "Total number of upstream relay attempts, labeled by result status code.",
+	}, append(append([]string{}, relayLabels...), "status_code"))
+
+	upstreamLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "relay_upstream_latency_seconds",
+		Help:      "Latency of upstream relay attempts in seconds.",
+		Buckets:   latencyBuckets,
+	}, relayLabels)
+
+	firstTokenLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "relay_time_to_first_token_seconds",
+		Help:      "Time to first token of streaming relay requests in seconds.",
+		Buckets:   latencyBuckets,
+	}, relayLabels)
+
+	relayRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "relay_retries_total",
+		Help:      "Total number of relay retries, labeled by the channel that failed.",
+	}, relayLabels)
+
+	channelAutoDisabled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "channel_auto_disabled_total",
+		Help:      "Total number of channels (or multi-key keys) disabled automatically.",
+	}, []string{"channel_id", "channel_type"})
+
+	channelCircuitOpened = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "channel_circuit_opened_total",
+		Help:      "Total number of times a channel circuit breaker opened due to high error rate.",
+	}, []string{"channel_id", "channel_type"})
+
+	quotaConsumed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "quota_consumed_total",
+		Help:      "Total quota consumed by relay requests.",
+	}, []string{"channel_id", "channel_type", "model", "group"})
+
+	responseCacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "response_cache_lookups_total",
+		Help:      "Total number of response cache lookups, labeled by result (hit or miss).",
+	}, []string{"model", "result"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		relayRequests,
+		upstreamLatency,
+		firstTokenLatency,
+		relayRetries,
+		channelAutoDisabled,
+		channelCircuitOpened,
+		quotaConsumed,
+		responseCacheLookups,
+	)
+	registerBodyStorageMetrics()
+}
+
+// registerBodyStorageMetrics 磁盘缓存 / 请求体存储指标，采集时直接读取原子计数器
+func registerBodyStorageMetrics() {
+	gauge := func(name, help string, value func(stats common.DiskCacheStats) int64) prometheus.Collector {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      name,
+			Help:      help,
+		}, func() float64 {
+			return float64(value(common.GetDiskCacheStats()))
+		})
+	}
+	counter := func(name, help string, value func(stats common.DiskCacheStats) int64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      name,
+			Help:      help,
+		}, func() float64 {
+			return float64(value(common.GetDiskCacheStats()))
+		})
+	}
+	registry.MustRegister(
+		gauge("body_storage_disk_files", "Number of request bodies currently cached on disk.",
+			func(s common.DiskCacheStats) int64 { return s.ActiveDiskFiles }),
+		gauge("body_storage_disk_bytes", "Bytes of request bodies currently cached on disk.",
+			func(s common.DiskCacheStats) int64 { return s.CurrentDiskUsageBytes }),
+		gauge("body_storage_disk_max_bytes", "Configured maximum bytes of the disk cache.",
+			func(s common.DiskCacheStats) int64 { return s.DiskCacheMaxBytes }),
+		gauge("body_storage_memory_buffers", "Number of request bodies currently buffered in memory.",
+			func(s common.DiskCacheStats) int64 { return s.ActiveMemoryBuffers }),
+		gauge("body_storage_memory_bytes", "Bytes of request bodies currently buffered in memory.",
+			func(s common.DiskCacheStats) int64 { return s.CurrentMemoryUsageBytes }),
+		counter("body_storage_disk_hits_total", "Total number of request bodies stored on disk.",
+			func(s common.DiskCacheStats) int64 { return s.DiskCacheHits }),
+		counter("body_storage_memory_hits_total", "Total number of request bodies stored in memory.",
+			func(s common.DiskCacheStats) int64 { return s.MemoryCacheHits }),
+	)
+}
+
+// Handler 返回 /metrics 处理器，支持 Prometheus 文本格式与 OpenMetrics 格式协商
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+		EnableOpenMetrics: true,
+	})
+}
+
+// RelayLabels 一次转发尝试的指标标签
+type RelayLabels struct {
+	ChannelId   int
+	ChannelType int
+	Model       string
+	Group       string
+	RelayFormat string
+}
+
+func (l RelayLabels) values() []string {
+	return []string{strconv.Itoa(l.ChannelId), strconv.Itoa(l.ChannelType), l.Model, l.Group, l.RelayFormat}
+}
+
+// ObserveRelayAttempt 记录一次上游转发尝试的结果与耗时
+func ObserveRelayAttempt(labels RelayLabels, statusCode int, latency time.Duration) {
+	values := labels.values()
+	relayRequests.WithLabelValues(append(values, strconv.Itoa(statusCode))...).Inc()
+	upstreamLatency.WithLabelValues(values...).Observe(latency.Seconds())
+}
+
+// ObserveFirstToken 记录流式请求的首字耗时
+func ObserveFirstToken(labels RelayLabels, latency time.Duration) {
+	if latency <= 0 {
+		return
+	}
+	firstTokenLatency.WithLabelValues(labels.values()...).Observe(latency.Seconds())
+}
+
+// IncRelayRetry 记录一次重试，labels 为失败的渠道
+func IncRelayRetry(labels RelayLabels) {
+	relayRetries.WithLabelValues(labels.values()...).Inc()
+}
+
+// IncChannelAutoDisabled 记录一次渠道自动禁用
+func IncChannelAutoDisabled(channelId int, channelType int) {
+	channelAutoDisabled.WithLabelValues(strconv.Itoa(channelId), strconv.Itoa(channelType)).Inc()
+}
+
+// IncChannelCircuitOpened 记录一次渠道熔断
+func IncChannelCircuitOpened(channelId int, channelType int) {
+	channelCircuitOpened.WithLabelValues(strconv.Itoa(channelId), strconv.Itoa(channelType)).Inc()
+}
+
+// AddQuotaConsumed 累加消耗的额度
+func AddQuotaConsumed(channelId int, channelType int, model string, group string, quota int) {
+	if quota <= 0 {
+		return
+	}
+	quotaConsumed.WithLabelValues(strconv.Itoa(channelId), strconv.Itoa(channelType), model, group).Add(float64(quota))
+}
+
+// ObserveResponseCacheLookup 记录一次响应缓存查询结果
+func ObserveResponseCacheLookup(model string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	responseCacheLookups.WithLabelValues(model, result).Inc()
+}
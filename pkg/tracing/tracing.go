@@ -0,0 +1,114 @@
+// Package tracing 基于 OpenTelemetry 的链路追踪，通过 OTLP/HTTP 导出到 Jaeger、Tempo 等后端。
+//
+// 未配置 OTEL_EXPORTER_OTLP_ENDPOINT（或 OTEL_EXPORTER_OTLP_TRACES_ENDPOINT）时不启用，
+// 此时全局 TracerProvider 为空实现，创建 span 几乎没有开销。
+// 服务名、资源属性、采样率等沿用 OpenTelemetry 标准环境变量
+// （OTEL_SERVICE_NAME、OTEL_RESOURCE_ATTRIBUTES、OTEL_TRACES_SAMPLER、OTEL_TRACES_SAMPLER_ARG）。
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/QuantumNous/new-api"
+
+const defaultServiceName = "new-api"
+
+// 常用的 span 属性键
+const (
+	AttrRequestId   = attribute.Key("newapi.request_id")
+	AttrUserId      = attribute.Key("newapi.user_id")
+	AttrTokenId     = attribute.Key("newapi.token_id")
+	AttrGroup       = attribute.Key("newapi.group")
+	AttrModel       = attribute.Key("newapi.model")
+	AttrChannelId   = attribute.Key("newapi.channel_id")
+	AttrChannelType = attribute.Key("newapi.channel_type")
+	AttrRelayFormat = attribute.Key("newapi.relay_format")
+	AttrRetryIndex  = attribute.Key("newapi.retry_index")
+	AttrIsStream    = attribute.Key("newapi.is_stream")
+)
+
+// W3C Trace Context 与 Baggage
+var propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// Start 初始化 OTLP 导出，返回的 shutdown 用于退出前刷新剩余 span
+func Start(ctx context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagator)
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(serviceName())))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+func serviceName() string {
+	if name := os.Getenv("OTEL_SERVICE_NAME"); name != "" {
+		return name
+	}
+	return defaultServiceName
+}
+
+// StartSpan 创建内部 span
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartServerSpan 创建入站请求的 span，若请求头携带 traceparent 则作为其子 span
+func StartServerSpan(r *http.Request, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+}
+
+// StartClientSpan 创建出站请求的 span
+func StartClientSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// Inject 将 ctx 中的链路信息写入上游请求头（traceparent / tracestate），
+// 不透传 baggage，避免将客户端携带的数据泄露给上游
+func Inject(ctx context.Context, header http.Header) {
+	if ctx == nil {
+		return
+	}
+	propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// EndSpan 结束 span，err 非空时标记为错误
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -31,6 +31,9 @@ type Adaptor struct {
 	AwsModelId string
 	AwsReq     any
 	IsNova     bool
+	// IsConverse 非 Claude、非 Nova 模型统一使用 Converse API
+	IsConverse  bool
+	IsEmbedding bool
 }
 
 func (a *Adaptor) ConvertGeminiRequest(*gin.Context, *relaycommon.RelayInfo, *dto.GeminiChatRequest) (any, error) {
@@ -38,7 +41,22 @@ func (a *Adaptor) ConvertGeminiRequest(*gin.Context, *relaycommon.RelayInfo, *dt
 	return nil, errors.New("not implemented")
 }
 
+// API Key 模式直接请求 Claude Messages 格式的接口，Converse 与嵌入仅支持 AK/SK 模式
+func isAwsApiKeyMode(info *relaycommon.RelayInfo) bool {
+	return info.ChannelOtherSettings.AwsKeyType == dto.AwsKeyTypeApiKey
+}
+
 func (a *Adaptor) ConvertClaudeRequest(c *gin.Context, info *relaycommon.RelayInfo, request *dto.ClaudeRequest) (any, error) {
+	if isConverseModel(getAwsModelID(info.UpstreamModelName)) {
+		if isAwsApiKeyMode(info) {
+			return nil, fmt.Errorf("model %s uses the Converse API, which is unsupported in API-key mode", info.UpstreamModelName)
+		}
+		openAIRequest, err := service.ClaudeToOpenAIRequest(*request, info)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to convert claude request to openai request")
+		}
+		return a.ConvertOpenAIRequest(c, info, openAIRequest)
+	}
 	for i, message := range request.Messages {
 		updated := false
 		if !message.IsStringContent() {
@@ -89,7 +107,7 @@ func (a *Adaptor) Init(info *relaycommon.RelayInfo) {
 }
 
 func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
-	if info.ChannelOtherSettings.AwsKeyType == dto.AwsKeyTypeApiKey {
+	if isAwsApiKeyMode(info) {
 		awsModelId := getAwsModelID(info.UpstreamModelName)
 		a.ClientMode = ClientModeApiKey
 		awsSecret := strings.Split(info.ApiKey, "|")
@@ -122,6 +140,14 @@ func (a *Adaptor) ConvertOpenAIRequest(c *gin.Context, info *relaycommon.RelayIn
 		return novaReq, nil
 	}
 
+	if isConverseModel(getAwsModelID(info.UpstreamModelName)) {
+		if isAwsApiKeyMode(info) {
+			return nil, fmt.Errorf("model %s uses the Converse API, which is unsupported in API-key mode", info.UpstreamModelName)
+		}
+		a.IsConverse = true
+		return convertOpenAI2ConverseRequest(c, request)
+	}
+
 	// 原有的Claude模型处理逻辑
 	claudeReq, err := claude.RequestOpenAI2ClaudeMessage(c, *request)
 	if err != nil {
@@ -136,8 +162,15 @@ func (a *Adaptor) ConvertRerankRequest(c *gin.Context, relayMode int, request dt
 }
 
 func (a *Adaptor) ConvertEmbeddingRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.EmbeddingRequest) (any, error) {
-	//TODO implement me
-	return nil, errors.New("not implemented")
+	if isAwsApiKeyMode(info) {
+		return nil, errors.New("aws embeddings are unsupported in API-key mode, use an AK/SK key instead")
+	}
+	awsModelId := getAwsModelID(info.UpstreamModelName)
+	if !isEmbeddingModel(awsModelId) {
+		return nil, fmt.Errorf("model %s does not support embeddings", info.UpstreamModelName)
+	}
+	a.IsEmbedding = true
+	return convertOpenAI2AwsEmbeddingRequest(awsModelId, request)
 }
 
 func (a *Adaptor) ConvertOpenAIResponsesRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.OpenAIResponsesRequest) (any, error) {
@@ -158,8 +191,16 @@ func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycom
 		claudeAdaptor := claude.Adaptor{}
 		usage, err = claudeAdaptor.DoResponse(c, resp, info)
 	} else {
-		if a.IsNova {
+		if a.IsEmbedding {
+			err, usage = awsEmbeddingHandler(c, info, a)
+		} else if a.IsNova {
 			err, usage = handleNovaRequest(c, info, a)
+		} else if a.IsConverse {
+			if info.IsStream {
+				err, usage = converseStreamHandler(c, info, a)
+			} else {
+				err, usage = converseHandler(c, info, a)
+			}
 		} else {
 			if info.IsStream {
 				err, usage = awsStreamHandler(c, info, a)
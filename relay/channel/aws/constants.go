@@ -27,6 +27,22 @@ var awsModelIDMap = map[string]string{
 	"nova-reel-v1:0":    "amazon.nova-reel-v1:0",
 	"nova-reel-v1:1":    "amazon.nova-reel-v1:1",
 	"nova-sonic-v1:0":   "amazon.nova-sonic-v1:0",
+	// Converse API models
+	"llama3-3-70b-instruct": "meta.llama3-3-70b-instruct-v1:0",
+	"llama4-maverick-17b":   "meta.llama4-maverick-17b-instruct-v1:0",
+	"llama4-scout-17b":      "meta.llama4-scout-17b-instruct-v1:0",
+	"mistral-large-2407":    "mistral.mistral-large-2407-v1:0",
+	"pixtral-large-2502":    "mistral.pixtral-large-2502-v1:0",
+	"command-r-plus":        "cohere.command-r-plus-v1:0",
+	"command-r":             "cohere.command-r-v1:0",
+	"deepseek-r1":           "deepseek.r1-v1:0",
+	"qwen3-32b":             "qwen.qwen3-32b-v1:0",
+	"qwen3-coder-30b-a3b":   "qwen.qwen3-coder-30b-a3b-v1:0",
+	// Embedding models
+	"titan-embed-text-v2":          "amazon.titan-embed-text-v2:0",
+	"cohere-embed-english-v3":      "cohere.embed-english-v3",
+	"cohere-embed-multilingual-v3": "cohere.embed-multilingual-v3",
+	"cohere-embed-v4":              "cohere.embed-v4:0",
 }
 
 var awsModelCanCrossRegionMap = map[string]map[string]bool{
@@ -133,6 +149,23 @@ var awsModelCanCrossRegionMap = map[string]map[string]bool{
 		"eu":   true,
 		"apac": true,
 	},
+	// Converse API models
+	"meta.llama3-3-70b-instruct-v1:0": {
+		"us": true,
+	},
+	"meta.llama4-maverick-17b-instruct-v1:0": {
+		"us": true,
+	},
+	"meta.llama4-scout-17b-instruct-v1:0": {
+		"us": true,
+	},
+	"mistral.pixtral-large-2502-v1:0": {
+		"us": true,
+		"eu": true,
+	},
+	"deepseek.r1-v1:0": {
+		"us": true,
+	},
 }
 
 var awsRegionCrossModelPrefixMap = map[string]string{
@@ -147,3 +180,74 @@ var ChannelName = "aws"
 func isNovaModel(modelId string) bool {
 	return strings.Contains(modelId, "nova-")
 }
+
+// 判断是否为Claude模型，包括跨区域推理配置文件与包含模型名的ARN
+func isClaudeModel(modelId string) bool {
+	return strings.HasPrefix(modelId, "claude") || strings.Contains(modelId, "anthropic.claude")
+}
+
+// 走Converse通用路径的模型提供方，模型ID形如 <provider>.<model>，可带跨区域前缀
+var awsConverseProviders = map[string]bool{
+	"meta":     true,
+	"mistral":  true,
+	"deepseek": true,
+	"qwen":     true,
+	"cohere":   true,
+	"ai21":     true,
+	"writer":   true,
+	"openai":   true,
+}
+
+// 跨区域推理配置文件的区域前缀
+var awsInferenceProfilePrefixes = map[string]bool{
+	"us":     true,
+	"us-gov": true,
+	"eu":     true,
+	"apac":   true,
+	"jp":     true,
+	"au":     true,
+	"global": true,
+}
+
+// awsModelBaseID 去掉ARN路径与跨区域前缀，返回 <provider>.<model> 形式的基础模型ID；
+// 应用推理配置文件等不含模型名的ARN会返回不含提供方的ID
+func awsModelBaseID(modelId string) string {
+	if idx := strings.LastIndex(modelId, "/"); idx >= 0 {
+		modelId = modelId[idx+1:]
+	}
+	if prefix, rest, ok := strings.Cut(modelId, "."); ok && awsInferenceProfilePrefixes[prefix] {
+		return rest
+	}
+	return modelId
+}
+
+// 判断是否使用Converse API：仅已知提供方（Llama、Mistral、DeepSeek、Qwen 等）的模型ID、推理配置文件与ARN走Converse，
+// 无法识别提供方的ID（如应用推理配置文件ARN）保持原有InvokeModel路径
+func isConverseModel(modelId string) bool {
+	if isClaudeModel(modelId) || isNovaModel(modelId) {
+		return false
+	}
+	baseId := awsModelBaseID(modelId)
+	if strings.HasPrefix(baseId, "amazon.titan-text") {
+		return true
+	}
+	provider, _, ok := strings.Cut(baseId, ".")
+	return ok && awsConverseProviders[provider]
+}
+
+// 判断是否为嵌入模型
+func isEmbeddingModel(modelId string) bool {
+	return strings.Contains(modelId, "titan-embed") || strings.Contains(modelId, "cohere.embed")
+}
+
+func isCohereEmbeddingModel(modelId string) bool {
+	return strings.Contains(modelId, "cohere.embed")
+}
+
+// 单次调用可携带的输入条数：Titan 每次仅支持一条，Cohere 最多 96 条
+func awsEmbeddingBatchSize(modelId string) int {
+	if isCohereEmbeddingModel(modelId) {
+		return 96
+	}
+	return 1
+}
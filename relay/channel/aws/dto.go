@@ -143,3 +143,128 @@ func parseStopSequences(stop any) []string {
 	}
 	return nil
 }
+
+// ConverseRequest Bedrock Converse/ConverseStream 请求体，字段与 REST API 一致，发送前转换为 SDK 输入
+type ConverseRequest struct {
+	Messages        []ConverseMessage        `json:"messages"`
+	System          []ConverseSystemContent  `json:"system,omitempty"`
+	InferenceConfig *ConverseInferenceConfig `json:"inferenceConfig,omitempty"`
+	ToolConfig      *ConverseToolConfig      `json:"toolConfig,omitempty"`
+	// AdditionalModelRequestFields 模型专有参数，可通过参数覆盖设置，如 {"top_k": 50}
+	AdditionalModelRequestFields map[string]any `json:"additionalModelRequestFields,omitempty"`
+}
+
+type ConverseMessage struct {
+	Role    string                 `json:"role"`
+	Content []ConverseContentBlock `json:"content"`
+}
+
+// ConverseContentBlock 内容块，每个块只设置一个字段
+type ConverseContentBlock struct {
+	Text       *string             `json:"text,omitempty"`
+	Image      *ConverseImageBlock `json:"image,omitempty"`
+	ToolUse    *ConverseToolUse    `json:"toolUse,omitempty"`
+	ToolResult *ConverseToolResult `json:"toolResult,omitempty"`
+}
+
+type ConverseImageBlock struct {
+	Format string              `json:"format"` // png | jpeg | gif | webp
+	Source ConverseImageSource `json:"source"`
+}
+
+type ConverseImageSource struct {
+	Bytes string `json:"bytes"` // base64
+}
+
+type ConverseToolUse struct {
+	ToolUseId string `json:"toolUseId"`
+	Name      string `json:"name"`
+	Input     any    `json:"input"`
+}
+
+type ConverseToolResult struct {
+	ToolUseId string                      `json:"toolUseId"`
+	Content   []ConverseToolResultContent `json:"content"`
+	Status    string                      `json:"status,omitempty"`
+}
+
+type ConverseToolResultContent struct {
+	Text string `json:"text"`
+}
+
+type ConverseSystemContent struct {
+	Text string `json:"text"`
+}
+
+type ConverseInferenceConfig struct {
+	MaxTokens     *int     `json:"maxTokens,omitempty"`
+	Temperature   *float64 `json:"temperature,omitempty"`
+	TopP          *float64 `json:"topP,omitempty"`
+	StopSequences []string `json:"stopSequences,omitempty"`
+}
+
+type ConverseToolConfig struct {
+	Tools      []ConverseTool      `json:"tools"`
+	ToolChoice *ConverseToolChoice `json:"toolChoice,omitempty"`
+}
+
+type ConverseTool struct {
+	ToolSpec ConverseToolSpec `json:"toolSpec"`
+}
+
+type ConverseToolSpec struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	InputSchema ConverseInputSchema `json:"inputSchema"`
+}
+
+type ConverseInputSchema struct {
+	Json any `json:"json"`
+}
+
+// ConverseToolChoice 三选一：auto、any（必须调用工具）或指定 tool
+type ConverseToolChoice struct {
+	Auto *struct{}                 `json:"auto,omitempty"`
+	Any  *struct{}                 `json:"any,omitempty"`
+	Tool *ConverseSpecificToolName `json:"tool,omitempty"`
+}
+
+type ConverseSpecificToolName struct {
+	Name string `json:"name"`
+}
+
+// AwsEmbeddingRequest 嵌入请求的中间格式，字段沿用 Titan/Cohere 的命名，可通过参数覆盖调整，发送前按模型拆分批次
+type AwsEmbeddingRequest struct {
+	Texts []string `json:"texts"`
+	// InputType Cohere 必填：search_document | search_query | classification | clustering
+	InputType string `json:"input_type,omitempty"`
+	// Truncate Cohere 超长输入的截断方式：NONE | START | END
+	Truncate   string `json:"truncate,omitempty"`
+	Dimensions *int   `json:"dimensions,omitempty"`
+	// Normalize 仅 Titan v2 支持
+	Normalize *bool `json:"normalize,omitempty"`
+}
+
+type TitanEmbeddingRequest struct {
+	InputText  string `json:"inputText"`
+	Dimensions *int   `json:"dimensions,omitempty"`
+	Normalize  *bool  `json:"normalize,omitempty"`
+}
+
+type TitanEmbeddingResponse struct {
+	Embedding           []float64 `json:"embedding"`
+	InputTextTokenCount int       `json:"inputTextTokenCount"`
+}
+
+type CohereEmbeddingRequest struct {
+	Texts           []string `json:"texts"`
+	InputType       string   `json:"input_type"`
+	Truncate        string   `json:"truncate,omitempty"`
+	EmbeddingTypes  []string `json:"embedding_types,omitempty"`
+	OutputDimension *int     `json:"output_dimension,omitempty"`
+}
+
+// CohereEmbeddingResponse 指定 embedding_types 时 embeddings 为 {"float": [...]}，否则为二维数组
+type CohereEmbeddingResponse struct {
+	Embeddings json.RawMessage `json:"embeddings"`
+}
@@ -115,6 +115,42 @@ func doAwsClientRequest(c *gin.Context, info *relaycommon.RelayInfo, a *Adaptor,
 		requestHeader.Set(key, value)
 	}
 
+	if a.IsEmbedding {
+		var embeddingReq AwsEmbeddingRequest
+		if err = common.DecodeJson(requestBody, &embeddingReq); err != nil {
+			return nil, types.NewError(errors.Wrap(err, "decode embedding request fail"), types.ErrorCodeBadRequestBody)
+		}
+		a.AwsReq, err = buildAwsEmbeddingInputs(&embeddingReq, awsModelId)
+		if err != nil {
+			return nil, types.NewError(errors.Wrap(err, "build embedding request fail"), types.ErrorCodeBadRequestBody)
+		}
+		return nil, nil
+	}
+
+	if a.IsConverse {
+		var converseReq ConverseRequest
+		if err = common.DecodeJson(requestBody, &converseReq); err != nil {
+			return nil, types.NewError(errors.Wrap(err, "decode converse request fail"), types.ErrorCodeBadRequestBody)
+		}
+		converseInput, err := buildConverseInput(&converseReq, awsModelId)
+		if err != nil {
+			return nil, types.NewError(errors.Wrap(err, "build converse request fail"), types.ErrorCodeBadRequestBody)
+		}
+		if info.IsStream {
+			a.AwsReq = &bedrockruntime.ConverseStreamInput{
+				ModelId:                      converseInput.ModelId,
+				Messages:                     converseInput.Messages,
+				System:                       converseInput.System,
+				InferenceConfig:              converseInput.InferenceConfig,
+				ToolConfig:                   converseInput.ToolConfig,
+				AdditionalModelRequestFields: converseInput.AdditionalModelRequestFields,
+			}
+		} else {
+			a.AwsReq = converseInput
+		}
+		return nil, nil
+	}
+
 	if isNovaModel(awsModelId) {
 		var novaReq *NovaRequest
 		err = common.DecodeJson(requestBody, &novaReq)
@@ -0,0 +1,485 @@
+package aws
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/relay/channel/openai"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relay/helper"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
+	bedrockruntimeTypes "github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+// convertOpenAI2ConverseRequest 将 OpenAI 请求转换为 Converse 请求，连续的同角色消息会合并，tool 消息转为 user 的 toolResult
+func convertOpenAI2ConverseRequest(c *gin.Context, request *dto.GeneralOpenAIRequest) (*ConverseRequest, error) {
+	converseReq := &ConverseRequest{}
+	for _, message := range request.Messages {
+		switch message.Role {
+		case "system", "developer":
+			if text := message.StringContent(); text != "" {
+				converseReq.System = append(converseReq.System, ConverseSystemContent{Text: text})
+			}
+			continue
+		case "tool":
+			converseReq.appendContent("user", ConverseContentBlock{ToolResult: &ConverseToolResult{
+				ToolUseId: message.ToolCallId,
+				Content:   []ConverseToolResultContent{{Text: message.StringContent()}},
+			}})
+			continue
+		}
+
+		role := "user"
+		if message.Role == "assistant" {
+			role = "assistant"
+		}
+		var blocks []ConverseContentBlock
+		if message.IsStringContent() {
+			if text := message.StringContent(); strings.TrimSpace(text) != "" {
+				blocks = append(blocks, ConverseContentBlock{Text: aws.String(text)})
+			}
+		} else {
+			for _, content := range message.ParseContent() {
+				switch content.Type {
+				case dto.ContentTypeText:
+					if strings.TrimSpace(content.Text) != "" {
+						blocks = append(blocks, ConverseContentBlock{Text: aws.String(content.Text)})
+					}
+				case dto.ContentTypeImageURL:
+					image, err := convertConverseImage(c, content.GetImageMedia())
+					if err != nil {
+						return nil, err
+					}
+					blocks = append(blocks, ConverseContentBlock{Image: image})
+				}
+			}
+		}
+		for _, toolCall := range message.ParseToolCalls() {
+			input := map[string]any{}
+			if toolCall.Function.Arguments != "" {
+				if err := common.UnmarshalJsonStr(toolCall.Function.Arguments, &input); err != nil {
+					return nil, fmt.Errorf("invalid tool call arguments for %s: %w", toolCall.Function.Name, err)
+				}
+			}
+			blocks = append(blocks, ConverseContentBlock{ToolUse: &ConverseToolUse{
+				ToolUseId: toolCall.ID,
+				Name:      toolCall.Function.Name,
+				Input:     input,
+			}})
+		}
+		converseReq.appendContent(role, blocks...)
+	}
+
+	inferenceConfig := &ConverseInferenceConfig{
+		Temperature:   request.Temperature,
+		TopP:          request.TopP,
+		StopSequences: parseStopSequences(request.Stop),
+	}
+	if maxTokens := request.GetMaxTokens(); maxTokens > 0 {
+		inferenceConfig.MaxTokens = common.GetPointer(int(maxTokens))
+	}
+	if inferenceConfig.MaxTokens != nil || inferenceConfig.Temperature != nil || inferenceConfig.TopP != nil || len(inferenceConfig.StopSequences) > 0 {
+		converseReq.InferenceConfig = inferenceConfig
+	}
+
+	if len(request.Tools) > 0 {
+		toolConfig := &ConverseToolConfig{}
+		for _, tool := range request.Tools {
+			if tool.Type != "" && tool.Type != "function" {
+				continue
+			}
+			schema := tool.Function.Parameters
+			if schema == nil {
+				schema = map[string]any{"type": "object", "properties": map[string]any{}}
+			}
+			toolConfig.Tools = append(toolConfig.Tools, ConverseTool{ToolSpec: ConverseToolSpec{
+				Name:        tool.Function.Name,
+				Description: tool.Function.Description,
+				InputSchema: ConverseInputSchema{Json: schema},
+			}})
+		}
+		toolConfig.ToolChoice = convertConverseToolChoice(request.ToolChoice)
+		if len(toolConfig.Tools) > 0 {
+			converseReq.ToolConfig = toolConfig
+		}
+	}
+	return converseReq, nil
+}
+
+// appendContent 追加消息内容，与上一条消息角色相同时合并，Converse 要求 user 与 assistant 交替出现
+func (r *ConverseRequest) appendContent(role string, blocks ...ConverseContentBlock) {
+	if len(blocks) == 0 {
+		return
+	}
+	if n := len(r.Messages); n > 0 && r.Messages[n-1].Role == role {
+		r.Messages[n-1].Content = append(r.Messages[n-1].Content, blocks...)
+		return
+	}
+	r.Messages = append(r.Messages, ConverseMessage{Role: role, Content: blocks})
+}
+
+func convertConverseImage(c *gin.Context, imageUrl *dto.MessageImageUrl) (*ConverseImageBlock, error) {
+	if imageUrl == nil {
+		return nil, errors.New("image_url is empty")
+	}
+	var source *types.FileSource
+	if strings.HasPrefix(imageUrl.Url, "http") {
+		source = types.NewURLFileSource(imageUrl.Url)
+	} else {
+		source = types.NewBase64FileSource(imageUrl.Url, "")
+	}
+	base64Data, mimeType, err := service.GetBase64Data(c, source, "formatting image for Bedrock Converse")
+	if err != nil {
+		return nil, fmt.Errorf("get file data failed: %s", err.Error())
+	}
+	format := strings.TrimPrefix(strings.ToLower(mimeType), "image/")
+	if format == "jpg" {
+		format = "jpeg"
+	}
+	switch format {
+	case "png", "jpeg", "gif", "webp":
+	default:
+		return nil, fmt.Errorf("unsupported image format for Bedrock Converse: %s", mimeType)
+	}
+	return &ConverseImageBlock{Format: format, Source: ConverseImageSource{Bytes: base64Data}}, nil
+}
+
+// convertConverseToolChoice Converse 没有 none 选项，none 与未指定时均使用默认的 auto
+func convertConverseToolChoice(toolChoice any) *ConverseToolChoice {
+	switch v := toolChoice.(type) {
+	case string:
+		switch v {
+		case "required":
+			return &ConverseToolChoice{Any: &struct{}{}}
+		case "auto":
+			return &ConverseToolChoice{Auto: &struct{}{}}
+		}
+	case map[string]any:
+		if function, ok := v["function"].(map[string]any); ok {
+			if name, _ := function["name"].(string); name != "" {
+				return &ConverseToolChoice{Tool: &ConverseSpecificToolName{Name: name}}
+			}
+		}
+	}
+	return nil
+}
+
+// buildConverseInput 将请求体转换为 SDK 的 Converse 输入
+func buildConverseInput(req *ConverseRequest, modelId string) (*bedrockruntime.ConverseInput, error) {
+	input := &bedrockruntime.ConverseInput{ModelId: aws.String(modelId)}
+	for _, message := range req.Messages {
+		sdkMessage := bedrockruntimeTypes.Message{Role: bedrockruntimeTypes.ConversationRole(message.Role)}
+		for _, block := range message.Content {
+			sdkBlock, err := buildConverseContentBlock(block)
+			if err != nil {
+				return nil, err
+			}
+			if sdkBlock != nil {
+				sdkMessage.Content = append(sdkMessage.Content, sdkBlock)
+			}
+		}
+		input.Messages = append(input.Messages, sdkMessage)
+	}
+	for _, system := range req.System {
+		input.System = append(input.System, &bedrockruntimeTypes.SystemContentBlockMemberText{Value: system.Text})
+	}
+	if cfg := req.InferenceConfig; cfg != nil {
+		input.InferenceConfig = &bedrockruntimeTypes.InferenceConfiguration{StopSequences: cfg.StopSequences}
+		if cfg.MaxTokens != nil {
+			input.InferenceConfig.MaxTokens = aws.Int32(int32(*cfg.MaxTokens))
+		}
+		if cfg.Temperature != nil {
+			input.InferenceConfig.Temperature = aws.Float32(float32(*cfg.Temperature))
+		}
+		if cfg.TopP != nil {
+			input.InferenceConfig.TopP = aws.Float32(float32(*cfg.TopP))
+		}
+	}
+	if cfg := req.ToolConfig; cfg != nil {
+		input.ToolConfig = &bedrockruntimeTypes.ToolConfiguration{}
+		for _, tool := range cfg.Tools {
+			spec := bedrockruntimeTypes.ToolSpecification{
+				Name:        aws.String(tool.ToolSpec.Name),
+				InputSchema: &bedrockruntimeTypes.ToolInputSchemaMemberJson{Value: document.NewLazyDocument(tool.ToolSpec.InputSchema.Json)},
+			}
+			if tool.ToolSpec.Description != "" {
+				spec.Description = aws.String(tool.ToolSpec.Description)
+			}
+			input.ToolConfig.Tools = append(input.ToolConfig.Tools, &bedrockruntimeTypes.ToolMemberToolSpec{Value: spec})
+		}
+		if choice := cfg.ToolChoice; choice != nil {
+			switch {
+			case choice.Tool != nil:
+				input.ToolConfig.ToolChoice = &bedrockruntimeTypes.ToolChoiceMemberTool{Value: bedrockruntimeTypes.SpecificToolChoice{Name: aws.String(choice.Tool.Name)}}
+			case choice.Any != nil:
+				input.ToolConfig.ToolChoice = &bedrockruntimeTypes.ToolChoiceMemberAny{}
+			case choice.Auto != nil:
+				input.ToolConfig.ToolChoice = &bedrockruntimeTypes.ToolChoiceMemberAuto{}
+			}
+		}
+	}
+	if len(req.AdditionalModelRequestFields) > 0 {
+		input.AdditionalModelRequestFields = document.NewLazyDocument(req.AdditionalModelRequestFields)
+	}
+	return input, nil
+}
+
+func buildConverseContentBlock(block ConverseContentBlock) (bedrockruntimeTypes.ContentBlock, error) {
+	switch {
+	case block.Text != nil:
+		return &bedrockruntimeTypes.ContentBlockMemberText{Value: *block.Text}, nil
+	case block.Image != nil:
+		data, err := base64.StdEncoding.DecodeString(block.Image.Source.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "decode image bytes")
+		}
+		return &bedrockruntimeTypes.ContentBlockMemberImage{Value: bedrockruntimeTypes.ImageBlock{
+			Format: bedrockruntimeTypes.ImageFormat(block.Image.Format),
+			Source: &bedrockruntimeTypes.ImageSourceMemberBytes{Value: data},
+		}}, nil
+	case block.ToolUse != nil:
+		input := block.ToolUse.Input
+		if input == nil {
+			input = map[string]any{}
+		}
+		return &bedrockruntimeTypes.ContentBlockMemberToolUse{Value: bedrockruntimeTypes.ToolUseBlock{
+			ToolUseId: aws.String(block.ToolUse.ToolUseId),
+			Name:      aws.String(block.ToolUse.Name),
+			Input:     document.NewLazyDocument(input),
+		}}, nil
+	case block.ToolResult != nil:
+		result := bedrockruntimeTypes.ToolResultBlock{
+			ToolUseId: aws.String(block.ToolResult.ToolUseId),
+			Status:    bedrockruntimeTypes.ToolResultStatus(block.ToolResult.Status),
+		}
+		for _, content := range block.ToolResult.Content {
+			result.Content = append(result.Content, &bedrockruntimeTypes.ToolResultContentBlockMemberText{Value: content.Text})
+		}
+		return &bedrockruntimeTypes.ContentBlockMemberToolResult{Value: result}, nil
+	}
+	return nil, nil
+}
+
+// converseFinishReason 将 Converse 的 stopReason 映射为 OpenAI 的 finish_reason
+func converseFinishReason(stopReason bedrockruntimeTypes.StopReason) string {
+	switch stopReason {
+	case bedrockruntimeTypes.StopReasonToolUse:
+		return constant.FinishReasonToolCalls
+	case bedrockruntimeTypes.StopReasonMaxTokens, bedrockruntimeTypes.StopReasonModelContextWindowExceeded:
+		return constant.FinishReasonLength
+	case bedrockruntimeTypes.StopReasonGuardrailIntervened, bedrockruntimeTypes.StopReasonContentFiltered:
+		return constant.FinishReasonContentFilter
+	}
+	return constant.FinishReasonStop
+}
+
+// converseUsage 缓存读写的 token 计入 prompt_tokens，与 OpenAI 口径一致
+func converseUsage(tokenUsage *bedrockruntimeTypes.TokenUsage) *dto.Usage {
+	usage := &dto.Usage{}
+	if tokenUsage == nil {
+		return usage
+	}
+	cacheRead := int(aws.ToInt32(tokenUsage.CacheReadInputTokens))
+	cacheWrite := int(aws.ToInt32(tokenUsage.CacheWriteInputTokens))
+	usage.PromptTokens = int(aws.ToInt32(tokenUsage.InputTokens)) + cacheRead + cacheWrite
+	usage.CompletionTokens = int(aws.ToInt32(tokenUsage.OutputTokens))
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	usage.PromptTokensDetails.CachedTokens = cacheRead
+	usage.PromptTokensDetails.CachedCreationTokens = cacheWrite
+	return usage
+}
+
+func converseToolArguments(input document.Interface) string {
+	if input == nil {
+		return "{}"
+	}
+	data, err := input.MarshalSmithyDocument()
+	if err != nil || len(data) == 0 {
+		return "{}"
+	}
+	return string(data)
+}
+
+func converseHandler(c *gin.Context, info *relaycommon.RelayInfo, a *Adaptor) (*types.NewAPIError, *dto.Usage) {
+	ctx, cancel := newAwsInvokeContext()
+	defer cancel()
+
+	awsResp, err := a.AwsClient.Converse(ctx, a.AwsReq.(*bedrockruntime.ConverseInput))
+	if err != nil {
+		statusCode := getAwsErrorStatusCode(err)
+		return types.NewOpenAIError(errors.Wrap(err, "Converse"), types.ErrorCodeAwsInvokeError, statusCode), nil
+	}
+
+	message := dto.Message{Role: "assistant"}
+	var texts, reasoning []string
+	var toolCalls []dto.ToolCallResponse
+	if output, ok := awsResp.Output.(*bedrockruntimeTypes.ConverseOutputMemberMessage); ok {
+		for _, block := range output.Value.Content {
+			switch v := block.(type) {
+			case *bedrockruntimeTypes.ContentBlockMemberText:
+				texts = append(texts, v.Value)
+			case *bedrockruntimeTypes.ContentBlockMemberReasoningContent:
+				if reasoningText, ok := v.Value.(*bedrockruntimeTypes.ReasoningContentBlockMemberReasoningText); ok {
+					reasoning = append(reasoning, aws.ToString(reasoningText.Value.Text))
+				}
+			case *bedrockruntimeTypes.ContentBlockMemberToolUse:
+				toolCalls = append(toolCalls, dto.ToolCallResponse{
+					ID:   aws.ToString(v.Value.ToolUseId),
+					Type: "function",
+					Function: dto.FunctionResponse{
+						Name:      aws.ToString(v.Value.Name),
+						Arguments: converseToolArguments(v.Value.Input),
+					},
+				})
+			}
+		}
+	}
+	message.SetStringContent(strings.Join(texts, ""))
+	message.ReasoningContent = strings.Join(reasoning, "")
+	if len(toolCalls) > 0 {
+		message.SetToolCalls(toolCalls)
+	}
+
+	usage := converseUsage(awsResp.Usage)
+	response := &dto.OpenAITextResponse{
+		Id:      helper.GetResponseID(c),
+		Object:  "chat.completion",
+		Created: common.GetTimestamp(),
+		Model:   info.UpstreamModelName,
+		Choices: []dto.OpenAITextResponseChoice{{
+			Index:        0,
+			Message:      message,
+			FinishReason: converseFinishReason(awsResp.StopReason),
+		}},
+		Usage: *usage,
+	}
+
+	var responseBody any = response
+	if info.RelayFormat == types.RelayFormatClaude {
+		responseBody = service.ResponseOpenAI2Claude(response, info)
+	}
+	c.JSON(http.StatusOK, responseBody)
+	return nil, usage
+}
+
+func converseStreamHandler(c *gin.Context, info *relaycommon.RelayInfo, a *Adaptor) (*types.NewAPIError, *dto.Usage) {
+	ctx, cancel := newAwsInvokeContext()
+	defer cancel()
+
+	awsResp, err := a.AwsClient.ConverseStream(ctx, a.AwsReq.(*bedrockruntime.ConverseStreamInput))
+	if err != nil {
+		statusCode := getAwsErrorStatusCode(err)
+		return types.NewOpenAIError(errors.Wrap(err, "ConverseStream"), types.ErrorCodeAwsInvokeError, statusCode), nil
+	}
+	stream := awsResp.GetStream()
+	defer stream.Close()
+
+	helper.SetEventStreamHeaders(c)
+	responseId := helper.GetResponseID(c)
+	createdAt := common.GetTimestamp()
+	usage := &dto.Usage{}
+	responseText := strings.Builder{}
+	finishReason := constant.FinishReasonStop
+	// 内容块下标 -> OpenAI tool_calls 下标
+	toolIndexes := make(map[int32]int)
+
+	sendChunk := func(delta dto.ChatCompletionsStreamResponseChoiceDelta) {
+		chunk := &dto.ChatCompletionsStreamResponse{
+			Id:      responseId,
+			Object:  "chat.completion.chunk",
+			Created: createdAt,
+			Model:   info.UpstreamModelName,
+			Choices: []dto.ChatCompletionsStreamResponseChoice{{Index: 0, Delta: delta}},
+		}
+		if err := sendConverseStreamChunk(c, info, chunk); err != nil {
+			logger.LogError(c, err.Error())
+		}
+	}
+
+	for event := range stream.Events() {
+		info.SetFirstResponseTime()
+		switch v := event.(type) {
+		case *bedrockruntimeTypes.ConverseStreamOutputMemberMessageStart:
+			sendChunk(dto.ChatCompletionsStreamResponseChoiceDelta{Role: "assistant"})
+		case *bedrockruntimeTypes.ConverseStreamOutputMemberContentBlockStart:
+			toolUse, ok := v.Value.Start.(*bedrockruntimeTypes.ContentBlockStartMemberToolUse)
+			if !ok {
+				continue
+			}
+			index := len(toolIndexes)
+			toolIndexes[aws.ToInt32(v.Value.ContentBlockIndex)] = index
+			toolCall := dto.ToolCallResponse{
+				ID:       aws.ToString(toolUse.Value.ToolUseId),
+				Type:     "function",
+				Function: dto.FunctionResponse{Name: aws.ToString(toolUse.Value.Name)},
+			}
+			toolCall.SetIndex(index)
+			sendChunk(dto.ChatCompletionsStreamResponseChoiceDelta{ToolCalls: []dto.ToolCallResponse{toolCall}})
+		case *bedrockruntimeTypes.ConverseStreamOutputMemberContentBlockDelta:
+			delta := dto.ChatCompletionsStreamResponseChoiceDelta{}
+			switch d := v.Value.Delta.(type) {
+			case *bedrockruntimeTypes.ContentBlockDeltaMemberText:
+				responseText.WriteString(d.Value)
+				delta.SetContentString(d.Value)
+			case *bedrockruntimeTypes.ContentBlockDeltaMemberReasoningContent:
+				text, ok := d.Value.(*bedrockruntimeTypes.ReasoningContentBlockDeltaMemberText)
+				if !ok {
+					continue
+				}
+				responseText.WriteString(text.Value)
+				delta.SetReasoningContent(text.Value)
+			case *bedrockruntimeTypes.ContentBlockDeltaMemberToolUse:
+				arguments := aws.ToString(d.Value.Input)
+				responseText.WriteString(arguments)
+				toolCall := dto.ToolCallResponse{Function: dto.FunctionResponse{Arguments: arguments}}
+				toolCall.SetIndex(toolIndexes[aws.ToInt32(v.Value.ContentBlockIndex)])
+				delta.ToolCalls = []dto.ToolCallResponse{toolCall}
+			default:
+				continue
+			}
+			sendChunk(delta)
+		case *bedrockruntimeTypes.ConverseStreamOutputMemberMessageStop:
+			finishReason = converseFinishReason(v.Value.StopReason)
+		case *bedrockruntimeTypes.ConverseStreamOutputMemberMetadata:
+			usage = converseUsage(v.Value.Usage)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return types.NewOpenAIError(errors.Wrap(err, "ConverseStream"), types.ErrorCodeAwsInvokeError, getAwsErrorStatusCode(err)), nil
+	}
+
+	if usage.TotalTokens == 0 {
+		usage = service.ResponseText2Usage(c, responseText.String(), info.UpstreamModelName, info.GetEstimatePromptTokens())
+	}
+	if err := sendConverseStreamChunk(c, info, helper.GenerateStopResponse(responseId, createdAt, info.UpstreamModelName, finishReason)); err != nil {
+		logger.LogError(c, err.Error())
+	}
+	final := helper.GenerateFinalUsageResponse(responseId, createdAt, info.UpstreamModelName, *usage)
+	finalData, err := common.Marshal(final)
+	if err == nil {
+		openai.HandleFinalResponse(c, info, string(finalData), responseId, createdAt, info.UpstreamModelName, "", usage, false)
+	}
+	return nil, usage
+}
+
+// sendConverseStreamChunk 以 OpenAI 流式块为中间格式，按客户端请求的格式输出
+func sendConverseStreamChunk(c *gin.Context, info *relaycommon.RelayInfo, chunk *dto.ChatCompletionsStreamResponse) error {
+	data, err := common.Marshal(chunk)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stream response: %w", err)
+	}
+	return openai.HandleStreamFormat(c, info, string(data), info.ChannelSetting.ForceFormat, info.ChannelSetting.ThinkingToContent)
+}
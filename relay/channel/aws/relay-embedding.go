@@ -0,0 +1,193 @@
+package aws
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"golang.org/x/sync/errgroup"
+)
+
+// awsEmbeddingConcurrency 同一请求拆分出的批次并发调用数
+const awsEmbeddingConcurrency = 4
+
+// Bedrock 在响应头中返回输入 token 数，按此计费
+const awsInputTokenCountHeader = "X-Amzn-Bedrock-Input-Token-Count"
+
+func convertOpenAI2AwsEmbeddingRequest(modelId string, request dto.EmbeddingRequest) (*AwsEmbeddingRequest, error) {
+	inputs := request.ParseInput()
+	if len(inputs) == 0 {
+		return nil, errors.New("input is empty")
+	}
+	embeddingReq := &AwsEmbeddingRequest{Texts: inputs}
+	dimensions := lo.FromPtrOr(request.Dimensions, 0)
+	if isCohereEmbeddingModel(modelId) {
+		embeddingReq.InputType = "search_document"
+		embeddingReq.Truncate = "END"
+		if dimensions > 0 {
+			// 仅 Embed v4 支持指定输出维度
+			if !strings.Contains(modelId, "embed-v4") {
+				return nil, fmt.Errorf("model %s does not support dimensions", modelId)
+			}
+			if !lo.Contains([]int{256, 512, 1024, 1536}, dimensions) {
+				return nil, errors.New("dimensions must be one of 256, 512, 1024, 1536")
+			}
+			embeddingReq.Dimensions = request.Dimensions
+		}
+		return embeddingReq, nil
+	}
+	if dimensions > 0 {
+		if !strings.Contains(modelId, "titan-embed-text-v2") {
+			return nil, fmt.Errorf("model %s does not support dimensions", modelId)
+		}
+		if !lo.Contains([]int{256, 512, 1024}, dimensions) {
+			return nil, errors.New("dimensions must be one of 256, 512, 1024")
+		}
+		embeddingReq.Dimensions = request.Dimensions
+	}
+	return embeddingReq, nil
+}
+
+// buildAwsEmbeddingInputs 按模型单次调用上限拆分批次，生成各批次的 InvokeModel 请求
+func buildAwsEmbeddingInputs(req *AwsEmbeddingRequest, modelId string) ([]*bedrockruntime.InvokeModelInput, error) {
+	if len(req.Texts) == 0 {
+		return nil, errors.New("input is empty")
+	}
+	inputs := make([]*bedrockruntime.InvokeModelInput, 0)
+	for _, texts := range lo.Chunk(req.Texts, awsEmbeddingBatchSize(modelId)) {
+		var body any
+		if isCohereEmbeddingModel(modelId) {
+			body = CohereEmbeddingRequest{
+				Texts:           texts,
+				InputType:       lo.CoalesceOrEmpty(req.InputType, "search_document"),
+				Truncate:        req.Truncate,
+				EmbeddingTypes:  []string{"float"},
+				OutputDimension: req.Dimensions,
+			}
+		} else {
+			body = TitanEmbeddingRequest{
+				InputText:  texts[0],
+				Dimensions: req.Dimensions,
+				Normalize:  req.Normalize,
+			}
+		}
+		data, err := common.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, &bedrockruntime.InvokeModelInput{
+			ModelId:     aws.String(modelId),
+			Accept:      aws.String("application/json"),
+			ContentType: aws.String("application/json"),
+			Body:        data,
+		})
+	}
+	return inputs, nil
+}
+
+// parseAwsEmbeddingResponse 解析单个批次的响应，返回向量与输入 token 数
+func parseAwsEmbeddingResponse(modelId string, output *bedrockruntime.InvokeModelOutput) ([][]float64, int, error) {
+	tokens := 0
+	if rawResp, ok := awsmiddleware.GetRawResponse(output.ResultMetadata).(*smithyhttp.Response); ok {
+		tokens, _ = strconv.Atoi(rawResp.Header.Get(awsInputTokenCountHeader))
+	}
+	if isCohereEmbeddingModel(modelId) {
+		var cohereResp CohereEmbeddingResponse
+		if err := common.Unmarshal(output.Body, &cohereResp); err != nil {
+			return nil, 0, err
+		}
+		var byType struct {
+			Float [][]float64 `json:"float"`
+		}
+		if err := common.Unmarshal(cohereResp.Embeddings, &byType); err == nil {
+			return byType.Float, tokens, nil
+		}
+		var embeddings [][]float64
+		if err := common.Unmarshal(cohereResp.Embeddings, &embeddings); err != nil {
+			return nil, 0, err
+		}
+		return embeddings, tokens, nil
+	}
+	var titanResp TitanEmbeddingResponse
+	if err := common.Unmarshal(output.Body, &titanResp); err != nil {
+		return nil, 0, err
+	}
+	if tokens == 0 {
+		tokens = titanResp.InputTextTokenCount
+	}
+	return [][]float64{titanResp.Embedding}, tokens, nil
+}
+
+func awsEmbeddingHandler(c *gin.Context, info *relaycommon.RelayInfo, a *Adaptor) (*types.NewAPIError, *dto.Usage) {
+	ctx, cancel := newAwsInvokeContext()
+	defer cancel()
+
+	inputs := a.AwsReq.([]*bedrockruntime.InvokeModelInput)
+	embeddings := make([][][]float64, len(inputs))
+	promptTokens := 0
+	var lock sync.Mutex
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(awsEmbeddingConcurrency)
+	for i, input := range inputs {
+		group.Go(func() error {
+			output, err := a.AwsClient.InvokeModel(groupCtx, input)
+			if err != nil {
+				return err
+			}
+			vectors, tokens, err := parseAwsEmbeddingResponse(aws.ToString(input.ModelId), output)
+			if err != nil {
+				return types.NewError(errors.Wrap(err, "unmarshal embedding response"), types.ErrorCodeBadResponseBody)
+			}
+			lock.Lock()
+			embeddings[i] = vectors
+			promptTokens += tokens
+			lock.Unlock()
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		var apiErr *types.NewAPIError
+		if errors.As(err, &apiErr) {
+			return apiErr, nil
+		}
+		return types.NewOpenAIError(errors.Wrap(err, "InvokeModel"), types.ErrorCodeAwsInvokeError, getAwsErrorStatusCode(err)), nil
+	}
+
+	response := dto.OpenAIEmbeddingResponse{
+		Object: "list",
+		Data:   make([]dto.OpenAIEmbeddingResponseItem, 0, len(inputs)),
+		Model:  info.UpstreamModelName,
+	}
+	for _, vectors := range embeddings {
+		for _, vector := range vectors {
+			response.Data = append(response.Data, dto.OpenAIEmbeddingResponseItem{
+				Object:    "embedding",
+				Index:     len(response.Data),
+				Embedding: vector,
+			})
+		}
+	}
+	if promptTokens > 0 {
+		response.Usage = dto.Usage{PromptTokens: promptTokens, TotalTokens: promptTokens}
+	} else {
+		response.Usage = *service.ResponseText2Usage(c, "", info.UpstreamModelName, info.GetEstimatePromptTokens())
+	}
+
+	c.JSON(http.StatusOK, response)
+	return nil, &response.Usage
+}
@@ -4,12 +4,19 @@ import (
 	"bytes"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/types"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	bedrockruntimeTypes "github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
 	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/require"
 )
 
@@ -53,3 +60,221 @@ func TestDoAwsClientRequest_AppliesRuntimeHeaderOverrideToAnthropicBeta(t *testi
 	require.True(t, ok)
 	require.Equal(t, []any{"computer-use-2025-01-24"}, values)
 }
+
+func TestConvertOpenAI2ConverseRequest(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+
+	var request dto.GeneralOpenAIRequest
+	require.NoError(t, common.UnmarshalJsonStr(`{
+		"model": "meta.llama3-3-70b-instruct-v1:0",
+		"max_tokens": 256,
+		"stop": "END",
+		"messages": [
+			{"role": "system", "content": "be brief"},
+			{"role": "user", "content": "weather in Paris and Rome?"},
+			{"role": "assistant", "content": "", "tool_calls": [
+				{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}},
+				{"id": "call_2", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Rome\"}"}}
+			]},
+			{"role": "tool", "tool_call_id": "call_1", "content": "sunny"},
+			{"role": "tool", "tool_call_id": "call_2", "content": "rainy"}
+		],
+		"tools": [{"type": "function", "function": {"name": "get_weather", "parameters": {"type": "object", "properties": {"city": {"type": "string"}}}}}],
+		"tool_choice": "required"
+	}`, &request))
+
+	converseReq, err := convertOpenAI2ConverseRequest(ctx, &request)
+	require.NoError(t, err)
+	require.Equal(t, []ConverseSystemContent{{Text: "be brief"}}, converseReq.System)
+	require.Len(t, converseReq.Messages, 3)
+	require.Equal(t, "assistant", converseReq.Messages[1].Role)
+	require.Len(t, converseReq.Messages[1].Content, 2)
+	require.Equal(t, map[string]any{"city": "Paris"}, converseReq.Messages[1].Content[0].ToolUse.Input)
+	// 连续的工具结果合并到同一条 user 消息
+	require.Equal(t, "user", converseReq.Messages[2].Role)
+	require.Len(t, converseReq.Messages[2].Content, 2)
+	require.Equal(t, "call_2", converseReq.Messages[2].Content[1].ToolResult.ToolUseId)
+	require.Equal(t, 256, *converseReq.InferenceConfig.MaxTokens)
+	require.Equal(t, []string{"END"}, converseReq.InferenceConfig.StopSequences)
+	require.NotNil(t, converseReq.ToolConfig.ToolChoice.Any)
+
+	input, err := buildConverseInput(converseReq, "us.meta.llama3-3-70b-instruct-v1:0")
+	require.NoError(t, err)
+	require.Len(t, input.Messages, 3)
+	require.Len(t, input.ToolConfig.Tools, 1)
+}
+
+func TestIsConverseModel(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]bool{
+		"meta.llama3-3-70b-instruct-v1:0":        true,
+		"us.meta.llama4-scout-17b-instruct-v1:0": true,
+		"deepseek.r1-v1:0":                       true,
+		"qwen.qwen3-32b-v1:0":                    true,
+		"arn:aws:bedrock:us-east-1:123456789012:inference-profile/us.mistral.pixtral-large-2502-v1:0": true,
+		"anthropic.claude-3-5-sonnet-20241022-v2:0":                                                   false,
+		"us.amazon.nova-pro-v1:0":                                                                     false,
+		// 应用推理配置文件ARN无法识别提供方，保持InvokeModel
+		"arn:aws:bedrock:us-east-1:123456789012:application-inference-profile/a1b2c3d4e5f6": false,
+		"custom-model": false,
+	}
+	for modelId, expected := range cases {
+		require.Equal(t, expected, isConverseModel(modelId), modelId)
+	}
+}
+
+func TestConverseAndEmbeddingUnsupportedInApiKeyMode(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	info := &relaycommon.RelayInfo{
+		ChannelMeta: &relaycommon.ChannelMeta{
+			UpstreamModelName:    "meta.llama3-3-70b-instruct-v1:0",
+			ChannelOtherSettings: dto.ChannelOtherSettings{AwsKeyType: dto.AwsKeyTypeApiKey},
+		},
+	}
+
+	adaptor := &Adaptor{}
+	_, err := adaptor.ConvertOpenAIRequest(ctx, info, &dto.GeneralOpenAIRequest{Model: "meta.llama3-3-70b-instruct-v1:0"})
+	require.ErrorContains(t, err, "unsupported in API-key mode")
+	require.False(t, adaptor.IsConverse)
+	_, err = adaptor.ConvertEmbeddingRequest(ctx, info, dto.EmbeddingRequest{Model: "amazon.titan-embed-text-v2:0", Input: "hi"})
+	require.ErrorContains(t, err, "unsupported in API-key mode")
+	require.False(t, adaptor.IsEmbedding)
+}
+
+func TestConverseHandler(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/model/deepseek.r1-v1:0/converse", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"output":{"message":{"role":"assistant","content":[
+			{"reasoningContent":{"reasoningText":{"text":"thinking"}}},
+			{"text":"Let me check."},
+			{"toolUse":{"toolUseId":"tool_1","name":"get_weather","input":{"city":"Paris"}}}
+		]}},"stopReason":"tool_use","usage":{"inputTokens":10,"outputTokens":5,"totalTokens":15,"cacheReadInputTokens":4},"metrics":{"latencyMs":1}}`))
+	}))
+	defer server.Close()
+
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+
+	adaptor := &Adaptor{
+		IsConverse: true,
+		AwsClient: bedrockruntime.New(bedrockruntime.Options{
+			Region:       "us-east-1",
+			BaseEndpoint: aws.String(server.URL),
+			Credentials:  credentials.NewStaticCredentialsProvider("ak", "sk", ""),
+		}),
+		AwsReq: &bedrockruntime.ConverseInput{
+			ModelId: aws.String("deepseek.r1-v1:0"),
+			Messages: []bedrockruntimeTypes.Message{{
+				Role:    bedrockruntimeTypes.ConversationRoleUser,
+				Content: []bedrockruntimeTypes.ContentBlock{&bedrockruntimeTypes.ContentBlockMemberText{Value: "hi"}},
+			}},
+		},
+	}
+	info := &relaycommon.RelayInfo{
+		RelayFormat: types.RelayFormatOpenAI,
+		ChannelMeta: &relaycommon.ChannelMeta{UpstreamModelName: "deepseek.r1-v1:0"},
+	}
+
+	apiErr, usage := converseHandler(ctx, info, adaptor)
+	require.Nil(t, apiErr)
+	require.Equal(t, 14, usage.PromptTokens)
+	require.Equal(t, 4, usage.PromptTokensDetails.CachedTokens)
+	require.Equal(t, 19, usage.TotalTokens)
+
+	var response dto.OpenAITextResponse
+	require.NoError(t, common.Unmarshal(recorder.Body.Bytes(), &response))
+	require.Equal(t, "tool_calls", response.Choices[0].FinishReason)
+	require.Equal(t, "Let me check.", response.Choices[0].Message.StringContent())
+	require.Equal(t, "thinking", response.Choices[0].Message.ReasoningContent)
+	toolCalls := response.Choices[0].Message.ParseToolCalls()
+	require.Len(t, toolCalls, 1)
+	require.Equal(t, "tool_1", toolCalls[0].ID)
+	require.JSONEq(t, `{"city":"Paris"}`, toolCalls[0].Function.Arguments)
+}
+
+func TestAwsEmbeddingHandler(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/model/amazon.titan-embed-text-v2:0/invoke", r.URL.Path)
+		var titanReq TitanEmbeddingRequest
+		require.NoError(t, common.DecodeJson(r.Body, &titanReq))
+		require.Equal(t, 256, *titanReq.Dimensions)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set(awsInputTokenCountHeader, "3")
+		_, _ = w.Write([]byte(`{"embedding":[` + strconv.Itoa(len(titanReq.InputText)) + `],"inputTextTokenCount":3}`))
+	}))
+	defer server.Close()
+
+	request := dto.EmbeddingRequest{Input: []any{"a", "bb", "ccc"}, Dimensions: lo.ToPtr(256)}
+	embeddingReq, err := convertOpenAI2AwsEmbeddingRequest("amazon.titan-embed-text-v2:0", request)
+	require.NoError(t, err)
+	// Titan 每次调用仅支持一条输入
+	inputs, err := buildAwsEmbeddingInputs(embeddingReq, "amazon.titan-embed-text-v2:0")
+	require.NoError(t, err)
+	require.Len(t, inputs, 3)
+
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v1/embeddings", nil)
+	adaptor := &Adaptor{
+		IsEmbedding: true,
+		AwsClient: bedrockruntime.New(bedrockruntime.Options{
+			Region:       "us-east-1",
+			BaseEndpoint: aws.String(server.URL),
+			Credentials:  credentials.NewStaticCredentialsProvider("ak", "sk", ""),
+		}),
+		AwsReq: inputs,
+	}
+	info := &relaycommon.RelayInfo{ChannelMeta: &relaycommon.ChannelMeta{UpstreamModelName: "titan-embed-text-v2"}}
+
+	apiErr, usage := awsEmbeddingHandler(ctx, info, adaptor)
+	require.Nil(t, apiErr)
+	require.Equal(t, 9, usage.PromptTokens)
+
+	var response dto.OpenAIEmbeddingResponse
+	require.NoError(t, common.Unmarshal(recorder.Body.Bytes(), &response))
+	require.Len(t, response.Data, 3)
+	for i, item := range response.Data {
+		require.Equal(t, i, item.Index)
+		require.Equal(t, []float64{float64(i + 1)}, item.Embedding)
+	}
+}
+
+func TestConvertOpenAI2AwsEmbeddingRequestCohere(t *testing.T) {
+	t.Parallel()
+
+	texts := make([]any, 100)
+	for i := range texts {
+		texts[i] = "text"
+	}
+	embeddingReq, err := convertOpenAI2AwsEmbeddingRequest("cohere.embed-multilingual-v3", dto.EmbeddingRequest{Input: texts})
+	require.NoError(t, err)
+	inputs, err := buildAwsEmbeddingInputs(embeddingReq, "cohere.embed-multilingual-v3")
+	require.NoError(t, err)
+	require.Len(t, inputs, 2)
+
+	var cohereReq CohereEmbeddingRequest
+	require.NoError(t, common.Unmarshal(inputs[0].Body, &cohereReq))
+	require.Len(t, cohereReq.Texts, 96)
+	require.Equal(t, "search_document", cohereReq.InputType)
+	require.Equal(t, "END", cohereReq.Truncate)
+
+	_, err = convertOpenAI2AwsEmbeddingRequest("cohere.embed-multilingual-v3", dto.EmbeddingRequest{Input: "a", Dimensions: lo.ToPtr(512)})
+	require.Error(t, err)
+}
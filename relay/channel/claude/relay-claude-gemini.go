@@ -0,0 +1,532 @@
+package claude
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/model_setting"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+)
+
+const (
+	// 思考预算的最小值，Claude 要求 budget_tokens >= 1024
+	claudeMinThinkingBudget = 1024
+	// Gemini 渠道为缺少签名的 functionCall 填充的占位签名，不是真实签名
+	geminiThoughtSignatureBypassValue = "context_engineering_is_the_way_to_go"
+)
+
+// geminiToolUseBuffer 流式响应中尚未结束的 tool_use 内容块
+type geminiToolUseBuffer struct {
+	name  string
+	input any
+	json  strings.Builder
+}
+
+// RequestGemini2ClaudeMessage 将 Gemini 原生请求直接转换为 Claude Messages 请求，
+// 保留思考签名、工具调用与工具结果的结构
+func RequestGemini2ClaudeMessage(c *gin.Context, geminiRequest dto.GeminiChatRequest, info *relaycommon.RelayInfo) (*dto.ClaudeRequest, error) {
+	claudeRequest := dto.ClaudeRequest{
+		Model: info.UpstreamModelName,
+	}
+	if info.IsStream {
+		claudeRequest.Stream = common.GetPointer(true)
+	}
+
+	config := geminiRequest.GenerationConfig
+	if config.MaxOutputTokens != nil && *config.MaxOutputTokens > 0 {
+		claudeRequest.MaxTokens = common.GetPointer(*config.MaxOutputTokens)
+	} else {
+		claudeRequest.MaxTokens = common.GetPointer(uint(model_setting.GetClaudeSettings().GetDefaultMaxTokens(claudeRequest.Model)))
+	}
+	claudeRequest.Temperature = config.Temperature
+	claudeRequest.TopP = config.TopP
+	if config.TopK != nil {
+		claudeRequest.TopK = common.GetPointer(int(*config.TopK))
+	}
+	if len(config.StopSequences) > 0 {
+		claudeRequest.StopSequences = config.StopSequences
+	}
+	geminiThinking2Claude(&claudeRequest, config.ThinkingConfig)
+
+	if geminiRequest.SystemInstructions != nil {
+		systems := make([]dto.ClaudeMediaMessage, 0, len(geminiRequest.SystemInstructions.Parts))
+		for _, part := range geminiRequest.SystemInstructions.Parts {
+			if part.Text == "" {
+				continue
+			}
+			system := dto.ClaudeMediaMessage{Type: "text"}
+			system.SetText(part.Text)
+			systems = append(systems, system)
+		}
+		if len(systems) > 0 {
+			claudeRequest.System = systems
+		}
+	}
+
+	if tools := geminiTools2Claude(geminiRequest.GetTools()); len(tools) > 0 {
+		claudeRequest.Tools = tools
+		if geminiRequest.ToolConfig != nil {
+			claudeRequest.ToolChoice = geminiToolConfig2Claude(geminiRequest.ToolConfig.FunctionCallingConfig)
+		}
+	}
+
+	// Gemini 的 functionCall 没有 id，按函数名依次为 functionResponse 匹配对应的 tool_use_id
+	pendingToolUseIds := make(map[string][]string)
+	messages := make([]dto.ClaudeMessage, 0, len(geminiRequest.Contents))
+	for _, content := range geminiRequest.Contents {
+		role := "user"
+		if content.Role == "model" {
+			role = "assistant"
+		}
+		blocks := make([]dto.ClaudeMediaMessage, 0, len(content.Parts))
+		for _, part := range content.Parts {
+			block, err := geminiPart2ClaudeBlock(c, &part, pendingToolUseIds)
+			if err != nil {
+				return nil, err
+			}
+			if block != nil {
+				blocks = append(blocks, *block)
+			}
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+		// Claude 要求 user 与 assistant 交替出现，合并相邻的同角色消息
+		if n := len(messages); n > 0 && messages[n-1].Role == role {
+			previous := messages[n-1].Content.([]dto.ClaudeMediaMessage)
+			messages[n-1].Content = append(previous, blocks...)
+			continue
+		}
+		messages = append(messages, dto.ClaudeMessage{Role: role, Content: blocks})
+	}
+	claudeRequest.Messages = messages
+	return &claudeRequest, nil
+}
+
+// geminiThinking2Claude 将 thinkingConfig 转换为 Claude 的 extended thinking 配置
+func geminiThinking2Claude(claudeRequest *dto.ClaudeRequest, thinkingConfig *dto.GeminiThinkingConfig) {
+	budget := 0
+	claudeSettings := model_setting.GetClaudeSettings()
+	if thinkingConfig != nil {
+		if thinkingConfig.ThinkingBudget != nil {
+			budget = *thinkingConfig.ThinkingBudget
+		} else if thinkingConfig.IncludeThoughts || thinkingConfig.ThinkingLevel != "" {
+			// 动态思考
+			budget = -1
+		}
+	}
+	if claudeSettings.ThinkingAdapterEnabled && strings.HasSuffix(claudeRequest.Model, "-thinking") {
+		if budget == 0 {
+			budget = -1
+		}
+		if !model_setting.ShouldPreserveThinkingSuffix(claudeRequest.Model) {
+			claudeRequest.Model = strings.TrimSuffix(claudeRequest.Model, "-thinking")
+		}
+	}
+	if budget == 0 {
+		return
+	}
+	if budget < 0 {
+		budget = int(float64(*claudeRequest.MaxTokens) * claudeSettings.ThinkingAdapterBudgetTokensPercentage)
+	}
+	if budget < claudeMinThinkingBudget {
+		budget = claudeMinThinkingBudget
+	}
+	// budget_tokens 必须小于 max_tokens
+	if int(*claudeRequest.MaxTokens) <= budget {
+		claudeRequest.MaxTokens = common.GetPointer(uint(budget + claudeMinThinkingBudget))
+	}
+	claudeRequest.Thinking = &dto.Thinking{
+		Type:         "enabled",
+		BudgetTokens: common.GetPointer(budget),
+	}
+	// https://docs.anthropic.com/en/docs/build-with-claude/extended-thinking#important-considerations-when-using-extended-thinking
+	claudeRequest.Temperature = common.GetPointer[float64](1.0)
+	claudeRequest.TopP = nil
+	claudeRequest.TopK = nil
+}
+
+// geminiTools2Claude 转换 functionDeclarations 与 googleSearch，其余内置工具 Claude 不支持，直接忽略
+func geminiTools2Claude(tools []dto.GeminiChatTool) []any {
+	claudeTools := make([]any, 0)
+	for _, tool := range tools {
+		if tool.GoogleSearch != nil || tool.GoogleSearchRetrieval != nil {
+			claudeTools = append(claudeTools, &dto.ClaudeWebSearchTool{
+				Type: "web_search_20250305",
+				Name: "web_search",
+			})
+		}
+		if tool.FunctionDeclarations == nil {
+			continue
+		}
+		declarations, err := common.Any2Type[[]map[string]any](tool.FunctionDeclarations)
+		if err != nil {
+			continue
+		}
+		for _, declaration := range declarations {
+			name, _ := declaration["name"].(string)
+			if name == "" {
+				continue
+			}
+			description, _ := declaration["description"].(string)
+			claudeTools = append(claudeTools, &dto.Tool{
+				Name:        name,
+				Description: description,
+				InputSchema: geminiFunctionSchema2Claude(declaration),
+			})
+		}
+	}
+	return claudeTools
+}
+
+// geminiFunctionSchema2Claude 优先使用 parametersJsonSchema（标准 JSON Schema），
+// 否则将 OpenAPI 风格的 parameters 转换为 JSON Schema
+func geminiFunctionSchema2Claude(declaration map[string]any) map[string]any {
+	var schema map[string]any
+	if jsonSchema, ok := declaration["parametersJsonSchema"].(map[string]any); ok {
+		schema = jsonSchema
+	} else if parameters, ok := declaration["parameters"].(map[string]any); ok {
+		schema, _ = geminiSchema2JSONSchema(parameters, 0).(map[string]any)
+	}
+	if schema == nil {
+		schema = map[string]any{}
+	}
+	if _, ok := schema["type"]; !ok {
+		schema["type"] = "object"
+	}
+	if _, ok := schema["properties"]; !ok {
+		schema["properties"] = map[string]any{}
+	}
+	return schema
+}
+
+// geminiSchema2JSONSchema Gemini 的 Schema 类型名为大写（如 STRING），并使用 nullable 表示可空
+func geminiSchema2JSONSchema(schema any, depth int) any {
+	if depth >= 32 {
+		return schema
+	}
+	switch v := schema.(type) {
+	case map[string]any:
+		result := make(map[string]any, len(v))
+		for key, value := range v {
+			switch key {
+			case "propertyOrdering", "nullable":
+				continue
+			case "type":
+				if typeName, ok := value.(string); ok {
+					value = strings.ToLower(typeName)
+				}
+			case "properties":
+				if properties, ok := value.(map[string]any); ok {
+					converted := make(map[string]any, len(properties))
+					for name, property := range properties {
+						converted[name] = geminiSchema2JSONSchema(property, depth+1)
+					}
+					value = converted
+				}
+			default:
+				value = geminiSchema2JSONSchema(value, depth+1)
+			}
+			result[key] = value
+		}
+		if nullable, _ := v["nullable"].(bool); nullable {
+			if typeName, ok := result["type"].(string); ok {
+				result["type"] = []any{typeName, "null"}
+			}
+		}
+		return result
+	case []any:
+		result := make([]any, len(v))
+		for i, item := range v {
+			result[i] = geminiSchema2JSONSchema(item, depth+1)
+		}
+		return result
+	}
+	return schema
+}
+
+func geminiToolConfig2Claude(config *dto.FunctionCallingConfig) *dto.ClaudeToolChoice {
+	if config == nil {
+		return nil
+	}
+	switch strings.ToUpper(string(config.Mode)) {
+	case "AUTO":
+		return &dto.ClaudeToolChoice{Type: "auto"}
+	case "ANY", "VALIDATED":
+		if len(config.AllowedFunctionNames) == 1 {
+			return &dto.ClaudeToolChoice{Type: "tool", Name: config.AllowedFunctionNames[0]}
+		}
+		return &dto.ClaudeToolChoice{Type: "any"}
+	case "NONE":
+		return &dto.ClaudeToolChoice{Type: "none"}
+	}
+	return nil
+}
+
+// geminiPart2ClaudeBlock 将单个 part 转换为 Claude 内容块，返回 nil 表示该 part 不需要转发
+func geminiPart2ClaudeBlock(c *gin.Context, part *dto.GeminiPart, pendingToolUseIds map[string][]string) (*dto.ClaudeMediaMessage, error) {
+	signature := geminiThoughtSignature(part.ThoughtSignature)
+	switch {
+	case part.Thought:
+		// Claude 仅接受带签名的思考块，无签名的思考内容无法回传
+		if signature == "" {
+			return nil, nil
+		}
+		return &dto.ClaudeMediaMessage{
+			Type:      "thinking",
+			Thinking:  common.GetPointer(part.Text),
+			Signature: signature,
+		}, nil
+	case part.FunctionCall != nil:
+		id := "toolu_" + common.GetUUID()
+		name := part.FunctionCall.FunctionName
+		pendingToolUseIds[name] = append(pendingToolUseIds[name], id)
+		input := part.FunctionCall.Arguments
+		if input == nil {
+			input = map[string]any{}
+		}
+		return &dto.ClaudeMediaMessage{
+			Type:  "tool_use",
+			Id:    id,
+			Name:  name,
+			Input: input,
+		}, nil
+	case part.FunctionResponse != nil:
+		return geminiFunctionResponse2ClaudeBlock(part.FunctionResponse, pendingToolUseIds)
+	case part.InlineData != nil:
+		return geminiMedia2ClaudeBlock(part.InlineData.MimeType, &dto.ClaudeMessageSource{
+			Type:      "base64",
+			MediaType: part.InlineData.MimeType,
+			Data:      part.InlineData.Data,
+		}), nil
+	case part.FileData != nil:
+		return geminiMedia2ClaudeBlock(part.FileData.MimeType, &dto.ClaudeMessageSource{
+			Type: "url",
+			Url:  part.FileData.FileUri,
+		}), nil
+	case part.ExecutableCode != nil:
+		return newClaudeTextBlock(fmt.Sprintf("```%s\n%s\n```", strings.ToLower(part.ExecutableCode.Language), part.ExecutableCode.Code)), nil
+	case part.CodeExecutionResult != nil:
+		return newClaudeTextBlock(fmt.Sprintf("```output\n%s\n```", part.CodeExecutionResult.Output)), nil
+	case part.Text != "":
+		return newClaudeTextBlock(part.Text), nil
+	}
+	return nil, nil
+}
+
+func newClaudeTextBlock(text string) *dto.ClaudeMediaMessage {
+	block := &dto.ClaudeMediaMessage{Type: "text"}
+	block.SetText(text)
+	return block
+}
+
+func geminiMedia2ClaudeBlock(mimeType string, source *dto.ClaudeMessageSource) *dto.ClaudeMediaMessage {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return &dto.ClaudeMediaMessage{Type: "image", Source: source}
+	case mimeType == "application/pdf":
+		return &dto.ClaudeMediaMessage{Type: "document", Source: source}
+	}
+	// Claude 不支持的文件类型（音视频等）无法转发
+	return nil
+}
+
+func geminiFunctionResponse2ClaudeBlock(response *dto.GeminiFunctionResponse, pendingToolUseIds map[string][]string) (*dto.ClaudeMediaMessage, error) {
+	var content string
+	isError := false
+	if text, ok := response.Response["content"].(string); ok && len(response.Response) == 1 {
+		content = text
+	} else if errorValue, ok := response.Response["error"]; ok && len(response.Response) == 1 {
+		isError = true
+		if text, ok := errorValue.(string); ok {
+			content = text
+		} else {
+			content = common.GetJsonString(errorValue)
+		}
+	} else {
+		data, err := common.Marshal(response.Response)
+		if err != nil {
+			return nil, err
+		}
+		content = string(data)
+	}
+
+	ids := pendingToolUseIds[response.Name]
+	if len(ids) == 0 {
+		// 没有对应的 tool_use，Claude 会拒绝孤立的 tool_result，退化为文本
+		return newClaudeTextBlock(fmt.Sprintf("Function %s returned: %s", response.Name, content)), nil
+	}
+	pendingToolUseIds[response.Name] = ids[1:]
+	return &dto.ClaudeMediaMessage{
+		Type:      "tool_result",
+		ToolUseId: ids[0],
+		Content:   content,
+		IsError:   isError,
+	}, nil
+}
+
+// geminiThoughtSignature 解析 thoughtSignature，忽略用于跳过签名校验的占位值
+func geminiThoughtSignature(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var signature string
+	if err := common.Unmarshal(raw, &signature); err != nil {
+		return ""
+	}
+	if signature == geminiThoughtSignatureBypassValue {
+		return ""
+	}
+	return signature
+}
+
+func stopReasonClaude2Gemini(reason string) string {
+	switch reason {
+	case "max_tokens":
+		return "MAX_TOKENS"
+	case "refusal":
+		return "SAFETY"
+	}
+	return "STOP"
+}
+
+// buildGeminiUsageMetadataFromClaudeUsage promptTokenCount 包含缓存命中与缓存写入的 token
+func buildGeminiUsageMetadataFromClaudeUsage(usage *dto.Usage) dto.GeminiUsageMetadata {
+	if usage == nil {
+		return dto.GeminiUsageMetadata{}
+	}
+	promptTokens := usage.PromptTokens + usage.PromptTokensDetails.CachedTokens + cacheCreationTokensForOpenAIUsage(usage)
+	return dto.GeminiUsageMetadata{
+		PromptTokenCount:        promptTokens,
+		CandidatesTokenCount:    usage.CompletionTokens,
+		CachedContentTokenCount: usage.PromptTokensDetails.CachedTokens,
+		TotalTokenCount:         promptTokens + usage.CompletionTokens,
+	}
+}
+
+func quoteThoughtSignature(signature string) json.RawMessage {
+	data, _ := common.Marshal(signature)
+	return data
+}
+
+// ResponseClaude2Gemini 将 Claude 非流式响应转换为 Gemini generateContent 响应
+func ResponseClaude2Gemini(claudeResponse *dto.ClaudeResponse, usage *dto.Usage) *dto.GeminiChatResponse {
+	parts := make([]dto.GeminiPart, 0, len(claudeResponse.Content))
+	for _, block := range claudeResponse.Content {
+		switch block.Type {
+		case "thinking":
+			part := dto.GeminiPart{Thought: true}
+			if block.Thinking != nil {
+				part.Text = *block.Thinking
+			}
+			if block.Signature != "" {
+				part.ThoughtSignature = quoteThoughtSignature(block.Signature)
+			}
+			parts = append(parts, part)
+		case "text":
+			parts = append(parts, dto.GeminiPart{Text: block.GetText()})
+		case "tool_use":
+			parts = append(parts, dto.GeminiPart{
+				FunctionCall: &dto.FunctionCall{
+					FunctionName: block.Name,
+					Arguments:    block.Input,
+				},
+			})
+		}
+	}
+	return &dto.GeminiChatResponse{
+		Candidates: []dto.GeminiChatCandidate{
+			{
+				Content:      dto.GeminiChatContent{Role: "model", Parts: parts},
+				FinishReason: common.GetPointer(stopReasonClaude2Gemini(claudeResponse.StopReason)),
+			},
+		},
+		UsageMetadata: buildGeminiUsageMetadataFromClaudeUsage(usage),
+	}
+}
+
+func newGeminiStreamChunk(parts []dto.GeminiPart) *dto.GeminiChatResponse {
+	return &dto.GeminiChatResponse{
+		Candidates: []dto.GeminiChatCandidate{
+			{Content: dto.GeminiChatContent{Role: "model", Parts: parts}},
+		},
+	}
+}
+
+// StreamResponseClaude2Gemini 将 Claude 流式事件转换为 Gemini 流式响应块，返回 nil 表示该事件无需输出
+// tool_use 的参数在 content_block_stop 时才完整，因此缓存到内容块结束后一次性输出 functionCall
+func StreamResponseClaude2Gemini(claudeResponse *dto.ClaudeResponse, claudeInfo *ClaudeResponseInfo) *dto.GeminiChatResponse {
+	index := 0
+	if claudeResponse.Index != nil {
+		index = *claudeResponse.Index
+	}
+	switch claudeResponse.Type {
+	case "content_block_start":
+		if claudeResponse.ContentBlock != nil && claudeResponse.ContentBlock.Type == "tool_use" {
+			if claudeInfo.geminiToolUses == nil {
+				claudeInfo.geminiToolUses = make(map[int]*geminiToolUseBuffer)
+			}
+			claudeInfo.geminiToolUses[index] = &geminiToolUseBuffer{
+				name:  claudeResponse.ContentBlock.Name,
+				input: claudeResponse.ContentBlock.Input,
+			}
+		}
+	case "content_block_delta":
+		delta := claudeResponse.Delta
+		if delta == nil {
+			return nil
+		}
+		switch delta.Type {
+		case "text_delta":
+			if delta.Text != nil && *delta.Text != "" {
+				return newGeminiStreamChunk([]dto.GeminiPart{{Text: *delta.Text}})
+			}
+		case "thinking_delta":
+			if delta.Thinking != nil && *delta.Thinking != "" {
+				return newGeminiStreamChunk([]dto.GeminiPart{{Text: *delta.Thinking, Thought: true}})
+			}
+		case "signature_delta":
+			if delta.Signature != "" {
+				return newGeminiStreamChunk([]dto.GeminiPart{{Thought: true, ThoughtSignature: quoteThoughtSignature(delta.Signature)}})
+			}
+		case "input_json_delta":
+			if buffer, ok := claudeInfo.geminiToolUses[index]; ok && delta.PartialJson != nil {
+				buffer.json.WriteString(*delta.PartialJson)
+			}
+		}
+	case "content_block_stop":
+		buffer, ok := claudeInfo.geminiToolUses[index]
+		if !ok {
+			return nil
+		}
+		delete(claudeInfo.geminiToolUses, index)
+		args := buffer.input
+		if buffer.json.Len() > 0 {
+			var parsed map[string]any
+			if err := common.UnmarshalJsonStr(buffer.json.String(), &parsed); err == nil {
+				args = parsed
+			}
+		}
+		if args == nil {
+			args = map[string]any{}
+		}
+		return newGeminiStreamChunk([]dto.GeminiPart{{
+			FunctionCall: &dto.FunctionCall{FunctionName: buffer.name, Arguments: args},
+		}})
+	case "message_delta":
+		stopReason := claudeResponse.StopReason
+		if claudeResponse.Delta != nil && claudeResponse.Delta.StopReason != nil {
+			stopReason = *claudeResponse.Delta.StopReason
+		}
+		response := newGeminiStreamChunk([]dto.GeminiPart{})
+		response.Candidates[0].FinishReason = common.GetPointer(stopReasonClaude2Gemini(stopReason))
+		response.UsageMetadata = buildGeminiUsageMetadataFromClaudeUsage(claudeInfo.Usage)
+		return response
+	}
+	return nil
+}
@@ -562,6 +562,8 @@ type ClaudeResponseInfo struct {
 	ResponseText strings.Builder
 	Usage        *dto.Usage
 	Done         bool
+	// 转换为 Gemini 格式时缓存未结束的 tool_use 内容块
+	geminiToolUses map[int]*geminiToolUseBuffer
 }
 
 func cacheCreationTokensForOpenAIUsage(usage *dto.Usage) int {
@@ -793,6 +795,16 @@ func HandleStreamResponseData(c *gin.Context, info *relaycommon.RelayInfo, claud
 			return nil
 		}
 
+		err = helper.ObjectData(c, response)
+		if err != nil {
+			logger.LogError(c, "send_stream_response_failed: "+err.Error())
+		}
+	} else if info.RelayFormat == types.RelayFormatGemini {
+		FormatClaudeResponseInfo(&claudeResponse, nil, claudeInfo)
+		response := StreamResponseClaude2Gemini(&claudeResponse, claudeInfo)
+		if response == nil {
+			return nil
+		}
 		err = helper.ObjectData(c, response)
 		if err != nil {
 			logger.LogError(c, "send_stream_response_failed: "+err.Error())
@@ -892,6 +904,12 @@ func HandleClaudeResponseData(c *gin.Context, info *relaycommon.RelayInfo, claud
 		}
 	case types.RelayFormatClaude:
 		responseData = data
+	case types.RelayFormatGemini:
+		geminiResponse := ResponseClaude2Gemini(&claudeResponse, claudeInfo.Usage)
+		responseData, err = json.Marshal(geminiResponse)
+		if err != nil {
+			return types.NewError(err, types.ErrorCodeBadResponseBody)
+		}
 	}
 
 	if claudeResponse.Usage != nil && claudeResponse.Usage.ServerToolUse != nil && claudeResponse.Usage.ServerToolUse.WebSearchRequests > 0 {
@@ -0,0 +1,88 @@
+package claude
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestGemini2ClaudeMessage_PairsToolResultsAndSignatures(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1beta/models/claude-sonnet-4:generateContent", nil)
+
+	var geminiRequest dto.GeminiChatRequest
+	require.NoError(t, common.Unmarshal([]byte(`{
+		"systemInstruction": {"parts": [{"text": "be brief"}]},
+		"contents": [
+			{"role": "user", "parts": [{"text": "weather?"}]},
+			{"role": "model", "parts": [
+				{"text": "need tool", "thought": true, "thoughtSignature": "sig-1"},
+				{"functionCall": {"name": "get_weather", "args": {"city": "Paris"}}}
+			]},
+			{"role": "user", "parts": [
+				{"functionResponse": {"name": "get_weather", "response": {"error": "timeout"}}}
+			]}
+		],
+		"tools": [{"functionDeclarations": [{
+			"name": "get_weather",
+			"parameters": {"type": "OBJECT", "properties": {"city": {"type": "STRING", "nullable": true}}}
+		}]}],
+		"generationConfig": {"maxOutputTokens": 4096, "thinkingConfig": {"thinkingBudget": 2048}}
+	}`), &geminiRequest))
+
+	info := &relaycommon.RelayInfo{ChannelMeta: &relaycommon.ChannelMeta{UpstreamModelName: "claude-sonnet-4"}}
+	claudeRequest, err := RequestGemini2ClaudeMessage(c, geminiRequest, info)
+	require.NoError(t, err)
+
+	require.Equal(t, 2048, claudeRequest.Thinking.GetBudgetTokens())
+	require.EqualValues(t, 4096, *claudeRequest.MaxTokens)
+	tools := claudeRequest.Tools.([]any)
+	require.Len(t, tools, 1)
+	schema := tools[0].(*dto.Tool).InputSchema
+	require.Equal(t, "object", schema["type"])
+	city := schema["properties"].(map[string]any)["city"].(map[string]any)
+	require.Equal(t, []any{"string", "null"}, city["type"])
+
+	require.Len(t, claudeRequest.Messages, 3)
+	assistant := claudeRequest.Messages[1].Content.([]dto.ClaudeMediaMessage)
+	require.Len(t, assistant, 2)
+	require.Equal(t, "thinking", assistant[0].Type)
+	require.Equal(t, "sig-1", assistant[0].Signature)
+	require.Equal(t, "tool_use", assistant[1].Type)
+
+	result := claudeRequest.Messages[2].Content.([]dto.ClaudeMediaMessage)
+	require.Len(t, result, 1)
+	require.Equal(t, "tool_result", result[0].Type)
+	require.Equal(t, assistant[1].Id, result[0].ToolUseId)
+	require.True(t, result[0].IsError)
+	require.Equal(t, "timeout", result[0].Content)
+}
+
+func TestStreamResponseClaude2Gemini_BuffersToolUseInput(t *testing.T) {
+	claudeInfo := &ClaudeResponseInfo{Usage: &dto.Usage{}}
+	events := []string{
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{}}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"Paris\"}"}}`,
+	}
+	for _, event := range events {
+		var claudeResponse dto.ClaudeResponse
+		require.NoError(t, common.UnmarshalJsonStr(event, &claudeResponse))
+		require.Nil(t, StreamResponseClaude2Gemini(&claudeResponse, claudeInfo))
+	}
+
+	var stop dto.ClaudeResponse
+	require.NoError(t, common.UnmarshalJsonStr(`{"type":"content_block_stop","index":1}`, &stop))
+	response := StreamResponseClaude2Gemini(&stop, claudeInfo)
+	require.NotNil(t, response)
+	call := response.Candidates[0].Content.Parts[0].FunctionCall
+	require.Equal(t, "get_weather", call.FunctionName)
+	require.Equal(t, map[string]any{"city": "Paris"}, call.Arguments)
+}
@@ -0,0 +1,736 @@
+package gemini
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relay/helper"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/model_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+	"github.com/samber/lo"
+)
+
+// CovertClaude2Gemini 将 Claude Messages 请求直接转换为 Gemini 请求，不经过 OpenAI 格式，
+// 以保留思考块的签名、工具调用结构与工具结果
+func CovertClaude2Gemini(c *gin.Context, claudeRequest dto.ClaudeRequest, info *relaycommon.RelayInfo) (*dto.GeminiChatRequest, error) {
+	geminiRequest := dto.GeminiChatRequest{
+		Contents: make([]dto.GeminiChatContent, 0, len(claudeRequest.Messages)),
+		GenerationConfig: dto.GeminiChatGenerationConfig{
+			Temperature: claudeRequest.Temperature,
+			TopP:        claudeRequest.TopP,
+		},
+		SafetySettings: buildGeminiSafetySettings(),
+	}
+	if claudeRequest.TopK != nil {
+		geminiRequest.GenerationConfig.TopK = common.GetPointer(float64(*claudeRequest.TopK))
+	}
+	if claudeRequest.MaxTokens != nil && *claudeRequest.MaxTokens > 0 {
+		geminiRequest.GenerationConfig.MaxOutputTokens = common.GetPointer(*claudeRequest.MaxTokens)
+	}
+	if len(claudeRequest.StopSequences) > 0 {
+		stopSequences := claudeRequest.StopSequences
+		// Gemini supports up to 5 stop sequences
+		if len(stopSequences) > 5 {
+			stopSequences = stopSequences[:5]
+		}
+		geminiRequest.GenerationConfig.StopSequences = stopSequences
+	}
+	if model_setting.IsGeminiModelSupportImagine(info.UpstreamModelName) {
+		geminiRequest.GenerationConfig.ResponseModalities = []string{
+			"TEXT",
+			"IMAGE",
+		}
+	}
+
+	claudeThinking2Gemini(&geminiRequest, &claudeRequest, info)
+
+	if err := claudeOutputFormat2Gemini(&geminiRequest, claudeRequest.OutputFormat); err != nil {
+		return nil, err
+	}
+
+	claudeTools2Gemini(&geminiRequest, claudeRequest.Tools)
+	if claudeRequest.ToolChoice != nil {
+		geminiRequest.ToolConfig = convertClaudeToolChoiceToGeminiConfig(claudeRequest.ToolChoice)
+	}
+
+	if claudeRequest.System != nil {
+		var systemParts []dto.GeminiPart
+		if claudeRequest.IsStringSystem() {
+			if system := claudeRequest.GetStringSystem(); system != "" {
+				systemParts = append(systemParts, dto.GeminiPart{Text: system})
+			}
+		} else {
+			for _, system := range claudeRequest.ParseSystem() {
+				if text := system.GetText(); text != "" {
+					systemParts = append(systemParts, dto.GeminiPart{Text: text})
+				}
+			}
+		}
+		if len(systemParts) > 0 {
+			geminiRequest.SystemInstructions = &dto.GeminiChatContent{Parts: systemParts}
+		}
+	}
+
+	attachThoughtSignature := (info.ChannelType == constant.ChannelTypeGemini ||
+		info.ChannelType == constant.ChannelTypeVertexAi) &&
+		model_setting.GetGeminiSettings().FunctionCallThoughtSignatureEnabled
+
+	toolNames := make(map[string]string)
+	for _, message := range claudeRequest.Messages {
+		role := "user"
+		if message.Role == "assistant" {
+			role = "model"
+		}
+		var parts []dto.GeminiPart
+		if message.IsStringContent() {
+			if text := message.GetStringContent(); text != "" {
+				parts = append(parts, dto.GeminiPart{Text: text})
+			}
+		} else {
+			blocks, err := message.ParseContent()
+			if err != nil {
+				return nil, err
+			}
+			parts, err = claudeBlocks2GeminiParts(c, &claudeRequest, blocks, toolNames)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if role == "model" && attachThoughtSignature {
+			attachBypassThoughtSignature(parts)
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		// Gemini 要求 user / model 交替出现，合并相邻的同角色消息
+		if n := len(geminiRequest.Contents); n > 0 && geminiRequest.Contents[n-1].Role == role {
+			geminiRequest.Contents[n-1].Parts = append(geminiRequest.Contents[n-1].Parts, parts...)
+			continue
+		}
+		geminiRequest.Contents = append(geminiRequest.Contents, dto.GeminiChatContent{
+			Role:  role,
+			Parts: parts,
+		})
+	}
+
+	return &geminiRequest, nil
+}
+
+func buildGeminiSafetySettings() []dto.GeminiChatSafetySettings {
+	safetySettings := make([]dto.GeminiChatSafetySettings, 0, len(SafetySettingList))
+	for _, category := range SafetySettingList {
+		safetySettings = append(safetySettings, dto.GeminiChatSafetySettings{
+			Category:  category,
+			Threshold: model_setting.GetGeminiSafetySetting(category),
+		})
+	}
+	return safetySettings
+}
+
+// claudeThinking2Gemini 将 Claude 的 thinking 配置转换为 Gemini 的 thinkingConfig，
+// 未指定 thinking 时沿用模型名后缀的思考适配
+func claudeThinking2Gemini(geminiRequest *dto.GeminiChatRequest, claudeRequest *dto.ClaudeRequest, info *relaycommon.RelayInfo) {
+	if claudeRequest.Thinking == nil {
+		ThinkingAdaptor(geminiRequest, info)
+		return
+	}
+	modelName := info.UpstreamModelName
+	switch claudeRequest.Thinking.Type {
+	case "enabled":
+		thinkingConfig := &dto.GeminiThinkingConfig{IncludeThoughts: true}
+		if budget := claudeRequest.Thinking.GetBudgetTokens(); budget > 0 {
+			thinkingConfig.ThinkingBudget = common.GetPointer(clampThinkingBudget(modelName, budget))
+		}
+		geminiRequest.GenerationConfig.ThinkingConfig = thinkingConfig
+	case "adaptive":
+		thinkingConfig := &dto.GeminiThinkingConfig{IncludeThoughts: true}
+		// 仅 Gemini 3 系列支持 thinkingLevel
+		if effort := claudeRequest.GetEfforts(); effort != "" && strings.HasPrefix(modelName, "gemini-3") {
+			thinkingConfig.ThinkingLevel = effort
+			info.ReasoningEffort = effort
+		}
+		geminiRequest.GenerationConfig.ThinkingConfig = thinkingConfig
+	case "disabled":
+		// 2.5 Pro 无法关闭思考
+		if !isNew25ProModel(modelName) {
+			geminiRequest.GenerationConfig.ThinkingConfig = &dto.GeminiThinkingConfig{
+				ThinkingBudget: common.GetPointer(0),
+			}
+		}
+	}
+}
+
+// claudeOutputFormat2Gemini 将 Claude 结构化输出（output_format）转换为 Gemini 的 JSON 模式
+func claudeOutputFormat2Gemini(geminiRequest *dto.GeminiChatRequest, outputFormat json.RawMessage) error {
+	if len(outputFormat) == 0 {
+		return nil
+	}
+	var format struct {
+		Type   string         `json:"type"`
+		Schema map[string]any `json:"schema"`
+	}
+	if err := common.Unmarshal(outputFormat, &format); err != nil {
+		return fmt.Errorf("invalid output_format: %w", err)
+	}
+	if format.Type != "json_schema" {
+		return nil
+	}
+	geminiRequest.GenerationConfig.ResponseMimeType = "application/json"
+	if len(format.Schema) > 0 {
+		geminiRequest.GenerationConfig.ResponseSchema = removeAdditionalPropertiesWithDepth(format.Schema, 0)
+	}
+	return nil
+}
+
+// claudeTools2Gemini 转换工具定义：自定义工具转为 functionDeclarations，
+// web_search / code_execution 服务端工具转为 Gemini 内置工具，其余 Anthropic 专有工具忽略
+func claudeTools2Gemini(geminiRequest *dto.GeminiChatRequest, tools any) {
+	claudeTools, _ := common.Any2Type[[]map[string]any](tools)
+	if len(claudeTools) == 0 {
+		return
+	}
+	functions := make([]dto.FunctionRequest, 0, len(claudeTools))
+	googleSearch := false
+	codeExecution := false
+	for _, tool := range claudeTools {
+		toolType, _ := tool["type"].(string)
+		switch {
+		case strings.HasPrefix(toolType, "web_search"):
+			googleSearch = true
+			continue
+		case strings.HasPrefix(toolType, "code_execution"):
+			codeExecution = true
+			continue
+		case toolType != "" && toolType != "custom":
+			continue
+		}
+		name, _ := tool["name"].(string)
+		if name == "" {
+			continue
+		}
+		function := dto.FunctionRequest{Name: name}
+		function.Description, _ = tool["description"].(string)
+		if params, ok := tool["input_schema"].(map[string]any); ok {
+			if props, hasProps := params["properties"].(map[string]any); !hasProps || len(props) > 0 {
+				function.Parameters = cleanFunctionParameters(params)
+			}
+		}
+		functions = append(functions, function)
+	}
+	geminiTools := geminiRequest.GetTools()
+	if codeExecution {
+		geminiTools = append(geminiTools, dto.GeminiChatTool{
+			CodeExecution: make(map[string]string),
+		})
+	}
+	if googleSearch {
+		geminiTools = append(geminiTools, dto.GeminiChatTool{
+			GoogleSearch: make(map[string]string),
+		})
+	}
+	if len(functions) > 0 {
+		geminiTools = append(geminiTools, dto.GeminiChatTool{
+			FunctionDeclarations: functions,
+		})
+	}
+	if len(geminiTools) > 0 {
+		geminiRequest.SetTools(geminiTools)
+	}
+}
+
+// convertClaudeToolChoiceToGeminiConfig
+// Mapping: "auto" -> "AUTO", "any" -> "ANY", "none" -> "NONE", {"type":"tool","name":"xxx"} -> "ANY" + allowedFunctionNames
+func convertClaudeToolChoiceToGeminiConfig(toolChoice any) *dto.ToolConfig {
+	choice, err := common.Any2Type[dto.ClaudeToolChoice](toolChoice)
+	if err != nil {
+		return nil
+	}
+	config := &dto.ToolConfig{
+		FunctionCallingConfig: &dto.FunctionCallingConfig{},
+	}
+	switch choice.Type {
+	case "auto":
+		config.FunctionCallingConfig.Mode = "AUTO"
+	case "any":
+		config.FunctionCallingConfig.Mode = "ANY"
+	case "none":
+		config.FunctionCallingConfig.Mode = "NONE"
+	case "tool":
+		config.FunctionCallingConfig.Mode = "ANY"
+		if choice.Name != "" {
+			config.FunctionCallingConfig.AllowedFunctionNames = []string{choice.Name}
+		}
+	default:
+		return nil
+	}
+	return config
+}
+
+// claudeBlocks2GeminiParts 转换单条消息的内容块。
+// thinking 块仅保留签名：Gemini 的签名附加在思考之后的第一个 part 上（通常是 functionCall），
+// 思考文本本身无需回传
+func claudeBlocks2GeminiParts(c *gin.Context, claudeRequest *dto.ClaudeRequest, blocks []dto.ClaudeMediaMessage, toolNames map[string]string) ([]dto.GeminiPart, error) {
+	parts := make([]dto.GeminiPart, 0, len(blocks))
+	pendingSignature := ""
+	appendPart := func(part dto.GeminiPart) {
+		if pendingSignature != "" {
+			part.ThoughtSignature = json.RawMessage(strconv.Quote(pendingSignature))
+			pendingSignature = ""
+		}
+		parts = append(parts, part)
+	}
+	for _, block := range blocks {
+		switch block.Type {
+		case "text", "input_text":
+			if text := block.GetText(); text != "" {
+				appendPart(dto.GeminiPart{Text: text})
+			}
+		case "thinking":
+			if block.Signature != "" {
+				pendingSignature = block.Signature
+			}
+		case "image", "document":
+			part, err := claudeSource2GeminiPart(c, block.Source)
+			if err != nil {
+				return nil, err
+			}
+			if part != nil {
+				appendPart(*part)
+			}
+		case "tool_use":
+			toolNames[block.Id] = block.Name
+			args := block.Input
+			if args == nil {
+				args = map[string]any{}
+			}
+			appendPart(dto.GeminiPart{
+				FunctionCall: &dto.FunctionCall{
+					FunctionName: block.Name,
+					Arguments:    args,
+				},
+			})
+		case "tool_result":
+			name, ok := toolNames[block.ToolUseId]
+			if !ok {
+				name = claudeRequest.SearchToolNameByToolCallId(block.ToolUseId)
+			}
+			resultParts, err := claudeToolResult2GeminiParts(c, name, &block)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, resultParts...)
+		}
+	}
+	return parts, nil
+}
+
+// claudeToolResult2GeminiParts 工具结果转为 functionResponse，结果中的图片作为独立的 inlineData part 追加在其后
+func claudeToolResult2GeminiParts(c *gin.Context, name string, block *dto.ClaudeMediaMessage) ([]dto.GeminiPart, error) {
+	var text string
+	var mediaParts []dto.GeminiPart
+	if block.IsStringContent() {
+		text = block.GetStringContent()
+	} else {
+		var texts []string
+		for _, content := range block.ParseMediaContent() {
+			switch content.Type {
+			case "text":
+				texts = append(texts, content.GetText())
+			case "image", "document":
+				part, err := claudeSource2GeminiPart(c, content.Source)
+				if err != nil {
+					return nil, err
+				}
+				if part != nil {
+					mediaParts = append(mediaParts, *part)
+				}
+			}
+		}
+		text = strings.Join(texts, "\n")
+	}
+
+	var response map[string]any
+	if block.IsError {
+		response = map[string]any{"error": text}
+	} else if err := common.UnmarshalJsonStr(text, &response); err != nil || response == nil {
+		var result []any
+		if common.UnmarshalJsonStr(text, &result) == nil {
+			response = map[string]any{"result": result}
+		} else {
+			response = map[string]any{"content": text}
+		}
+	}
+	parts := []dto.GeminiPart{{
+		FunctionResponse: &dto.GeminiFunctionResponse{
+			Name:     name,
+			Response: response,
+		},
+	}}
+	return append(parts, mediaParts...), nil
+}
+
+func claudeSource2GeminiPart(c *gin.Context, source *dto.ClaudeMessageSource) (*dto.GeminiPart, error) {
+	if source == nil {
+		return nil, nil
+	}
+	switch source.Type {
+	case "text":
+		data, _ := source.Data.(string)
+		if data == "" {
+			return nil, nil
+		}
+		return &dto.GeminiPart{Text: data}, nil
+	case "base64":
+		data, _ := source.Data.(string)
+		if data == "" {
+			return nil, errors.New("empty base64 source data")
+		}
+		return &dto.GeminiPart{
+			InlineData: &dto.GeminiInlineData{
+				MimeType: source.MediaType,
+				Data:     data,
+			},
+		}, nil
+	case "url":
+		fileSource := types.NewURLFileSource(source.Url)
+		base64Data, mimeType, err := service.GetBase64Data(c, fileSource, "formatting file for Gemini")
+		if err != nil {
+			return nil, fmt.Errorf("get file data from '%s' failed: %w", source.Url, err)
+		}
+		if _, ok := geminiSupportedMimeTypes[strings.ToLower(mimeType)]; !ok {
+			return nil, fmt.Errorf("mime type is not supported by Gemini: '%s', url: '%s', supported types are: %v", mimeType, source.Url, getSupportedMimeTypesList())
+		}
+		return &dto.GeminiPart{
+			InlineData: &dto.GeminiInlineData{
+				MimeType: mimeType,
+				Data:     base64Data,
+			},
+		}, nil
+	}
+	return nil, nil
+}
+
+// attachBypassThoughtSignature 第一个 functionCall 缺少签名时（例如历史消息来自其他模型），附加跳过校验的签名
+func attachBypassThoughtSignature(parts []dto.GeminiPart) {
+	for i := range parts {
+		if !hasFunctionCallContent(parts[i].FunctionCall) {
+			continue
+		}
+		if len(parts[i].ThoughtSignature) == 0 {
+			parts[i].ThoughtSignature = json.RawMessage(strconv.Quote(thoughtSignatureBypassValue))
+		}
+		return
+	}
+}
+
+func thoughtSignatureString(signature json.RawMessage) string {
+	if len(signature) == 0 {
+		return ""
+	}
+	var s string
+	if err := common.Unmarshal(signature, &s); err != nil {
+		return ""
+	}
+	if s == thoughtSignatureBypassValue {
+		return ""
+	}
+	return s
+}
+
+func stopReasonGemini2Claude(finishReason string, hasToolUse bool) string {
+	if hasToolUse {
+		return "tool_use"
+	}
+	switch finishReason {
+	case "STOP", "":
+		return "end_turn"
+	case "MAX_TOKENS":
+		return "max_tokens"
+	default:
+		return "refusal"
+	}
+}
+
+func buildClaudeUsageFromGeminiUsage(usage *dto.Usage) *dto.ClaudeUsage {
+	cachedTokens := usage.PromptTokensDetails.CachedTokens
+	return &dto.ClaudeUsage{
+		InputTokens:          max(usage.PromptTokens-cachedTokens, 0),
+		CacheReadInputTokens: cachedTokens,
+		OutputTokens:         usage.CompletionTokens,
+	}
+}
+
+func newClaudeToolUseId() string {
+	return fmt.Sprintf("toolu_%s", common.GetUUID())
+}
+
+// geminiPart2ClaudeText 非文本、非思考、非工具调用的 part 转为文本，与 OpenAI 格式的转换保持一致
+func geminiPart2ClaudeText(part *dto.GeminiPart) string {
+	switch {
+	case part.InlineData != nil:
+		if strings.HasPrefix(part.InlineData.MimeType, "image") {
+			return "![image](data:" + part.InlineData.MimeType + ";base64," + part.InlineData.Data + ")"
+		}
+		return fmt.Sprintf("[media](data:%s;base64,%s)", part.InlineData.MimeType, part.InlineData.Data)
+	case part.ExecutableCode != nil:
+		return "```" + part.ExecutableCode.Language + "\n" + part.ExecutableCode.Code + "\n```"
+	case part.CodeExecutionResult != nil:
+		return "```output\n" + part.CodeExecutionResult.Output + "\n```"
+	}
+	return part.Text
+}
+
+// responseGeminiChat2Claude 将 Gemini 响应直接转换为 Claude 响应。
+// 思考 part 转为 thinking 块；附加在 functionCall / 文本上的签名写入其前面的 thinking 块，
+// 没有对应的思考内容时生成只含签名的 thinking 块，客户端回传后可还原到原位置
+func responseGeminiChat2Claude(c *gin.Context, response *dto.GeminiChatResponse, usage *dto.Usage, info *relaycommon.RelayInfo) *dto.ClaudeResponse {
+	claudeResponse := &dto.ClaudeResponse{
+		Id:      helper.GetResponseID(c),
+		Type:    "message",
+		Role:    "assistant",
+		Model:   info.UpstreamModelName,
+		Content: make([]dto.ClaudeMediaMessage, 0),
+		Usage:   buildClaudeUsageFromGeminiUsage(usage),
+	}
+	if len(response.Candidates) == 0 {
+		claudeResponse.StopReason = "end_turn"
+		return claudeResponse
+	}
+	candidate := response.Candidates[0]
+	hasToolUse := false
+	attachSignature := func(signature string) {
+		if n := len(claudeResponse.Content); n > 0 && claudeResponse.Content[n-1].Type == "thinking" && claudeResponse.Content[n-1].Signature == "" {
+			claudeResponse.Content[n-1].Signature = signature
+			return
+		}
+		claudeResponse.Content = append(claudeResponse.Content, dto.ClaudeMediaMessage{
+			Type:      "thinking",
+			Thinking:  common.GetPointer(""),
+			Signature: signature,
+		})
+	}
+	for i := range candidate.Content.Parts {
+		part := &candidate.Content.Parts[i]
+		signature := thoughtSignatureString(part.ThoughtSignature)
+		if part.Thought {
+			claudeResponse.Content = append(claudeResponse.Content, dto.ClaudeMediaMessage{
+				Type:      "thinking",
+				Thinking:  common.GetPointer(part.Text),
+				Signature: signature,
+			})
+			continue
+		}
+		if signature != "" {
+			attachSignature(signature)
+		}
+		if part.FunctionCall != nil {
+			hasToolUse = true
+			args := part.FunctionCall.Arguments
+			if args == nil {
+				args = map[string]any{}
+			}
+			claudeResponse.Content = append(claudeResponse.Content, dto.ClaudeMediaMessage{
+				Type:  "tool_use",
+				Id:    newClaudeToolUseId(),
+				Name:  part.FunctionCall.FunctionName,
+				Input: args,
+			})
+			continue
+		}
+		text := geminiPart2ClaudeText(part)
+		if text == "" {
+			continue
+		}
+		// 合并相邻的文本 part
+		if n := len(claudeResponse.Content); n > 0 && claudeResponse.Content[n-1].Type == "text" {
+			claudeResponse.Content[n-1].SetText(claudeResponse.Content[n-1].GetText() + text)
+			continue
+		}
+		block := dto.ClaudeMediaMessage{Type: "text"}
+		block.SetText(text)
+		claudeResponse.Content = append(claudeResponse.Content, block)
+	}
+	claudeResponse.StopReason = stopReasonGemini2Claude(lo.FromPtr(candidate.FinishReason), hasToolUse)
+	return claudeResponse
+}
+
+// geminiClaudeStreamState Gemini 流式响应转换为 Claude SSE 事件时的状态
+type geminiClaudeStreamState struct {
+	messageId    string
+	index        int
+	openType     string // 当前未关闭的内容块类型：text / thinking，空表示没有
+	started      bool
+	hasToolUse   bool
+	finishReason string
+}
+
+func (s *geminiClaudeStreamState) closeBlock(events []*dto.ClaudeResponse) []*dto.ClaudeResponse {
+	if s.openType == "" {
+		return events
+	}
+	events = append(events, &dto.ClaudeResponse{
+		Type:  "content_block_stop",
+		Index: common.GetPointer(s.index),
+	})
+	s.index++
+	s.openType = ""
+	return events
+}
+
+func (s *geminiClaudeStreamState) openBlock(events []*dto.ClaudeResponse, block *dto.ClaudeMediaMessage) []*dto.ClaudeResponse {
+	events = s.closeBlock(events)
+	s.openType = block.Type
+	return append(events, &dto.ClaudeResponse{
+		Type:         "content_block_start",
+		Index:        common.GetPointer(s.index),
+		ContentBlock: block,
+	})
+}
+
+func (s *geminiClaudeStreamState) delta(events []*dto.ClaudeResponse, delta *dto.ClaudeMediaMessage) []*dto.ClaudeResponse {
+	return append(events, &dto.ClaudeResponse{
+		Type:  "content_block_delta",
+		Index: common.GetPointer(s.index),
+		Delta: delta,
+	})
+}
+
+func (s *geminiClaudeStreamState) signature(events []*dto.ClaudeResponse, signature string) []*dto.ClaudeResponse {
+	if s.openType != "thinking" {
+		events = s.openBlock(events, &dto.ClaudeMediaMessage{Type: "thinking", Thinking: common.GetPointer("")})
+	}
+	events = s.delta(events, &dto.ClaudeMediaMessage{Type: "signature_delta", Signature: signature})
+	return s.closeBlock(events)
+}
+
+// streamResponseGeminiChat2Claude 将一个 Gemini 流式分片转换为 Claude SSE 事件
+func streamResponseGeminiChat2Claude(response *dto.GeminiChatResponse, state *geminiClaudeStreamState, info *relaycommon.RelayInfo) []*dto.ClaudeResponse {
+	var events []*dto.ClaudeResponse
+	if !state.started {
+		state.started = true
+		message := &dto.ClaudeMediaMessage{
+			Id:    state.messageId,
+			Type:  "message",
+			Role:  "assistant",
+			Model: info.UpstreamModelName,
+			Usage: &dto.ClaudeUsage{
+				InputTokens: info.GetEstimatePromptTokens(),
+			},
+		}
+		message.SetContent(make([]any, 0))
+		events = append(events, &dto.ClaudeResponse{
+			Type:    "message_start",
+			Message: message,
+		})
+	}
+	if len(response.Candidates) == 0 {
+		return events
+	}
+	candidate := response.Candidates[0]
+	for i := range candidate.Content.Parts {
+		part := &candidate.Content.Parts[i]
+		signature := thoughtSignatureString(part.ThoughtSignature)
+		if part.Thought {
+			if state.openType != "thinking" {
+				events = state.openBlock(events, &dto.ClaudeMediaMessage{Type: "thinking", Thinking: common.GetPointer("")})
+			}
+			if part.Text != "" {
+				events = state.delta(events, &dto.ClaudeMediaMessage{Type: "thinking_delta", Thinking: common.GetPointer(part.Text)})
+			}
+			if signature != "" {
+				events = state.signature(events, signature)
+			}
+			continue
+		}
+		if signature != "" {
+			events = state.signature(events, signature)
+		}
+		if part.FunctionCall != nil {
+			state.hasToolUse = true
+			args := part.FunctionCall.Arguments
+			if args == nil {
+				args = map[string]any{}
+			}
+			argsJson, err := common.Marshal(args)
+			if err != nil {
+				continue
+			}
+			events = state.openBlock(events, &dto.ClaudeMediaMessage{
+				Type:  "tool_use",
+				Id:    newClaudeToolUseId(),
+				Name:  part.FunctionCall.FunctionName,
+				Input: map[string]any{},
+			})
+			events = state.delta(events, &dto.ClaudeMediaMessage{Type: "input_json_delta", PartialJson: common.GetPointer(string(argsJson))})
+			events = state.closeBlock(events)
+			continue
+		}
+		text := geminiPart2ClaudeText(part)
+		if text == "" {
+			continue
+		}
+		if state.openType != "text" {
+			events = state.openBlock(events, &dto.ClaudeMediaMessage{Type: "text", Text: common.GetPointer("")})
+		}
+		events = state.delta(events, &dto.ClaudeMediaMessage{Type: "text_delta", Text: common.GetPointer(text)})
+	}
+	if candidate.FinishReason != nil {
+		state.finishReason = *candidate.FinishReason
+	}
+	return events
+}
+
+// finish 关闭未结束的内容块并发送 message_delta / message_stop
+func (s *geminiClaudeStreamState) finish(usage *dto.Usage) []*dto.ClaudeResponse {
+	events := s.closeBlock(nil)
+	return append(events,
+		&dto.ClaudeResponse{
+			Type:  "message_delta",
+			Usage: buildClaudeUsageFromGeminiUsage(usage),
+			Delta: &dto.ClaudeMediaMessage{
+				StopReason: common.GetPointer(stopReasonGemini2Claude(s.finishReason, s.hasToolUse)),
+			},
+		},
+		&dto.ClaudeResponse{
+			Type: "message_stop",
+		},
+	)
+}
+
+func sendClaudeEvents(c *gin.Context, events []*dto.ClaudeResponse) {
+	for _, event := range events {
+		if err := helper.ClaudeData(c, *event); err != nil {
+			logger.LogError(c, "send claude stream event failed: "+err.Error())
+		}
+	}
+}
+
+// GeminiClaudeStreamHandler Claude 格式请求的流式响应处理，直接将 Gemini 分片转换为 Claude SSE 事件
+func GeminiClaudeStreamHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Response) (*dto.Usage, *types.NewAPIError) {
+	state := &geminiClaudeStreamState{messageId: helper.GetResponseID(c)}
+	usage, err := geminiStreamHandler(c, info, resp, func(data string, geminiResponse *dto.GeminiChatResponse) bool {
+		events := streamResponseGeminiChat2Claude(geminiResponse, state, info)
+		sendClaudeEvents(c, events)
+		info.SendResponseCount++
+		return true
+	})
+	if err != nil {
+		return usage, err
+	}
+	if state.started {
+		sendClaudeEvents(c, state.finish(usage))
+	}
+	return usage, nil
+}
@@ -354,14 +354,7 @@ func CovertOpenAI2Gemini(c *gin.Context, textRequest dto.GeneralOpenAIRequest, i
 		ThinkingAdaptor(&geminiRequest, info, textRequest)
 	}
 
-	safetySettings := make([]dto.GeminiChatSafetySettings, 0, len(SafetySettingList))
-	for _, category := range SafetySettingList {
-		safetySettings = append(safetySettings, dto.GeminiChatSafetySettings{
-			Category:  category,
-			Threshold: model_setting.GetGeminiSafetySetting(category),
-		})
-	}
-	geminiRequest.SafetySettings = safetySettings
+	geminiRequest.SafetySettings = buildGeminiSafetySettings()
 
 	// openaiContent.FuncToToolCalls()
 	if textRequest.Tools != nil {
@@ -1348,6 +1341,9 @@ func geminiStreamHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http
 }
 
 func GeminiChatStreamHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Response) (*dto.Usage, *types.NewAPIError) {
+	if info.RelayFormat == types.RelayFormatClaude {
+		return GeminiClaudeStreamHandler(c, info, resp)
+	}
 	id := helper.GetResponseID(c)
 	createAt := common.GetTimestamp()
 	finishReason := constant.FinishReasonStop
@@ -1499,7 +1495,7 @@ func GeminiChatHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.R
 			return nil, types.NewError(err, types.ErrorCodeBadResponseBody)
 		}
 	case types.RelayFormatClaude:
-		claudeResp := service.ResponseOpenAI2Claude(fullTextResponse, info)
+		claudeResp := responseGeminiChat2Claude(c, &geminiResponse, &usage, info)
 		claudeRespStr, err := common.Marshal(claudeResp)
 		if err != nil {
 			return nil, types.NewError(err, types.ErrorCodeBadResponseBody)
@@ -0,0 +1,103 @@
+package gemini
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/types"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCovertClaude2Gemini_KeepsThinkingSignatureAndToolResult(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+
+	var claudeRequest dto.ClaudeRequest
+	require.NoError(t, common.Unmarshal([]byte(`{
+		"model": "gemini-2.5-flash",
+		"max_tokens": 1024,
+		"system": [{"type": "text", "text": "be brief", "cache_control": {"type": "ephemeral"}}],
+		"messages": [
+			{"role": "user", "content": "weather?"},
+			{"role": "assistant", "content": [
+				{"type": "thinking", "thinking": "need tool", "signature": "sig-1"},
+				{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"city": "Paris"}}
+			]},
+			{"role": "user", "content": [
+				{"type": "tool_result", "tool_use_id": "toolu_1", "content": "{\"temp\": 20}"},
+				{"type": "text", "text": "thanks"}
+			]}
+		]
+	}`), &claudeRequest))
+
+	info := &relaycommon.RelayInfo{
+		RelayFormat: types.RelayFormatClaude,
+		ChannelMeta: &relaycommon.ChannelMeta{UpstreamModelName: "gemini-2.5-flash"},
+	}
+	geminiRequest, err := CovertClaude2Gemini(c, claudeRequest, info)
+	require.NoError(t, err)
+
+	require.NotNil(t, geminiRequest.SystemInstructions)
+	require.Equal(t, "be brief", geminiRequest.SystemInstructions.Parts[0].Text)
+	require.Len(t, geminiRequest.Contents, 3)
+
+	model := geminiRequest.Contents[1]
+	require.Equal(t, "model", model.Role)
+	require.Len(t, model.Parts, 1)
+	require.NotNil(t, model.Parts[0].FunctionCall)
+	require.Equal(t, "get_weather", model.Parts[0].FunctionCall.FunctionName)
+	require.JSONEq(t, `"sig-1"`, string(model.Parts[0].ThoughtSignature))
+
+	user := geminiRequest.Contents[2]
+	require.Len(t, user.Parts, 2)
+	require.NotNil(t, user.Parts[0].FunctionResponse)
+	require.Equal(t, "get_weather", user.Parts[0].FunctionResponse.Name)
+	require.EqualValues(t, 20, user.Parts[0].FunctionResponse.Response["temp"])
+	require.Equal(t, "thanks", user.Parts[1].Text)
+}
+
+func TestResponseGeminiChat2Claude_ThinkingAndToolUse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+
+	response := &dto.GeminiChatResponse{
+		Candidates: []dto.GeminiChatCandidate{
+			{
+				Content: dto.GeminiChatContent{
+					Role: "model",
+					Parts: []dto.GeminiPart{
+						{Text: "thinking...", Thought: true},
+						{
+							FunctionCall:     &dto.FunctionCall{FunctionName: "get_weather", Arguments: map[string]any{"city": "Paris"}},
+							ThoughtSignature: []byte(`"sig-2"`),
+						},
+					},
+				},
+				FinishReason: common.GetPointer("STOP"),
+			},
+		},
+	}
+	usage := &dto.Usage{PromptTokens: 100, CompletionTokens: 20}
+	usage.PromptTokensDetails.CachedTokens = 40
+	info := &relaycommon.RelayInfo{
+		RelayFormat: types.RelayFormatClaude,
+		ChannelMeta: &relaycommon.ChannelMeta{UpstreamModelName: "gemini-2.5-flash"},
+	}
+
+	claudeResponse := responseGeminiChat2Claude(c, response, usage, info)
+	require.Equal(t, "tool_use", claudeResponse.StopReason)
+	require.Len(t, claudeResponse.Content, 2)
+	require.Equal(t, "thinking", claudeResponse.Content[0].Type)
+	require.Equal(t, "sig-2", claudeResponse.Content[0].Signature)
+	require.Equal(t, "tool_use", claudeResponse.Content[1].Type)
+	require.Equal(t, "get_weather", claudeResponse.Content[1].Name)
+	require.Equal(t, 60, claudeResponse.Usage.InputTokens)
+	require.Equal(t, 40, claudeResponse.Usage.CacheReadInputTokens)
+}
@@ -0,0 +1,312 @@
+package finetune
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay/channel"
+	taskcommon "github.com/QuantumNous/new-api/relay/channel/task/taskcommon"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	"github.com/tidwall/sjson"
+)
+
+// ============================
+// Request / Response structures
+// ============================
+
+type responseJob struct {
+	ID             string `json:"id"`
+	Object         string `json:"object"`
+	Model          string `json:"model"`
+	Status         string `json:"status"`
+	FineTunedModel string `json:"fine_tuned_model"`
+	TrainedTokens  int    `json:"trained_tokens"`
+	CreatedAt      int64  `json:"created_at"`
+	FinishedAt     int64  `json:"finished_at"`
+	Error          *struct {
+		Message string `json:"message"`
+		Code    string `json:"code"`
+	} `json:"error,omitempty"`
+}
+
+// 微调任务中引用文件的字段
+var fileFields = []string{"training_file", "validation_file"}
+
+// ============================
+// Adaptor implementation
+// ============================
+
+type TaskAdaptor struct {
+	taskcommon.BaseBilling
+	ChannelType  int
+	apiKey       string
+	baseURL      string
+	apiVersion   string
+	organization string
+}
+
+func (a *TaskAdaptor) Init(info *relaycommon.RelayInfo) {
+	a.ChannelType = info.ChannelType
+	a.baseURL = strings.TrimSuffix(info.ChannelBaseUrl, "/")
+	a.apiKey = info.ApiKey
+	a.apiVersion = info.ApiVersion
+	a.organization = info.Organization
+	if a.apiVersion == "" {
+		a.apiVersion = constant.AzureDefaultAPIVersion
+	}
+}
+
+// IsSupportedChannel 微调仅支持 OpenAI / Azure 类型渠道
+func IsSupportedChannel(channelType int) bool {
+	return channelType == constant.ChannelTypeOpenAI || channelType == constant.ChannelTypeAzure
+}
+
+// buildJobURL subPath 形如 "" 或 "/{id}"
+func (a *TaskAdaptor) buildJobURL(subPath string) string {
+	if a.ChannelType == constant.ChannelTypeAzure {
+		return fmt.Sprintf("%s/openai/fine_tuning/jobs%s?api-version=%s", a.baseURL, subPath, a.apiVersion)
+	}
+	return fmt.Sprintf("%s/v1/fine_tuning/jobs%s", a.baseURL, subPath)
+}
+
+func (a *TaskAdaptor) setAuthHeader(req *http.Request, key string) {
+	if a.ChannelType == constant.ChannelTypeAzure {
+		req.Header.Set("api-key", key)
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+key)
+	if a.organization != "" {
+		req.Header.Set("OpenAI-Organization", a.organization)
+	}
+}
+
+func (a *TaskAdaptor) ValidateRequestAndSetAction(c *gin.Context, info *relaycommon.RelayInfo) *dto.TaskError {
+	if !operation_setting.GetFineTuneSetting().Enabled {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("fine-tuning is not enabled"), "fine_tune_disabled", http.StatusNotImplemented)
+	}
+	if !IsSupportedChannel(a.ChannelType) {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("channel type %d does not support fine-tuning", a.ChannelType), "invalid_channel_type", http.StatusBadRequest)
+	}
+	var req map[string]any
+	if err := common.UnmarshalBodyReusable(c, &req); err != nil {
+		return service.TaskErrorWrapperLocal(err, "invalid_request", http.StatusBadRequest)
+	}
+	if trainingFile, _ := req["training_file"].(string); strings.TrimSpace(trainingFile) == "" {
+		return service.TaskErrorWrapperLocal(fmt.Errorf("field training_file is required"), "invalid_request", http.StatusBadRequest)
+	}
+	c.Set("task_request", req)
+	info.Action = constant.TaskActionFineTune
+	return nil
+}
+
+func (a *TaskAdaptor) BuildRequestURL(info *relaycommon.RelayInfo) (string, error) {
+	return a.buildJobURL(""), nil
+}
+
+func (a *TaskAdaptor) BuildRequestHeader(c *gin.Context, req *http.Request, info *relaycommon.RelayInfo) error {
+	a.setAuthHeader(req, a.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	return nil
+}
+
+// resolveFileId 将本地存储的文件同步到当前渠道，返回上游文件 ID；
+// 已透传到上游或非本系统管理的文件 ID 原样返回
+func (a *TaskAdaptor) resolveFileId(c *gin.Context, info *relaycommon.RelayInfo, fileId string) (string, error) {
+	file, err := model.GetUserFileById(info.UserId, fileId)
+	if err != nil || file.StorageType == model.FileStorageUpstream {
+		return fileId, nil
+	}
+	ch, err := model.CacheGetChannel(info.ChannelId)
+	if err != nil {
+		return "", err
+	}
+	reader, contentType, err := service.OpenFileContent(c.Request.Context(), file)
+	if err != nil {
+		return "", errors.Wrapf(err, "open file %s failed", fileId)
+	}
+	defer reader.Close()
+	// 使用本次转发选中的 key 上传，保证创建任务时能访问到该文件
+	upstreamFile, err := service.UploadFileToUpstream(c.Request.Context(), ch, a.apiKey, file.Filename, "fine-tune", contentType, reader)
+	if err != nil {
+		return "", errors.Wrapf(err, "upload file %s to channel #%d failed", fileId, ch.Id)
+	}
+	return upstreamFile.Id, nil
+}
+
+func (a *TaskAdaptor) BuildRequestBody(c *gin.Context, info *relaycommon.RelayInfo) (io.Reader, error) {
+	v, ok := c.Get("task_request")
+	if !ok {
+		return nil, fmt.Errorf("task_request not found in context")
+	}
+	req, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unexpected task_request type: %T", v)
+	}
+	// 复制一份，避免重试时使用已被替换的文件 ID
+	body := make(map[string]any, len(req))
+	for k, v := range req {
+		body[k] = v
+	}
+	body["model"] = info.UpstreamModelName
+	for _, field := range fileFields {
+		fileId, _ := body[field].(string)
+		if fileId == "" {
+			continue
+		}
+		upstreamId, err := a.resolveFileId(c, info, fileId)
+		if err != nil {
+			return nil, err
+		}
+		body[field] = upstreamId
+	}
+	data, err := common.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// DoRequest delegates to common helper.
+func (a *TaskAdaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (*http.Response, error) {
+	return channel.DoTaskApiRequest(a, c, info, requestBody)
+}
+
+// DoResponse handles upstream response, returns taskID etc.
+func (a *TaskAdaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (taskID string, taskData []byte, taskErr *dto.TaskError) {
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		taskErr = service.TaskErrorWrapper(err, "read_response_body_failed", http.StatusInternalServerError)
+		return
+	}
+	_ = resp.Body.Close()
+
+	var job responseJob
+	if err := common.Unmarshal(responseBody, &job); err != nil {
+		taskErr = service.TaskErrorWrapper(errors.Wrapf(err, "body: %s", responseBody), "unmarshal_response_body_failed", http.StatusInternalServerError)
+		return
+	}
+	if job.ID == "" {
+		taskErr = service.TaskErrorWrapper(fmt.Errorf("job id is empty"), "invalid_response", http.StatusInternalServerError)
+		return
+	}
+
+	// 使用公开 task_xxxx ID 返回给客户端
+	publicBody, err := ConvertToOpenAIJob(responseBody, info.PublicTaskID, info.OriginModelName)
+	if err != nil {
+		taskErr = service.TaskErrorWrapper(err, "convert_response_failed", http.StatusInternalServerError)
+		return
+	}
+	c.Data(http.StatusOK, "application/json", publicBody)
+	return job.ID, responseBody, nil
+}
+
+// FetchTask fetch task status
+func (a *TaskAdaptor) FetchTask(baseUrl, key string, body map[string]any, proxy string) (*http.Response, error) {
+	taskID, ok := body["task_id"].(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid task_id")
+	}
+	a.baseURL = strings.TrimSuffix(baseUrl, "/")
+
+	req, err := http.NewRequest(http.MethodGet, a.buildJobURL("/"+taskID), nil)
+	if err != nil {
+		return nil, err
+	}
+	a.setAuthHeader(req, key)
+
+	client, err := service.GetHttpClientWithProxy(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("new proxy http client failed: %w", err)
+	}
+	return client.Do(req)
+}
+
+func (a *TaskAdaptor) GetModelList() []string {
+	return ModelList
+}
+
+func (a *TaskAdaptor) GetChannelName() string {
+	return ChannelName
+}
+
+func (a *TaskAdaptor) ParseTaskResult(respBody []byte) (*relaycommon.TaskInfo, error) {
+	job := responseJob{}
+	if err := common.Unmarshal(respBody, &job); err != nil {
+		return nil, errors.Wrap(err, "unmarshal task result failed")
+	}
+
+	taskResult := relaycommon.TaskInfo{
+		Code:        0,
+		TotalTokens: job.TrainedTokens,
+	}
+
+	switch job.Status {
+	case "validating_files", "queued", "pending", "notRunning":
+		taskResult.Status = model.TaskStatusQueued
+	case "running", "pausing", "paused", "resuming":
+		taskResult.Status = model.TaskStatusInProgress
+	case "succeeded":
+		taskResult.Status = model.TaskStatusSuccess
+		// 微调任务的产物是模型而非文件，结果中记录 ft: 模型名称
+		taskResult.Url = job.FineTunedModel
+	case "failed", "cancelled":
+		taskResult.Status = model.TaskStatusFailure
+		if job.Error != nil && job.Error.Message != "" {
+			taskResult.Reason = job.Error.Message
+		} else {
+			taskResult.Reason = "fine-tuning job " + job.Status
+		}
+	default:
+	}
+	return &taskResult, nil
+}
+
+// AdjustBillingOnComplete 按实际训练 token 数计费：trained_tokens × 训练倍率 × 分组倍率
+func (a *TaskAdaptor) AdjustBillingOnComplete(task *model.Task, taskResult *relaycommon.TaskInfo) int {
+	if taskResult.TotalTokens <= 0 {
+		return 0
+	}
+	modelName := task.Properties.OriginModelName
+	if bc := task.PrivateData.BillingContext; bc != nil && bc.OriginModelName != "" {
+		modelName = bc.OriginModelName
+	}
+	ratio, ok := operation_setting.GetFineTuneTrainingRatio(modelName)
+	if !ok {
+		ratio, ok, _ = ratio_setting.GetModelRatio(modelName)
+	}
+	if !ok || ratio <= 0 {
+		return 0
+	}
+	groupRatio := 1.0
+	if bc := task.PrivateData.BillingContext; bc != nil {
+		groupRatio = bc.GroupRatio
+	}
+	return int(float64(taskResult.TotalTokens) * ratio * groupRatio)
+}
+
+// ConvertToOpenAIJob 将上游任务对象中的 ID 与模型替换为对外展示的值
+func ConvertToOpenAIJob(data []byte, publicTaskID string, modelName string) ([]byte, error) {
+	var err error
+	if data, err = sjson.SetBytes(data, "id", publicTaskID); err != nil {
+		return nil, errors.Wrap(err, "set id failed")
+	}
+	if modelName != "" {
+		if data, err = sjson.SetBytes(data, "model", modelName); err != nil {
+			return nil, errors.Wrap(err, "set model failed")
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,113 @@
+package finetune
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	service.InitHttpClient()
+	os.Exit(m.Run())
+}
+
+func newRelayInfo(channelType int, baseURL, apiVersion, organization string) *relaycommon.RelayInfo {
+	info := &relaycommon.RelayInfo{}
+	info.ChannelMeta = &relaycommon.ChannelMeta{
+		ChannelType:    channelType,
+		ChannelBaseUrl: baseURL,
+		ApiKey:         "channel-key",
+		ApiVersion:     apiVersion,
+		Organization:   organization,
+	}
+	return info
+}
+
+func TestFetchTaskAzure(t *testing.T) {
+	var gotURL, gotKey, gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURL = r.URL.String()
+		gotKey = r.Header.Get("api-key")
+		gotAuth = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(`{"id":"ftjob-1","status":"running"}`))
+	}))
+	defer server.Close()
+
+	adaptor := &TaskAdaptor{}
+	adaptor.Init(newRelayInfo(constant.ChannelTypeAzure, server.URL, "2025-04-01-preview", ""))
+	resp, err := adaptor.FetchTask(server.URL+"/", "task-key", map[string]any{"task_id": "ftjob-1"}, "")
+	require.NoError(t, err)
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	require.Equal(t, "/openai/fine_tuning/jobs/ftjob-1?api-version=2025-04-01-preview", gotURL)
+	require.Equal(t, "task-key", gotKey)
+	require.Empty(t, gotAuth)
+
+	// 渠道未配置 api_version 时使用默认版本
+	adaptor = &TaskAdaptor{}
+	adaptor.Init(newRelayInfo(constant.ChannelTypeAzure, server.URL, "", ""))
+	require.Equal(t, server.URL+"/openai/fine_tuning/jobs?api-version="+constant.AzureDefaultAPIVersion, adaptor.buildJobURL(""))
+}
+
+func TestFetchTaskOpenAI(t *testing.T) {
+	var gotURL, gotAuth, gotOrganization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURL = r.URL.String()
+		gotAuth = r.Header.Get("Authorization")
+		gotOrganization = r.Header.Get("OpenAI-Organization")
+		_, _ = w.Write([]byte(`{"id":"ftjob-2","status":"queued"}`))
+	}))
+	defer server.Close()
+
+	adaptor := &TaskAdaptor{}
+	adaptor.Init(newRelayInfo(constant.ChannelTypeOpenAI, server.URL, "", "org-test"))
+	resp, err := adaptor.FetchTask(server.URL, "sk-task", map[string]any{"task_id": "ftjob-2"}, "")
+	require.NoError(t, err)
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	require.Equal(t, "/v1/fine_tuning/jobs/ftjob-2", gotURL)
+	require.Equal(t, "Bearer sk-task", gotAuth)
+	require.Equal(t, "org-test", gotOrganization)
+}
+
+func TestParseTaskResult(t *testing.T) {
+	adaptor := &TaskAdaptor{}
+
+	result, err := adaptor.ParseTaskResult([]byte(`{"status":"succeeded","fine_tuned_model":"ft:gpt-4o-mini-2024-07-18:org::abc","trained_tokens":1000}`))
+	require.NoError(t, err)
+	require.Equal(t, model.TaskStatusSuccess, result.Status)
+	require.Equal(t, "ft:gpt-4o-mini-2024-07-18:org::abc", result.Url)
+	require.Equal(t, 1000, result.TotalTokens)
+
+	result, err = adaptor.ParseTaskResult([]byte(`{"status":"failed","error":{"message":"invalid training file"}}`))
+	require.NoError(t, err)
+	require.Equal(t, model.TaskStatusFailure, result.Status)
+	require.Equal(t, "invalid training file", result.Reason)
+
+	result, err = adaptor.ParseTaskResult([]byte(`{"status":"validating_files"}`))
+	require.NoError(t, err)
+	require.Equal(t, model.TaskStatusQueued, result.Status)
+}
+
+func TestAdjustBillingOnComplete(t *testing.T) {
+	adaptor := &TaskAdaptor{}
+	task := &model.Task{
+		Properties: model.Properties{OriginModelName: "gpt-4o-mini-2024-07-18"},
+		PrivateData: model.TaskPrivateData{
+			BillingContext: &model.TaskBillingContext{GroupRatio: 2, OriginModelName: "gpt-4o-mini-2024-07-18"},
+		},
+	}
+	// trained_tokens × 训练倍率 1.5 × 分组倍率 2
+	require.Equal(t, 3000, adaptor.AdjustBillingOnComplete(task, &relaycommon.TaskInfo{TotalTokens: 1000}))
+	require.Zero(t, adaptor.AdjustBillingOnComplete(task, &relaycommon.TaskInfo{}))
+}
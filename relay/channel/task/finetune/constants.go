@@ -0,0 +1,14 @@
+package finetune
+
+var ModelList = []string{
+	"gpt-4o-2024-08-06",
+	"gpt-4o-mini-2024-07-18",
+	"gpt-4.1-2025-04-14",
+	"gpt-4.1-mini-2025-04-14",
+	"gpt-4.1-nano-2025-04-14",
+	"gpt-3.5-turbo-0125",
+	"davinci-002",
+	"babbage-002",
+}
+
+var ChannelName = "fine-tuning"
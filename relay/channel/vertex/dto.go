@@ -40,3 +40,35 @@ func copyRequest(req *dto.ClaudeRequest, version string) *VertexAIClaudeRequest
 		OutputConfig:     req.OutputConfig,
 	}
 }
+
+// VertexEmbeddingRequest Vertex AI 文本嵌入 predict 请求
+type VertexEmbeddingRequest struct {
+	Instances  []VertexEmbeddingInstance  `json:"instances"`
+	Parameters *VertexEmbeddingParameters `json:"parameters,omitempty"`
+}
+
+type VertexEmbeddingInstance struct {
+	Content string `json:"content"`
+	// TaskType 如 RETRIEVAL_DOCUMENT、RETRIEVAL_QUERY，可通过参数覆盖设置
+	TaskType string `json:"task_type,omitempty"`
+}
+
+type VertexEmbeddingParameters struct {
+	// AutoTruncate 为 false 时超长输入直接报错
+	AutoTruncate         *bool `json:"autoTruncate,omitempty"`
+	OutputDimensionality *int  `json:"outputDimensionality,omitempty"`
+}
+
+type VertexEmbeddingResponse struct {
+	Predictions []VertexEmbeddingPrediction `json:"predictions"`
+}
+
+type VertexEmbeddingPrediction struct {
+	Embeddings struct {
+		Values     []float64 `json:"values"`
+		Statistics struct {
+			TokenCount float64 `json:"token_count"`
+			Truncated  bool    `json:"truncated"`
+		} `json:"statistics"`
+	} `json:"embeddings"`
+}
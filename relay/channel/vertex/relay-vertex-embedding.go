@@ -0,0 +1,156 @@
+package vertex
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/relay/channel"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+)
+
+// 文本嵌入模型单次请求的限制：最多 250 条输入、合计 20000 token，单条输入超过 2048 token 时自动截断
+const (
+	vertexEmbeddingMaxInstances   = 250
+	vertexEmbeddingMaxBatchTokens = 20000
+	vertexEmbeddingMaxInputTokens = 2048
+)
+
+func convertOpenAI2VertexEmbeddingRequest(request dto.EmbeddingRequest) (*VertexEmbeddingRequest, error) {
+	inputs := request.ParseInput()
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("input is empty")
+	}
+	embeddingReq := &VertexEmbeddingRequest{
+		Instances: make([]VertexEmbeddingInstance, 0, len(inputs)),
+		Parameters: &VertexEmbeddingParameters{
+			AutoTruncate: lo.ToPtr(true),
+		},
+	}
+	for _, input := range inputs {
+		embeddingReq.Instances = append(embeddingReq.Instances, VertexEmbeddingInstance{Content: input})
+	}
+	if lo.FromPtrOr(request.Dimensions, 0) > 0 {
+		embeddingReq.Parameters.OutputDimensionality = request.Dimensions
+	}
+	return embeddingReq, nil
+}
+
+// splitVertexEmbeddingInstances 按模型单次请求的条数与 token 上限拆分批次，gemini-embedding 每次仅支持一条输入
+func splitVertexEmbeddingInstances(instances []VertexEmbeddingInstance, modelName string) [][]VertexEmbeddingInstance {
+	if strings.HasPrefix(modelName, "gemini-embedding") {
+		return lo.Chunk(instances, 1)
+	}
+	batches := make([][]VertexEmbeddingInstance, 0)
+	var batch []VertexEmbeddingInstance
+	batchTokens := 0
+	for _, instance := range instances {
+		tokens := min(service.CountTextToken(instance.Content, modelName), vertexEmbeddingMaxInputTokens)
+		if len(batch) > 0 && (len(batch) >= vertexEmbeddingMaxInstances || batchTokens+tokens > vertexEmbeddingMaxBatchTokens) {
+			batches = append(batches, batch)
+			batch = nil
+			batchTokens = 0
+		}
+		batch = append(batch, instance)
+		batchTokens += tokens
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// doEmbeddingRequest 拆分批次依次请求上游，合并各批次的 predictions 为一个响应；任一批次失败时直接返回该响应
+func (a *Adaptor) doEmbeddingRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (*http.Response, error) {
+	var embeddingReq VertexEmbeddingRequest
+	if err := common.DecodeJson(requestBody, &embeddingReq); err != nil {
+		return nil, fmt.Errorf("decode embedding request failed: %w", err)
+	}
+	batches := splitVertexEmbeddingInstances(embeddingReq.Instances, info.UpstreamModelName)
+	merged := VertexEmbeddingResponse{
+		Predictions: make([]VertexEmbeddingPrediction, 0, len(embeddingReq.Instances)),
+	}
+	var lastResp *http.Response
+	for _, batch := range batches {
+		data, err := common.Marshal(VertexEmbeddingRequest{Instances: batch, Parameters: embeddingReq.Parameters})
+		if err != nil {
+			return nil, err
+		}
+		resp, err := channel.DoApiRequest(a, c, info, bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK || len(batches) == 1 {
+			return resp, nil
+		}
+		body, err := io.ReadAll(resp.Body)
+		service.CloseResponseBodyGracefully(resp)
+		if err != nil {
+			return nil, err
+		}
+		var batchResp VertexEmbeddingResponse
+		if err = common.Unmarshal(body, &batchResp); err != nil {
+			return nil, fmt.Errorf("unmarshal embedding response failed: %w", err)
+		}
+		merged.Predictions = append(merged.Predictions, batchResp.Predictions...)
+		lastResp = resp
+	}
+	data, err := common.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	lastResp.Body = io.NopCloser(bytes.NewReader(data))
+	lastResp.ContentLength = int64(len(data))
+	lastResp.Header.Del("Content-Length")
+	return lastResp, nil
+}
+
+func vertexEmbeddingHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Response) (*dto.Usage, *types.NewAPIError) {
+	defer service.CloseResponseBodyGracefully(resp)
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+	}
+	var vertexResponse VertexEmbeddingResponse
+	if err = common.Unmarshal(responseBody, &vertexResponse); err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+	}
+
+	openAIResponse := dto.OpenAIEmbeddingResponse{
+		Object: "list",
+		Data:   make([]dto.OpenAIEmbeddingResponseItem, 0, len(vertexResponse.Predictions)),
+		Model:  info.UpstreamModelName,
+	}
+	promptTokens := 0
+	for i, prediction := range vertexResponse.Predictions {
+		openAIResponse.Data = append(openAIResponse.Data, dto.OpenAIEmbeddingResponseItem{
+			Object:    "embedding",
+			Index:     i,
+			Embedding: prediction.Embeddings.Values,
+		})
+		promptTokens += int(prediction.Embeddings.Statistics.TokenCount)
+	}
+	// 以上游统计的 token 数计费，缺失时按输入估算
+	if promptTokens > 0 {
+		openAIResponse.Usage = dto.Usage{PromptTokens: promptTokens, TotalTokens: promptTokens}
+	} else {
+		openAIResponse.Usage = *service.ResponseText2Usage(c, "", info.UpstreamModelName, info.GetEstimatePromptTokens())
+	}
+
+	jsonResponse, err := common.Marshal(openAIResponse)
+	if err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+	}
+	service.IOCopyBytesGracefully(c, resp, jsonResponse)
+	return &openAIResponse.Usage, nil
+}
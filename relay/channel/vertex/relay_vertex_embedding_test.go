@@ -0,0 +1,74 @@
+package vertex
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitVertexEmbeddingInstances(t *testing.T) {
+	t.Parallel()
+
+	request := dto.EmbeddingRequest{Input: []any{"a", "b", "c"}, Dimensions: lo.ToPtr(256)}
+	embeddingReq, err := convertOpenAI2VertexEmbeddingRequest(request)
+	require.NoError(t, err)
+	require.Equal(t, 256, *embeddingReq.Parameters.OutputDimensionality)
+	require.True(t, *embeddingReq.Parameters.AutoTruncate)
+
+	// gemini-embedding 每次请求仅支持一条输入
+	require.Len(t, splitVertexEmbeddingInstances(embeddingReq.Instances, "gemini-embedding-001"), 3)
+	require.Len(t, splitVertexEmbeddingInstances(embeddingReq.Instances, "text-embedding-005"), 1)
+
+	instances := make([]VertexEmbeddingInstance, 300)
+	for i := range instances {
+		instances[i] = VertexEmbeddingInstance{Content: "hello"}
+	}
+	batches := splitVertexEmbeddingInstances(instances, "text-embedding-005")
+	require.Len(t, batches, 2)
+	require.Len(t, batches[0], vertexEmbeddingMaxInstances)
+
+	// 超过单次请求 token 上限时拆分
+	long := VertexEmbeddingInstance{Content: strings.Repeat("hello ", 3000)}
+	batches = splitVertexEmbeddingInstances(lo.Times(12, func(int) VertexEmbeddingInstance { return long }), "text-embedding-005")
+	require.Len(t, batches, 2)
+	require.Len(t, batches[0], vertexEmbeddingMaxBatchTokens/vertexEmbeddingMaxInputTokens)
+}
+
+func TestVertexEmbeddingHandler(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/v1/embeddings", nil)
+
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body: io.NopCloser(strings.NewReader(`{"predictions":[
+			{"embeddings":{"values":[0.1,0.2],"statistics":{"token_count":4,"truncated":false}}},
+			{"embeddings":{"values":[0.3,0.4],"statistics":{"token_count":6,"truncated":false}}}
+		]}`)),
+	}
+	info := &relaycommon.RelayInfo{ChannelMeta: &relaycommon.ChannelMeta{UpstreamModelName: "text-embedding-005"}}
+
+	usage, apiErr := vertexEmbeddingHandler(ctx, info, resp)
+	require.Nil(t, apiErr)
+	require.Equal(t, 10, usage.PromptTokens)
+	require.Equal(t, 10, usage.TotalTokens)
+
+	var response dto.OpenAIEmbeddingResponse
+	require.NoError(t, common.Unmarshal(recorder.Body.Bytes(), &response))
+	require.Len(t, response.Data, 2)
+	require.Equal(t, 1, response.Data[1].Index)
+	require.Equal(t, []float64{0.3, 0.4}, response.Data[1].Embedding)
+}
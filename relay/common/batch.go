@@ -0,0 +1,20 @@
+package common
+
+import "context"
+
+type batchIdContextKey struct{}
+
+// WithBatchId 标记请求来自批处理任务。批处理请求在内部通过路由重新分发，
+// gin 上下文会被重置，因此使用 request context 传递，客户端无法伪造
+func WithBatchId(ctx context.Context, batchId string) context.Context {
+	return context.WithValue(ctx, batchIdContextKey{}, batchId)
+}
+
+// GetBatchId 返回请求所属的批次 ID，非批处理请求返回空字符串
+func GetBatchId(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	batchId, _ := ctx.Value(batchIdContextKey{}).(string)
+	return batchId
+}
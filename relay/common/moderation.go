@@ -0,0 +1,26 @@
+package common
+
+import "context"
+
+type moderationGroupContextKey struct{}
+
+// WithModerationRequest 标记请求为内容审核发起的内部请求，审核接口调用通过路由重新分发，
+// 使用 request context 传递，客户端无法伪造。group 非空时在该分组内选择审核渠道
+func WithModerationRequest(ctx context.Context, group string) context.Context {
+	return context.WithValue(ctx, moderationGroupContextKey{}, group)
+}
+
+// GetModerationGroup 返回内容审核内部请求指定的分组，ok 表示请求是否为内容审核的内部请求
+func GetModerationGroup(ctx context.Context) (group string, ok bool) {
+	if ctx == nil {
+		return "", false
+	}
+	group, ok = ctx.Value(moderationGroupContextKey{}).(string)
+	return group, ok
+}
+
+// IsModerationRequest 请求是否为内容审核的内部请求，内部请求本身不再审核
+func IsModerationRequest(ctx context.Context) bool {
+	_, ok := GetModerationGroup(ctx)
+	return ok
+}
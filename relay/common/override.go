@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/pkg/tracing"
 	"github.com/QuantumNous/new-api/types"
 	"github.com/samber/lo"
 	"github.com/tidwall/gjson"
@@ -172,8 +173,10 @@ func ApplyParamOverrideWithRelayInfo(jsonData []byte, info *RelayInfo) ([]byte,
 		return jsonData, nil
 	}
 
+	_, span := tracing.StartSpan(info.TraceContext, "param_override")
 	overrideCtx := BuildParamOverrideContext(info)
 	result, err := ApplyParamOverride(jsonData, paramOverride, overrideCtx)
+	tracing.EndSpan(span, err)
 	if err != nil {
 		return nil, err
 	}
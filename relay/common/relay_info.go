@@ -1,6 +1,7 @@
 package common
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -11,6 +12,7 @@ import (
 	"github.com/QuantumNous/new-api/dto"
 	relayconstant "github.com/QuantumNous/new-api/relay/constant"
 	"github.com/QuantumNous/new-api/setting/model_setting"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
 	"github.com/QuantumNous/new-api/types"
 	"github.com/goccy/go-json"
 
@@ -86,6 +88,7 @@ type RelayInfo struct {
 	TokenId           int
 	TokenKey          string
 	TokenGroup        string
+	OrganizationId    int // 令牌所属组织，非 0 时从组织共享钱包扣费
 	UserId            int
 	UsingGroup        string // 使用的分组，当auto跨分组重试时，会变动
 	UserGroup         string // 用户所在分组
@@ -97,13 +100,17 @@ type RelayInfo struct {
 	IsStream               bool
 	IsGeminiBatchEmbedding bool
 	IsPlayground           bool
+	BatchId                string // 批处理请求所属的批次 ID，非空时按批处理倍率计费
+	ResponseCacheHit       bool   // 命中响应缓存，按缓存命中倍率计费
+	PayloadLogged          bool   // 已记录本次请求的请求/响应载荷
 	UsePrice               bool
 	RelayMode              int
 	OriginModelName        string
 	RequestURLPath         string
 	RequestHeaders         map[string]string
 	ShouldIncludeUsage     bool
-	DisablePing            bool // 是否禁止向下游发送自定义 Ping
+	DisablePing            bool          // 是否禁止向下游发送自定义 Ping
+	StreamIdleTimeout      time.Duration // 流式续传检测上游停滞的空闲超时，0 表示使用全局流式超时
 	ClientWs               *websocket.Conn
 	TargetWs               *websocket.Conn
 	InputAudioFormat       string
@@ -123,11 +130,13 @@ type RelayInfo struct {
 	// 强制预扣全额。用于异步任务（视频/音乐生成等），因为请求返回后任务仍在运行，
 	// 必须在提交前锁定全额。
 	ForcePreConsume bool
+	// TraceContext 当前转发尝试的链路追踪上下文，供无法拿到 gin.Context 的阶段创建子 span
+	TraceContext context.Context
 	// Billing 是计费会话，封装了预扣费/结算/退款的统一生命周期。
 	// 免费模型时为 nil。
 	Billing BillingSettler
-	// BillingSource indicates whether this request is billed from wallet quota or subscription.
-	// "" or "wallet" => wallet; "subscription" => subscription
+	// BillingSource indicates whether this request is billed from wallet quota, subscription or organization.
+	// "" or "wallet" => wallet; "subscription" => subscription; "organization" => organization wallet
 	BillingSource string
 	// SubscriptionId is the user_subscriptions.id used when BillingSource == "subscription"
 	SubscriptionId int
@@ -470,6 +479,7 @@ func genBaseRelayInfo(c *gin.Context, request dto.Request) *RelayInfo {
 		TokenKey:       common.GetContextKeyString(c, constant.ContextKeyTokenKey),
 		TokenUnlimited: common.GetContextKeyBool(c, constant.ContextKeyTokenUnlimited),
 		TokenGroup:     tokenGroup,
+		OrganizationId: common.GetContextKeyInt(c, constant.ContextKeyTokenOrganizationId),
 
 		isFirstResponse: true,
 		RelayMode:       relayconstant.Path2RelayMode(c.Request.URL.Path),
@@ -498,6 +508,7 @@ func genBaseRelayInfo(c *gin.Context, request dto.Request) *RelayInfo {
 		info.RequestURLPath = strings.TrimPrefix(info.RequestURLPath, "/pg")
 		info.RequestURLPath = "/v1" + info.RequestURLPath
 	}
+	info.BatchId = GetBatchId(c.Request.Context())
 
 	userSetting, ok := common.GetContextKeyType[dto.UserSetting](c, constant.ContextKeyUserSetting)
 	if ok {
@@ -650,6 +661,18 @@ func (info *RelayInfo) GetEstimatePromptTokens() int {
 	return info.estimatePromptTokens
 }
 
+// ApplyPricingTier 按实际提示 tokens 重新匹配分档计价规则，更新 PriceData 中的价格。
+// 月用量沿用预扣费时查询到的值，时段按请求开始时间匹配；按次计费与提示长度无关，不重新匹配。
+func (info *RelayInfo) ApplyPricingTier(promptTokens int) {
+	if info.PriceData.PricingTier == nil || info.PriceData.UsePrice {
+		return
+	}
+	ratio_setting.ApplyPricingTier(info.OriginModelName, &info.PriceData, ratio_setting.PricingTierContext{
+		PromptTokens: promptTokens,
+		Time:         info.StartTime,
+	})
+}
+
 func (info *RelayInfo) SetFirstResponseTime() {
 	if info.isFirstResponse {
 		info.FirstResponseTime = time.Now()
@@ -661,6 +684,14 @@ func (info *RelayInfo) HasSendResponse() bool {
 	return info.FirstResponseTime.After(info.StartTime)
 }
 
+// FirstResponseSince 返回从 start 到首个响应的耗时；首个响应早于 start（如由之前的重试尝试发出）时返回 false
+func (info *RelayInfo) FirstResponseSince(start time.Time) (time.Duration, bool) {
+	if !info.HasSendResponse() || info.FirstResponseTime.Before(start) {
+		return 0, false
+	}
+	return info.FirstResponseTime.Sub(start), true
+}
+
 type TaskRelayInfo struct {
 	Action       string
 	OriginTaskID string
@@ -50,6 +50,8 @@ func TextHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *types
 		return types.NewError(err, types.ErrorCodeChannelModelMappedError, types.ErrOptionWithSkipRetry())
 	}
 
+	applyStreamFailoverContinuation(c, info, request)
+
 	includeUsage := true
 	// 判断用户是否需要返回使用情况
 	if request.StreamOptions != nil {
@@ -83,6 +85,7 @@ func TextHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *types
 		service.ShouldChatCompletionsUseResponsesGlobal(info.ChannelId, info.ChannelType, info.OriginModelName) {
 		applySystemPromptIfNeeded(c, info, request)
 		usage, newApiErr := chatCompletionsViaResponses(c, info, adaptor, request)
+		usage, newApiErr = handleStreamFailover(c, info, usage, newApiErr)
 		if newApiErr != nil {
 			return newApiErr
 		}
@@ -93,14 +96,18 @@ func TextHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *types
 		if containAudioTokens && containsAudioRatios {
 			service.PostAudioConsumeQuota(c, info, usage, "")
 		} else {
-			postConsumeQuota(c, info, usage)
+			postConsumeQuota(c, info, usage, streamFailoverLogContent(c, info)...)
 		}
 		return nil
 	}
 
 	var requestBody io.Reader
+	var payloadBody []byte
+	var responseCacheKey string
+	var responseCapture *service.ResponseCaptureWriter
 
-	if passThroughGlobal || info.ChannelSetting.PassThroughBodyEnabled {
+	// 续写请求需要追加已输出的内容，不能透传原始请求体
+	if (passThroughGlobal || info.ChannelSetting.PassThroughBodyEnabled) && !service.GetStreamFailover(c).Resumed() {
 		storage, err := common.GetBodyStorage(c)
 		if err != nil {
 			return types.NewErrorWithStatusCode(err, types.ErrorCodeReadRequestBodyFailed, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
@@ -181,9 +188,24 @@ func TextHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *types
 
 		logger.LogDebug(c, fmt.Sprintf("text request body: %s", string(jsonData)))
 
+		// 响应缓存：以模型映射与参数覆盖后的最终请求体作为缓存键
+		if service.ShouldUseResponseCache(c, info) && !service.GetStreamFailover(c).Resumed() {
+			responseCacheKey = service.ResponseCacheKey(info, jsonData)
+			if entry, hit := service.GetCachedResponse(c, info, responseCacheKey); hit {
+				postConsumeQuota(c, info, service.ReplayCachedResponse(c, info, entry))
+				return nil
+			}
+			responseCapture = service.StartResponseCapture(c)
+			defer responseCapture.Restore(c)
+		}
+
 		requestBody = bytes.NewBuffer(jsonData)
+		payloadBody = jsonData
 	}
 
+	payloadCapture := service.StartPayloadCapture(c, info, payloadBody)
+	defer func() { payloadCapture.Finish(c, info, newAPIError) }()
+
 	var httpResp *http.Response
 	resp, err := adaptor.DoRequest(c, info, requestBody)
 	if err != nil {
@@ -203,20 +225,27 @@ func TextHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *types
 		}
 	}
 
-	usage, newApiErr := adaptor.DoResponse(c, httpResp, info)
+	usageAny, newApiErr := adaptor.DoResponse(c, httpResp, info)
 	if newApiErr != nil {
 		// reset status code 重置状态码
 		service.ResetStatusCode(newApiErr, statusCodeMappingStr)
+	}
+	usage, _ := usageAny.(*dto.Usage)
+	usage, newApiErr = handleStreamFailover(c, info, usage, newApiErr)
+	if newApiErr != nil {
 		return newApiErr
 	}
+	if responseCacheKey != "" {
+		service.StoreCapturedResponse(responseCapture, info, responseCacheKey, usage)
+	}
 
-	var containAudioTokens = usage.(*dto.Usage).CompletionTokenDetails.AudioTokens > 0 || usage.(*dto.Usage).PromptTokensDetails.AudioTokens > 0
+	var containAudioTokens = usage.CompletionTokenDetails.AudioTokens > 0 || usage.PromptTokensDetails.AudioTokens > 0
 	var containsAudioRatios = ratio_setting.ContainsAudioRatio(info.OriginModelName) || ratio_setting.ContainsAudioCompletionRatio(info.OriginModelName)
 
 	if containAudioTokens && containsAudioRatios {
-		service.PostAudioConsumeQuota(c, info, usage.(*dto.Usage), "")
+		service.PostAudioConsumeQuota(c, info, usage, "")
 	} else {
-		postConsumeQuota(c, info, usage.(*dto.Usage))
+		postConsumeQuota(c, info, usage, streamFailoverLogContent(c, info)...)
 	}
 	return nil
 }
@@ -238,6 +267,13 @@ func postConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usage
 
 	adminRejectReason := common.GetContextKeyString(ctx, constant.ContextKeyAdminRejectReason)
 
+	// 按实际提示 tokens 重新匹配分档计价（如长上下文分档），流式续传时按单次尝试的最大提示 tokens 匹配
+	if failover := service.GetStreamFailover(ctx); failover.Resumed() {
+		relayInfo.ApplyPricingTier(failover.MaxPromptTokens())
+	} else {
+		relayInfo.ApplyPricingTier(usage.PromptTokens)
+	}
+
 	useTimeSeconds := time.Now().Unix() - relayInfo.StartTime.Unix()
 	promptTokens := usage.PromptTokens
 	cacheTokens := usage.PromptTokensDetails.CachedTokens
@@ -29,8 +29,7 @@ func modelPriceNotConfiguredError(modelName string, userId int) error {
 	)
 }
 
-// https://docs.claude.com/en/docs/build-with-claude/prompt-caching#1-hour-cache-duration
-const claudeCacheCreation1hMultiplier = 6 / 3.75
+const claudeCacheCreation1hMultiplier = ratio_setting.ClaudeCacheCreation1hMultiplier
 
 // HandleGroupRatio checks for "auto_group" in the context and updates the group ratio and relayInfo.UsingGroup if present
 func HandleGroupRatio(ctx *gin.Context, relayInfo *relaycommon.RelayInfo) types.GroupRatioInfo {
@@ -58,12 +57,41 @@ func HandleGroupRatio(ctx *gin.Context, relayInfo *relaycommon.RelayInfo) types.
 		groupRatioInfo.GroupRatio = ratio_setting.GetGroupRatio(relayInfo.UsingGroup)
 	}
 
+	// 批处理请求按折扣倍率计费
+	if relayInfo.BatchId != "" {
+		discountGroupRatio(&groupRatioInfo, operation_setting.GetBatchDiscountRatio())
+	}
+
+	// 命中响应缓存的请求按缓存命中倍率计费
+	if relayInfo.ResponseCacheHit {
+		discountGroupRatio(&groupRatioInfo, operation_setting.GetResponseCacheHitRatio())
+	}
+
 	// 写回 PriceData，确保所有后续读 PriceData.GroupRatioInfo 的地方都用实际分组倍率
 	relayInfo.PriceData.GroupRatioInfo = groupRatioInfo
 
 	return groupRatioInfo
 }
 
+// discountGroupRatio 按折扣倍率调整分组倍率，命中用户分组专属倍率时同步调整，
+// 保证日志中的 user_group_ratio 与实际计费一致
+func discountGroupRatio(groupRatioInfo *types.GroupRatioInfo, ratio float64) {
+	groupRatioInfo.GroupRatio *= ratio
+	if groupRatioInfo.HasSpecialRatio {
+		groupRatioInfo.GroupSpecialRatio *= ratio
+	}
+}
+
+func newPricingTierContext(info *relaycommon.RelayInfo, promptTokens int) ratio_setting.PricingTierContext {
+	return ratio_setting.PricingTierContext{
+		PromptTokens: promptTokens,
+		Time:         info.StartTime,
+		MonthlyUsedQuota: func() int64 {
+			return model.GetUserMonthlyUsedQuota(info.UserId)
+		},
+	}
+}
+
 func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens int, meta *types.TokenCountMeta) (types.PriceData, error) {
 	modelPrice, usePrice := ratio_setting.GetModelPrice(info.OriginModelName, false)
 
@@ -80,8 +108,9 @@ func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens
 	var audioRatio float64
 	var audioCompletionRatio float64
 	var freeModel bool
+	var preConsumedTokens int
 	if !usePrice {
-		preConsumedTokens := common.Max(promptTokens, common.PreConsumedQuota)
+		preConsumedTokens = common.Max(promptTokens, common.PreConsumedQuota)
 		if meta.MaxTokens != 0 {
 			preConsumedTokens += meta.MaxTokens
 		}
@@ -106,6 +135,29 @@ func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens
 		imageRatio, _ = ratio_setting.GetImageRatio(info.OriginModelName)
 		audioRatio = ratio_setting.GetAudioRatio(info.OriginModelName)
 		audioCompletionRatio = ratio_setting.GetAudioCompletionRatio(info.OriginModelName)
+	}
+
+	// 按提示长度、时段和用户月用量匹配分档计价规则
+	tierPrice := types.PriceData{
+		UsePrice:             usePrice,
+		ModelPrice:           modelPrice,
+		ModelRatio:           modelRatio,
+		CompletionRatio:      completionRatio,
+		CacheRatio:           cacheRatio,
+		CacheCreationRatio:   cacheCreationRatio,
+		CacheCreation5mRatio: cacheCreationRatio5m,
+		CacheCreation1hRatio: cacheCreationRatio1h,
+	}
+	ratio_setting.ApplyPricingTier(info.OriginModelName, &tierPrice, newPricingTierContext(info, promptTokens))
+	modelPrice = tierPrice.ModelPrice
+	modelRatio = tierPrice.ModelRatio
+	completionRatio = tierPrice.CompletionRatio
+	cacheRatio = tierPrice.CacheRatio
+	cacheCreationRatio = tierPrice.CacheCreationRatio
+	cacheCreationRatio5m = tierPrice.CacheCreation5mRatio
+	cacheCreationRatio1h = tierPrice.CacheCreation1hRatio
+
+	if !usePrice {
 		ratio := modelRatio * groupRatioInfo.GroupRatio
 		preConsumedQuota = int(float64(preConsumedTokens) * ratio)
 	} else {
@@ -149,6 +201,7 @@ func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens
 		CacheCreation5mRatio: cacheCreationRatio5m,
 		CacheCreation1hRatio: cacheCreationRatio1h,
 		QuotaToPreConsume:    preConsumedQuota,
+		PricingTier:          tierPrice.PricingTier,
 	}
 
 	if common.DebugEnabled {
@@ -185,6 +238,10 @@ func ModelPriceHelperPerCall(c *gin.Context, info *relaycommon.RelayInfo) (types
 		}
 
 	}
+
+	tierPrice := types.PriceData{UsePrice: true, ModelPrice: modelPrice}
+	ratio_setting.ApplyPricingTier(info.OriginModelName, &tierPrice, newPricingTierContext(info, 0))
+	modelPrice = tierPrice.ModelPrice
 	quota := int(modelPrice * common.QuotaPerUnit * groupRatioInfo.GroupRatio)
 
 	// 免费模型检测（与 ModelPriceHelper 对齐）
@@ -201,6 +258,7 @@ func ModelPriceHelperPerCall(c *gin.Context, info *relaycommon.RelayInfo) (types
 		ModelPrice:     modelPrice,
 		Quota:          quota,
 		GroupRatioInfo: groupRatioInfo,
+		PricingTier:    tierPrice.PricingTier,
 	}
 	return priceData, nil
 }
@@ -0,0 +1,46 @@
+package helper
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleGroupRatioBatchDiscount(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setting := operation_setting.GetBatchSetting()
+	original := setting.DiscountRatio
+	setting.DiscountRatio = 0.5
+	t.Cleanup(func() { setting.DiscountRatio = original })
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	info := &relaycommon.RelayInfo{UsingGroup: "default"}
+	require.Equal(t, 1.0, HandleGroupRatio(c, info).GroupRatio)
+
+	// 批处理中的每个请求按折扣倍率计费
+	info.BatchId = relaycommon.GetBatchId(relaycommon.WithBatchId(context.Background(), "batch_test"))
+	require.Equal(t, "batch_test", info.BatchId)
+	require.Equal(t, 0.5, HandleGroupRatio(c, info).GroupRatio)
+	require.Equal(t, 0.5, info.PriceData.GroupRatioInfo.GroupRatio)
+
+	// 命中用户分组专属倍率时，日志记录的专属倍率同样按折扣调整
+	originalGroupGroupRatio := ratio_setting.GroupGroupRatio2JSONString()
+	require.NoError(t, ratio_setting.UpdateGroupGroupRatioByJSONString(`{"vip":{"default":0.8}}`))
+	t.Cleanup(func() { _ = ratio_setting.UpdateGroupGroupRatioByJSONString(originalGroupGroupRatio) })
+	info.UserGroup = "vip"
+	groupRatioInfo := HandleGroupRatio(c, info)
+	require.True(t, groupRatioInfo.HasSpecialRatio)
+	require.InDelta(t, 0.4, groupRatioInfo.GroupRatio, 1e-9)
+	require.InDelta(t, 0.4, groupRatioInfo.GroupSpecialRatio, 1e-9)
+	info.UserGroup = ""
+
+	// 非法的折扣倍率按原价计费
+	setting.DiscountRatio = -1
+	require.Equal(t, 1.0, HandleGroupRatio(c, info).GroupRatio)
+}
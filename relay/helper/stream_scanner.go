@@ -13,12 +13,14 @@ import (
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/pkg/tracing"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
 	"github.com/QuantumNous/new-api/setting/operation_setting"
 
 	"github.com/bytedance/gopkg/util/gopool"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const (
@@ -40,6 +42,16 @@ func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon
 		return
 	}
 
+	// 流式读取与格式转换阶段
+	_, span := tracing.StartSpan(c.Request.Context(), "stream_response")
+	defer func() {
+		span.SetAttributes(
+			attribute.Int("newapi.stream.received_count", info.ReceivedResponseCount),
+			attribute.Int("newapi.stream.sent_count", info.SendResponseCount),
+		)
+		span.End()
+	}()
+
 	// 确保响应体总是被关闭
 	defer func() {
 		if resp.Body != nil {
@@ -48,6 +60,9 @@ func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon
 	}()
 
 	streamingTimeout := time.Duration(constant.StreamingTimeout) * time.Second
+	if info.StreamIdleTimeout > 0 && info.StreamIdleTimeout < streamingTimeout {
+		streamingTimeout = info.StreamIdleTimeout
+	}
 
 	var (
 		stopChan   = make(chan bool, 3) // 增加缓冲区避免阻塞
@@ -271,8 +286,11 @@ func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon
 	// 主循环等待完成或超时
 	select {
 	case <-ticker.C:
-		// 超时处理逻辑
+		// 超时处理逻辑，立即关闭响应体使阻塞在读取上的 scanner 退出
 		logger.LogError(c, "streaming timeout")
+		if resp.Body != nil {
+			_ = resp.Body.Close()
+		}
 	case <-stopChan:
 		// 正常结束
 		logger.LogInfo(c, "streaming finished")
@@ -42,6 +42,7 @@ func RerankHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *typ
 	adaptor.Init(info)
 
 	var requestBody io.Reader
+	var payloadBody []byte
 	if model_setting.GetGlobalSettings().PassThroughRequestEnabled || info.ChannelSetting.PassThroughBodyEnabled {
 		storage, err := common.GetBodyStorage(c)
 		if err != nil {
@@ -71,8 +72,12 @@ func RerankHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *typ
 			println(fmt.Sprintf("Rerank request body: %s", string(jsonData)))
 		}
 		requestBody = bytes.NewBuffer(jsonData)
+		payloadBody = jsonData
 	}
 
+	payloadCapture := service.StartPayloadCapture(c, info, payloadBody)
+	defer func() { payloadCapture.Finish(c, info, newAPIError) }()
+
 	resp, err := adaptor.DoRequest(c, info, requestBody)
 	if err != nil {
 		return types.NewOpenAIError(err, types.ErrorCodeDoRequestFailed, http.StatusInternalServerError)
@@ -10,6 +10,7 @@ import (
 	"github.com/QuantumNous/new-api/common"
 	appconstant "github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
 	relayconstant "github.com/QuantumNous/new-api/relay/constant"
 	"github.com/QuantumNous/new-api/relay/helper"
@@ -69,9 +70,33 @@ func ResponsesHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *
 	if adaptor == nil {
 		return types.NewError(fmt.Errorf("invalid api type: %d", info.ApiType), types.ErrorCodeInvalidApiType, types.ErrOptionWithSkipRetry())
 	}
+	passThrough := model_setting.GetGlobalSettings().PassThroughRequestEnabled || info.ChannelSetting.PassThroughBodyEnabled
+	bridged := !passThrough && info.RelayMode == relayconstant.RelayModeResponses && !responsesNativeApiType(info.ApiType)
+	if !passThrough && info.RelayMode == relayconstant.RelayModeResponses && request.PreviousResponseID != "" {
+		if newAPIError := rebuildResponsesConversation(info, request, bridged); newAPIError != nil {
+			return newAPIError
+		}
+	}
+	if bridged {
+		usage, response, newAPIError := responsesViaChatCompletions(c, info, adaptor, request)
+		if newAPIError != nil {
+			return newAPIError
+		}
+		if service.ResponsesStoreEnabled(responsesReq) {
+			// 仅保存本轮输入，历史由 previous_response_id 链展开
+			if err := service.SaveResponseRecord(info.UserId, info.TokenId, responsesReq, responsesReq.Input, response); err != nil {
+				logger.LogError(c, "save response record failed: "+err.Error())
+			}
+		}
+		helper.HandleGroupRatio(c, info)
+		postConsumeQuota(c, info, usage)
+		return nil
+	}
+
 	adaptor.Init(info)
 	var requestBody io.Reader
-	if model_setting.GetGlobalSettings().PassThroughRequestEnabled || info.ChannelSetting.PassThroughBodyEnabled {
+	var payloadBody []byte
+	if passThrough {
 		storage, err := common.GetBodyStorage(c)
 		if err != nil {
 			return types.NewError(err, types.ErrorCodeReadRequestBodyFailed, types.ErrOptionWithSkipRetry())
@@ -106,8 +131,12 @@ func ResponsesHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *
 			println("requestBody: ", string(jsonData))
 		}
 		requestBody = bytes.NewBuffer(jsonData)
+		payloadBody = jsonData
 	}
 
+	payloadCapture := service.StartPayloadCapture(c, info, payloadBody)
+	defer func() { payloadCapture.Finish(c, info, newAPIError) }()
+
 	var httpResp *http.Response
 	resp, err := adaptor.DoRequest(c, info, requestBody)
 	if err != nil {
@@ -0,0 +1,303 @@
+package relay
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/QuantumNous/new-api/common"
+	appconstant "github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/relay/channel"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	relayconstant "github.com/QuantumNous/new-api/relay/constant"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// responsesNativeApiType 上游原生支持 /v1/responses 的渠道直接透传，其余渠道经 Chat Completions 桥接
+func responsesNativeApiType(apiType int) bool {
+	switch apiType {
+	case appconstant.APITypeOpenAI, appconstant.APITypeCodex, appconstant.APITypeOpenRouter, appconstant.APITypeXinference,
+		appconstant.APITypeXai, appconstant.APITypeAli, appconstant.APITypeCloudflare, appconstant.APITypePerplexity,
+		appconstant.APITypeVolcEngine:
+		return true
+	default:
+		return false
+	}
+}
+
+// responsesBridgeWriter 拦截渠道输出的 Chat Completions 响应并转换为 Responses 格式写回客户端
+// 流式模式逐个解析 SSE 事件实时转换，非流式模式缓存完整响应体后统一转换
+type responsesBridgeWriter struct {
+	gin.ResponseWriter
+	origin    gin.ResponseWriter
+	stream    bool
+	converter *service.ChatToResponsesStreamConverter
+	mu        sync.Mutex
+	buf       bytes.Buffer
+}
+
+func (w *responsesBridgeWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf.Write(data)
+	if w.stream {
+		w.processEvents(false)
+	}
+	return len(data), nil
+}
+
+func (w *responsesBridgeWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// 非流式模式下响应头需在转换完成后才能发送，拦截渠道处理器的提前刷新
+func (w *responsesBridgeWriter) Flush() {
+	if w.stream {
+		w.origin.Flush()
+	}
+}
+
+func (w *responsesBridgeWriter) WriteHeaderNow() {
+	if w.stream {
+		w.origin.WriteHeaderNow()
+	}
+}
+
+// restore 恢复原始的 ResponseWriter
+func (w *responsesBridgeWriter) restore(c *gin.Context) {
+	if c.Writer == w {
+		c.Writer = w.origin
+	}
+}
+
+// processEvents 处理缓冲区中完整的 SSE 事件，flush 为 true 时同时处理末尾不完整的部分
+func (w *responsesBridgeWriter) processEvents(flush bool) {
+	for {
+		data := w.buf.Bytes()
+		idx := bytes.Index(data, []byte("\n\n"))
+		if idx < 0 {
+			if flush && len(bytes.TrimSpace(data)) > 0 {
+				w.handleEvent(string(data))
+			}
+			if flush {
+				w.buf.Reset()
+			}
+			return
+		}
+		block := string(data[:idx])
+		w.buf.Next(idx + 2)
+		w.handleEvent(block)
+	}
+}
+
+func (w *responsesBridgeWriter) handleEvent(block string) {
+	for _, line := range strings.Split(block, "\n") {
+		line = strings.TrimRight(line, "\r")
+		switch {
+		case strings.HasPrefix(line, ":"):
+			// 保活注释原样转发
+			_, _ = w.origin.WriteString(line + "\n\n")
+			w.origin.Flush()
+		case strings.HasPrefix(line, "data:"):
+			payload := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			if payload == "" || payload == "[DONE]" {
+				continue
+			}
+			var chunk dto.ChatCompletionsStreamResponse
+			if err := common.UnmarshalJsonStr(payload, &chunk); err != nil {
+				common.SysError("failed to unmarshal chat completions chunk: " + err.Error())
+				continue
+			}
+			w.writeEvents(w.converter.HandleChunk(&chunk))
+		}
+	}
+}
+
+func (w *responsesBridgeWriter) writeEvents(events []dto.ResponsesStreamResponse) {
+	if len(events) == 0 {
+		return
+	}
+	for _, event := range events {
+		data, err := common.Marshal(event)
+		if err != nil {
+			common.SysError("failed to marshal responses stream event: " + err.Error())
+			continue
+		}
+		_, _ = w.origin.WriteString(fmt.Sprintf("event: %s\ndata: %s\n\n", event.Type, data))
+	}
+	w.origin.Flush()
+}
+
+// finishStream 输出剩余事件与 response.completed
+func (w *responsesBridgeWriter) finishStream(usage *dto.Usage) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.processEvents(true)
+	w.writeEvents(w.converter.Finish(usage))
+}
+
+// failStream 流已开始输出后上游出错，以 response.failed 结束
+func (w *responsesBridgeWriter) failStream(newAPIError *types.NewAPIError) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.converter.Started() {
+		return false
+	}
+	w.writeEvents(w.converter.Fail(string(newAPIError.GetErrorCode()), newAPIError.Error()))
+	return true
+}
+
+// rebuildResponsesConversation 将网关保存的历史会话展开到 request.Input 之前
+// 桥接渠道找不到 previous_response_id 时直接报错；原生渠道找不到时交由上游处理，找到时展开后不再传递该字段
+func rebuildResponsesConversation(info *relaycommon.RelayInfo, request *dto.OpenAIResponsesRequest, bridged bool) *types.NewAPIError {
+	history, err := service.LoadResponsesConversation(request.PreviousResponseID, info.UserId, info.TokenId)
+	if err != nil {
+		if !errors.Is(err, service.ErrResponseNotFound) {
+			return types.NewError(err, types.ErrorCodeQueryDataError, types.ErrOptionWithSkipRetry())
+		}
+		if !bridged {
+			return nil
+		}
+		return types.NewErrorWithStatusCode(
+			fmt.Errorf("previous response with id '%s' not found", request.PreviousResponseID),
+			types.ErrorCodeInvalidRequest,
+			http.StatusBadRequest,
+			types.ErrOptionWithSkipRetry(),
+		)
+	}
+	currentItems, err := service.ResponsesInputItems(request.Input)
+	if err != nil {
+		return types.NewErrorWithStatusCode(err, types.ErrorCodeInvalidRequest, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
+	}
+	input, err := common.Marshal(append(history, currentItems...))
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeConvertRequestFailed, types.ErrOptionWithSkipRetry())
+	}
+	request.Input = input
+	if !bridged {
+		request.PreviousResponseID = ""
+	}
+	return nil
+}
+
+// responsesViaChatCompletions 将 Responses 请求转换为 Chat Completions 请求发往不支持 Responses API 的渠道，
+// 再将渠道输出的 Chat Completions 响应转换回 Responses 格式；调用前 request.Input 需已展开 previous_response_id 的历史会话
+func responsesViaChatCompletions(c *gin.Context, info *relaycommon.RelayInfo, adaptor channel.Adaptor, request *dto.OpenAIResponsesRequest) (usage *dto.Usage, response *dto.OpenAIResponsesResponse, newAPIError *types.NewAPIError) {
+	messages, err := service.ResponsesInputToChatMessages(request.Input)
+	if err != nil {
+		return nil, nil, types.NewErrorWithStatusCode(err, types.ErrorCodeInvalidRequest, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
+	}
+	chatReq, err := service.ResponsesRequestToChatCompletionsRequest(request, messages)
+	if err != nil {
+		return nil, nil, types.NewErrorWithStatusCode(err, types.ErrorCodeInvalidRequest, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
+	}
+	applySystemPromptIfNeeded(c, info, chatReq)
+	info.AppendRequestConversion(types.RelayFormatOpenAI)
+
+	savedRelayMode := info.RelayMode
+	savedRelayFormat := info.RelayFormat
+	savedRequestURLPath := info.RequestURLPath
+	defer func() {
+		info.RelayMode = savedRelayMode
+		info.RelayFormat = savedRelayFormat
+		info.RequestURLPath = savedRequestURLPath
+	}()
+
+	info.RelayMode = relayconstant.RelayModeChatCompletions
+	info.RelayFormat = types.RelayFormatOpenAI
+	info.RequestURLPath = "/v1/chat/completions"
+	adaptor.Init(info)
+
+	convertedRequest, err := adaptor.ConvertOpenAIRequest(c, info, chatReq)
+	if err != nil {
+		return nil, nil, types.NewError(err, types.ErrorCodeConvertRequestFailed, types.ErrOptionWithSkipRetry())
+	}
+	relaycommon.AppendRequestConversionFromRequest(info, convertedRequest)
+
+	jsonData, err := common.Marshal(convertedRequest)
+	if err != nil {
+		return nil, nil, types.NewError(err, types.ErrorCodeConvertRequestFailed, types.ErrOptionWithSkipRetry())
+	}
+
+	jsonData, err = relaycommon.RemoveDisabledFields(jsonData, info.ChannelOtherSettings, info.ChannelSetting.PassThroughBodyEnabled)
+	if err != nil {
+		return nil, nil, types.NewError(err, types.ErrorCodeConvertRequestFailed, types.ErrOptionWithSkipRetry())
+	}
+
+	if len(info.ParamOverride) > 0 {
+		jsonData, err = relaycommon.ApplyParamOverrideWithRelayInfo(jsonData, info)
+		if err != nil {
+			return nil, nil, newAPIErrorFromParamOverride(err)
+		}
+	}
+
+	if common.DebugEnabled {
+		println("requestBody: ", string(jsonData))
+	}
+
+	payloadCapture := service.StartPayloadCapture(c, info, jsonData)
+	defer func() { payloadCapture.Finish(c, info, newAPIError) }()
+
+	response = service.NewResponsesResponse("resp_"+common.GetUUID(), info.OriginModelName, request)
+	writer := &responsesBridgeWriter{
+		ResponseWriter: c.Writer,
+		origin:         c.Writer,
+		stream:         info.IsStream,
+		converter:      service.NewChatToResponsesStreamConverter(response),
+	}
+	c.Writer = writer
+	defer writer.restore(c)
+
+	var httpResp *http.Response
+	resp, err := adaptor.DoRequest(c, info, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, nil, types.NewOpenAIError(err, types.ErrorCodeDoRequestFailed, http.StatusInternalServerError)
+	}
+
+	statusCodeMappingStr := c.GetString("status_code_mapping")
+
+	if resp != nil {
+		httpResp = resp.(*http.Response)
+		if httpResp.StatusCode != http.StatusOK {
+			newAPIError = service.RelayErrorHandler(c.Request.Context(), httpResp, false)
+			service.ResetStatusCode(newAPIError, statusCodeMappingStr)
+			return nil, nil, newAPIError
+		}
+	}
+
+	usageAny, newAPIError := adaptor.DoResponse(c, httpResp, info)
+	if newAPIError != nil {
+		service.ResetStatusCode(newAPIError, statusCodeMappingStr)
+		if writer.stream {
+			writer.failStream(newAPIError)
+		}
+		return nil, nil, newAPIError
+	}
+	usage = usageAny.(*dto.Usage)
+
+	if writer.stream {
+		writer.finishStream(usage)
+	} else {
+		writer.restore(c)
+		var chatResp dto.OpenAITextResponse
+		if err := common.Unmarshal(writer.buf.Bytes(), &chatResp); err != nil {
+			return nil, nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+		}
+		// 以渠道处理器返回的用量为准
+		chatResp.Usage = *usage
+		if err := service.ChatCompletionsResponseToResponsesResponse(&chatResp, response); err != nil {
+			return nil, nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+		}
+		c.Writer.Header().Del("Content-Length")
+		c.JSON(http.StatusOK, response)
+	}
+
+	return usage, response, nil
+}
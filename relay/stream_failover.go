@@ -0,0 +1,93 @@
+package relay
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	appconstant "github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamFailoverSupportsPrefill 渠道是否支持以末尾的 assistant 消息预填充续写
+func streamFailoverSupportsPrefill(info *relaycommon.RelayInfo) bool {
+	switch info.ApiType {
+	case appconstant.APITypeAnthropic:
+		return true
+	case appconstant.APITypeAws, appconstant.APITypeVertexAi:
+		return strings.Contains(strings.ToLower(info.UpstreamModelName), "claude")
+	default:
+		return false
+	}
+}
+
+// applyStreamFailoverContinuation 续写时将已输出的内容追加到请求中
+func applyStreamFailoverContinuation(c *gin.Context, info *relaycommon.RelayInfo, request *dto.GeneralOpenAIRequest) {
+	failover := service.GetStreamFailover(c)
+	if !failover.Resumed() {
+		return
+	}
+	prefill := streamFailoverSupportsPrefill(info)
+	failover.ApplyContinuation(request, prefill)
+	logger.LogInfo(c, fmt.Sprintf("stream failover: resuming on channel #%d, prefill=%t", info.ChannelId, prefill))
+}
+
+// handleStreamFailover 在渠道处理完流式响应后调用。
+// 输出中途中断且可以续传时返回 stream_interrupted 错误，由 controller 切换渠道续写；
+// 否则结束暂扣的事件并返回各次尝试的合计用量
+func handleStreamFailover(c *gin.Context, info *relaycommon.RelayInfo, usage *dto.Usage, newAPIError *types.NewAPIError) (*dto.Usage, *types.NewAPIError) {
+	failover := service.GetStreamFailover(c)
+	if failover == nil {
+		return usage, newAPIError
+	}
+	if failover.Interrupted() && failover.CanFailover() {
+		reason := "missing finish_reason"
+		if newAPIError != nil {
+			reason = newAPIError.Error()
+			usage = nil
+		}
+		failover.Suspend(info, usage)
+		logger.LogWarn(c, fmt.Sprintf("stream failover: output interrupted on channel #%d (%s), failover %d", info.ChannelId, reason, failover.Failovers()))
+		return nil, types.NewErrorWithStatusCode(
+			fmt.Errorf("upstream stream interrupted on channel #%d: %s", info.ChannelId, reason),
+			types.ErrorCodeStreamInterrupted,
+			http.StatusBadGateway,
+		)
+	}
+	if newAPIError != nil {
+		// 由 controller 以 FinishStreamFailover 结束流并计费
+		return nil, newAPIError
+	}
+	return failover.Complete(info, usage), nil
+}
+
+// streamFailoverLogContent 续写成功时计费日志中的附加说明
+func streamFailoverLogContent(c *gin.Context, info *relaycommon.RelayInfo) []string {
+	failover := service.GetStreamFailover(c)
+	if !failover.Resumed() {
+		return nil
+	}
+	return []string{failover.LogContent(info.ChannelId)}
+}
+
+// FinishStreamFailover 续传失败且已向客户端输出内容时，以错误事件结束流并按已产生的用量计费，
+// 此时不再退还预扣费，也不再写回错误响应
+func FinishStreamFailover(c *gin.Context, info *relaycommon.RelayInfo, newAPIError *types.NewAPIError) bool {
+	failover := service.GetStreamFailover(c)
+	if !failover.Pending() {
+		return false
+	}
+	usage := failover.Abort(info, newAPIError)
+	extraContent := []string{fmt.Sprintf("流式输出中断且续传失败：%s", newAPIError.Error())}
+	if failover.Resumed() {
+		extraContent = append(extraContent, failover.LogContent(0))
+	}
+	postConsumeQuota(c, info, usage, extraContent...)
+	return true
+}
@@ -116,7 +116,7 @@ func SetApiRouter(router *gin.Engine) {
 			}
 
 			adminRoute := userRoute.Group("/")
-			adminRoute.Use(middleware.AdminAuth())
+			adminRoute.Use(middleware.AdminAuth(), middleware.AuditLog("user"))
 			{
 				adminRoute.GET("/", controller.GetAllUsers)
 				adminRoute.GET("/topup", controller.GetAllTopUps)
@@ -151,7 +151,7 @@ func SetApiRouter(router *gin.Engine) {
 			subscriptionRoute.POST("/creem/pay", middleware.CriticalRateLimit(), controller.SubscriptionRequestCreemPay)
 		}
 		subscriptionAdminRoute := apiRouter.Group("/subscription/admin")
-		subscriptionAdminRoute.Use(middleware.AdminAuth())
+		subscriptionAdminRoute.Use(middleware.AdminAuth(), middleware.AuditLog("subscription"))
 		{
 			subscriptionAdminRoute.GET("/plans", controller.AdminListSubscriptionPlans)
 			subscriptionAdminRoute.POST("/plans", controller.AdminCreateSubscriptionPlan)
@@ -166,13 +166,50 @@ func SetApiRouter(router *gin.Engine) {
 			subscriptionAdminRoute.DELETE("/user_subscriptions/:id", controller.AdminDeleteUserSubscription)
 		}
 
+		organizationRoute := apiRouter.Group("/organization")
+		organizationRoute.Use(middleware.UserAuth())
+		{
+			organizationRoute.GET("/self", controller.GetSelfOrganizations)
+			organizationRoute.POST("/", controller.CreateOrganization)
+			organizationRoute.GET("/:id", controller.GetOrganization)
+			organizationRoute.PUT("/:id", controller.UpdateOrganization)
+			organizationRoute.DELETE("/:id", controller.DeleteOrganization)
+			organizationRoute.GET("/:id/members", controller.GetOrganizationMembers)
+			organizationRoute.POST("/:id/members", controller.AddOrganizationMember)
+			organizationRoute.PUT("/:id/members/:user_id", controller.UpdateOrganizationMember)
+			organizationRoute.DELETE("/:id/members/:user_id", controller.RemoveOrganizationMember)
+			organizationRoute.POST("/:id/transfer", middleware.CriticalRateLimit(), controller.TransferQuotaToOrganization)
+			organizationRoute.GET("/:id/logs", controller.GetOrganizationLogs)
+			organizationRoute.GET("/:id/data", controller.GetOrganizationQuotaData)
+		}
+		organizationAdminRoute := apiRouter.Group("/organization/admin")
+		organizationAdminRoute.Use(middleware.AdminAuth())
+		{
+			organizationAdminRoute.GET("/", controller.AdminGetAllOrganizations)
+			organizationAdminRoute.GET("/:id/members", controller.AdminGetOrganizationMembers)
+			organizationAdminRoute.POST("/manage", controller.AdminManageOrganization)
+		}
+
+		webhookRoute := apiRouter.Group("/webhook")
+		webhookRoute.Use(middleware.UserAuth())
+		{
+			webhookRoute.GET("/events", controller.GetWebhookEventTypes)
+			webhookRoute.GET("/self", controller.GetSelfWebhookEndpoints)
+			webhookRoute.POST("/", controller.CreateWebhookEndpoint)
+			webhookRoute.PUT("/:id", controller.UpdateWebhookEndpoint)
+			webhookRoute.DELETE("/:id", controller.DeleteWebhookEndpoint)
+			webhookRoute.POST("/:id/test", middleware.CriticalRateLimit(), controller.TestWebhookEndpoint)
+			webhookRoute.GET("/delivery", controller.GetSelfWebhookDeliveries)
+			webhookRoute.POST("/delivery/:id/retry", controller.RetryWebhookDelivery)
+		}
+
 		// Subscription payment callbacks (no auth)
 		apiRouter.POST("/subscription/epay/notify", controller.SubscriptionEpayNotify)
 		apiRouter.GET("/subscription/epay/notify", controller.SubscriptionEpayNotify)
 		apiRouter.GET("/subscription/epay/return", controller.SubscriptionEpayReturn)
 		apiRouter.POST("/subscription/epay/return", controller.SubscriptionEpayReturn)
 		optionRoute := apiRouter.Group("/option")
-		optionRoute.Use(middleware.RootAuth())
+		optionRoute.Use(middleware.RootAuth(), middleware.AuditLog("option"))
 		{
 			optionRoute.GET("/", controller.GetOptions)
 			optionRoute.PUT("/", controller.UpdateOption)
@@ -182,6 +219,14 @@ func SetApiRouter(router *gin.Engine) {
 			optionRoute.POST("/migrate_console_setting", controller.MigrateConsoleSetting) // 用于迁移检测的旧键，下个版本会删除
 		}
 
+		// 声明式配置 (Root 权限)
+		declarativeConfigRoute := apiRouter.Group("/config")
+		declarativeConfigRoute.Use(middleware.RootAuth())
+		{
+			declarativeConfigRoute.POST("/plan", controller.PlanDeclarativeConfig)
+			declarativeConfigRoute.POST("/apply", controller.ApplyDeclarativeConfig)
+		}
+
 		// 站点管理员获取自己的站点（specific route must come before group to avoid conflicts）
 		apiRouter.GET("/proxy_site/mine", middleware.UserAuth(), controller.GetMySite)
 
@@ -227,6 +272,7 @@ func SetApiRouter(router *gin.Engine) {
 		{
 			performanceRoute.GET("/stats", controller.GetPerformanceStats)
 			performanceRoute.DELETE("/disk_cache", controller.ClearDiskCache)
+			performanceRoute.DELETE("/response_cache", controller.ClearResponseCache)
 			performanceRoute.POST("/reset_stats", controller.ResetPerformanceStats)
 			performanceRoute.POST("/gc", controller.ForceGC)
 		}
@@ -237,10 +283,12 @@ func SetApiRouter(router *gin.Engine) {
 			ratioSyncRoute.POST("/fetch", controller.FetchUpstreamRatios)
 		}
 		channelRoute := apiRouter.Group("/channel")
-		channelRoute.Use(middleware.AdminAuth())
+		channelRoute.Use(middleware.AdminAuth(), middleware.AuditLog("channel"))
 		{
 			channelRoute.GET("/", controller.GetAllChannels)
 			channelRoute.GET("/search", controller.SearchChannels)
+			channelRoute.GET("/health", controller.GetChannelHealth)
+			channelRoute.DELETE("/health/:id", controller.ResetChannelHealth)
 			channelRoute.GET("/models", controller.ChannelListModels)
 			channelRoute.GET("/models_enabled", controller.EnabledListModels)
 			channelRoute.GET("/:id", controller.GetChannel)
@@ -285,6 +333,7 @@ func SetApiRouter(router *gin.Engine) {
 			tokenRoute.GET("/", controller.GetAllTokens)
 			tokenRoute.GET("/search", middleware.SearchRateLimit(), controller.SearchTokens)
 			tokenRoute.GET("/:id", controller.GetToken)
+			tokenRoute.GET("/:id/usage", controller.GetTokenBudgetUsage)
 			tokenRoute.POST("/:id/key", middleware.CriticalRateLimit(), middleware.DisableCache(), controller.GetTokenKey)
 			tokenRoute.POST("/", controller.AddToken)
 			tokenRoute.PUT("/", controller.UpdateToken)
@@ -303,7 +352,7 @@ func SetApiRouter(router *gin.Engine) {
 		}
 
 		redemptionRoute := apiRouter.Group("/redemption")
-		redemptionRoute.Use(middleware.AdminAuth())
+		redemptionRoute.Use(middleware.AdminAuth(), middleware.AuditLog("redemption"))
 		{
 			redemptionRoute.GET("/", controller.GetAllRedemptions)
 			redemptionRoute.GET("/search", controller.SearchRedemptions)
@@ -322,10 +371,30 @@ func SetApiRouter(router *gin.Engine) {
 		logRoute.GET("/search", middleware.AdminAuth(), controller.SearchAllLogs)
 		logRoute.GET("/self", middleware.UserAuth(), controller.GetUserLogs)
 		logRoute.GET("/self/search", middleware.UserAuth(), middleware.SearchRateLimit(), controller.SearchUserLogs)
+		logRoute.GET("/export", middleware.AdminAuth(), controller.ExportAllLogs)
+		logRoute.GET("/self/export", middleware.UserAuth(), middleware.SearchRateLimit(), controller.ExportUserLogs)
+		logRoute.GET("/payload", middleware.AdminAuth(), controller.GetPayloadLogs)
+		logRoute.GET("/payload/:request_id", middleware.AdminAuth(), controller.GetPayloadLogsByRequestId)
+
+		auditLogRoute := apiRouter.Group("/audit_log")
+		auditLogRoute.Use(middleware.RootAuth())
+		{
+			auditLogRoute.GET("/", controller.GetAuditLogs)
+			auditLogRoute.GET("/:id", controller.GetAuditLog)
+		}
 
 		dataRoute := apiRouter.Group("/data")
 		dataRoute.GET("/", middleware.AdminAuth(), controller.GetAllQuotaDates)
 		dataRoute.GET("/self", middleware.UserAuth(), controller.GetUserQuotaDates)
+		dataRoute.GET("/export", middleware.AdminAuth(), controller.ExportAllQuotaData)
+		dataRoute.GET("/self/export", middleware.UserAuth(), middleware.SearchRateLimit(), controller.ExportUserQuotaData)
+
+		statementRoute := apiRouter.Group("/statement")
+		statementRoute.GET("/self", middleware.UserAuth(), controller.GetSelfStatements)
+		statementRoute.GET("/self/:period", middleware.UserAuth(), controller.GetSelfStatement)
+		statementRoute.GET("/", middleware.AdminAuth(), controller.AdminGetStatements)
+		statementRoute.GET("/detail/:period", middleware.AdminAuth(), controller.AdminGetStatement)
+		statementRoute.POST("/generate", middleware.AdminAuth(), controller.AdminGenerateStatement)
 
 		logRoute.Use(middleware.CORS(), middleware.CriticalRateLimit())
 		{
@@ -356,6 +425,12 @@ func SetApiRouter(router *gin.Engine) {
 			taskRoute.GET("/", middleware.AdminAuth(), controller.GetAllTask)
 		}
 
+		batchRoute := apiRouter.Group("/batch")
+		{
+			batchRoute.GET("/self", middleware.UserAuth(), controller.GetUserBatch)
+			batchRoute.GET("/", middleware.AdminAuth(), controller.GetAllBatch)
+		}
+
 		vendorRoute := apiRouter.Group("/vendors")
 		vendorRoute.Use(middleware.AdminAuth())
 		{
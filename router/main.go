@@ -18,6 +18,7 @@ func SetRouter(router *gin.Engine, indexPage func() []byte, docPage func() []byt
 	SetDashboardRouter(router)
 	SetRelayRouter(router)
 	SetVideoRouter(router)
+	SetMetricsRouter(router)
 	SetDocRouter(router, docPage)
 	frontendBaseUrl := os.Getenv("FRONTEND_BASE_URL")
 	if common.IsMasterNode && frontendBaseUrl != "" {
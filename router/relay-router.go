@@ -71,6 +71,7 @@ func SetRelayRouter(router *gin.Engine) {
 	relayV1Router.Use(middleware.SystemPerformanceCheck())
 	relayV1Router.Use(middleware.TokenAuth())
 	relayV1Router.Use(middleware.ModelRequestRateLimit())
+	relayV1Router.Use(middleware.UsageLimit())
 	{
 		// WebSocket 路由（统一到 Relay）
 		wsRouter := relayV1Router.Group("")
@@ -79,6 +80,38 @@ func SetRelayRouter(router *gin.Engine) {
 			controller.Relay(c, types.RelayFormatOpenAIRealtime)
 		})
 	}
+	{
+		// files / batches / responses routes（不经过 Distribute，由 controller 自行处理）
+		filesRouter := relayV1Router.Group("/files")
+		filesRouter.GET("", controller.ListFiles)
+		filesRouter.POST("", controller.UploadFile)
+		filesRouter.GET("/:id", controller.RetrieveFile)
+		filesRouter.DELETE("/:id", controller.DeleteFile)
+		filesRouter.GET("/:id/content", controller.GetFileContent)
+
+		batchesRouter := relayV1Router.Group("/batches")
+		batchesRouter.GET("", controller.ListBatches)
+		batchesRouter.POST("", controller.CreateBatch)
+		batchesRouter.GET("/:id", controller.RetrieveBatch)
+		batchesRouter.POST("/:id/cancel", controller.CancelBatch)
+
+		// 网关为桥接渠道保存的 Responses API 响应
+		responsesRouter := relayV1Router.Group("/responses")
+		responsesRouter.GET("/:id", controller.RetrieveResponse)
+		responsesRouter.DELETE("/:id", controller.DeleteResponse)
+		responsesRouter.GET("/:id/input_items", controller.ListResponseInputItems)
+
+		// fine-tuning routes，仅创建任务需要经过 Distribute 选择渠道，/fine-tunes 为旧版接口别名
+		for _, path := range []string{"/fine_tuning/jobs", "/fine-tunes"} {
+			fineTuneRouter := relayV1Router.Group(path)
+			fineTuneRouter.GET("", controller.ListFineTuneJobs)
+			fineTuneRouter.POST("", middleware.Distribute(), controller.RelayTask)
+			fineTuneRouter.GET("/:id", controller.RetrieveFineTuneJob)
+			fineTuneRouter.POST("/:id/cancel", controller.CancelFineTuneJob)
+			fineTuneRouter.GET("/:id/events", controller.ListFineTuneJobEvents)
+			fineTuneRouter.GET("/:id/checkpoints", controller.ListFineTuneJobCheckpoints)
+		}
+	}
 	{
 		//http router
 		httpRouter := relayV1Router.Group("")
@@ -152,16 +185,6 @@ func SetRelayRouter(router *gin.Engine) {
 
 		// not implemented
 		httpRouter.POST("/images/variations", controller.RelayNotImplemented)
-		httpRouter.GET("/files", controller.RelayNotImplemented)
-		httpRouter.POST("/files", controller.RelayNotImplemented)
-		httpRouter.DELETE("/files/:id", controller.RelayNotImplemented)
-		httpRouter.GET("/files/:id", controller.RelayNotImplemented)
-		httpRouter.GET("/files/:id/content", controller.RelayNotImplemented)
-		httpRouter.POST("/fine-tunes", controller.RelayNotImplemented)
-		httpRouter.GET("/fine-tunes", controller.RelayNotImplemented)
-		httpRouter.GET("/fine-tunes/:id", controller.RelayNotImplemented)
-		httpRouter.POST("/fine-tunes/:id/cancel", controller.RelayNotImplemented)
-		httpRouter.GET("/fine-tunes/:id/events", controller.RelayNotImplemented)
 		httpRouter.DELETE("/models/:model", controller.RelayNotImplemented)
 	}
 
@@ -191,6 +214,7 @@ func SetRelayRouter(router *gin.Engine) {
 	relayGeminiRouter.Use(middleware.SystemPerformanceCheck())
 	relayGeminiRouter.Use(middleware.TokenAuth())
 	relayGeminiRouter.Use(middleware.ModelRequestRateLimit())
+	relayGeminiRouter.Use(middleware.UsageLimit())
 	relayGeminiRouter.Use(middleware.Distribute())
 	{
 		// Gemini API 路径格式: /v1beta/models/{model_name}:{action}
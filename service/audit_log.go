@@ -0,0 +1,457 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// auditRequestMaxBytes 超过该长度的请求体不解析，仅记录操作本身
+	auditRequestMaxBytes  = 1 << 20
+	auditResponseMaxBytes = 64 << 10
+	auditMaskedValue      = "******"
+	auditSyslogTimeout    = 5 * time.Second
+)
+
+// 审计目标类型
+const (
+	AuditTargetOption           = "option"
+	AuditTargetChannel          = "channel"
+	AuditTargetUser             = "user"
+	AuditTargetRedemption       = "redemption"
+	AuditTargetSubscription     = "subscription"
+	AuditTargetSubscriptionPlan = "subscription_plan"
+	AuditTargetUserSubscription = "user_subscription"
+)
+
+// AuditDiffEntry 单个字段的变更，敏感字段的值已替换为掩码
+type AuditDiffEntry struct {
+	Field  string `json:"field"`
+	Before any    `json:"before"`
+	After  any    `json:"after"`
+}
+
+// AuditRecorder 记录一次管理请求的审计信息，请求处理前保存目标快照，处理后比较差异
+type AuditRecorder struct {
+	log    *model.AuditLog
+	body   any
+	before any
+	writer *ResponseCaptureWriter
+}
+
+// BeginAudit 解析请求的审计目标并保存变更前快照，group 为路由分组对应的目标类型
+func BeginAudit(c *gin.Context, group string) *AuditRecorder {
+	if !operation_setting.GetAuditLogSetting().Enabled {
+		return nil
+	}
+	recorder := &AuditRecorder{
+		log: &model.AuditLog{
+			CreatedAt: common.GetTimestamp(),
+			ActorId:   c.GetInt("id"),
+			ActorName: c.GetString("username"),
+			ActorRole: c.GetInt("role"),
+			Ip:        c.ClientIP(),
+			RequestId: c.GetString(common.RequestIdKey),
+			Action:    c.Request.Method + " " + c.FullPath(),
+		},
+	}
+	recorder.body = readAuditRequestBody(c)
+	recorder.log.TargetType, recorder.log.TargetId = resolveAuditTarget(c, group, recorder.body)
+	if recorder.log.TargetId != "" {
+		recorder.before = loadAuditSnapshot(recorder.log.TargetType, recorder.log.TargetId)
+	}
+	recorder.writer = &ResponseCaptureWriter{ResponseWriter: c.Writer, origin: c.Writer, limit: auditResponseMaxBytes}
+	c.Writer = recorder.writer
+	return recorder
+}
+
+// Finish 根据响应判断操作是否成功，保存变更后快照与差异并异步写入和转发
+func (r *AuditRecorder) Finish(c *gin.Context) {
+	if r == nil {
+		return
+	}
+	r.writer.Restore(c)
+	r.log.Success, r.log.Message = parseAuditResponse(r.writer)
+	if r.log.TargetId == "" && r.log.Success {
+		// 新建类操作在响应中返回 ID 时，补充变更后快照
+		if id := auditResponseId(r.writer); id != "" {
+			r.log.TargetId = id
+		}
+	}
+	var after any
+	if r.log.TargetId != "" && r.log.Success {
+		after = loadAuditSnapshot(r.log.TargetType, r.log.TargetId)
+	}
+	if after == nil && (r.before == nil || !r.log.Success) {
+		// 无法确定单个目标或操作失败时记录脱敏后的请求体
+		after = r.requestSnapshot()
+	}
+	diff := make([]AuditDiffEntry, 0)
+	if r.log.Success {
+		diffAuditValues("", r.before, after, false, &diff)
+	}
+	r.log.Before = marshalAuditValue(maskAuditValue(r.before, false))
+	r.log.After = marshalAuditValue(maskAuditValue(after, false))
+	r.log.Diff = marshalAuditValue(diff)
+	log := r.log
+	gopool.Go(func() {
+		if err := log.Insert(); err != nil {
+			common.SysError(fmt.Sprintf("failed to save audit log: %v", err))
+			return
+		}
+		forwardAuditLog(log, diff)
+	})
+}
+
+// requestSnapshot 以请求体作为变更后的内容。
+// 配置项的请求体为 {"key":..., "value":...}，按字段名无法识别密钥，转换为与快照相同的形式后按配置项名脱敏
+func (r *AuditRecorder) requestSnapshot() any {
+	if r.log.TargetType != AuditTargetOption {
+		return r.body
+	}
+	if r.log.TargetId == "" {
+		return nil
+	}
+	fields, _ := r.body.(map[string]any)
+	value := fields["value"]
+	if model.IsSecretOptionKey(r.log.TargetId) {
+		value = maskAuditValue(value, true)
+	}
+	return map[string]any{r.log.TargetId: value}
+}
+
+// readAuditRequestBody 读取并还原请求体，返回解析后的 JSON，非 JSON 或过大时返回 nil
+func readAuditRequestBody(c *gin.Context) any {
+	if c.Request.Body == nil || c.Request.Body == http.NoBody {
+		return nil
+	}
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, auditRequestMaxBytes+1))
+	rest := c.Request.Body
+	c.Request.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(data), rest), Closer: rest}
+	if err != nil || len(data) > auditRequestMaxBytes || !strings.Contains(c.GetHeader("Content-Type"), "json") {
+		return nil
+	}
+	var body any
+	if err = common.Unmarshal(data, &body); err != nil {
+		return nil
+	}
+	return normalizeAuditValue(body)
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// resolveAuditTarget 从路径参数或请求体中解析目标类型与 ID，批量操作等无法确定单个目标时 ID 为空
+func resolveAuditTarget(c *gin.Context, group string, body any) (string, string) {
+	fields, _ := body.(map[string]any)
+	switch group {
+	case AuditTargetOption:
+		key, _ := fields["key"].(string)
+		return AuditTargetOption, key
+	case AuditTargetSubscription:
+		path := c.FullPath()
+		switch {
+		case strings.Contains(path, "/plans"):
+			return AuditTargetSubscriptionPlan, c.Param("id")
+		case strings.Contains(path, "/user_subscriptions/"):
+			return AuditTargetUserSubscription, c.Param("id")
+		case strings.Contains(path, "/users/:id"):
+			return AuditTargetUser, c.Param("id")
+		case strings.HasSuffix(path, "/bind"):
+			return AuditTargetUser, auditFieldId(fields, "user_id")
+		}
+		return AuditTargetSubscription, ""
+	}
+	if id := c.Param("id"); id != "" {
+		return group, id
+	}
+	return group, auditFieldId(fields, "id", "channel_id")
+}
+
+func auditFieldId(fields map[string]any, names ...string) string {
+	for _, name := range names {
+		switch v := fields[name].(type) {
+		case float64:
+			if v > 0 {
+				return strconv.FormatInt(int64(v), 10)
+			}
+		case string:
+			if v != "" {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// loadAuditSnapshot 读取目标当前状态，目标不存在时返回 nil
+func loadAuditSnapshot(targetType string, targetId string) any {
+	if targetType == AuditTargetOption {
+		common.OptionMapRWMutex.RLock()
+		value, ok := common.OptionMap[targetId]
+		common.OptionMapRWMutex.RUnlock()
+		if !ok {
+			return nil
+		}
+		return normalizeAuditValue(map[string]any{targetId: value})
+	}
+	id, err := strconv.Atoi(targetId)
+	if err != nil || id <= 0 {
+		return nil
+	}
+	var snapshot any
+	switch targetType {
+	case AuditTargetChannel:
+		snapshot, err = model.GetChannelById(id, true)
+	case AuditTargetUser:
+		var user *model.User
+		if user, err = model.GetUserById(id, true); err == nil {
+			subscriptions, _ := model.GetAllUserSubscriptions(id)
+			snapshot = map[string]any{"user": user, "subscriptions": subscriptions}
+		}
+	case AuditTargetRedemption:
+		snapshot, err = model.GetRedemptionById(id)
+	case AuditTargetSubscriptionPlan:
+		snapshot, err = model.GetSubscriptionPlanById(id)
+	case AuditTargetUserSubscription:
+		snapshot, err = model.GetUserSubscriptionById(id)
+	default:
+		return nil
+	}
+	if err != nil {
+		return nil
+	}
+	data, err := common.Marshal(snapshot)
+	if err != nil {
+		return nil
+	}
+	var value any
+	if err = common.Unmarshal(data, &value); err != nil {
+		return nil
+	}
+	return normalizeAuditValue(value)
+}
+
+// normalizeAuditValue 展开以 JSON 字符串保存的字段（如倍率、模型映射），使差异精确到子字段
+func normalizeAuditValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = normalizeAuditValue(item)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = normalizeAuditValue(item)
+		}
+		return v
+	case string:
+		trimmed := strings.TrimSpace(v)
+		if strings.HasPrefix(trimmed, "{") && strings.HasSuffix(trimmed, "}") {
+			var parsed map[string]any
+			if err := common.UnmarshalJsonStr(trimmed, &parsed); err == nil {
+				return normalizeAuditValue(parsed)
+			}
+		}
+		return v
+	}
+	return value
+}
+
+// isSensitiveAuditField 判断字段是否保存密钥、密码等敏感信息
+func isSensitiveAuditField(name string) bool {
+	lower := strings.ToLower(name)
+	if idx := strings.LastIndex(lower, "."); idx >= 0 {
+		lower = lower[idx+1:]
+	}
+	for _, word := range []string{"secret", "password", "credential", "private"} {
+		if strings.Contains(lower, word) {
+			return true
+		}
+	}
+	for _, suffix := range []string{"key", "keys", "token"} {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskAuditValue 将敏感字段的字符串、数组与对象值替换为掩码，数字与布尔值保留
+func maskAuditValue(value any, sensitive bool) any {
+	switch v := value.(type) {
+	case map[string]any:
+		if sensitive && len(v) > 0 {
+			return auditMaskedValue
+		}
+		masked := make(map[string]any, len(v))
+		for key, item := range v {
+			masked[key] = maskAuditValue(item, isSensitiveAuditField(key))
+		}
+		return masked
+	case []any:
+		if sensitive && len(v) > 0 {
+			return auditMaskedValue
+		}
+		masked := make([]any, len(v))
+		for i, item := range v {
+			masked[i] = maskAuditValue(item, false)
+		}
+		return masked
+	case string:
+		if sensitive && v != "" {
+			return auditMaskedValue
+		}
+	}
+	return value
+}
+
+// diffAuditValues 递归比较对象，数组整体比较，结果按字段路径排序
+func diffAuditValues(path string, before any, after any, sensitive bool, diff *[]AuditDiffEntry) {
+	beforeMap, beforeOk := before.(map[string]any)
+	afterMap, afterOk := after.(map[string]any)
+	if (beforeOk || before == nil) && (afterOk || after == nil) && (beforeOk || afterOk) && !sensitive {
+		keys := make([]string, 0, len(beforeMap)+len(afterMap))
+		for key := range beforeMap {
+			keys = append(keys, key)
+		}
+		for key := range afterMap {
+			if _, ok := beforeMap[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			field := key
+			if path != "" {
+				field = path + "." + key
+			}
+			diffAuditValues(field, beforeMap[key], afterMap[key], isSensitiveAuditField(key), diff)
+		}
+		return
+	}
+	if reflect.DeepEqual(before, after) {
+		return
+	}
+	*diff = append(*diff, AuditDiffEntry{
+		Field:  path,
+		Before: maskAuditValue(before, sensitive),
+		After:  maskAuditValue(after, sensitive),
+	})
+}
+
+func marshalAuditValue(value any) string {
+	if value == nil {
+		return ""
+	}
+	data, err := common.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// parseAuditResponse 按响应中的 success 字段判断操作结果，非 JSON 响应按状态码判断
+func parseAuditResponse(writer *ResponseCaptureWriter) (bool, string) {
+	var resp struct {
+		Success *bool  `json:"success"`
+		Message string `json:"message"`
+	}
+	if !writer.overflow && common.Unmarshal(writer.buf.Bytes(), &resp) == nil && resp.Success != nil {
+		return *resp.Success, resp.Message
+	}
+	return writer.Status() < 400, ""
+}
+
+func auditResponseId(writer *ResponseCaptureWriter) string {
+	var resp struct {
+		Data map[string]any `json:"data"`
+	}
+	if writer.overflow || common.Unmarshal(writer.buf.Bytes(), &resp) != nil {
+		return ""
+	}
+	return auditFieldId(resp.Data, "id")
+}
+
+// forwardAuditLog 按配置将审计记录推送到 Webhook 与 syslog
+func forwardAuditLog(log *model.AuditLog, diff []AuditDiffEntry) {
+	setting := operation_setting.GetAuditLogSetting()
+	if setting.WebhookEnabled {
+		PublishWebhookEvent(model.WebhookEventAuditLogged, 0, auditEventData(log, diff))
+	}
+	if setting.SyslogAddress != "" {
+		payload, err := common.Marshal(auditEventData(log, diff))
+		if err == nil {
+			err = sendAuditSyslog(setting.SyslogAddress, setting.SyslogTag, payload)
+		}
+		if err != nil {
+			common.SysError(fmt.Sprintf("failed to forward audit log %d to syslog: %v", log.Id, err))
+		}
+	}
+}
+
+func auditEventData(log *model.AuditLog, diff []AuditDiffEntry) map[string]interface{} {
+	return map[string]interface{}{
+		"id":          log.Id,
+		"created_at":  log.CreatedAt,
+		"actor_id":    log.ActorId,
+		"actor_name":  log.ActorName,
+		"ip":          log.Ip,
+		"request_id":  log.RequestId,
+		"action":      log.Action,
+		"target_type": log.TargetType,
+		"target_id":   log.TargetId,
+		"success":     log.Success,
+		"diff":        diff,
+	}
+}
+
+// sendAuditSyslog 以 RFC 5424 格式发送一条 syslog 消息，TCP 连接使用 RFC 6587 的长度前缀分帧
+func sendAuditSyslog(address string, tag string, payload []byte) error {
+	network, host := "udp", address
+	if u, err := url.Parse(address); err == nil && u.Host != "" {
+		network, host = u.Scheme, u.Host
+	}
+	if network != "udp" && network != "tcp" {
+		return fmt.Errorf("unsupported syslog network %q", network)
+	}
+	conn, err := net.DialTimeout(network, host, auditSyslogTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(auditSyslogTimeout))
+	if tag == "" {
+		tag = "new-api"
+	}
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+	// facility local0，severity notice
+	msg := fmt.Sprintf("<%d>1 %s %s %s %d audit - %s", 16*8+5, time.Now().Format(time.RFC3339), hostname, tag, os.Getpid(), payload)
+	if network == "tcp" {
+		msg = fmt.Sprintf("%d %s", len(msg), msg)
+	}
+	_, err = conn.Write([]byte(msg))
+	return err
+}
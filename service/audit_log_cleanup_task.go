@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+const (
+	auditLogCleanupTickInterval = 6 * time.Hour
+	auditLogCleanupBatchSize    = 1000
+)
+
+var (
+	auditLogCleanupOnce    sync.Once
+	auditLogCleanupRunning atomic.Bool
+)
+
+// StartAuditLogCleanupTask 定期清理超过保留天数的审计记录
+func StartAuditLogCleanupTask() {
+	auditLogCleanupOnce.Do(func() {
+		if !common.IsMasterNode {
+			return
+		}
+		gopool.Go(func() {
+			logger.LogInfo(context.Background(), fmt.Sprintf("audit log cleanup task started: tick=%s", auditLogCleanupTickInterval))
+			ticker := time.NewTicker(auditLogCleanupTickInterval)
+			defer ticker.Stop()
+
+			runAuditLogCleanupOnce()
+			for range ticker.C {
+				runAuditLogCleanupOnce()
+			}
+		})
+	})
+}
+
+func runAuditLogCleanupOnce() {
+	retentionDays := operation_setting.GetAuditLogSetting().RetentionDays
+	if retentionDays <= 0 {
+		return
+	}
+	if !auditLogCleanupRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer auditLogCleanupRunning.Store(false)
+
+	ctx := context.Background()
+	target := time.Now().AddDate(0, 0, -retentionDays).Unix()
+	deleted, err := model.DeleteOldAuditLogs(ctx, target, auditLogCleanupBatchSize)
+	if err != nil {
+		logger.LogWarn(ctx, fmt.Sprintf("audit log cleanup task failed: %v", err))
+		return
+	}
+	if common.DebugEnabled && deleted > 0 {
+		logger.LogDebug(ctx, "audit log cleanup: deleted_count=%d", deleted)
+	}
+}
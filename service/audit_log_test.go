@@ -0,0 +1,85 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffAuditValues(t *testing.T) {
+	before := normalizeAuditValue(map[string]any{
+		"name":          "openai",
+		"key":           "sk-old",
+		"priority":      float64(0),
+		"model_mapping": `{"gpt-4":"gpt-4o"}`,
+		"setting":       map[string]any{"proxy": "", "api_token": "t1"},
+	})
+	after := normalizeAuditValue(map[string]any{
+		"name":          "openai",
+		"key":           "sk-new",
+		"priority":      float64(5),
+		"model_mapping": `{"gpt-4":"gpt-4.1","o3":"o3-mini"}`,
+		"setting":       map[string]any{"proxy": "", "api_token": "t2"},
+	})
+	diff := make([]AuditDiffEntry, 0)
+	diffAuditValues("", before, after, false, &diff)
+	require.Equal(t, []AuditDiffEntry{
+		{Field: "key", Before: auditMaskedValue, After: auditMaskedValue},
+		{Field: "model_mapping.gpt-4", Before: "gpt-4o", After: "gpt-4.1"},
+		{Field: "model_mapping.o3", Before: nil, After: "o3-mini"},
+		{Field: "priority", Before: float64(0), After: float64(5)},
+		{Field: "setting.api_token", Before: auditMaskedValue, After: auditMaskedValue},
+	}, diff)
+
+	// 快照中的敏感字段同样需要脱敏，空值与数字保留
+	masked := maskAuditValue(map[string]any{"password": "secret", "access_token": "", "max_tokens": float64(10), "keys": []any{"a"}}, false)
+	require.Equal(t, map[string]any{"password": auditMaskedValue, "access_token": "", "max_tokens": float64(10), "keys": auditMaskedValue}, masked)
+}
+
+func TestAuditFailedSecretOptionUpdate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	common.OptionMapRWMutex.Lock()
+	if common.OptionMap == nil {
+		common.OptionMap = make(map[string]string)
+	}
+	common.OptionMap["StripeApiSecret"] = "sk_live_old"
+	common.OptionMapRWMutex.Unlock()
+	t.Cleanup(func() {
+		common.OptionMapRWMutex.Lock()
+		delete(common.OptionMap, "StripeApiSecret")
+		common.OptionMapRWMutex.Unlock()
+	})
+
+	var recorder *AuditRecorder
+	router := gin.New()
+	router.PUT("/api/option/", func(c *gin.Context) {
+		recorder = BeginAudit(c, AuditTargetOption)
+		c.JSON(http.StatusOK, gin.H{"success": false, "message": "invalid value"})
+		recorder.Finish(c)
+	})
+	audit := func(key string) *AuditRecorder {
+		body := `{"key":"` + key + `","value":"sk_live_new"}`
+		req := httptest.NewRequest(http.MethodPut, "/api/option/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		require.NotNil(t, recorder)
+		require.False(t, recorder.log.Success)
+		return recorder
+	}
+
+	// 更新失败时记录的请求体按配置项名脱敏
+	r := audit("StripeApiSecret")
+	require.NotContains(t, r.log.Before, "sk_live")
+	require.NotContains(t, r.log.After, "sk_live")
+	require.JSONEq(t, `{"StripeApiSecret":"******"}`, r.log.After)
+
+	// 配置项不存在（无变更前快照）时同样脱敏
+	r = audit("NewWebhookSecret")
+	require.Empty(t, r.log.Before)
+	require.JSONEq(t, `{"NewWebhookSecret":"******"}`, r.log.After)
+}
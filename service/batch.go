@@ -0,0 +1,547 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/tidwall/gjson"
+)
+
+// BatchRequestHandler 将批处理中的单个请求交给 HTTP 路由执行（完整经过鉴权、分发、relay、计费流程）。
+// 由 main 在路由初始化后注入，避免 service -> router 循环依赖
+var BatchRequestHandler func(w http.ResponseWriter, req *http.Request)
+
+const (
+	batchTickInterval  = 5 * time.Second
+	batchMaxLineBytes  = 16 << 20
+	batchMaxRetries    = 3
+	BatchOutputPurpose = "batch_output"
+)
+
+var (
+	// batchStatusCheckInterval 执行过程中检查取消与过期的间隔
+	batchStatusCheckInterval = 3 * time.Second
+
+	batchWorkerOnce sync.Once
+	batchRunning    sync.Map // batch.Id -> struct{}
+	batchRunCount   atomic.Int32
+	batchSemOnce    sync.Once
+	batchSem        chan struct{}
+)
+
+// StartBatchWorker 启动批处理后台任务，仅在主节点运行
+func StartBatchWorker() {
+	batchWorkerOnce.Do(func() {
+		if !common.IsMasterNode {
+			return
+		}
+		gopool.Go(func() {
+			logger.LogInfo(context.Background(), fmt.Sprintf("batch worker started: tick=%s", batchTickInterval))
+			recoverInterruptedBatches()
+			ticker := time.NewTicker(batchTickInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				dispatchPendingBatches()
+			}
+		})
+	})
+}
+
+func getBatchSemaphore() chan struct{} {
+	batchSemOnce.Do(func() {
+		workers := operation_setting.GetBatchSetting().WorkerCount
+		if workers <= 0 {
+			workers = 1
+		}
+		batchSem = make(chan struct{}, workers)
+	})
+	return batchSem
+}
+
+// recoverInterruptedBatches 服务重启时，执行中的批次无法恢复进度（已执行的请求已计费），直接标记为失败
+func recoverInterruptedBatches() {
+	batches, err := model.GetPendingBatches(1000)
+	if err != nil {
+		logger.LogWarn(context.Background(), fmt.Sprintf("batch recover failed: %v", err))
+		return
+	}
+	now := common.GetTimestamp()
+	for _, batch := range batches {
+		switch batch.Status {
+		case model.BatchStatusInProgress:
+			_, _ = model.UpdateBatchStatusFrom(batch.Id, batch.Status, map[string]any{
+				"status":    model.BatchStatusFailed,
+				"failed_at": now,
+				"errors":    batchErrorsJson("batch_interrupted", "Batch was interrupted by a server restart", nil),
+			})
+		case model.BatchStatusCancelling:
+			_, _ = model.UpdateBatchStatusFrom(batch.Id, batch.Status, map[string]any{
+				"status":       model.BatchStatusCancelled,
+				"cancelled_at": now,
+			})
+		}
+	}
+}
+
+func dispatchPendingBatches() {
+	setting := operation_setting.GetBatchSetting()
+	if !setting.Enabled || BatchRequestHandler == nil {
+		return
+	}
+	maxRunning := max(setting.MaxRunningBatches, 1)
+	if int(batchRunCount.Load()) >= maxRunning {
+		return
+	}
+	batches, err := model.GetPendingBatches(maxRunning * 4)
+	if err != nil {
+		logger.LogWarn(context.Background(), fmt.Sprintf("batch dispatch failed: %v", err))
+		return
+	}
+	for _, batch := range batches {
+		if _, running := batchRunning.Load(batch.Id); running {
+			continue
+		}
+		if batch.Status == model.BatchStatusCancelling {
+			// 未在执行中的取消请求直接完成取消
+			_, _ = model.UpdateBatchStatusFrom(batch.Id, batch.Status, map[string]any{
+				"status":       model.BatchStatusCancelled,
+				"cancelled_at": common.GetTimestamp(),
+			})
+			continue
+		}
+		if batch.Status != model.BatchStatusValidating {
+			continue
+		}
+		if int(batchRunCount.Load()) >= maxRunning {
+			return
+		}
+		batchRunning.Store(batch.Id, struct{}{})
+		batchRunCount.Add(1)
+		b := batch
+		gopool.Go(func() {
+			defer func() {
+				if r := recover(); r != nil {
+					logger.LogError(context.Background(), fmt.Sprintf("batch %s panic: %v", b.BatchId, r))
+				}
+				batchRunning.Delete(b.Id)
+				batchRunCount.Add(-1)
+			}()
+			runBatch(b)
+		})
+	}
+}
+
+func batchErrorsJson(code string, message string, line *int) string {
+	errs := dto.OpenAIBatchErrors{
+		Object: "list",
+		Data:   []dto.OpenAIBatchError{{Code: code, Message: message, Line: line}},
+	}
+	data, _ := common.Marshal(errs)
+	return string(data)
+}
+
+func failBatch(batch *model.Batch, code string, message string, line *int) {
+	_, err := model.UpdateBatchStatusFrom(batch.Id, batch.Status, map[string]any{
+		"status":    model.BatchStatusFailed,
+		"failed_at": common.GetTimestamp(),
+		"errors":    batchErrorsJson(code, message, line),
+	})
+	if err != nil {
+		logger.LogWarn(context.Background(), fmt.Sprintf("batch %s update failed: %v", batch.BatchId, err))
+	}
+}
+
+// loadBatchInput 读取并校验输入文件
+func loadBatchInput(ctx context.Context, batch *model.Batch) ([]dto.OpenAIBatchRequestLine, string, string, *int) {
+	file, err := model.GetUserFileById(batch.UserId, batch.InputFileId)
+	if err != nil {
+		return nil, "invalid_file", "Input file not found", nil
+	}
+	reader, _, err := OpenFileContent(ctx, file)
+	if err != nil {
+		return nil, "invalid_file", "Failed to read input file", nil
+	}
+	defer reader.Close()
+
+	maxRequests := operation_setting.GetBatchSetting().MaxRequestsPerBatch
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), batchMaxLineBytes)
+	lines := make([]dto.OpenAIBatchRequestLine, 0)
+	customIds := make(map[string]struct{})
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		n := lineNo
+		var line dto.OpenAIBatchRequestLine
+		if err := common.Unmarshal(raw, &line); err != nil {
+			return nil, "invalid_json_line", "This line is not parseable as valid JSON.", &n
+		}
+		if line.CustomId == "" {
+			return nil, "missing_required_parameter", "Missing required parameter: 'custom_id'.", &n
+		}
+		if _, exists := customIds[line.CustomId]; exists {
+			return nil, "duplicate_custom_id", fmt.Sprintf("The custom_id '%s' is duplicated.", line.CustomId), &n
+		}
+		customIds[line.CustomId] = struct{}{}
+		if line.Method != http.MethodPost {
+			return nil, "invalid_method", "Only POST method is supported.", &n
+		}
+		if line.Url != batch.Endpoint {
+			return nil, "mismatched_endpoint", fmt.Sprintf("The url '%s' does not match the batch endpoint '%s'.", line.Url, batch.Endpoint), &n
+		}
+		if len(line.Body) == 0 || !gjson.ValidBytes(line.Body) || !gjson.ParseBytes(line.Body).IsObject() {
+			return nil, "invalid_request", "The request body must be a JSON object.", &n
+		}
+		if gjson.GetBytes(line.Body, "stream").Bool() {
+			return nil, "invalid_request", "Streaming is not supported in batch requests.", &n
+		}
+		lines = append(lines, line)
+		if maxRequests > 0 && len(lines) > maxRequests {
+			return nil, "too_many_requests", fmt.Sprintf("The batch contains more than %d requests.", maxRequests), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, "invalid_file", "Failed to read input file: " + err.Error(), nil
+	}
+	if len(lines) == 0 {
+		return nil, "empty_file", "The input file contains no requests.", nil
+	}
+	return lines, "", "", nil
+}
+
+type batchResultWriter struct {
+	mu        sync.Mutex
+	output    *os.File
+	errors    *os.File
+	outBytes  int64
+	errBytes  int64
+	completed int
+	failed    int
+	reqIds    []string
+}
+
+func (w *batchResultWriter) write(result dto.OpenAIBatchResultLine, success bool) {
+	data, err := common.Marshal(result)
+	if err != nil {
+		return
+	}
+	data = append(data, '\n')
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if result.Response != nil && result.Response.RequestId != "" {
+		w.reqIds = append(w.reqIds, result.Response.RequestId)
+	}
+	if success {
+		n, _ := w.output.Write(data)
+		w.outBytes += int64(n)
+		w.completed++
+	} else {
+		n, _ := w.errors.Write(data)
+		w.errBytes += int64(n)
+		w.failed++
+	}
+}
+
+func (w *batchResultWriter) counts() (int, int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.completed, w.failed
+}
+
+func runBatch(batch *model.Batch) {
+	ctx := context.Background()
+	lines, code, message, line := loadBatchInput(ctx, batch)
+	if code != "" {
+		failBatch(batch, code, message, line)
+		return
+	}
+	token, err := model.GetTokenById(batch.TokenId)
+	if err != nil || token.UserId != batch.UserId {
+		failBatch(batch, "invalid_token", "The token used to create this batch is no longer available", nil)
+		return
+	}
+
+	now := common.GetTimestamp()
+	ok, err := model.UpdateBatchStatusFrom(batch.Id, model.BatchStatusValidating, map[string]any{
+		"status":         model.BatchStatusInProgress,
+		"in_progress_at": now,
+		"total_count":    len(lines),
+	})
+	if err != nil || !ok {
+		// 已被取消
+		return
+	}
+	batch.Status = model.BatchStatusInProgress
+	batch.InProgressAt = now
+	batch.TotalCount = len(lines)
+
+	writer := &batchResultWriter{}
+	writer.output, err = os.CreateTemp("", "new-api-batch-output-*")
+	if err != nil {
+		failBatch(batch, "server_error", "Failed to create output file", nil)
+		return
+	}
+	defer os.Remove(writer.output.Name())
+	defer writer.output.Close()
+	writer.errors, err = os.CreateTemp("", "new-api-batch-error-*")
+	if err != nil {
+		failBatch(batch, "server_error", "Failed to create error file", nil)
+		return
+	}
+	defer os.Remove(writer.errors.Name())
+	defer writer.errors.Close()
+
+	finalStatus := executeBatchLines(ctx, batch, token.Key, lines, writer)
+
+	// 写入结果文件
+	finalizing := map[string]any{
+		"status":        model.BatchStatusFinalizing,
+		"finalizing_at": common.GetTimestamp(),
+	}
+	if ok, _ := model.UpdateBatchStatusFrom(batch.Id, batch.Status, finalizing); !ok {
+		// 执行结束后才收到取消请求，结果照常保存
+		_, _ = model.UpdateBatchStatusFrom(batch.Id, model.BatchStatusCancelling, finalizing)
+	}
+	batch.Status = model.BatchStatusFinalizing
+
+	updates := map[string]any{"status": finalStatus}
+	completed, failed := writer.counts()
+	updates["completed_count"] = completed
+	updates["failed_count"] = failed
+	if writer.outBytes > 0 {
+		if f, err := saveBatchResultFile(ctx, batch, writer.output, writer.outBytes, "output"); err == nil {
+			updates["output_file_id"] = f.FileId
+		} else {
+			logger.LogError(ctx, fmt.Sprintf("batch %s save output file failed: %v", batch.BatchId, err))
+		}
+	}
+	if writer.errBytes > 0 {
+		if f, err := saveBatchResultFile(ctx, batch, writer.errors, writer.errBytes, "error"); err == nil {
+			updates["error_file_id"] = f.FileId
+		} else {
+			logger.LogError(ctx, fmt.Sprintf("batch %s save error file failed: %v", batch.BatchId, err))
+		}
+	}
+	if quota, err := model.SumQuotaByRequestIds(writer.reqIds); err == nil {
+		updates["quota"] = quota
+	}
+	finishedAt := common.GetTimestamp()
+	switch finalStatus {
+	case model.BatchStatusCompleted:
+		updates["completed_at"] = finishedAt
+	case model.BatchStatusCancelled:
+		updates["cancelled_at"] = finishedAt
+	case model.BatchStatusExpired:
+		updates["expired_at"] = finishedAt
+	}
+	if _, err := model.UpdateBatchStatusFrom(batch.Id, model.BatchStatusFinalizing, updates); err != nil {
+		logger.LogError(ctx, fmt.Sprintf("batch %s finalize failed: %v", batch.BatchId, err))
+	}
+}
+
+func saveBatchResultFile(ctx context.Context, batch *model.Batch, f *os.File, size int64, kind string) (*model.File, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	filename := fmt.Sprintf("%s_%s.jsonl", batch.BatchId, kind)
+	return SaveGeneratedFile(ctx, batch.UserId, batch.TokenId, filename, BatchOutputPurpose, "application/jsonl", f, size)
+}
+
+// executeBatchLines 通过共享的 worker 池执行所有请求，返回批次最终状态
+func executeBatchLines(ctx context.Context, batch *model.Batch, tokenKey string, lines []dto.OpenAIBatchRequestLine, writer *batchResultWriter) string {
+	sem := getBatchSemaphore()
+	var wg sync.WaitGroup
+	finalStatus := model.BatchStatusCompleted
+	lastCheck := time.Now()
+	lastProgress := time.Now()
+
+	for _, line := range lines {
+		if time.Since(lastCheck) >= batchStatusCheckInterval {
+			lastCheck = time.Now()
+			status, err := model.GetBatchStatus(batch.Id)
+			if err == nil && status == model.BatchStatusCancelling {
+				batch.Status = model.BatchStatusCancelling
+				finalStatus = model.BatchStatusCancelled
+				break
+			}
+			if batch.ExpiresAt > 0 && common.GetTimestamp() >= batch.ExpiresAt {
+				finalStatus = model.BatchStatusExpired
+				break
+			}
+			if time.Since(lastProgress) >= 10*time.Second {
+				lastProgress = time.Now()
+				completed, failed := writer.counts()
+				_ = model.UpdateBatchProgress(batch.Id, completed, failed)
+			}
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		l := line
+		gopool.Go(func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			result, success := executeBatchLine(ctx, batch, tokenKey, l)
+			writer.write(result, success)
+		})
+	}
+	wg.Wait()
+
+	if finalStatus != model.BatchStatusCompleted {
+		// 未执行的请求写入错误文件
+		completed, failed := writer.counts()
+		for _, line := range lines[min(completed+failed, len(lines)):] {
+			code := "batch_cancelled"
+			message := "This request was not executed because the batch was cancelled."
+			if finalStatus == model.BatchStatusExpired {
+				code = "batch_expired"
+				message = "This request could not be executed before the completion window expired."
+			}
+			writer.write(dto.OpenAIBatchResultLine{
+				Id:       batchRequestId(),
+				CustomId: line.CustomId,
+				Error:    &dto.OpenAIBatchError{Code: code, Message: message},
+			}, false)
+		}
+	}
+	return finalStatus
+}
+
+func batchRequestId() string {
+	key, _ := common.GenerateRandomCharsKey(24)
+	return "batch_req_" + key
+}
+
+func executeBatchLine(ctx context.Context, batch *model.Batch, tokenKey string, line dto.OpenAIBatchRequestLine) (dto.OpenAIBatchResultLine, bool) {
+	result := dto.OpenAIBatchResultLine{
+		Id:       batchRequestId(),
+		CustomId: line.CustomId,
+	}
+	var recorder *httptest.ResponseRecorder
+	for attempt := 0; attempt < batchMaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(1<<attempt) * time.Second)
+		}
+		req, err := http.NewRequestWithContext(relaycommon.WithBatchId(ctx, batch.BatchId), http.MethodPost, line.Url, bytes.NewReader(line.Body))
+		if err != nil {
+			result.Error = &dto.OpenAIBatchError{Code: "invalid_request", Message: err.Error()}
+			return result, false
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer sk-"+tokenKey)
+		clientIp := batch.ClientIp
+		if clientIp == "" {
+			clientIp = "127.0.0.1"
+		}
+		req.RemoteAddr = net.JoinHostPort(clientIp, "0")
+		recorder = httptest.NewRecorder()
+		BatchRequestHandler(recorder, req)
+		// 仅在触发限流时重试，其余错误直接写入错误文件
+		if recorder.Code != http.StatusTooManyRequests {
+			break
+		}
+	}
+
+	body := recorder.Body.Bytes()
+	if !gjson.ValidBytes(body) {
+		body, _ = common.Marshal(map[string]any{
+			"error": map[string]any{"message": string(body), "type": "new_api_error"},
+		})
+	}
+	result.Response = &dto.OpenAIBatchResponse{
+		StatusCode: recorder.Code,
+		RequestId:  recorder.Header().Get(common.RequestIdKey),
+		Body:       body,
+	}
+	return result, recorder.Code/100 == 2
+}
+
+// CreateBatch 校验并创建批次，实际执行由后台 worker 完成
+func CreateBatch(userId int, tokenId int, clientIp string, req *dto.OpenAIBatchCreateRequest) (*model.Batch, error) {
+	setting := operation_setting.GetBatchSetting()
+	if !slices.Contains(setting.AllowedEndpoints, req.Endpoint) {
+		return nil, fmt.Errorf("invalid endpoint: '%s'", req.Endpoint)
+	}
+	if req.CompletionWindow != "24h" {
+		return nil, fmt.Errorf("invalid completion_window: '%s', only '24h' is supported", req.CompletionWindow)
+	}
+	file, err := model.GetUserFileById(userId, req.InputFileId)
+	if err != nil {
+		return nil, fmt.Errorf("no such file: %s", req.InputFileId)
+	}
+	if file.Purpose != "batch" {
+		return nil, errors.New("the input file must be uploaded with purpose 'batch'")
+	}
+	metadata := ""
+	if len(req.Metadata) > 0 {
+		data, err := common.Marshal(req.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		metadata = string(data)
+	}
+	now := common.GetTimestamp()
+	batch := &model.Batch{
+		BatchId:          model.GenerateBatchId(),
+		UserId:           userId,
+		TokenId:          tokenId,
+		ClientIp:         clientIp,
+		Endpoint:         req.Endpoint,
+		InputFileId:      req.InputFileId,
+		CompletionWindow: req.CompletionWindow,
+		Status:           model.BatchStatusValidating,
+		Metadata:         metadata,
+		CreatedAt:        now,
+		ExpiresAt:        now + 24*3600,
+	}
+	if err := batch.Insert(); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// CancelBatch 请求取消批次，执行中的批次由 worker 在下一次状态检查时停止
+func CancelBatch(batch *model.Batch) error {
+	if batch.Status != model.BatchStatusValidating && batch.Status != model.BatchStatusInProgress {
+		return fmt.Errorf("cannot cancel a batch with status '%s'", batch.Status)
+	}
+	now := common.GetTimestamp()
+	ok, err := model.UpdateBatchStatusFrom(batch.Id, batch.Status, map[string]any{
+		"status":        model.BatchStatusCancelling,
+		"cancelling_at": now,
+	})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("batch status has changed, please retry")
+	}
+	batch.Status = model.BatchStatusCancelling
+	batch.CancellingAt = now
+	return nil
+}
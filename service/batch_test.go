@@ -0,0 +1,232 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+const batchTestEndpoint = "/v1/chat/completions"
+
+func setupBatchTest(t *testing.T) {
+	t.Helper()
+	truncate(t)
+	fileSetting := operation_setting.GetFileSetting()
+	originalFile := *fileSetting
+	fileSetting.StorageType = operation_setting.FileStorageLocal
+	fileSetting.LocalPath = t.TempDir()
+	originalHandler := BatchRequestHandler
+	originalInterval := batchStatusCheckInterval
+	// 每个请求执行前都检查取消状态，单个 worker 保证请求按顺序执行
+	batchStatusCheckInterval = 0
+	getBatchSemaphore()
+	originalSem := batchSem
+	batchSem = make(chan struct{}, 1)
+	t.Cleanup(func() {
+		*fileSetting = originalFile
+		BatchRequestHandler = originalHandler
+		batchStatusCheckInterval = originalInterval
+		batchSem = originalSem
+		model.DB.Exec("DELETE FROM files")
+		model.DB.Exec("DELETE FROM batches")
+	})
+}
+
+func batchTestLine(customId string, body string) string {
+	return fmt.Sprintf(`{"custom_id":%q,"method":"POST","url":%q,"body":%s}`, customId, batchTestEndpoint, body)
+}
+
+func createTestBatch(t *testing.T, userId int, tokenId int, input string) *model.Batch {
+	t.Helper()
+	file, err := SaveGeneratedFile(context.Background(), userId, tokenId, "input.jsonl", "batch", "application/jsonl", strings.NewReader(input), int64(len(input)))
+	require.NoError(t, err)
+	batch, err := CreateBatch(userId, tokenId, "", &dto.OpenAIBatchCreateRequest{
+		InputFileId:      file.FileId,
+		Endpoint:         batchTestEndpoint,
+		CompletionWindow: "24h",
+	})
+	require.NoError(t, err)
+	return batch
+}
+
+func readBatchResultFile(t *testing.T, userId int, fileId string) map[string]dto.OpenAIBatchResultLine {
+	t.Helper()
+	require.NotEmpty(t, fileId)
+	file, err := model.GetUserFileById(userId, fileId)
+	require.NoError(t, err)
+	require.Equal(t, BatchOutputPurpose, file.Purpose)
+	reader, _, err := OpenFileContent(context.Background(), file)
+	require.NoError(t, err)
+	defer reader.Close()
+	results := make(map[string]dto.OpenAIBatchResultLine)
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		var line dto.OpenAIBatchResultLine
+		require.NoError(t, common.Unmarshal(scanner.Bytes(), &line))
+		results[line.CustomId] = line
+	}
+	require.NoError(t, scanner.Err())
+	return results
+}
+
+func TestLoadBatchInputErrors(t *testing.T) {
+	setupBatchTest(t)
+	seedUser(t, 50, 0)
+
+	valid := batchTestLine("a", `{"model":"gpt-4o-mini"}`)
+	cases := []struct {
+		input string
+		code  string
+		line  int
+	}{
+		{valid + "\n{not json}", "invalid_json_line", 2},
+		{batchTestLine("", `{}`), "missing_required_parameter", 1},
+		{valid + "\n\n" + valid, "duplicate_custom_id", 3},
+		{`{"custom_id":"a","method":"GET","url":"/v1/chat/completions","body":{}}`, "invalid_method", 1},
+		{`{"custom_id":"a","method":"POST","url":"/v1/embeddings","body":{}}`, "mismatched_endpoint", 1},
+		{batchTestLine("a", `[1]`), "invalid_request", 1},
+		{batchTestLine("a", `{"stream":true}`), "invalid_request", 1},
+		{"\n\n", "empty_file", 0},
+	}
+	for _, tc := range cases {
+		batch := createTestBatch(t, 50, 0, tc.input)
+		_, code, _, line := loadBatchInput(context.Background(), batch)
+		require.Equal(t, tc.code, code, tc.input)
+		if tc.line == 0 {
+			require.Nil(t, line)
+		} else {
+			require.NotNil(t, line)
+			require.Equal(t, tc.line, *line)
+		}
+	}
+
+	lines, code, _, _ := loadBatchInput(context.Background(), createTestBatch(t, 50, 0, valid+"\n"+batchTestLine("b", `{}`)+"\n"))
+	require.Empty(t, code)
+	require.Len(t, lines, 2)
+
+	// 校验失败时批次标记为 failed 并记录出错的行号
+	batch := createTestBatch(t, 50, 0, valid+"\n{not json}")
+	runBatch(batch)
+	reloaded, err := model.GetUserBatchById(50, batch.BatchId)
+	require.NoError(t, err)
+	require.Equal(t, model.BatchStatusFailed, reloaded.Status)
+	require.Equal(t, "invalid_json_line", gjson.Get(reloaded.Errors, "data.0.code").String())
+	require.EqualValues(t, 2, gjson.Get(reloaded.Errors, "data.0.line").Int())
+}
+
+// batchTestHandler 模拟路由执行单个请求：按批次倍率计费并写入消费日志
+func batchTestHandler(t *testing.T, batchId string, tokenKey string, onRequest func(n int64)) func(w http.ResponseWriter, req *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		require.Equal(t, batchId, relaycommon.GetBatchId(req.Context()))
+		require.Equal(t, "Bearer sk-"+tokenKey, req.Header.Get("Authorization"))
+		body, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		n := gjson.GetBytes(body, "n").Int()
+		if onRequest != nil {
+			onRequest(n)
+		}
+		if gjson.GetBytes(body, "fail").Bool() {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"message":"bad request"}}`))
+			return
+		}
+		requestId := fmt.Sprintf("req-%s-%d", batchId, n)
+		quota := int(100 * operation_setting.GetBatchDiscountRatio())
+		require.NoError(t, model.LOG_DB.Create(&model.Log{Type: model.LogTypeConsume, RequestId: requestId, Quota: quota}).Error)
+		w.Header().Set(common.RequestIdKey, requestId)
+		_, _ = w.Write([]byte(fmt.Sprintf(`{"id":"chatcmpl-%d","object":"chat.completion"}`, n)))
+	}
+}
+
+func TestRunBatchWritesOutputAndErrorFiles(t *testing.T) {
+	setupBatchTest(t)
+	seedUser(t, 51, 0)
+	seedToken(t, 51, 51, "batch-token", 1000)
+
+	input := strings.Join([]string{
+		batchTestLine("ok-1", `{"n":1}`),
+		batchTestLine("bad", `{"n":2,"fail":true}`),
+		batchTestLine("ok-2", `{"n":3}`),
+	}, "\n")
+	batch := createTestBatch(t, 51, 51, input)
+	BatchRequestHandler = batchTestHandler(t, batch.BatchId, "batch-token", nil)
+
+	runBatch(batch)
+
+	reloaded, err := model.GetUserBatchById(51, batch.BatchId)
+	require.NoError(t, err)
+	require.Equal(t, model.BatchStatusCompleted, reloaded.Status)
+	require.Equal(t, 3, reloaded.TotalCount)
+	require.Equal(t, 2, reloaded.CompletedCount)
+	require.Equal(t, 1, reloaded.FailedCount)
+	require.NotZero(t, reloaded.CompletedAt)
+	// 批次额度为各成功请求按批处理倍率计费后的合计
+	require.Equal(t, 2*int(100*operation_setting.GetBatchDiscountRatio()), reloaded.Quota)
+
+	output := readBatchResultFile(t, 51, reloaded.OutputFileId)
+	require.Len(t, output, 2)
+	require.Equal(t, http.StatusOK, output["ok-1"].Response.StatusCode)
+	require.Equal(t, "req-"+batch.BatchId+"-1", output["ok-1"].Response.RequestId)
+	require.Equal(t, "chatcmpl-3", gjson.GetBytes(output["ok-2"].Response.Body, "id").String())
+
+	errors := readBatchResultFile(t, 51, reloaded.ErrorFileId)
+	require.Len(t, errors, 1)
+	require.Equal(t, http.StatusBadRequest, errors["bad"].Response.StatusCode)
+	require.Equal(t, "bad request", gjson.GetBytes(errors["bad"].Response.Body, "error.message").String())
+}
+
+func TestRunBatchCancelled(t *testing.T) {
+	setupBatchTest(t)
+	seedUser(t, 52, 0)
+	seedToken(t, 52, 52, "batch-cancel", 1000)
+
+	lines := make([]string, 0, 5)
+	for i := 1; i <= 5; i++ {
+		lines = append(lines, batchTestLine(fmt.Sprintf("req-%d", i), fmt.Sprintf(`{"n":%d}`, i)))
+	}
+	batch := createTestBatch(t, 52, 52, strings.Join(lines, "\n"))
+	var once sync.Once
+	BatchRequestHandler = batchTestHandler(t, batch.BatchId, "batch-cancel", func(n int64) {
+		// 第一个请求执行期间用户取消批次
+		once.Do(func() {
+			current, err := model.GetUserBatchById(52, batch.BatchId)
+			require.NoError(t, err)
+			require.NoError(t, CancelBatch(current))
+		})
+	})
+
+	runBatch(batch)
+
+	reloaded, err := model.GetUserBatchById(52, batch.BatchId)
+	require.NoError(t, err)
+	require.Equal(t, model.BatchStatusCancelled, reloaded.Status)
+	require.NotZero(t, reloaded.CancelledAt)
+	require.Equal(t, 5, reloaded.CompletedCount+reloaded.FailedCount)
+	require.GreaterOrEqual(t, reloaded.CompletedCount, 1)
+	require.Less(t, reloaded.CompletedCount, 5)
+
+	// 已执行的请求写入结果文件，未执行的请求以 batch_cancelled 写入错误文件
+	output := readBatchResultFile(t, 52, reloaded.OutputFileId)
+	require.Contains(t, output, "req-1")
+	errors := readBatchResultFile(t, 52, reloaded.ErrorFileId)
+	require.Len(t, errors, reloaded.FailedCount)
+	require.Contains(t, errors, "req-5")
+	for _, line := range errors {
+		require.Nil(t, line.Response)
+		require.Equal(t, "batch_cancelled", line.Error.Code)
+	}
+	require.Equal(t, reloaded.CompletedCount*int(100*operation_setting.GetBatchDiscountRatio()), reloaded.Quota)
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -175,6 +176,12 @@ func (s *BillingSession) preConsume(c *gin.Context, quota int) *types.NewAPIErro
 			}
 			s.tokenConsumed = 0
 		}
+		switch {
+		case errors.Is(err, model.ErrOrganizationQuotaInsufficient), errors.Is(err, model.ErrOrganizationMemberQuotaExceeded):
+			return types.NewErrorWithStatusCode(fmt.Errorf("组织额度不足: %s", err.Error()), types.ErrorCodeInsufficientOrganizationQuota, http.StatusForbidden, types.ErrOptionWithSkipRetry(), types.ErrOptionWithNoRecordErrorLog())
+		case errors.Is(err, model.ErrOrganizationNotFound), errors.Is(err, model.ErrOrganizationDisabled), errors.Is(err, model.ErrOrganizationNotMember):
+			return types.NewErrorWithStatusCode(fmt.Errorf("无法使用组织额度: %s", err.Error()), types.ErrorCodeAccessDenied, http.StatusForbidden, types.ErrOptionWithSkipRetry(), types.ErrOptionWithNoRecordErrorLog())
+		}
 		// TODO: model 层应定义哨兵错误（如 ErrNoActiveSubscription），用 errors.Is 替代字符串匹配
 		errMsg := err.Error()
 		if strings.Contains(errMsg, "no active subscription") || strings.Contains(errMsg, "subscription quota insufficient") {
@@ -222,6 +229,9 @@ func (s *BillingSession) shouldTrust(c *gin.Context) bool {
 		// 2. SubscriptionFunding.PreConsume 忽略参数，始终用 s.amount 预扣
 		// 3. 若信任旁路将 effectiveQuota 设为 0，会导致 preConsumedQuota 与实际订阅预扣不一致
 		return false
+	case BillingSourceOrganization:
+		// 组织成员子额度需要精确计入每次请求的用量，不启用信任旁路
+		return false
 	default:
 		return false
 	}
@@ -257,6 +267,21 @@ func NewBillingSession(c *gin.Context, relayInfo *relaycommon.RelayInfo, preCons
 		return nil, types.NewError(fmt.Errorf("relayInfo is nil"), types.ErrorCodeInvalidRequest, types.ErrOptionWithSkipRetry())
 	}
 
+	// 组织令牌只从组织共享钱包扣费，不参与个人计费偏好的回退
+	if relayInfo.OrganizationId > 0 {
+		session := &BillingSession{
+			relayInfo: relayInfo,
+			funding: &OrganizationFunding{
+				organizationId: relayInfo.OrganizationId,
+				userId:         relayInfo.UserId,
+			},
+		}
+		if apiErr := session.preConsume(c, preConsumedQuota); apiErr != nil {
+			return nil, apiErr
+		}
+		return session, nil
+	}
+
 	pref := common.NormalizeBillingPreference(relayInfo.UserSetting.BillingPreference)
 
 	// 钱包路径需要先检查用户额度
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/pkg/channelhealth"
+	"github.com/QuantumNous/new-api/pkg/metrics"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+)
+
+// isChannelHealthFailure 判断错误是否应计入渠道失败，口径与重试判断一致：
+// 渠道错误与可重试的状态码计为失败，请求本身的错误（如 400）不计入统计
+func isChannelHealthFailure(err *types.NewAPIError) (failure bool, counted bool) {
+	if err == nil {
+		return false, true
+	}
+	if types.IsChannelError(err) {
+		return true, true
+	}
+	if types.IsSkipRetryError(err) {
+		return false, false
+	}
+	code := err.StatusCode
+	if code < 100 || code > 599 {
+		return true, true
+	}
+	if operation_setting.IsAlwaysSkipRetryCode(err.GetErrorCode()) {
+		return false, false
+	}
+	if operation_setting.ShouldRetryByStatusCode(code) {
+		return true, true
+	}
+	return false, false
+}
+
+// RecordChannelOutcome 将一次转发尝试的结果计入渠道（及多密钥渠道的密钥）健康度统计，
+// 首字耗时从本次尝试开始计算，不包含之前失败的尝试
+func RecordChannelOutcome(info *relaycommon.RelayInfo, channel *model.Channel, err *types.NewAPIError, attemptStart time.Time) {
+	if !operation_setting.GetChannelSelectionSetting().AdaptiveEnabled {
+		return
+	}
+	failure, counted := isChannelHealthFailure(err)
+	if !counted {
+		return
+	}
+	outcome := channelhealth.Outcome{Success: !failure}
+	if !failure && info.IsStream {
+		if ttft, ok := info.FirstResponseSince(attemptStart); ok {
+			outcome.TTFT = ttft
+		}
+	}
+	cfg := operation_setting.GetChannelSelectionSetting().HealthConfig()
+	if channelhealth.RecordOutcome(cfg, channelhealth.Key{ChannelId: channel.Id, KeyIndex: channelhealth.ChannelLevel}, outcome) {
+		metrics.IncChannelCircuitOpened(channel.Id, channel.Type)
+		common.SysLog(fmt.Sprintf("通道「%s」（#%d）错误率过高，已熔断 %d 秒", channel.Name, channel.Id, operation_setting.GetChannelSelectionSetting().OpenSeconds))
+	}
+	if channel.ChannelInfo.IsMultiKey {
+		keyIndex := info.ChannelMultiKeyIndex
+		if channelhealth.RecordOutcome(cfg, channelhealth.Key{ChannelId: channel.Id, KeyIndex: keyIndex}, outcome) {
+			common.SysLog(fmt.Sprintf("通道「%s」（#%d）密钥 #%d 错误率过高，已熔断 %d 秒", channel.Name, channel.Id, keyIndex, operation_setting.GetChannelSelectionSetting().OpenSeconds))
+		}
+	}
+}
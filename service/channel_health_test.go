@@ -0,0 +1,45 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/pkg/channelhealth"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordChannelOutcomeTTFT(t *testing.T) {
+	setting := operation_setting.GetChannelSelectionSetting()
+	original := setting.AdaptiveEnabled
+	setting.AdaptiveEnabled = true
+	t.Cleanup(func() {
+		setting.AdaptiveEnabled = original
+		channelhealth.Reset(7001)
+		channelhealth.Reset(7002)
+	})
+
+	now := time.Now()
+	info := &relaycommon.RelayInfo{
+		IsStream:          true,
+		StartTime:         now.Add(-10 * time.Second),
+		FirstResponseTime: now.Add(-time.Second),
+	}
+	// 首字耗时从本次尝试开始计算，不包含之前失败的尝试
+	RecordChannelOutcome(info, &model.Channel{Id: 7001}, nil, now.Add(-3*time.Second))
+	// 首个响应由之前的尝试发出（如流式续传）时不计入首字耗时
+	RecordChannelOutcome(info, &model.Channel{Id: 7002}, nil, now)
+
+	stats := make(map[int]channelhealth.Stat)
+	for _, stat := range channelhealth.Snapshot(setting.HealthConfig()) {
+		if stat.KeyIndex == channelhealth.ChannelLevel {
+			stats[stat.ChannelId] = stat
+		}
+	}
+	require.Equal(t, 1, stats[7001].Samples)
+	require.EqualValues(t, 2000, stats[7001].AvgTTFTMs)
+	require.Equal(t, 1, stats[7002].Samples)
+	require.Zero(t, stats[7002].AvgTTFTMs)
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+const (
+	channelKeyScheduleTickInterval = 5 * time.Minute
+	// channelKeyUsageRetentionDays key每日用量记录保留天数
+	channelKeyUsageRetentionDays = 31
+)
+
+var (
+	channelKeyScheduleOnce    sync.Once
+	channelKeyScheduleRunning atomic.Bool
+)
+
+// StartChannelKeyScheduleTask 定期检查多Key渠道的key：禁用过期key、发送临期提醒、次日恢复达到每日上限的key
+func StartChannelKeyScheduleTask() {
+	channelKeyScheduleOnce.Do(func() {
+		if !common.IsMasterNode {
+			return
+		}
+		gopool.Go(func() {
+			logger.LogInfo(context.Background(), fmt.Sprintf("channel key schedule task started: tick=%s", channelKeyScheduleTickInterval))
+			ticker := time.NewTicker(channelKeyScheduleTickInterval)
+			defer ticker.Stop()
+
+			runChannelKeyScheduleOnce()
+			for range ticker.C {
+				runChannelKeyScheduleOnce()
+			}
+		})
+	})
+}
+
+func runChannelKeyScheduleOnce() {
+	if !channelKeyScheduleRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer channelKeyScheduleRunning.Store(false)
+
+	ctx := context.Background()
+	channelIds, err := model.GetMultiKeyChannelIds()
+	if err != nil {
+		logger.LogWarn(ctx, fmt.Sprintf("channel key schedule task failed: %v", err))
+		return
+	}
+	now := time.Now()
+	alertBefore := time.Duration(operation_setting.GetMonitorSetting().MultiKeyExpiryAlertDays) * 24 * time.Hour
+	changed := false
+	for _, channelId := range channelIds {
+		result, err := model.ApplyMultiKeySchedule(channelId, now, alertBefore)
+		if err != nil {
+			logger.LogWarn(ctx, fmt.Sprintf("channel key schedule failed: channel_id=%d, error=%v", channelId, err))
+			continue
+		}
+		if !result.Changed() {
+			continue
+		}
+		changed = true
+		notifyChannelKeySchedule(result)
+	}
+	if changed {
+		model.InitChannelCache()
+	}
+
+	beforeDay := now.AddDate(0, 0, -channelKeyUsageRetentionDays).Format("2006-01-02")
+	if _, err := model.DeleteOldChannelKeyUsage(beforeDay); err != nil {
+		logger.LogWarn(ctx, fmt.Sprintf("channel key usage cleanup failed: %v", err))
+	}
+}
+
+func notifyChannelKeySchedule(result *model.MultiKeyScheduleResult) {
+	if len(result.Enabled) > 0 {
+		common.SysLog(fmt.Sprintf("通道「%s」（#%d）已恢复 %d 个密钥", result.ChannelName, result.ChannelId, len(result.Enabled)))
+	}
+	if result.StatusChanged {
+		common.SysLog(fmt.Sprintf("通道「%s」（#%d）因密钥状态变化已更新渠道状态", result.ChannelName, result.ChannelId))
+	}
+	if len(result.Expiring) == 0 && len(result.Expired) == 0 {
+		return
+	}
+	channel, err := model.GetChannelById(result.ChannelId, false)
+	if err != nil {
+		return
+	}
+	for _, idx := range result.Expiring {
+		meta := channel.ChannelInfo.GetMultiKeyMeta(idx)
+		subject := fmt.Sprintf("通道「%s」（#%d）的密钥 #%d 即将过期", result.ChannelName, result.ChannelId, idx+1)
+		content := fmt.Sprintf("%s，过期时间：%s，备注：%s", subject, time.Unix(meta.ExpiresAt, 0).Format("2006-01-02 15:04:05"), meta.Note)
+		NotifyRootUser(fmt.Sprintf("%s_%d_key_%d_expiring", dto.NotifyTypeChannelUpdate, result.ChannelId, idx), subject, content)
+		PublishWebhookEvent(model.WebhookEventChannelKeyExpiring, 0, channelKeyEventData(result, idx, meta))
+	}
+	for _, idx := range result.Expired {
+		meta := channel.ChannelInfo.GetMultiKeyMeta(idx)
+		subject := fmt.Sprintf("通道「%s」（#%d）的密钥 #%d 已过期并被禁用", result.ChannelName, result.ChannelId, idx+1)
+		content := fmt.Sprintf("%s，备注：%s", subject, meta.Note)
+		NotifyRootUser(fmt.Sprintf("%s_%d_key_%d_expired", dto.NotifyTypeChannelUpdate, result.ChannelId, idx), subject, content)
+		PublishWebhookEvent(model.WebhookEventChannelKeyExpired, 0, channelKeyEventData(result, idx, meta))
+	}
+}
+
+func channelKeyEventData(result *model.MultiKeyScheduleResult, idx int, meta model.MultiKeyMeta) map[string]interface{} {
+	return map[string]interface{}{
+		"channel_id":   result.ChannelId,
+		"channel_name": result.ChannelName,
+		"key_index":    idx,
+		"expires_at":   meta.ExpiresAt,
+		"note":         meta.Note,
+	}
+}
@@ -154,16 +154,18 @@ func CacheGetRandomSatisfiedChannel(param *RetryParam) (*model.Channel, string,
 			// 为下一次重试准备状态
 			if crossGroupRetry && priorityRetry >= common.RetryTimes {
 				nextGroupIdx := i + 1
-				common.SetContextKey(param.Ctx, constant.ContextKeyAutoGroupIndex, nextGroupIdx)
 				if nextGroupIdx < len(autoGroups) {
 					// Current group exhausted, switch to next group
 					// 当前分组已用完所有重试次数，切换到下一个分组
+					common.SetContextKey(param.Ctx, constant.ContextKeyAutoGroupIndex, nextGroupIdx)
 					logger.LogDebug(param.Ctx, "Current group %s retries exhausted (priorityRetry=%d >= RetryTimes=%d), switching to next group", autoGroup, priorityRetry, common.RetryTimes)
 					param.SetRetry(0)
 					param.ResetRetryNextTry()
 				} else {
 					// Last group exhausted, do not reset retry counter
-					// 已是最后一个分组，不重置重试计数器，让外层循环自然停止
+					// 已是最后一个分组，不重置重试计数器，让外层循环自然停止；
+					// 分组索引保持不变，流式续传的额外尝试仍可在该分组内选择渠道
+					common.SetContextKey(param.Ctx, constant.ContextKeyAutoGroupIndex, i)
 					logger.LogDebug(param.Ctx, "Current group %s retries exhausted (priorityRetry=%d >= RetryTimes=%d), last group reached, stopping", autoGroup, priorityRetry, common.RetryTimes)
 				}
 			} else {
@@ -219,16 +221,18 @@ func selectFromOrderedGroups(param *RetryParam, groups []string) (*model.Channel
 
 		if crossGroupRetry && priorityRetry >= common.RetryTimes {
 			nextGroupIdx := i + 1
-			common.SetContextKey(param.Ctx, constant.ContextKeyAutoGroupIndex, nextGroupIdx)
 			if nextGroupIdx < len(groups) {
 				// 还有下一个分组，重置重试计数器并准备切换
+				common.SetContextKey(param.Ctx, constant.ContextKeyAutoGroupIndex, nextGroupIdx)
 				logger.LogDebug(param.Ctx, "Multi-group %s retries exhausted (priorityRetry=%d >= RetryTimes=%d), switching to next group", group, priorityRetry, common.RetryTimes)
 				param.SetRetry(0)
 				param.ResetRetryNextTry()
 			} else {
 				// 已是最后一个分组，不重置重试计数器
 				// 这样外层 shouldRetry 计算 RetryTimes-GetRetry()=0 → 停止重试，
-				// 避免触发一次多余的 getChannel 调用而产生误导性的"渠道不存在"错误
+				// 避免触发一次多余的 getChannel 调用而产生误导性的"渠道不存在"错误；
+				// 分组索引保持不变，流式续传的额外尝试仍可在该分组内选择渠道
+				common.SetContextKey(param.Ctx, constant.ContextKeyAutoGroupIndex, i)
 				logger.LogDebug(param.Ctx, "Multi-group %s retries exhausted (priorityRetry=%d >= RetryTimes=%d), last group reached, stopping", group, priorityRetry, common.RetryTimes)
 			}
 		} else {
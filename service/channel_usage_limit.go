@@ -0,0 +1,27 @@
+package service
+
+import (
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/common/limiter"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TrackChannelUsage 计入渠道本次转发的请求数与并发数（限制取自选择渠道时写入上下文的配置），返回的 release 需在转发结束后调用。
+// 渠道是否超出限制已在选择渠道时检查，这里不再拒绝；
+// 渠道设置了 TPM 限制时记录计数键，消费日志记录时计入实际 token 消耗
+func TrackChannelUsage(c *gin.Context, channelId int) (release func()) {
+	limits, _ := common.GetContextKeyType[limiter.UsageLimits](c, constant.ContextKeyChannelUsageLimits)
+	key := model.ChannelUsageLimitKey(channelId)
+	if limits.TPM > 0 {
+		common.SetContextKey(c, constant.ContextKeyChannelUsageLimitKey, key)
+	} else {
+		common.SetContextKey(c, constant.ContextKeyChannelUsageLimitKey, "")
+	}
+	if !limits.Enabled() {
+		return func() {}
+	}
+	return limiter.TrackUsage(c.Request.Context(), key, limits)
+}
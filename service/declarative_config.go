@@ -0,0 +1,924 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"gopkg.in/yaml.v3"
+)
+
+// 声明式配置：使用 YAML/JSON 文件描述渠道、倍率、限流、预填组与系统选项，
+// 通过 plan 与数据库对比差异，通过 apply 将数据库收敛到文件描述的状态。
+// 运行时仍以 options 表与 channels 表为准，文件只是写入这些表的另一种入口。
+
+const (
+	DeclarativeConfigKindOption       = "option"
+	DeclarativeConfigKindChannel      = "channel"
+	DeclarativeConfigKindPrefillGroup = "prefill_group"
+
+	DeclarativeConfigActionCreate = "create"
+	DeclarativeConfigActionUpdate = "update"
+	DeclarativeConfigActionDelete = "delete"
+
+	DeclarativeConfigModePlan  = "plan"
+	DeclarativeConfigModeApply = "apply"
+)
+
+const declarativeConfigRedacted = "******"
+
+type DeclarativeConfig struct {
+	// Options 直接写入 options 表的键值，对象与数组会序列化为 JSON 字符串
+	Options          map[string]any            `yaml:"options"`
+	Ratios           DeclarativeRatios         `yaml:"ratios"`
+	UserUsableGroups map[string]string         `yaml:"user_usable_groups"`
+	RateLimit        *DeclarativeRateLimit     `yaml:"rate_limit"`
+	Channels         []DeclarativeChannel      `yaml:"channels"`
+	PrefillGroups    []DeclarativePrefillGroup `yaml:"prefill_groups"`
+	Prune            DeclarativePrune          `yaml:"prune"`
+
+	options map[string]string
+	hash    string
+}
+
+type DeclarativeRatios struct {
+	GroupRatio      map[string]float64 `yaml:"group_ratio"`
+	ModelRatio      map[string]float64 `yaml:"model_ratio"`
+	CompletionRatio map[string]float64 `yaml:"completion_ratio"`
+	ModelPrice      map[string]float64 `yaml:"model_price"`
+	CacheRatio      map[string]float64 `yaml:"cache_ratio"`
+}
+
+type DeclarativeRateLimit struct {
+	Enabled         *bool `yaml:"enabled"`
+	DurationMinutes *int  `yaml:"duration_minutes"`
+	Count           *int  `yaml:"count"`
+	SuccessCount    *int  `yaml:"success_count"`
+	// Groups 分组限流，值为 [总请求数, 成功请求数]
+	Groups map[string][]int `yaml:"groups"`
+}
+
+// DeclarativeChannel 以名称作为渠道标识；指针与 map 字段为空时表示不由配置文件管理
+type DeclarativeChannel struct {
+	Name string `yaml:"name"`
+	Type int    `yaml:"type"`
+	// Key、KeyEnv、KeyFile 三选一，后两者用于避免把密钥写入配置文件
+	Key               string            `yaml:"key"`
+	KeyEnv            string            `yaml:"key_env"`
+	KeyFile           string            `yaml:"key_file"`
+	BaseURL           *string           `yaml:"base_url"`
+	Models            []string          `yaml:"models"`
+	Groups            []string          `yaml:"groups"`
+	Priority          *int64            `yaml:"priority"`
+	Weight            *uint             `yaml:"weight"`
+	Enabled           *bool             `yaml:"enabled"`
+	AutoBan           *bool             `yaml:"auto_ban"`
+	Tag               *string           `yaml:"tag"`
+	Remark            *string           `yaml:"remark"`
+	TestModel         *string           `yaml:"test_model"`
+	Other             *string           `yaml:"other"`
+	ModelMapping      map[string]string `yaml:"model_mapping"`
+	StatusCodeMapping map[string]string `yaml:"status_code_mapping"`
+	ParamOverride     map[string]any    `yaml:"param_override"`
+	HeaderOverride    map[string]any    `yaml:"header_override"`
+	Setting           map[string]any    `yaml:"setting"`
+	Settings          map[string]any    `yaml:"settings"`
+	RpmLimit          *int              `yaml:"rpm_limit"`
+	TpmLimit          *int              `yaml:"tpm_limit"`
+	MaxInFlight       *int              `yaml:"max_in_flight"`
+
+	resolvedKey string
+}
+
+type DeclarativePrefillGroup struct {
+	Name        string   `yaml:"name"`
+	Type        string   `yaml:"type"`
+	Items       []string `yaml:"items"`
+	Description string   `yaml:"description"`
+}
+
+// DeclarativePrune 控制是否删除数据库中存在但配置文件未声明的对象，选项永远不会被删除
+type DeclarativePrune struct {
+	Channels      bool `yaml:"channels"`
+	PrefillGroups bool `yaml:"prefill_groups"`
+}
+
+type DeclarativeConfigChange struct {
+	Kind   string   `json:"kind"`
+	Action string   `json:"action"`
+	Name   string   `json:"name"`
+	Id     int      `json:"id,omitempty"`
+	Fields []string `json:"fields,omitempty"`
+	Before string   `json:"before,omitempty"`
+	After  string   `json:"after,omitempty"`
+
+	optionValue  string
+	channel      *model.Channel
+	prefillGroup *model.PrefillGroup
+}
+
+type DeclarativeConfigPlan struct {
+	Hash    string                     `json:"hash"`
+	Create  int                        `json:"create"`
+	Update  int                        `json:"update"`
+	Delete  int                        `json:"delete"`
+	Changes []*DeclarativeConfigChange `json:"changes"`
+	Applied bool                       `json:"applied"`
+}
+
+func (plan *DeclarativeConfigPlan) add(change *DeclarativeConfigChange) {
+	switch change.Action {
+	case DeclarativeConfigActionCreate:
+		plan.Create++
+	case DeclarativeConfigActionUpdate:
+		plan.Update++
+	case DeclarativeConfigActionDelete:
+		plan.Delete++
+	}
+	plan.Changes = append(plan.Changes, change)
+}
+
+func (plan *DeclarativeConfigPlan) Empty() bool {
+	return len(plan.Changes) == 0
+}
+
+func (plan *DeclarativeConfigPlan) Summary() string {
+	return fmt.Sprintf("create=%d, update=%d, delete=%d", plan.Create, plan.Update, plan.Delete)
+}
+
+var declarativeConfigLock sync.Mutex
+
+// GetDeclarativeConfigPath 返回 DECLARATIVE_CONFIG_FILE 指定的配置文件路径，未配置时为空
+func GetDeclarativeConfigPath() string {
+	return strings.TrimSpace(os.Getenv("DECLARATIVE_CONFIG_FILE"))
+}
+
+// LoadDeclarativeConfigFile 读取配置文件，key_file 的相对路径以配置文件所在目录为基准
+func LoadDeclarativeConfigFile(path string) (*DeclarativeConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取声明式配置文件失败: %w", err)
+	}
+	return ParseDeclarativeConfig(data, filepath.Dir(path), true)
+}
+
+// ParseDeclarativeConfig 解析并校验配置内容。allowExternalSecrets 为 false 时禁止通过
+// key_env/key_file 读取服务器上的环境变量与文件，用于校验通过接口提交的内容。
+func ParseDeclarativeConfig(data []byte, baseDir string, allowExternalSecrets bool) (*DeclarativeConfig, error) {
+	cfg := &DeclarativeConfig{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("解析声明式配置失败: %w", err)
+	}
+
+	hasher := sha256.New()
+	hasher.Write(data)
+
+	channelNames := make(map[string]struct{}, len(cfg.Channels))
+	for i := range cfg.Channels {
+		spec := &cfg.Channels[i]
+		spec.Name = strings.TrimSpace(spec.Name)
+		if spec.Name == "" {
+			return nil, fmt.Errorf("channels[%d]: 渠道名称不能为空", i)
+		}
+		if _, ok := channelNames[spec.Name]; ok {
+			return nil, fmt.Errorf("渠道名称重复: %s", spec.Name)
+		}
+		channelNames[spec.Name] = struct{}{}
+		if spec.Type <= 0 {
+			return nil, fmt.Errorf("渠道 %s: type 必须为正整数", spec.Name)
+		}
+		for _, m := range spec.Models {
+			if len(m) > 255 {
+				return nil, fmt.Errorf("渠道 %s: 模型名称过长: %s", spec.Name, m)
+			}
+		}
+		key, err := resolveDeclarativeChannelKey(spec, baseDir, allowExternalSecrets)
+		if err != nil {
+			return nil, fmt.Errorf("渠道 %s: %w", spec.Name, err)
+		}
+		spec.resolvedKey = key
+		// 密钥来自环境变量或文件时，轮换密钥也应视为配置变更
+		hasher.Write([]byte(spec.Name))
+		hasher.Write([]byte{0})
+		hasher.Write([]byte(key))
+		hasher.Write([]byte{0})
+	}
+
+	groupNames := make(map[string]struct{}, len(cfg.PrefillGroups))
+	for i := range cfg.PrefillGroups {
+		group := &cfg.PrefillGroups[i]
+		group.Name = strings.TrimSpace(group.Name)
+		if group.Name == "" || strings.TrimSpace(group.Type) == "" {
+			return nil, fmt.Errorf("prefill_groups[%d]: 名称与类型不能为空", i)
+		}
+		if _, ok := groupNames[group.Name]; ok {
+			return nil, fmt.Errorf("预填组名称重复: %s", group.Name)
+		}
+		groupNames[group.Name] = struct{}{}
+	}
+
+	options, err := cfg.buildOptions()
+	if err != nil {
+		return nil, err
+	}
+	cfg.options = options
+	cfg.hash = hex.EncodeToString(hasher.Sum(nil))
+	return cfg, nil
+}
+
+func resolveDeclarativeChannelKey(spec *DeclarativeChannel, baseDir string, allowExternalSecrets bool) (string, error) {
+	sources := 0
+	for _, s := range []string{spec.Key, spec.KeyEnv, spec.KeyFile} {
+		if s != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return "", errors.New("key、key_env、key_file 必须且只能填写一个")
+	}
+	if spec.Key != "" {
+		return strings.TrimSpace(spec.Key), nil
+	}
+	if !allowExternalSecrets {
+		return "", errors.New("通过接口提交的配置不支持 key_env 与 key_file")
+	}
+	if spec.KeyEnv != "" {
+		key := strings.TrimSpace(os.Getenv(spec.KeyEnv))
+		if key == "" {
+			return "", fmt.Errorf("环境变量 %s 为空", spec.KeyEnv)
+		}
+		return key, nil
+	}
+	path := spec.KeyFile
+	if !filepath.IsAbs(path) && baseDir != "" {
+		path = filepath.Join(baseDir, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("读取密钥文件失败: %w", err)
+	}
+	key := strings.TrimSpace(string(data))
+	if key == "" {
+		return "", fmt.Errorf("密钥文件 %s 为空", spec.KeyFile)
+	}
+	return key, nil
+}
+
+// buildOptions 将各配置段展开为 options 表的键值，并使用与选项接口相同的 ValidateOptionValue 校验取值
+func (cfg *DeclarativeConfig) buildOptions() (map[string]string, error) {
+	options := make(map[string]string)
+	set := func(key string, value any) error {
+		if _, ok := options[key]; ok {
+			return fmt.Errorf("配置项 %s 重复声明", key)
+		}
+		str, err := declarativeOptionValue(value)
+		if err != nil {
+			return fmt.Errorf("配置项 %s: %w", key, err)
+		}
+		options[key] = str
+		return nil
+	}
+
+	for key, value := range cfg.Options {
+		if err := set(key, value); err != nil {
+			return nil, err
+		}
+	}
+	ratios := []struct {
+		key   string
+		value map[string]float64
+	}{
+		{"GroupRatio", cfg.Ratios.GroupRatio},
+		{"ModelRatio", cfg.Ratios.ModelRatio},
+		{"CompletionRatio", cfg.Ratios.CompletionRatio},
+		{"ModelPrice", cfg.Ratios.ModelPrice},
+		{"CacheRatio", cfg.Ratios.CacheRatio},
+	}
+	for _, ratio := range ratios {
+		if ratio.value == nil {
+			continue
+		}
+		if err := set(ratio.key, ratio.value); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.UserUsableGroups != nil {
+		if err := set("UserUsableGroups", cfg.UserUsableGroups); err != nil {
+			return nil, err
+		}
+	}
+	if rl := cfg.RateLimit; rl != nil {
+		if rl.Enabled != nil {
+			if err := set("ModelRequestRateLimitEnabled", *rl.Enabled); err != nil {
+				return nil, err
+			}
+		}
+		if rl.DurationMinutes != nil {
+			if err := set("ModelRequestRateLimitDurationMinutes", *rl.DurationMinutes); err != nil {
+				return nil, err
+			}
+		}
+		if rl.Count != nil {
+			if err := set("ModelRequestRateLimitCount", *rl.Count); err != nil {
+				return nil, err
+			}
+		}
+		if rl.SuccessCount != nil {
+			if err := set("ModelRequestRateLimitSuccessCount", *rl.SuccessCount); err != nil {
+				return nil, err
+			}
+		}
+		if rl.Groups != nil {
+			groups := make(map[string][2]int, len(rl.Groups))
+			for group, limits := range rl.Groups {
+				if len(limits) != 2 {
+					return nil, fmt.Errorf("分组 %s 的限流配置必须为 [总请求数, 成功请求数]", group)
+				}
+				groups[group] = [2]int{limits[0], limits[1]}
+			}
+			if err := set("ModelRequestRateLimitGroup", groups); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	common.OptionMapRWMutex.RLock()
+	for key := range options {
+		if _, ok := common.OptionMap[key]; !ok {
+			common.OptionMapRWMutex.RUnlock()
+			return nil, fmt.Errorf("未知的配置项: %s", key)
+		}
+	}
+	common.OptionMapRWMutex.RUnlock()
+
+	for key, value := range options {
+		if err := ValidateOptionValue(key, value); err != nil {
+			return nil, fmt.Errorf("配置项 %s: %w", key, err)
+		}
+	}
+	return options, nil
+}
+
+func declarativeOptionValue(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	default:
+		data, err := common.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+}
+
+// normalizeDeclarativeValue 对 JSON 值做规范化（排序键、统一空值），非 JSON 字符串原样返回
+func normalizeDeclarativeValue(raw string) string {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" || trimmed == "null" || trimmed == "{}" {
+		return ""
+	}
+	var v any
+	if err := common.UnmarshalJsonStr(trimmed, &v); err != nil {
+		return raw
+	}
+	data, err := common.Marshal(v)
+	if err != nil {
+		return raw
+	}
+	return string(data)
+}
+
+// PlanDeclarativeConfig 计算将数据库收敛到配置所需的变更，不做任何写入
+func PlanDeclarativeConfig(cfg *DeclarativeConfig) (*DeclarativeConfigPlan, error) {
+	plan := &DeclarativeConfigPlan{Hash: cfg.hash, Changes: make([]*DeclarativeConfigChange, 0)}
+	planDeclarativeOptions(cfg, plan)
+	if err := planDeclarativePrefillGroups(cfg, plan); err != nil {
+		return nil, err
+	}
+	if err := planDeclarativeChannels(cfg, plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func planDeclarativeOptions(cfg *DeclarativeConfig, plan *DeclarativeConfigPlan) {
+	keys := make([]string, 0, len(cfg.options))
+	for key := range cfg.options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	common.OptionMapRWMutex.RLock()
+	defer common.OptionMapRWMutex.RUnlock()
+	for _, key := range keys {
+		desired := cfg.options[key]
+		current := common.OptionMap[key]
+		if normalizeDeclarativeValue(current) == normalizeDeclarativeValue(desired) {
+			continue
+		}
+		change := &DeclarativeConfigChange{
+			Kind:        DeclarativeConfigKindOption,
+			Action:      DeclarativeConfigActionUpdate,
+			Name:        key,
+			Before:      current,
+			After:       desired,
+			optionValue: desired,
+		}
+		if model.IsSecretOptionKey(key) {
+			change.Before = declarativeConfigRedacted
+			change.After = declarativeConfigRedacted
+		}
+		plan.add(change)
+	}
+}
+
+func planDeclarativePrefillGroups(cfg *DeclarativeConfig, plan *DeclarativeConfigPlan) error {
+	existing, err := model.GetAllPrefillGroups("")
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]*model.PrefillGroup, len(existing))
+	for _, group := range existing {
+		byName[group.Name] = group
+	}
+	declared := make(map[string]struct{}, len(cfg.PrefillGroups))
+	for _, spec := range cfg.PrefillGroups {
+		declared[spec.Name] = struct{}{}
+		items := spec.Items
+		if items == nil {
+			items = []string{}
+		}
+		itemsJSON, err := common.Marshal(items)
+		if err != nil {
+			return err
+		}
+		group, ok := byName[spec.Name]
+		if !ok {
+			plan.add(&DeclarativeConfigChange{
+				Kind:   DeclarativeConfigKindPrefillGroup,
+				Action: DeclarativeConfigActionCreate,
+				Name:   spec.Name,
+				prefillGroup: &model.PrefillGroup{
+					Name:        spec.Name,
+					Type:        spec.Type,
+					Items:       model.JSONValue(itemsJSON),
+					Description: spec.Description,
+				},
+			})
+			continue
+		}
+		fields := make([]string, 0)
+		if group.Type != spec.Type {
+			group.Type = spec.Type
+			fields = append(fields, "type")
+		}
+		if normalizeDeclarativeValue(string(group.Items)) != normalizeDeclarativeValue(string(itemsJSON)) {
+			group.Items = model.JSONValue(itemsJSON)
+			fields = append(fields, "items")
+		}
+		if group.Description != spec.Description {
+			group.Description = spec.Description
+			fields = append(fields, "description")
+		}
+		if len(fields) > 0 {
+			plan.add(&DeclarativeConfigChange{
+				Kind:         DeclarativeConfigKindPrefillGroup,
+				Action:       DeclarativeConfigActionUpdate,
+				Name:         spec.Name,
+				Id:           group.Id,
+				Fields:       fields,
+				prefillGroup: group,
+			})
+		}
+	}
+	if cfg.Prune.PrefillGroups {
+		for _, group := range existing {
+			if _, ok := declared[group.Name]; ok {
+				continue
+			}
+			plan.add(&DeclarativeConfigChange{
+				Kind:         DeclarativeConfigKindPrefillGroup,
+				Action:       DeclarativeConfigActionDelete,
+				Name:         group.Name,
+				Id:           group.Id,
+				prefillGroup: group,
+			})
+		}
+	}
+	return nil
+}
+
+func planDeclarativeChannels(cfg *DeclarativeConfig, plan *DeclarativeConfigPlan) error {
+	existing, err := model.GetAllChannels(0, 0, true, false)
+	if err != nil {
+		return err
+	}
+	sort.Slice(existing, func(i, j int) bool {
+		return existing[i].Id < existing[j].Id
+	})
+	byName := make(map[string][]*model.Channel, len(existing))
+	for _, channel := range existing {
+		byName[channel.Name] = append(byName[channel.Name], channel)
+	}
+	declared := make(map[string]struct{}, len(cfg.Channels))
+	for i := range cfg.Channels {
+		spec := &cfg.Channels[i]
+		declared[spec.Name] = struct{}{}
+		matched := byName[spec.Name]
+		switch len(matched) {
+		case 0:
+			channel := &model.Channel{
+				Name:        spec.Name,
+				Status:      common.ChannelStatusEnabled,
+				Group:       "default",
+				CreatedTime: common.GetTimestamp(),
+			}
+			spec.applyTo(channel)
+			if err := channel.ValidateSettings(); err != nil {
+				return fmt.Errorf("渠道 %s: 渠道额外设置[channel setting] 格式错误：%w", spec.Name, err)
+			}
+			plan.add(&DeclarativeConfigChange{
+				Kind:    DeclarativeConfigKindChannel,
+				Action:  DeclarativeConfigActionCreate,
+				Name:    spec.Name,
+				channel: channel,
+			})
+		case 1:
+			channel := matched[0]
+			columns := spec.applyTo(channel)
+			if err := channel.ValidateSettings(); err != nil {
+				return fmt.Errorf("渠道 %s: 渠道额外设置[channel setting] 格式错误：%w", spec.Name, err)
+			}
+			if len(columns) > 0 {
+				plan.add(&DeclarativeConfigChange{
+					Kind:    DeclarativeConfigKindChannel,
+					Action:  DeclarativeConfigActionUpdate,
+					Name:    spec.Name,
+					Id:      channel.Id,
+					Fields:  columns,
+					channel: channel,
+				})
+			}
+		default:
+			return fmt.Errorf("数据库中存在 %d 个名为 %s 的渠道，无法与配置对应，请先重命名", len(matched), spec.Name)
+		}
+	}
+	if cfg.Prune.Channels {
+		for _, channel := range existing {
+			if _, ok := declared[channel.Name]; ok {
+				continue
+			}
+			plan.add(&DeclarativeConfigChange{
+				Kind:    DeclarativeConfigKindChannel,
+				Action:  DeclarativeConfigActionDelete,
+				Name:    channel.Name,
+				Id:      channel.Id,
+				channel: channel,
+			})
+		}
+	}
+	return nil
+}
+
+// remapMultiKeyInfo 多密钥渠道更换密钥时按密钥内容迁移各密钥的状态、禁用原因、禁用时间与元数据，
+// 已移除的密钥的记录随之丢弃，并按新的密钥列表重新计算数量
+func remapMultiKeyInfo(channel *model.Channel, newKey string) {
+	oldIndexes := make(map[string]int)
+	for i, key := range channel.GetKeys() {
+		key = strings.TrimSpace(key)
+		if _, ok := oldIndexes[key]; !ok {
+			oldIndexes[key] = i
+		}
+	}
+	newKeys := (&model.Channel{Key: newKey}).GetKeys()
+
+	info := &channel.ChannelInfo
+	statusList := make(map[int]int)
+	disabledReason := make(map[int]string)
+	disabledTime := make(map[int]int64)
+	meta := make(map[int]model.MultiKeyMeta)
+	for i, key := range newKeys {
+		oldIndex, ok := oldIndexes[strings.TrimSpace(key)]
+		if !ok {
+			continue
+		}
+		if status, ok := info.MultiKeyStatusList[oldIndex]; ok {
+			statusList[i] = status
+		}
+		if reason, ok := info.MultiKeyDisabledReason[oldIndex]; ok {
+			disabledReason[i] = reason
+		}
+		if disabledAt, ok := info.MultiKeyDisabledTime[oldIndex]; ok {
+			disabledTime[i] = disabledAt
+		}
+		if keyMeta, ok := info.MultiKeyMeta[oldIndex]; ok {
+			meta[i] = keyMeta
+		}
+	}
+	info.MultiKeySize = len(newKeys)
+	info.MultiKeyStatusList = statusList
+	info.MultiKeyDisabledReason = disabledReason
+	info.MultiKeyDisabledTime = disabledTime
+	info.MultiKeyMeta = meta
+	if info.MultiKeyPollingIndex >= info.MultiKeySize {
+		info.MultiKeyPollingIndex = 0
+	}
+}
+
+// applyTo 把声明的字段写入 channel，返回发生变化的列名
+func (spec *DeclarativeChannel) applyTo(channel *model.Channel) []string {
+	columns := make([]string, 0)
+	if channel.Type != spec.Type {
+		channel.Type = spec.Type
+		columns = append(columns, "type")
+	}
+	if channel.Key != spec.resolvedKey {
+		if channel.ChannelInfo.IsMultiKey {
+			remapMultiKeyInfo(channel, spec.resolvedKey)
+			columns = append(columns, "channel_info")
+		}
+		channel.Key = spec.resolvedKey
+		channel.Keys = nil
+		columns = append(columns, "key")
+	}
+	if spec.Models != nil {
+		models := strings.Join(spec.Models, ",")
+		if channel.Models != models {
+			channel.Models = models
+			columns = append(columns, "models")
+		}
+	}
+	if spec.Groups != nil {
+		group := strings.Join(spec.Groups, ",")
+		if channel.Group != group {
+			channel.Group = group
+			columns = append(columns, "group")
+		}
+	}
+	if spec.Enabled != nil {
+		// 自动禁用是运行时状态，声明启用时不强制恢复，避免与自动禁用逻辑来回拉扯
+		if *spec.Enabled && channel.Status == common.ChannelStatusManuallyDisabled {
+			channel.Status = common.ChannelStatusEnabled
+			columns = append(columns, "status")
+		} else if !*spec.Enabled && channel.Status == common.ChannelStatusEnabled {
+			channel.Status = common.ChannelStatusManuallyDisabled
+			columns = append(columns, "status")
+		}
+	}
+	if spec.AutoBan != nil {
+		autoBan := 0
+		if *spec.AutoBan {
+			autoBan = 1
+		}
+		if channel.AutoBan == nil || *channel.AutoBan != autoBan {
+			channel.AutoBan = &autoBan
+			columns = append(columns, "auto_ban")
+		}
+	}
+	if spec.Priority != nil && (channel.Priority == nil || *channel.Priority != *spec.Priority) {
+		channel.Priority = common.GetPointer(*spec.Priority)
+		columns = append(columns, "priority")
+	}
+	if spec.Weight != nil && (channel.Weight == nil || *channel.Weight != *spec.Weight) {
+		channel.Weight = common.GetPointer(*spec.Weight)
+		columns = append(columns, "weight")
+	}
+	if spec.Other != nil && channel.Other != *spec.Other {
+		channel.Other = *spec.Other
+		columns = append(columns, "other")
+	}
+
+	stringFields := []struct {
+		column  string
+		desired *string
+		current **string
+	}{
+		{"base_url", spec.BaseURL, &channel.BaseURL},
+		{"tag", spec.Tag, &channel.Tag},
+		{"remark", spec.Remark, &channel.Remark},
+		{"test_model", spec.TestModel, &channel.TestModel},
+	}
+	for _, field := range stringFields {
+		if field.desired == nil {
+			continue
+		}
+		if *field.current == nil || **field.current != *field.desired {
+			*field.current = common.GetPointer(*field.desired)
+			columns = append(columns, field.column)
+		}
+	}
+
+	intFields := []struct {
+		column  string
+		desired *int
+		current **int
+	}{
+		{"rpm_limit", spec.RpmLimit, &channel.RpmLimit},
+		{"tpm_limit", spec.TpmLimit, &channel.TpmLimit},
+		{"max_in_flight", spec.MaxInFlight, &channel.MaxInFlight},
+	}
+	for _, field := range intFields {
+		if field.desired == nil {
+			continue
+		}
+		if *field.current == nil || **field.current != *field.desired {
+			*field.current = common.GetPointer(*field.desired)
+			columns = append(columns, field.column)
+		}
+	}
+
+	jsonFields := []struct {
+		column  string
+		desired any
+		present bool
+		current **string
+	}{
+		{"model_mapping", spec.ModelMapping, spec.ModelMapping != nil, &channel.ModelMapping},
+		{"status_code_mapping", spec.StatusCodeMapping, spec.StatusCodeMapping != nil, &channel.StatusCodeMapping},
+		{"param_override", spec.ParamOverride, spec.ParamOverride != nil, &channel.ParamOverride},
+		{"header_override", spec.HeaderOverride, spec.HeaderOverride != nil, &channel.HeaderOverride},
+		{"setting", spec.Setting, spec.Setting != nil, &channel.Setting},
+	}
+	for _, field := range jsonFields {
+		if !field.present {
+			continue
+		}
+		desired := declarativeJSONText(field.desired)
+		current := ""
+		if *field.current != nil {
+			current = **field.current
+		}
+		if normalizeDeclarativeValue(current) != normalizeDeclarativeValue(desired) {
+			*field.current = common.GetPointer(desired)
+			columns = append(columns, field.column)
+		}
+	}
+	if spec.Settings != nil {
+		desired := declarativeJSONText(spec.Settings)
+		if normalizeDeclarativeValue(channel.OtherSettings) != normalizeDeclarativeValue(desired) {
+			channel.OtherSettings = desired
+			columns = append(columns, "settings")
+		}
+	}
+	return columns
+}
+
+// declarativeJSONText 将 map 序列化为数据库中保存的 JSON 文本，空 map 保存为空字符串
+func declarativeJSONText(value any) string {
+	text, err := declarativeOptionValue(value)
+	if err != nil || normalizeDeclarativeValue(text) == "" {
+		return ""
+	}
+	return text
+}
+
+func (change *DeclarativeConfigChange) apply() error {
+	switch change.Kind {
+	case DeclarativeConfigKindOption:
+		return model.UpdateOption(change.Name, change.optionValue)
+	case DeclarativeConfigKindPrefillGroup:
+		switch change.Action {
+		case DeclarativeConfigActionCreate:
+			return change.prefillGroup.Insert()
+		case DeclarativeConfigActionUpdate:
+			return change.prefillGroup.Update()
+		case DeclarativeConfigActionDelete:
+			return model.DeletePrefillGroupByID(change.Id)
+		}
+	case DeclarativeConfigKindChannel:
+		switch change.Action {
+		case DeclarativeConfigActionCreate:
+			return change.channel.Insert()
+		case DeclarativeConfigActionUpdate:
+			return change.channel.UpdateFields(change.Fields)
+		case DeclarativeConfigActionDelete:
+			return change.channel.Delete()
+		}
+	}
+	return fmt.Errorf("unsupported change: %s %s", change.Kind, change.Action)
+}
+
+// ApplyDeclarativeConfig 计算差异并按顺序写入数据库。中途失败时已写入的变更不会回滚，
+// 再次 apply 会从剩余差异继续。
+func ApplyDeclarativeConfig(cfg *DeclarativeConfig) (*DeclarativeConfigPlan, error) {
+	declarativeConfigLock.Lock()
+	defer declarativeConfigLock.Unlock()
+
+	plan, err := PlanDeclarativeConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	channelChanged := false
+	for i, change := range plan.Changes {
+		if err := change.apply(); err != nil {
+			return plan, fmt.Errorf("应用变更失败 (%s %s %s)，已应用 %d/%d 项: %w",
+				change.Kind, change.Action, change.Name, i, len(plan.Changes), err)
+		}
+		if change.Kind == DeclarativeConfigKindChannel {
+			channelChanged = true
+		}
+	}
+	if channelChanged {
+		model.InitChannelCache()
+		ResetProxyClientCache()
+	}
+	plan.Applied = true
+	return plan, nil
+}
+
+var declarativeConfigTaskOnce sync.Once
+
+// StartDeclarativeConfigTask 启动时按 DECLARATIVE_CONFIG_MODE 对配置文件执行 plan 或 apply；
+// apply 模式下配置 DECLARATIVE_CONFIG_WATCH_INTERVAL 后会定期检查文件与密钥变化并重新收敛。
+func StartDeclarativeConfigTask() {
+	declarativeConfigTaskOnce.Do(func() {
+		path := GetDeclarativeConfigPath()
+		if path == "" || !common.IsMasterNode {
+			return
+		}
+		mode := strings.ToLower(common.GetEnvOrDefaultString("DECLARATIVE_CONFIG_MODE", DeclarativeConfigModePlan))
+		interval := common.GetEnvOrDefault("DECLARATIVE_CONFIG_WATCH_INTERVAL", 0)
+
+		lastHash := runDeclarativeConfigOnce(path, mode, "")
+		if mode != DeclarativeConfigModeApply || interval <= 0 {
+			return
+		}
+		gopool.Go(func() {
+			logger.LogInfo(context.Background(), fmt.Sprintf("declarative config watcher started: file=%s, interval=%ds", path, interval))
+			ticker := time.NewTicker(time.Duration(interval) * time.Second)
+			defer ticker.Stop()
+			for range ticker.C {
+				lastHash = runDeclarativeConfigOnce(path, mode, lastHash)
+			}
+		})
+	})
+}
+
+// runDeclarativeConfigOnce 返回本次成功处理的配置哈希；内容未变化或处理失败时返回 lastHash
+func runDeclarativeConfigOnce(path string, mode string, lastHash string) string {
+	ctx := context.Background()
+	cfg, err := LoadDeclarativeConfigFile(path)
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("declarative config load failed: %v", err))
+		return lastHash
+	}
+	if cfg.hash == lastHash {
+		return lastHash
+	}
+	if mode != DeclarativeConfigModeApply {
+		plan, err := PlanDeclarativeConfig(cfg)
+		if err != nil {
+			logger.LogError(ctx, fmt.Sprintf("declarative config plan failed: %v", err))
+			return lastHash
+		}
+		logger.LogInfo(ctx, fmt.Sprintf("declarative config plan: %s", plan.Summary()))
+		for _, change := range plan.Changes {
+			line := fmt.Sprintf("  %s %s %s", change.Action, change.Kind, change.Name)
+			if len(change.Fields) > 0 {
+				line += " (" + strings.Join(change.Fields, ", ") + ")"
+			}
+			logger.LogInfo(ctx, line)
+		}
+		return cfg.hash
+	}
+	plan, err := ApplyDeclarativeConfig(cfg)
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("declarative config apply failed: %v", err))
+		return lastHash
+	}
+	if !plan.Empty() {
+		logger.LogInfo(ctx, fmt.Sprintf("declarative config applied: %s", plan.Summary()))
+	}
+	return cfg.hash
+}
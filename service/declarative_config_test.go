@@ -0,0 +1,145 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDeclarativeConfigResolvesKeys(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "openai.key"), []byte("sk-file\n"), 0600))
+	t.Setenv("DECLARATIVE_TEST_KEY", "sk-env")
+
+	data := []byte(`
+channels:
+  - name: inline
+    type: 1
+    key: sk-inline
+  - name: from-env
+    type: 1
+    key_env: DECLARATIVE_TEST_KEY
+  - name: from-file
+    type: 1
+    key_file: openai.key
+`)
+	cfg, err := ParseDeclarativeConfig(data, dir, true)
+	require.NoError(t, err)
+	require.Equal(t, "sk-inline", cfg.Channels[0].resolvedKey)
+	require.Equal(t, "sk-env", cfg.Channels[1].resolvedKey)
+	require.Equal(t, "sk-file", cfg.Channels[2].resolvedKey)
+
+	_, err = ParseDeclarativeConfig(data, dir, false)
+	require.Error(t, err)
+
+	_, err = ParseDeclarativeConfig([]byte("channels:\n  - name: a\n    type: 1\n    key: x\n    unknown: 1\n"), dir, true)
+	require.Error(t, err)
+
+	_, err = ParseDeclarativeConfig([]byte("channels:\n  - name: a\n    type: 1\n    key: x\n    key_env: Y\n"), dir, true)
+	require.Error(t, err)
+}
+
+func TestDeclarativeChannelApplyTo(t *testing.T) {
+	spec := &DeclarativeChannel{
+		Name:         "main",
+		Type:         1,
+		Models:       []string{"gpt-4o", "gpt-4o-mini"},
+		Groups:       []string{"default", "vip"},
+		Priority:     common.GetPointer[int64](10),
+		Enabled:      common.GetPointer(true),
+		ModelMapping: map[string]string{"gpt-4": "gpt-4o"},
+		resolvedKey:  "sk-1",
+	}
+	channel := &model.Channel{
+		Type:         1,
+		Key:          "sk-1",
+		Status:       common.ChannelStatusAutoDisabled,
+		Models:       "gpt-4o",
+		Group:        "default,vip",
+		Priority:     common.GetPointer[int64](10),
+		ModelMapping: common.GetPointer(`{ "gpt-4": "gpt-4o" }`),
+	}
+	require.Equal(t, []string{"models"}, spec.applyTo(channel))
+	require.Equal(t, "gpt-4o,gpt-4o-mini", channel.Models)
+	// 自动禁用的渠道不会因声明 enabled 被强制启用
+	require.Equal(t, common.ChannelStatusAutoDisabled, channel.Status)
+	require.Empty(t, spec.applyTo(channel))
+
+	spec.Enabled = common.GetPointer(false)
+	spec.resolvedKey = "sk-2"
+	require.Equal(t, []string{"key"}, spec.applyTo(channel))
+
+	channel.Status = common.ChannelStatusEnabled
+	require.Equal(t, []string{"status"}, spec.applyTo(channel))
+	require.Equal(t, common.ChannelStatusManuallyDisabled, channel.Status)
+}
+
+func TestDeclarativeChannelApplyToMultiKey(t *testing.T) {
+	spec := &DeclarativeChannel{Name: "multi", Type: 1, resolvedKey: "sk-c\nsk-a\nsk-d"}
+	channel := &model.Channel{
+		Type: 1,
+		Key:  "sk-a\nsk-b\nsk-c",
+		ChannelInfo: model.ChannelInfo{
+			IsMultiKey:             true,
+			MultiKeySize:           3,
+			MultiKeyStatusList:     map[int]int{1: common.ChannelStatusAutoDisabled, 2: common.ChannelStatusManuallyDisabled},
+			MultiKeyDisabledReason: map[int]string{1: "quota", 2: "manual"},
+			MultiKeyDisabledTime:   map[int]int64{1: 100, 2: 200},
+			MultiKeyMeta:           map[int]model.MultiKeyMeta{0: {Note: "a"}, 1: {Note: "b"}},
+			MultiKeyPollingIndex:   2,
+		},
+	}
+	require.Equal(t, []string{"channel_info", "key"}, spec.applyTo(channel))
+
+	// 状态与元数据跟随密钥内容迁移到新位置，已移除密钥的记录被丢弃
+	info := channel.ChannelInfo
+	require.Equal(t, 3, info.MultiKeySize)
+	require.Equal(t, map[int]int{0: common.ChannelStatusManuallyDisabled}, info.MultiKeyStatusList)
+	require.Equal(t, map[int]string{0: "manual"}, info.MultiKeyDisabledReason)
+	require.Equal(t, map[int]int64{0: 200}, info.MultiKeyDisabledTime)
+	require.Equal(t, map[int]model.MultiKeyMeta{1: {Note: "a"}}, info.MultiKeyMeta)
+	require.Empty(t, spec.applyTo(channel))
+}
+
+func TestNormalizeDeclarativeValue(t *testing.T) {
+	require.Equal(t, normalizeDeclarativeValue(`{"b":1,"a":2}`), normalizeDeclarativeValue(`{ "a": 2, "b": 1 }`))
+	require.Equal(t, "", normalizeDeclarativeValue("{}"))
+	require.Equal(t, "", normalizeDeclarativeValue(""))
+	require.Equal(t, "My Site", normalizeDeclarativeValue("My Site"))
+}
+
+func TestDeclarativeOptionsUseOptionValidators(t *testing.T) {
+	common.OptionMapRWMutex.Lock()
+	if common.OptionMap == nil {
+		common.OptionMap = make(map[string]string)
+	}
+	for _, key := range []string{"AutomaticRetryStatusCodes", "StripeApiSecret"} {
+		if _, ok := common.OptionMap[key]; !ok {
+			common.OptionMap[key] = ""
+			t.Cleanup(func() {
+				common.OptionMapRWMutex.Lock()
+				delete(common.OptionMap, key)
+				common.OptionMapRWMutex.Unlock()
+			})
+		}
+	}
+	common.OptionMapRWMutex.Unlock()
+
+	// 与选项接口相同的取值校验
+	_, err := ParseDeclarativeConfig([]byte("options:\n  AutomaticRetryStatusCodes: \"abc\"\n"), "", true)
+	require.ErrorContains(t, err, "AutomaticRetryStatusCodes")
+	_, err = ParseDeclarativeConfig([]byte("options:\n  AutomaticRetryStatusCodes: \"429,500-599\"\n"), "", true)
+	require.NoError(t, err)
+
+	// 密钥类配置项在变更计划中脱敏
+	cfg, err := ParseDeclarativeConfig([]byte("options:\n  StripeApiSecret: sk_live_x\n"), "", true)
+	require.NoError(t, err)
+	plan := &DeclarativeConfigPlan{}
+	planDeclarativeOptions(cfg, plan)
+	require.Len(t, plan.Changes, 1)
+	require.Equal(t, declarativeConfigRedacted, plan.Changes[0].After)
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+const (
+	fileCleanupTickInterval = 10 * time.Minute
+	fileCleanupBatchSize    = 200
+)
+
+var (
+	fileCleanupOnce    sync.Once
+	fileCleanupRunning atomic.Bool
+)
+
+// DeleteFileObject 删除文件内容（本地/S3/上游）及其元数据
+func DeleteFileObject(ctx context.Context, file *model.File) error {
+	if file.StorageType == model.FileStorageUpstream {
+		channel, err := model.CacheGetChannel(file.ChannelId)
+		if err == nil && channel != nil {
+			key, err := fileChannelKey(channel, file)
+			if err != nil {
+				return err
+			}
+			resp, err := DoFileUpstreamRequest(ctx, channel, key, http.MethodDelete, "/files/"+file.FileId, nil, "")
+			if err != nil {
+				return err
+			}
+			resp.Body.Close()
+			if resp.StatusCode/100 != 2 && resp.StatusCode != http.StatusNotFound {
+				return fmt.Errorf("upstream file delete failed: status %d", resp.StatusCode)
+			}
+		}
+	} else {
+		storage, err := GetFileStorageByType(file.StorageType)
+		if err != nil {
+			return err
+		}
+		if err = storage.Delete(ctx, file.StorageKey); err != nil {
+			return err
+		}
+	}
+	return file.Delete()
+}
+
+// StartFileCleanupTask 定期清理已过期的文件
+func StartFileCleanupTask() {
+	fileCleanupOnce.Do(func() {
+		if !common.IsMasterNode {
+			return
+		}
+		gopool.Go(func() {
+			logger.LogInfo(context.Background(), fmt.Sprintf("file cleanup task started: tick=%s", fileCleanupTickInterval))
+			ticker := time.NewTicker(fileCleanupTickInterval)
+			defer ticker.Stop()
+
+			runFileCleanupOnce()
+			for range ticker.C {
+				runFileCleanupOnce()
+			}
+		})
+	})
+}
+
+func runFileCleanupOnce() {
+	if !fileCleanupRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer fileCleanupRunning.Store(false)
+
+	ctx := context.Background()
+	files, err := model.GetExpiredFiles(fileCleanupBatchSize)
+	if err != nil {
+		logger.LogWarn(ctx, fmt.Sprintf("file cleanup task failed: %v", err))
+		return
+	}
+	deleted := 0
+	for _, file := range files {
+		if err := DeleteFileObject(ctx, file); err != nil {
+			logger.LogWarn(ctx, fmt.Sprintf("failed to delete expired file %s: %v", file.FileId, err))
+			continue
+		}
+		deleted++
+	}
+	if common.DebugEnabled && deleted > 0 {
+		logger.LogDebug(ctx, "file cleanup: deleted_count=%d", deleted)
+	}
+}
@@ -0,0 +1,306 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// FileStorage 文件内容存储后端
+type FileStorage interface {
+	// Type 返回存储类型标识，与 model.File.StorageType 对应
+	Type() string
+	Put(ctx context.Context, key string, reader io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// 文件存储目录，位于磁盘缓存目录下。磁盘缓存清理只处理根目录中的文件，不会删除子目录
+const fileStorageDir = "new-api-files"
+
+// GetFileStorage 根据当前配置返回存储后端
+func GetFileStorage() (FileStorage, error) {
+	return GetFileStorageByType(operation_setting.GetFileSetting().StorageType)
+}
+
+// GetFileStorageByType 根据存储类型返回存储后端，用于读取/删除历史文件
+func GetFileStorageByType(storageType string) (FileStorage, error) {
+	fileSetting := operation_setting.GetFileSetting()
+	switch storageType {
+	case operation_setting.FileStorageS3:
+		if fileSetting.S3Endpoint == "" || fileSetting.S3Bucket == "" {
+			return nil, errors.New("s3 file storage is not configured")
+		}
+		return &S3FileStorage{
+			Endpoint:    fileSetting.S3Endpoint,
+			Region:      fileSetting.S3Region,
+			Bucket:      fileSetting.S3Bucket,
+			AccessKeyId: fileSetting.S3AccessKeyId,
+			SecretKey:   fileSetting.S3Secret,
+			PathStyle:   fileSetting.S3PathStyle,
+		}, nil
+	case operation_setting.FileStorageLocal, "":
+		root := fileSetting.LocalPath
+		if root == "" {
+			root = common.GetDiskCachePath()
+			if root == "" {
+				root = os.TempDir()
+			}
+			root = filepath.Join(root, fileStorageDir)
+		}
+		return &LocalFileStorage{Root: root}, nil
+	default:
+		return nil, fmt.Errorf("unsupported file storage type: %s", storageType)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// LocalFileStorage — 本地磁盘存储
+// ---------------------------------------------------------------------------
+
+type LocalFileStorage struct {
+	Root string
+}
+
+func (s *LocalFileStorage) Type() string { return operation_setting.FileStorageLocal }
+
+func (s *LocalFileStorage) path(key string) (string, error) {
+	cleaned := filepath.Clean("/" + key)
+	if cleaned == "/" {
+		return "", errors.New("invalid storage key")
+	}
+	return filepath.Join(s.Root, cleaned), nil
+}
+
+func (s *LocalFileStorage) Put(_ context.Context, key string, reader io.Reader, _ int64, _ string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return fmt.Errorf("failed to create file storage directory: %w", err)
+	}
+	tmp := p + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	if _, err := io.Copy(f, reader); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+	return os.Rename(tmp, p)
+}
+
+func (s *LocalFileStorage) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+func (s *LocalFileStorage) Delete(_ context.Context, key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// S3FileStorage — S3 兼容存储（AWS S3 / MinIO / R2 等），使用 SigV4 签名
+// ---------------------------------------------------------------------------
+
+type S3FileStorage struct {
+	Endpoint    string
+	Region      string
+	Bucket      string
+	AccessKeyId string
+	SecretKey   string
+	PathStyle   bool
+}
+
+func (s *S3FileStorage) Type() string { return operation_setting.FileStorageS3 }
+
+func (s *S3FileStorage) objectURL(key string) (*url.URL, error) {
+	endpoint := s.Endpoint
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	u, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid s3 endpoint: %w", err)
+	}
+	escapedKey := (&url.URL{Path: strings.TrimPrefix(key, "/")}).EscapedPath()
+	if s.PathStyle {
+		u.Path = "/" + s.Bucket + "/" + strings.TrimPrefix(key, "/")
+		u.RawPath = "/" + url.PathEscape(s.Bucket) + "/" + escapedKey
+	} else {
+		u.Host = s.Bucket + "." + u.Host
+		u.Path = "/" + strings.TrimPrefix(key, "/")
+		u.RawPath = "/" + escapedKey
+	}
+	return u, nil
+}
+
+func (s *S3FileStorage) do(ctx context.Context, method string, key string, body io.Reader, size int64, contentType string) (*http.Response, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	const unsignedPayload = "UNSIGNED-PAYLOAD"
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+	region := s.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	credentials := aws.Credentials{AccessKeyID: s.AccessKeyId, SecretAccessKey: s.SecretKey}
+	if err := v4.NewSigner().SignHTTP(ctx, credentials, req, unsignedPayload, "s3", region, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to sign s3 request: %w", err)
+	}
+	return GetHttpClient().Do(req)
+}
+
+func (s *S3FileStorage) Put(ctx context.Context, key string, reader io.Reader, size int64, contentType string) error {
+	resp, err := s.do(ctx, http.MethodPut, key, reader, size, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("s3 put object failed: status %d, %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+func (s *S3FileStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, 0, "")
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("s3 get object failed: status %d, %s", resp.StatusCode, string(body))
+	}
+	return resp.Body, nil
+}
+
+func (s *S3FileStorage) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, 0, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 && resp.StatusCode != http.StatusNotFound {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("s3 delete object failed: status %d, %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// OpenFileContent 打开文件内容，透传文件从上游渠道读取。返回的 contentType 可能为空
+func OpenFileContent(ctx context.Context, file *model.File) (io.ReadCloser, string, error) {
+	if file.StorageType == model.FileStorageUpstream {
+		channel, err := model.CacheGetChannel(file.ChannelId)
+		if err != nil {
+			return nil, "", fmt.Errorf("channel #%d of file is unavailable: %w", file.ChannelId, err)
+		}
+		key, err := fileChannelKey(channel, file)
+		if err != nil {
+			return nil, "", err
+		}
+		resp, err := DoFileUpstreamRequest(ctx, channel, key, http.MethodGet, "/files/"+file.FileId+"/content", nil, "")
+		if err != nil {
+			return nil, "", err
+		}
+		if resp.StatusCode/100 != 2 {
+			resp.Body.Close()
+			return nil, "", fmt.Errorf("upstream returned status %d", resp.StatusCode)
+		}
+		contentType := resp.Header.Get("Content-Type")
+		if contentType == "" {
+			contentType = file.ContentType
+		}
+		return resp.Body, contentType, nil
+	}
+	storage, err := GetFileStorageByType(file.StorageType)
+	if err != nil {
+		return nil, "", err
+	}
+	reader, err := storage.Get(ctx, file.StorageKey)
+	if err != nil {
+		return nil, "", err
+	}
+	return reader, file.ContentType, nil
+}
+
+// SaveGeneratedFile 将网关生成的文件（如批处理结果）保存到当前存储后端并写入元数据
+func SaveGeneratedFile(ctx context.Context, userId int, tokenId int, filename string, purpose string, contentType string, reader io.Reader, size int64) (*model.File, error) {
+	storage, err := GetFileStorage()
+	if err != nil {
+		return nil, err
+	}
+	fileSetting := operation_setting.GetFileSetting()
+	file := &model.File{
+		FileId:      model.GenerateFileId(),
+		UserId:      userId,
+		TokenId:     tokenId,
+		Filename:    filename,
+		Purpose:     purpose,
+		Bytes:       size,
+		ContentType: contentType,
+		Status:      model.FileStatusProcessed,
+		StorageType: storage.Type(),
+		CreatedAt:   common.GetTimestamp(),
+	}
+	file.StorageKey = fmt.Sprintf("%d/%s", userId, file.FileId)
+	if storage.Type() == operation_setting.FileStorageS3 {
+		file.StorageKey = fileSetting.S3Prefix + file.StorageKey
+	}
+	if fileSetting.DefaultExpireHours > 0 {
+		file.ExpiresAt = file.CreatedAt + int64(fileSetting.DefaultExpireHours)*3600
+	}
+	if err = storage.Put(ctx, file.StorageKey, reader, size, contentType); err != nil {
+		return nil, err
+	}
+	if err = file.Insert(); err != nil {
+		_ = storage.Delete(ctx, file.StorageKey)
+		return nil, err
+	}
+	return file, nil
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalFileStorage(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	storage := &LocalFileStorage{Root: root}
+
+	require.NoError(t, storage.Put(ctx, "1/file-a", strings.NewReader("hello"), 5, "text/plain"))
+	reader, err := storage.Get(ctx, "1/file-a")
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	require.NoError(t, reader.Close())
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(data))
+
+	// 存储 key 不能逃逸出根目录
+	require.NoError(t, storage.Put(ctx, "../../escape", strings.NewReader("x"), 1, ""))
+	_, err = os.Stat(filepath.Join(root, "escape"))
+	require.NoError(t, err)
+	require.Error(t, storage.Put(ctx, "/", strings.NewReader("x"), 1, ""))
+
+	require.NoError(t, storage.Delete(ctx, "1/file-a"))
+	require.NoError(t, storage.Delete(ctx, "1/file-a"))
+	_, err = storage.Get(ctx, "1/file-a")
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestS3FileStorage(t *testing.T) {
+	InitHttpClient()
+	ctx := context.Background()
+	var mu sync.Mutex
+	objects := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/"))
+		require.Equal(t, "UNSIGNED-PAYLOAD", r.Header.Get("X-Amz-Content-Sha256"))
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			objects[r.URL.EscapedPath()] = string(body)
+		case http.MethodGet:
+			body, ok := objects[r.URL.EscapedPath()]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(body))
+		case http.MethodDelete:
+			if _, ok := objects[r.URL.EscapedPath()]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			delete(objects, r.URL.EscapedPath())
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	storage := &S3FileStorage{
+		Endpoint:    server.URL,
+		Bucket:      "bucket",
+		AccessKeyId: "AKID",
+		SecretKey:   "secret",
+		PathStyle:   true,
+	}
+	require.NoError(t, storage.Put(ctx, "files/1/file a", strings.NewReader("hello"), 5, "text/plain"))
+	require.Contains(t, objects, "/bucket/files/1/file%20a")
+
+	reader, err := storage.Get(ctx, "files/1/file a")
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	require.NoError(t, reader.Close())
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(data))
+
+	require.NoError(t, storage.Delete(ctx, "files/1/file a"))
+	// 对象不存在时删除视为成功，读取返回错误
+	require.NoError(t, storage.Delete(ctx, "files/1/file a"))
+	_, err = storage.Get(ctx, "files/1/file a")
+	require.Error(t, err)
+
+	u, err := (&S3FileStorage{Endpoint: "s3.example.com", Bucket: "bucket"}).objectURL("files/a")
+	require.NoError(t, err)
+	require.Equal(t, "https://bucket.s3.example.com/files/a", u.String())
+}
+
+func TestUploadFileToUpstream(t *testing.T) {
+	InitHttpClient()
+	var gotURL, gotKey, gotName, gotPurpose, gotContent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURL = r.URL.String()
+		gotKey = r.Header.Get("api-key")
+		file, header, err := r.FormFile("file")
+		require.NoError(t, err)
+		content, _ := io.ReadAll(file)
+		_ = file.Close()
+		gotName = header.Filename
+		gotPurpose = r.FormValue("purpose")
+		gotContent = string(content)
+		_, _ = w.Write([]byte(`{"id":"file-azure123456","object":"file","status":"processed"}`))
+	}))
+	defer server.Close()
+
+	channel := &model.Channel{
+		Type:    constant.ChannelTypeAzure,
+		Key:     "azure-key",
+		BaseURL: &server.URL,
+		Other:   "2025-04-01-preview",
+	}
+	file, err := UploadFileToUpstream(context.Background(), channel, "azure-key", `a"b.jsonl`, "batch", "", strings.NewReader("line"))
+	require.NoError(t, err)
+	require.Equal(t, "file-azure123456", file.Id)
+	require.Equal(t, "/openai/files?api-version=2025-04-01-preview", gotURL)
+	require.Equal(t, "azure-key", gotKey)
+	require.Equal(t, `a"b.jsonl`, gotName)
+	require.Equal(t, "batch", gotPurpose)
+	require.Equal(t, "line", gotContent)
+
+	// 上游返回错误时不写入文件
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":{"message":"bad file"}}`))
+	})
+	_, err = UploadFileToUpstream(context.Background(), channel, "azure-key", "a.jsonl", "batch", "", strings.NewReader("line"))
+	require.ErrorContains(t, err, "status 400")
+}
+
+func TestUpstreamFileUsesUploadKey(t *testing.T) {
+	InitHttpClient()
+	truncate(t)
+	t.Cleanup(func() { model.DB.Exec("DELETE FROM files") })
+
+	var gotAuth []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = append(gotAuth, r.Method+" "+r.URL.Path+" "+r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	channel := &model.Channel{
+		Id:          7101,
+		Type:        constant.ChannelTypeOpenAI,
+		Key:         "sk-0\nsk-1\nsk-2",
+		BaseURL:     &server.URL,
+		Status:      common.ChannelStatusEnabled,
+		ChannelInfo: model.ChannelInfo{IsMultiKey: true, MultiKeySize: 3, MultiKeyMode: constant.MultiKeyModePolling},
+	}
+	require.NoError(t, model.DB.Create(channel).Error)
+	file := &model.File{
+		FileId:          "file-upstream-key-1",
+		UserId:          1,
+		StorageType:     model.FileStorageUpstream,
+		ChannelId:       channel.Id,
+		ChannelKeyIndex: 1,
+	}
+	require.NoError(t, file.Insert())
+
+	// 读取与删除都使用上传时记录的 key，而不是轮询到的下一个 key
+	reader, _, err := OpenFileContent(context.Background(), file)
+	require.NoError(t, err)
+	_ = reader.Close()
+	require.NoError(t, DeleteFileObject(context.Background(), file))
+	require.Equal(t, []string{
+		"GET /v1/files/file-upstream-key-1/content Bearer sk-1",
+		"DELETE /v1/files/file-upstream-key-1 Bearer sk-1",
+	}, gotAuth)
+
+	// key 被移除后不再随意换用其他 key
+	file.ChannelKeyIndex = 5
+	_, _, err = OpenFileContent(context.Background(), file)
+	require.ErrorContains(t, err, "no longer exists")
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"regexp"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SelectFileUpstreamChannel 为文件透传选择上游渠道，仅支持 OpenAI / Azure 类型渠道
+func SelectFileUpstreamChannel(c *gin.Context) (*model.Channel, error) {
+	modelName := operation_setting.GetFileSetting().PassThroughModel
+	if modelName == "" {
+		return nil, errors.New("file pass-through model is not configured")
+	}
+	usingGroup := common.GetContextKeyString(c, constant.ContextKeyUsingGroup)
+	channel, _, err := CacheGetRandomSatisfiedChannel(&RetryParam{
+		Ctx:        c,
+		TokenGroup: usingGroup,
+		ModelName:  modelName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if channel == nil {
+		return nil, fmt.Errorf("no available channel for model %s", modelName)
+	}
+	if !IsFileUpstreamChannel(channel) {
+		return nil, fmt.Errorf("channel #%d does not support files api", channel.Id)
+	}
+	return channel, nil
+}
+
+// IsFileUpstreamChannel 渠道是否支持 Files API 透传
+func IsFileUpstreamChannel(channel *model.Channel) bool {
+	return channel.Type == constant.ChannelTypeOpenAI || channel.Type == constant.ChannelTypeAzure
+}
+
+// DoFileUpstreamRequest 使用指定 key 向渠道的 Files API 发送请求，subPath 形如 "/files/{id}/content"
+func DoFileUpstreamRequest(ctx context.Context, channel *model.Channel, key string, method string, subPath string, body io.Reader, contentType string) (*http.Response, error) {
+	return doOpenAIUpstreamRequest(ctx, channel, key, method, subPath, "", body, contentType)
+}
+
+// fileChannelKey 返回上传文件时使用的 key。上游文件归属于上传时的 key，
+// 多 key 渠道必须按记录的序号取回同一个 key，否则上游会返回 404
+func fileChannelKey(channel *model.Channel, file *model.File) (string, error) {
+	if !channel.ChannelInfo.IsMultiKey {
+		return channel.Key, nil
+	}
+	keys := channel.GetKeys()
+	if file.ChannelKeyIndex < 0 || file.ChannelKeyIndex >= len(keys) {
+		return "", fmt.Errorf("key #%d of channel #%d for file %s no longer exists", file.ChannelKeyIndex, channel.Id, file.FileId)
+	}
+	return keys[file.ChannelKeyIndex], nil
+}
+
+// doOpenAIUpstreamRequest 使用指定 key 向 OpenAI / Azure 渠道发送请求，Azure 渠道自动转换路径并附加 api-version
+func doOpenAIUpstreamRequest(ctx context.Context, channel *model.Channel, key string, method string, subPath string, rawQuery string, body io.Reader, contentType string) (*http.Response, error) {
+	baseURL := strings.TrimSuffix(channel.GetBaseURL(), "/")
+	var requestURL string
+	if channel.Type == constant.ChannelTypeAzure {
+		apiVersion := channel.Other
+		if apiVersion == "" {
+			apiVersion = constant.AzureDefaultAPIVersion
+		}
+		requestURL = fmt.Sprintf("%s/openai%s?api-version=%s", baseURL, subPath, apiVersion)
+		if rawQuery != "" {
+			requestURL += "&" + rawQuery
+		}
+	} else {
+		requestURL = baseURL + "/v1" + subPath
+		if rawQuery != "" {
+			requestURL += "?" + rawQuery
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if channel.Type == constant.ChannelTypeAzure {
+		req.Header.Set("api-key", key)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+key)
+		if channel.OpenAIOrganization != nil && *channel.OpenAIOrganization != "" {
+			req.Header.Set("OpenAI-Organization", *channel.OpenAIOrganization)
+		}
+	}
+	client, err := GetHttpClientWithProxy(channel.GetSetting().Proxy)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req)
+}
+
+// UploadFileToUpstream 使用指定 key 将文件以 multipart 形式上传到上游渠道，返回上游的文件对象
+func UploadFileToUpstream(ctx context.Context, channel *model.Channel, key string, filename string, purpose string, contentType string, reader io.Reader) (*dto.OpenAIFile, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	if err := writer.WriteField("purpose", purpose); err != nil {
+		return nil, err
+	}
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, escapeQuotes(filename)))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header.Set("Content-Type", contentType)
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = io.Copy(part, reader); err != nil {
+		return nil, err
+	}
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+
+	resp, err := DoFileUpstreamRequest(ctx, channel, key, http.MethodPost, "/files", &buf, writer.FormDataContentType())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("upstream file upload failed: status %d, %s", resp.StatusCode, string(respBody))
+	}
+	var file dto.OpenAIFile
+	if err = common.Unmarshal(respBody, &file); err != nil {
+		return nil, err
+	}
+	if file.Id == "" {
+		return nil, errors.New("upstream file upload returned empty id")
+	}
+	return &file, nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
+
+var fileIdPattern = regexp.MustCompile(`"(file-[A-Za-z0-9_-]{8,})"`)
+
+// maxFileIdScan 请求体中最多检查的文件 ID 数量
+const maxFileIdScan = 32
+
+// GetFileBoundChannelId 检查请求体中引用的文件，若包含透传到上游的文件则返回其所属渠道，
+// 使后续请求固定到上传文件的渠道
+func GetFileBoundChannelId(c *gin.Context) (int, bool) {
+	if !operation_setting.GetFileSetting().PassThroughEnabled {
+		return 0, false
+	}
+	contentType := c.Request.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "application/json") {
+		return 0, false
+	}
+	storage, err := common.GetBodyStorage(c)
+	if err != nil {
+		return 0, false
+	}
+	body, err := storage.Bytes()
+	if err != nil || !bytes.Contains(body, []byte(`"file-`)) {
+		return 0, false
+	}
+	matches := fileIdPattern.FindAllSubmatch(body, maxFileIdScan)
+	if len(matches) == 0 {
+		return 0, false
+	}
+	fileIds := make([]string, 0, len(matches))
+	for _, m := range matches {
+		fileIds = append(fileIds, string(m[1]))
+	}
+	channelIds, err := model.GetFileChannelIds(c.GetInt("id"), fileIds)
+	if err != nil {
+		common.SysLog("failed to get file channel ids: " + err.Error())
+		return 0, false
+	}
+	for _, id := range fileIds {
+		if channelId, ok := channelIds[id]; ok {
+			return channelId, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/tidwall/gjson"
+)
+
+// onFineTuneTaskSuccess 微调成功后将产出的模型加入任务所属渠道，使其可直接调用
+func onFineTuneTaskSuccess(ctx context.Context, task *model.Task) {
+	if !operation_setting.GetFineTuneSetting().AutoAddModel {
+		return
+	}
+	modelName := gjson.GetBytes(task.Data, "fine_tuned_model").String()
+	if modelName == "" {
+		return
+	}
+	added, err := model.AddChannelModel(task.ChannelId, modelName)
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("add fine-tuned model %s to channel #%d failed: %s", modelName, task.ChannelId, err.Error()))
+		return
+	}
+	if added {
+		model.InitChannelCache()
+		logger.LogInfo(ctx, fmt.Sprintf("fine-tuned model %s added to channel #%d", modelName, task.ChannelId))
+	}
+}
+
+// DoFineTuneJobRequest 使用任务所属渠道及创建时的 key 请求上游微调任务接口，subPath 形如 "/cancel"
+func DoFineTuneJobRequest(ctx context.Context, task *model.Task, method string, subPath string, rawQuery string) (*http.Response, error) {
+	channel, err := model.CacheGetChannel(task.ChannelId)
+	if err != nil {
+		return nil, fmt.Errorf("channel #%d of task is unavailable: %w", task.ChannelId, err)
+	}
+	key := task.PrivateData.Key
+	if key == "" {
+		nextKey, _, apiErr := channel.GetNextEnabledKey()
+		if apiErr != nil {
+			return nil, apiErr
+		}
+		key = nextKey
+	}
+	path := "/fine_tuning/jobs/" + task.GetUpstreamTaskID() + subPath
+	return doOpenAIUpstreamRequest(ctx, channel, key, method, path, rawQuery, nil, "")
+}
@@ -7,12 +7,12 @@ import (
 )
 
 // ---------------------------------------------------------------------------
-// FundingSource — 资金来源接口（钱包 or 订阅）
+// FundingSource — 资金来源接口（钱包 / 订阅 / 组织）
 // ---------------------------------------------------------------------------
 
 // FundingSource 抽象了预扣费的资金来源。
 type FundingSource interface {
-	// Source 返回资金来源标识："wallet" 或 "subscription"
+	// Source 返回资金来源标识："wallet"、"subscription" 或 "organization"
 	Source() string
 	// PreConsume 从该资金来源预扣 amount 额度
 	PreConsume(amount int) error
@@ -117,6 +117,39 @@ func (s *SubscriptionFunding) Refund() error {
 	})
 }
 
+// ---------------------------------------------------------------------------
+// OrganizationFunding — 组织共享钱包资金来源实现
+// ---------------------------------------------------------------------------
+
+type OrganizationFunding struct {
+	organizationId int
+	userId         int
+	consumed       int // 实际预扣的组织额度
+}
+
+func (o *OrganizationFunding) Source() string { return BillingSourceOrganization }
+
+func (o *OrganizationFunding) PreConsume(amount int) error {
+	// amount 为 0 时仍需调用，用于校验组织状态与成员身份
+	if err := model.PreConsumeOrganizationQuota(o.organizationId, o.userId, amount); err != nil {
+		return err
+	}
+	o.consumed = amount
+	return nil
+}
+
+func (o *OrganizationFunding) Settle(delta int) error {
+	return model.AdjustOrganizationQuota(o.organizationId, o.userId, delta)
+}
+
+func (o *OrganizationFunding) Refund() error {
+	if o.consumed <= 0 {
+		return nil
+	}
+	// 与钱包相同，quota += N 非幂等，不能重试
+	return model.AdjustOrganizationQuota(o.organizationId, o.userId, -o.consumed)
+}
+
 // refundWithRetry 尝试多次执行退款操作以提高成功率，只能用于基于事务的退款函数！！！！！！
 // try to refund with retries, only for refund functions based on transactions!!!
 func refundWithRetry(fn func() error) error {
@@ -7,6 +7,7 @@ import (
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
 	"github.com/QuantumNous/new-api/types"
 
 	"github.com/gin-gonic/gin"
@@ -45,6 +46,17 @@ func GenerateTextOtherInfo(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, m
 	if relayInfo.ReasoningEffort != "" {
 		other["reasoning_effort"] = relayInfo.ReasoningEffort
 	}
+	if relayInfo.BatchId != "" {
+		other["batch_id"] = relayInfo.BatchId
+		other["batch_discount_ratio"] = operation_setting.GetBatchDiscountRatio()
+	}
+	if tier := relayInfo.PriceData.PricingTierName(); tier != "" {
+		other["pricing_tier"] = tier
+	}
+	if relayInfo.ResponseCacheHit {
+		other["response_cache_hit"] = true
+		other["response_cache_hit_ratio"] = operation_setting.GetResponseCacheHitRatio()
+	}
 	if relayInfo.IsModelMapped {
 		other["is_model_mapped"] = true
 		other["upstream_model_name"] = relayInfo.UpstreamModelName
@@ -68,6 +80,10 @@ func GenerateTextOtherInfo(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, m
 		adminInfo["local_count_tokens"] = isLocalCountTokens
 	}
 
+	if relayInfo.PayloadLogged {
+		adminInfo["payload_logged"] = true
+	}
+
 	AppendChannelAffinityAdminInfo(ctx, adminInfo)
 
 	other["admin_info"] = adminInfo
@@ -211,6 +227,9 @@ func GenerateMjOtherInfo(relayInfo *relaycommon.RelayInfo, priceData types.Price
 	if priceData.GroupRatioInfo.HasSpecialRatio {
 		other["user_group_ratio"] = priceData.GroupRatioInfo.GroupSpecialRatio
 	}
+	if tier := priceData.PricingTierName(); tier != "" {
+		other["pricing_tier"] = tier
+	}
 	appendRequestPath(nil, relayInfo, other)
 	return other
 }
@@ -0,0 +1,799 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	relayconstant "github.com/QuantumNous/new-api/relay/constant"
+	"github.com/QuantumNous/new-api/setting"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const (
+	ModerationStagePrompt     = "prompt"
+	ModerationStageCompletion = "completion"
+
+	moderationRedacted = "**###**"
+	// moderationOverlapRunes 流式增量审核时与上一段重叠的字符数，避免命中内容恰好跨越两段
+	moderationOverlapRunes = 32
+)
+
+// ModerationInput 待审核的内容
+type ModerationInput struct {
+	Text      string
+	ImageURLs []string
+}
+
+// ModerationProvider 内容审核提供方，可通过 RegisterModerationProvider 扩展
+type ModerationProvider interface {
+	Name() string
+	// Check 审核内容，返回命中的类别（敏感词、规则名或审核接口返回的类别），未命中时返回空
+	Check(c *gin.Context, info *relaycommon.RelayInfo, input *ModerationInput) ([]string, error)
+	// Redact 替换文本中命中的内容，无法定位命中内容的提供方返回 false
+	Redact(text string) (string, bool)
+}
+
+// ModerationResult 审核命中结果，记录在日志的 other.moderation 中
+type ModerationResult struct {
+	Stage      string   `json:"stage"`
+	Provider   string   `json:"provider"`
+	Action     string   `json:"action"`
+	Categories []string `json:"categories"`
+
+	redactable bool
+}
+
+var moderationProviders = map[string]ModerationProvider{}
+
+// RegisterModerationProvider 注册审核提供方，名称与配置中的 providers 对应
+func RegisterModerationProvider(provider ModerationProvider) {
+	moderationProviders[provider.Name()] = provider
+}
+
+func init() {
+	RegisterModerationProvider(sensitiveWordsModerationProvider{})
+	RegisterModerationProvider(regexModerationProvider{})
+	RegisterModerationProvider(apiModerationProvider{})
+}
+
+// ModerationRequestHandler 将审核接口请求交给 HTTP 路由执行（完整经过鉴权、分发、relay、计费流程）。
+// 由 main 在路由初始化后注入，避免 service -> router 循环依赖
+var ModerationRequestHandler func(w http.ResponseWriter, req *http.Request)
+
+// IsModerationEnabled 是否启用内容审核
+func IsModerationEnabled() bool {
+	setting := operation_setting.GetModerationSetting()
+	return setting.Enabled && len(setting.Providers) > 0
+}
+
+// IsModerationCheckingSensitiveWords 内容审核是否包含敏感词提供方，包含时替代原有的敏感词检查
+func IsModerationCheckingSensitiveWords() bool {
+	return IsModerationEnabled() && slices.Contains(operation_setting.GetModerationSetting().Providers, operation_setting.ModerationProviderSensitiveWords)
+}
+
+// runModeration 依次执行配置的审核提供方，返回第一个命中结果
+func runModeration(c *gin.Context, info *relaycommon.RelayInfo, input *ModerationInput) *ModerationResult {
+	if input.Text == "" && len(input.ImageURLs) == 0 {
+		return nil
+	}
+	setting := operation_setting.GetModerationSetting()
+	for _, name := range setting.Providers {
+		provider, ok := moderationProviders[name]
+		if !ok {
+			continue
+		}
+		categories, err := provider.Check(c, info, input)
+		if err != nil {
+			logger.LogWarn(c, fmt.Sprintf("moderation provider %s failed: %v", name, err))
+			if setting.ApiFailOpen {
+				continue
+			}
+			categories = []string{"moderation_error"}
+		}
+		if len(categories) == 0 {
+			continue
+		}
+		_, redactable := provider.Redact("")
+		return &ModerationResult{
+			Provider:   name,
+			Categories: categories,
+			redactable: redactable && err == nil,
+		}
+	}
+	return nil
+}
+
+// redactModerationText 使用所有可定位命中内容的提供方替换文本
+func redactModerationText(text string) string {
+	for _, name := range operation_setting.GetModerationSetting().Providers {
+		if provider, ok := moderationProviders[name]; ok {
+			if redacted, ok := provider.Redact(text); ok {
+				text = redacted
+			}
+		}
+	}
+	return text
+}
+
+// redactModerationJSON 替换 JSON 中所有字符串值内命中的内容，返回替换后的内容与是否有改动
+func redactModerationJSON(body []byte) ([]byte, bool) {
+	var paths, values []string
+	var walk func(node gjson.Result, prefix string)
+	walk = func(node gjson.Result, prefix string) {
+		switch {
+		case node.IsObject() || node.IsArray():
+			idx := 0
+			node.ForEach(func(key, value gjson.Result) bool {
+				name := key.String()
+				if node.IsArray() {
+					name = strconv.Itoa(idx)
+				}
+				idx++
+				name = escapeJSONPathKey(name)
+				if prefix != "" {
+					name = prefix + "." + name
+				}
+				walk(value, name)
+				return true
+			})
+		case node.Type == gjson.String:
+			if redacted := redactModerationText(node.Str); redacted != node.Str {
+				paths = append(paths, prefix)
+				values = append(values, redacted)
+			}
+		}
+	}
+	walk(gjson.ParseBytes(body), "")
+	changed := false
+	for i, path := range paths {
+		if redacted, err := sjson.SetBytes(body, path, values[i]); err == nil {
+			body = redacted
+			changed = true
+		}
+	}
+	return body, changed
+}
+
+// recordModerationViolation 记录审核命中日志
+func recordModerationViolation(c *gin.Context, info *relaycommon.RelayInfo, result *ModerationResult) {
+	logger.LogWarn(c, fmt.Sprintf("moderation flagged: stage=%s, provider=%s, action=%s, categories=%s",
+		result.Stage, result.Provider, result.Action, strings.Join(result.Categories, ", ")))
+	other := map[string]interface{}{
+		"moderation": result,
+	}
+	if c.Request != nil && c.Request.URL != nil {
+		other["request_path"] = c.Request.URL.Path
+	}
+	channelId := 0
+	if info.ChannelMeta != nil {
+		channelId = info.ChannelId
+	}
+	content := fmt.Sprintf("内容审核命中（%s），提供方：%s，处理方式：%s", result.Stage, result.Provider, result.Action)
+	model.RecordModerationLog(c, info.UserId, channelId, info.OriginModelName, c.GetString("token_name"), content,
+		info.TokenId, info.IsStream, info.UsingGroup, other)
+}
+
+func newModerationBlockedError(stage string) *types.NewAPIError {
+	message := "request content violates the content policy"
+	if stage == ModerationStageCompletion {
+		message = "response content violates the content policy"
+	}
+	return types.NewErrorWithStatusCode(errors.New(message), types.ErrorCodeSensitiveWordsDetected, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
+}
+
+// moderationImageURLs 提取请求中的图片，base64 图片转换为 data URL
+func moderationImageURLs(files []*types.FileMeta) []string {
+	var urls []string
+	for _, file := range files {
+		if file == nil || file.FileType != types.FileTypeImage || file.Source == nil {
+			continue
+		}
+		switch {
+		case file.Source.URL != "":
+			urls = append(urls, file.Source.URL)
+		case strings.HasPrefix(file.Source.Base64Data, "data:"):
+			urls = append(urls, file.Source.Base64Data)
+		case file.Source.Base64Data != "":
+			mimeType := file.Source.MimeType
+			if mimeType == "" {
+				mimeType = "image/png"
+			}
+			urls = append(urls, "data:"+mimeType+";base64,"+file.Source.Base64Data)
+		}
+	}
+	return urls
+}
+
+// ModeratePrompt 审核请求内容，命中后按分组策略拦截、脱敏或仅记录。
+// 返回 true 表示请求体已脱敏，调用方需重新解析请求
+func ModeratePrompt(c *gin.Context, info *relaycommon.RelayInfo, meta *types.TokenCountMeta) (bool, *types.NewAPIError) {
+	if meta == nil {
+		return false, nil
+	}
+	result := runModeration(c, info, &ModerationInput{Text: meta.CombineText, ImageURLs: moderationImageURLs(meta.Files)})
+	if result == nil {
+		return false, nil
+	}
+	result.Stage = ModerationStagePrompt
+	result.Action = operation_setting.GetModerationAction(info.UsingGroup)
+	redacted := false
+	if result.Action == operation_setting.ModerationActionRedact {
+		// 审核接口无法定位命中内容、请求体不是 JSON 或命中内容不在文本中（如图片）时改为拦截
+		redacted = result.redactable && redactRequestBody(c)
+		if !redacted {
+			result.Action = operation_setting.ModerationActionBlock
+		}
+	}
+	recordModerationViolation(c, info, result)
+	if result.Action == operation_setting.ModerationActionBlock {
+		return false, newModerationBlockedError(ModerationStagePrompt)
+	}
+	return redacted, nil
+}
+
+func redactRequestBody(c *gin.Context) bool {
+	storage, err := common.GetBodyStorage(c)
+	if err != nil {
+		return false
+	}
+	body, err := storage.Bytes()
+	if err != nil || !gjson.ValidBytes(body) {
+		return false
+	}
+	redacted, changed := redactModerationJSON(bytes.Clone(body))
+	if !changed {
+		return false
+	}
+	return common.ReplaceBodyStorage(c, redacted) == nil
+}
+
+// sensitiveWordsModerationProvider 使用敏感词列表审核
+type sensitiveWordsModerationProvider struct{}
+
+func (sensitiveWordsModerationProvider) Name() string {
+	return operation_setting.ModerationProviderSensitiveWords
+}
+
+func (sensitiveWordsModerationProvider) Check(_ *gin.Context, _ *relaycommon.RelayInfo, input *ModerationInput) ([]string, error) {
+	contains, words := SensitiveWordContains(input.Text)
+	if !contains {
+		return nil, nil
+	}
+	slices.Sort(words)
+	return slices.Compact(words), nil
+}
+
+// Redact 按字符位置替换命中的敏感词，重叠的命中合并为一处
+func (sensitiveWordsModerationProvider) Redact(text string) (string, bool) {
+	if text == "" || len(setting.SensitiveWords) == 0 {
+		return text, true
+	}
+	m := getOrBuildAC(setting.SensitiveWords)
+	if m == nil {
+		return text, true
+	}
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	hits := m.MultiPatternSearch(lower, false)
+	if len(hits) == 0 {
+		return text, true
+	}
+	masked := make([]bool, len(runes))
+	for _, hit := range hits {
+		for i := hit.Pos; i < hit.Pos+len(hit.Word) && i < len(runes); i++ {
+			masked[i] = true
+		}
+	}
+	var b strings.Builder
+	b.Grow(len(text))
+	for i := 0; i < len(runes); i++ {
+		if !masked[i] {
+			b.WriteRune(runes[i])
+			continue
+		}
+		b.WriteString(moderationRedacted)
+		for i+1 < len(runes) && masked[i+1] {
+			i++
+		}
+	}
+	return b.String(), true
+}
+
+// regexModerationProvider 使用正则规则审核
+type regexModerationProvider struct{}
+
+func (regexModerationProvider) Name() string {
+	return operation_setting.ModerationProviderRegex
+}
+
+func (regexModerationProvider) Check(_ *gin.Context, _ *relaycommon.RelayInfo, input *ModerationInput) ([]string, error) {
+	var names []string
+	for _, rule := range operation_setting.GetModerationRegexps() {
+		if rule.Re.MatchString(input.Text) {
+			names = append(names, rule.Name)
+		}
+	}
+	return names, nil
+}
+
+func (regexModerationProvider) Redact(text string) (string, bool) {
+	for _, rule := range operation_setting.GetModerationRegexps() {
+		text = rule.Re.ReplaceAllString(text, moderationRedacted)
+	}
+	return text, true
+}
+
+// apiModerationProvider 通过渠道的 /v1/moderations 接口审核，支持图片
+type apiModerationProvider struct{}
+
+type moderationAPIResponse struct {
+	Results []struct {
+		Flagged    bool            `json:"flagged"`
+		Categories map[string]bool `json:"categories"`
+	} `json:"results"`
+}
+
+func (apiModerationProvider) Name() string {
+	return operation_setting.ModerationProviderAPI
+}
+
+func (apiModerationProvider) Check(c *gin.Context, info *relaycommon.RelayInfo, input *ModerationInput) ([]string, error) {
+	setting := operation_setting.GetModerationSetting()
+	if setting.ApiModel == "" {
+		return nil, errors.New("moderation model is not configured")
+	}
+	if ModerationRequestHandler == nil {
+		return nil, errors.New("moderation request handler is not initialized")
+	}
+	if info.TokenKey == "" {
+		return nil, errors.New("moderation api requires a token")
+	}
+
+	var requestInput any = input.Text
+	if len(input.ImageURLs) > 0 {
+		parts := make([]map[string]any, 0, len(input.ImageURLs)+1)
+		if input.Text != "" {
+			parts = append(parts, map[string]any{"type": "text", "text": input.Text})
+		}
+		for _, url := range input.ImageURLs {
+			parts = append(parts, map[string]any{"type": "image_url", "image_url": map[string]string{"url": url}})
+		}
+		requestInput = parts
+	}
+	body, err := common.Marshal(map[string]any{"model": setting.ApiModel, "input": requestInput})
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := time.Duration(setting.ApiTimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(relaycommon.WithModerationRequest(c.Request.Context(), setting.ApiGroup), timeout)
+	defer cancel()
+	// 与批处理一样通过路由重新分发，使用当前请求的令牌完成渠道选择、重试、计费与日志
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/v1/moderations", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer sk-"+info.TokenKey)
+	req.RemoteAddr = net.JoinHostPort(c.ClientIP(), "0")
+	recorder := httptest.NewRecorder()
+	ModerationRequestHandler(recorder, req)
+	respBody := recorder.Body.Bytes()
+	if recorder.Code != http.StatusOK {
+		return nil, fmt.Errorf("moderation api returned status %d: %s", recorder.Code, string(respBody))
+	}
+	var result moderationAPIResponse
+	if err = common.Unmarshal(respBody, &result); err != nil {
+		return nil, err
+	}
+	var categories []string
+	for _, item := range result.Results {
+		if !item.Flagged {
+			continue
+		}
+		for category, hit := range item.Categories {
+			if hit && !slices.Contains(categories, category) {
+				categories = append(categories, category)
+			}
+		}
+		if len(categories) == 0 {
+			categories = append(categories, "flagged")
+		}
+	}
+	slices.Sort(categories)
+	return categories, nil
+}
+
+// Redact 审核接口无法定位命中内容
+func (apiModerationProvider) Redact(text string) (string, bool) {
+	return text, false
+}
+
+// ShouldModerateCompletion 是否需要审核该请求的模型输出，仅支持文本对话类接口
+func ShouldModerateCompletion(info *relaycommon.RelayInfo) bool {
+	if !IsModerationEnabled() || !operation_setting.GetModerationSetting().CheckCompletion {
+		return false
+	}
+	switch info.RelayFormat {
+	case types.RelayFormatOpenAI:
+		return info.RelayMode == relayconstant.RelayModeChatCompletions || info.RelayMode == relayconstant.RelayModeCompletions
+	case types.RelayFormatClaude, types.RelayFormatOpenAIResponses:
+		return true
+	case types.RelayFormatGemini:
+		return !strings.Contains(info.RequestURLPath, "embed")
+	}
+	return false
+}
+
+// ModerationWriter 审核写回客户端的模型输出。
+// 流式响应按行暂存，累计到一定字符数后审核一次，通过后再下发；非流式响应在结束时整体审核
+type ModerationWriter struct {
+	gin.ResponseWriter
+	origin gin.ResponseWriter
+	c      *gin.Context
+	info   *relaycommon.RelayInfo
+	action string
+	stream bool
+
+	// 流式状态
+	line        []byte
+	held        bytes.Buffer
+	pending     strings.Builder
+	checkedTail string
+	checkChars  int
+	blocked     bool
+	flagged     bool
+
+	// 非流式响应体
+	body bytes.Buffer
+}
+
+// StartCompletionModeration 开始审核模型输出，不需要审核时返回 nil
+func StartCompletionModeration(c *gin.Context, info *relaycommon.RelayInfo) *ModerationWriter {
+	if !ShouldModerateCompletion(info) {
+		return nil
+	}
+	checkChars := operation_setting.GetModerationSetting().StreamCheckChars
+	if checkChars <= 0 {
+		checkChars = 200
+	}
+	writer := &ModerationWriter{
+		ResponseWriter: c.Writer,
+		origin:         c.Writer,
+		c:              c,
+		info:           info,
+		action:         operation_setting.GetModerationAction(info.UsingGroup),
+		stream:         info.IsStream,
+		checkChars:     checkChars,
+	}
+	c.Writer = writer
+	return writer
+}
+
+func (w *ModerationWriter) Write(data []byte) (int, error) {
+	if w.blocked {
+		return len(data), nil
+	}
+	if !w.stream {
+		return w.body.Write(data)
+	}
+	w.line = append(w.line, data...)
+	for {
+		idx := bytes.IndexByte(w.line, '\n')
+		if idx < 0 {
+			break
+		}
+		line := bytes.Clone(w.line[:idx+1])
+		w.line = w.line[idx+1:]
+		if err := w.handleLine(line); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+func (w *ModerationWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow 非流式响应在审核完成前不发送响应头，以便拦截时改写状态码
+func (w *ModerationWriter) WriteHeaderNow() {
+	if w.stream {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *ModerationWriter) Flush() {
+	if w.stream {
+		w.ResponseWriter.Flush()
+	}
+}
+
+func (w *ModerationWriter) handleLine(line []byte) error {
+	if w.blocked {
+		return nil
+	}
+	text, isData := moderationStreamText(line)
+	if w.action == operation_setting.ModerationActionLog {
+		// 仅记录时不暂存，边下发边审核
+		if _, err := w.origin.Write(line); err != nil {
+			return err
+		}
+		if !w.flagged && text != "" {
+			w.pending.WriteString(text)
+			if w.pending.Len() >= w.checkChars {
+				w.check()
+			}
+		}
+		return nil
+	}
+	w.held.Write(line)
+	if text != "" {
+		w.pending.WriteString(text)
+		if w.pending.Len() >= w.checkChars {
+			return w.check()
+		}
+		return nil
+	}
+	// 结束、用量等不含文本的事件到达时审核剩余内容
+	if isData || w.pending.Len() == 0 {
+		return w.check()
+	}
+	return nil
+}
+
+// check 审核暂存的内容，通过后下发
+func (w *ModerationWriter) check() error {
+	if w.pending.Len() > 0 {
+		window := w.checkedTail + w.pending.String()
+		w.pending.Reset()
+		result := runModeration(w.c, w.info, &ModerationInput{Text: window})
+		if result != nil {
+			w.handleFlagged(result)
+			if result.Action == operation_setting.ModerationActionRedact {
+				// 重叠部分已按脱敏后的内容下发，避免下一段再次命中
+				window = redactModerationText(window)
+			}
+		}
+		runes := []rune(window)
+		w.checkedTail = string(runes[max(0, len(runes)-moderationOverlapRunes):])
+	}
+	if w.blocked || w.held.Len() == 0 {
+		return nil
+	}
+	_, err := w.origin.Write(w.held.Bytes())
+	w.held.Reset()
+	return err
+}
+
+func (w *ModerationWriter) handleFlagged(result *ModerationResult) {
+	result.Stage = ModerationStageCompletion
+	result.Action = w.action
+	if w.action == operation_setting.ModerationActionRedact && !(result.redactable && w.redactHeld()) {
+		result.Action = operation_setting.ModerationActionBlock
+	}
+	if !w.flagged {
+		w.flagged = true
+		recordModerationViolation(w.c, w.info, result)
+	}
+	if result.Action == operation_setting.ModerationActionBlock {
+		w.blocked = true
+		w.held.Reset()
+		w.writeStreamError()
+	}
+}
+
+// redactHeld 替换暂存事件中命中的内容，命中内容跨越多个事件而无法替换时返回 false
+func (w *ModerationWriter) redactHeld() bool {
+	var out bytes.Buffer
+	changed := false
+	for _, line := range bytes.SplitAfter(w.held.Bytes(), []byte("\n")) {
+		data, ok := sseDataPayload(line)
+		if !ok || !gjson.ValidBytes(data) {
+			out.Write(line)
+			continue
+		}
+		redacted, lineChanged := redactModerationJSON(bytes.Clone(data))
+		if !lineChanged {
+			out.Write(line)
+			continue
+		}
+		changed = true
+		out.WriteString("data: ")
+		out.Write(redacted)
+		out.WriteByte('\n')
+	}
+	if changed {
+		w.held = out
+	}
+	return changed
+}
+
+func (w *ModerationWriter) writeStreamError() {
+	apiErr := newModerationBlockedError(ModerationStageCompletion)
+	var event string
+	switch w.info.RelayFormat {
+	case types.RelayFormatClaude:
+		data, _ := common.Marshal(map[string]any{"type": "error", "error": apiErr.ToClaudeError()})
+		event = "event: error\ndata: " + string(data) + "\n\n"
+	default:
+		data, _ := common.Marshal(map[string]any{"error": apiErr.ToOpenAIError()})
+		event = "data: " + string(data) + "\n\n"
+		if w.info.RelayFormat == types.RelayFormatOpenAI {
+			event += "data: [DONE]\n\n"
+		}
+	}
+	_, _ = w.origin.WriteString(event)
+	w.origin.Flush()
+}
+
+// Finish 审核剩余内容并恢复原始的 ResponseWriter，需在写回错误响应之前调用
+func (w *ModerationWriter) Finish() {
+	if w == nil {
+		return
+	}
+	defer func() {
+		if w.c.Writer == w {
+			w.c.Writer = w.origin
+		}
+	}()
+	if w.stream {
+		if len(w.line) > 0 && !w.blocked {
+			_ = w.handleLine(w.line)
+			w.line = nil
+		}
+		if !w.blocked {
+			_ = w.check()
+		}
+		w.origin.Flush()
+		return
+	}
+	w.finishNonStream()
+}
+
+func (w *ModerationWriter) finishNonStream() {
+	body := w.body.Bytes()
+	if len(body) == 0 {
+		return
+	}
+	var result *ModerationResult
+	if w.origin.Status() < http.StatusBadRequest {
+		result = runModeration(w.c, w.info, &ModerationInput{Text: moderationCompletionText(body)})
+	}
+	if result != nil {
+		result.Stage = ModerationStageCompletion
+		result.Action = w.action
+		if w.action == operation_setting.ModerationActionRedact {
+			redacted, changed := body, false
+			if result.redactable && gjson.ValidBytes(body) {
+				redacted, changed = redactModerationJSON(bytes.Clone(body))
+			}
+			if changed {
+				body = redacted
+			} else {
+				result.Action = operation_setting.ModerationActionBlock
+			}
+		}
+		recordModerationViolation(w.c, w.info, result)
+		if result.Action == operation_setting.ModerationActionBlock {
+			// 上游已生成内容，仍按正常请求计费
+			apiErr := newModerationBlockedError(ModerationStageCompletion)
+			var payload any = map[string]any{"error": apiErr.ToOpenAIError()}
+			if w.info.RelayFormat == types.RelayFormatClaude {
+				payload = map[string]any{"type": "error", "error": apiErr.ToClaudeError()}
+			}
+			body, _ = common.Marshal(payload)
+			w.origin.Header().Set("Content-Type", "application/json")
+			w.origin.WriteHeader(apiErr.StatusCode)
+		}
+	}
+	if w.origin.Header().Get("Content-Length") != "" {
+		w.origin.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	}
+	_, _ = w.origin.Write(body)
+}
+
+// sseDataPayload 返回 SSE data 行的内容
+func sseDataPayload(line []byte) ([]byte, bool) {
+	line = bytes.TrimRight(line, "\r\n")
+	if !bytes.HasPrefix(line, []byte("data:")) {
+		return nil, false
+	}
+	return bytes.TrimSpace(line[len("data:"):]), true
+}
+
+// moderationStreamText 提取流式事件中的输出文本，第二个返回值表示是否为 data 行
+func moderationStreamText(line []byte) (string, bool) {
+	data, ok := sseDataPayload(line)
+	if !ok {
+		return "", false
+	}
+	if !gjson.ValidBytes(data) {
+		return "", true
+	}
+	event := gjson.ParseBytes(data)
+	var b strings.Builder
+	event.Get("choices").ForEach(func(_, choice gjson.Result) bool {
+		b.WriteString(choice.Get("delta.content").String())
+		b.WriteString(choice.Get("text").String())
+		return true
+	})
+	event.Get("candidates").ForEach(func(_, candidate gjson.Result) bool {
+		candidate.Get("content.parts").ForEach(func(_, part gjson.Result) bool {
+			b.WriteString(part.Get("text").String())
+			return true
+		})
+		return true
+	})
+	switch event.Get("type").String() {
+	case "content_block_delta":
+		b.WriteString(event.Get("delta.text").String())
+	case "response.output_text.delta":
+		b.WriteString(event.Get("delta").String())
+	}
+	return b.String(), true
+}
+
+// moderationCompletionText 提取非流式响应中的输出文本，支持 OpenAI、Claude、Gemini 与 Responses 格式
+func moderationCompletionText(body []byte) string {
+	if !gjson.ValidBytes(body) {
+		return ""
+	}
+	resp := gjson.ParseBytes(body)
+	var b strings.Builder
+	add := func(value gjson.Result) {
+		if value.Type == gjson.String && value.Str != "" {
+			b.WriteString(value.Str)
+			b.WriteByte('\n')
+		}
+	}
+	resp.Get("choices").ForEach(func(_, choice gjson.Result) bool {
+		add(choice.Get("message.content"))
+		add(choice.Get("text"))
+		return true
+	})
+	resp.Get("content").ForEach(func(_, block gjson.Result) bool {
+		add(block.Get("text"))
+		return true
+	})
+	resp.Get("candidates").ForEach(func(_, candidate gjson.Result) bool {
+		candidate.Get("content.parts").ForEach(func(_, part gjson.Result) bool {
+			add(part.Get("text"))
+			return true
+		})
+		return true
+	})
+	resp.Get("output").ForEach(func(_, item gjson.Result) bool {
+		item.Get("content").ForEach(func(_, part gjson.Result) bool {
+			add(part.Get("text"))
+			return true
+		})
+		return true
+	})
+	return b.String()
+}
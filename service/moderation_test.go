@@ -0,0 +1,103 @@
+package service
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModerationRedact(t *testing.T) {
+	moderation := operation_setting.GetModerationSetting()
+	originalProviders := moderation.Providers
+	originalRules := moderation.RegexRules
+	originalWords := setting.SensitiveWords
+	moderation.Providers = []string{operation_setting.ModerationProviderSensitiveWords, operation_setting.ModerationProviderRegex}
+	moderation.RegexRules = []operation_setting.ModerationRegexRule{{Name: "phone", Pattern: `1\d{10}`}}
+	setting.SensitiveWords = []string{"违禁词", "bad"}
+	t.Cleanup(func() {
+		moderation.Providers = originalProviders
+		moderation.RegexRules = originalRules
+		setting.SensitiveWords = originalWords
+	})
+
+	// 按字符位置替换，非 ASCII 与大小写不影响定位
+	require.Equal(t, "这是**###**，**###** word", redactModerationText("这是违禁词，BAD word"))
+	require.Equal(t, "call **###**", redactModerationText("call 13800138000"))
+
+	body := `{"model":"gpt-4o","messages":[{"role":"user","content":[{"type":"text","text":"说个违禁词"}]}]}`
+	redacted, changed := redactModerationJSON([]byte(body))
+	require.True(t, changed)
+	require.Equal(t, `{"model":"gpt-4o","messages":[{"role":"user","content":[{"type":"text","text":"说个**###**"}]}]}`, string(redacted))
+
+	_, changed = redactModerationJSON([]byte(`{"messages":[{"content":"hello"}]}`))
+	require.False(t, changed)
+}
+
+func TestModerationCompletionText(t *testing.T) {
+	text, isData := moderationStreamText([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"Hi\"}}]}\n"))
+	require.True(t, isData)
+	require.Equal(t, "Hi", text)
+	text, _ = moderationStreamText([]byte("data: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"there\"}}\n"))
+	require.Equal(t, "there", text)
+	text, isData = moderationStreamText([]byte("data: [DONE]\n"))
+	require.True(t, isData)
+	require.Empty(t, text)
+	_, isData = moderationStreamText([]byte("event: ping\n"))
+	require.False(t, isData)
+
+	require.Equal(t, "Hello\n", moderationCompletionText([]byte(`{"choices":[{"message":{"role":"assistant","content":"Hello"}}]}`)))
+	require.Equal(t, "a\nb\n", moderationCompletionText([]byte(`{"candidates":[{"content":{"parts":[{"text":"a"},{"text":"b"}]}}]}`)))
+	require.Equal(t, "c\n", moderationCompletionText([]byte(`{"output":[{"type":"message","content":[{"type":"output_text","text":"c"}]}]}`)))
+}
+
+func TestApiModerationDispatchesThroughRelay(t *testing.T) {
+	moderation := operation_setting.GetModerationSetting()
+	originalModel := moderation.ApiModel
+	originalGroup := moderation.ApiGroup
+	originalHandler := ModerationRequestHandler
+	moderation.ApiModel = "omni-moderation-latest"
+	moderation.ApiGroup = "moderation"
+	t.Cleanup(func() {
+		moderation.ApiModel = originalModel
+		moderation.ApiGroup = originalGroup
+		ModerationRequestHandler = originalHandler
+	})
+
+	// 审核请求以当前令牌重新进入路由，并携带审核分组
+	ModerationRequestHandler = func(w http.ResponseWriter, req *http.Request) {
+		require.Equal(t, "/v1/moderations", req.URL.Path)
+		require.Equal(t, "Bearer sk-moderation-token", req.Header.Get("Authorization"))
+		group, ok := relaycommon.GetModerationGroup(req.Context())
+		require.True(t, ok)
+		require.Equal(t, "moderation", group)
+		body, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		var payload map[string]any
+		require.NoError(t, common.Unmarshal(body, &payload))
+		require.Equal(t, "omni-moderation-latest", payload["model"])
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"results":[{"flagged":true,"categories":{"violence":true,"hate":false}}]}`))
+	}
+
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	info := &relaycommon.RelayInfo{TokenKey: "moderation-token"}
+	categories, err := apiModerationProvider{}.Check(c, info, &ModerationInput{Text: "hello"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"violence"}, categories)
+
+	ModerationRequestHandler = func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_, err = apiModerationProvider{}.Check(c, info, &ModerationInput{Text: "hello"})
+	require.ErrorContains(t, err, "status 503")
+}
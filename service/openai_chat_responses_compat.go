@@ -1,6 +1,8 @@
 package service
 
 import (
+	"github.com/goccy/go-json"
+
 	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/service/openaicompat"
 )
@@ -16,3 +18,29 @@ func ResponsesResponseToChatCompletionsResponse(resp *dto.OpenAIResponsesRespons
 func ExtractOutputTextFromResponses(resp *dto.OpenAIResponsesResponse) string {
 	return openaicompat.ExtractOutputTextFromResponses(resp)
 }
+
+func ResponsesInputItems(input json.RawMessage) ([]json.RawMessage, error) {
+	return openaicompat.ResponsesInputItems(input)
+}
+
+func ResponsesInputToChatMessages(input json.RawMessage) ([]dto.Message, error) {
+	return openaicompat.ResponsesInputToChatMessages(input)
+}
+
+func ResponsesRequestToChatCompletionsRequest(req *dto.OpenAIResponsesRequest, messages []dto.Message) (*dto.GeneralOpenAIRequest, error) {
+	return openaicompat.ResponsesRequestToChatCompletionsRequest(req, messages)
+}
+
+func NewResponsesResponse(id string, model string, req *dto.OpenAIResponsesRequest) *dto.OpenAIResponsesResponse {
+	return openaicompat.NewResponsesResponse(id, model, req)
+}
+
+func ChatCompletionsResponseToResponsesResponse(chatResp *dto.OpenAITextResponse, response *dto.OpenAIResponsesResponse) error {
+	return openaicompat.ChatCompletionsResponseToResponsesResponse(chatResp, response)
+}
+
+func NewChatToResponsesStreamConverter(response *dto.OpenAIResponsesResponse) *ChatToResponsesStreamConverter {
+	return openaicompat.NewChatToResponsesStreamConverter(response)
+}
+
+type ChatToResponsesStreamConverter = openaicompat.ChatToResponsesStreamConverter
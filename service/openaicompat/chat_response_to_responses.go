@@ -0,0 +1,424 @@
+package openaicompat
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/goccy/go-json"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/samber/lo"
+)
+
+const (
+	responsesStatusInProgress = "in_progress"
+	responsesStatusCompleted  = "completed"
+	responsesStatusIncomplete = "incomplete"
+	responsesStatusFailed     = "failed"
+)
+
+func newResponsesItemID(prefix string) string {
+	return fmt.Sprintf("%s_%s", prefix, common.GetUUID())
+}
+
+func responsesStatus(status string) json.RawMessage {
+	raw, _ := common.Marshal(status)
+	return raw
+}
+
+func rawOrDefault(raw json.RawMessage, def string) json.RawMessage {
+	if len(raw) == 0 {
+		return json.RawMessage(def)
+	}
+	return raw
+}
+
+// ResponsesStoreEnabled 未显式指定 store=false 时保存响应，与 OpenAI 默认行为一致
+func ResponsesStoreEnabled(req *dto.OpenAIResponsesRequest) bool {
+	return req == nil || common.GetJsonType(req.Store) != "boolean" || string(req.Store) != "false"
+}
+
+// NewResponsesResponse 构造桥接模式下的 Responses 响应对象，回显请求中的采样与工具参数
+func NewResponsesResponse(id string, model string, req *dto.OpenAIResponsesRequest) *dto.OpenAIResponsesResponse {
+	response := &dto.OpenAIResponsesResponse{
+		ID:                id,
+		Object:            "response",
+		CreatedAt:         int(time.Now().Unix()),
+		Status:            responsesStatus(responsesStatusInProgress),
+		Instructions:      rawOrDefault(req.Instructions, "null"),
+		MaxOutputTokens:   int(lo.FromPtrOr(req.MaxOutputTokens, 0)),
+		Model:             model,
+		Output:            make([]dto.ResponsesOutput, 0),
+		ParallelToolCalls: string(req.ParallelToolCalls) != "false",
+		Reasoning:         req.Reasoning,
+		Store:             ResponsesStoreEnabled(req),
+		Temperature:       lo.FromPtrOr(req.Temperature, 1),
+		ToolChoice:        rawOrDefault(req.ToolChoice, `"auto"`),
+		Tools:             make([]map[string]any, 0),
+		TopP:              lo.FromPtrOr(req.TopP, 1),
+		Truncation:        rawOrDefault(req.Truncation, `"disabled"`),
+		User:              rawOrDefault(req.User, "null"),
+		Metadata:          rawOrDefault(req.Metadata, "{}"),
+	}
+	if req.PreviousResponseID != "" {
+		response.PreviousResponseID, _ = common.Marshal(req.PreviousResponseID)
+	} else {
+		response.PreviousResponseID = json.RawMessage("null")
+	}
+	if len(req.Tools) > 0 {
+		_ = common.Unmarshal(req.Tools, &response.Tools)
+	}
+	return response
+}
+
+func chatUsageToResponsesUsage(usage *dto.Usage) *dto.Usage {
+	if usage == nil {
+		return nil
+	}
+	return &dto.Usage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+		InputTokens:      usage.PromptTokens,
+		OutputTokens:     usage.CompletionTokens,
+		InputTokensDetails: &dto.InputTokenDetails{
+			CachedTokens: usage.PromptTokensDetails.CachedTokens,
+		},
+		OutputTokensDetails: &dto.OutputTokenDetails{
+			ReasoningTokens: usage.CompletionTokenDetails.ReasoningTokens,
+		},
+	}
+}
+
+// finishResponsesResponse 根据 Chat 的 finish_reason 设置最终状态与用量
+func finishResponsesResponse(response *dto.OpenAIResponsesResponse, finishReason string, usage *dto.Usage) {
+	response.Usage = chatUsageToResponsesUsage(usage)
+	if finishReason == "length" {
+		response.Status = responsesStatus(responsesStatusIncomplete)
+		response.IncompleteDetails = &dto.IncompleteDetails{Reason: "max_output_tokens"}
+		return
+	}
+	response.Status = responsesStatus(responsesStatusCompleted)
+}
+
+// ChatCompletionsResponseToResponsesResponse 将 Chat Completions 非流式响应转换为 Responses 输出条目
+func ChatCompletionsResponseToResponsesResponse(chatResp *dto.OpenAITextResponse, response *dto.OpenAIResponsesResponse) error {
+	if chatResp == nil || response == nil {
+		return errors.New("response is nil")
+	}
+	if len(chatResp.Choices) == 0 {
+		return errors.New("chat completions response has no choices")
+	}
+	choice := chatResp.Choices[0]
+	message := choice.Message
+
+	if reasoning := lo.CoalesceOrEmpty(message.ReasoningContent, message.Reasoning); reasoning != "" {
+		response.Output = append(response.Output, dto.ResponsesOutput{
+			Type:    "reasoning",
+			ID:      newResponsesItemID("rs"),
+			Status:  responsesStatusCompleted,
+			Summary: []dto.ResponsesReasoningSummaryPart{{Type: "summary_text", Text: reasoning}},
+		})
+	}
+	if text := message.StringContent(); text != "" {
+		response.Output = append(response.Output, dto.ResponsesOutput{
+			Type:   "message",
+			ID:     newResponsesItemID("msg"),
+			Status: responsesStatusCompleted,
+			Role:   "assistant",
+			Content: []dto.ResponsesOutputContent{
+				{Type: "output_text", Text: text, Annotations: []interface{}{}},
+			},
+		})
+	}
+	for _, toolCall := range message.ParseToolCalls() {
+		response.Output = append(response.Output, dto.ResponsesOutput{
+			Type:      "function_call",
+			ID:        newResponsesItemID("fc"),
+			Status:    responsesStatusCompleted,
+			CallId:    lo.CoalesceOrEmpty(toolCall.ID, newResponsesItemID("call")),
+			Name:      toolCall.Function.Name,
+			Arguments: toolCall.Function.Arguments,
+		})
+	}
+	finishResponsesResponse(response, choice.FinishReason, &chatResp.Usage)
+	return nil
+}
+
+// ChatToResponsesStreamConverter 将 Chat Completions 流式分片转换为 Responses 流式事件
+// 同一时刻只有一个输出条目处于打开状态，新类型的内容到达时先结束当前条目
+type ChatToResponsesStreamConverter struct {
+	response  *dto.OpenAIResponsesResponse
+	sequence  int
+	started   bool
+	current   int
+	toolCalls map[int]int
+	finish    string
+}
+
+func NewChatToResponsesStreamConverter(response *dto.OpenAIResponsesResponse) *ChatToResponsesStreamConverter {
+	return &ChatToResponsesStreamConverter{
+		response:  response,
+		current:   -1,
+		toolCalls: make(map[int]int),
+	}
+}
+
+func (s *ChatToResponsesStreamConverter) Response() *dto.OpenAIResponsesResponse {
+	return s.response
+}
+
+func (s *ChatToResponsesStreamConverter) Started() bool {
+	return s.started
+}
+
+func (s *ChatToResponsesStreamConverter) event(event dto.ResponsesStreamResponse) dto.ResponsesStreamResponse {
+	s.sequence++
+	event.SequenceNumber = s.sequence
+	return event
+}
+
+// snapshot 返回当前响应对象的浅拷贝，避免事件序列化前被后续分片修改
+func (s *ChatToResponsesStreamConverter) snapshot() *dto.OpenAIResponsesResponse {
+	response := *s.response
+	response.Output = append([]dto.ResponsesOutput{}, s.response.Output...)
+	return &response
+}
+
+// Start 输出 response.created 与 response.in_progress 事件，仅首次调用生效
+func (s *ChatToResponsesStreamConverter) Start() []dto.ResponsesStreamResponse {
+	if s.started {
+		return nil
+	}
+	s.started = true
+	return []dto.ResponsesStreamResponse{
+		s.event(dto.ResponsesStreamResponse{Type: "response.created", Response: s.snapshot()}),
+		s.event(dto.ResponsesStreamResponse{Type: "response.in_progress", Response: s.snapshot()}),
+	}
+}
+
+func (s *ChatToResponsesStreamConverter) HandleChunk(chunk *dto.ChatCompletionsStreamResponse) []dto.ResponsesStreamResponse {
+	events := s.Start()
+	for _, choice := range chunk.Choices {
+		if choice.Index != 0 {
+			continue
+		}
+		if reasoning := choice.Delta.GetReasoningContent(); reasoning != "" {
+			events = append(events, s.appendReasoning(reasoning)...)
+		}
+		if content := choice.Delta.GetContentString(); content != "" {
+			events = append(events, s.appendText(content)...)
+		}
+		for _, toolCall := range choice.Delta.ToolCalls {
+			events = append(events, s.appendToolCall(toolCall)...)
+		}
+		if choice.FinishReason != nil && *choice.FinishReason != "" {
+			s.finish = *choice.FinishReason
+		}
+	}
+	return events
+}
+
+func (s *ChatToResponsesStreamConverter) currentType() string {
+	if s.current < 0 {
+		return ""
+	}
+	return s.response.Output[s.current].Type
+}
+
+func (s *ChatToResponsesStreamConverter) openItem(item dto.ResponsesOutput) dto.ResponsesStreamResponse {
+	s.response.Output = append(s.response.Output, item)
+	s.current = len(s.response.Output) - 1
+	added := item
+	return s.event(dto.ResponsesStreamResponse{
+		Type:        dto.ResponsesOutputTypeItemAdded,
+		OutputIndex: lo.ToPtr(s.current),
+		Item:        &added,
+	})
+}
+
+func (s *ChatToResponsesStreamConverter) appendReasoning(delta string) []dto.ResponsesStreamResponse {
+	var events []dto.ResponsesStreamResponse
+	if s.currentType() != "reasoning" {
+		events = append(events, s.closeCurrent()...)
+		events = append(events, s.openItem(dto.ResponsesOutput{
+			Type:    "reasoning",
+			ID:      newResponsesItemID("rs"),
+			Status:  responsesStatusInProgress,
+			Summary: []dto.ResponsesReasoningSummaryPart{},
+		}))
+		item := &s.response.Output[s.current]
+		item.Summary = append(item.Summary, dto.ResponsesReasoningSummaryPart{Type: "summary_text"})
+		events = append(events, s.event(dto.ResponsesStreamResponse{
+			Type:         "response.reasoning_summary_part.added",
+			ItemID:       item.ID,
+			OutputIndex:  lo.ToPtr(s.current),
+			SummaryIndex: lo.ToPtr(0),
+			Part:         &dto.ResponsesReasoningSummaryPart{Type: "summary_text"},
+		}))
+	}
+	item := &s.response.Output[s.current]
+	item.Summary[0].Text += delta
+	return append(events, s.event(dto.ResponsesStreamResponse{
+		Type:         "response.reasoning_summary_text.delta",
+		ItemID:       item.ID,
+		OutputIndex:  lo.ToPtr(s.current),
+		SummaryIndex: lo.ToPtr(0),
+		Delta:        delta,
+	}))
+}
+
+func (s *ChatToResponsesStreamConverter) appendText(delta string) []dto.ResponsesStreamResponse {
+	var events []dto.ResponsesStreamResponse
+	if s.currentType() != "message" {
+		events = append(events, s.closeCurrent()...)
+		events = append(events, s.openItem(dto.ResponsesOutput{
+			Type:    "message",
+			ID:      newResponsesItemID("msg"),
+			Status:  responsesStatusInProgress,
+			Role:    "assistant",
+			Content: []dto.ResponsesOutputContent{},
+		}))
+		item := &s.response.Output[s.current]
+		item.Content = append(item.Content, dto.ResponsesOutputContent{Type: "output_text", Annotations: []interface{}{}})
+		events = append(events, s.event(dto.ResponsesStreamResponse{
+			Type:         "response.content_part.added",
+			ItemID:       item.ID,
+			OutputIndex:  lo.ToPtr(s.current),
+			ContentIndex: lo.ToPtr(0),
+			Part:         &dto.ResponsesReasoningSummaryPart{Type: "output_text"},
+		}))
+	}
+	item := &s.response.Output[s.current]
+	item.Content[0].Text += delta
+	return append(events, s.event(dto.ResponsesStreamResponse{
+		Type:         "response.output_text.delta",
+		ItemID:       item.ID,
+		OutputIndex:  lo.ToPtr(s.current),
+		ContentIndex: lo.ToPtr(0),
+		Delta:        delta,
+	}))
+}
+
+func (s *ChatToResponsesStreamConverter) appendToolCall(toolCall dto.ToolCallResponse) []dto.ResponsesStreamResponse {
+	var events []dto.ResponsesStreamResponse
+	index := lo.FromPtrOr(toolCall.Index, 0)
+	outputIndex, ok := s.toolCalls[index]
+	if !ok {
+		events = append(events, s.closeCurrent()...)
+		events = append(events, s.openItem(dto.ResponsesOutput{
+			Type:   "function_call",
+			ID:     newResponsesItemID("fc"),
+			Status: responsesStatusInProgress,
+			CallId: lo.CoalesceOrEmpty(toolCall.ID, newResponsesItemID("call")),
+			Name:   toolCall.Function.Name,
+		}))
+		outputIndex = s.current
+		s.toolCalls[index] = outputIndex
+	}
+	item := &s.response.Output[outputIndex]
+	if item.Name == "" {
+		item.Name = toolCall.Function.Name
+	}
+	if toolCall.Function.Arguments == "" {
+		return events
+	}
+	item.Arguments += toolCall.Function.Arguments
+	if outputIndex != s.current {
+		// 已结束条目的迟到参数只追加到最终结果中
+		return events
+	}
+	return append(events, s.event(dto.ResponsesStreamResponse{
+		Type:        "response.function_call_arguments.delta",
+		ItemID:      item.ID,
+		OutputIndex: lo.ToPtr(outputIndex),
+		Delta:       toolCall.Function.Arguments,
+	}))
+}
+
+// closeCurrent 结束当前打开的输出条目并输出对应的 done 事件
+func (s *ChatToResponsesStreamConverter) closeCurrent() []dto.ResponsesStreamResponse {
+	if s.current < 0 {
+		return nil
+	}
+	index := s.current
+	s.current = -1
+	item := &s.response.Output[index]
+	item.Status = responsesStatusCompleted
+
+	var events []dto.ResponsesStreamResponse
+	switch item.Type {
+	case "reasoning":
+		text := item.Summary[0].Text
+		events = append(events,
+			s.event(dto.ResponsesStreamResponse{
+				Type:         "response.reasoning_summary_text.done",
+				ItemID:       item.ID,
+				OutputIndex:  lo.ToPtr(index),
+				SummaryIndex: lo.ToPtr(0),
+				Text:         text,
+			}),
+			s.event(dto.ResponsesStreamResponse{
+				Type:         "response.reasoning_summary_part.done",
+				ItemID:       item.ID,
+				OutputIndex:  lo.ToPtr(index),
+				SummaryIndex: lo.ToPtr(0),
+				Part:         &dto.ResponsesReasoningSummaryPart{Type: "summary_text", Text: text},
+			}),
+		)
+	case "message":
+		text := item.Content[0].Text
+		events = append(events,
+			s.event(dto.ResponsesStreamResponse{
+				Type:         "response.output_text.done",
+				ItemID:       item.ID,
+				OutputIndex:  lo.ToPtr(index),
+				ContentIndex: lo.ToPtr(0),
+				Text:         text,
+			}),
+			s.event(dto.ResponsesStreamResponse{
+				Type:         "response.content_part.done",
+				ItemID:       item.ID,
+				OutputIndex:  lo.ToPtr(index),
+				ContentIndex: lo.ToPtr(0),
+				Part:         &dto.ResponsesReasoningSummaryPart{Type: "output_text", Text: text},
+			}),
+		)
+	case "function_call":
+		events = append(events, s.event(dto.ResponsesStreamResponse{
+			Type:        "response.function_call_arguments.done",
+			ItemID:      item.ID,
+			OutputIndex: lo.ToPtr(index),
+			Arguments:   item.Arguments,
+		}))
+	}
+	done := *item
+	return append(events, s.event(dto.ResponsesStreamResponse{
+		Type:        dto.ResponsesOutputTypeItemDone,
+		OutputIndex: lo.ToPtr(index),
+		Item:        &done,
+	}))
+}
+
+// Finish 结束所有输出条目，输出 response.completed（因长度截断时为 response.incomplete）
+func (s *ChatToResponsesStreamConverter) Finish(usage *dto.Usage) []dto.ResponsesStreamResponse {
+	events := s.Start()
+	events = append(events, s.closeCurrent()...)
+	finishResponsesResponse(s.response, s.finish, usage)
+	eventType := "response.completed"
+	if s.finish == "length" {
+		eventType = "response.incomplete"
+	}
+	return append(events, s.event(dto.ResponsesStreamResponse{Type: eventType, Response: s.snapshot()}))
+}
+
+// Fail 在流已开始后上游出错时输出 response.failed
+func (s *ChatToResponsesStreamConverter) Fail(code string, message string) []dto.ResponsesStreamResponse {
+	events := s.Start()
+	events = append(events, s.closeCurrent()...)
+	s.response.Status = responsesStatus(responsesStatusFailed)
+	s.response.Error = map[string]any{"code": code, "message": message}
+	return append(events, s.event(dto.ResponsesStreamResponse{Type: "response.failed", Response: s.snapshot()}))
+}
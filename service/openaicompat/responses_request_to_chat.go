@@ -0,0 +1,391 @@
+package openaicompat
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/goccy/go-json"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/samber/lo"
+)
+
+// ResponsesInputToChatMessages 将 Responses API 的 input 转换为 Chat Completions 消息列表
+// 支持 message / function_call / function_call_output / reasoning 条目，其余条目类型忽略
+func ResponsesInputToChatMessages(input json.RawMessage) ([]dto.Message, error) {
+	if len(input) == 0 || common.GetJsonType(input) == "null" {
+		return nil, nil
+	}
+	if common.GetJsonType(input) == "string" {
+		var text string
+		if err := common.Unmarshal(input, &text); err != nil {
+			return nil, err
+		}
+		return []dto.Message{{Role: "user", Content: text}}, nil
+	}
+
+	var items []map[string]any
+	if err := common.Unmarshal(input, &items); err != nil {
+		return nil, fmt.Errorf("invalid input: %w", err)
+	}
+
+	messages := make([]dto.Message, 0, len(items))
+	// reasoning 条目在同一轮中先于 assistant 消息或工具调用出现，暂存后挂到下一条 assistant 消息上
+	pendingReasoning := ""
+	// 当前轮次的 assistant 消息下标，function_call 条目合并到该消息的 tool_calls 中
+	assistantIndex := -1
+
+	for _, item := range items {
+		itemType := common.Interface2String(item["type"])
+		switch itemType {
+		case "", "message":
+			role := strings.TrimSpace(common.Interface2String(item["role"]))
+			if role == "" {
+				role = "user"
+			}
+			if role == "developer" {
+				role = "system"
+			}
+			content, err := convertResponsesContentToChat(item["content"])
+			if err != nil {
+				return nil, err
+			}
+			message := dto.Message{Role: role, Content: content}
+			if role == "assistant" {
+				message.ReasoningContent = pendingReasoning
+				pendingReasoning = ""
+				messages = append(messages, message)
+				assistantIndex = len(messages) - 1
+				continue
+			}
+			messages = append(messages, message)
+			assistantIndex = -1
+		case "function_call":
+			callID := common.Interface2String(item["call_id"])
+			name := common.Interface2String(item["name"])
+			if callID == "" || name == "" {
+				return nil, errors.New("function_call item requires call_id and name")
+			}
+			if assistantIndex < 0 {
+				messages = append(messages, dto.Message{Role: "assistant", ReasoningContent: pendingReasoning})
+				pendingReasoning = ""
+				assistantIndex = len(messages) - 1
+			}
+			toolCalls := messages[assistantIndex].ParseToolCalls()
+			toolCalls = append(toolCalls, dto.ToolCallRequest{
+				ID:   callID,
+				Type: "function",
+				Function: dto.FunctionRequest{
+					Name:      name,
+					Arguments: common.Interface2String(item["arguments"]),
+				},
+			})
+			messages[assistantIndex].SetToolCalls(toolCalls)
+		case "function_call_output":
+			callID := common.Interface2String(item["call_id"])
+			if callID == "" {
+				return nil, errors.New("function_call_output item requires call_id")
+			}
+			output, err := convertResponsesToolOutputToChat(item["output"])
+			if err != nil {
+				return nil, err
+			}
+			messages = append(messages, dto.Message{Role: "tool", ToolCallId: callID, Content: output})
+			assistantIndex = -1
+		case "reasoning":
+			pendingReasoning += extractResponsesReasoningSummary(item["summary"])
+		default:
+			// item_reference、内置工具调用等条目无法在 Chat Completions 中表达，直接忽略
+		}
+	}
+	return messages, nil
+}
+
+func extractResponsesReasoningSummary(summary any) string {
+	parts, ok := summary.([]any)
+	if !ok {
+		return ""
+	}
+	texts := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if m, ok := part.(map[string]any); ok {
+			if text := common.Interface2String(m["text"]); text != "" {
+				texts = append(texts, text)
+			}
+		}
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+// convertResponsesContentToChat 转换消息内容；仅含文本时合并为字符串，便于各渠道处理
+func convertResponsesContentToChat(content any) (any, error) {
+	switch v := content.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []any:
+		parts := make([]dto.MediaContent, 0, len(v))
+		textOnly := true
+		for _, raw := range v {
+			part, ok := raw.(map[string]any)
+			if !ok {
+				continue
+			}
+			mediaContent, err := convertResponsesContentPartToChat(part)
+			if err != nil {
+				return nil, err
+			}
+			if mediaContent == nil {
+				continue
+			}
+			if mediaContent.Type != dto.ContentTypeText {
+				textOnly = false
+			}
+			parts = append(parts, *mediaContent)
+		}
+		if textOnly {
+			texts := lo.Map(parts, func(part dto.MediaContent, _ int) string { return part.Text })
+			return strings.Join(texts, "\n"), nil
+		}
+		return parts, nil
+	default:
+		return nil, fmt.Errorf("unsupported message content type %T", content)
+	}
+}
+
+func convertResponsesContentPartToChat(part map[string]any) (*dto.MediaContent, error) {
+	partType := common.Interface2String(part["type"])
+	switch partType {
+	case "input_text", "output_text", "text", "summary_text", "reasoning_text":
+		return &dto.MediaContent{Type: dto.ContentTypeText, Text: common.Interface2String(part["text"])}, nil
+	case "refusal":
+		return &dto.MediaContent{Type: dto.ContentTypeText, Text: common.Interface2String(part["refusal"])}, nil
+	case "input_image":
+		imageUrl := dto.MessageImageUrl{Detail: lo.CoalesceOrEmpty(common.Interface2String(part["detail"]), "auto")}
+		switch v := part["image_url"].(type) {
+		case string:
+			imageUrl.Url = v
+		case map[string]any:
+			imageUrl.Url = common.Interface2String(v["url"])
+		}
+		if imageUrl.Url == "" {
+			return nil, errors.New("input_image requires image_url, file_id is not supported")
+		}
+		return &dto.MediaContent{Type: dto.ContentTypeImageURL, ImageUrl: imageUrl}, nil
+	case "input_file":
+		file := dto.MessageFile{
+			FileName: common.Interface2String(part["filename"]),
+			FileData: common.Interface2String(part["file_data"]),
+			FileId:   common.Interface2String(part["file_id"]),
+		}
+		if nested, ok := part["file"].(map[string]any); ok {
+			file.FileName = lo.CoalesceOrEmpty(file.FileName, common.Interface2String(nested["filename"]))
+			file.FileData = lo.CoalesceOrEmpty(file.FileData, common.Interface2String(nested["file_data"]))
+			file.FileId = lo.CoalesceOrEmpty(file.FileId, common.Interface2String(nested["file_id"]))
+		}
+		if fileUrl := common.Interface2String(part["file_url"]); fileUrl != "" && file.FileData == "" {
+			file.FileData = fileUrl
+		}
+		return &dto.MediaContent{Type: dto.ContentTypeFile, File: file}, nil
+	case "input_audio":
+		audio, _ := part["input_audio"].(map[string]any)
+		if audio == nil {
+			audio = part
+		}
+		return &dto.MediaContent{Type: dto.ContentTypeInputAudio, InputAudio: dto.MessageInputAudio{
+			Data:   common.Interface2String(audio["data"]),
+			Format: common.Interface2String(audio["format"]),
+		}}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// convertResponsesToolOutputToChat function_call_output 的 output 可以是字符串或内容数组
+func convertResponsesToolOutputToChat(output any) (any, error) {
+	switch v := output.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []any:
+		return convertResponsesContentToChat(v)
+	default:
+		b, err := common.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	}
+}
+
+// ResponsesRequestToChatCompletionsRequest 将 Responses 请求转换为 Chat Completions 请求
+// messages 为已展开的历史与本轮输入，instructions 作为首条 system 消息
+func ResponsesRequestToChatCompletionsRequest(req *dto.OpenAIResponsesRequest, messages []dto.Message) (*dto.GeneralOpenAIRequest, error) {
+	if req == nil {
+		return nil, errors.New("request is nil")
+	}
+	if req.Model == "" {
+		return nil, errors.New("model is required")
+	}
+
+	out := &dto.GeneralOpenAIRequest{
+		Model:       req.Model,
+		Stream:      req.Stream,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+		TopLogProbs: req.TopLogProbs,
+		User:        req.User,
+		Metadata:    req.Metadata,
+		MaxTokens:   req.MaxOutputTokens,
+	}
+
+	if len(req.Instructions) > 0 && common.GetJsonType(req.Instructions) == "string" {
+		var instructions string
+		if err := common.Unmarshal(req.Instructions, &instructions); err != nil {
+			return nil, fmt.Errorf("invalid instructions: %w", err)
+		}
+		if strings.TrimSpace(instructions) != "" {
+			out.Messages = append(out.Messages, dto.Message{Role: "system", Content: instructions})
+		}
+	}
+	out.Messages = append(out.Messages, messages...)
+	if len(out.Messages) == 0 {
+		return nil, errors.New("input is required")
+	}
+
+	if lo.FromPtrOr(req.Stream, false) {
+		out.StreamOptions = &dto.StreamOptions{IncludeUsage: true}
+	}
+	if req.Reasoning != nil && req.Reasoning.Effort != "" && req.Reasoning.Effort != "none" {
+		out.ReasoningEffort = req.Reasoning.Effort
+	}
+
+	if len(req.ParallelToolCalls) > 0 && common.GetJsonType(req.ParallelToolCalls) == "boolean" {
+		var parallel bool
+		if err := common.Unmarshal(req.ParallelToolCalls, &parallel); err == nil {
+			out.ParallelTooCalls = &parallel
+		}
+	}
+
+	if len(req.Tools) > 0 {
+		var tools []map[string]any
+		if err := common.Unmarshal(req.Tools, &tools); err != nil {
+			return nil, fmt.Errorf("invalid tools: %w", err)
+		}
+		for _, tool := range tools {
+			// 内置工具（web_search、file_search 等）依赖 OpenAI 服务端执行，无法桥接
+			if common.Interface2String(tool["type"]) != "function" {
+				continue
+			}
+			out.Tools = append(out.Tools, dto.ToolCallRequest{
+				Type: "function",
+				Function: dto.FunctionRequest{
+					Name:        common.Interface2String(tool["name"]),
+					Description: common.Interface2String(tool["description"]),
+					Parameters:  tool["parameters"],
+				},
+			})
+		}
+	}
+
+	if len(req.ToolChoice) > 0 && len(out.Tools) > 0 {
+		out.ToolChoice = convertResponsesToolChoiceToChat(req.ToolChoice)
+	}
+
+	responseFormat, err := convertResponsesTextToChatResponseFormat(req.Text)
+	if err != nil {
+		return nil, err
+	}
+	out.ResponseFormat = responseFormat
+	return out, nil
+}
+
+func convertResponsesToolChoiceToChat(toolChoice json.RawMessage) any {
+	if common.GetJsonType(toolChoice) == "string" {
+		var choice string
+		_ = common.Unmarshal(toolChoice, &choice)
+		return choice
+	}
+	var m map[string]any
+	if err := common.Unmarshal(toolChoice, &m); err != nil {
+		return nil
+	}
+	switch common.Interface2String(m["type"]) {
+	case "function":
+		// Responses: {"type":"function","name":"..."}
+		// Chat: {"type":"function","function":{"name":"..."}}
+		return map[string]any{
+			"type":     "function",
+			"function": map[string]any{"name": common.Interface2String(m["name"])},
+		}
+	case "allowed_tools":
+		return lo.CoalesceOrEmpty(common.Interface2String(m["mode"]), "auto")
+	default:
+		return nil
+	}
+}
+
+func convertResponsesTextToChatResponseFormat(text json.RawMessage) (*dto.ResponseFormat, error) {
+	if len(text) == 0 || common.GetJsonType(text) != "object" {
+		return nil, nil
+	}
+	var textConfig struct {
+		Format map[string]any `json:"format"`
+	}
+	if err := common.Unmarshal(text, &textConfig); err != nil {
+		return nil, fmt.Errorf("invalid text: %w", err)
+	}
+	formatType := common.Interface2String(textConfig.Format["type"])
+	switch formatType {
+	case "":
+		return nil, nil
+	case "json_schema":
+		schema := dto.FormatJsonSchema{
+			Name:        common.Interface2String(textConfig.Format["name"]),
+			Description: common.Interface2String(textConfig.Format["description"]),
+			Schema:      textConfig.Format["schema"],
+		}
+		if strict, ok := textConfig.Format["strict"].(bool); ok {
+			schema.Strict, _ = common.Marshal(strict)
+		}
+		schemaRaw, err := common.Marshal(schema)
+		if err != nil {
+			return nil, err
+		}
+		return &dto.ResponseFormat{Type: formatType, JsonSchema: schemaRaw}, nil
+	default:
+		return &dto.ResponseFormat{Type: formatType}, nil
+	}
+}
+
+// ResponsesInputItems 将 input 规范化为条目数组，字符串输入视为一条 user 消息
+func ResponsesInputItems(input json.RawMessage) ([]json.RawMessage, error) {
+	switch common.GetJsonType(input) {
+	case "unknown", "null":
+		return nil, nil
+	case "string":
+		var text string
+		if err := common.Unmarshal(input, &text); err != nil {
+			return nil, err
+		}
+		item, err := common.Marshal(map[string]any{
+			"type":    "message",
+			"role":    "user",
+			"content": text,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return []json.RawMessage{item}, nil
+	default:
+		var items []json.RawMessage
+		if err := common.Unmarshal(input, &items); err != nil {
+			return nil, fmt.Errorf("invalid input: %w", err)
+		}
+		return items, nil
+	}
+}
@@ -0,0 +1,134 @@
+package openaicompat
+
+import (
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponsesInputToChatMessages(t *testing.T) {
+	input := json.RawMessage(`[
+		{"role":"developer","content":"be brief"},
+		{"role":"user","content":[{"type":"input_text","text":"look"},{"type":"input_image","image_url":"https://example.com/a.png"}]},
+		{"type":"reasoning","id":"rs_1","summary":[{"type":"summary_text","text":"thinking"}]},
+		{"type":"message","role":"assistant","content":[{"type":"output_text","text":"calling"}]},
+		{"type":"function_call","call_id":"call_1","name":"get_weather","arguments":"{\"city\":\"Paris\"}"},
+		{"type":"function_call_output","call_id":"call_1","output":"sunny"},
+		{"type":"item_reference","id":"msg_x"}
+	]`)
+	messages, err := ResponsesInputToChatMessages(input)
+	require.NoError(t, err)
+	require.Len(t, messages, 4)
+
+	require.Equal(t, "system", messages[0].Role)
+	require.Equal(t, "be brief", messages[0].Content)
+
+	parts, ok := messages[1].Content.([]dto.MediaContent)
+	require.True(t, ok)
+	require.Len(t, parts, 2)
+	require.Equal(t, dto.ContentTypeImageURL, parts[1].Type)
+
+	require.Equal(t, "assistant", messages[2].Role)
+	require.Equal(t, "calling", messages[2].Content)
+	require.Equal(t, "thinking", messages[2].ReasoningContent)
+	toolCalls := messages[2].ParseToolCalls()
+	require.Len(t, toolCalls, 1)
+	require.Equal(t, "call_1", toolCalls[0].ID)
+	require.Equal(t, `{"city":"Paris"}`, toolCalls[0].Function.Arguments)
+
+	require.Equal(t, "tool", messages[3].Role)
+	require.Equal(t, "call_1", messages[3].ToolCallId)
+	require.Equal(t, "sunny", messages[3].Content)
+}
+
+func TestResponsesRequestToChatCompletionsRequest(t *testing.T) {
+	req := &dto.OpenAIResponsesRequest{
+		Model:           "claude-sonnet-4-5",
+		Instructions:    json.RawMessage(`"be nice"`),
+		MaxOutputTokens: func() *uint { v := uint(256); return &v }(),
+		Reasoning:       &dto.Reasoning{Effort: "high"},
+		Stream:          func() *bool { v := true; return &v }(),
+		Tools:           json.RawMessage(`[{"type":"function","name":"f","parameters":{"type":"object"}},{"type":"web_search"}]`),
+		ToolChoice:      json.RawMessage(`{"type":"function","name":"f"}`),
+		Text:            json.RawMessage(`{"format":{"type":"json_schema","name":"out","schema":{"type":"object"},"strict":true}}`),
+	}
+	chatReq, err := ResponsesRequestToChatCompletionsRequest(req, []dto.Message{{Role: "user", Content: "hi"}})
+	require.NoError(t, err)
+	require.Len(t, chatReq.Messages, 2)
+	require.Equal(t, "system", chatReq.Messages[0].Role)
+	require.Equal(t, uint(256), *chatReq.MaxTokens)
+	require.Equal(t, "high", chatReq.ReasoningEffort)
+	require.True(t, chatReq.StreamOptions.IncludeUsage)
+	require.Len(t, chatReq.Tools, 1)
+	require.Equal(t, "f", chatReq.Tools[0].Function.Name)
+	require.Equal(t, map[string]any{"type": "function", "function": map[string]any{"name": "f"}}, chatReq.ToolChoice)
+	require.Equal(t, "json_schema", chatReq.ResponseFormat.Type)
+	require.JSONEq(t, `{"name":"out","schema":{"type":"object"},"strict":true}`, string(chatReq.ResponseFormat.JsonSchema))
+}
+
+func TestChatToResponsesStreamConverter(t *testing.T) {
+	text := func(s string) *string { return &s }
+	index := 0
+	stop := "tool_calls"
+	converter := NewChatToResponsesStreamConverter(NewResponsesResponse("resp_1", "m", &dto.OpenAIResponsesRequest{}))
+
+	var events []dto.ResponsesStreamResponse
+	events = append(events, converter.HandleChunk(&dto.ChatCompletionsStreamResponse{Choices: []dto.ChatCompletionsStreamResponseChoice{
+		{Delta: dto.ChatCompletionsStreamResponseChoiceDelta{ReasoningContent: text("hmm")}},
+	}})...)
+	events = append(events, converter.HandleChunk(&dto.ChatCompletionsStreamResponse{Choices: []dto.ChatCompletionsStreamResponseChoice{
+		{Delta: dto.ChatCompletionsStreamResponseChoiceDelta{Content: text("hi")}},
+	}})...)
+	events = append(events, converter.HandleChunk(&dto.ChatCompletionsStreamResponse{Choices: []dto.ChatCompletionsStreamResponseChoice{
+		{Delta: dto.ChatCompletionsStreamResponseChoiceDelta{ToolCalls: []dto.ToolCallResponse{
+			{Index: &index, ID: "call_1", Function: dto.FunctionResponse{Name: "f", Arguments: `{"a":`}},
+		}}},
+	}})...)
+	events = append(events, converter.HandleChunk(&dto.ChatCompletionsStreamResponse{Choices: []dto.ChatCompletionsStreamResponseChoice{
+		{Delta: dto.ChatCompletionsStreamResponseChoiceDelta{ToolCalls: []dto.ToolCallResponse{
+			{Index: &index, Function: dto.FunctionResponse{Arguments: `1}`}},
+		}}, FinishReason: &stop},
+	}})...)
+	events = append(events, converter.Finish(&dto.Usage{PromptTokens: 3, CompletionTokens: 4, TotalTokens: 7})...)
+
+	types := make([]string, 0, len(events))
+	for i, event := range events {
+		types = append(types, event.Type)
+		require.Equal(t, i+1, event.SequenceNumber)
+	}
+	require.Equal(t, []string{
+		"response.created",
+		"response.in_progress",
+		"response.output_item.added",
+		"response.reasoning_summary_part.added",
+		"response.reasoning_summary_text.delta",
+		"response.reasoning_summary_text.done",
+		"response.reasoning_summary_part.done",
+		"response.output_item.done",
+		"response.output_item.added",
+		"response.content_part.added",
+		"response.output_text.delta",
+		"response.output_text.done",
+		"response.content_part.done",
+		"response.output_item.done",
+		"response.output_item.added",
+		"response.function_call_arguments.delta",
+		"response.function_call_arguments.delta",
+		"response.function_call_arguments.done",
+		"response.output_item.done",
+		"response.completed",
+	}, types)
+
+	response := events[len(events)-1].Response
+	require.Equal(t, `"completed"`, string(response.Status))
+	require.Len(t, response.Output, 3)
+	require.Equal(t, "hmm", response.Output[0].Summary[0].Text)
+	require.Equal(t, "hi", response.Output[1].Content[0].Text)
+	require.Equal(t, `{"a":1}`, response.Output[2].Arguments)
+	require.Equal(t, "call_1", response.Output[2].CallId)
+	require.Equal(t, 3, response.Usage.InputTokens)
+	require.Equal(t, 4, response.Usage.OutputTokens)
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"github.com/QuantumNous/new-api/setting"
+	"github.com/QuantumNous/new-api/setting/console_setting"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+)
+
+// optionValidators 配置项取值的校验函数，仅检查取值本身，不修改任何运行时配置
+var optionValidators = map[string]func(value string) error{
+	"GroupRatio":                          ratio_setting.CheckGroupRatio,
+	"PricingTiers":                        ratio_setting.CheckPricingTiers,
+	"ModelRequestRateLimitGroup":          setting.CheckModelRequestRateLimitGroup,
+	"usage_limit_setting.group_limits":    operation_setting.CheckGroupUsageLimits,
+	"payload_log_setting.redact_patterns": operation_setting.CheckPayloadRedactPatterns,
+	"moderation_setting.providers":        operation_setting.CheckModerationProviders,
+	"moderation_setting.regex_rules":      operation_setting.CheckModerationRegexRules,
+	"moderation_setting.group_actions":    operation_setting.CheckModerationGroupActions,
+	"AutomaticDisableStatusCodes":         checkHTTPStatusCodeRanges,
+	"AutomaticRetryStatusCodes":           checkHTTPStatusCodeRanges,
+	"console_setting.api_info":            consoleSettingValidator("ApiInfo"),
+	"console_setting.announcements":       consoleSettingValidator("Announcements"),
+	"console_setting.faq":                 consoleSettingValidator("FAQ"),
+	"console_setting.uptime_kuma_groups":  consoleSettingValidator("UptimeKumaGroups"),
+}
+
+func checkHTTPStatusCodeRanges(value string) error {
+	_, err := operation_setting.ParseHTTPStatusCodeRanges(value)
+	return err
+}
+
+func consoleSettingValidator(settingType string) func(value string) error {
+	return func(value string) error {
+		return console_setting.ValidateConsoleSettings(value, settingType)
+	}
+}
+
+// ValidateOptionValue 校验配置项取值，供选项接口与声明式配置共用
+func ValidateOptionValue(key string, value string) error {
+	if validate, ok := optionValidators[key]; ok {
+		return validate(value)
+	}
+	return nil
+}
@@ -0,0 +1,341 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+const payloadRedacted = "[REDACTED]"
+
+// PayloadCapture 记录一次上游尝试的请求体与写回客户端的响应体
+type PayloadCapture struct {
+	writer           *ResponseCaptureWriter
+	request          []byte
+	requestTruncated bool
+	createdAt        int64
+}
+
+func payloadLogLimit() int {
+	limit := operation_setting.GetPayloadLogSetting().MaxBodyKB << 10
+	if limit <= 0 {
+		limit = 256 << 10
+	}
+	return limit
+}
+
+// StartPayloadCapture 对开启载荷记录的分组或令牌开始记录，requestBody 为参数覆盖后实际发送给上游的请求体，
+// 为 nil 时（透传模式）使用客户端原始请求体
+func StartPayloadCapture(c *gin.Context, info *relaycommon.RelayInfo, requestBody []byte) *PayloadCapture {
+	if info == nil || !operation_setting.ShouldLogPayload(info.UsingGroup, info.TokenId) {
+		return nil
+	}
+	if requestBody == nil {
+		if storage, err := common.GetBodyStorage(c); err == nil {
+			requestBody, _ = storage.Bytes()
+		}
+	}
+	limit := payloadLogLimit()
+	capture := &PayloadCapture{createdAt: common.GetTimestamp()}
+	if len(requestBody) > limit {
+		requestBody = requestBody[:limit]
+		capture.requestTruncated = true
+	}
+	capture.request = bytes.Clone(requestBody)
+	capture.writer = &ResponseCaptureWriter{ResponseWriter: c.Writer, origin: c.Writer, limit: limit, keepPrefix: true}
+	c.Writer = capture.writer
+	info.PayloadLogged = true
+	return capture
+}
+
+// Finish 恢复原始的 ResponseWriter，脱敏后异步保存载荷
+func (p *PayloadCapture) Finish(c *gin.Context, info *relaycommon.RelayInfo, apiErr *types.NewAPIError) {
+	if p == nil {
+		return
+	}
+	p.writer.Restore(c)
+	payloadLog := &model.PayloadLog{
+		RequestId:         info.RequestId,
+		CreatedAt:         p.createdAt,
+		UserId:            info.UserId,
+		TokenId:           info.TokenId,
+		Group:             info.UsingGroup,
+		ModelName:         info.OriginModelName,
+		RequestPath:       info.RequestURLPath,
+		IsStream:          info.IsStream,
+		StatusCode:        p.writer.Status(),
+		RequestTruncated:  p.requestTruncated,
+		ResponseTruncated: p.writer.overflow,
+	}
+	if info.ChannelMeta != nil {
+		payloadLog.ChannelId = info.ChannelId
+	}
+	if apiErr != nil {
+		payloadLog.StatusCode = apiErr.StatusCode
+		payloadLog.ErrorMessage = RedactPayload([]byte(apiErr.Error()))
+	}
+	request := p.request
+	response := bytes.Clone(p.writer.buf.Bytes())
+	gopool.Go(func() {
+		if payloadLog.IsStream {
+			response = AssembleStreamPayload(response)
+		}
+		payloadLog.RequestBody = RedactPayload(request)
+		payloadLog.ResponseBody = RedactPayload(response)
+		if err := model.CreatePayloadLog(payloadLog); err != nil {
+			common.SysLog(fmt.Sprintf("failed to save payload log for request %s: %v", payloadLog.RequestId, err))
+		}
+	})
+}
+
+// RedactPayload 按配置的 JSON 路径与正则脱敏，载荷不是合法 JSON（如被截断）时仅按正则脱敏
+func RedactPayload(body []byte) string {
+	setting := operation_setting.GetPayloadLogSetting()
+	if len(setting.RedactJSONPaths) > 0 && gjson.ValidBytes(body) {
+		for _, path := range setting.RedactJSONPaths {
+			body = redactJSONPath(body, path)
+		}
+	}
+	text := string(body)
+	for _, re := range operation_setting.GetPayloadRedactRegexps() {
+		text = re.ReplaceAllString(text, payloadRedacted)
+	}
+	return text
+}
+
+func redactJSONPath(body []byte, path string) []byte {
+	if path == "" {
+		return body
+	}
+	var paths []string
+	collectRedactPaths(gjson.ParseBytes(body), splitJSONPath(path), "", &paths)
+	for _, p := range paths {
+		if redacted, err := sjson.SetBytes(body, p, payloadRedacted); err == nil {
+			body = redacted
+		}
+	}
+	return body
+}
+
+// splitJSONPath 按未转义的 . 拆分路径，键名中的 . 可写作 \.
+func splitJSONPath(path string) []string {
+	var parts []string
+	var b strings.Builder
+	escaped := false
+	for _, r := range path {
+		switch {
+		case escaped:
+			b.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '.':
+			parts = append(parts, b.String())
+			b.Reset()
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return append(parts, b.String())
+}
+
+// collectRedactPaths 将带通配符的路径展开为具体路径
+func collectRedactPaths(node gjson.Result, parts []string, prefix string, out *[]string) {
+	if len(parts) == 0 {
+		if prefix != "" {
+			*out = append(*out, prefix)
+		}
+		return
+	}
+	join := func(key string) string {
+		key = escapeJSONPathKey(key)
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+	if parts[0] != "*" {
+		child := node.Get(escapeJSONPathKey(parts[0]))
+		if child.Exists() {
+			collectRedactPaths(child, parts[1:], join(parts[0]), out)
+		}
+		return
+	}
+	idx := 0
+	node.ForEach(func(key, value gjson.Result) bool {
+		name := key.String()
+		if node.IsArray() {
+			name = strconv.Itoa(idx)
+		}
+		idx++
+		collectRedactPaths(value, parts[1:], join(name), out)
+		return true
+	})
+}
+
+func escapeJSONPathKey(key string) string {
+	var b strings.Builder
+	for _, r := range key {
+		switch r {
+		case '.', '*', '?', '\\', '|', '#', '@', '!', ':':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+type assembledToolCall struct {
+	Index     int    `json:"index"`
+	Id        string `json:"id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+}
+
+// assembledStreamPayload 由流式响应事件拼接出的完整响应
+type assembledStreamPayload struct {
+	Stream       bool                 `json:"stream"`
+	Events       int                  `json:"events"`
+	Content      string               `json:"content,omitempty"`
+	Reasoning    string               `json:"reasoning,omitempty"`
+	ToolCalls    []*assembledToolCall `json:"tool_calls,omitempty"`
+	FinishReason string               `json:"finish_reason,omitempty"`
+	Usage        json.RawMessage      `json:"usage,omitempty"`
+	Response     json.RawMessage      `json:"response,omitempty"`
+}
+
+// AssembleStreamPayload 将 SSE 响应拼接为完整响应，支持 OpenAI、Claude、Gemini 与 Responses 格式，
+// 无法识别时返回原始内容
+func AssembleStreamPayload(body []byte) []byte {
+	var content, reasoning strings.Builder
+	result := &assembledStreamPayload{Stream: true}
+	toolCalls := make(map[int]*assembledToolCall)
+	toolCall := func(index int) *assembledToolCall {
+		call, ok := toolCalls[index]
+		if !ok {
+			call = &assembledToolCall{Index: index}
+			toolCalls[index] = call
+			result.ToolCalls = append(result.ToolCalls, call)
+		}
+		return call
+	}
+
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if !bytes.HasPrefix(line, []byte("data:")) {
+			continue
+		}
+		data := bytes.TrimSpace(line[len("data:"):])
+		if !gjson.ValidBytes(data) {
+			continue
+		}
+		event := gjson.ParseBytes(data)
+		result.Events++
+
+		switch {
+		case event.Get("choices").Exists():
+			choice := event.Get("choices.0")
+			content.WriteString(choice.Get("delta.content").String())
+			reasoning.WriteString(choice.Get("delta.reasoning_content").String())
+			reasoning.WriteString(choice.Get("delta.reasoning").String())
+			choice.Get("delta.tool_calls").ForEach(func(_, item gjson.Result) bool {
+				call := toolCall(int(item.Get("index").Int()))
+				if id := item.Get("id").String(); id != "" {
+					call.Id = id
+				}
+				if name := item.Get("function.name").String(); name != "" {
+					call.Name = name
+				}
+				call.Arguments += item.Get("function.arguments").String()
+				return true
+			})
+			if reason := choice.Get("finish_reason").String(); reason != "" {
+				result.FinishReason = reason
+			}
+			if usage := event.Get("usage"); usage.IsObject() {
+				result.Usage = json.RawMessage(usage.Raw)
+			}
+		case event.Get("candidates").Exists():
+			candidate := event.Get("candidates.0")
+			candidate.Get("content.parts").ForEach(func(_, part gjson.Result) bool {
+				if fc := part.Get("functionCall"); fc.Exists() {
+					call := toolCall(len(result.ToolCalls))
+					call.Name = fc.Get("name").String()
+					call.Arguments = fc.Get("args").Raw
+				} else if part.Get("thought").Bool() {
+					reasoning.WriteString(part.Get("text").String())
+				} else {
+					content.WriteString(part.Get("text").String())
+				}
+				return true
+			})
+			if reason := candidate.Get("finishReason").String(); reason != "" {
+				result.FinishReason = reason
+			}
+			if usage := event.Get("usageMetadata"); usage.IsObject() {
+				result.Usage = json.RawMessage(usage.Raw)
+			}
+		default:
+			switch event.Get("type").String() {
+			case "message_start":
+				if usage := event.Get("message.usage"); usage.IsObject() {
+					result.Usage = json.RawMessage(usage.Raw)
+				}
+			case "content_block_start":
+				if block := event.Get("content_block"); block.Get("type").String() == "tool_use" {
+					call := toolCall(int(event.Get("index").Int()))
+					call.Id = block.Get("id").String()
+					call.Name = block.Get("name").String()
+				}
+			case "content_block_delta":
+				delta := event.Get("delta")
+				switch delta.Get("type").String() {
+				case "text_delta":
+					content.WriteString(delta.Get("text").String())
+				case "thinking_delta":
+					reasoning.WriteString(delta.Get("thinking").String())
+				case "input_json_delta":
+					toolCall(int(event.Get("index").Int())).Arguments += delta.Get("partial_json").String()
+				}
+			case "message_delta":
+				if reason := event.Get("delta.stop_reason").String(); reason != "" {
+					result.FinishReason = reason
+				}
+				if usage := event.Get("usage"); usage.IsObject() {
+					result.Usage = json.RawMessage(usage.Raw)
+				}
+			case "response.output_text.delta":
+				content.WriteString(event.Get("delta").String())
+			case "response.reasoning_summary_text.delta", "response.reasoning_text.delta":
+				reasoning.WriteString(event.Get("delta").String())
+			case "response.completed", "response.incomplete", "response.failed":
+				if response := event.Get("response"); response.IsObject() {
+					result.Response = json.RawMessage(response.Raw)
+				}
+			}
+		}
+	}
+	if result.Events == 0 {
+		return body
+	}
+	result.Content = content.String()
+	result.Reasoning = reasoning.String()
+	assembled, err := common.Marshal(result)
+	if err != nil {
+		return body
+	}
+	return assembled
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+const (
+	payloadLogCleanupTickInterval = 30 * time.Minute
+	payloadLogCleanupBatchSize    = 1000
+)
+
+var (
+	payloadLogCleanupOnce    sync.Once
+	payloadLogCleanupRunning atomic.Bool
+)
+
+// StartPayloadLogCleanupTask 定期清理超过保留时长的载荷记录
+func StartPayloadLogCleanupTask() {
+	payloadLogCleanupOnce.Do(func() {
+		if !common.IsMasterNode {
+			return
+		}
+		gopool.Go(func() {
+			logger.LogInfo(context.Background(), fmt.Sprintf("payload log cleanup task started: tick=%s", payloadLogCleanupTickInterval))
+			ticker := time.NewTicker(payloadLogCleanupTickInterval)
+			defer ticker.Stop()
+
+			runPayloadLogCleanupOnce()
+			for range ticker.C {
+				runPayloadLogCleanupOnce()
+			}
+		})
+	})
+}
+
+func runPayloadLogCleanupOnce() {
+	retentionHours := operation_setting.GetPayloadLogSetting().RetentionHours
+	if retentionHours <= 0 {
+		return
+	}
+	if !payloadLogCleanupRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer payloadLogCleanupRunning.Store(false)
+
+	ctx := context.Background()
+	target := time.Now().Add(-time.Duration(retentionHours) * time.Hour).Unix()
+	deleted, err := model.DeleteOldPayloadLogs(ctx, target, payloadLogCleanupBatchSize)
+	if err != nil {
+		logger.LogWarn(ctx, fmt.Sprintf("payload log cleanup task failed: %v", err))
+		return
+	}
+	if common.DebugEnabled && deleted > 0 {
+		logger.LogDebug(ctx, "payload log cleanup: deleted_count=%d", deleted)
+	}
+}
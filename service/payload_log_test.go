@@ -0,0 +1,59 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactPayload(t *testing.T) {
+	setting := operation_setting.GetPayloadLogSetting()
+	originalPaths := setting.RedactJSONPaths
+	originalPatterns := setting.RedactPatterns
+	setting.RedactJSONPaths = []string{"messages.*.content", "metadata.user\\.email"}
+	setting.RedactPatterns = []string{`\d{11}`}
+	t.Cleanup(func() {
+		setting.RedactJSONPaths = originalPaths
+		setting.RedactPatterns = originalPatterns
+	})
+
+	body := `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"},{"role":"assistant","content":"hello"}],"metadata":{"user.email":"a@b.c","phone":"13800138000"}}`
+	redacted := RedactPayload([]byte(body))
+	require.Equal(t, `{"model":"gpt-4o","messages":[{"role":"user","content":"[REDACTED]"},{"role":"assistant","content":"[REDACTED]"}],"metadata":{"user.email":"[REDACTED]","phone":"[REDACTED]"}}`, redacted)
+
+	// 截断后的载荷不是合法 JSON，仅按正则脱敏
+	require.Equal(t, `{"messages":[{"content":"call [REDACTED]`, RedactPayload([]byte(`{"messages":[{"content":"call 13800138000`)))
+}
+
+func TestAssembleStreamPayload(t *testing.T) {
+	openaiStream := "data: {\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"content\":\"Hel\"}}]}\n\n" +
+		"data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"lo\"}}]}\n\n" +
+		"data: {\"choices\":[{\"index\":0,\"delta\":{\"tool_calls\":[{\"index\":0,\"id\":\"call_1\",\"function\":{\"name\":\"get_weather\",\"arguments\":\"{\\\"city\\\":\"}}]}}]}\n\n" +
+		"data: {\"choices\":[{\"index\":0,\"delta\":{\"tool_calls\":[{\"index\":0,\"function\":{\"arguments\":\"\\\"Paris\\\"}\"}}]},\"finish_reason\":\"tool_calls\"}]}\n\n" +
+		"data: {\"choices\":[],\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":2}}\n\n" +
+		"data: [DONE]\n\n"
+	var result assembledStreamPayload
+	require.NoError(t, common.Unmarshal(AssembleStreamPayload([]byte(openaiStream)), &result))
+	require.Equal(t, 5, result.Events)
+	require.Equal(t, "Hello", result.Content)
+	require.Equal(t, "tool_calls", result.FinishReason)
+	require.Len(t, result.ToolCalls, 1)
+	require.Equal(t, "get_weather", result.ToolCalls[0].Name)
+	require.Equal(t, `{"city":"Paris"}`, result.ToolCalls[0].Arguments)
+	require.JSONEq(t, `{"prompt_tokens":3,"completion_tokens":2}`, string(result.Usage))
+
+	claudeStream := "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":5}}}\n\n" +
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"thinking_delta\",\"thinking\":\"hmm\"}}\n\n" +
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":1,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hi\"}}\n\n" +
+		"event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"end_turn\"},\"usage\":{\"output_tokens\":1}}\n\n"
+	result = assembledStreamPayload{}
+	require.NoError(t, common.Unmarshal(AssembleStreamPayload([]byte(claudeStream)), &result))
+	require.Equal(t, "Hi", result.Content)
+	require.Equal(t, "hmm", result.Reasoning)
+	require.Equal(t, "end_turn", result.FinishReason)
+
+	// 无法识别的内容原样返回
+	require.Equal(t, "not a stream", string(AssembleStreamPayload([]byte("not a stream"))))
+}
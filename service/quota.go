@@ -36,6 +36,17 @@ type QuotaInfo struct {
 	ModelPrice    float64
 	ModelRatio    float64
 	GroupRatio    float64
+	// CompletionRatio 分档计价调整后的补全倍率，为空时使用模型配置的补全倍率
+	CompletionRatio *float64
+}
+
+// tierCompletionRatio 命中分档计价时返回调整后的补全倍率
+func tierCompletionRatio(relayInfo *relaycommon.RelayInfo) *float64 {
+	if relayInfo.PriceData.PricingTier == nil {
+		return nil
+	}
+	completionRatio := relayInfo.PriceData.CompletionRatio
+	return &completionRatio
 }
 
 func hasCustomModelRatio(modelName string, currentRatio float64) bool {
@@ -57,6 +68,9 @@ func calculateAudioQuota(info QuotaInfo) int {
 	}
 
 	completionRatio := decimal.NewFromFloat(ratio_setting.GetCompletionRatio(info.ModelName))
+	if info.CompletionRatio != nil {
+		completionRatio = decimal.NewFromFloat(*info.CompletionRatio)
+	}
 	audioRatio := decimal.NewFromFloat(ratio_setting.GetAudioRatio(info.ModelName))
 	audioCompletionRatio := decimal.NewFromFloat(ratio_setting.GetAudioCompletionRatio(info.ModelName))
 
@@ -155,6 +169,9 @@ func PreWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usag
 func PostWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, modelName string,
 	usage *dto.RealtimeUsage, extraContent string) {
 
+	// 按实际输入 tokens 重新匹配分档计价
+	relayInfo.ApplyPricingTier(usage.InputTokens)
+
 	useTimeSeconds := time.Now().Unix() - relayInfo.StartTime.Unix()
 	textInputTokens := usage.InputTokenDetails.TextTokens
 	textOutTokens := usage.OutputTokenDetails.TextTokens
@@ -164,6 +181,9 @@ func PostWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, mod
 
 	tokenName := ctx.GetString("token_name")
 	completionRatio := decimal.NewFromFloat(ratio_setting.GetCompletionRatio(modelName))
+	if tierRatio := tierCompletionRatio(relayInfo); tierRatio != nil {
+		completionRatio = decimal.NewFromFloat(*tierRatio)
+	}
 	audioRatio := decimal.NewFromFloat(ratio_setting.GetAudioRatio(relayInfo.OriginModelName))
 	audioCompletionRatio := decimal.NewFromFloat(ratio_setting.GetAudioCompletionRatio(modelName))
 
@@ -181,10 +201,11 @@ func PostWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, mod
 			TextTokens:  textOutTokens,
 			AudioTokens: audioOutTokens,
 		},
-		ModelName:  modelName,
-		UsePrice:   usePrice,
-		ModelRatio: modelRatio,
-		GroupRatio: groupRatio,
+		ModelName:       modelName,
+		UsePrice:        usePrice,
+		ModelRatio:      modelRatio,
+		GroupRatio:      groupRatio,
+		CompletionRatio: tierCompletionRatio(relayInfo),
 	}
 
 	quota := calculateAudioQuota(quotaInfo)
@@ -238,6 +259,13 @@ func PostClaudeConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 		ObserveChannelAffinityUsageCacheByRelayFormat(ctx, usage, relayInfo.GetFinalRequestRelayFormat())
 	}
 
+	// 按实际提示 tokens 重新匹配分档计价，Claude 的 input_tokens 不含缓存部分，长上下文需按完整输入计算
+	tierPromptTokens := usage.PromptTokens
+	if relayInfo.ChannelType != constant.ChannelTypeOpenRouter {
+		tierPromptTokens += usage.PromptTokensDetails.CachedTokens + usage.PromptTokensDetails.CachedCreationTokens
+	}
+	relayInfo.ApplyPricingTier(tierPromptTokens)
+
 	useTimeSeconds := time.Now().Unix() - relayInfo.StartTime.Unix()
 	promptTokens := usage.PromptTokens
 	completionTokens := usage.CompletionTokens
@@ -358,6 +386,9 @@ func CalcOpenRouterCacheCreateTokens(usage dto.Usage, priceData types.PriceData)
 
 func PostAudioConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usage *dto.Usage, extraContent string) {
 
+	// 按实际提示 tokens 重新匹配分档计价
+	relayInfo.ApplyPricingTier(usage.PromptTokens)
+
 	useTimeSeconds := time.Now().Unix() - relayInfo.StartTime.Unix()
 	textInputTokens := usage.PromptTokensDetails.TextTokens
 	textOutTokens := usage.CompletionTokenDetails.TextTokens
@@ -367,6 +398,9 @@ func PostAudioConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, u
 
 	tokenName := ctx.GetString("token_name")
 	completionRatio := decimal.NewFromFloat(ratio_setting.GetCompletionRatio(relayInfo.OriginModelName))
+	if tierRatio := tierCompletionRatio(relayInfo); tierRatio != nil {
+		completionRatio = decimal.NewFromFloat(*tierRatio)
+	}
 	audioRatio := decimal.NewFromFloat(ratio_setting.GetAudioRatio(relayInfo.OriginModelName))
 	audioCompletionRatio := decimal.NewFromFloat(ratio_setting.GetAudioCompletionRatio(relayInfo.OriginModelName))
 
@@ -384,10 +418,11 @@ func PostAudioConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, u
 			TextTokens:  textOutTokens,
 			AudioTokens: audioOutTokens,
 		},
-		ModelName:  relayInfo.OriginModelName,
-		UsePrice:   usePrice,
-		ModelRatio: modelRatio,
-		GroupRatio: groupRatio,
+		ModelName:       relayInfo.OriginModelName,
+		UsePrice:        usePrice,
+		ModelRatio:      modelRatio,
+		GroupRatio:      groupRatio,
+		CompletionRatio: tierCompletionRatio(relayInfo),
 	}
 
 	quota := calculateAudioQuota(quotaInfo)
@@ -466,8 +501,12 @@ func PreConsumeTokenQuota(relayInfo *relaycommon.RelayInfo, quota int) error {
 
 func PostConsumeQuota(relayInfo *relaycommon.RelayInfo, quota int, preConsumedQuota int, sendEmail bool) (err error) {
 
-	// 1) Consume from wallet quota OR subscription item
-	if relayInfo != nil && relayInfo.BillingSource == BillingSourceSubscription {
+	// 1) Consume from wallet quota, subscription item OR organization wallet
+	if relayInfo != nil && relayInfo.BillingSource == BillingSourceOrganization {
+		if err := model.AdjustOrganizationQuota(relayInfo.OrganizationId, relayInfo.UserId, quota); err != nil {
+			return err
+		}
+	} else if relayInfo != nil && relayInfo.BillingSource == BillingSourceSubscription {
 		if relayInfo.SubscriptionId == 0 {
 			return errors.New("subscription id is missing")
 		}
@@ -501,7 +540,7 @@ func PostConsumeQuota(relayInfo *relaycommon.RelayInfo, quota int, preConsumedQu
 		}
 	}
 
-	if sendEmail {
+	if sendEmail && relayInfo.BillingSource != BillingSourceOrganization {
 		if (quota + preConsumedQuota) != 0 {
 			checkAndSendQuotaNotify(relayInfo, quota, preConsumedQuota)
 		}
@@ -0,0 +1,335 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/pkg/cachex"
+	"github.com/QuantumNous/new-api/pkg/metrics"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/hot"
+)
+
+const (
+	responseCacheNamespace = "new-api:response_cache:v1"
+	// 响应体写入磁盘时使用独立目录，避免被请求体缓存的定时清理删除
+	responseCacheDiskDir = "new-api-response-cache"
+)
+
+// ResponseCacheEntry 缓存的响应，Body 与 FilePath 二选一
+type ResponseCacheEntry struct {
+	Body        []byte    `json:"body,omitempty"`
+	FilePath    string    `json:"file_path,omitempty"`
+	ContentType string    `json:"content_type"`
+	IsStream    bool      `json:"is_stream"`
+	Usage       dto.Usage `json:"usage"`
+	CreatedAt   int64     `json:"created_at"`
+}
+
+var (
+	responseCache     *cachex.HybridCache[ResponseCacheEntry]
+	responseCacheOnce sync.Once
+
+	responseCacheJanitorOnce sync.Once
+)
+
+func getResponseCache() *cachex.HybridCache[ResponseCacheEntry] {
+	responseCacheOnce.Do(func() {
+		setting := operation_setting.GetResponseCacheSetting()
+		capacity := setting.MaxEntries
+		if capacity <= 0 {
+			capacity = 10000
+		}
+		responseCache = cachex.NewHybridCache[ResponseCacheEntry](cachex.HybridCacheConfig[ResponseCacheEntry]{
+			Namespace: cachex.Namespace(responseCacheNamespace),
+			Redis:     common.RDB,
+			RedisEnabled: func() bool {
+				return common.RedisEnabled && common.RDB != nil
+			},
+			RedisCodec: cachex.JSONCodec[ResponseCacheEntry]{},
+			Memory: func() *hot.HotCache[string, ResponseCacheEntry] {
+				return hot.NewHotCache[string, ResponseCacheEntry](hot.LRU, capacity).
+					WithTTL(responseCacheTTL()).
+					WithJanitor().
+					Build()
+			},
+		})
+	})
+	return responseCache
+}
+
+func responseCacheTTL() time.Duration {
+	ttl := operation_setting.GetResponseCacheSetting().TTLSeconds
+	if ttl <= 0 {
+		ttl = 3600
+	}
+	return time.Duration(ttl) * time.Second
+}
+
+// ShouldUseResponseCache 判断本次请求是否启用响应缓存
+// 客户端可通过 Cache-Control: no-store 跳过缓存
+func ShouldUseResponseCache(c *gin.Context, info *relaycommon.RelayInfo) bool {
+	if !operation_setting.IsResponseCacheEnabledForModel(info.OriginModelName) {
+		return false
+	}
+	if info.IsPlayground || info.BatchId != "" {
+		return false
+	}
+	return !strings.Contains(strings.ToLower(c.GetHeader("Cache-Control")), "no-store")
+}
+
+// normalizeResponseCacheBody 规范化请求体：按键排序、去除空白与不影响响应内容的字段
+func normalizeResponseCacheBody(body []byte) []byte {
+	var parsed map[string]any
+	if err := common.Unmarshal(body, &parsed); err != nil {
+		return body
+	}
+	delete(parsed, "user")
+	normalized, err := common.Marshal(parsed)
+	if err != nil {
+		return body
+	}
+	return normalized
+}
+
+// ResponseCacheKey 根据模型映射与参数覆盖后的最终请求体计算缓存键，不同用户之间不共享缓存
+func ResponseCacheKey(info *relaycommon.RelayInfo, requestBody []byte) string {
+	h := sha256.New()
+	h.Write([]byte(strings.Join([]string{
+		strconv.Itoa(info.UserId),
+		strconv.Itoa(info.RelayMode),
+		string(info.RelayFormat),
+		info.OriginModelName,
+		strconv.FormatBool(info.ShouldIncludeUsage),
+	}, "|")))
+	h.Write([]byte{'|'})
+	h.Write(normalizeResponseCacheBody(requestBody))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// GetCachedResponse 查询缓存的响应
+// 客户端可通过 Cache-Control: no-cache 强制请求上游（响应仍会写入缓存）
+func GetCachedResponse(c *gin.Context, info *relaycommon.RelayInfo, key string) (*ResponseCacheEntry, bool) {
+	if strings.Contains(strings.ToLower(c.GetHeader("Cache-Control")), "no-cache") {
+		return nil, false
+	}
+	entry, found, err := getResponseCache().Get(key)
+	if err != nil {
+		logger.LogWarn(c, "response cache get failed: "+err.Error())
+		found = false
+	}
+	if found && entry.FilePath != "" {
+		body, readErr := os.ReadFile(entry.FilePath)
+		if readErr != nil {
+			// 磁盘文件仅在写入的实例上存在，或已被清理
+			found = false
+		} else {
+			entry.Body = body
+		}
+	}
+	metrics.ObserveResponseCacheLookup(info.OriginModelName, found)
+	if !found {
+		return nil, false
+	}
+	return &entry, true
+}
+
+// ReplayCachedResponse 将缓存的响应写回客户端，流式响应按 SSE 事件逐条写出
+func ReplayCachedResponse(c *gin.Context, info *relaycommon.RelayInfo, entry *ResponseCacheEntry) *dto.Usage {
+	info.ResponseCacheHit = true
+	info.IsStream = entry.IsStream
+	info.SetFirstResponseTime()
+	c.Header("X-Response-Cache", "hit")
+	if !entry.IsStream {
+		c.Data(http.StatusOK, entry.ContentType, entry.Body)
+	} else {
+		c.Writer.Header().Set("Content-Type", "text/event-stream")
+		c.Writer.Header().Set("Cache-Control", "no-cache")
+		c.Writer.Header().Set("Connection", "keep-alive")
+		c.Writer.Header().Set("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		events := bytes.SplitAfter(entry.Body, []byte("\n\n"))
+		for _, event := range events {
+			if len(event) == 0 {
+				continue
+			}
+			if _, err := c.Writer.Write(event); err != nil {
+				break
+			}
+			c.Writer.Flush()
+		}
+	}
+	usage := entry.Usage
+	return &usage
+}
+
+// ResponseCaptureWriter 在写回客户端的同时记录响应体，超过上限后停止记录
+type ResponseCaptureWriter struct {
+	gin.ResponseWriter
+	origin   gin.ResponseWriter
+	buf      bytes.Buffer
+	limit    int
+	overflow bool
+	// keepPrefix 超过上限时保留已记录的部分，否则丢弃全部内容
+	keepPrefix bool
+}
+
+func (w *ResponseCaptureWriter) capture(data []byte) {
+	if w.overflow {
+		return
+	}
+	if w.buf.Len()+len(data) > w.limit {
+		w.overflow = true
+		if w.keepPrefix {
+			w.buf.Write(data[:w.limit-w.buf.Len()])
+		} else {
+			w.buf.Reset()
+		}
+		return
+	}
+	w.buf.Write(data)
+}
+
+func (w *ResponseCaptureWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *ResponseCaptureWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Restore 恢复原始的 ResponseWriter
+func (w *ResponseCaptureWriter) Restore(c *gin.Context) {
+	if w != nil && c.Writer == w {
+		c.Writer = w.origin
+	}
+}
+
+// StartResponseCapture 开始记录写回客户端的响应，用于写入缓存
+func StartResponseCapture(c *gin.Context) *ResponseCaptureWriter {
+	limit := operation_setting.GetResponseCacheSetting().MaxBodyKB << 10
+	if limit <= 0 {
+		return nil
+	}
+	writer := &ResponseCaptureWriter{ResponseWriter: c.Writer, origin: c.Writer, limit: limit}
+	c.Writer = writer
+	return writer
+}
+
+// StoreCapturedResponse 将记录的响应写入缓存，仅缓存成功且有用量信息的响应
+func StoreCapturedResponse(writer *ResponseCaptureWriter, info *relaycommon.RelayInfo, key string, usage *dto.Usage) {
+	if writer == nil || writer.overflow || writer.buf.Len() == 0 {
+		return
+	}
+	if usage == nil || usage.TotalTokens <= 0 || writer.Status() != http.StatusOK {
+		return
+	}
+	// 音频按独立价格计费，不参与缓存
+	if usage.PromptTokensDetails.AudioTokens > 0 || usage.CompletionTokenDetails.AudioTokens > 0 {
+		return
+	}
+	entry := ResponseCacheEntry{
+		Body:        bytes.Clone(writer.buf.Bytes()),
+		ContentType: writer.Header().Get("Content-Type"),
+		IsStream:    info.IsStream,
+		Usage:       *usage,
+		CreatedAt:   common.GetTimestamp(),
+	}
+	gopool.Go(func() {
+		if err := storeResponseCacheEntry(key, entry); err != nil {
+			common.SysLog(fmt.Sprintf("response cache store failed: %v", err))
+		}
+	})
+}
+
+func storeResponseCacheEntry(key string, entry ResponseCacheEntry) error {
+	ttl := responseCacheTTL()
+	spill := operation_setting.GetResponseCacheSetting().DiskSpillKB << 10
+	if spill > 0 && len(entry.Body) > spill {
+		filePath, err := writeResponseCacheFile(key, entry.Body)
+		if err != nil {
+			return err
+		}
+		entry.FilePath = filePath
+		entry.Body = nil
+	}
+	return getResponseCache().SetWithTTL(key, entry, ttl)
+}
+
+func responseCacheFileDir() string {
+	cachePath := common.GetDiskCachePath()
+	if cachePath == "" {
+		cachePath = os.TempDir()
+	}
+	return filepath.Join(cachePath, responseCacheDiskDir)
+}
+
+func writeResponseCacheFile(key string, body []byte) (string, error) {
+	dir := responseCacheFileDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create response cache directory: %w", err)
+	}
+	responseCacheJanitorOnce.Do(func() {
+		gopool.Go(cleanupResponseCacheFiles)
+	})
+	filePath := filepath.Join(dir, key+".cache")
+	if err := os.WriteFile(filePath, body, 0600); err != nil {
+		return "", fmt.Errorf("failed to write response cache file: %w", err)
+	}
+	return filePath, nil
+}
+
+// cleanupResponseCacheFiles 定期删除已过期的响应缓存文件
+func cleanupResponseCacheFiles() {
+	for {
+		time.Sleep(10 * time.Minute)
+		dir := responseCacheFileDir()
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		expired := time.Now().Add(-responseCacheTTL())
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			fileInfo, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			if fileInfo.ModTime().Before(expired) {
+				_ = os.Remove(filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+}
+
+// PurgeResponseCache 清空响应缓存及磁盘上的缓存文件
+func PurgeResponseCache() error {
+	if err := getResponseCache().Purge(); err != nil {
+		return err
+	}
+	err := os.RemoveAll(responseCacheFileDir())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
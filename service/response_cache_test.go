@@ -0,0 +1,49 @@
+package service
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseCacheKey_NormalizesRequestBody(t *testing.T) {
+	info := &relaycommon.RelayInfo{UserId: 1, OriginModelName: "gpt-4o-mini"}
+
+	a := ResponseCacheKey(info, []byte(`{"model":"gpt-4o-mini","messages":[{"role":"user","content":"hi"}],"user":"a"}`))
+	b := ResponseCacheKey(info, []byte(`{ "messages":[{"content":"hi","role":"user"}], "model":"gpt-4o-mini", "user":"b" }`))
+	require.Equal(t, a, b)
+
+	c := ResponseCacheKey(info, []byte(`{"model":"gpt-4o-mini","messages":[{"role":"user","content":"hello"}]}`))
+	require.NotEqual(t, a, c)
+
+	other := &relaycommon.RelayInfo{UserId: 2, OriginModelName: "gpt-4o-mini"}
+	require.NotEqual(t, a, ResponseCacheKey(other, []byte(`{"model":"gpt-4o-mini","messages":[{"role":"user","content":"hi"}]}`)))
+}
+
+func TestResponseCaptureWriter_StopsAtLimit(t *testing.T) {
+	setting := operation_setting.GetResponseCacheSetting()
+	original := setting.MaxBodyKB
+	setting.MaxBodyKB = 1
+	t.Cleanup(func() { setting.MaxBodyKB = original })
+
+	rec := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(rec)
+	origin := ctx.Writer
+
+	writer := StartResponseCapture(ctx)
+	require.NotNil(t, writer)
+	_, _ = ctx.Writer.WriteString("data: {}\n\n")
+	require.Equal(t, "data: {}\n\n", writer.buf.String())
+
+	_, _ = ctx.Writer.Write(make([]byte, 2048))
+	require.True(t, writer.overflow)
+	require.Zero(t, writer.buf.Len())
+	require.Equal(t, 10+2048, rec.Body.Len())
+
+	writer.Restore(ctx)
+	require.Equal(t, origin, ctx.Writer)
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+const (
+	responseRecordCleanupTickInterval = 6 * time.Hour
+	responseRecordCleanupBatchSize    = 1000
+)
+
+var (
+	responseRecordCleanupOnce    sync.Once
+	responseRecordCleanupRunning atomic.Bool
+)
+
+// StartResponseRecordCleanupTask 定期清理已过期的 Responses API 响应记录
+func StartResponseRecordCleanupTask() {
+	responseRecordCleanupOnce.Do(func() {
+		if !common.IsMasterNode {
+			return
+		}
+		gopool.Go(func() {
+			logger.LogInfo(context.Background(), fmt.Sprintf("response record cleanup task started: tick=%s", responseRecordCleanupTickInterval))
+			ticker := time.NewTicker(responseRecordCleanupTickInterval)
+			defer ticker.Stop()
+
+			runResponseRecordCleanupOnce()
+			for range ticker.C {
+				runResponseRecordCleanupOnce()
+			}
+		})
+	})
+}
+
+func runResponseRecordCleanupOnce() {
+	if !responseRecordCleanupRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer responseRecordCleanupRunning.Store(false)
+
+	ctx := context.Background()
+	deleted, err := model.DeleteExpiredResponseRecords(ctx, responseRecordCleanupBatchSize)
+	if err != nil {
+		logger.LogWarn(ctx, fmt.Sprintf("response record cleanup task failed: %v", err))
+		return
+	}
+	if common.DebugEnabled && deleted > 0 {
+		logger.LogDebug(ctx, "response record cleanup: deleted_count=%d", deleted)
+	}
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/pkg/cachex"
+	"github.com/QuantumNous/new-api/service/openaicompat"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/samber/hot"
+	"gorm.io/gorm"
+)
+
+// 渠道不支持原生 Responses API 时由网关保存响应，用于展开 previous_response_id
+const (
+	responseRecordCacheNamespace = "new-api:response_record:v1"
+	responseRecordCacheTTL       = time.Hour
+	// responsesConversationMaxDepth previous_response_id 链的最大展开深度
+	responsesConversationMaxDepth = 1000
+)
+
+var ErrResponseNotFound = errors.New("response not found")
+
+var (
+	responseRecordCacheOnce sync.Once
+	responseRecordCache     *cachex.HybridCache[model.ResponseRecord]
+)
+
+func getResponseRecordCache() *cachex.HybridCache[model.ResponseRecord] {
+	responseRecordCacheOnce.Do(func() {
+		capacity := common.GetEnvOrDefault("RESPONSE_RECORD_CACHE_CAP", 2000)
+		if capacity <= 0 {
+			capacity = 2000
+		}
+		responseRecordCache = cachex.NewHybridCache[model.ResponseRecord](cachex.HybridCacheConfig[model.ResponseRecord]{
+			Namespace:    cachex.Namespace(responseRecordCacheNamespace),
+			Redis:        common.RDB,
+			RedisEnabled: responseRecordCacheShared,
+			RedisCodec:   cachex.JSONCodec[model.ResponseRecord]{},
+			Memory: func() *hot.HotCache[string, model.ResponseRecord] {
+				return hot.NewHotCache[string, model.ResponseRecord](hot.LRU, capacity).
+					WithTTL(responseRecordCacheTTL).
+					WithJanitor().
+					Build()
+			},
+		})
+	})
+	return responseRecordCache
+}
+
+// responseRecordCacheShared 缓存是否由各节点共享（Redis），删除后所有节点立即可见
+func responseRecordCacheShared() bool {
+	return common.RedisEnabled && common.RDB != nil
+}
+
+// ResponsesStoreEnabled 网关存储已开启且请求未指定 store=false
+func ResponsesStoreEnabled(req *dto.OpenAIResponsesRequest) bool {
+	return operation_setting.GetResponsesStoreSetting().Enabled && openaicompat.ResponsesStoreEnabled(req)
+}
+
+// GetResponseRecord 读取响应记录，记录创建后不再修改，优先从缓存读取
+func GetResponseRecord(responseId string, userId int, tokenId int) (*model.ResponseRecord, error) {
+	record, found, err := getResponseRecordCache().Get(responseId)
+	if err == nil && found && !responseRecordCacheShared() {
+		// 仅有本地内存缓存时，其他节点的删除无法使缓存失效，需确认记录仍存在
+		exists, err := model.ResponseRecordExists(responseId)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			_, _ = getResponseRecordCache().DeleteMany([]string{responseId})
+			return nil, ErrResponseNotFound
+		}
+	}
+	if err != nil || !found {
+		dbRecord, err := model.GetResponseRecord(responseId, userId, tokenId)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrResponseNotFound
+			}
+			return nil, err
+		}
+		record = *dbRecord
+		_ = getResponseRecordCache().SetWithTTL(responseId, record, responseRecordCacheTTL)
+	}
+	if record.UserId != userId || record.TokenId != tokenId || record.IsExpired() {
+		return nil, ErrResponseNotFound
+	}
+	return &record, nil
+}
+
+func DeleteResponseRecord(responseId string, userId int, tokenId int) (bool, error) {
+	deleted, err := model.DeleteResponseRecord(responseId, userId, tokenId)
+	if err != nil {
+		return false, err
+	}
+	_, _ = getResponseRecordCache().DeleteMany([]string{responseId})
+	return deleted, nil
+}
+
+// responseRecordInputItems 返回记录中本轮的输入条目
+func responseRecordInputItems(record *model.ResponseRecord) ([]json.RawMessage, error) {
+	var items []json.RawMessage
+	if record.Input == "" {
+		return items, nil
+	}
+	err := common.UnmarshalJsonStr(record.Input, &items)
+	return items, err
+}
+
+func responseRecordOutputItems(record *model.ResponseRecord) ([]json.RawMessage, error) {
+	var response struct {
+		Output []json.RawMessage `json:"output"`
+	}
+	err := common.UnmarshalJsonStr(record.Response, &response)
+	return response.Output, err
+}
+
+// LoadResponsesConversation 沿 previous_response_id 链展开完整会话，按时间顺序返回各轮的输入与输出条目
+// 与 OpenAI 一致，instructions 不会被继承
+func LoadResponsesConversation(responseId string, userId int, tokenId int) ([]json.RawMessage, error) {
+	if !operation_setting.GetResponsesStoreSetting().Enabled {
+		return nil, ErrResponseNotFound
+	}
+	var turns [][]json.RawMessage
+	for id := responseId; id != ""; {
+		if len(turns) >= responsesConversationMaxDepth {
+			return nil, fmt.Errorf("conversation exceeds %d responses", responsesConversationMaxDepth)
+		}
+		record, err := GetResponseRecord(id, userId, tokenId)
+		if err != nil {
+			return nil, err
+		}
+		input, err := responseRecordInputItems(record)
+		if err != nil {
+			return nil, err
+		}
+		output, err := responseRecordOutputItems(record)
+		if err != nil {
+			return nil, err
+		}
+		turns = append(turns, append(input, output...))
+		id = record.PreviousResponseId
+	}
+	items := make([]json.RawMessage, 0)
+	for i := len(turns) - 1; i >= 0; i-- {
+		items = append(items, turns[i]...)
+	}
+	return items, nil
+}
+
+// ResponseConversationInputItems 返回生成该响应时的完整输入条目，即历史会话加上本轮输入
+// 链上更早的响应已过期或被删除时仅返回仍可读取的部分
+func ResponseConversationInputItems(record *model.ResponseRecord) ([]json.RawMessage, error) {
+	items := make([]json.RawMessage, 0)
+	if record.PreviousResponseId != "" {
+		history, err := LoadResponsesConversation(record.PreviousResponseId, record.UserId, record.TokenId)
+		if err != nil && !errors.Is(err, ErrResponseNotFound) {
+			return nil, err
+		}
+		items = append(items, history...)
+	}
+	input, err := responseRecordInputItems(record)
+	if err != nil {
+		return nil, err
+	}
+	return append(items, input...), nil
+}
+
+// assignResponsesInputItemIds 为缺少 id 的输入条目补充 id，供 /input_items 接口返回
+func assignResponsesInputItemIds(items []json.RawMessage) []json.RawMessage {
+	result := make([]json.RawMessage, 0, len(items))
+	for _, item := range items {
+		var m map[string]any
+		if err := common.Unmarshal(item, &m); err != nil || m["id"] != nil {
+			result = append(result, item)
+			continue
+		}
+		prefix := "item"
+		if itemType := common.Interface2String(m["type"]); itemType == "" || itemType == "message" {
+			prefix = "msg"
+		}
+		m["id"] = fmt.Sprintf("%s_%s", prefix, common.GetUUID())
+		if raw, err := common.Marshal(m); err == nil {
+			item = raw
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+// SaveResponseRecord 保存桥接模式下生成的响应；input 为请求中本轮的原始输入
+func SaveResponseRecord(userId int, tokenId int, request *dto.OpenAIResponsesRequest, input json.RawMessage, response *dto.OpenAIResponsesResponse) error {
+	items, err := ResponsesInputItems(input)
+	if err != nil {
+		return err
+	}
+	inputData, err := common.Marshal(assignResponsesInputItemIds(items))
+	if err != nil {
+		return err
+	}
+	responseData, err := common.Marshal(response)
+	if err != nil {
+		return err
+	}
+	record := &model.ResponseRecord{
+		ResponseId:         response.ID,
+		UserId:             userId,
+		TokenId:            tokenId,
+		PreviousResponseId: request.PreviousResponseID,
+		ModelName:          response.Model,
+		Input:              string(inputData),
+		Response:           string(responseData),
+	}
+	if ttlHours := operation_setting.GetResponsesStoreSetting().TTLHours; ttlHours > 0 {
+		record.ExpiresAt = common.GetTimestamp() + int64(ttlHours)*3600
+	}
+	return record.Insert()
+}
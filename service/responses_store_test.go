@@ -0,0 +1,93 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/goccy/go-json"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/stretchr/testify/require"
+)
+
+func enableResponsesStore(t *testing.T) {
+	t.Helper()
+	setting := operation_setting.GetResponsesStoreSetting()
+	original := setting.Enabled
+	setting.Enabled = true
+	t.Cleanup(func() {
+		setting.Enabled = original
+		model.DB.Exec("DELETE FROM response_records")
+	})
+}
+
+func saveTestResponse(t *testing.T, id string, previousId string, input string, outputText string) {
+	t.Helper()
+	request := &dto.OpenAIResponsesRequest{Input: json.RawMessage(input), PreviousResponseID: previousId}
+	response := NewResponsesResponse(id, "m", request)
+	response.Output = []dto.ResponsesOutput{{
+		Type:    "message",
+		ID:      "msg_" + id,
+		Status:  "completed",
+		Role:    "assistant",
+		Content: []dto.ResponsesOutputContent{{Type: "output_text", Text: outputText}},
+	}}
+	require.NoError(t, SaveResponseRecord(1, 2, request, request.Input, response))
+}
+
+func TestResponsesConversationChain(t *testing.T) {
+	enableResponsesStore(t)
+	saveTestResponse(t, "resp_a", "", `"hello"`, "hi")
+	saveTestResponse(t, "resp_b", "resp_a", `[{"role":"user","content":"again"}]`, "hi again")
+
+	items, err := LoadResponsesConversation("resp_b", 1, 2)
+	require.NoError(t, err)
+	require.Len(t, items, 4)
+	roles := make([]string, 0, len(items))
+	for _, item := range items {
+		var v struct {
+			Id   string `json:"id"`
+			Role string `json:"role"`
+		}
+		require.NoError(t, common.Unmarshal(item, &v))
+		require.NotEmpty(t, v.Id)
+		roles = append(roles, v.Role)
+	}
+	require.Equal(t, []string{"user", "assistant", "user", "assistant"}, roles)
+
+	// 其他令牌无法读取
+	_, err = LoadResponsesConversation("resp_b", 1, 3)
+	require.ErrorIs(t, err, ErrResponseNotFound)
+
+	record, err := GetResponseRecord("resp_b", 1, 2)
+	require.NoError(t, err)
+	inputItems, err := ResponseConversationInputItems(record)
+	require.NoError(t, err)
+	require.Len(t, inputItems, 3)
+
+	deleted, err := DeleteResponseRecord("resp_a", 1, 2)
+	require.NoError(t, err)
+	require.True(t, deleted)
+	_, err = LoadResponsesConversation("resp_b", 1, 2)
+	require.ErrorIs(t, err, ErrResponseNotFound)
+}
+
+func TestResponseRecordDeletedByOtherNode(t *testing.T) {
+	enableResponsesStore(t)
+	saveTestResponse(t, "resp_node", "", `"hello"`, "hi")
+
+	// 读取后记录进入本地内存缓存
+	_, err := GetResponseRecord("resp_node", 1, 2)
+	require.NoError(t, err)
+
+	// 其他节点删除记录时本节点的内存缓存不会被清除，读取时需以数据库为准
+	deleted, err := model.DeleteResponseRecord("resp_node", 1, 2)
+	require.NoError(t, err)
+	require.True(t, deleted)
+	_, err = GetResponseRecord("resp_node", 1, 2)
+	require.ErrorIs(t, err, ErrResponseNotFound)
+	_, err = LoadResponsesConversation("resp_node", 1, 2)
+	require.ErrorIs(t, err, ErrResponseNotFound)
+}
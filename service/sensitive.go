@@ -17,7 +17,7 @@ func CheckSensitiveMessages(messages []dto.Message) ([]string, error) {
 		arrayContent := message.ParseContent()
 		for _, m := range arrayContent {
 			if m.Type == "image_url" {
-				// TODO: check image url
+				// 图片内容由内容审核的 moderation_api 提供方审核，见 ModeratePrompt
 				continue
 			}
 			// 检查 text 是否为空
@@ -0,0 +1,411 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	relayconstant "github.com/QuantumNous/new-api/relay/constant"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+type streamFailoverLineAction int
+
+const (
+	streamFailoverForward streamFailoverLineAction = iota
+	streamFailoverHold
+	streamFailoverDrop
+)
+
+// StreamFailoverWriter 流式续传。
+// 转发对话补全流式输出的同时记录已下发的内容，用量与 [DONE] 等结束事件暂扣到本次尝试结束；
+// 上游中途断开或停滞（未收到 finish_reason）时丢弃暂扣的结束事件，由下一次尝试携带已输出内容续写，
+// 续写的输出改写为首次响应的 id 后拼接到同一个 SSE 流中
+type StreamFailoverWriter struct {
+	gin.ResponseWriter
+	origin gin.ResponseWriter
+	c      *gin.Context
+
+	maxFailovers int
+	prompt       string
+
+	// 跨尝试状态
+	failovers       int
+	channelIds      []int
+	content         strings.Builder
+	usage           dto.Usage
+	maxPromptTokens int
+	id              string
+	created         int64
+	completed       bool
+
+	// 本次尝试状态
+	line        []byte
+	action      streamFailoverLineAction
+	tail        bytes.Buffer
+	attemptText strings.Builder
+	finished    bool
+	toolCalls   bool
+	trimLeading bool
+	roleDropped bool
+}
+
+// ShouldUseStreamFailover 是否对该请求启用流式续传，仅支持 n=1 的 OpenAI 格式流式对话补全
+func ShouldUseStreamFailover(c *gin.Context, info *relaycommon.RelayInfo) bool {
+	setting := operation_setting.GetStreamFailoverSetting()
+	if !setting.Enabled || setting.MaxFailovers <= 0 || !info.IsStream {
+		return false
+	}
+	if info.RelayFormat != types.RelayFormatOpenAI || info.RelayMode != relayconstant.RelayModeChatCompletions {
+		return false
+	}
+	// 指定渠道时无法切换渠道
+	if _, ok := c.Get("specific_channel_id"); ok {
+		return false
+	}
+	request, ok := info.Request.(*dto.GeneralOpenAIRequest)
+	if !ok {
+		return false
+	}
+	return request.N == nil || *request.N <= 1
+}
+
+// StartStreamFailover 开始流式续传，不需要续传时返回 nil
+func StartStreamFailover(c *gin.Context, info *relaycommon.RelayInfo) *StreamFailoverWriter {
+	if !ShouldUseStreamFailover(c, info) {
+		return nil
+	}
+	setting := operation_setting.GetStreamFailoverSetting()
+	if setting.IdleTimeoutSeconds > 0 {
+		info.StreamIdleTimeout = time.Duration(setting.IdleTimeoutSeconds) * time.Second
+	}
+	writer := &StreamFailoverWriter{
+		ResponseWriter: c.Writer,
+		origin:         c.Writer,
+		c:              c,
+		maxFailovers:   setting.MaxFailovers,
+		prompt:         setting.ContinuationPrompt,
+	}
+	c.Writer = writer
+	common.SetContextKey(c, constant.ContextKeyStreamFailover, writer)
+	return writer
+}
+
+// GetStreamFailover 获取当前请求的流式续传，未启用时返回 nil
+func GetStreamFailover(c *gin.Context) *StreamFailoverWriter {
+	if v, ok := common.GetContextKey(c, constant.ContextKeyStreamFailover); ok {
+		if writer, ok := v.(*StreamFailoverWriter); ok {
+			return writer
+		}
+	}
+	return nil
+}
+
+func (w *StreamFailoverWriter) Write(data []byte) (int, error) {
+	w.line = append(w.line, data...)
+	for {
+		idx := bytes.IndexByte(w.line, '\n')
+		if idx < 0 {
+			break
+		}
+		line := bytes.Clone(w.line[:idx+1])
+		w.line = w.line[idx+1:]
+		if err := w.handleLine(line); err != nil {
+			return 0, err
+		}
+	}
+	return len(data), nil
+}
+
+func (w *StreamFailoverWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *StreamFailoverWriter) handleLine(line []byte) error {
+	trimmed := bytes.TrimRight(line, "\r\n")
+	if len(trimmed) > 0 {
+		w.action = streamFailoverForward
+		if data, ok := sseDataPayload(line); ok {
+			var rewritten []byte
+			w.action, rewritten = w.handleData(data)
+			if rewritten != nil {
+				line = append(append([]byte("data: "), rewritten...), '\n')
+			}
+		}
+	}
+	switch w.action {
+	case streamFailoverHold:
+		w.tail.Write(line)
+		return nil
+	case streamFailoverDrop:
+		return nil
+	}
+	_, err := w.origin.Write(line)
+	return err
+}
+
+// handleData 记录输出内容并决定事件的去向，续写时返回改写后的事件
+func (w *StreamFailoverWriter) handleData(data []byte) (streamFailoverLineAction, []byte) {
+	if !gjson.ValidBytes(data) {
+		// [DONE]
+		return streamFailoverHold, nil
+	}
+	event := gjson.ParseBytes(data)
+	choices := event.Get("choices").Array()
+	if len(choices) == 0 {
+		// 用量、错误等不含输出的事件
+		return streamFailoverHold, nil
+	}
+	if w.id == "" {
+		w.id = event.Get("id").String()
+		w.created = event.Get("created").Int()
+	}
+
+	choice := choices[0]
+	delta := choice.Get("delta")
+	content := delta.Get("content").String()
+	reasoning := delta.Get("reasoning_content").String() + delta.Get("reasoning").String()
+	finishReason := choice.Get("finish_reason").String()
+	hasToolCalls := len(delta.Get("tool_calls").Array()) > 0 || delta.Get("function_call").Exists()
+	if hasToolCalls {
+		w.toolCalls = true
+	}
+	if finishReason != "" {
+		w.finished = true
+	}
+	// 与 finish_reason 一同返回的用量同样暂扣，续写时改写为合计用量
+	action := streamFailoverForward
+	if finishReason != "" && event.Get("usage").IsObject() {
+		action = streamFailoverHold
+	}
+	if w.failovers == 0 {
+		w.content.WriteString(content)
+		w.attemptText.WriteString(content + reasoning)
+		return action, nil
+	}
+
+	// 续写：去掉开头的角色事件与重复的空白，并改写为首次响应的 id
+	if content == "" && reasoning == "" && finishReason == "" && !hasToolCalls && !w.roleDropped && delta.Get("role").Exists() {
+		w.roleDropped = true
+		return streamFailoverDrop, nil
+	}
+	if w.trimLeading && content != "" {
+		trimmedContent := strings.TrimLeftFunc(content, unicode.IsSpace)
+		if trimmedContent != content {
+			content = trimmedContent
+			data, _ = sjson.SetBytes(data, "choices.0.delta.content", content)
+		}
+		if content != "" {
+			w.trimLeading = false
+		} else if reasoning == "" && finishReason == "" && !hasToolCalls {
+			return streamFailoverDrop, nil
+		}
+	}
+	w.content.WriteString(content)
+	w.attemptText.WriteString(content + reasoning)
+	return action, w.rewriteId(data)
+}
+
+func (w *StreamFailoverWriter) rewriteId(data []byte) []byte {
+	if w.id == "" {
+		return data
+	}
+	data, _ = sjson.SetBytes(data, "id", w.id)
+	if w.created > 0 {
+		data, _ = sjson.SetBytes(data, "created", w.created)
+	}
+	return data
+}
+
+func (w *StreamFailoverWriter) Flush() {
+	w.origin.Flush()
+}
+
+// Failovers 已续传次数，nil 时为 0
+func (w *StreamFailoverWriter) Failovers() int {
+	if w == nil {
+		return 0
+	}
+	return w.failovers
+}
+
+// Resumed 当前尝试是否为续写
+func (w *StreamFailoverWriter) Resumed() bool {
+	return w != nil && w.failovers > 0
+}
+
+// Pending 已向客户端输出内容但流尚未正常结束
+func (w *StreamFailoverWriter) Pending() bool {
+	return w != nil && !w.completed && w.origin.Written()
+}
+
+// Interrupted 本次尝试是否已输出内容但在 finish_reason 之前中断
+// 没有任何输出时（如上游在首个事件前报错）按普通错误重试；已输出工具调用时无法续写，按原样结束
+func (w *StreamFailoverWriter) Interrupted() bool {
+	return w.attemptText.Len() > 0 && !w.finished && !w.toolCalls
+}
+
+// CanFailover 是否还可以续传，客户端已断开时不再续传
+func (w *StreamFailoverWriter) CanFailover() bool {
+	if w.failovers >= w.maxFailovers {
+		return false
+	}
+	return w.c.Request.Context().Err() == nil
+}
+
+// attemptUsage 本次尝试的用量，渠道未返回用量时按已输出内容估算
+func (w *StreamFailoverWriter) attemptUsage(info *relaycommon.RelayInfo, usage *dto.Usage) *dto.Usage {
+	if usage != nil {
+		return usage
+	}
+	return ResponseText2Usage(w.c, w.attemptText.String(), info.UpstreamModelName, info.GetEstimatePromptTokens())
+}
+
+func (w *StreamFailoverWriter) addUsage(usage *dto.Usage) {
+	w.usage.PromptTokens += usage.PromptTokens
+	w.usage.CompletionTokens += usage.CompletionTokens
+	w.usage.TotalTokens += usage.TotalTokens
+	w.usage.PromptTokensDetails.CachedTokens += usage.PromptTokensDetails.CachedTokens
+	w.usage.PromptTokensDetails.CachedCreationTokens += usage.PromptTokensDetails.CachedCreationTokens
+	w.usage.PromptTokensDetails.TextTokens += usage.PromptTokensDetails.TextTokens
+	w.usage.PromptTokensDetails.ImageTokens += usage.PromptTokensDetails.ImageTokens
+	w.usage.CompletionTokenDetails.TextTokens += usage.CompletionTokenDetails.TextTokens
+	w.usage.CompletionTokenDetails.ReasoningTokens += usage.CompletionTokenDetails.ReasoningTokens
+	w.usage.ClaudeCacheCreation5mTokens += usage.ClaudeCacheCreation5mTokens
+	w.usage.ClaudeCacheCreation1hTokens += usage.ClaudeCacheCreation1hTokens
+	w.maxPromptTokens = max(w.maxPromptTokens, usage.PromptTokens)
+}
+
+// MaxPromptTokens 各次尝试中最大的提示 tokens，用于匹配分档计价
+func (w *StreamFailoverWriter) MaxPromptTokens() int {
+	return w.maxPromptTokens
+}
+
+func (w *StreamFailoverWriter) resetAttempt() {
+	w.line = nil
+	w.action = streamFailoverForward
+	w.tail.Reset()
+	w.attemptText.Reset()
+	w.finished = false
+	w.toolCalls = false
+	w.roleDropped = false
+}
+
+// Suspend 中断本次尝试：记录用量，丢弃暂扣的结束事件，等待下一次尝试续写
+func (w *StreamFailoverWriter) Suspend(info *relaycommon.RelayInfo, usage *dto.Usage) {
+	w.addUsage(w.attemptUsage(info, usage))
+	w.channelIds = append(w.channelIds, info.ChannelId)
+	w.failovers++
+	w.resetAttempt()
+}
+
+// ApplyContinuation 将已输出内容追加到续写请求中。
+// prefill 为 true 时以 assistant 消息预填充，否则追加续写提示
+func (w *StreamFailoverWriter) ApplyContinuation(request *dto.GeneralOpenAIRequest, prefill bool) {
+	content := w.content.String()
+	if strings.TrimSpace(content) == "" {
+		return
+	}
+	// 预填充内容不能以空白结尾，续写开头的空白已下发过
+	trimmed := strings.TrimRightFunc(content, unicode.IsSpace)
+	w.trimLeading = trimmed != content
+	request.Messages = append(request.Messages, dto.Message{Role: "assistant", Content: trimmed})
+	if !prefill {
+		request.Messages = append(request.Messages, dto.Message{Role: "user", Content: w.prompt})
+	}
+}
+
+// Complete 正常结束：下发暂扣的结束事件，返回所有尝试的合计用量
+func (w *StreamFailoverWriter) Complete(info *relaycommon.RelayInfo, usage *dto.Usage) *dto.Usage {
+	w.completed = true
+	if w.failovers == 0 {
+		w.flushTail(nil)
+		return usage
+	}
+	w.addUsage(w.attemptUsage(info, usage))
+	merged := w.usage
+	w.flushTail(&merged)
+	return &merged
+}
+
+// Abort 续传失败：向客户端发送错误事件结束流，返回各次尝试的合计用量
+// 本次尝试没有任何输出时（如未能选到渠道、上游直接报错）不计入用量
+func (w *StreamFailoverWriter) Abort(info *relaycommon.RelayInfo, apiErr *types.NewAPIError) *dto.Usage {
+	w.completed = true
+	if w.attemptText.Len() > 0 {
+		w.addUsage(w.attemptUsage(info, nil))
+		w.channelIds = append(w.channelIds, info.ChannelId)
+	}
+	data, _ := common.Marshal(map[string]any{"error": apiErr.ToOpenAIError()})
+	_, _ = w.origin.WriteString("data: " + string(data) + "\n\ndata: [DONE]\n\n")
+	w.origin.Flush()
+	merged := w.usage
+	return &merged
+}
+
+// flushTail 下发暂扣的事件，续写时将用量事件改写为合计用量
+func (w *StreamFailoverWriter) flushTail(usage *dto.Usage) {
+	if len(w.line) > 0 {
+		w.tail.Write(w.line)
+		w.line = nil
+	}
+	if w.tail.Len() == 0 {
+		return
+	}
+	if usage == nil {
+		_, _ = w.origin.Write(w.tail.Bytes())
+		w.origin.Flush()
+		return
+	}
+	var out bytes.Buffer
+	for _, line := range bytes.SplitAfter(w.tail.Bytes(), []byte("\n")) {
+		data, ok := sseDataPayload(line)
+		if !ok || !gjson.ValidBytes(data) || !gjson.GetBytes(data, "usage").IsObject() {
+			out.Write(line)
+			continue
+		}
+		data, _ = sjson.SetBytes(data, "usage", usage)
+		out.WriteString("data: ")
+		out.Write(w.rewriteId(data))
+		out.WriteByte('\n')
+	}
+	_, _ = w.origin.Write(out.Bytes())
+	w.origin.Flush()
+}
+
+// LogContent 计费日志中的续传说明，channelId 为完成输出的渠道，续传失败时为 0
+func (w *StreamFailoverWriter) LogContent(channelId int) string {
+	ids := w.channelIds
+	if channelId > 0 {
+		ids = append(ids, channelId)
+	}
+	channels := make([]string, 0, len(ids))
+	for _, id := range ids {
+		channels = append(channels, fmt.Sprintf("#%d", id))
+	}
+	return fmt.Sprintf("流式续传 %d 次（渠道 %s），按各次尝试的合计用量计费", w.failovers, strings.Join(channels, " -> "))
+}
+
+// Restore 下发仍暂扣的事件并恢复原始的 ResponseWriter，需在写回错误响应之前调用
+func (w *StreamFailoverWriter) Restore() {
+	if w == nil {
+		return
+	}
+	if !w.completed && w.origin.Written() {
+		w.flushTail(nil)
+	}
+	if w.c.Writer == w {
+		w.c.Writer = w.origin
+	}
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamFailoverWriterSplice(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	writer := &StreamFailoverWriter{
+		ResponseWriter: c.Writer,
+		origin:         c.Writer,
+		c:              c,
+		maxFailovers:   1,
+		prompt:         "continue",
+	}
+	c.Writer = writer
+
+	// 没有任何输出时按普通错误处理，不触发续传
+	require.False(t, writer.Interrupted())
+
+	// 首次尝试输出部分内容后中断，结束事件被暂扣
+	_, _ = writer.WriteString("data: {\"id\":\"a\",\"created\":1,\"choices\":[{\"delta\":{\"role\":\"assistant\"}}]}\n\n")
+	_, _ = writer.WriteString("data: {\"id\":\"a\",\"created\":1,\"choices\":[{\"delta\":{\"content\":\"Hello \"}}]}\n\n")
+	_, _ = writer.WriteString("data: [DONE]\n\n")
+	require.True(t, writer.Interrupted())
+	require.True(t, writer.CanFailover())
+	require.NotContains(t, recorder.Body.String(), "[DONE]")
+
+	info := &relaycommon.RelayInfo{ChannelMeta: &relaycommon.ChannelMeta{ChannelId: 1}}
+	writer.Suspend(info, &dto.Usage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12})
+	require.True(t, writer.Resumed())
+	require.False(t, writer.CanFailover())
+
+	require.False(t, writer.Interrupted())
+
+	request := &dto.GeneralOpenAIRequest{Messages: []dto.Message{{Role: "user", Content: "hi"}}}
+	writer.ApplyContinuation(request, false)
+	require.Len(t, request.Messages, 3)
+	require.Equal(t, "Hello", request.Messages[1].StringContent())
+	require.Equal(t, "continue", request.Messages[2].StringContent())
+
+	// 续写去掉角色事件与开头的空白，并沿用首次响应的 id
+	_, _ = writer.WriteString("data: {\"id\":\"b\",\"created\":2,\"choices\":[{\"delta\":{\"role\":\"assistant\"}}]}\n\n")
+	_, _ = writer.WriteString("data: {\"id\":\"b\",\"created\":2,\"choices\":[{\"delta\":{\"content\":\" world\"}}]}\n\n")
+	_, _ = writer.WriteString("data: {\"id\":\"b\",\"created\":2,\"choices\":[{\"delta\":{},\"finish_reason\":\"stop\"}],\"usage\":{\"prompt_tokens\":12,\"completion_tokens\":1,\"total_tokens\":13}}\n\n")
+	_, _ = writer.WriteString("data: [DONE]\n\n")
+	require.False(t, writer.Interrupted())
+	require.NotContains(t, recorder.Body.String(), "finish_reason")
+
+	info.ChannelId = 2
+	usage := writer.Complete(info, &dto.Usage{PromptTokens: 12, CompletionTokens: 1, TotalTokens: 13})
+	require.Equal(t, 22, usage.PromptTokens)
+	require.Equal(t, 3, usage.CompletionTokens)
+	require.Equal(t, 12, writer.MaxPromptTokens())
+	require.Equal(t, "流式续传 1 次（渠道 #1 -> #2），按各次尝试的合计用量计费", writer.LogContent(info.ChannelId))
+
+	body := recorder.Body.String()
+	require.NotContains(t, body, `"id":"b"`)
+	require.Equal(t, 1, strings.Count(body, `"role":"assistant"`))
+	require.Equal(t, 1, strings.Count(body, "[DONE]"))
+	require.Contains(t, body, `"content":"world"`)
+	require.Contains(t, body, `"prompt_tokens":22`)
+
+	writer.Restore()
+	require.Equal(t, writer.origin, c.Writer)
+}
@@ -80,6 +80,7 @@ func sweepTimedOutTasks(ctx context.Context) {
 		if !isLegacy && task.Quota != 0 {
 			RefundTaskQuota(ctx, task, reason)
 		}
+		PublishTaskFinishedEvent(task)
 	}
 
 	if timedOutCount > 0 {
@@ -226,6 +227,7 @@ func updateSunoTasks(ctx context.Context, channelId int, taskIds []string, taskM
 			continue
 		}
 
+		wasDone := task.Status == model.TaskStatusSuccess || task.Status == model.TaskStatusFailure
 		task.Status = lo.If(model.TaskStatus(responseItem.Status) != "", model.TaskStatus(responseItem.Status)).Else(task.Status)
 		task.FailReason = lo.If(responseItem.FailReason != "", responseItem.FailReason).Else(task.FailReason)
 		task.SubmitTime = lo.If(responseItem.SubmitTime != 0, responseItem.SubmitTime).Else(task.SubmitTime)
@@ -244,6 +246,8 @@ func updateSunoTasks(ctx context.Context, channelId int, taskIds []string, taskM
 		err = task.Update()
 		if err != nil {
 			common.SysLog("UpdateSunoTask task error: " + err.Error())
+		} else if !wasDone && (task.Status == model.TaskStatusSuccess || task.Status == model.TaskStatusFailure) {
+			PublishTaskFinishedEvent(task)
 		}
 	}
 	return nil
@@ -325,12 +329,7 @@ func updateVideoTasks(ctx context.Context, platform constant.TaskPlatform, chann
 	if adaptor == nil {
 		return fmt.Errorf("video adaptor not found")
 	}
-	info := &relaycommon.RelayInfo{}
-	info.ChannelMeta = &relaycommon.ChannelMeta{
-		ChannelBaseUrl: cacheGetChannel.GetBaseURL(),
-	}
-	info.ApiKey = cacheGetChannel.Key
-	adaptor.Init(info)
+	adaptor.Init(newTaskPollingRelayInfo(cacheGetChannel))
 	for _, taskId := range taskIds {
 		if err := updateVideoSingleTask(ctx, adaptor, cacheGetChannel, taskId, taskM); err != nil {
 			logger.LogError(ctx, fmt.Sprintf("Failed to update video task %s: %s", taskId, err.Error()))
@@ -341,6 +340,27 @@ func updateVideoTasks(ctx context.Context, platform constant.TaskPlatform, chann
 	return nil
 }
 
+// newTaskPollingRelayInfo 按渠道配置构造轮询使用的 RelayInfo，
+// 渠道信息与提交任务时 SetupContextForSelectedChannel 写入上下文的保持一致
+func newTaskPollingRelayInfo(ch *model.Channel) *relaycommon.RelayInfo {
+	info := &relaycommon.RelayInfo{}
+	info.ChannelMeta = &relaycommon.ChannelMeta{
+		ChannelType:    ch.Type,
+		ChannelId:      ch.Id,
+		ChannelBaseUrl: ch.GetBaseURL(),
+		ApiKey:         ch.Key,
+	}
+	switch ch.Type {
+	case constant.ChannelTypeAzure, constant.ChannelTypeVertexAi:
+		// Azure 的 api_version 与 Vertex 的 region 均保存在 Other 字段
+		info.ApiVersion = ch.Other
+	}
+	if ch.OpenAIOrganization != nil {
+		info.Organization = *ch.OpenAIOrganization
+	}
+	return info
+}
+
 func updateVideoSingleTask(ctx context.Context, adaptor TaskPollingAdaptor, ch *model.Channel, taskId string, taskM map[string]*model.Task) error {
 	baseURL := constant.ChannelBaseURLs[ch.Type]
 	if ch.GetBaseURL() != "" {
@@ -471,6 +491,7 @@ func updateVideoSingleTask(ctx context.Context, adaptor TaskPollingAdaptor, ch *
 	}
 
 	isDone := task.Status == model.TaskStatusSuccess || task.Status == model.TaskStatusFailure
+	finished := false
 	if isDone && snap.Status != task.Status {
 		won, err := task.UpdateWithStatus(snap.Status)
 		if err != nil {
@@ -481,6 +502,8 @@ func updateVideoSingleTask(ctx context.Context, adaptor TaskPollingAdaptor, ch *
 			logger.LogWarn(ctx, fmt.Sprintf("Task %s already transitioned by another process, skip billing", task.TaskID))
 			shouldRefund = false
 			shouldSettle = false
+		} else {
+			finished = true
 		}
 	} else if !snap.Equal(task.Snapshot()) {
 		if _, err := task.UpdateWithStatus(snap.Status); err != nil {
@@ -493,10 +516,16 @@ func updateVideoSingleTask(ctx context.Context, adaptor TaskPollingAdaptor, ch *
 
 	if shouldSettle {
 		settleTaskBillingOnComplete(ctx, adaptor, task, taskResult)
+		if task.Platform == constant.TaskPlatformFineTune {
+			onFineTuneTaskSuccess(ctx, task)
+		}
 	}
 	if shouldRefund {
 		RefundTaskQuota(ctx, task, task.FailReason)
 	}
+	if finished {
+		PublishTaskFinishedEvent(task)
+	}
 
 	return nil
 }
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingAdaptor 记录轮询时传入的渠道信息
+type recordingAdaptor struct {
+	mockAdaptor
+	info    *relaycommon.RelayInfo
+	baseURL string
+	key     string
+}
+
+func (a *recordingAdaptor) Init(info *relaycommon.RelayInfo) { a.info = info }
+func (a *recordingAdaptor) FetchTask(baseURL string, key string, _ map[string]any, _ string) (*http.Response, error) {
+	a.baseURL = baseURL
+	a.key = key
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader([]byte(`{}`)))}, nil
+}
+func (a *recordingAdaptor) ParseTaskResult([]byte) (*relaycommon.TaskInfo, error) {
+	return &relaycommon.TaskInfo{Status: model.TaskStatusQueued}, nil
+}
+
+func TestNewTaskPollingRelayInfo(t *testing.T) {
+	baseURL := "https://example.openai.azure.com"
+	info := newTaskPollingRelayInfo(&model.Channel{
+		Id:      1,
+		Type:    constant.ChannelTypeAzure,
+		Key:     "azure-key",
+		BaseURL: &baseURL,
+		Other:   "2025-04-01-preview",
+	})
+	require.Equal(t, constant.ChannelTypeAzure, info.ChannelType)
+	require.Equal(t, baseURL, info.ChannelBaseUrl)
+	require.Equal(t, "azure-key", info.ApiKey)
+	require.Equal(t, "2025-04-01-preview", info.ApiVersion)
+	require.Empty(t, info.Organization)
+
+	organization := "org-test"
+	info = newTaskPollingRelayInfo(&model.Channel{
+		Id:                 2,
+		Type:               constant.ChannelTypeOpenAI,
+		Key:                "sk-test",
+		Other:              "ignored",
+		OpenAIOrganization: &organization,
+	})
+	require.Empty(t, info.ApiVersion)
+	require.Equal(t, organization, info.Organization)
+}
+
+func TestUpdateVideoTasksInitAdaptorWithChannel(t *testing.T) {
+	truncate(t)
+	originalFunc := GetTaskAdaptorFunc
+	t.Cleanup(func() { GetTaskAdaptorFunc = originalFunc })
+	originalCache := common.MemoryCacheEnabled
+	common.MemoryCacheEnabled = false
+	t.Cleanup(func() { common.MemoryCacheEnabled = originalCache })
+
+	organization := "org-polling"
+	baseURL := "https://example.openai.azure.com"
+	require.NoError(t, model.DB.Create(&model.Channel{
+		Id:                 40,
+		Name:               "azure",
+		Type:               constant.ChannelTypeAzure,
+		Key:                "azure-key",
+		BaseURL:            &baseURL,
+		Other:              "2025-04-01-preview",
+		OpenAIOrganization: &organization,
+		Status:             common.ChannelStatusEnabled,
+	}).Error)
+	task := makeTask(40, 40, 0, 0, BillingSourceWallet, 0)
+	task.Platform = constant.TaskPlatformFineTune
+	task.PrivateData.UpstreamTaskID = "ftjob-upstream"
+	require.NoError(t, model.DB.Create(task).Error)
+
+	adaptor := &recordingAdaptor{}
+	GetTaskAdaptorFunc = func(constant.TaskPlatform) TaskPollingAdaptor { return adaptor }
+	err := updateVideoTasks(context.Background(), constant.TaskPlatformFineTune, 40, []string{"ftjob-upstream"}, map[string]*model.Task{"ftjob-upstream": task})
+	require.NoError(t, err)
+
+	require.NotNil(t, adaptor.info)
+	require.Equal(t, "2025-04-01-preview", adaptor.info.ApiVersion)
+	require.Equal(t, organization, adaptor.info.Organization)
+	require.Equal(t, baseURL, adaptor.baseURL)
+	require.Equal(t, "azure-key", adaptor.key)
+
+	var reloaded model.Task
+	require.NoError(t, model.DB.First(&reloaded, task.ID).Error)
+	require.EqualValues(t, model.TaskStatusQueued, reloaded.Status)
+}
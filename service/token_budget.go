@@ -0,0 +1,67 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/system_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+var tokenBudgetPeriodNames = map[string]string{
+	model.SubscriptionResetDaily:   "每日",
+	model.SubscriptionResetWeekly:  "每周",
+	model.SubscriptionResetMonthly: "每月",
+}
+
+// NotifyTokenBudget 令牌预算用量达到 80% / 100% 时通知令牌所属用户
+func NotifyTokenBudget(notification model.TokenBudgetNotification) {
+	gopool.Go(func() {
+		token := notification.Token
+		status := notification.Status
+		userCache, err := model.GetUserCache(token.UserId)
+		if err != nil {
+			common.SysError(fmt.Sprintf("failed to get user %d for token budget notify: %s", token.UserId, err.Error()))
+			return
+		}
+		userSetting := userCache.GetSetting()
+
+		var prompt string
+		if notification.Percent >= 100 {
+			prompt = fmt.Sprintf("令牌「%s」的%s预算已用尽", token.Name, tokenBudgetPeriodNames[status.Period])
+		} else {
+			prompt = fmt.Sprintf("令牌「%s」的%s预算已使用 %d%%", token.Name, tokenBudgetPeriodNames[status.Period], notification.Percent)
+		}
+		resetTime := time.Unix(status.NextResetTime, 0).Format("2006-01-02 15:04:05")
+		tokenLink := fmt.Sprintf("%s/console/token", system_setting.ServerAddress)
+
+		// 根据通知方式生成不同的内容格式
+		var content string
+		var values []interface{}
+
+		notifyType := userSetting.NotifyType
+		if notifyType == "" {
+			notifyType = dto.NotifyTypeEmail
+		}
+
+		if notifyType == dto.NotifyTypeBark || notifyType == dto.NotifyTypeGotify {
+			// Bark / Gotify 推送使用简短文本，不支持HTML
+			content = "{{value}}，已用 {{value}} / {{value}}，将于 {{value}} 重置"
+			values = []interface{}{prompt, logger.FormatQuota(int(status.UsedQuota)), logger.FormatQuota(status.QuotaLimit), resetTime}
+		} else {
+			// 默认内容格式，适用于Email和Webhook（支持HTML）
+			content = "{{value}}，当前周期已用 {{value}}，预算为 {{value}}，将于 {{value}} 重置。<br/>令牌管理：<a href='{{value}}'>{{value}}</a>"
+			values = []interface{}{prompt, logger.FormatQuota(int(status.UsedQuota)), logger.FormatQuota(status.QuotaLimit), resetTime, tokenLink, tokenLink}
+		}
+
+		err = NotifyUser(token.UserId, userCache.Email, userSetting, dto.NewNotify(dto.NotifyTypeTokenBudget, prompt, content, values))
+		if err != nil {
+			common.SysError(fmt.Sprintf("failed to send token budget notify to user %d: %s", token.UserId, err.Error()))
+		}
+	})
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+const userStatementTickInterval = 1 * time.Hour
+
+var (
+	userStatementOnce    sync.Once
+	userStatementRunning atomic.Bool
+)
+
+// GenerateUserStatement 生成并保存用户指定周期的账单，sendEmail 为 true 时发送账单邮件
+func GenerateUserStatement(userId int, period string, sendEmail bool) (*model.UserStatement, error) {
+	statement, err := model.BuildUserStatement(userId, period)
+	if err != nil {
+		return nil, err
+	}
+	if err = model.SaveUserStatement(statement); err != nil {
+		return nil, err
+	}
+	// 重复生成时 Create 不会回填已有记录的 id，重新读取
+	saved, err := model.GetUserStatement(userId, period)
+	if err != nil {
+		return nil, err
+	}
+	if sendEmail {
+		if err = SendUserStatementEmail(saved); err != nil {
+			logger.LogWarn(context.Background(), fmt.Sprintf("failed to send statement %s to user %d: %v", period, userId, err))
+		}
+	}
+	return saved, nil
+}
+
+// SendUserStatementEmail 发送账单邮件，用户未绑定邮箱时跳过
+func SendUserStatementEmail(statement *model.UserStatement) error {
+	user, err := model.GetUserById(statement.UserId, false)
+	if err != nil {
+		return err
+	}
+	if user.Email == "" {
+		return nil
+	}
+	subject := fmt.Sprintf("%s %s 月度账单", common.SystemName, statement.Period)
+	if err = common.SendEmail(subject, user.Email, renderUserStatementEmail(user.Username, statement)); err != nil {
+		return err
+	}
+	return model.MarkUserStatementEmailSent(statement.Id)
+}
+
+func renderUserStatementEmail(username string, statement *model.UserStatement) string {
+	var detail model.StatementDetail
+	_ = common.UnmarshalJsonStr(statement.Detail, &detail)
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("<p>%s，您好：</p>", html.EscapeString(username)))
+	b.WriteString(fmt.Sprintf("<p>以下是您在 %s 的 %s 月度账单。</p>", html.EscapeString(common.SystemName), statement.Period))
+	b.WriteString("<ul>")
+	b.WriteString(fmt.Sprintf("<li>在线充值：%d 笔，支付金额 %.2f</li>", statement.TopUpCount, statement.TopUpMoney))
+	b.WriteString(fmt.Sprintf("<li>兑换码充值：%d 次，共 %s</li>", statement.RedemptionCount, logger.FormatQuota(int(statement.RedemptionQuota))))
+	b.WriteString(fmt.Sprintf("<li>订阅购买：%d 笔，支付金额 %.2f</li>", statement.SubscriptionCount, statement.SubscriptionMoney))
+	b.WriteString(fmt.Sprintf("<li>模型调用：%d 次，消费 %s，退还 %s</li>", statement.RequestCount,
+		logger.FormatQuota(int(statement.ConsumeQuota)), logger.FormatQuota(int(statement.RefundQuota))))
+	b.WriteString("</ul>")
+	if len(detail.Models) > 0 {
+		b.WriteString(`<table border="1" cellspacing="0" cellpadding="4"><tr><th>模型</th><th>请求次数</th><th>输入 tokens</th><th>输出 tokens</th><th>消费</th></tr>`)
+		for _, item := range detail.Models {
+			b.WriteString(fmt.Sprintf("<tr><td>%s</td><td>%d</td><td>%d</td><td>%d</td><td>%s</td></tr>",
+				html.EscapeString(item.ModelName), item.RequestCount, item.PromptTokens, item.CompletionTokens,
+				logger.FormatQuota(int(item.Quota-item.RefundQuota))))
+		}
+		b.WriteString("</table>")
+	}
+	b.WriteString("<p>完整明细可在控制台的账单页面查看或导出。</p>")
+	return b.String()
+}
+
+// StartUserStatementTask 每月初为上月有记录的用户生成账单
+func StartUserStatementTask() {
+	userStatementOnce.Do(func() {
+		if !common.IsMasterNode {
+			return
+		}
+		gopool.Go(func() {
+			logger.LogInfo(context.Background(), fmt.Sprintf("user statement task started: tick=%s", userStatementTickInterval))
+			ticker := time.NewTicker(userStatementTickInterval)
+			defer ticker.Stop()
+
+			runUserStatementOnce()
+			for range ticker.C {
+				runUserStatementOnce()
+			}
+		})
+	})
+}
+
+func runUserStatementOnce() {
+	setting := operation_setting.GetStatementSetting()
+	if !setting.Enabled {
+		return
+	}
+	if !userStatementRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer userStatementRunning.Store(false)
+
+	ctx := context.Background()
+	// 每月初生成上个自然月的账单，已生成的用户会被跳过
+	now := time.Now()
+	period := model.StatementPeriodOf(now.AddDate(0, 0, -now.Day()))
+	userIds, err := model.GetStatementCandidateUserIds(period)
+	if err != nil {
+		logger.LogWarn(ctx, fmt.Sprintf("user statement task failed: %v", err))
+		return
+	}
+	generated := 0
+	for _, userId := range userIds {
+		if _, err := GenerateUserStatement(userId, period, setting.EmailEnabled); err != nil {
+			logger.LogWarn(ctx, fmt.Sprintf("failed to generate statement %s for user %d: %v", period, userId, err))
+			continue
+		}
+		generated++
+	}
+	if generated > 0 {
+		logger.LogInfo(ctx, fmt.Sprintf("user statement task: period=%s, generated=%d", period, generated))
+	}
+}
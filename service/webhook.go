@@ -2,6 +2,7 @@ package service
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
@@ -54,9 +55,15 @@ func SendWebhookNotify(webhookURL string, secret string, data dto.Notify) error
 		return fmt.Errorf("failed to marshal webhook payload: %v", err)
 	}
 
-	// 创建 HTTP 请求
+	_, err = postWebhook(context.Background(), webhookURL, secret, payloadBytes, nil)
+	return err
+}
+
+// postWebhook 发送 webhook 请求，secret 非空时附带签名，返回响应状态码
+func postWebhook(ctx context.Context, webhookURL string, secret string, payloadBytes []byte, headers map[string]string) (int, error) {
 	var req *http.Request
 	var resp *http.Response
+	var err error
 
 	if system_setting.EnableWorker() {
 		// 构建worker请求数据
@@ -69,6 +76,9 @@ func SendWebhookNotify(webhookURL string, secret string, data dto.Notify) error
 			},
 			Body: payloadBytes,
 		}
+		for key, value := range headers {
+			workerReq.Headers[key] = value
+		}
 
 		// 如果有secret，添加签名到headers
 		if secret != "" {
@@ -79,28 +89,31 @@ func SendWebhookNotify(webhookURL string, secret string, data dto.Notify) error
 
 		resp, err = DoWorkerRequest(workerReq)
 		if err != nil {
-			return fmt.Errorf("failed to send webhook request through worker: %v", err)
+			return 0, fmt.Errorf("failed to send webhook request through worker: %v", err)
 		}
 		defer resp.Body.Close()
 
 		// 检查响应状态
 		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-			return fmt.Errorf("webhook request failed with status code: %d", resp.StatusCode)
+			return resp.StatusCode, fmt.Errorf("webhook request failed with status code: %d", resp.StatusCode)
 		}
 	} else {
 		// SSRF防护：验证Webhook URL（非Worker模式）
 		fetchSetting := system_setting.GetFetchSetting()
 		if err := common.ValidateURLWithFetchSetting(webhookURL, fetchSetting.EnableSSRFProtection, fetchSetting.AllowPrivateIp, fetchSetting.DomainFilterMode, fetchSetting.IpFilterMode, fetchSetting.DomainList, fetchSetting.IpList, fetchSetting.AllowedPorts, fetchSetting.ApplyIPFilterForDomain); err != nil {
-			return fmt.Errorf("request reject: %v", err)
+			return 0, fmt.Errorf("request reject: %v", err)
 		}
 
-		req, err = http.NewRequest(http.MethodPost, webhookURL, bytes.NewBuffer(payloadBytes))
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewBuffer(payloadBytes))
 		if err != nil {
-			return fmt.Errorf("failed to create webhook request: %v", err)
+			return 0, fmt.Errorf("failed to create webhook request: %v", err)
 		}
 
 		// 设置请求头
 		req.Header.Set("Content-Type", "application/json")
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
 
 		// 如果有 secret，生成签名
 		if secret != "" {
@@ -112,15 +125,15 @@ func SendWebhookNotify(webhookURL string, secret string, data dto.Notify) error
 		client := GetHttpClient()
 		resp, err = client.Do(req)
 		if err != nil {
-			return fmt.Errorf("failed to send webhook request: %v", err)
+			return 0, fmt.Errorf("failed to send webhook request: %v", err)
 		}
 		defer resp.Body.Close()
 
 		// 检查响应状态
 		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-			return fmt.Errorf("webhook request failed with status code: %d", resp.StatusCode)
+			return resp.StatusCode, fmt.Errorf("webhook request failed with status code: %d", resp.StatusCode)
 		}
 	}
 
-	return nil
+	return resp.StatusCode, nil
 }
@@ -0,0 +1,344 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+const (
+	webhookDeliveryTickInterval     = 10 * time.Second
+	webhookDeliveryBatchSize        = 100
+	webhookDeliveryCleanupInterval  = time.Hour
+	webhookDeliveryCleanupBatchSize = 1000
+	webhookDeliveryMaxErrorLength   = 500
+	// webhookTokenExhaustedDedupeTTL 同一令牌的耗尽事件在该时间内只发送一次
+	webhookTokenExhaustedDedupeTTL = time.Hour
+)
+
+var (
+	webhookDeliveryOnce    sync.Once
+	webhookDeliveryRunning atomic.Bool
+	webhookDeliveryWake    = make(chan struct{}, 1)
+	webhookEventDedupe     sync.Map
+)
+
+// WebhookEvent 投递给端点的事件内容，请求体即该结构的 JSON
+type WebhookEvent struct {
+	Id        string                 `json:"id"`
+	Type      string                 `json:"type"`
+	CreatedAt int64                  `json:"created_at"`
+	UserId    int                    `json:"user_id,omitempty"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+func newWebhookEvent(eventType string, userId int, data map[string]interface{}) *WebhookEvent {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	return &WebhookEvent{
+		Id:        "evt_" + common.GetUUID(),
+		Type:      eventType,
+		CreatedAt: common.GetTimestamp(),
+		UserId:    userId,
+		Data:      data,
+	}
+}
+
+// PublishWebhookEvent 发布平台事件，为订阅该事件的端点写入投递队列，由投递任务异步发送
+func PublishWebhookEvent(eventType string, userId int, data map[string]interface{}) {
+	if !operation_setting.GetWebhookEventSetting().Enabled {
+		return
+	}
+	event := newWebhookEvent(eventType, userId, data)
+	gopool.Go(func() {
+		if err := enqueueWebhookEvent(event); err != nil {
+			common.SysError(fmt.Sprintf("failed to enqueue webhook event %s: %v", event.Type, err))
+		}
+	})
+}
+
+func enqueueWebhookEvent(event *WebhookEvent) error {
+	endpoints, err := model.GetWebhookEndpointsForEvent(event.Type, event.UserId)
+	if err != nil || len(endpoints) == 0 {
+		return err
+	}
+	payload, err := common.Marshal(event)
+	if err != nil {
+		return err
+	}
+	now := common.GetTimestamp()
+	deliveries := make([]*model.WebhookDelivery, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		deliveries = append(deliveries, &model.WebhookDelivery{
+			EndpointId:    endpoint.Id,
+			UserId:        endpoint.UserId,
+			EventId:       event.Id,
+			EventType:     event.Type,
+			Payload:       string(payload),
+			Status:        model.WebhookDeliveryStatusPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+	}
+	if err = model.CreateWebhookDeliveries(deliveries); err != nil {
+		return err
+	}
+	WakeWebhookDelivery()
+	return nil
+}
+
+// WakeWebhookDelivery 通知投递任务立即处理队列，仅在主节点生效，其他节点写入的投递由主节点定时拉取
+func WakeWebhookDelivery() {
+	select {
+	case webhookDeliveryWake <- struct{}{}:
+	default:
+	}
+}
+
+// SendWebhookTestEvent 向指定端点同步发送一条测试事件，并记录到投递日志
+func SendWebhookTestEvent(endpoint *model.WebhookEndpoint) (*model.WebhookDelivery, error) {
+	event := newWebhookEvent(model.WebhookEventTest, endpoint.UserId, map[string]interface{}{
+		"endpoint_id": endpoint.Id,
+		"message":     "This is a test event",
+	})
+	payload, err := common.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	now := common.GetTimestamp()
+	delivery := &model.WebhookDelivery{
+		EndpointId:    endpoint.Id,
+		UserId:        endpoint.UserId,
+		EventId:       event.Id,
+		EventType:     event.Type,
+		Payload:       string(payload),
+		Status:        model.WebhookDeliveryStatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+	if err = model.CreateWebhookDeliveries([]*model.WebhookDelivery{delivery}); err != nil {
+		return nil, err
+	}
+	deliverWebhook(delivery, endpoint)
+	return delivery, nil
+}
+
+// webhookRetryDelay 第 attempts 次失败后的等待时间，按指数退避并限制上限
+func webhookRetryDelay(attempts int, baseSeconds int, maxSeconds int) int64 {
+	if baseSeconds <= 0 {
+		baseSeconds = 1
+	}
+	delay := int64(baseSeconds)
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if maxSeconds > 0 && delay >= int64(maxSeconds) {
+			return int64(maxSeconds)
+		}
+	}
+	if maxSeconds > 0 && delay > int64(maxSeconds) {
+		return int64(maxSeconds)
+	}
+	return delay
+}
+
+// deliverWebhook 执行一次投递尝试并保存结果
+func deliverWebhook(delivery *model.WebhookDelivery, endpoint *model.WebhookEndpoint) {
+	setting := operation_setting.GetWebhookEventSetting()
+	timeout := time.Duration(setting.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	headers := map[string]string{
+		"X-Webhook-Event":    delivery.EventType,
+		"X-Webhook-Id":       delivery.EventId,
+		"X-Webhook-Delivery": strconv.Itoa(delivery.Id),
+	}
+	statusCode, err := postWebhook(ctx, endpoint.Url, endpoint.Secret, []byte(delivery.Payload), headers)
+
+	now := common.GetTimestamp()
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	if err == nil {
+		delivery.Status = model.WebhookDeliveryStatusSuccess
+		delivery.LastError = ""
+		delivery.DeliveredAt = now
+	} else {
+		failWebhookDelivery(delivery, err.Error(), now)
+	}
+	if err := delivery.UpdateAttempt(); err != nil {
+		common.SysError(fmt.Sprintf("failed to update webhook delivery #%d: %v", delivery.Id, err))
+	}
+}
+
+// failWebhookDelivery 记录失败原因，未超过最大尝试次数时按退避时间重新排队
+func failWebhookDelivery(delivery *model.WebhookDelivery, reason string, now int64) {
+	setting := operation_setting.GetWebhookEventSetting()
+	if len(reason) > webhookDeliveryMaxErrorLength {
+		reason = reason[:webhookDeliveryMaxErrorLength]
+	}
+	delivery.LastError = reason
+	if delivery.Attempts >= setting.MaxAttempts {
+		delivery.Status = model.WebhookDeliveryStatusFailed
+		return
+	}
+	delivery.Status = model.WebhookDeliveryStatusPending
+	delivery.NextAttemptAt = now + webhookRetryDelay(delivery.Attempts, setting.RetryBaseSeconds, setting.RetryMaxSeconds)
+}
+
+// StartWebhookDeliveryTask 投递队列中到期的事件，并清理超过保留天数的投递记录
+func StartWebhookDeliveryTask() {
+	webhookDeliveryOnce.Do(func() {
+		if !common.IsMasterNode {
+			return
+		}
+		gopool.Go(func() {
+			logger.LogInfo(context.Background(), fmt.Sprintf("webhook delivery task started: tick=%s", webhookDeliveryTickInterval))
+			ticker := time.NewTicker(webhookDeliveryTickInterval)
+			defer ticker.Stop()
+
+			lastCleanup := time.Time{}
+			for {
+				runWebhookDeliveryOnce()
+				if time.Since(lastCleanup) >= webhookDeliveryCleanupInterval {
+					runWebhookDeliveryCleanupOnce()
+					lastCleanup = time.Now()
+				}
+				select {
+				case <-ticker.C:
+				case <-webhookDeliveryWake:
+				}
+			}
+		})
+	})
+}
+
+func runWebhookDeliveryOnce() {
+	if !webhookDeliveryRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer webhookDeliveryRunning.Store(false)
+
+	endpoints := make(map[int]*model.WebhookEndpoint)
+	for {
+		deliveries, err := model.GetDueWebhookDeliveries(common.GetTimestamp(), webhookDeliveryBatchSize)
+		if err != nil {
+			logger.LogWarn(context.Background(), fmt.Sprintf("webhook delivery task failed: %v", err))
+			return
+		}
+		for _, delivery := range deliveries {
+			endpoint, ok := endpoints[delivery.EndpointId]
+			if !ok {
+				endpoint, err = model.GetWebhookEndpointById(delivery.EndpointId, 0)
+				if err != nil {
+					endpoint = nil
+				}
+				endpoints[delivery.EndpointId] = endpoint
+			}
+			if endpoint == nil || !endpoint.Enabled {
+				// 端点已删除或禁用，直接结束投递，可在启用后手动重试
+				delivery.Status = model.WebhookDeliveryStatusFailed
+				delivery.LastError = "endpoint not found or disabled"
+				if err := delivery.UpdateAttempt(); err != nil {
+					common.SysError(fmt.Sprintf("failed to update webhook delivery #%d: %v", delivery.Id, err))
+				}
+				continue
+			}
+			deliverWebhook(delivery, endpoint)
+		}
+		if len(deliveries) < webhookDeliveryBatchSize {
+			return
+		}
+	}
+}
+
+func runWebhookDeliveryCleanupOnce() {
+	retentionDays := operation_setting.GetWebhookEventSetting().RetentionDays
+	if retentionDays <= 0 {
+		return
+	}
+	ctx := context.Background()
+	target := time.Now().AddDate(0, 0, -retentionDays).Unix()
+	deleted, err := model.DeleteOldWebhookDeliveries(ctx, target, webhookDeliveryCleanupBatchSize)
+	if err != nil {
+		logger.LogWarn(ctx, fmt.Sprintf("webhook delivery cleanup failed: %v", err))
+		return
+	}
+	if common.DebugEnabled && deleted > 0 {
+		logger.LogDebug(ctx, "webhook delivery cleanup: deleted_count=%d", deleted)
+	}
+}
+
+// acquireWebhookEventOnce 在 ttl 内同一 key 只返回一次 true，用于对重复触发的事件去重
+func acquireWebhookEventOnce(key string, ttl time.Duration) bool {
+	if common.RedisEnabled {
+		ok, err := common.RDB.SetNX(context.Background(), "webhook_event:"+key, "1", ttl).Result()
+		return err == nil && ok
+	}
+	now := time.Now()
+	if value, loaded := webhookEventDedupe.LoadOrStore(key, now); loaded {
+		if now.Sub(value.(time.Time)) < ttl {
+			return false
+		}
+		webhookEventDedupe.Store(key, now)
+	}
+	return true
+}
+
+// PublishTokenExhaustedEvent 令牌额度耗尽后首次被拒绝时发布事件
+func PublishTokenExhaustedEvent(token *model.Token) {
+	if !operation_setting.GetWebhookEventSetting().Enabled {
+		return
+	}
+	if !acquireWebhookEventOnce(fmt.Sprintf("token_exhausted:%d", token.Id), webhookTokenExhaustedDedupeTTL) {
+		return
+	}
+	PublishWebhookEvent(model.WebhookEventTokenExhausted, token.UserId, map[string]interface{}{
+		"token_id":     token.Id,
+		"token_name":   token.Name,
+		"used_quota":   token.UsedQuota,
+		"remain_quota": token.RemainQuota,
+	})
+}
+
+// PublishTaskFinishedEvent 异步任务进入成功或失败终态时发布事件
+func PublishTaskFinishedEvent(task *model.Task) {
+	PublishWebhookEvent(model.WebhookEventTaskFinished, task.UserId, map[string]interface{}{
+		"task_id":     task.TaskID,
+		"platform":    string(task.Platform),
+		"action":      task.Action,
+		"status":      string(task.Status),
+		"progress":    task.Progress,
+		"fail_reason": task.FailReason,
+		"quota":       task.Quota,
+		"finish_time": task.FinishTime,
+	})
+}
+
+// PublishMidjourneyFinishedEvent Midjourney 任务进入成功或失败终态时发布事件
+func PublishMidjourneyFinishedEvent(task *model.Midjourney) {
+	PublishWebhookEvent(model.WebhookEventTaskFinished, task.UserId, map[string]interface{}{
+		"task_id":     task.MjId,
+		"platform":    "mj",
+		"action":      task.Action,
+		"status":      task.Status,
+		"progress":    task.Progress,
+		"fail_reason": task.FailReason,
+		"quota":       task.Quota,
+		"image_url":   task.ImageUrl,
+		"finish_time": task.FinishTime,
+	})
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookRetryDelay(t *testing.T) {
+	require.Equal(t, int64(30), webhookRetryDelay(1, 30, 3600))
+	require.Equal(t, int64(60), webhookRetryDelay(2, 30, 3600))
+	require.Equal(t, int64(240), webhookRetryDelay(4, 30, 3600))
+	require.Equal(t, int64(3600), webhookRetryDelay(10, 30, 3600))
+	require.Equal(t, int64(3600), webhookRetryDelay(100, 30, 3600))
+	require.Equal(t, int64(1), webhookRetryDelay(1, 0, 0))
+}
+
+func TestFailWebhookDelivery(t *testing.T) {
+	setting := operation_setting.GetWebhookEventSetting()
+	original := *setting
+	setting.MaxAttempts = 3
+	setting.RetryBaseSeconds = 10
+	setting.RetryMaxSeconds = 100
+	t.Cleanup(func() {
+		*setting = original
+	})
+
+	delivery := &model.WebhookDelivery{Status: model.WebhookDeliveryStatusPending, Attempts: 2}
+	failWebhookDelivery(delivery, "status code: 500", 1000)
+	require.Equal(t, model.WebhookDeliveryStatusPending, delivery.Status)
+	require.Equal(t, int64(1020), delivery.NextAttemptAt)
+	require.Equal(t, "status code: 500", delivery.LastError)
+
+	delivery.Attempts = 3
+	failWebhookDelivery(delivery, "timeout", 2000)
+	require.Equal(t, model.WebhookDeliveryStatusFailed, delivery.Status)
+}
+
+func TestWebhookEndpointSubscribes(t *testing.T) {
+	endpoint := &model.WebhookEndpoint{}
+	endpoint.SetEvents([]string{model.WebhookEventTopupCompleted})
+	require.True(t, endpoint.Subscribes(model.WebhookEventTopupCompleted))
+	require.False(t, endpoint.Subscribes(model.WebhookEventTaskFinished))
+
+	endpoint.SetEvents([]string{model.WebhookEventAll})
+	require.True(t, endpoint.Subscribes(model.WebhookEventTaskFinished))
+}
@@ -0,0 +1,34 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// AuditLogSetting 管理操作审计日志配置
+type AuditLogSetting struct {
+	Enabled bool `json:"enabled"`
+	// RetentionDays 审计记录保留天数，0 表示不清理
+	RetentionDays int `json:"retention_days"`
+	// WebhookEnabled 是否将审计记录作为 audit.logged 事件推送到 Webhook 端点
+	WebhookEnabled bool `json:"webhook_enabled"`
+	// SyslogAddress 转发审计记录的 syslog 地址，如 udp://127.0.0.1:514、tcp://syslog:601，留空表示不转发
+	SyslogAddress string `json:"syslog_address"`
+	// SyslogTag syslog 消息中的应用名
+	SyslogTag string `json:"syslog_tag"`
+}
+
+// 默认配置
+var auditLogSetting = AuditLogSetting{
+	Enabled:        true,
+	RetentionDays:  365,
+	WebhookEnabled: false,
+	SyslogAddress:  "",
+	SyslogTag:      "new-api",
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("audit_log_setting", &auditLogSetting)
+}
+
+func GetAuditLogSetting() *AuditLogSetting {
+	return &auditLogSetting
+}
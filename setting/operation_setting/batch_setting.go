@@ -0,0 +1,51 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// BatchSetting OpenAI Batch API 相关配置
+type BatchSetting struct {
+	Enabled bool `json:"enabled"` // 是否启用 /v1/batches（依赖 Files API）
+	// DiscountRatio 批处理请求的计费倍率，在分组倍率基础上相乘，例如 0.5 表示五折
+	DiscountRatio float64 `json:"discount_ratio"`
+	// WorkerCount 后台同时执行的请求数（所有批次共享）
+	WorkerCount int `json:"worker_count"`
+	// MaxRequestsPerBatch 单个批次最多包含的请求数
+	MaxRequestsPerBatch int `json:"max_requests_per_batch"`
+	// MaxRunningBatches 同时处理的批次数量
+	MaxRunningBatches int `json:"max_running_batches"`
+	// AllowedEndpoints 允许的批处理端点
+	AllowedEndpoints []string `json:"allowed_endpoints"`
+}
+
+// 默认配置
+var batchSetting = BatchSetting{
+	Enabled:             false,
+	DiscountRatio:       0.5,
+	WorkerCount:         4,
+	MaxRequestsPerBatch: 50000,
+	MaxRunningBatches:   2,
+	AllowedEndpoints: []string{
+		"/v1/chat/completions",
+		"/v1/completions",
+		"/v1/embeddings",
+		"/v1/responses",
+		"/v1/moderations",
+	},
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("batch_setting", &batchSetting)
+}
+
+func GetBatchSetting() *BatchSetting {
+	return &batchSetting
+}
+
+// GetBatchDiscountRatio 返回批处理计费倍率，配置非法时按原价计费
+func GetBatchDiscountRatio() float64 {
+	if batchSetting.DiscountRatio < 0 {
+		return 1
+	}
+	return batchSetting.DiscountRatio
+}
@@ -0,0 +1,97 @@
+package operation_setting
+
+import (
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/pkg/channelhealth"
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// ChannelSelectionSetting 渠道自适应选择（基于真实流量的健康度与熔断）配置
+type ChannelSelectionSetting struct {
+	// AdaptiveEnabled 是否启用自适应选择，关闭时按静态优先级 + 权重随机选择
+	AdaptiveEnabled bool `json:"adaptive_enabled"`
+	// AdaptiveScopes 启用自适应选择的 分组:模型 范围，如 "default:gpt-4o*"、"*:claude-*"，
+	// 模型支持末尾 * 前缀匹配，为空表示所有分组与模型
+	AdaptiveScopes []string `json:"adaptive_scopes"`
+	// WindowSeconds 统计滑动窗口长度（秒）
+	WindowSeconds int `json:"window_seconds"`
+	// MinSamples 窗口内样本数达到该值才参与评估
+	MinSamples int `json:"min_samples"`
+	// ErrorRateThreshold 错误率达到该值时熔断（0-1）
+	ErrorRateThreshold float64 `json:"error_rate_threshold"`
+	// OpenSeconds 熔断持续时间（秒），之后进入半开状态探测
+	OpenSeconds int `json:"open_seconds"`
+	// HalfOpenSuccesses 半开状态下连续成功该次数后恢复
+	HalfOpenSuccesses int `json:"half_open_successes"`
+	// HalfOpenWeightRatio 半开状态的初始权重比例，随探测成功逐步恢复到 1
+	HalfOpenWeightRatio float64 `json:"half_open_weight_ratio"`
+	// MinWeightRatio 劣化但未熔断的渠道的最低权重比例
+	MinWeightRatio float64 `json:"min_weight_ratio"`
+}
+
+// 默认配置
+var channelSelectionSetting = ChannelSelectionSetting{
+	AdaptiveEnabled:     false,
+	AdaptiveScopes:      []string{},
+	WindowSeconds:       300,
+	MinSamples:          10,
+	ErrorRateThreshold:  0.5,
+	OpenSeconds:         60,
+	HalfOpenSuccesses:   3,
+	HalfOpenWeightRatio: 0.1,
+	MinWeightRatio:      0.05,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("channel_selection_setting", &channelSelectionSetting)
+}
+
+func GetChannelSelectionSetting() *ChannelSelectionSetting {
+	return &channelSelectionSetting
+}
+
+// HealthConfig 转换为健康度统计参数
+func (s *ChannelSelectionSetting) HealthConfig() channelhealth.Config {
+	return channelhealth.Config{
+		Window:              time.Duration(s.WindowSeconds) * time.Second,
+		MinSamples:          s.MinSamples,
+		ErrorRateThreshold:  s.ErrorRateThreshold,
+		OpenDuration:        time.Duration(s.OpenSeconds) * time.Second,
+		HalfOpenSuccesses:   s.HalfOpenSuccesses,
+		HalfOpenWeightRatio: s.HalfOpenWeightRatio,
+		MinWeightRatio:      s.MinWeightRatio,
+	}
+}
+
+// IsAdaptiveSelectionEnabled 指定分组与模型是否使用自适应选择
+func IsAdaptiveSelectionEnabled(group string, model string) bool {
+	if !channelSelectionSetting.AdaptiveEnabled {
+		return false
+	}
+	if len(channelSelectionSetting.AdaptiveScopes) == 0 {
+		return true
+	}
+	for _, scope := range channelSelectionSetting.AdaptiveScopes {
+		scopeGroup, scopeModel, found := strings.Cut(strings.TrimSpace(scope), ":")
+		if !found {
+			scopeModel = "*"
+		}
+		if matchSelectionScope(scopeGroup, group) && matchSelectionScope(scopeModel, model) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSelectionScope(pattern string, value string) bool {
+	if pattern == "" || pattern == "*" {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(value, prefix)
+	}
+	return pattern == value
+}
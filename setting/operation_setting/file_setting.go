@@ -0,0 +1,75 @@
+package operation_setting
+
+import (
+	"slices"
+
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+const (
+	FileStorageLocal = "local"
+	FileStorageS3    = "s3"
+)
+
+// FileSetting OpenAI Files API 相关配置
+type FileSetting struct {
+	Enabled bool `json:"enabled"` // 是否启用 /v1/files
+	// StorageType 存储后端：local（磁盘缓存目录下的 new-api-files）或 s3（S3 兼容存储）
+	StorageType string `json:"storage_type"`
+	// LocalPath 本地存储目录，空表示使用磁盘缓存目录
+	LocalPath string `json:"local_path"`
+
+	MaxFileSizeMB      int      `json:"max_file_size_mb"`     // 单文件最大大小（MB）
+	UserMaxStorageMB   int      `json:"user_max_storage_mb"`  // 每用户文件总大小上限（MB），0 表示不限制
+	UserMaxFiles       int      `json:"user_max_files"`       // 每用户文件数量上限，0 表示不限制
+	AllowedPurposes    []string `json:"allowed_purposes"`     // 允许的 purpose
+	DefaultExpireHours int      `json:"default_expire_hours"` // 文件默认过期时间（小时），0 表示不过期
+
+	// PassThroughEnabled 开启后文件直接上传到上游渠道，文件 ID 使用上游 ID，
+	// 后续引用该文件的请求会固定到该渠道
+	PassThroughEnabled bool `json:"pass_through_enabled"`
+	// PassThroughModel 用于为文件上传选择上游渠道的模型名称
+	PassThroughModel string `json:"pass_through_model"`
+
+	S3Endpoint    string `json:"s3_endpoint"`
+	S3Region      string `json:"s3_region"`
+	S3Bucket      string `json:"s3_bucket"`
+	S3AccessKeyId string `json:"s3_access_key_id"`
+	S3Secret      string `json:"s3_secret"`
+	S3PathStyle   bool   `json:"s3_path_style"`
+	S3Prefix      string `json:"s3_prefix"`
+}
+
+// 默认配置
+var fileSetting = FileSetting{
+	Enabled:            false,
+	StorageType:        FileStorageLocal,
+	LocalPath:          "",
+	MaxFileSizeMB:      512,
+	UserMaxStorageMB:   10240,
+	UserMaxFiles:       1000,
+	AllowedPurposes:    []string{"assistants", "batch", "fine-tune", "vision", "user_data", "evals"},
+	DefaultExpireHours: 0,
+	PassThroughEnabled: false,
+	PassThroughModel:   "gpt-4o-mini",
+	S3Region:           "us-east-1",
+	S3PathStyle:        true,
+	S3Prefix:           "files/",
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("file_setting", &fileSetting)
+}
+
+func GetFileSetting() *FileSetting {
+	return &fileSetting
+}
+
+// IsFilePurposeAllowed 检查 purpose 是否在允许列表中，列表为空时不限制
+func IsFilePurposeAllowed(purpose string) bool {
+	if len(fileSetting.AllowedPurposes) == 0 {
+		return purpose != ""
+	}
+	return slices.Contains(fileSetting.AllowedPurposes, purpose)
+}
@@ -0,0 +1,55 @@
+package operation_setting
+
+import (
+	"strings"
+
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// FineTuneSetting 模型微调（/v1/fine_tuning/jobs）相关配置
+type FineTuneSetting struct {
+	Enabled bool `json:"enabled"` // 是否启用微调任务转发
+	// TrainingRatios 训练 token 的计费倍率（与模型倍率同一口径，1 = $0.002 / 1K tokens），
+	// 以基础模型名称为键，未配置时回退到该模型的普通模型倍率
+	TrainingRatios map[string]float64 `json:"training_ratios"`
+	// AutoAddModel 训练成功后自动将 ft: 模型加入所属渠道的模型列表
+	AutoAddModel bool `json:"auto_add_model"`
+}
+
+// 默认配置，训练价格参考 OpenAI 官方定价
+var fineTuneSetting = FineTuneSetting{
+	Enabled: true,
+	TrainingRatios: map[string]float64{
+		"gpt-4o-2024-08-06":       12.5,
+		"gpt-4o-mini-2024-07-18":  1.5,
+		"gpt-4.1-2025-04-14":      12.5,
+		"gpt-4.1-mini-2025-04-14": 2.5,
+		"gpt-4.1-nano-2025-04-14": 0.75,
+		"gpt-3.5-turbo-0125":      4,
+		"gpt-3.5-turbo-1106":      4,
+		"davinci-002":             3,
+		"babbage-002":             0.2,
+	},
+	AutoAddModel: true,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("fine_tune_setting", &fineTuneSetting)
+}
+
+func GetFineTuneSetting() *FineTuneSetting {
+	return &fineTuneSetting
+}
+
+// GetFineTuneTrainingRatio 获取基础模型的训练倍率，微调模型（ft:base:org::id）按其基础模型匹配
+func GetFineTuneTrainingRatio(modelName string) (float64, bool) {
+	if strings.HasPrefix(modelName, "ft:") {
+		parts := strings.Split(modelName, ":")
+		if len(parts) > 1 {
+			modelName = parts[1]
+		}
+	}
+	ratio, ok := fineTuneSetting.TrainingRatios[modelName]
+	return ratio, ok
+}
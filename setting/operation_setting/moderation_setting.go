@@ -0,0 +1,181 @@
+package operation_setting
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sync"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// 内容审核提供方
+const (
+	ModerationProviderSensitiveWords = "sensitive_words"
+	ModerationProviderRegex          = "regex"
+	ModerationProviderAPI            = "moderation_api"
+)
+
+// 命中后的处理策略
+const (
+	ModerationActionBlock  = "block"
+	ModerationActionRedact = "redact"
+	ModerationActionLog    = "log"
+)
+
+// ModerationRegexRule 正则审核规则，Name 作为命中类别记录到日志
+type ModerationRegexRule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+}
+
+// ModerationSetting 内容审核，启用后替代原有的提示词敏感词检查
+type ModerationSetting struct {
+	Enabled bool `json:"enabled"`
+	// Providers 依次执行的审核提供方：sensitive_words（敏感词列表）、regex（正则规则）、moderation_api（调用渠道的 /v1/moderations）
+	Providers []string `json:"providers"`
+	// RegexRules 正则审核规则
+	RegexRules []ModerationRegexRule `json:"regex_rules"`
+	// ApiModel 审核接口使用的模型，按模型选择渠道
+	ApiModel string `json:"api_model"`
+	// ApiGroup 选择审核渠道的分组，为空时使用请求所在的分组
+	ApiGroup string `json:"api_group"`
+	// ApiTimeoutSeconds 审核接口超时时间（秒）
+	ApiTimeoutSeconds int `json:"api_timeout_seconds"`
+	// ApiFailOpen 审核接口调用失败时是否放行，否则按命中处理
+	ApiFailOpen bool `json:"api_fail_open"`
+	// CheckCompletion 是否同时审核模型输出
+	CheckCompletion bool `json:"check_completion"`
+	// StreamCheckChars 流式输出每累计多少字符审核一次，审核通过前的内容暂不下发
+	StreamCheckChars int `json:"stream_check_chars"`
+	// DefaultAction 默认处理策略：block / redact / log
+	DefaultAction string `json:"default_action"`
+	// GroupActions 分组处理策略，覆盖默认策略
+	GroupActions map[string]string `json:"group_actions"`
+}
+
+// 默认配置
+var moderationSetting = ModerationSetting{
+	Enabled:           false,
+	Providers:         []string{ModerationProviderSensitiveWords},
+	RegexRules:        []ModerationRegexRule{},
+	ApiModel:          "omni-moderation-latest",
+	ApiGroup:          "",
+	ApiTimeoutSeconds: 10,
+	ApiFailOpen:       true,
+	CheckCompletion:   false,
+	StreamCheckChars:  200,
+	DefaultAction:     ModerationActionBlock,
+	GroupActions:      map[string]string{},
+}
+
+// ModerationRegexp 编译后的正则规则
+type ModerationRegexp struct {
+	Name string
+	Re   *regexp.Regexp
+}
+
+var (
+	moderationRegexMutex    sync.RWMutex
+	moderationRegexSource   []ModerationRegexRule
+	moderationRegexCompiled []ModerationRegexp
+)
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("moderation_setting", &moderationSetting)
+}
+
+func GetModerationSetting() *ModerationSetting {
+	return &moderationSetting
+}
+
+// GetModerationAction 返回分组的处理策略，未配置或配置非法时使用默认策略
+func GetModerationAction(group string) string {
+	if action, ok := moderationSetting.GroupActions[group]; ok && isValidModerationAction(action) {
+		return action
+	}
+	if isValidModerationAction(moderationSetting.DefaultAction) {
+		return moderationSetting.DefaultAction
+	}
+	return ModerationActionBlock
+}
+
+func isValidModerationAction(action string) bool {
+	return action == ModerationActionBlock || action == ModerationActionRedact || action == ModerationActionLog
+}
+
+// CheckModerationProviders 校验审核提供方配置
+func CheckModerationProviders(jsonStr string) error {
+	var providers []string
+	if err := common.UnmarshalJsonStr(jsonStr, &providers); err != nil {
+		return err
+	}
+	for _, provider := range providers {
+		switch provider {
+		case ModerationProviderSensitiveWords, ModerationProviderRegex, ModerationProviderAPI:
+		default:
+			return fmt.Errorf("unknown moderation provider %q", provider)
+		}
+	}
+	return nil
+}
+
+// CheckModerationRegexRules 校验正则审核规则配置
+func CheckModerationRegexRules(jsonStr string) error {
+	var rules []ModerationRegexRule
+	if err := common.UnmarshalJsonStr(jsonStr, &rules); err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("invalid moderation rule %q: %w", rule.Name, err)
+		}
+	}
+	return nil
+}
+
+// CheckModerationGroupActions 校验分组处理策略配置
+func CheckModerationGroupActions(jsonStr string) error {
+	var actions map[string]string
+	if err := common.UnmarshalJsonStr(jsonStr, &actions); err != nil {
+		return err
+	}
+	for group, action := range actions {
+		if !isValidModerationAction(action) {
+			return fmt.Errorf("invalid moderation action %q for group %s", action, group)
+		}
+	}
+	return nil
+}
+
+// GetModerationRegexps 返回编译后的正则规则，配置变更后重新编译，非法的正则会被忽略
+func GetModerationRegexps() []ModerationRegexp {
+	moderationRegexMutex.RLock()
+	if slices.Equal(moderationRegexSource, moderationSetting.RegexRules) && moderationRegexCompiled != nil {
+		defer moderationRegexMutex.RUnlock()
+		return moderationRegexCompiled
+	}
+	moderationRegexMutex.RUnlock()
+
+	moderationRegexMutex.Lock()
+	defer moderationRegexMutex.Unlock()
+	rules := slices.Clone(moderationSetting.RegexRules)
+	compiled := make([]ModerationRegexp, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Pattern == "" {
+			continue
+		}
+		if re, err := regexp.Compile(rule.Pattern); err == nil {
+			name := rule.Name
+			if name == "" {
+				name = rule.Pattern
+			}
+			compiled = append(compiled, ModerationRegexp{Name: name, Re: re})
+		}
+	}
+	moderationRegexSource = rules
+	moderationRegexCompiled = compiled
+	return compiled
+}
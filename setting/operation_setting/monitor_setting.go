@@ -10,12 +10,15 @@ import (
 type MonitorSetting struct {
 	AutoTestChannelEnabled bool    `json:"auto_test_channel_enabled"`
 	AutoTestChannelMinutes float64 `json:"auto_test_channel_minutes"`
+	// MultiKeyExpiryAlertDays 多Key渠道中的key在过期前多少天发送提醒，0 表示不提醒
+	MultiKeyExpiryAlertDays int `json:"multi_key_expiry_alert_days"`
 }
 
 // 默认配置
 var monitorSetting = MonitorSetting{
-	AutoTestChannelEnabled: false,
-	AutoTestChannelMinutes: 10,
+	AutoTestChannelEnabled:  false,
+	AutoTestChannelMinutes:  10,
+	MultiKeyExpiryAlertDays: 7,
 }
 
 func init() {
@@ -0,0 +1,113 @@
+package operation_setting
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sync"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// PayloadLogSetting 请求/响应载荷记录，仅对指定分组或令牌生效，用于事后排查问题
+type PayloadLogSetting struct {
+	Enabled bool `json:"enabled"`
+	// Groups 记录载荷的分组
+	Groups []string `json:"groups"`
+	// TokenIds 记录载荷的令牌 ID
+	TokenIds []int `json:"token_ids"`
+	// MaxBodyKB 请求体与响应体各自保存的最大长度（KB），超出部分截断
+	MaxBodyKB int `json:"max_body_kb"`
+	// RetentionHours 载荷保留时长（小时），过期后自动清理
+	RetentionHours int `json:"retention_hours"`
+	// RedactPatterns 脱敏正则，匹配到的内容替换为 [REDACTED]
+	RedactPatterns []string `json:"redact_patterns"`
+	// RedactJSONPaths 脱敏 JSON 路径，以 . 分隔（键名中的 . 写作 \.），* 匹配任意键或数组元素，如 messages.*.content
+	RedactJSONPaths []string `json:"redact_json_paths"`
+}
+
+// 默认配置
+var payloadLogSetting = PayloadLogSetting{
+	Enabled:        false,
+	Groups:         []string{},
+	TokenIds:       []int{},
+	MaxBodyKB:      256,
+	RetentionHours: 72,
+	RedactPatterns: []string{
+		`sk-[A-Za-z0-9_\-]{16,}`,
+		`(?i)bearer\s+[A-Za-z0-9._\-]{16,}`,
+	},
+	RedactJSONPaths: []string{},
+}
+
+var (
+	payloadRedactMutex    sync.RWMutex
+	payloadRedactSource   []string
+	payloadRedactCompiled []*regexp.Regexp
+)
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("payload_log_setting", &payloadLogSetting)
+}
+
+func GetPayloadLogSetting() *PayloadLogSetting {
+	return &payloadLogSetting
+}
+
+// ShouldLogPayload 判断指定分组或令牌的请求是否需要记录载荷
+func ShouldLogPayload(group string, tokenId int) bool {
+	if !payloadLogSetting.Enabled {
+		return false
+	}
+	return slices.Contains(payloadLogSetting.Groups, group) || slices.Contains(payloadLogSetting.TokenIds, tokenId)
+}
+
+// CheckPayloadRedactPatterns 校验脱敏正则配置
+func CheckPayloadRedactPatterns(jsonStr string) error {
+	var patterns []string
+	if err := common.UnmarshalJsonStr(jsonStr, &patterns); err != nil {
+		return err
+	}
+	_, err := compilePayloadRedactPatterns(patterns)
+	return err
+}
+
+func compilePayloadRedactPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		if pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// GetPayloadRedactRegexps 返回编译后的脱敏正则，配置变更后重新编译，非法的正则会被忽略
+func GetPayloadRedactRegexps() []*regexp.Regexp {
+	payloadRedactMutex.RLock()
+	if slices.Equal(payloadRedactSource, payloadLogSetting.RedactPatterns) && payloadRedactCompiled != nil {
+		defer payloadRedactMutex.RUnlock()
+		return payloadRedactCompiled
+	}
+	payloadRedactMutex.RUnlock()
+
+	payloadRedactMutex.Lock()
+	defer payloadRedactMutex.Unlock()
+	patterns := slices.Clone(payloadLogSetting.RedactPatterns)
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		if re, err := regexp.Compile(pattern); err == nil && pattern != "" {
+			compiled = append(compiled, re)
+		}
+	}
+	payloadRedactSource = patterns
+	payloadRedactCompiled = compiled
+	return compiled
+}
@@ -0,0 +1,60 @@
+package operation_setting
+
+import (
+	"slices"
+
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// ResponseCacheSetting 对话补全响应缓存，完全相同的请求直接返回已缓存的响应
+type ResponseCacheSetting struct {
+	Enabled bool `json:"enabled"`
+	// TTLSeconds 缓存有效期（秒）
+	TTLSeconds int `json:"ttl_seconds"`
+	// HitRatio 命中缓存时的计费倍率，在分组倍率基础上相乘，0 表示不计费
+	HitRatio float64 `json:"hit_ratio"`
+	// MaxEntries 未启用 Redis 时内存缓存的最大条目数
+	MaxEntries int `json:"max_entries"`
+	// MaxBodyKB 可缓存的最大响应体（KB），超出时不缓存
+	MaxBodyKB int `json:"max_body_kb"`
+	// DiskSpillKB 响应体超过该大小（KB）时写入磁盘，缓存中仅保存文件路径，0 表示不写入磁盘
+	DiskSpillKB int `json:"disk_spill_kb"`
+	// Models 允许缓存的模型，为空时所有模型均可缓存
+	Models []string `json:"models"`
+}
+
+// 默认配置
+var responseCacheSetting = ResponseCacheSetting{
+	Enabled:     false,
+	TTLSeconds:  3600,
+	HitRatio:    0.1,
+	MaxEntries:  10000,
+	MaxBodyKB:   4096,
+	DiskSpillKB: 256,
+	Models:      []string{},
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("response_cache_setting", &responseCacheSetting)
+}
+
+func GetResponseCacheSetting() *ResponseCacheSetting {
+	return &responseCacheSetting
+}
+
+// IsResponseCacheEnabledForModel 响应缓存是否对指定模型生效
+func IsResponseCacheEnabledForModel(modelName string) bool {
+	if !responseCacheSetting.Enabled {
+		return false
+	}
+	return len(responseCacheSetting.Models) == 0 || slices.Contains(responseCacheSetting.Models, modelName)
+}
+
+// GetResponseCacheHitRatio 返回缓存命中计费倍率，配置非法时按原价计费
+func GetResponseCacheHitRatio() float64 {
+	if responseCacheSetting.HitRatio < 0 {
+		return 1
+	}
+	return responseCacheSetting.HitRatio
+}
@@ -0,0 +1,26 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// ResponsesStoreSetting 网关侧 Responses 响应存储配置
+// 渠道不支持 Responses API 时由网关保存响应，用于展开 previous_response_id 及 GET/DELETE /v1/responses/{id}
+type ResponsesStoreSetting struct {
+	Enabled bool `json:"enabled"`
+	// TTLHours 响应保存时长（小时），过期后无法再被引用，0 表示不过期
+	TTLHours int `json:"ttl_hours"`
+}
+
+// 默认关闭，开启后保存时长与 OpenAI 一致为 30 天
+var responsesStoreSetting = ResponsesStoreSetting{
+	Enabled:  false,
+	TTLHours: 720,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("responses_store_setting", &responsesStoreSetting)
+}
+
+func GetResponsesStoreSetting() *ResponsesStoreSetting {
+	return &responsesStoreSetting
+}
@@ -0,0 +1,26 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// StatementSetting 用户月度账单
+type StatementSetting struct {
+	// Enabled 每月初自动为上月有充值或消费记录的用户生成账单
+	Enabled bool `json:"enabled"`
+	// EmailEnabled 生成账单后发送邮件给已绑定邮箱的用户
+	EmailEnabled bool `json:"email_enabled"`
+}
+
+// 默认配置
+var statementSetting = StatementSetting{
+	Enabled:      false,
+	EmailEnabled: false,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("statement_setting", &statementSetting)
+}
+
+func GetStatementSetting() *StatementSetting {
+	return &statementSetting
+}
@@ -0,0 +1,32 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// StreamFailoverSetting 流式续传配置
+// 对话补全流式输出中途断开或停滞时，携带已输出内容切换到其他渠道继续生成，并拼接到同一个 SSE 流中
+type StreamFailoverSetting struct {
+	Enabled bool `json:"enabled"`
+	// IdleTimeoutSeconds 上游超过该时长没有新数据即视为停滞，0 表示使用全局流式超时
+	IdleTimeoutSeconds int `json:"idle_timeout_seconds"`
+	// MaxFailovers 单个请求最多续传次数，不占用普通重试次数
+	MaxFailovers int `json:"max_failovers"`
+	// ContinuationPrompt 渠道不支持 assistant 预填充时追加的续写提示
+	ContinuationPrompt string `json:"continuation_prompt"`
+}
+
+// 默认配置
+var streamFailoverSetting = StreamFailoverSetting{
+	Enabled:            false,
+	IdleTimeoutSeconds: 30,
+	MaxFailovers:       1,
+	ContinuationPrompt: "Continue exactly where your previous message was cut off. Do not repeat any text that was already written and do not add any preamble.",
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("stream_failover_setting", &streamFailoverSetting)
+}
+
+func GetStreamFailoverSetting() *StreamFailoverSetting {
+	return &streamFailoverSetting
+}
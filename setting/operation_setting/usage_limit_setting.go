@@ -0,0 +1,53 @@
+package operation_setting
+
+import (
+	"fmt"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// GroupUsageLimit 用户分组的用量限制，按用户计数，0 表示不限制
+type GroupUsageLimit struct {
+	RPM         int `json:"rpm"`
+	TPM         int `json:"tpm"`
+	MaxInFlight int `json:"max_in_flight"`
+}
+
+// UsageLimitSetting TPM / RPM / 并发数限制，令牌与渠道的限制在各自的编辑页配置
+type UsageLimitSetting struct {
+	// GroupLimits 以用户分组为键
+	GroupLimits map[string]GroupUsageLimit `json:"group_limits"`
+}
+
+var usageLimitSetting = UsageLimitSetting{
+	GroupLimits: map[string]GroupUsageLimit{},
+}
+
+func init() {
+	config.GlobalConfig.Register("usage_limit_setting", &usageLimitSetting)
+}
+
+func GetUsageLimitSetting() *UsageLimitSetting {
+	return &usageLimitSetting
+}
+
+// GetGroupUsageLimit 获取用户分组的用量限制
+func GetGroupUsageLimit(group string) (GroupUsageLimit, bool) {
+	limit, ok := usageLimitSetting.GroupLimits[group]
+	return limit, ok
+}
+
+// CheckGroupUsageLimits 校验分组用量限制配置
+func CheckGroupUsageLimits(jsonStr string) error {
+	limits := make(map[string]GroupUsageLimit)
+	if err := common.Unmarshal([]byte(jsonStr), &limits); err != nil {
+		return err
+	}
+	for group, limit := range limits {
+		if limit.RPM < 0 || limit.TPM < 0 || limit.MaxInFlight < 0 {
+			return fmt.Errorf("group %s has negative usage limit values", group)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package operation_setting
+
+import "github.com/QuantumNous/new-api/setting/config"
+
+// WebhookEventSetting 平台事件 Webhook 订阅与投递配置
+type WebhookEventSetting struct {
+	Enabled bool `json:"enabled"`
+	// UserEnabled 是否允许普通用户注册 Webhook 端点
+	UserEnabled bool `json:"user_enabled"`
+	// MaxEndpointsPerUser 每个用户最多可注册的端点数量
+	MaxEndpointsPerUser int `json:"max_endpoints_per_user"`
+	// MaxAttempts 单次投递的最大尝试次数，超过后标记为失败
+	MaxAttempts int `json:"max_attempts"`
+	// RetryBaseSeconds 首次重试的等待时间（秒），之后每次翻倍
+	RetryBaseSeconds int `json:"retry_base_seconds"`
+	// RetryMaxSeconds 重试等待时间上限（秒）
+	RetryMaxSeconds int `json:"retry_max_seconds"`
+	// TimeoutSeconds 单次投递请求超时（秒）
+	TimeoutSeconds int `json:"timeout_seconds"`
+	// RetentionDays 投递记录保留天数，0 表示不清理
+	RetentionDays int `json:"retention_days"`
+}
+
+// 默认配置
+var webhookEventSetting = WebhookEventSetting{
+	Enabled:             true,
+	UserEnabled:         true,
+	MaxEndpointsPerUser: 10,
+	MaxAttempts:         8,
+	RetryBaseSeconds:    30,
+	RetryMaxSeconds:     3600,
+	TimeoutSeconds:      10,
+	RetentionDays:       7,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("webhook_event_setting", &webhookEventSetting)
+}
+
+func GetWebhookEventSetting() *WebhookEventSetting {
+	return &webhookEventSetting
+}
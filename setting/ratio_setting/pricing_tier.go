@@ -0,0 +1,352 @@
+package ratio_setting
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/types"
+)
+
+// PricingTier 分档计价规则，所有已配置的条件同时满足时命中。
+// 同一模型的规则按配置顺序匹配，先命中者生效；均未命中时使用基础倍率。
+type PricingTier struct {
+	Name string `json:"name"`
+
+	// 提示 tokens 区间 (gt, lte]，0 表示不限制。例如长上下文：prompt_tokens_gt = 200000
+	PromptTokensGt  int `json:"prompt_tokens_gt,omitempty"`
+	PromptTokensLte int `json:"prompt_tokens_lte,omitempty"`
+	// 时段，格式 "HH:MM-HH:MM"，结束时间小于开始时间表示跨越零点
+	TimeRanges []string `json:"time_ranges,omitempty"`
+	// 星期，0 表示周日，为空表示每天
+	Weekdays []int `json:"weekdays,omitempty"`
+	// 时段与星期使用的时区，支持 IANA 名称或 "UTC+8" 形式，为空时使用服务器时区
+	Timezone string `json:"timezone,omitempty"`
+	// 用户本月（自然月）累计消耗额度达到该值时命中，用于阶梯用量折扣
+	MinMonthlyQuota int64 `json:"min_monthly_quota,omitempty"`
+
+	// 命中后的价格，未设置的项沿用基础倍率。按量计费的模型只能设置倍率，按次计费的模型只能设置 model_price
+	ModelRatio         *float64 `json:"model_ratio,omitempty"`
+	CompletionRatio    *float64 `json:"completion_ratio,omitempty"`
+	CacheRatio         *float64 `json:"cache_ratio,omitempty"`
+	CacheCreationRatio *float64 `json:"cache_creation_ratio,omitempty"` // 5 分钟缓存写入倍率，1 小时缓存写入按固定比例换算
+	ModelPrice         *float64 `json:"model_price,omitempty"`
+	// 在上述价格基础上再乘以该系数（同时作用于按量和按次计费），0 表示不调整
+	Multiplier float64 `json:"multiplier,omitempty"`
+
+	location *time.Location
+	ranges   [][2]int
+}
+
+// PricingTierContext 匹配分档计价规则所需的请求信息
+type PricingTierContext struct {
+	PromptTokens int
+	Time         time.Time
+	// MonthlyUsedQuota 获取用户本月累计消耗额度，仅在规则包含月用量条件时调用，结果会缓存到 PriceData 中
+	MonthlyUsedQuota func() int64
+}
+
+// ClaudeCacheCreation1hMultiplier 1 小时与 5 分钟缓存写入价格的固定比例
+// https://docs.claude.com/en/docs/build-with-claude/prompt-caching#1-hour-cache-duration
+const ClaudeCacheCreation1hMultiplier = 6 / 3.75
+
+var pricingTiersMap = types.NewRWMap[string, []*PricingTier]()
+
+func PricingTiers2JSONString() string {
+	return pricingTiersMap.MarshalJSONString()
+}
+
+func UpdatePricingTiersByJSONString(jsonStr string) error {
+	tiers := make(map[string][]*PricingTier)
+	if strings.TrimSpace(jsonStr) != "" {
+		if err := common.Unmarshal([]byte(jsonStr), &tiers); err != nil {
+			return err
+		}
+	}
+	for modelName, rules := range tiers {
+		for i, tier := range rules {
+			if tier == nil {
+				return fmt.Errorf("model %s tier %d is empty", modelName, i)
+			}
+			if err := tier.compile(); err != nil {
+				return fmt.Errorf("model %s tier %q: %w", modelName, tier.Name, err)
+			}
+		}
+	}
+	pricingTiersMap.Clear()
+	pricingTiersMap.AddAll(tiers)
+	InvalidateExposedDataCache()
+	return nil
+}
+
+func GetPricingTiersCopy() map[string][]*PricingTier {
+	return pricingTiersMap.ReadAll()
+}
+
+// GetPricingTiers 获取模型的分档计价规则，先精确匹配再按规范化名称匹配
+func GetPricingTiers(name string) []*PricingTier {
+	if tiers, ok := pricingTiersMap.Get(name); ok {
+		return tiers
+	}
+	tiers, _ := pricingTiersMap.Get(FormatMatchingModelName(name))
+	return tiers
+}
+
+func (t *PricingTier) compile() error {
+	if t.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if t.PromptTokensGt < 0 || t.PromptTokensLte < 0 || t.MinMonthlyQuota < 0 || t.Multiplier < 0 {
+		return fmt.Errorf("thresholds and multiplier must not be negative")
+	}
+	if t.PromptTokensLte > 0 && t.PromptTokensLte <= t.PromptTokensGt {
+		return fmt.Errorf("prompt_tokens_lte must be greater than prompt_tokens_gt")
+	}
+	for _, ratio := range []*float64{t.ModelRatio, t.CompletionRatio, t.CacheRatio, t.CacheCreationRatio, t.ModelPrice} {
+		if ratio != nil && *ratio < 0 {
+			return fmt.Errorf("ratio and price must not be negative")
+		}
+	}
+	if t.ModelPrice != nil && t.setsRatio() {
+		return fmt.Errorf("model_price cannot be combined with ratios")
+	}
+	for _, weekday := range t.Weekdays {
+		if weekday < 0 || weekday > 6 {
+			return fmt.Errorf("invalid weekday %d", weekday)
+		}
+	}
+	location, err := parsePricingTierLocation(t.Timezone)
+	if err != nil {
+		return err
+	}
+	t.location = location
+	t.ranges = make([][2]int, 0, len(t.TimeRanges))
+	for _, timeRange := range t.TimeRanges {
+		start, end, ok := strings.Cut(timeRange, "-")
+		if !ok {
+			return fmt.Errorf("invalid time range %q", timeRange)
+		}
+		startMinute, err := parseClockMinute(start)
+		if err != nil {
+			return err
+		}
+		endMinute, err := parseClockMinute(end)
+		if err != nil {
+			return err
+		}
+		if startMinute == endMinute {
+			return fmt.Errorf("invalid time range %q", timeRange)
+		}
+		t.ranges = append(t.ranges, [2]int{startMinute, endMinute})
+	}
+	return nil
+}
+
+func parsePricingTierLocation(timezone string) (*time.Location, error) {
+	timezone = strings.TrimSpace(timezone)
+	if timezone == "" {
+		return time.Local, nil
+	}
+	upper := strings.ToUpper(timezone)
+	if strings.HasPrefix(upper, "UTC") && len(upper) > 3 {
+		hours, err := strconv.ParseFloat(upper[3:], 64)
+		if err != nil || hours < -12 || hours > 14 {
+			return nil, fmt.Errorf("invalid timezone %q", timezone)
+		}
+		return time.FixedZone(upper, int(hours*3600)), nil
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q", timezone)
+	}
+	return location, nil
+}
+
+// parseClockMinute 将 "HH:MM" 转为当天的分钟数，允许 "24:00" 表示当天结束
+func parseClockMinute(clock string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(clock))
+	if err != nil {
+		if strings.TrimSpace(clock) == "24:00" {
+			return 24 * 60, nil
+		}
+		return 0, fmt.Errorf("invalid time %q", clock)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (t *PricingTier) setsRatio() bool {
+	return t.ModelRatio != nil || t.CompletionRatio != nil || t.CacheRatio != nil || t.CacheCreationRatio != nil
+}
+
+// CheckPricingTiers 校验分档计价配置，并要求分档与模型当前的计费方式一致：
+// 分档不能改变计费方式，按量计费的模型设置 model_price、按次计费的模型设置倍率都不会生效
+func CheckPricingTiers(jsonStr string) error {
+	tiers := make(map[string][]*PricingTier)
+	if strings.TrimSpace(jsonStr) != "" {
+		if err := common.Unmarshal([]byte(jsonStr), &tiers); err != nil {
+			return err
+		}
+	}
+	for modelName, rules := range tiers {
+		_, usePrice := GetModelPrice(modelName, false)
+		for i, tier := range rules {
+			if tier == nil {
+				return fmt.Errorf("model %s tier %d is empty", modelName, i)
+			}
+			if err := tier.compile(); err != nil {
+				return fmt.Errorf("model %s tier %q: %w", modelName, tier.Name, err)
+			}
+			if usePrice && tier.setsRatio() {
+				return fmt.Errorf("model %s tier %q: model is billed per call, use model_price instead of ratios", modelName, tier.Name)
+			}
+			if !usePrice && tier.ModelPrice != nil {
+				return fmt.Errorf("model %s tier %q: model is billed by ratio, model_price is not allowed", modelName, tier.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func (t *PricingTier) usesMonthlyQuota() bool {
+	return t.MinMonthlyQuota > 0
+}
+
+func (t *PricingTier) matchTime(now time.Time) bool {
+	if len(t.ranges) == 0 && len(t.Weekdays) == 0 {
+		return true
+	}
+	location := t.location
+	if location == nil {
+		location = time.Local
+	}
+	now = now.In(location)
+	minute := now.Hour()*60 + now.Minute()
+	weekday := int(now.Weekday())
+	if len(t.ranges) > 0 {
+		matched := false
+		for _, r := range t.ranges {
+			if r[0] < r[1] {
+				matched = minute >= r[0] && minute < r[1]
+			} else {
+				// 跨越零点的时段，凌晨部分按前一天的星期计算
+				if minute >= r[0] {
+					matched = true
+				} else if minute < r[1] {
+					matched = true
+					weekday = (weekday + 6) % 7
+				}
+			}
+			if matched {
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(t.Weekdays) == 0 {
+		return true
+	}
+	for _, day := range t.Weekdays {
+		if day == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *PricingTier) match(ctx PricingTierContext, monthlyUsedQuota func() int64) bool {
+	if t.PromptTokensGt > 0 && ctx.PromptTokens <= t.PromptTokensGt {
+		return false
+	}
+	if t.PromptTokensLte > 0 && ctx.PromptTokens > t.PromptTokensLte {
+		return false
+	}
+	if !t.matchTime(ctx.Time) {
+		return false
+	}
+	if t.usesMonthlyQuota() && monthlyUsedQuota() < t.MinMonthlyQuota {
+		return false
+	}
+	return true
+}
+
+// ApplyPricingTier 按分档计价规则调整 priceData 中的价格。
+// 首次调用时记录基础价格，之后可按实际用量重复调用重新匹配（如结算时按实际提示 tokens 匹配长上下文分档）。
+// 计费方式由 priceData.UsePrice 决定，分档只调整对应计费方式的价格，不会改变计费方式。
+func ApplyPricingTier(modelName string, priceData *types.PriceData, ctx PricingTierContext) {
+	tiers := GetPricingTiers(modelName)
+	if len(tiers) == 0 {
+		return
+	}
+	info := priceData.PricingTier
+	if info == nil {
+		info = &types.PricingTierInfo{
+			BaseModelPrice:           priceData.ModelPrice,
+			BaseModelRatio:           priceData.ModelRatio,
+			BaseCompletionRatio:      priceData.CompletionRatio,
+			BaseCacheRatio:           priceData.CacheRatio,
+			BaseCacheCreationRatio:   priceData.CacheCreationRatio,
+			BaseCacheCreation5mRatio: priceData.CacheCreation5mRatio,
+			BaseCacheCreation1hRatio: priceData.CacheCreation1hRatio,
+			MonthlyUsedQuota:         -1,
+		}
+		priceData.PricingTier = info
+	}
+	monthlyUsedQuota := func() int64 {
+		if info.MonthlyUsedQuota < 0 {
+			info.MonthlyUsedQuota = 0
+			if ctx.MonthlyUsedQuota != nil {
+				info.MonthlyUsedQuota = ctx.MonthlyUsedQuota()
+			}
+		}
+		return info.MonthlyUsedQuota
+	}
+	if ctx.Time.IsZero() {
+		ctx.Time = time.Now()
+	}
+
+	priceData.ModelPrice = info.BaseModelPrice
+	priceData.ModelRatio = info.BaseModelRatio
+	priceData.CompletionRatio = info.BaseCompletionRatio
+	priceData.CacheRatio = info.BaseCacheRatio
+	priceData.CacheCreationRatio = info.BaseCacheCreationRatio
+	priceData.CacheCreation5mRatio = info.BaseCacheCreation5mRatio
+	priceData.CacheCreation1hRatio = info.BaseCacheCreation1hRatio
+	info.Name = ""
+	for _, tier := range tiers {
+		if !tier.match(ctx, monthlyUsedQuota) {
+			continue
+		}
+		info.Name = tier.Name
+		if priceData.UsePrice {
+			if tier.ModelPrice != nil {
+				priceData.ModelPrice = *tier.ModelPrice
+			}
+			if tier.Multiplier > 0 {
+				priceData.ModelPrice *= tier.Multiplier
+			}
+			return
+		}
+		if tier.ModelRatio != nil {
+			priceData.ModelRatio = *tier.ModelRatio
+		}
+		if tier.CompletionRatio != nil {
+			priceData.CompletionRatio = *tier.CompletionRatio
+		}
+		if tier.CacheRatio != nil {
+			priceData.CacheRatio = *tier.CacheRatio
+		}
+		if tier.CacheCreationRatio != nil {
+			priceData.CacheCreationRatio = *tier.CacheCreationRatio
+			priceData.CacheCreation5mRatio = *tier.CacheCreationRatio
+			priceData.CacheCreation1hRatio = *tier.CacheCreationRatio * ClaudeCacheCreation1hMultiplier
+		}
+		if tier.Multiplier > 0 {
+			priceData.ModelRatio *= tier.Multiplier
+		}
+		return
+	}
+}
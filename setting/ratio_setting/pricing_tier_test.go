@@ -0,0 +1,121 @@
+package ratio_setting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyPricingTier(t *testing.T) {
+	require.NoError(t, UpdatePricingTiersByJSONString(`{
+		"tier-test-model": [
+			{"name": "long-context", "prompt_tokens_gt": 200000, "model_ratio": 2.5, "completion_ratio": 3},
+			{"name": "off-peak", "time_ranges": ["23:00-07:00"], "timezone": "UTC+8", "multiplier": 0.5},
+			{"name": "volume", "min_monthly_quota": 1000, "multiplier": 0.8}
+		]
+	}`))
+	t.Cleanup(func() {
+		require.NoError(t, UpdatePricingTiersByJSONString(`{}`))
+	})
+
+	peak := time.Date(2026, 10, 16, 12, 0, 0, 0, time.FixedZone("UTC+8", 8*3600))
+	offPeak := time.Date(2026, 10, 16, 2, 30, 0, 0, time.FixedZone("UTC+8", 8*3600))
+	monthlyQuota := int64(0)
+	newCtx := func(promptTokens int, now time.Time) PricingTierContext {
+		return PricingTierContext{
+			PromptTokens:     promptTokens,
+			Time:             now,
+			MonthlyUsedQuota: func() int64 { return monthlyQuota },
+		}
+	}
+
+	priceData := &types.PriceData{ModelRatio: 1.25, CompletionRatio: 8}
+	ApplyPricingTier("tier-test-model", priceData, newCtx(1000, peak))
+	require.Equal(t, "", priceData.PricingTierName())
+	require.Equal(t, 1.25, priceData.ModelRatio)
+
+	// 结算时按实际提示 tokens 重新匹配，基于基础价格调整
+	ApplyPricingTier("tier-test-model", priceData, newCtx(250000, peak))
+	require.Equal(t, "long-context", priceData.PricingTierName())
+	require.Equal(t, 2.5, priceData.ModelRatio)
+	require.Equal(t, 3.0, priceData.CompletionRatio)
+
+	priceData = &types.PriceData{ModelRatio: 1.25, CompletionRatio: 8}
+	ApplyPricingTier("tier-test-model", priceData, newCtx(1000, offPeak))
+	require.Equal(t, "off-peak", priceData.PricingTierName())
+	require.Equal(t, 0.625, priceData.ModelRatio)
+	require.Equal(t, 8.0, priceData.CompletionRatio)
+
+	monthlyQuota = 5000
+	priceData = &types.PriceData{ModelRatio: 1.25, CompletionRatio: 8}
+	ApplyPricingTier("tier-test-model", priceData, newCtx(1000, peak))
+	require.Equal(t, "volume", priceData.PricingTierName())
+	require.Equal(t, 1.0, priceData.ModelRatio)
+
+	// 月用量在首次匹配时缓存，后续重新匹配不再查询
+	monthlyQuota = 0
+	ApplyPricingTier("tier-test-model", priceData, newCtx(2000, peak))
+	require.Equal(t, "volume", priceData.PricingTierName())
+
+	priceData = &types.PriceData{ModelRatio: 1.25}
+	ApplyPricingTier("other-model", priceData, newCtx(250000, peak))
+	require.Nil(t, priceData.PricingTier)
+}
+
+func TestUpdatePricingTiersByJSONString_Invalid(t *testing.T) {
+	require.Error(t, UpdatePricingTiersByJSONString(`{"m": [{"name": "bad", "time_ranges": ["25:00-26:00"]}]}`))
+	require.Error(t, UpdatePricingTiersByJSONString(`{"m": [{"name": "bad", "prompt_tokens_gt": 100, "prompt_tokens_lte": 50}]}`))
+	require.Error(t, UpdatePricingTiersByJSONString(`{"m": [{"prompt_tokens_gt": 100}]}`))
+	require.Error(t, UpdatePricingTiersByJSONString(`{"m": [{"name": "bad", "timezone": "Mars/Base"}]}`))
+}
+
+func TestApplyPricingTierKeepsBillingMode(t *testing.T) {
+	require.NoError(t, UpdatePricingTiersByJSONString(`{
+		"tier-ratio-model": [
+			{"name": "long-context", "prompt_tokens_gt": 1000, "model_ratio": 2, "cache_creation_ratio": 2.5}
+		],
+		"tier-price-model": [
+			{"name": "long-context", "prompt_tokens_gt": 1000, "model_price": 0.2, "multiplier": 0.5}
+		]
+	}`))
+	t.Cleanup(func() {
+		require.NoError(t, UpdatePricingTiersByJSONString(`{}`))
+	})
+
+	// 缓存写入倍率随分档调整，1 小时缓存写入按固定比例换算；重新匹配未命中时恢复基础倍率
+	priceData := &types.PriceData{ModelPrice: -1, ModelRatio: 1, CacheCreationRatio: 1.25, CacheCreation5mRatio: 1.25, CacheCreation1hRatio: 2}
+	ApplyPricingTier("tier-ratio-model", priceData, PricingTierContext{PromptTokens: 2000})
+	require.False(t, priceData.UsePrice)
+	require.Equal(t, -1.0, priceData.ModelPrice)
+	require.Equal(t, 2.0, priceData.ModelRatio)
+	require.Equal(t, 2.5, priceData.CacheCreationRatio)
+	require.Equal(t, 2.5, priceData.CacheCreation5mRatio)
+	require.Equal(t, 4.0, priceData.CacheCreation1hRatio)
+	ApplyPricingTier("tier-ratio-model", priceData, PricingTierContext{PromptTokens: 10})
+	require.Equal(t, 1.0, priceData.ModelRatio)
+	require.Equal(t, 1.25, priceData.CacheCreation5mRatio)
+	require.Equal(t, 2.0, priceData.CacheCreation1hRatio)
+
+	priceData = &types.PriceData{UsePrice: true, ModelPrice: 0.1}
+	ApplyPricingTier("tier-price-model", priceData, PricingTierContext{PromptTokens: 2000})
+	require.True(t, priceData.UsePrice)
+	require.Equal(t, 0.1, priceData.ModelPrice)
+	require.Equal(t, 0.0, priceData.ModelRatio)
+}
+
+func TestCheckPricingTiers(t *testing.T) {
+	originalModelPrice := ModelPrice2JSONString()
+	require.NoError(t, UpdateModelPriceByJSONString(`{"tier-check-price-model": 0.1}`))
+	t.Cleanup(func() {
+		require.NoError(t, UpdateModelPriceByJSONString(originalModelPrice))
+	})
+
+	require.NoError(t, CheckPricingTiers(`{"tier-check-price-model": [{"name": "a", "model_price": 0.2}]}`))
+	require.NoError(t, CheckPricingTiers(`{"tier-check-ratio-model": [{"name": "a", "model_ratio": 2, "cache_creation_ratio": 3}]}`))
+	require.ErrorContains(t, CheckPricingTiers(`{"tier-check-ratio-model": [{"name": "a", "model_price": 0.2}]}`), "model_price is not allowed")
+	require.ErrorContains(t, CheckPricingTiers(`{"tier-check-price-model": [{"name": "a", "model_ratio": 2}]}`), "billed per call")
+	require.ErrorContains(t, CheckPricingTiers(`{"m": [{"name": "a", "model_ratio": 2, "model_price": 0.2}]}`), "cannot be combined")
+	require.Error(t, CheckPricingTiers(`{"m": [{"name": "bad", "time_ranges": ["25:00-26:00"]}]}`))
+}
@@ -74,6 +74,7 @@ const (
 	ErrorCodeBadResponse            ErrorCode = "bad_response"
 	ErrorCodeBadResponseBody        ErrorCode = "bad_response_body"
 	ErrorCodeEmptyResponse          ErrorCode = "empty_response"
+	ErrorCodeStreamInterrupted      ErrorCode = "stream_interrupted"
 	ErrorCodeAwsInvokeError         ErrorCode = "aws_invoke_error"
 	ErrorCodeModelNotFound          ErrorCode = "model_not_found"
 	ErrorCodePromptBlocked          ErrorCode = "prompt_blocked"
@@ -83,8 +84,10 @@ const (
 	ErrorCodeUpdateDataError ErrorCode = "update_data_error"
 
 	// quota error
-	ErrorCodeInsufficientUserQuota      ErrorCode = "insufficient_user_quota"
-	ErrorCodePreConsumeTokenQuotaFailed ErrorCode = "pre_consume_token_quota_failed"
+	ErrorCodeInsufficientUserQuota         ErrorCode = "insufficient_user_quota"
+	ErrorCodePreConsumeTokenQuotaFailed    ErrorCode = "pre_consume_token_quota_failed"
+	ErrorCodeTokenBudgetExceeded           ErrorCode = "token_budget_exceeded"
+	ErrorCodeInsufficientOrganizationQuota ErrorCode = "insufficient_organization_quota"
 )
 
 type NewAPIError struct {
@@ -25,6 +25,30 @@ type PriceData struct {
 	Quota                int // 按次计费的最终额度（MJ / Task）
 	QuotaToPreConsume    int // 按量计费的预消耗额度
 	GroupRatioInfo       GroupRatioInfo
+	PricingTier          *PricingTierInfo // 模型配置了分档计价时非 nil
+}
+
+// PricingTierInfo 分档计价的匹配结果
+type PricingTierInfo struct {
+	Name string // 命中的分档名称，为空表示未命中任何分档，使用基础价格
+	// 分档调整前的基础价格，用于结算时按实际用量重新匹配
+	BaseModelPrice           float64
+	BaseModelRatio           float64
+	BaseCompletionRatio      float64
+	BaseCacheRatio           float64
+	BaseCacheCreationRatio   float64
+	BaseCacheCreation5mRatio float64
+	BaseCacheCreation1hRatio float64
+	// MonthlyUsedQuota 匹配时使用的用户本月累计消耗额度，-1 表示尚未查询
+	MonthlyUsedQuota int64
+}
+
+// PricingTierName 返回命中的分档名称，未命中时返回空字符串
+func (p *PriceData) PricingTierName() string {
+	if p.PricingTier == nil {
+		return ""
+	}
+	return p.PricingTier.Name
 }
 
 func (p *PriceData) AddOtherRatio(key string, ratio float64) {
@@ -0,0 +1,289 @@
+/*
+Copyright (C) 2025 QuantumNous
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+For commercial licensing, please contact support@quantumnous.com
+*/
+
+import React, { useEffect, useState } from 'react';
+import {
+  Button,
+  Descriptions,
+  Input,
+  Modal,
+  Select,
+  Space,
+  Table,
+  Tag,
+  Typography,
+} from '@douyinfe/semi-ui';
+import { IconSearch } from '@douyinfe/semi-icons';
+import { API, showError, timestamp2string } from '../../helpers';
+
+const { Text } = Typography;
+
+const TARGET_TYPES = [
+  'option',
+  'channel',
+  'user',
+  'redemption',
+  'subscription',
+  'subscription_plan',
+  'user_subscription',
+];
+
+function parseJSON(value, fallback) {
+  if (!value) return fallback;
+  try {
+    return JSON.parse(value);
+  } catch (e) {
+    return fallback;
+  }
+}
+
+function formatValue(value) {
+  if (value === null || value === undefined) return '-';
+  if (typeof value === 'object') return JSON.stringify(value);
+  return String(value);
+}
+
+const AuditLogDetail = ({ record, t }) => {
+  const diff = parseJSON(record.diff, []);
+  const after = parseJSON(record.after, null);
+  return (
+    <div style={{ padding: '0 8px' }}>
+      <Descriptions
+        size='small'
+        data={[
+          { key: 'Request ID', value: record.request_id || '-' },
+          { key: t('消息'), value: record.message || '-' },
+        ]}
+      />
+      {diff.length > 0 ? (
+        <Table
+          size='small'
+          rowKey='field'
+          dataSource={diff}
+          pagination={false}
+          columns={[
+            { title: t('字段'), dataIndex: 'field', width: 240 },
+            {
+              title: t('变更前'),
+              dataIndex: 'before',
+              render: (value) => (
+                <Text type='danger'>{formatValue(value)}</Text>
+              ),
+            },
+            {
+              title: t('变更后'),
+              dataIndex: 'after',
+              render: (value) => (
+                <Text type='success'>{formatValue(value)}</Text>
+              ),
+            },
+          ]}
+        />
+      ) : (
+        after && (
+          <pre
+            style={{
+              maxHeight: 240,
+              overflow: 'auto',
+              whiteSpace: 'pre-wrap',
+              wordBreak: 'break-all',
+              background: 'var(--semi-color-fill-0)',
+              padding: 12,
+              borderRadius: 8,
+              margin: 0,
+            }}
+          >
+            {JSON.stringify(after, null, 2)}
+          </pre>
+        )
+      )}
+    </div>
+  );
+};
+
+const AuditLogModal = ({ visible, onCancel, t }) => {
+  const [loading, setLoading] = useState(false);
+  const [logs, setLogs] = useState([]);
+  const [total, setTotal] = useState(0);
+  const [page, setPage] = useState(1);
+  const [pageSize, setPageSize] = useState(10);
+  const [filters, setFilters] = useState({
+    actor_name: '',
+    action: '',
+    target_type: '',
+    target_id: '',
+    request_id: '',
+  });
+
+  const loadLogs = async (currentPage = page, currentPageSize = pageSize) => {
+    setLoading(true);
+    try {
+      const params = new URLSearchParams({
+        p: currentPage,
+        page_size: currentPageSize,
+      });
+      Object.entries(filters).forEach(([key, value]) => {
+        if (value) params.append(key, value);
+      });
+      const res = await API.get(`/api/audit_log/?${params.toString()}`);
+      const { success, message, data } = res.data;
+      if (success) {
+        setLogs(data.items || []);
+        setTotal(data.total || 0);
+      } else {
+        showError(message);
+      }
+    } catch (error) {
+      showError(error);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  useEffect(() => {
+    if (visible) {
+      setPage(1);
+      loadLogs(1, pageSize);
+    }
+  }, [visible]);
+
+  const updateFilter = (key) => (value) => {
+    setFilters((prev) => ({ ...prev, [key]: value }));
+  };
+
+  const columns = [
+    {
+      title: t('时间'),
+      dataIndex: 'created_at',
+      width: 170,
+      render: (value) => timestamp2string(value),
+    },
+    {
+      title: t('操作人'),
+      dataIndex: 'actor_name',
+      render: (value, record) => `${value} (#${record.actor_id})`,
+    },
+    { title: 'IP', dataIndex: 'ip' },
+    { title: t('操作'), dataIndex: 'action' },
+    {
+      title: t('目标'),
+      dataIndex: 'target_type',
+      render: (value, record) =>
+        record.target_id ? `${value} #${record.target_id}` : value,
+    },
+    {
+      title: t('结果'),
+      dataIndex: 'success',
+      width: 80,
+      render: (value) =>
+        value ? (
+          <Tag color='green'>{t('成功')}</Tag>
+        ) : (
+          <Tag color='red'>{t('失败')}</Tag>
+        ),
+    },
+  ];
+
+  return (
+    <Modal
+      title={t('审计日志')}
+      visible={visible}
+      onCancel={onCancel}
+      footer={null}
+      width={1100}
+      centered
+    >
+      <Space wrap style={{ marginBottom: 12 }}>
+        <Input
+          prefix={<IconSearch />}
+          placeholder={t('操作人用户名')}
+          value={filters.actor_name}
+          onChange={updateFilter('actor_name')}
+          showClear
+        />
+        <Input
+          placeholder={t('操作前缀，如 PUT /api/channel')}
+          value={filters.action}
+          onChange={updateFilter('action')}
+          showClear
+        />
+        <Select
+          placeholder={t('目标类型')}
+          value={filters.target_type || undefined}
+          onChange={(value) => updateFilter('target_type')(value || '')}
+          optionList={TARGET_TYPES.map((type) => ({
+            label: type,
+            value: type,
+          }))}
+          showClear
+          style={{ width: 180 }}
+        />
+        <Input
+          placeholder={t('目标 ID')}
+          value={filters.target_id}
+          onChange={updateFilter('target_id')}
+          showClear
+        />
+        <Input
+          placeholder='Request ID'
+          value={filters.request_id}
+          onChange={updateFilter('request_id')}
+          showClear
+        />
+        <Button
+          type='primary'
+          onClick={() => {
+            setPage(1);
+            loadLogs(1, pageSize);
+          }}
+        >
+          {t('查询')}
+        </Button>
+      </Space>
+      <Table
+        size='small'
+        rowKey='id'
+        loading={loading}
+        columns={columns}
+        dataSource={logs}
+        expandedRowRender={(record) => (
+          <AuditLogDetail record={record} t={t} />
+        )}
+        pagination={{
+          currentPage: page,
+          pageSize,
+          total,
+          showSizeChanger: true,
+          pageSizeOpts: [10, 20, 50],
+          onPageChange: (nextPage) => {
+            setPage(nextPage);
+            loadLogs(nextPage, pageSize);
+          },
+          onPageSizeChange: (nextPageSize) => {
+            setPageSize(nextPageSize);
+            setPage(1);
+            loadLogs(1, nextPageSize);
+          },
+        }}
+      />
+    </Modal>
+  );
+};
+
+export default AuditLogModal;
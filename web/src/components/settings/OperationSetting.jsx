@@ -23,10 +23,16 @@ import SettingsGeneral from '../../pages/Setting/Operation/SettingsGeneral';
 import SettingsHeaderNavModules from '../../pages/Setting/Operation/SettingsHeaderNavModules';
 import SettingsSidebarModulesAdmin from '../../pages/Setting/Operation/SettingsSidebarModulesAdmin';
 import SettingsSensitiveWords from '../../pages/Setting/Operation/SettingsSensitiveWords';
+import SettingsModeration from '../../pages/Setting/Operation/SettingsModeration';
 import SettingsLog from '../../pages/Setting/Operation/SettingsLog';
+import SettingsPayloadLog from '../../pages/Setting/Operation/SettingsPayloadLog';
+import SettingsAuditLog from '../../pages/Setting/Operation/SettingsAuditLog';
 import SettingsMonitoring from '../../pages/Setting/Operation/SettingsMonitoring';
 import SettingsCreditLimit from '../../pages/Setting/Operation/SettingsCreditLimit';
 import SettingsCheckin from '../../pages/Setting/Operation/SettingsCheckin';
+import SettingsChannelSelection from '../../pages/Setting/Operation/SettingsChannelSelection';
+import SettingsStatement from '../../pages/Setting/Operation/SettingsStatement';
+import SettingsWebhookEvent from '../../pages/Setting/Operation/SettingsWebhookEvent';
 import { API, showError, toBoolean } from '../../helpers';
 
 const OperationSetting = () => {
@@ -70,9 +76,38 @@ const OperationSetting = () => {
     CheckSensitiveOnPromptEnabled: false,
     SensitiveWords: '',
 
+    /* 内容审核设置 */
+    'moderation_setting.enabled': false,
+    'moderation_setting.providers': '[]',
+    'moderation_setting.regex_rules': '[]',
+    'moderation_setting.api_model': '',
+    'moderation_setting.api_group': '',
+    'moderation_setting.api_timeout_seconds': 10,
+    'moderation_setting.api_fail_open': true,
+    'moderation_setting.check_completion': false,
+    'moderation_setting.stream_check_chars': 200,
+    'moderation_setting.default_action': 'block',
+    'moderation_setting.group_actions': '{}',
+
     /* 日志设置 */
     LogConsumeEnabled: false,
 
+    /* 载荷记录设置 */
+    'payload_log_setting.enabled': false,
+    'payload_log_setting.groups': '[]',
+    'payload_log_setting.token_ids': '[]',
+    'payload_log_setting.max_body_kb': 256,
+    'payload_log_setting.retention_hours': 72,
+    'payload_log_setting.redact_patterns': '[]',
+    'payload_log_setting.redact_json_paths': '[]',
+
+    /* 审计日志设置 */
+    'audit_log_setting.enabled': true,
+    'audit_log_setting.retention_days': 365,
+    'audit_log_setting.webhook_enabled': false,
+    'audit_log_setting.syslog_address': '',
+    'audit_log_setting.syslog_tag': 'new-api',
+
     /* 监控设置 */
     ChannelDisableThreshold: 0,
     QuotaRemindThreshold: 0,
@@ -83,11 +118,39 @@ const OperationSetting = () => {
     AutomaticRetryStatusCodes:
       '100-199,300-399,401-407,409-499,500-503,505-523,525-599',
     'monitor_setting.auto_test_channel_enabled': false,
-    'monitor_setting.auto_test_channel_minutes': 10 /* 签到设置 */,
+    'monitor_setting.auto_test_channel_minutes': 10,
+    'monitor_setting.multi_key_expiry_alert_days': 7,
+
+    /* 渠道自适应选择 */
+    'channel_selection_setting.adaptive_enabled': false,
+    'channel_selection_setting.adaptive_scopes': '',
+    'channel_selection_setting.window_seconds': 300,
+    'channel_selection_setting.min_samples': 10,
+    'channel_selection_setting.error_rate_threshold': 0.5,
+    'channel_selection_setting.open_seconds': 60,
+    'channel_selection_setting.half_open_successes': 3,
+    'channel_selection_setting.half_open_weight_ratio': 0.1,
+    'channel_selection_setting.min_weight_ratio': 0.05,
+
+    /* 签到设置 */
     'checkin_setting.enabled': false,
     'checkin_setting.min_quota': 1000,
     'checkin_setting.max_quota': 10000,
 
+    /* 月度账单设置 */
+    'statement_setting.enabled': false,
+    'statement_setting.email_enabled': false,
+
+    /* 事件订阅设置 */
+    'webhook_event_setting.enabled': true,
+    'webhook_event_setting.user_enabled': true,
+    'webhook_event_setting.max_endpoints_per_user': 10,
+    'webhook_event_setting.max_attempts': 8,
+    'webhook_event_setting.retry_base_seconds': 30,
+    'webhook_event_setting.retry_max_seconds': 3600,
+    'webhook_event_setting.timeout_seconds': 10,
+    'webhook_event_setting.retention_days': 7,
+
     /* 令牌设置 */
     'token_setting.max_user_tokens': 1000,
   });
@@ -147,14 +210,30 @@ const OperationSetting = () => {
         <Card style={{ marginTop: '10px' }}>
           <SettingsSensitiveWords options={inputs} refresh={onRefresh} />
         </Card>
+        {/* 内容审核设置 */}
+        <Card style={{ marginTop: '10px' }}>
+          <SettingsModeration options={inputs} refresh={onRefresh} />
+        </Card>
         {/* 日志设置 */}
         <Card style={{ marginTop: '10px' }}>
           <SettingsLog options={inputs} refresh={onRefresh} />
         </Card>
+        {/* 载荷记录设置 */}
+        <Card style={{ marginTop: '10px' }}>
+          <SettingsPayloadLog options={inputs} refresh={onRefresh} />
+        </Card>
+        {/* 审计日志设置 */}
+        <Card style={{ marginTop: '10px' }}>
+          <SettingsAuditLog options={inputs} refresh={onRefresh} />
+        </Card>
         {/* 监控设置 */}
         <Card style={{ marginTop: '10px' }}>
           <SettingsMonitoring options={inputs} refresh={onRefresh} />
         </Card>
+        {/* 渠道自适应选择 */}
+        <Card style={{ marginTop: '10px' }}>
+          <SettingsChannelSelection options={inputs} refresh={onRefresh} />
+        </Card>
         {/* 额度设置 */}
         <Card style={{ marginTop: '10px' }}>
           <SettingsCreditLimit options={inputs} refresh={onRefresh} />
@@ -163,6 +242,14 @@ const OperationSetting = () => {
         <Card style={{ marginTop: '10px' }}>
           <SettingsCheckin options={inputs} refresh={onRefresh} />
         </Card>
+        {/* 月度账单设置 */}
+        <Card style={{ marginTop: '10px' }}>
+          <SettingsStatement options={inputs} refresh={onRefresh} />
+        </Card>
+        {/* 事件订阅设置 */}
+        <Card style={{ marginTop: '10px' }}>
+          <SettingsWebhookEvent options={inputs} refresh={onRefresh} />
+        </Card>
       </Spin>
     </>
   );
@@ -20,6 +20,9 @@ For commercial licensing, please contact support@quantumnous.com
 import React, { useEffect, useState } from 'react';
 import { Card, Spin } from '@douyinfe/semi-ui';
 import SettingsPerformance from '../../pages/Setting/Performance/SettingsPerformance';
+import SettingsResponseCache from '../../pages/Setting/Performance/SettingsResponseCache';
+import SettingsResponsesStore from '../../pages/Setting/Performance/SettingsResponsesStore';
+import SettingsStreamFailover from '../../pages/Setting/Performance/SettingsStreamFailover';
 import { API, showError, toBoolean } from '../../helpers';
 
 const PerformanceSetting = () => {
@@ -28,6 +31,19 @@ const PerformanceSetting = () => {
     'performance_setting.disk_cache_threshold_mb': 10,
     'performance_setting.disk_cache_max_size_mb': 1024,
     'performance_setting.disk_cache_path': '',
+    'response_cache_setting.enabled': false,
+    'response_cache_setting.ttl_seconds': 3600,
+    'response_cache_setting.hit_ratio': 0.1,
+    'response_cache_setting.max_entries': 10000,
+    'response_cache_setting.max_body_kb': 4096,
+    'response_cache_setting.disk_spill_kb': 256,
+    'response_cache_setting.models': '[]',
+    'responses_store_setting.enabled': false,
+    'responses_store_setting.ttl_hours': 720,
+    'stream_failover_setting.enabled': false,
+    'stream_failover_setting.idle_timeout_seconds': 30,
+    'stream_failover_setting.max_failovers': 1,
+    'stream_failover_setting.continuation_prompt': '',
   });
 
   let [loading, setLoading] = useState(false);
@@ -72,6 +88,18 @@ const PerformanceSetting = () => {
         <Card style={{ marginTop: '10px' }}>
           <SettingsPerformance options={inputs} refresh={onRefresh} />
         </Card>
+        {/* 响应缓存 */}
+        <Card style={{ marginTop: '10px' }}>
+          <SettingsResponseCache options={inputs} refresh={onRefresh} />
+        </Card>
+        {/* Responses API 会话存储 */}
+        <Card style={{ marginTop: '10px' }}>
+          <SettingsResponsesStore options={inputs} refresh={onRefresh} />
+        </Card>
+        {/* 流式续传 */}
+        <Card style={{ marginTop: '10px' }}>
+          <SettingsStreamFailover options={inputs} refresh={onRefresh} />
+        </Card>
       </Spin>
     </>
   );
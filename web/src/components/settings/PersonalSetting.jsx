@@ -41,6 +41,7 @@ import AccountManagement from './personal/cards/AccountManagement';
 import NotificationSettings from './personal/cards/NotificationSettings';
 import PreferencesSettings from './personal/cards/PreferencesSettings';
 import CheckinCalendar from './personal/cards/CheckinCalendar';
+import WebhookSettings from './personal/cards/WebhookSettings';
 import EmailBindModal from './personal/modals/EmailBindModal';
 import WeChatBindModal from './personal/modals/WeChatBindModal';
 import AccountDeleteModal from './personal/modals/AccountDeleteModal';
@@ -61,6 +62,7 @@ const PersonalSetting = () => {
     set_new_password_confirmation: '',
   });
   const [status, setStatus] = useState({});
+  const isAdminUser = (userState?.user?.role || 0) >= 10;
   const [showChangePasswordModal, setShowChangePasswordModal] = useState(false);
   const [showWeChatBindModal, setShowWeChatBindModal] = useState(false);
   const [showEmailBindModal, setShowEmailBindModal] = useState(false);
@@ -501,6 +503,14 @@ const PersonalSetting = () => {
               saveNotificationSettings={saveNotificationSettings}
             />
           </div>
+
+          {/* 事件订阅 - 仅在启用时显示 */}
+          {status?.webhook_event_enabled &&
+            (status?.webhook_event_user_enabled || isAdminUser) && (
+              <div className='mt-4 md:mt-6'>
+                <WebhookSettings t={t} isAdmin={isAdminUser} />
+              </div>
+            )}
         </div>
       </div>
 
@@ -23,6 +23,7 @@ import { Card, Spin } from '@douyinfe/semi-ui';
 import { API, showError, toBoolean } from '../../helpers';
 import { useTranslation } from 'react-i18next';
 import RequestRateLimit from '../../pages/Setting/RateLimit/SettingsRequestRateLimit';
+import UsageLimit from '../../pages/Setting/RateLimit/SettingsUsageLimit';
 
 const RateLimitSetting = () => {
   const { t } = useTranslation();
@@ -32,6 +33,7 @@ const RateLimitSetting = () => {
     ModelRequestRateLimitSuccessCount: 1000,
     ModelRequestRateLimitDurationMinutes: 1,
     ModelRequestRateLimitGroup: '',
+    'usage_limit_setting.group_limits': '',
   });
 
   let [loading, setLoading] = useState(false);
@@ -42,7 +44,10 @@ const RateLimitSetting = () => {
     if (success) {
       let newInputs = {};
       data.forEach((item) => {
-        if (item.key === 'ModelRequestRateLimitGroup') {
+        if (
+          item.key === 'ModelRequestRateLimitGroup' ||
+          item.key === 'usage_limit_setting.group_limits'
+        ) {
           item.value = JSON.stringify(JSON.parse(item.value), null, 2);
         }
 
@@ -81,6 +86,10 @@ const RateLimitSetting = () => {
         <Card style={{ marginTop: '10px' }}>
           <RequestRateLimit options={inputs} refresh={onRefresh} />
         </Card>
+        {/* TPM / RPM / 并发数限制 */}
+        <Card style={{ marginTop: '10px' }}>
+          <UsageLimit options={inputs} refresh={onRefresh} />
+        </Card>
       </Spin>
     </>
   );
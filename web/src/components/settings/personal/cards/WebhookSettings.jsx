@@ -0,0 +1,607 @@
+/*
+Copyright (C) 2025 QuantumNous
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+For commercial licensing, please contact support@quantumnous.com
+*/
+
+import React, { useEffect, useRef, useState } from 'react';
+import {
+  Avatar,
+  Button,
+  Card,
+  Empty,
+  Form,
+  Modal,
+  Popconfirm,
+  Select,
+  Space,
+  Switch,
+  Table,
+  Tag,
+  Typography,
+} from '@douyinfe/semi-ui';
+import { Webhook } from 'lucide-react';
+import {
+  API,
+  copy,
+  showError,
+  showSuccess,
+  timestamp2string,
+} from '../../../../helpers';
+import CodeViewer from '../../../playground/CodeViewer';
+
+const DELIVERY_STATUS_COLORS = {
+  pending: 'orange',
+  success: 'green',
+  failed: 'red',
+};
+
+function parseEvents(events) {
+  try {
+    const parsed = JSON.parse(events || '[]');
+    return Array.isArray(parsed) ? parsed : [];
+  } catch (e) {
+    return [];
+  }
+}
+
+const WebhookSettings = ({ t, isAdmin }) => {
+  const formApiRef = useRef(null);
+  const [endpoints, setEndpoints] = useState([]);
+  const [eventTypes, setEventTypes] = useState([]);
+  const [loading, setLoading] = useState(false);
+  const [editing, setEditing] = useState(null);
+  const [saving, setSaving] = useState(false);
+
+  const [deliveryEndpoint, setDeliveryEndpoint] = useState(null);
+  const [deliveries, setDeliveries] = useState([]);
+  const [deliveryLoading, setDeliveryLoading] = useState(false);
+  const [deliveryPage, setDeliveryPage] = useState(1);
+  const [deliveryTotal, setDeliveryTotal] = useState(0);
+  const [deliveryStatus, setDeliveryStatus] = useState('');
+  const [payload, setPayload] = useState(null);
+
+  const eventLabels = {
+    '*': t('全部事件'),
+    'channel.auto_disabled': t('渠道被自动禁用'),
+    'channel.auto_enabled': t('渠道被自动启用'),
+    'channel.key_expiring': t('渠道密钥即将过期'),
+    'channel.key_expired': t('渠道密钥已过期'),
+    'audit.logged': t('管理操作审计'),
+    'topup.completed': t('充值完成'),
+    'subscription.expired': t('订阅到期'),
+    'token.exhausted': t('令牌额度耗尽'),
+    'task.finished': t('异步任务完成'),
+    'upstream_model.update_detected': t('检测到上游模型变更'),
+    'webhook.test': t('测试事件'),
+  };
+
+  const statusLabels = {
+    pending: t('等待投递'),
+    success: t('成功'),
+    failed: t('失败'),
+  };
+
+  const loadEndpoints = async () => {
+    setLoading(true);
+    try {
+      const res = await API.get('/api/webhook/self');
+      const { success, message, data } = res.data;
+      if (success) {
+        setEndpoints(data || []);
+      } else {
+        showError(message);
+      }
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const loadEventTypes = async () => {
+    const res = await API.get('/api/webhook/events');
+    if (res.data.success) {
+      setEventTypes(res.data.data || []);
+    }
+  };
+
+  const loadDeliveries = async (
+    endpoint,
+    page = 1,
+    status = deliveryStatus,
+  ) => {
+    setDeliveryLoading(true);
+    try {
+      const res = await API.get('/api/webhook/delivery', {
+        params: {
+          endpoint_id: endpoint.id,
+          status,
+          p: page,
+          page_size: 10,
+        },
+      });
+      const { success, message, data } = res.data;
+      if (success) {
+        setDeliveries(data.items || []);
+        setDeliveryTotal(data.total || 0);
+        setDeliveryPage(page);
+      } else {
+        showError(message);
+      }
+    } finally {
+      setDeliveryLoading(false);
+    }
+  };
+
+  useEffect(() => {
+    loadEndpoints();
+    loadEventTypes();
+  }, []);
+
+  const openEditor = (endpoint) => {
+    setEditing(
+      endpoint
+        ? { ...endpoint, events: parseEvents(endpoint.events), secret: '' }
+        : { name: '', url: '', secret: '', events: [], enabled: true },
+    );
+  };
+
+  const saveEndpoint = async () => {
+    let values;
+    try {
+      values = await formApiRef.current.validate();
+    } catch (e) {
+      return;
+    }
+    setSaving(true);
+    try {
+      const body = {
+        name: values.name || '',
+        url: values.url,
+        secret: values.secret || '',
+        events: values.events || [],
+        enabled: !!values.enabled,
+        all_users: !!values.all_users,
+      };
+      const res = editing.id
+        ? await API.put(`/api/webhook/${editing.id}`, body)
+        : await API.post('/api/webhook/', body);
+      const { success, message, data } = res.data;
+      if (success) {
+        showSuccess(t('保存成功'));
+        if (!editing.id && data?.secret) {
+          Modal.info({
+            title: t('签名密钥'),
+            content: (
+              <Typography.Text copyable>{data.secret}</Typography.Text>
+            ),
+          });
+        }
+        setEditing(null);
+        loadEndpoints();
+      } else {
+        showError(message);
+      }
+    } finally {
+      setSaving(false);
+    }
+  };
+
+  const toggleEndpoint = async (endpoint, enabled) => {
+    const res = await API.put(`/api/webhook/${endpoint.id}`, {
+      name: endpoint.name,
+      url: endpoint.url,
+      events: parseEvents(endpoint.events),
+      enabled,
+      all_users: endpoint.all_users,
+    });
+    if (res.data.success) {
+      loadEndpoints();
+    } else {
+      showError(res.data.message);
+    }
+  };
+
+  const deleteEndpoint = async (endpoint) => {
+    const res = await API.delete(`/api/webhook/${endpoint.id}`);
+    if (res.data.success) {
+      showSuccess(t('删除成功'));
+      loadEndpoints();
+    } else {
+      showError(res.data.message);
+    }
+  };
+
+  const testEndpoint = async (endpoint) => {
+    const res = await API.post(`/api/webhook/${endpoint.id}/test`);
+    const { success, message, data } = res.data;
+    if (!success) {
+      showError(message);
+    } else if (data.status === 'success') {
+      showSuccess(t('测试事件发送成功'));
+    } else {
+      showError(t('测试事件发送失败') + ': ' + (data.last_error || ''));
+    }
+  };
+
+  const retryDelivery = async (delivery) => {
+    const res = await API.post(`/api/webhook/delivery/${delivery.id}/retry`);
+    if (res.data.success) {
+      showSuccess(t('已重新加入投递队列'));
+      loadDeliveries(deliveryEndpoint, deliveryPage);
+    } else {
+      showError(res.data.message);
+    }
+  };
+
+  const openDeliveries = (endpoint) => {
+    setDeliveryEndpoint(endpoint);
+    setDeliveryStatus('');
+    loadDeliveries(endpoint, 1, '');
+  };
+
+  const renderEvents = (events) => (
+    <Space wrap spacing={4}>
+      {parseEvents(events).map((event) => (
+        <Tag key={event} size='small'>
+          {eventLabels[event] || event}
+        </Tag>
+      ))}
+    </Space>
+  );
+
+  const endpointColumns = [
+    {
+      title: t('名称'),
+      dataIndex: 'name',
+      render: (text, record) => (
+        <div>
+          <div>{text || '-'}</div>
+          {record.all_users && (
+            <Tag size='small' color='purple'>
+              {t('所有用户')}
+            </Tag>
+          )}
+        </div>
+      ),
+    },
+    {
+      title: t('地址'),
+      dataIndex: 'url',
+      render: (text) => (
+        <Typography.Text
+          ellipsis={{ showTooltip: true }}
+          style={{ maxWidth: 240 }}
+        >
+          {text}
+        </Typography.Text>
+      ),
+    },
+    {
+      title: t('订阅事件'),
+      dataIndex: 'events',
+      render: renderEvents,
+    },
+    {
+      title: t('启用'),
+      dataIndex: 'enabled',
+      render: (enabled, record) => (
+        <Switch
+          size='small'
+          checked={enabled}
+          onChange={(value) => toggleEndpoint(record, value)}
+        />
+      ),
+    },
+    {
+      title: '',
+      dataIndex: 'operate',
+      render: (text, record) => (
+        <Space>
+          <Button
+            size='small'
+            type='tertiary'
+            onClick={() => testEndpoint(record)}
+          >
+            {t('测试')}
+          </Button>
+          <Button
+            size='small'
+            type='tertiary'
+            onClick={() => openDeliveries(record)}
+          >
+            {t('投递记录')}
+          </Button>
+          <Button size='small' onClick={() => openEditor(record)}>
+            {t('编辑')}
+          </Button>
+          <Popconfirm
+            title={t('确定删除该端点？')}
+            onConfirm={() => deleteEndpoint(record)}
+          >
+            <Button size='small' type='danger'>
+              {t('删除')}
+            </Button>
+          </Popconfirm>
+        </Space>
+      ),
+    },
+  ];
+
+  const deliveryColumns = [
+    {
+      title: t('时间'),
+      dataIndex: 'created_at',
+      render: (value) => timestamp2string(value),
+    },
+    {
+      title: t('事件'),
+      dataIndex: 'event_type',
+      render: (value) => eventLabels[value] || value,
+    },
+    {
+      title: t('状态'),
+      dataIndex: 'status',
+      render: (value, record) => (
+        <Space spacing={4}>
+          <Tag color={DELIVERY_STATUS_COLORS[value]} size='small'>
+            {statusLabels[value] || value}
+          </Tag>
+          {record.last_status_code > 0 && (
+            <Tag size='small'>{record.last_status_code}</Tag>
+          )}
+        </Space>
+      ),
+    },
+    {
+      title: t('尝试次数'),
+      dataIndex: 'attempts',
+    },
+    {
+      title: t('下次尝试'),
+      dataIndex: 'next_attempt_at',
+      render: (value, record) =>
+        record.status === 'pending' && value ? timestamp2string(value) : '-',
+    },
+    {
+      title: t('错误信息'),
+      dataIndex: 'last_error',
+      render: (text) =>
+        text ? (
+          <Typography.Text
+            type='danger'
+            ellipsis={{ showTooltip: true }}
+            style={{ maxWidth: 200 }}
+          >
+            {text}
+          </Typography.Text>
+        ) : (
+          '-'
+        ),
+    },
+    {
+      title: '',
+      dataIndex: 'operate',
+      render: (text, record) => (
+        <Space>
+          <Button
+            size='small'
+            type='tertiary'
+            onClick={() => {
+              try {
+                setPayload(JSON.parse(record.payload));
+              } catch (e) {
+                setPayload(record.payload);
+              }
+            }}
+          >
+            {t('查看')}
+          </Button>
+          {record.status !== 'success' && (
+            <Button size='small' onClick={() => retryDelivery(record)}>
+              {t('重试')}
+            </Button>
+          )}
+        </Space>
+      ),
+    },
+  ];
+
+  return (
+    <Card
+      className='!rounded-2xl shadow-sm border-0'
+      footer={
+        <div className='flex justify-end'>
+          <Button type='primary' onClick={() => openEditor(null)}>
+            {t('添加端点')}
+          </Button>
+        </div>
+      }
+    >
+      {/* 卡片头部 */}
+      <div className='flex items-center mb-4'>
+        <Avatar size='small' color='cyan' className='mr-3 shadow-md'>
+          <Webhook size={16} />
+        </Avatar>
+        <div>
+          <Typography.Text className='text-lg font-medium'>
+            {t('事件订阅')}
+          </Typography.Text>
+          <div className='text-xs text-gray-600'>
+            {t(
+              '平台事件发生时向您的地址推送签名请求，失败后按指数退避自动重试',
+            )}
+          </div>
+        </div>
+      </div>
+
+      <Table
+        columns={endpointColumns}
+        dataSource={endpoints}
+        rowKey='id'
+        loading={loading}
+        pagination={false}
+        size='small'
+        empty={<Empty description={t('暂无端点')} />}
+      />
+
+      <Modal
+        title={editing?.id ? t('编辑端点') : t('添加端点')}
+        visible={!!editing}
+        onCancel={() => setEditing(null)}
+        onOk={saveEndpoint}
+        okButtonProps={{ loading: saving }}
+        width={640}
+      >
+        {editing && (
+          <Form
+            initValues={editing}
+            getFormApi={(api) => (formApiRef.current = api)}
+          >
+            <Form.Input field='name' label={t('名称')} />
+            <Form.Input
+              field='url'
+              label={t('地址')}
+              placeholder='https://example.com/webhook'
+              rules={[{ required: true, message: t('请输入地址') }]}
+            />
+            <Form.Input
+              field='secret'
+              label={t('签名密钥')}
+              mode='password'
+              placeholder={
+                editing.id
+                  ? t('留空则保持不变')
+                  : t('留空则自动生成')
+              }
+              extraText={t(
+                '请求体使用该密钥计算 HMAC-SHA256，结果以十六进制放在 X-Webhook-Signature 请求头中',
+              )}
+            />
+            <Form.Select
+              field='events'
+              label={t('订阅事件')}
+              multiple
+              style={{ width: '100%' }}
+              rules={[
+                { required: true, message: t('请至少选择一个事件') },
+              ]}
+              optionList={[
+                { value: '*', label: eventLabels['*'] },
+                ...eventTypes.map((item) => ({
+                  value: item.type,
+                  label: eventLabels[item.type] || item.type,
+                })),
+              ]}
+            />
+            <Form.Switch field='enabled' label={t('启用')} />
+            {isAdmin && (
+              <Form.Switch
+                field='all_users'
+                label={t('接收所有用户的事件')}
+                extraText={t(
+                  '关闭时仅接收与您账户相关的事件，渠道与上游模型事件仅推送到开启此项的端点',
+                )}
+              />
+            )}
+            <Form.Slot label={t('请求体示例')}>
+              <div style={{ height: '180px' }}>
+                <CodeViewer
+                  content={{
+                    id: 'evt_xxxxxxxx',
+                    type: 'topup.completed',
+                    created_at: 1739950503,
+                    user_id: 1,
+                    data: { source: 'stripe', quota: 500000, money: 1 },
+                  }}
+                  title='webhook'
+                  language='json'
+                />
+              </div>
+            </Form.Slot>
+          </Form>
+        )}
+      </Modal>
+
+      <Modal
+        title={
+          t('投递记录') +
+          (deliveryEndpoint?.name ? ` - ${deliveryEndpoint.name}` : '')
+        }
+        visible={!!deliveryEndpoint}
+        onCancel={() => setDeliveryEndpoint(null)}
+        footer={null}
+        width={960}
+      >
+        <div className='mb-3'>
+          <Select
+            value={deliveryStatus}
+            style={{ width: 160 }}
+            optionList={[
+              { value: '', label: t('全部状态') },
+              { value: 'pending', label: statusLabels.pending },
+              { value: 'success', label: statusLabels.success },
+              { value: 'failed', label: statusLabels.failed },
+            ]}
+            onChange={(value) => {
+              setDeliveryStatus(value);
+              loadDeliveries(deliveryEndpoint, 1, value);
+            }}
+          />
+        </div>
+        <Table
+          columns={deliveryColumns}
+          dataSource={deliveries}
+          rowKey='id'
+          loading={deliveryLoading}
+          size='small'
+          pagination={{
+            currentPage: deliveryPage,
+            pageSize: 10,
+            total: deliveryTotal,
+            onPageChange: (page) => loadDeliveries(deliveryEndpoint, page),
+          }}
+          empty={<Empty description={t('暂无投递记录')} />}
+        />
+      </Modal>
+
+      <Modal
+        title={t('请求体')}
+        visible={payload !== null}
+        onCancel={() => setPayload(null)}
+        footer={
+          <Button
+            onClick={() =>
+              copy(
+                typeof payload === 'string'
+                  ? payload
+                  : JSON.stringify(payload, null, 2),
+              )
+            }
+          >
+            {t('复制')}
+          </Button>
+        }
+        width={640}
+      >
+        <div style={{ height: '360px' }}>
+          <CodeViewer content={payload} title='payload' language='json' />
+        </div>
+      </Modal>
+    </Card>
+  );
+};
+
+export default WebhookSettings;
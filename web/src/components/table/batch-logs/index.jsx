@@ -0,0 +1,301 @@
+/*
+Copyright (C) 2025 QuantumNous
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+For commercial licensing, please contact support@quantumnous.com
+*/
+
+import React, { useEffect, useMemo, useState } from 'react';
+import { useTranslation } from 'react-i18next';
+import { Button, Empty, Form, Layout, Tag, Typography } from '@douyinfe/semi-ui';
+import { IconSearch } from '@douyinfe/semi-icons';
+import {
+  IllustrationNoResult,
+  IllustrationNoResultDark,
+} from '@douyinfe/semi-illustrations';
+import CardPro from '../../common/ui/CardPro';
+import CardTable from '../../common/ui/CardTable';
+import {
+  API,
+  copy,
+  isAdmin,
+  renderQuota,
+  showError,
+  showSuccess,
+  timestamp2string,
+} from '../../../helpers';
+import { ITEMS_PER_PAGE } from '../../../constants';
+import { useIsMobile } from '../../../hooks/common/useIsMobile';
+import { createCardProPagination } from '../../../helpers/utils';
+
+const { Text } = Typography;
+
+const BATCH_STATUS_MAP = {
+  validating: { color: 'blue', label: '校验中' },
+  in_progress: { color: 'light-blue', label: '执行中' },
+  finalizing: { color: 'cyan', label: '整理结果中' },
+  completed: { color: 'green', label: '已完成' },
+  failed: { color: 'red', label: '失败' },
+  expired: { color: 'grey', label: '已过期' },
+  cancelling: { color: 'orange', label: '取消中' },
+  cancelled: { color: 'grey', label: '已取消' },
+};
+
+const renderTimestamp = (ts) => (ts ? timestamp2string(ts) : '-');
+
+const BatchLogsPage = () => {
+  const { t } = useTranslation();
+  const isMobile = useIsMobile();
+  const isAdminUser = isAdmin();
+
+  const [batches, setBatches] = useState([]);
+  const [loading, setLoading] = useState(false);
+  const [activePage, setActivePage] = useState(1);
+  const [pageSize, setPageSize] = useState(ITEMS_PER_PAGE);
+  const [total, setTotal] = useState(0);
+  const [formApi, setFormApi] = useState(null);
+
+  const copyText = async (text) => {
+    if (await copy(text)) {
+      showSuccess(t('已复制：') + text);
+    }
+  };
+
+  const loadBatches = async (page = 1, size = pageSize) => {
+    setLoading(true);
+    const values = formApi ? formApi.getValues() : {};
+    const params = new URLSearchParams({
+      p: page,
+      page_size: size,
+      batch_id: values.batch_id || '',
+      status: values.status || '',
+    });
+    if (isAdminUser) {
+      params.set('user_id', values.user_id || '');
+    }
+    const url = isAdminUser ? '/api/batch/' : '/api/batch/self';
+    try {
+      const res = await API.get(`${url}?${params.toString()}`);
+      const { success, message, data } = res.data;
+      if (success) {
+        setBatches(
+          (data.items || []).map((item) => ({ ...item, key: item.batch_id })),
+        );
+        setActivePage(data.page || page);
+        setPageSize(data.page_size || size);
+        setTotal(data.total || 0);
+      } else {
+        showError(message);
+      }
+    } catch (error) {
+      showError(error.message);
+    }
+    setLoading(false);
+  };
+
+  useEffect(() => {
+    loadBatches(1, pageSize).then();
+  }, []);
+
+  const columns = useMemo(() => {
+    const cols = [
+      {
+        key: 'created_at',
+        title: t('提交时间'),
+        dataIndex: 'created_at',
+        render: renderTimestamp,
+      },
+      {
+        key: 'batch_id',
+        title: t('批次ID'),
+        dataIndex: 'batch_id',
+        render: (text) => (
+          <Text className='cursor-pointer' onClick={() => copyText(text)}>
+            {text}
+          </Text>
+        ),
+      },
+    ];
+    if (isAdminUser) {
+      cols.push({
+        key: 'user',
+        title: t('用户'),
+        dataIndex: 'username',
+        render: (text, record) => text || record.user_id,
+      });
+    }
+    cols.push(
+      {
+        key: 'endpoint',
+        title: t('端点'),
+        dataIndex: 'endpoint',
+      },
+      {
+        key: 'status',
+        title: t('状态'),
+        dataIndex: 'status',
+        render: (text) => {
+          const status = BATCH_STATUS_MAP[text];
+          return (
+            <Tag color={status?.color || 'grey'} shape='circle'>
+              {status ? t(status.label) : text}
+            </Tag>
+          );
+        },
+      },
+      {
+        key: 'progress',
+        title: t('进度'),
+        dataIndex: 'total_count',
+        render: (_, record) => (
+          <span>
+            <Text type='success'>{record.completed_count}</Text>
+            {' / '}
+            <Text type='danger'>{record.failed_count}</Text>
+            {' / '}
+            {record.total_count}
+          </span>
+        ),
+      },
+      {
+        key: 'quota',
+        title: t('花费'),
+        dataIndex: 'quota',
+        render: (quota) => renderQuota(quota || 0, 6),
+      },
+      {
+        key: 'output_file_id',
+        title: t('结果文件'),
+        dataIndex: 'output_file_id',
+        render: (text) => text || '-',
+      },
+      {
+        key: 'error_file_id',
+        title: t('错误文件'),
+        dataIndex: 'error_file_id',
+        render: (text) => text || '-',
+      },
+      {
+        key: 'completed_at',
+        title: t('完成时间'),
+        dataIndex: 'completed_at',
+        render: (_, record) =>
+          renderTimestamp(
+            record.completed_at ||
+              record.failed_at ||
+              record.cancelled_at ||
+              record.expired_at,
+          ),
+      },
+    );
+    return cols;
+  }, [t, isAdminUser]);
+
+  return (
+    <Layout>
+      <CardPro
+        type='type2'
+        statsArea={
+          <Text type='secondary'>
+            {t('批处理请求通过 /v1/batches 提交，按批处理折扣倍率计费')}
+          </Text>
+        }
+        searchArea={
+          <Form
+            getFormApi={(api) => setFormApi(api)}
+            onSubmit={() => loadBatches(1, pageSize)}
+            allowEmpty={true}
+            autoComplete='off'
+            layout='vertical'
+            trigger='change'
+          >
+            <div className='grid grid-cols-1 md:grid-cols-2 lg:grid-cols-4 gap-2'>
+              <Form.Input
+                field='batch_id'
+                prefix={<IconSearch />}
+                placeholder={t('批次ID')}
+                showClear
+                pure
+                size='small'
+              />
+              <Form.Select
+                field='status'
+                placeholder={t('状态')}
+                optionList={Object.entries(BATCH_STATUS_MAP).map(
+                  ([value, status]) => ({ label: t(status.label), value }),
+                )}
+                showClear
+                pure
+                size='small'
+              />
+              {isAdminUser && (
+                <Form.Input
+                  field='user_id'
+                  placeholder={t('用户ID')}
+                  showClear
+                  pure
+                  size='small'
+                />
+              )}
+              <Button
+                type='tertiary'
+                htmlType='submit'
+                loading={loading}
+                size='small'
+              >
+                {t('查询')}
+              </Button>
+            </div>
+          </Form>
+        }
+        paginationArea={createCardProPagination({
+          currentPage: activePage,
+          pageSize: pageSize,
+          total: total,
+          onPageChange: (page) => loadBatches(page, pageSize),
+          onPageSizeChange: (size) => loadBatches(1, size),
+          isMobile: isMobile,
+          t: t,
+        })}
+        t={t}
+      >
+        <CardTable
+          columns={columns}
+          dataSource={batches}
+          rowKey='key'
+          loading={loading}
+          scroll={{ x: 'max-content' }}
+          className='rounded-xl overflow-hidden'
+          size='middle'
+          empty={
+            <Empty
+              image={
+                <IllustrationNoResult style={{ width: 150, height: 150 }} />
+              }
+              darkModeImage={
+                <IllustrationNoResultDark style={{ width: 150, height: 150 }} />
+              }
+              description={t('搜索无结果')}
+              style={{ padding: 30 }}
+            />
+          }
+          hidePagination={true}
+        />
+      </CardPro>
+    </Layout>
+  );
+};
+
+export default BatchLogsPage;
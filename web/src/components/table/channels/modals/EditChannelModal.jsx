@@ -181,6 +181,9 @@ const EditChannelModal = (props) => {
     groups: ['default'],
     priority: 0,
     weight: 0,
+    rpm_limit: 0,
+    tpm_limit: 0,
+    max_in_flight: 0,
     tag: '',
     multi_key_mode: 'random',
     // 渠道额外设置的默认值
@@ -188,6 +191,7 @@ const EditChannelModal = (props) => {
     thinking_to_content: false,
     proxy: '',
     pass_through_body_enabled: false,
+    propagate_trace_context: false,
     system_prompt: '',
     system_prompt_override: false,
     settings: '',
@@ -843,6 +847,8 @@ const EditChannelModal = (props) => {
           data.proxy = parsedSettings.proxy || '';
           data.pass_through_body_enabled =
             parsedSettings.pass_through_body_enabled || false;
+          data.propagate_trace_context =
+            parsedSettings.propagate_trace_context || false;
           data.system_prompt = parsedSettings.system_prompt || '';
           data.system_prompt_override =
             parsedSettings.system_prompt_override || false;
@@ -852,6 +858,7 @@ const EditChannelModal = (props) => {
           data.thinking_to_content = false;
           data.proxy = '';
           data.pass_through_body_enabled = false;
+          data.propagate_trace_context = false;
           data.system_prompt = '';
           data.system_prompt_override = false;
         }
@@ -860,6 +867,7 @@ const EditChannelModal = (props) => {
         data.thinking_to_content = false;
         data.proxy = '';
         data.pass_through_body_enabled = false;
+        data.propagate_trace_context = false;
         data.system_prompt = '';
         data.system_prompt_override = false;
       }
@@ -968,6 +976,7 @@ const EditChannelModal = (props) => {
         thinking_to_content: data.thinking_to_content,
         proxy: data.proxy,
         pass_through_body_enabled: data.pass_through_body_enabled,
+        propagate_trace_context: data.propagate_trace_context || false,
         system_prompt: data.system_prompt,
         system_prompt_override: data.system_prompt_override || false,
       });
@@ -1326,6 +1335,7 @@ const EditChannelModal = (props) => {
       thinking_to_content: false,
       proxy: '',
       pass_through_body_enabled: false,
+      propagate_trace_context: false,
       system_prompt: '',
       system_prompt_override: false,
     });
@@ -1692,6 +1702,7 @@ const EditChannelModal = (props) => {
       thinking_to_content: localInputs.thinking_to_content || false,
       proxy: localInputs.proxy || '',
       pass_through_body_enabled: localInputs.pass_through_body_enabled || false,
+      propagate_trace_context: localInputs.propagate_trace_context || false,
       system_prompt: localInputs.system_prompt || '',
       system_prompt_override: localInputs.system_prompt_override || false,
     };
@@ -1773,6 +1784,7 @@ const EditChannelModal = (props) => {
     delete localInputs.thinking_to_content;
     delete localInputs.proxy;
     delete localInputs.pass_through_body_enabled;
+    delete localInputs.propagate_trace_context;
     delete localInputs.system_prompt;
     delete localInputs.system_prompt_override;
     delete localInputs.is_enterprise_account;
@@ -2746,6 +2758,14 @@ const EditChannelModal = (props) => {
                           optionList={[
                             { label: t('随机'), value: 'random' },
                             { label: t('轮询'), value: 'polling' },
+                            {
+                              label: t('最久未使用优先'),
+                              value: 'least_recently_used',
+                            },
+                            {
+                              label: t('当日消耗最少优先'),
+                              value: 'least_spent',
+                            },
                           ]}
                           style={{ width: '100%' }}
                           value={inputs.multi_key_mode || 'random'}
@@ -3449,6 +3469,51 @@ const EditChannelModal = (props) => {
                       </Col>
                     </Row>
 
+                    <Row gutter={12}>
+                      <Col span={8}>
+                        <Form.InputNumber
+                          field='rpm_limit'
+                          label={t('每分钟请求数 (RPM)')}
+                          min={0}
+                          precision={0}
+                          onNumberChange={(value) =>
+                            handleInputChange('rpm_limit', value)
+                          }
+                          style={{ width: '100%' }}
+                        />
+                      </Col>
+                      <Col span={8}>
+                        <Form.InputNumber
+                          field='tpm_limit'
+                          label={t('每分钟 Token 数 (TPM)')}
+                          min={0}
+                          step={1000}
+                          precision={0}
+                          onNumberChange={(value) =>
+                            handleInputChange('tpm_limit', value)
+                          }
+                          style={{ width: '100%' }}
+                        />
+                      </Col>
+                      <Col span={8}>
+                        <Form.InputNumber
+                          field='max_in_flight'
+                          label={t('最大并发请求数')}
+                          min={0}
+                          precision={0}
+                          onNumberChange={(value) =>
+                            handleInputChange('max_in_flight', value)
+                          }
+                          style={{ width: '100%' }}
+                        />
+                      </Col>
+                    </Row>
+                    <div className='text-xs text-gray-500 mb-3'>
+                      {t(
+                        '0 表示不限制；达到任一限制后选择渠道时将跳过该渠道，而不是直接返回 429',
+                      )}
+                    </div>
+
                     <Form.Switch
                       field='auto_ban'
                       label={t('是否自动禁用')}
@@ -3905,6 +3970,22 @@ const EditChannelModal = (props) => {
                       extraText={t('启用请求体透传功能')}
                     />
 
+                    <Form.Switch
+                      field='propagate_trace_context'
+                      label={t('透传链路追踪')}
+                      checkedText={t('开')}
+                      uncheckedText={t('关')}
+                      onChange={(value) =>
+                        handleChannelSettingsChange(
+                          'propagate_trace_context',
+                          value,
+                        )
+                      }
+                      extraText={t(
+                        '向上游请求附加 W3C traceparent 请求头，便于与上游链路关联',
+                      )}
+                    />
+
                     <Form.Input
                       field='proxy'
                       label={t('代理地址')}
@@ -36,6 +36,9 @@ import {
   Badge,
   Progress,
   Card,
+  DatePicker,
+  InputNumber,
+  Input,
 } from '@douyinfe/semi-ui';
 import {
   IllustrationNoResult,
@@ -43,6 +46,7 @@ import {
 } from '@douyinfe/semi-illustrations';
 import {
   API,
+  renderQuota,
   showError,
   showSuccess,
   timestamp2string,
@@ -50,6 +54,13 @@ import {
 
 const { Text } = Typography;
 
+const multiKeyModeLabels = {
+  random: '随机模式',
+  polling: '轮询模式',
+  least_recently_used: '最久未使用优先',
+  least_spent: '当日消耗最少优先',
+};
+
 const MultiKeyManageModal = ({ visible, onCancel, channel, onRefresh }) => {
   const { t } = useTranslation();
   const [loading, setLoading] = useState(false);
@@ -70,6 +81,10 @@ const MultiKeyManageModal = ({ visible, onCancel, channel, onRefresh }) => {
   // Filter states
   const [statusFilter, setStatusFilter] = useState(null); // null=all, 1=enabled, 2=manual_disabled, 3=auto_disabled
 
+  // Key meta editing states
+  const [editingKey, setEditingKey] = useState(null);
+  const [metaSaving, setMetaSaving] = useState(false);
+
   // Load key status data
   const loadKeyStatus = async (
     page = currentPage,
@@ -169,6 +184,35 @@ const MultiKeyManageModal = ({ visible, onCancel, channel, onRefresh }) => {
     }
   };
 
+  // Save expiry, daily spend cap and note of a key
+  const handleSaveKeyMeta = async () => {
+    if (!editingKey) return;
+    setMetaSaving(true);
+    try {
+      const res = await API.post('/api/channel/multi_key/manage', {
+        channel_id: channel.id,
+        action: 'set_key_meta',
+        key_index: editingKey.index,
+        expires_at: editingKey.expires_at || 0,
+        daily_spend_cap: editingKey.daily_spend_cap || 0,
+        note: editingKey.note || '',
+      });
+
+      if (res.data.success) {
+        showSuccess(t('密钥信息已更新'));
+        setEditingKey(null);
+        await loadKeyStatus(currentPage, pageSize);
+        onRefresh && onRefresh();
+      } else {
+        showError(res.data.message);
+      }
+    } catch (error) {
+      showError(t('更新密钥信息失败'));
+    } finally {
+      setMetaSaving(false);
+    }
+  };
+
   // Enable all disabled keys
   const handleEnableAll = async () => {
     setOperationLoading((prev) => ({ ...prev, enable_all: true }));
@@ -406,13 +450,85 @@ const MultiKeyManageModal = ({ visible, onCancel, channel, onRefresh }) => {
         );
       },
     },
+    {
+      title: t('过期时间'),
+      dataIndex: 'expires_at',
+      render: (time) => {
+        if (!time) {
+          return <Text type='quaternary'>-</Text>;
+        }
+        const expired = time * 1000 <= Date.now();
+        return (
+          <Text
+            style={{ fontSize: '12px' }}
+            type={expired ? 'danger' : undefined}
+          >
+            {timestamp2string(time)}
+          </Text>
+        );
+      },
+    },
+    {
+      title: t('今日消耗 / 上限'),
+      dataIndex: 'today_used_quota',
+      render: (used, record) => (
+        <Text style={{ fontSize: '12px' }}>
+          {renderQuota(used || 0)}
+          {' / '}
+          {record.daily_spend_cap
+            ? renderQuota(record.daily_spend_cap)
+            : t('不限制')}
+        </Text>
+      ),
+    },
+    {
+      title: t('最近使用'),
+      dataIndex: 'last_used_at',
+      render: (time) => {
+        if (!time) {
+          return <Text type='quaternary'>-</Text>;
+        }
+        return (
+          <Text style={{ fontSize: '12px' }}>{timestamp2string(time)}</Text>
+        );
+      },
+    },
+    {
+      title: t('备注'),
+      dataIndex: 'note',
+      render: (note) => {
+        if (!note) {
+          return <Text type='quaternary'>-</Text>;
+        }
+        return (
+          <Tooltip content={note}>
+            <Text style={{ maxWidth: '160px', display: 'block' }} ellipsis>
+              {note}
+            </Text>
+          </Tooltip>
+        );
+      },
+    },
     {
       title: t('操作'),
       key: 'action',
       fixed: 'right',
-      width: 150,
+      width: 210,
       render: (_, record) => (
         <Space>
+          <Button
+            size='small'
+            onClick={() =>
+              setEditingKey({
+                index: record.index,
+                expires_at: record.expires_at || 0,
+                daily_spend_cap: record.daily_spend_cap || 0,
+                note: record.note || '',
+              })
+            }
+          >
+            {t('编辑')}
+          </Button>
           {record.status === 1 ? (
             <Button
               type='danger'
@@ -467,9 +583,10 @@ const MultiKeyManageModal = ({ visible, onCancel, channel, onRefresh }) => {
           </Tag>
           {channel?.channel_info?.multi_key_mode && (
             <Tag size='small' shape='circle' color='white'>
-              {channel.channel_info.multi_key_mode === 'random'
-                ? t('随机模式')
-                : t('轮询模式')}
+              {t(
+                multiKeyModeLabels[channel.channel_info.multi_key_mode] ||
+                  '轮询模式',
+              )}
             </Tag>
           )}
         </Space>
@@ -735,6 +852,71 @@ const MultiKeyManageModal = ({ visible, onCancel, channel, onRefresh }) => {
           </Spin>
         </div>
       </div>
+      <Modal
+        title={
+          editingKey
+            ? `${t('编辑密钥信息')} #${editingKey.index + 1}`
+            : t('编辑密钥信息')
+        }
+        visible={!!editingKey}
+        onCancel={() => setEditingKey(null)}
+        onOk={handleSaveKeyMeta}
+        confirmLoading={metaSaving}
+      >
+        {editingKey && (
+          <Space vertical align='start' style={{ width: '100%' }}>
+            <Text strong>{t('过期时间')}</Text>
+            <DatePicker
+              type='dateTime'
+              style={{ width: '100%' }}
+              placeholder={t('不过期')}
+              value={
+                editingKey.expires_at
+                  ? new Date(editingKey.expires_at * 1000)
+                  : undefined
+              }
+              onChange={(date) =>
+                setEditingKey({
+                  ...editingKey,
+                  expires_at: date
+                    ? Math.floor(new Date(date).getTime() / 1000)
+                    : 0,
+                })
+              }
+            />
+            <Text type='tertiary' size='small'>
+              {t('过期后密钥将被自动禁用，过期前会按监控设置发送提醒')}
+            </Text>
+            <Text strong>{t('每日消耗上限')}</Text>
+            <InputNumber
+              style={{ width: '100%' }}
+              min={0}
+              step={1}
+              value={editingKey.daily_spend_cap}
+              suffix={renderQuota(editingKey.daily_spend_cap || 0)}
+              onChange={(value) =>
+                setEditingKey({
+                  ...editingKey,
+                  daily_spend_cap: parseInt(value) || 0,
+                })
+              }
+            />
+            <Text type='tertiary' size='small'>
+              {t(
+                '单位为额度，0 表示不限制；达到上限后当日自动禁用，次日自动恢复',
+              )}
+            </Text>
+            <Text strong>{t('备注')}</Text>
+            <Input
+              value={editingKey.note}
+              maxLength={255}
+              onChange={(value) =>
+                setEditingKey({ ...editingKey, note: value })
+              }
+            />
+          </Space>
+        )}
+      </Modal>
     </Modal>
   );
 };
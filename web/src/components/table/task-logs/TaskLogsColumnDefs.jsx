@@ -33,6 +33,7 @@ import {
   Hash,
   Video,
   Sparkles,
+  Brain,
 } from 'lucide-react';
 import {
   TASK_ACTION_FIRST_TAIL_GENERATE,
@@ -40,6 +41,7 @@ import {
   TASK_ACTION_REFERENCE_GENERATE,
   TASK_ACTION_TEXT_GENERATE,
   TASK_ACTION_REMIX_GENERATE,
+  TASK_ACTION_FINE_TUNE,
 } from '../../../constants/common.constant';
 import { CHANNEL_OPTIONS } from '../../../constants/channel.constants';
 import { stringToColor } from '../../../helpers/render';
@@ -134,6 +136,12 @@ const renderType = (type, t) => {
           {t('视频Remix')}
         </Tag>
       );
+    case TASK_ACTION_FINE_TUNE:
+      return (
+        <Tag color='purple' shape='circle' prefixIcon={<Brain size={14} />}>
+          {t('模型微调')}
+        </Tag>
+      );
     default:
       return (
         <Tag color='white' shape='circle' prefixIcon={<HelpCircle size={14} />}>
@@ -161,6 +169,12 @@ const renderPlatform = (platform, t) => {
           Suno
         </Tag>
       );
+    case 'fine_tune':
+      return (
+        <Tag color='purple' shape='circle'>
+          Fine-tuning
+        </Tag>
+      );
     default:
       return (
         <Tag color='white' shape='circle'>
@@ -430,6 +444,23 @@ export const getTaskLogsColumns = ({
             </a>
           );
         }
+        // 微调任务：展示训练产出的模型名称
+        if (
+          isSuccess &&
+          record.action === TASK_ACTION_FINE_TUNE &&
+          typeof resultUrl === 'string' &&
+          resultUrl
+        ) {
+          return (
+            <Typography.Text
+              copyable={{ content: resultUrl }}
+              ellipsis={{ showTooltip: true }}
+              style={{ width: 100 }}
+            >
+              {resultUrl}
+            </Typography.Text>
+          );
+        }
         if (!text) {
           return t('无');
         }
@@ -23,6 +23,7 @@ import {
   showError,
   showSuccess,
   timestamp2string,
+  renderQuota,
   renderQuotaWithPrompt,
   renderRatio,
   getModelCategories,
@@ -69,6 +70,10 @@ const EditTokenModal = (props) => {
   const [groups, setGroups] = useState([]);
   // 多分组优先级列表，每项 { group: string, priority: number }，按 priority 升序排列
   const [tokenGroups, setTokenGroups] = useState([]);
+  // 编辑时展示当前各预算周期的使用情况
+  const [budgetUsage, setBudgetUsage] = useState([]);
+  // 用户加入的组织，选择后令牌从组织共享钱包扣费
+  const [organizations, setOrganizations] = useState([]);
   const isEdit = props.editingToken.id !== undefined;
 
   const getInitValues = () => ({
@@ -79,6 +84,14 @@ const EditTokenModal = (props) => {
     model_limits_enabled: false,
     model_limits: [],
     allow_ips: '',
+    rpm_limit: 0,
+    tpm_limit: 0,
+    max_in_flight: 0,
+    daily_quota_limit: 0,
+    weekly_quota_limit: 0,
+    monthly_quota_limit: 0,
+    budget_notify_enabled: false,
+    organization_id: 0,
     tokenCount: 1,
   });
 
@@ -154,6 +167,12 @@ const EditTokenModal = (props) => {
     }
   };
 
+  const loadOrganizations = async () => {
+    const res = await API.get(`/api/organization/self`);
+    const { success, data } = res.data;
+    setOrganizations(success ? data || [] : []);
+  };
+
   const loadToken = async () => {
     setLoading(true);
     let res = await API.get(`/api/token/${props.editingToken.id}`);
@@ -184,12 +203,25 @@ const EditTokenModal = (props) => {
       if (formApiRef.current) {
         formApiRef.current.setValues({ ...getInitValues(), ...data });
       }
+      loadBudgetUsage();
     } else {
       showError(message);
     }
     setLoading(false);
   };
 
+  const loadBudgetUsage = async () => {
+    const res = await API.get(`/api/token/${props.editingToken.id}/usage`);
+    const { success, data } = res.data;
+    setBudgetUsage(success ? data.budgets || [] : []);
+  };
+
+  const budgetPeriodLabels = {
+    daily: t('每日预算'),
+    weekly: t('每周预算'),
+    monthly: t('每月预算'),
+  };
+
   useEffect(() => {
     if (formApiRef.current) {
       if (!isEdit) {
@@ -198,6 +230,7 @@ const EditTokenModal = (props) => {
     }
     loadModels();
     loadGroups();
+    loadOrganizations();
   }, [props.editingToken.id]);
 
   useEffect(() => {
@@ -401,6 +434,25 @@ const EditTokenModal = (props) => {
                       />
                     </Form.Slot>
                   </Col>
+                  {organizations.length > 0 && (
+                    <Col span={24}>
+                      <Form.Select
+                        field='organization_id'
+                        label={t('计费来源')}
+                        optionList={[
+                          { label: t('个人钱包'), value: 0 },
+                          ...organizations.map((org) => ({
+                            label: `${t('组织')}: ${org.name}`,
+                            value: org.id,
+                          })),
+                        ]}
+                        extraText={t(
+                          '选择组织后，该令牌的消耗从组织共享钱包扣除，并计入您在组织中的成员额度',
+                        )}
+                        style={{ width: '100%' }}
+                      />
+                    </Col>
+                  )}
                   <Col xs={24} sm={24} md={24} lg={10} xl={10}>
                     <Form.DatePicker
                       field='expired_time'
@@ -529,6 +581,78 @@ const EditTokenModal = (props) => {
                       )}
                     />
                   </Col>
+                  <Col span={8}>
+                    <Form.InputNumber
+                      field='daily_quota_limit'
+                      label={t('每日预算')}
+                      min={0}
+                      step={500000}
+                      precision={0}
+                      extraText={renderQuotaWithPrompt(
+                        values.daily_quota_limit,
+                      )}
+                      style={{ width: '100%' }}
+                    />
+                  </Col>
+                  <Col span={8}>
+                    <Form.InputNumber
+                      field='weekly_quota_limit'
+                      label={t('每周预算')}
+                      min={0}
+                      step={500000}
+                      precision={0}
+                      extraText={renderQuotaWithPrompt(
+                        values.weekly_quota_limit,
+                      )}
+                      style={{ width: '100%' }}
+                    />
+                  </Col>
+                  <Col span={8}>
+                    <Form.InputNumber
+                      field='monthly_quota_limit'
+                      label={t('每月预算')}
+                      min={0}
+                      step={500000}
+                      precision={0}
+                      extraText={renderQuotaWithPrompt(
+                        values.monthly_quota_limit,
+                      )}
+                      style={{ width: '100%' }}
+                    />
+                  </Col>
+                  <Col span={24}>
+                    <Form.Switch
+                      field='budget_notify_enabled'
+                      label={t('预算通知')}
+                      size='default'
+                      extraText={t(
+                        '预算用量达到 80% 和 100% 时通过账户的通知方式提醒',
+                      )}
+                    />
+                  </Col>
+                  <Col span={24}>
+                    <Text type='tertiary' size='small'>
+                      {t(
+                        '0 表示不限制；预算按自然日、周一、每月 1 日自动重置，用尽后令牌在重置前无法使用',
+                      )}
+                    </Text>
+                  </Col>
+                  {isEdit &&
+                    budgetUsage.map((item) => (
+                      <Col span={24} key={item.period}>
+                        <Text size='small'>
+                          {t(
+                            '{{period}}：已用 {{used}} / {{limit}}，将于 {{reset}} 重置',
+                            {
+                              period: budgetPeriodLabels[item.period],
+                              used: renderQuota(item.used_quota),
+                              limit: renderQuota(item.quota_limit),
+                              reset: timestamp2string(item.next_reset_time),
+                            },
+                          )}
+                        </Text>
+                      </Col>
+                    ))}
                 </Row>
               </Card>
 
@@ -581,6 +705,43 @@ const EditTokenModal = (props) => {
                       style={{ width: '100%' }}
                     />
                   </Col>
+                  <Col span={8}>
+                    <Form.InputNumber
+                      field='rpm_limit'
+                      label={t('每分钟请求数 (RPM)')}
+                      min={0}
+                      step={1}
+                      precision={0}
+                      style={{ width: '100%' }}
+                    />
+                  </Col>
+                  <Col span={8}>
+                    <Form.InputNumber
+                      field='tpm_limit'
+                      label={t('每分钟 Token 数 (TPM)')}
+                      min={0}
+                      step={1000}
+                      precision={0}
+                      style={{ width: '100%' }}
+                    />
+                  </Col>
+                  <Col span={8}>
+                    <Form.InputNumber
+                      field='max_in_flight'
+                      label={t('最大并发请求数')}
+                      min={0}
+                      step={1}
+                      precision={0}
+                      style={{ width: '100%' }}
+                    />
+                  </Col>
+                  <Col span={24}>
+                    <Text type='tertiary' size='small'>
+                      {t(
+                        '0 表示不限制；TPM 按请求结束后的实际消耗（输入 + 输出）统计',
+                      )}
+                    </Text>
+                  </Col>
                 </Row>
               </Card>
             </div>
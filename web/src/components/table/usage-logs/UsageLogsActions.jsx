@@ -18,7 +18,7 @@ For commercial licensing, please contact support@quantumnous.com
 */
 
 import React from 'react';
-import { Tag, Space, Skeleton } from '@douyinfe/semi-ui';
+import { Tag, Space, Skeleton, Dropdown, Button } from '@douyinfe/semi-ui';
 import { renderQuota } from '../../../helpers';
 import CompactModeToggle from '../../common/ui/CompactModeToggle';
 import { useMinimumLoadingTime } from '../../../hooks/common/useMinimumLoadingTime';
@@ -29,6 +29,8 @@ const LogsActions = ({
   showStat,
   compactMode,
   setCompactMode,
+  exporting,
+  exportLogs,
   t,
 }) => {
   const showSkeleton = useMinimumLoadingTime(loadingStat);
@@ -83,11 +85,36 @@ const LogsActions = ({
         </Space>
       </Skeleton>
 
-      <CompactModeToggle
-        compactMode={compactMode}
-        setCompactMode={setCompactMode}
-        t={t}
-      />
+      <Space>
+        <Dropdown
+          trigger='click'
+          position='bottomRight'
+          render={
+            <Dropdown.Menu>
+              <Dropdown.Item onClick={() => exportLogs('csv')}>
+                CSV
+              </Dropdown.Item>
+              <Dropdown.Item onClick={() => exportLogs('jsonl')}>
+                JSON Lines
+              </Dropdown.Item>
+            </Dropdown.Menu>
+          }
+        >
+          <Button
+            type='tertiary'
+            size='small'
+            loading={exporting}
+            className='w-full md:w-auto'
+          >
+            {t('导出')}
+          </Button>
+        </Dropdown>
+        <CompactModeToggle
+          compactMode={compactMode}
+          setCompactMode={setCompactMode}
+          t={t}
+        />
+      </Space>
     </div>
   );
 };
@@ -132,6 +132,12 @@ function renderType(type, t) {
           {t('退款')}
         </Tag>
       );
+    case 7:
+      return (
+        <Tag color='pink' shape='circle'>
+          {t('审核')}
+        </Tag>
+      );
     default:
       return (
         <Tag color='grey' shape='circle'>
@@ -157,6 +163,21 @@ function renderIsStream(bool, t) {
   }
 }
 
+function renderResponseCacheHit(other, t) {
+  if (!other?.response_cache_hit) {
+    return null;
+  }
+  return (
+    <Tooltip
+      content={t('计费倍率') + `：${other.response_cache_hit_ratio ?? 1}`}
+    >
+      <Tag color='green' shape='circle'>
+        {t('缓存命中')}
+      </Tag>
+    </Tooltip>
+  );
+}
+
 function renderUseTime(type, t) {
   const time = parseInt(type);
   if (time < 101) {
@@ -601,7 +622,8 @@ export const getLogsColumns = ({
         return record.type === 0 ||
           record.type === 2 ||
           record.type === 5 ||
-          record.type === 6 ? (
+          record.type === 6 ||
+          record.type === 7 ? (
           <div>
             <Tag
               color='grey'
@@ -628,7 +650,8 @@ export const getLogsColumns = ({
           record.type === 0 ||
           record.type === 2 ||
           record.type === 5 ||
-          record.type === 6
+          record.type === 6 ||
+          record.type === 7
         ) {
           if (record.group) {
             return <>{renderGroup(record.group)}</>;
@@ -672,7 +695,8 @@ export const getLogsColumns = ({
         return record.type === 0 ||
           record.type === 2 ||
           record.type === 5 ||
-          record.type === 6 ? (
+          record.type === 6 ||
+          record.type === 7 ? (
           <>{renderModelName(record, copyText, t)}</>
         ) : (
           <></>
@@ -687,14 +711,15 @@ export const getLogsColumns = ({
         if (!(record.type === 2 || record.type === 5)) {
           return <></>;
         }
+        let other = getLogOther(record.other);
         if (record.is_stream) {
-          let other = getLogOther(record.other);
           return (
             <>
               <Space>
                 {renderUseTime(text, t)}
                 {renderFirstUseTime(other?.frt, t)}
                 {renderIsStream(record.is_stream, t)}
+                {renderResponseCacheHit(other, t)}
               </Space>
             </>
           );
@@ -704,6 +729,7 @@ export const getLogsColumns = ({
               <Space>
                 {renderUseTime(text, t)}
                 {renderIsStream(record.is_stream, t)}
+                {renderResponseCacheHit(other, t)}
               </Space>
             </>
           );
@@ -0,0 +1,178 @@
+/*
+Copyright (C) 2025 QuantumNous
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+For commercial licensing, please contact support@quantumnous.com
+*/
+
+import React, { useEffect, useState } from 'react';
+import {
+  Modal,
+  Descriptions,
+  Empty,
+  Spin,
+  Tabs,
+  TabPane,
+  Tag,
+  Typography,
+} from '@douyinfe/semi-ui';
+import { API, showError, timestamp2string } from '../../../../helpers';
+
+const { Text } = Typography;
+
+function formatPayload(body) {
+  if (!body) return '';
+  try {
+    return JSON.stringify(JSON.parse(body), null, 2);
+  } catch (e) {
+    return body;
+  }
+}
+
+const PayloadBlock = ({ body, truncated, t }) => (
+  <div>
+    {truncated && (
+      <Tag color='orange' style={{ marginBottom: 8 }}>
+        {t('内容过长，已截断')}
+      </Tag>
+    )}
+    <pre
+      style={{
+        maxHeight: 360,
+        overflow: 'auto',
+        whiteSpace: 'pre-wrap',
+        wordBreak: 'break-all',
+        background: 'var(--semi-color-fill-0)',
+        padding: 12,
+        borderRadius: 8,
+        margin: 0,
+      }}
+    >
+      {formatPayload(body) || '-'}
+    </pre>
+  </div>
+);
+
+const PayloadLogModal = ({
+  t,
+  showPayloadLogModal,
+  setShowPayloadLogModal,
+  payloadLogRequestId,
+}) => {
+  const [loading, setLoading] = useState(false);
+  const [payloads, setPayloads] = useState([]);
+
+  useEffect(() => {
+    if (!showPayloadLogModal || !payloadLogRequestId) {
+      return;
+    }
+    const load = async () => {
+      setLoading(true);
+      try {
+        const res = await API.get(
+          `/api/log/payload/${encodeURIComponent(payloadLogRequestId)}`,
+        );
+        const { success, message, data } = res.data;
+        if (success) {
+          setPayloads(data || []);
+        } else {
+          showError(message);
+        }
+      } catch (error) {
+        showError(error);
+      } finally {
+        setLoading(false);
+      }
+    };
+    setPayloads([]);
+    load();
+  }, [showPayloadLogModal, payloadLogRequestId]);
+
+  return (
+    <Modal
+      title={t('请求载荷')}
+      visible={showPayloadLogModal}
+      onCancel={() => setShowPayloadLogModal(false)}
+      footer={null}
+      width={860}
+      centered
+    >
+      <Spin spinning={loading}>
+        {payloads.length === 0 ? (
+          <Empty
+            description={loading ? '' : t('载荷记录不存在或已过期清理')}
+          />
+        ) : (
+          <Tabs type='line'>
+            {payloads.map((payload, index) => (
+              <TabPane
+                key={payload.id}
+                itemKey={String(payload.id)}
+                tab={t('第 {{n}} 次尝试', { n: index + 1 })}
+              >
+                <Descriptions
+                  size='small'
+                  data={[
+                    { key: 'Request ID', value: payload.request_id },
+                    {
+                      key: t('时间'),
+                      value: timestamp2string(payload.created_at),
+                    },
+                    { key: t('渠道'), value: payload.channel_id },
+                    { key: t('请求路径'), value: payload.request_path },
+                    { key: t('状态码'), value: payload.status_code },
+                  ]}
+                />
+                {payload.error_message && (
+                  <Text
+                    type='danger'
+                    style={{ display: 'block', margin: '8px 0' }}
+                  >
+                    {payload.error_message}
+                  </Text>
+                )}
+                <Tabs type='button' size='small' style={{ marginTop: 8 }}>
+                  <TabPane tab={t('请求体')} itemKey='request'>
+                    <PayloadBlock
+                      body={payload.request_body}
+                      truncated={payload.request_truncated}
+                      t={t}
+                    />
+                  </TabPane>
+                  <TabPane
+                    tab={
+                      payload.is_stream
+                        ? t('响应体（已合并流式响应）')
+                        : t('响应体')
+                    }
+                    itemKey='response'
+                  >
+                    <PayloadBlock
+                      body={payload.response_body}
+                      truncated={payload.response_truncated}
+                      t={t}
+                    />
+                  </TabPane>
+                </Tabs>
+              </TabPane>
+            ))}
+          </Tabs>
+        )}
+      </Spin>
+    </Modal>
+  );
+};
+
+export default PayloadLogModal;
@@ -19,7 +19,7 @@ For commercial licensing, please contact support@quantumnous.com
 
 import { useState, useEffect } from 'react';
 import { useTranslation } from 'react-i18next';
-import { Modal } from '@douyinfe/semi-ui';
+import { Button, Modal } from '@douyinfe/semi-ui';
 import {
   API,
   getTodayStartTimestamp,
@@ -67,6 +67,7 @@ export const useLogsData = () => {
   const [showStat, setShowStat] = useState(false);
   const [loading, setLoading] = useState(false);
   const [loadingStat, setLoadingStat] = useState(false);
+  const [exporting, setExporting] = useState(false);
   const [activePage, setActivePage] = useState(1);
   const [logCount, setLogCount] = useState(0);
   const [pageSize, setPageSize] = useState(ITEMS_PER_PAGE);
@@ -182,6 +183,10 @@ export const useLogsData = () => {
   const [channelAffinityUsageCacheTarget, setChannelAffinityUsageCacheTarget] =
     useState(null);
 
+  // Payload log modal state (admin only)
+  const [showPayloadLogModal, setShowPayloadLogModal] = useState(false);
+  const [payloadLogRequestId, setPayloadLogRequestId] = useState('');
+
   // Initialize default column visibility
   const initDefaultColumns = () => {
     const defaults = getDefaultColumnVisibility();
@@ -345,6 +350,11 @@ export const useLogsData = () => {
     setShowChannelAffinityUsageCacheModal(true);
   };
 
+  const openPayloadLogModal = (requestId) => {
+    setPayloadLogRequestId(requestId);
+    setShowPayloadLogModal(true);
+  };
+
   // Format logs data
   const setLogsFormat = (logs) => {
     const requestConversionDisplayValue = (conversionChain) => {
@@ -376,6 +386,25 @@ export const useLogsData = () => {
           value: logs[i].request_id,
         });
       }
+      if (
+        isAdminUser &&
+        logs[i].request_id &&
+        other?.admin_info?.payload_logged
+      ) {
+        const requestId = logs[i].request_id;
+        expandDataLocal.push({
+          key: t('请求载荷'),
+          value: (
+            <Button
+              size='small'
+              theme='borderless'
+              onClick={() => openPayloadLogModal(requestId)}
+            >
+              {t('查看载荷')}
+            </Button>
+          ),
+        });
+      }
       if (other?.ws || other?.audio) {
         expandDataLocal.push({
           key: t('语音输入'),
@@ -584,6 +613,13 @@ export const useLogsData = () => {
           value: other.request_path,
         });
       }
+      if (logs[i].type === 7 && other?.moderation) {
+        const moderation = other.moderation;
+        expandDataLocal.push({
+          key: t('审核命中'),
+          value: `${moderation.provider}：${(moderation.categories || []).join(', ')}`,
+        });
+      }
       if (other?.billing_source === 'subscription') {
         const planId = other?.subscription_plan_id;
         const planTitle = other?.subscription_plan_title || '';
@@ -705,6 +741,55 @@ export const useLogsData = () => {
     setLoading(false);
   };
 
+  // Export function
+  const exportLogs = async (format = 'csv') => {
+    if (exporting) {
+      return;
+    }
+    const {
+      username,
+      token_name,
+      model_name,
+      start_timestamp,
+      end_timestamp,
+      channel,
+      group,
+      logType: formLogType,
+    } = getFormValues();
+    const currentLogType = formLogType !== undefined ? formLogType : logType;
+    let localStartTimestamp = Date.parse(start_timestamp) / 1000;
+    let localEndTimestamp = Date.parse(end_timestamp) / 1000;
+    let url = '';
+    if (isAdminUser) {
+      url = `/api/log/export?format=${format}&type=${currentLogType}&username=${username}&token_name=${token_name}&model_name=${model_name}&start_timestamp=${localStartTimestamp}&end_timestamp=${localEndTimestamp}&channel=${channel}&group=${group}`;
+    } else {
+      url = `/api/log/self/export?format=${format}&type=${currentLogType}&token_name=${token_name}&model_name=${model_name}&start_timestamp=${localStartTimestamp}&end_timestamp=${localEndTimestamp}&group=${group}`;
+    }
+    url = encodeURI(url);
+    setExporting(true);
+    try {
+      const res = await API.get(url, { responseType: 'blob' });
+      const blob = res.data;
+      // 导出失败时后端返回 JSON 错误信息
+      if (blob.type && blob.type.includes('application/json')) {
+        const { message } = JSON.parse(await blob.text());
+        showError(message);
+        return;
+      }
+      const a = document.createElement('a');
+      a.href = URL.createObjectURL(blob);
+      a.download = `logs-${Date.now()}.${format}`;
+      document.body.appendChild(a);
+      a.click();
+      document.body.removeChild(a);
+      URL.revokeObjectURL(a.href);
+    } catch (error) {
+      showError(error);
+    } finally {
+      setExporting(false);
+    }
+  };
+
   // Page handlers
   const handlePageChange = (page) => {
     setActivePage(page);
@@ -785,6 +870,10 @@ export const useLogsData = () => {
     formInitValues,
     getFormValues,
 
+    // Export
+    exporting,
+    exportLogs,
+
     // Column visibility
     visibleColumns,
     showColumnSelector,
@@ -812,6 +901,11 @@ export const useLogsData = () => {
     channelAffinityUsageCacheTarget,
     openChannelAffinityUsageCacheModal,
 
+    // Payload log modal
+    showPayloadLogModal,
+    setShowPayloadLogModal,
+    payloadLogRequestId,
+
     // Functions
     loadLogs,
     handlePageChange,
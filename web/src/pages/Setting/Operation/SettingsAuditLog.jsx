@@ -0,0 +1,203 @@
+/*
+Copyright (C) 2025 QuantumNous
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+For commercial licensing, please contact support@quantumnous.com
+*/
+import React, { useEffect, useState, useRef } from 'react';
+import {
+  Button,
+  Col,
+  Form,
+  Row,
+  Space,
+  Spin,
+  Typography,
+} from '@douyinfe/semi-ui';
+import {
+  compareObjects,
+  API,
+  showError,
+  showSuccess,
+  showWarning,
+} from '../../../helpers';
+import { useTranslation } from 'react-i18next';
+import AuditLogModal from '../../../components/settings/AuditLogModal';
+
+export default function SettingsAuditLog(props) {
+  const { t } = useTranslation();
+  const [loading, setLoading] = useState(false);
+  const [showAuditLogModal, setShowAuditLogModal] = useState(false);
+  const [inputs, setInputs] = useState({
+    'audit_log_setting.enabled': true,
+    'audit_log_setting.retention_days': 365,
+    'audit_log_setting.webhook_enabled': false,
+    'audit_log_setting.syslog_address': '',
+    'audit_log_setting.syslog_tag': 'new-api',
+  });
+  const refForm = useRef();
+  const [inputsRow, setInputsRow] = useState(inputs);
+
+  function handleFieldChange(fieldName) {
+    return (value) => {
+      setInputs((inputs) => ({ ...inputs, [fieldName]: value }));
+    };
+  }
+
+  function onSubmit() {
+    const updateArray = compareObjects(inputs, inputsRow);
+    if (!updateArray.length) return showWarning(t('你似乎并没有修改什么'));
+    const requestQueue = updateArray.map((item) => {
+      return API.put('/api/option/', {
+        key: item.key,
+        value: String(inputs[item.key]),
+      });
+    });
+    setLoading(true);
+    Promise.all(requestQueue)
+      .then((res) => {
+        if (res.includes(undefined)) {
+          return showError(t('部分保存失败，请重试'));
+        }
+        for (let i = 0; i < res.length; i++) {
+          if (!res[i].data.success) {
+            return showError(res[i].data.message);
+          }
+        }
+        showSuccess(t('保存成功'));
+        props.refresh();
+      })
+      .catch(() => {
+        showError(t('保存失败，请重试'));
+      })
+      .finally(() => {
+        setLoading(false);
+      });
+  }
+
+  useEffect(() => {
+    const currentInputs = {};
+    for (let key in props.options) {
+      if (Object.keys(inputs).includes(key)) {
+        currentInputs[key] = props.options[key];
+      }
+    }
+    setInputs(currentInputs);
+    setInputsRow(structuredClone(currentInputs));
+    refForm.current.setValues(currentInputs);
+  }, [props.options]);
+
+  return (
+    <>
+      <Spin spinning={loading}>
+        <Form
+          values={inputs}
+          getFormApi={(formAPI) => (refForm.current = formAPI)}
+          style={{ marginBottom: 15 }}
+        >
+          <Form.Section text={t('审计日志')}>
+            <Typography.Text
+              type='tertiary'
+              style={{ marginBottom: 16, display: 'block' }}
+            >
+              {t(
+                '记录管理员对系统设置、渠道、用户、兑换码与订阅的修改，包括操作人、IP、变更前后差异（密钥等敏感字段已脱敏）',
+              )}
+            </Typography.Text>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Switch
+                  field={'audit_log_setting.enabled'}
+                  label={t('启用审计日志')}
+                  size='default'
+                  checkedText='｜'
+                  uncheckedText='〇'
+                  onChange={handleFieldChange('audit_log_setting.enabled')}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  field={'audit_log_setting.retention_days'}
+                  label={t('保留天数')}
+                  extraText={t('0 表示永久保留')}
+                  min={0}
+                  precision={0}
+                  onChange={handleFieldChange(
+                    'audit_log_setting.retention_days',
+                  )}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Switch
+                  field={'audit_log_setting.webhook_enabled'}
+                  label={t('推送到 Webhook')}
+                  extraText={t(
+                    '以 audit.logged 事件推送到管理员的事件订阅端点',
+                  )}
+                  size='default'
+                  checkedText='｜'
+                  uncheckedText='〇'
+                  onChange={handleFieldChange(
+                    'audit_log_setting.webhook_enabled',
+                  )}
+                />
+              </Col>
+            </Row>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Input
+                  field={'audit_log_setting.syslog_address'}
+                  label={t('Syslog 地址')}
+                  placeholder='udp://127.0.0.1:514'
+                  extraText={t('支持 udp:// 与 tcp://，留空表示不转发')}
+                  onChange={handleFieldChange(
+                    'audit_log_setting.syslog_address',
+                  )}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Input
+                  field={'audit_log_setting.syslog_tag'}
+                  label={t('Syslog 应用名')}
+                  placeholder='new-api'
+                  onChange={handleFieldChange('audit_log_setting.syslog_tag')}
+                />
+              </Col>
+            </Row>
+            <Row>
+              <Space>
+                <Button size='default' onClick={onSubmit}>
+                  {t('保存审计日志设置')}
+                </Button>
+                <Button
+                  size='default'
+                  type='tertiary'
+                  onClick={() => setShowAuditLogModal(true)}
+                >
+                  {t('查看审计日志')}
+                </Button>
+              </Space>
+            </Row>
+          </Form.Section>
+        </Form>
+      </Spin>
+      <AuditLogModal
+        visible={showAuditLogModal}
+        onCancel={() => setShowAuditLogModal(false)}
+        t={t}
+      />
+    </>
+  );
+}
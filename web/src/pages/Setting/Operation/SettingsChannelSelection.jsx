@@ -0,0 +1,264 @@
+/*
+Copyright (C) 2025 QuantumNous
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+For commercial licensing, please contact support@quantumnous.com
+*/
+
+import React, { useEffect, useState, useRef } from 'react';
+import { Button, Col, Form, Row, Spin, Typography } from '@douyinfe/semi-ui';
+import {
+  compareObjects,
+  API,
+  showError,
+  showSuccess,
+  showWarning,
+} from '../../../helpers';
+import { useTranslation } from 'react-i18next';
+
+const SCOPES_KEY = 'channel_selection_setting.adaptive_scopes';
+
+// 范围在后端以 JSON 数组保存，界面中每行一个
+function scopesToText(value) {
+  if (!value) return '';
+  try {
+    const scopes = JSON.parse(value);
+    return Array.isArray(scopes) ? scopes.join('\n') : '';
+  } catch (e) {
+    return '';
+  }
+}
+
+function textToScopes(text) {
+  return JSON.stringify(
+    (text || '')
+      .split('\n')
+      .map((item) => item.trim())
+      .filter((item) => item !== ''),
+  );
+}
+
+export default function SettingsChannelSelection(props) {
+  const { t } = useTranslation();
+  const [loading, setLoading] = useState(false);
+  const [inputs, setInputs] = useState({
+    'channel_selection_setting.adaptive_enabled': false,
+    [SCOPES_KEY]: '',
+    'channel_selection_setting.window_seconds': 300,
+    'channel_selection_setting.min_samples': 10,
+    'channel_selection_setting.error_rate_threshold': 0.5,
+    'channel_selection_setting.open_seconds': 60,
+    'channel_selection_setting.half_open_successes': 3,
+    'channel_selection_setting.half_open_weight_ratio': 0.1,
+    'channel_selection_setting.min_weight_ratio': 0.05,
+  });
+  const refForm = useRef();
+  const [inputsRow, setInputsRow] = useState(inputs);
+  const enabled = inputs['channel_selection_setting.adaptive_enabled'];
+
+  function handleFieldChange(fieldName) {
+    return (value) => {
+      setInputs((inputs) => ({ ...inputs, [fieldName]: value }));
+    };
+  }
+
+  function onSubmit() {
+    const updateArray = compareObjects(inputs, inputsRow);
+    if (!updateArray.length) return showWarning(t('你似乎并没有修改什么'));
+    const requestQueue = updateArray.map((item) => {
+      let value = String(inputs[item.key]);
+      if (item.key === SCOPES_KEY) {
+        value = textToScopes(inputs[item.key]);
+      }
+      return API.put('/api/option/', {
+        key: item.key,
+        value,
+      });
+    });
+    setLoading(true);
+    Promise.all(requestQueue)
+      .then((res) => {
+        if (requestQueue.length === 1) {
+          if (res.includes(undefined)) return;
+        } else if (requestQueue.length > 1) {
+          if (res.includes(undefined))
+            return showError(t('部分保存失败，请重试'));
+        }
+        showSuccess(t('保存成功'));
+        props.refresh();
+      })
+      .catch(() => {
+        showError(t('保存失败，请重试'));
+      })
+      .finally(() => {
+        setLoading(false);
+      });
+  }
+
+  useEffect(() => {
+    const currentInputs = {};
+    for (let key in props.options) {
+      if (Object.keys(inputs).includes(key)) {
+        currentInputs[key] =
+          key === SCOPES_KEY
+            ? scopesToText(props.options[key])
+            : props.options[key];
+      }
+    }
+    setInputs(currentInputs);
+    setInputsRow(structuredClone(currentInputs));
+    refForm.current.setValues(currentInputs);
+  }, [props.options]);
+
+  return (
+    <>
+      <Spin spinning={loading}>
+        <Form
+          values={inputs}
+          getFormApi={(formAPI) => (refForm.current = formAPI)}
+          style={{ marginBottom: 15 }}
+        >
+          <Form.Section text={t('渠道自适应选择')}>
+            <Typography.Text
+              type='tertiary'
+              style={{ marginBottom: 16, display: 'block' }}
+            >
+              {t(
+                '根据真实请求的成功率、首字耗时与吞吐动态调整同优先级渠道的权重，错误率过高时熔断渠道，并通过半开探测逐步恢复',
+              )}
+            </Typography.Text>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Switch
+                  field={'channel_selection_setting.adaptive_enabled'}
+                  label={t('启用自适应选择')}
+                  size='default'
+                  checkedText='｜'
+                  uncheckedText='〇'
+                  onChange={handleFieldChange(
+                    'channel_selection_setting.adaptive_enabled',
+                  )}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={16} lg={16} xl={16}>
+                <Form.TextArea
+                  field={SCOPES_KEY}
+                  label={t('生效范围')}
+                  placeholder={'default:gpt-4o*\n*:claude-*'}
+                  extraText={t(
+                    '每行一个 分组:模型，模型支持末尾 * 前缀匹配，留空表示所有分组与模型',
+                  )}
+                  autosize
+                  onChange={handleFieldChange(SCOPES_KEY)}
+                  disabled={!enabled}
+                />
+              </Col>
+            </Row>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  field={'channel_selection_setting.window_seconds'}
+                  label={t('统计窗口（秒）')}
+                  min={10}
+                  onChange={handleFieldChange(
+                    'channel_selection_setting.window_seconds',
+                  )}
+                  disabled={!enabled}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  field={'channel_selection_setting.min_samples'}
+                  label={t('最少样本数')}
+                  min={1}
+                  onChange={handleFieldChange(
+                    'channel_selection_setting.min_samples',
+                  )}
+                  disabled={!enabled}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  field={'channel_selection_setting.error_rate_threshold'}
+                  label={t('熔断错误率')}
+                  min={0}
+                  max={1}
+                  step={0.05}
+                  onChange={handleFieldChange(
+                    'channel_selection_setting.error_rate_threshold',
+                  )}
+                  disabled={!enabled}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  field={'channel_selection_setting.open_seconds'}
+                  label={t('熔断时长（秒）')}
+                  min={1}
+                  onChange={handleFieldChange(
+                    'channel_selection_setting.open_seconds',
+                  )}
+                  disabled={!enabled}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  field={'channel_selection_setting.half_open_successes'}
+                  label={t('半开恢复所需成功次数')}
+                  min={1}
+                  onChange={handleFieldChange(
+                    'channel_selection_setting.half_open_successes',
+                  )}
+                  disabled={!enabled}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  field={'channel_selection_setting.half_open_weight_ratio'}
+                  label={t('半开初始权重比例')}
+                  min={0}
+                  max={1}
+                  step={0.05}
+                  onChange={handleFieldChange(
+                    'channel_selection_setting.half_open_weight_ratio',
+                  )}
+                  disabled={!enabled}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  field={'channel_selection_setting.min_weight_ratio'}
+                  label={t('最低权重比例')}
+                  min={0}
+                  max={1}
+                  step={0.01}
+                  onChange={handleFieldChange(
+                    'channel_selection_setting.min_weight_ratio',
+                  )}
+                  disabled={!enabled}
+                />
+              </Col>
+            </Row>
+            <Row>
+              <Button size='default' onClick={onSubmit}>
+                {t('保存渠道选择设置')}
+              </Button>
+            </Row>
+          </Form.Section>
+        </Form>
+      </Spin>
+    </>
+  );
+}
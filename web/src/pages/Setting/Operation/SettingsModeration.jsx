@@ -0,0 +1,311 @@
+/*
+Copyright (C) 2025 QuantumNous
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+For commercial licensing, please contact support@quantumnous.com
+*/
+import React, { useEffect, useState, useRef } from 'react';
+import { Button, Col, Form, Row, Spin, Typography } from '@douyinfe/semi-ui';
+import {
+  compareObjects,
+  API,
+  showError,
+  showSuccess,
+  showWarning,
+  verifyJSON,
+} from '../../../helpers';
+import { useTranslation } from 'react-i18next';
+
+const PROVIDERS_KEY = 'moderation_setting.providers';
+
+const JSON_DEFAULTS = {
+  'moderation_setting.regex_rules': '[]',
+  'moderation_setting.group_actions': '{}',
+};
+
+function parseProviders(value) {
+  try {
+    const parsed = JSON.parse(value || '[]');
+    return Array.isArray(parsed) ? parsed : [];
+  } catch (e) {
+    return [];
+  }
+}
+
+export default function SettingsModeration(props) {
+  const { t } = useTranslation();
+  const [loading, setLoading] = useState(false);
+  const [inputs, setInputs] = useState({
+    'moderation_setting.enabled': false,
+    'moderation_setting.providers': [],
+    'moderation_setting.regex_rules': '[]',
+    'moderation_setting.api_model': '',
+    'moderation_setting.api_group': '',
+    'moderation_setting.api_timeout_seconds': 10,
+    'moderation_setting.api_fail_open': true,
+    'moderation_setting.check_completion': false,
+    'moderation_setting.stream_check_chars': 200,
+    'moderation_setting.default_action': 'block',
+    'moderation_setting.group_actions': '{}',
+  });
+  const refForm = useRef();
+  const [inputsRow, setInputsRow] = useState(inputs);
+
+  function handleFieldChange(fieldName) {
+    return (value) => {
+      setInputs((inputs) => ({ ...inputs, [fieldName]: value }));
+    };
+  }
+
+  function onSubmit() {
+    // 提供方为数组，按内容比较
+    const updateArray = compareObjects(inputs, inputsRow).filter(
+      (item) =>
+        item.key !== PROVIDERS_KEY ||
+        JSON.stringify(item.oldValue) !== JSON.stringify(item.newValue),
+    );
+    if (!updateArray.length) return showWarning(t('你似乎并没有修改什么'));
+    const requestQueue = updateArray.map((item) => {
+      let value;
+      if (item.key === PROVIDERS_KEY) {
+        value = JSON.stringify(inputs[item.key] || []);
+      } else {
+        value = String(inputs[item.key]);
+        if (item.key in JSON_DEFAULTS && value.trim() === '') {
+          value = JSON_DEFAULTS[item.key];
+        }
+      }
+      return API.put('/api/option/', {
+        key: item.key,
+        value,
+      });
+    });
+    setLoading(true);
+    Promise.all(requestQueue)
+      .then((res) => {
+        if (res.includes(undefined)) {
+          return showError(t('部分保存失败，请重试'));
+        }
+        for (let i = 0; i < res.length; i++) {
+          if (!res[i].data.success) {
+            return showError(res[i].data.message);
+          }
+        }
+        showSuccess(t('保存成功'));
+        props.refresh();
+      })
+      .catch(() => {
+        showError(t('保存失败，请重试'));
+      })
+      .finally(() => {
+        setLoading(false);
+      });
+  }
+
+  useEffect(() => {
+    const currentInputs = {};
+    for (let key in props.options) {
+      if (Object.keys(inputs).includes(key)) {
+        currentInputs[key] =
+          key === PROVIDERS_KEY
+            ? parseProviders(props.options[key])
+            : props.options[key];
+      }
+    }
+    setInputs(currentInputs);
+    setInputsRow(structuredClone(currentInputs));
+    refForm.current.setValues(currentInputs);
+  }, [props.options]);
+
+  const jsonRules = [
+    {
+      validator: (rule, value) => !value || verifyJSON(value),
+      message: t('不是合法的 JSON 字符串'),
+    },
+  ];
+
+  const actionOptions = [
+    { value: 'block', label: t('拦截') },
+    { value: 'redact', label: t('脱敏') },
+    { value: 'log', label: t('仅记录') },
+  ];
+
+  return (
+    <>
+      <Spin spinning={loading}>
+        <Form
+          values={inputs}
+          getFormApi={(formAPI) => (refForm.current = formAPI)}
+          style={{ marginBottom: 15 }}
+        >
+          <Form.Section text={t('内容审核')}>
+            <Typography.Text
+              type='tertiary'
+              style={{ marginBottom: 16, display: 'block' }}
+            >
+              {t(
+                '启用后替代屏蔽词过滤，依次使用所选提供方审核请求与输出内容，命中记录在使用日志的审核类型中',
+              )}
+            </Typography.Text>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Switch
+                  field={'moderation_setting.enabled'}
+                  label={t('启用内容审核')}
+                  size='default'
+                  checkedText='｜'
+                  uncheckedText='〇'
+                  onChange={handleFieldChange('moderation_setting.enabled')}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Select
+                  field={PROVIDERS_KEY}
+                  label={t('审核提供方')}
+                  multiple
+                  style={{ width: '100%' }}
+                  optionList={[
+                    { value: 'sensitive_words', label: t('屏蔽词列表') },
+                    { value: 'regex', label: t('正则规则') },
+                    { value: 'moderation_api', label: t('审核接口') },
+                  ]}
+                  onChange={handleFieldChange(PROVIDERS_KEY)}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Select
+                  field={'moderation_setting.default_action'}
+                  label={t('默认处理方式')}
+                  style={{ width: '100%' }}
+                  optionList={actionOptions}
+                  extraText={t('审核接口无法定位命中内容，脱敏时改为拦截')}
+                  onChange={handleFieldChange(
+                    'moderation_setting.default_action',
+                  )}
+                />
+              </Col>
+            </Row>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Switch
+                  field={'moderation_setting.check_completion'}
+                  label={t('审核模型输出')}
+                  size='default'
+                  checkedText='｜'
+                  uncheckedText='〇'
+                  onChange={handleFieldChange(
+                    'moderation_setting.check_completion',
+                  )}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  field={'moderation_setting.stream_check_chars'}
+                  label={t('流式审核间隔（字符）')}
+                  extraText={t(
+                    '流式输出每累计该字符数审核一次，审核通过后再下发',
+                  )}
+                  min={1}
+                  precision={0}
+                  onChange={handleFieldChange(
+                    'moderation_setting.stream_check_chars',
+                  )}
+                />
+              </Col>
+            </Row>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={6} lg={6} xl={6}>
+                <Form.Input
+                  field={'moderation_setting.api_model'}
+                  label={t('审核接口模型')}
+                  placeholder='omni-moderation-latest'
+                  onChange={handleFieldChange('moderation_setting.api_model')}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={6} lg={6} xl={6}>
+                <Form.Input
+                  field={'moderation_setting.api_group'}
+                  label={t('审核接口分组')}
+                  placeholder={t('留空则使用请求所在分组')}
+                  onChange={handleFieldChange('moderation_setting.api_group')}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={6} lg={6} xl={6}>
+                <Form.InputNumber
+                  field={'moderation_setting.api_timeout_seconds'}
+                  label={t('审核接口超时（秒）')}
+                  min={1}
+                  precision={0}
+                  onChange={handleFieldChange(
+                    'moderation_setting.api_timeout_seconds',
+                  )}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={6} lg={6} xl={6}>
+                <Form.Switch
+                  field={'moderation_setting.api_fail_open'}
+                  label={t('审核接口失败时放行')}
+                  size='default'
+                  checkedText='｜'
+                  uncheckedText='〇'
+                  onChange={handleFieldChange(
+                    'moderation_setting.api_fail_open',
+                  )}
+                />
+              </Col>
+            </Row>
+            <Row gutter={16}>
+              <Col xs={24} sm={12}>
+                <Form.TextArea
+                  field={'moderation_setting.regex_rules'}
+                  label={t('正则规则')}
+                  placeholder={t(
+                    'JSON 数组，例如 [{"name": "phone", "pattern": "1\\\\d{10}"}]',
+                  )}
+                  autosize={{ minRows: 2, maxRows: 8 }}
+                  trigger='blur'
+                  stopValidateWithError
+                  rules={jsonRules}
+                  onChange={handleFieldChange('moderation_setting.regex_rules')}
+                />
+              </Col>
+              <Col xs={24} sm={12}>
+                <Form.TextArea
+                  field={'moderation_setting.group_actions'}
+                  label={t('分组处理方式')}
+                  placeholder={t(
+                    'JSON 对象，例如 {"vip": "log", "default": "redact"}，可选 block / redact / log',
+                  )}
+                  autosize={{ minRows: 2, maxRows: 8 }}
+                  trigger='blur'
+                  stopValidateWithError
+                  rules={jsonRules}
+                  onChange={handleFieldChange(
+                    'moderation_setting.group_actions',
+                  )}
+                />
+              </Col>
+            </Row>
+            <Row>
+              <Button size='default' onClick={onSubmit}>
+                {t('保存内容审核设置')}
+              </Button>
+            </Row>
+          </Form.Section>
+        </Form>
+      </Spin>
+    </>
+  );
+}
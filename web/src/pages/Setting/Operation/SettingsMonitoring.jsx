@@ -44,6 +44,7 @@ export default function SettingsMonitoring(props) {
       '100-199,300-399,401-407,409-499,500-503,505-523,525-599',
     'monitor_setting.auto_test_channel_enabled': false,
     'monitor_setting.auto_test_channel_minutes': 10,
+    'monitor_setting.multi_key_expiry_alert_days': 7,
   });
   const refForm = useRef();
   const [inputsRow, setInputsRow] = useState(inputs);
@@ -164,6 +165,26 @@ export default function SettingsMonitoring(props) {
                   }
                 />
               </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  label={t('多密钥过期提醒提前天数')}
+                  step={1}
+                  min={0}
+                  suffix={t('天')}
+                  extraText={t(
+                    '多密钥渠道中的密钥在过期前多少天通知管理员，0 表示不提醒',
+                  )}
+                  placeholder={''}
+                  field={'monitor_setting.multi_key_expiry_alert_days'}
+                  onChange={(value) =>
+                    setInputs({
+                      ...inputs,
+                      'monitor_setting.multi_key_expiry_alert_days':
+                        parseInt(value),
+                    })
+                  }
+                />
+              </Col>
             </Row>
             <Row gutter={16}>
               <Col xs={24} sm={12} md={8} lg={8} xl={8}>
@@ -0,0 +1,234 @@
+/*
+Copyright (C) 2025 QuantumNous
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+For commercial licensing, please contact support@quantumnous.com
+*/
+import React, { useEffect, useState, useRef } from 'react';
+import { Button, Col, Form, Row, Spin, Typography } from '@douyinfe/semi-ui';
+import {
+  compareObjects,
+  API,
+  showError,
+  showSuccess,
+  showWarning,
+  verifyJSON,
+} from '../../../helpers';
+import { useTranslation } from 'react-i18next';
+
+const JSON_ARRAY_KEYS = [
+  'payload_log_setting.groups',
+  'payload_log_setting.token_ids',
+  'payload_log_setting.redact_patterns',
+  'payload_log_setting.redact_json_paths',
+];
+
+export default function SettingsPayloadLog(props) {
+  const { t } = useTranslation();
+  const [loading, setLoading] = useState(false);
+  const [inputs, setInputs] = useState({
+    'payload_log_setting.enabled': false,
+    'payload_log_setting.groups': '[]',
+    'payload_log_setting.token_ids': '[]',
+    'payload_log_setting.max_body_kb': 256,
+    'payload_log_setting.retention_hours': 72,
+    'payload_log_setting.redact_patterns': '[]',
+    'payload_log_setting.redact_json_paths': '[]',
+  });
+  const refForm = useRef();
+  const [inputsRow, setInputsRow] = useState(inputs);
+
+  function handleFieldChange(fieldName) {
+    return (value) => {
+      setInputs((inputs) => ({ ...inputs, [fieldName]: value }));
+    };
+  }
+
+  function onSubmit() {
+    const updateArray = compareObjects(inputs, inputsRow);
+    if (!updateArray.length) return showWarning(t('你似乎并没有修改什么'));
+    const requestQueue = updateArray.map((item) => {
+      let value = String(inputs[item.key]);
+      if (JSON_ARRAY_KEYS.includes(item.key) && value.trim() === '') {
+        value = '[]';
+      }
+      return API.put('/api/option/', {
+        key: item.key,
+        value,
+      });
+    });
+    setLoading(true);
+    Promise.all(requestQueue)
+      .then((res) => {
+        if (res.includes(undefined)) {
+          return showError(t('部分保存失败，请重试'));
+        }
+        for (let i = 0; i < res.length; i++) {
+          if (!res[i].data.success) {
+            return showError(res[i].data.message);
+          }
+        }
+        showSuccess(t('保存成功'));
+        props.refresh();
+      })
+      .catch(() => {
+        showError(t('保存失败，请重试'));
+      })
+      .finally(() => {
+        setLoading(false);
+      });
+  }
+
+  useEffect(() => {
+    const currentInputs = {};
+    for (let key in props.options) {
+      if (Object.keys(inputs).includes(key)) {
+        currentInputs[key] = props.options[key];
+      }
+    }
+    setInputs(currentInputs);
+    setInputsRow(structuredClone(currentInputs));
+    refForm.current.setValues(currentInputs);
+  }, [props.options]);
+
+  const jsonRules = [
+    {
+      validator: (rule, value) => !value || verifyJSON(value),
+      message: t('不是合法的 JSON 字符串'),
+    },
+  ];
+
+  return (
+    <>
+      <Spin spinning={loading}>
+        <Form
+          values={inputs}
+          getFormApi={(formAPI) => (refForm.current = formAPI)}
+          style={{ marginBottom: 15 }}
+        >
+          <Form.Section text={t('载荷记录')}>
+            <Typography.Text
+              type='tertiary'
+              style={{ marginBottom: 16, display: 'block' }}
+            >
+              {t(
+                '为指定分组或令牌记录参数覆盖后的请求体与上游响应（流式响应合并后保存），可在使用日志详情中按 Request ID 查看',
+              )}
+            </Typography.Text>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Switch
+                  field={'payload_log_setting.enabled'}
+                  label={t('启用载荷记录')}
+                  size='default'
+                  checkedText='｜'
+                  uncheckedText='〇'
+                  onChange={handleFieldChange('payload_log_setting.enabled')}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  field={'payload_log_setting.retention_hours'}
+                  label={t('保留时长（小时）')}
+                  extraText={t('超过保留时长的载荷记录会被自动清理')}
+                  min={1}
+                  precision={0}
+                  onChange={handleFieldChange(
+                    'payload_log_setting.retention_hours',
+                  )}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  field={'payload_log_setting.max_body_kb'}
+                  label={t('最大记录长度 (KB)')}
+                  extraText={t('请求体与响应体分别计算，超出部分截断')}
+                  min={1}
+                  precision={0}
+                  onChange={handleFieldChange(
+                    'payload_log_setting.max_body_kb',
+                  )}
+                />
+              </Col>
+            </Row>
+            <Row gutter={16}>
+              <Col xs={24} sm={12}>
+                <Form.TextArea
+                  field={'payload_log_setting.groups'}
+                  label={t('记录的分组')}
+                  placeholder={t('JSON 数组，例如 ["default"]')}
+                  autosize={{ minRows: 2, maxRows: 6 }}
+                  trigger='blur'
+                  stopValidateWithError
+                  rules={jsonRules}
+                  onChange={handleFieldChange('payload_log_setting.groups')}
+                />
+              </Col>
+              <Col xs={24} sm={12}>
+                <Form.TextArea
+                  field={'payload_log_setting.token_ids'}
+                  label={t('记录的令牌 ID')}
+                  placeholder={t('JSON 数组，例如 [1, 2]')}
+                  autosize={{ minRows: 2, maxRows: 6 }}
+                  trigger='blur'
+                  stopValidateWithError
+                  rules={jsonRules}
+                  onChange={handleFieldChange('payload_log_setting.token_ids')}
+                />
+              </Col>
+            </Row>
+            <Row gutter={16}>
+              <Col xs={24} sm={12}>
+                <Form.TextArea
+                  field={'payload_log_setting.redact_patterns'}
+                  label={t('脱敏正则')}
+                  placeholder={t('JSON 数组，匹配到的内容替换为 [REDACTED]')}
+                  autosize={{ minRows: 2, maxRows: 8 }}
+                  trigger='blur'
+                  stopValidateWithError
+                  rules={jsonRules}
+                  onChange={handleFieldChange(
+                    'payload_log_setting.redact_patterns',
+                  )}
+                />
+              </Col>
+              <Col xs={24} sm={12}>
+                <Form.TextArea
+                  field={'payload_log_setting.redact_json_paths'}
+                  label={t('脱敏 JSON 路径')}
+                  placeholder={t(
+                    'JSON 数组，例如 ["messages.*.content", "user"]，* 匹配任意键或数组元素',
+                  )}
+                  autosize={{ minRows: 2, maxRows: 8 }}
+                  trigger='blur'
+                  stopValidateWithError
+                  rules={jsonRules}
+                  onChange={handleFieldChange(
+                    'payload_log_setting.redact_json_paths',
+                  )}
+                />
+              </Col>
+            </Row>
+            <Row>
+              <Button size='default' onClick={onSubmit}>
+                {t('保存载荷记录设置')}
+              </Button>
+            </Row>
+          </Form.Section>
+        </Form>
+      </Spin>
+    </>
+  );
+}
@@ -0,0 +1,220 @@
+/*
+Copyright (C) 2025 QuantumNous
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+For commercial licensing, please contact support@quantumnous.com
+*/
+import React, { useEffect, useState, useRef } from 'react';
+import { Button, Col, Form, Row, Spin, Typography } from '@douyinfe/semi-ui';
+import {
+  compareObjects,
+  API,
+  showError,
+  showSuccess,
+  showWarning,
+} from '../../../helpers';
+import { useTranslation } from 'react-i18next';
+
+export default function SettingsWebhookEvent(props) {
+  const { t } = useTranslation();
+  const [loading, setLoading] = useState(false);
+  const [inputs, setInputs] = useState({
+    'webhook_event_setting.enabled': true,
+    'webhook_event_setting.user_enabled': true,
+    'webhook_event_setting.max_endpoints_per_user': 10,
+    'webhook_event_setting.max_attempts': 8,
+    'webhook_event_setting.retry_base_seconds': 30,
+    'webhook_event_setting.retry_max_seconds': 3600,
+    'webhook_event_setting.timeout_seconds': 10,
+    'webhook_event_setting.retention_days': 7,
+  });
+  const refForm = useRef();
+  const [inputsRow, setInputsRow] = useState(inputs);
+
+  function handleFieldChange(fieldName) {
+    return (value) => {
+      setInputs((inputs) => ({ ...inputs, [fieldName]: value }));
+    };
+  }
+
+  function onSubmit() {
+    const updateArray = compareObjects(inputs, inputsRow);
+    if (!updateArray.length) return showWarning(t('你似乎并没有修改什么'));
+    const requestQueue = updateArray.map((item) => {
+      return API.put('/api/option/', {
+        key: item.key,
+        value: String(inputs[item.key]),
+      });
+    });
+    setLoading(true);
+    Promise.all(requestQueue)
+      .then((res) => {
+        if (requestQueue.length === 1) {
+          if (res.includes(undefined)) return;
+        } else if (requestQueue.length > 1) {
+          if (res.includes(undefined))
+            return showError(t('部分保存失败，请重试'));
+        }
+        showSuccess(t('保存成功'));
+        props.refresh();
+      })
+      .catch(() => {
+        showError(t('保存失败，请重试'));
+      })
+      .finally(() => {
+        setLoading(false);
+      });
+  }
+
+  useEffect(() => {
+    const currentInputs = {};
+    for (let key in props.options) {
+      if (Object.keys(inputs).includes(key)) {
+        currentInputs[key] = props.options[key];
+      }
+    }
+    setInputs(currentInputs);
+    setInputsRow(structuredClone(currentInputs));
+    refForm.current.setValues(currentInputs);
+  }, [props.options]);
+
+  return (
+    <>
+      <Spin spinning={loading}>
+        <Form
+          values={inputs}
+          getFormApi={(formAPI) => (refForm.current = formAPI)}
+          style={{ marginBottom: 15 }}
+        >
+          <Form.Section text={t('事件订阅设置')}>
+            <Typography.Text
+              type='tertiary'
+              style={{ marginBottom: 16, display: 'block' }}
+            >
+              {t(
+                '用户可在个人设置中注册 Webhook 端点订阅平台事件，事件写入投递队列后由主节点发送，失败按指数退避重试',
+              )}
+            </Typography.Text>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Switch
+                  field={'webhook_event_setting.enabled'}
+                  label={t('启用事件订阅')}
+                  size='default'
+                  checkedText='｜'
+                  uncheckedText='〇'
+                  onChange={handleFieldChange('webhook_event_setting.enabled')}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Switch
+                  field={'webhook_event_setting.user_enabled'}
+                  label={t('允许普通用户订阅')}
+                  extraText={t('关闭后仅管理员可注册端点')}
+                  size='default'
+                  checkedText='｜'
+                  uncheckedText='〇'
+                  onChange={handleFieldChange(
+                    'webhook_event_setting.user_enabled',
+                  )}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  field={'webhook_event_setting.max_endpoints_per_user'}
+                  label={t('每用户最多端点数')}
+                  min={0}
+                  precision={0}
+                  extraText={t('0 表示不限制')}
+                  onChange={handleFieldChange(
+                    'webhook_event_setting.max_endpoints_per_user',
+                  )}
+                />
+              </Col>
+            </Row>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={6} lg={6} xl={6}>
+                <Form.InputNumber
+                  field={'webhook_event_setting.max_attempts'}
+                  label={t('最大尝试次数')}
+                  min={1}
+                  precision={0}
+                  onChange={handleFieldChange(
+                    'webhook_event_setting.max_attempts',
+                  )}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={6} lg={6} xl={6}>
+                <Form.InputNumber
+                  field={'webhook_event_setting.retry_base_seconds'}
+                  label={t('首次重试间隔（秒）')}
+                  extraText={t('之后每次重试间隔翻倍')}
+                  min={1}
+                  precision={0}
+                  onChange={handleFieldChange(
+                    'webhook_event_setting.retry_base_seconds',
+                  )}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={6} lg={6} xl={6}>
+                <Form.InputNumber
+                  field={'webhook_event_setting.retry_max_seconds'}
+                  label={t('最大重试间隔（秒）')}
+                  min={1}
+                  precision={0}
+                  onChange={handleFieldChange(
+                    'webhook_event_setting.retry_max_seconds',
+                  )}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={6} lg={6} xl={6}>
+                <Form.InputNumber
+                  field={'webhook_event_setting.timeout_seconds'}
+                  label={t('请求超时（秒）')}
+                  min={1}
+                  precision={0}
+                  onChange={handleFieldChange(
+                    'webhook_event_setting.timeout_seconds',
+                  )}
+                />
+              </Col>
+            </Row>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  field={'webhook_event_setting.retention_days'}
+                  label={t('投递记录保留天数')}
+                  extraText={t(
+                    '0 表示不清理，等待投递的记录不会被清理',
+                  )}
+                  min={0}
+                  precision={0}
+                  onChange={handleFieldChange(
+                    'webhook_event_setting.retention_days',
+                  )}
+                />
+              </Col>
+            </Row>
+            <Row>
+              <Button size='default' onClick={onSubmit}>
+                {t('保存事件订阅设置')}
+              </Button>
+            </Row>
+          </Form.Section>
+        </Form>
+      </Spin>
+    </>
+  );
+}
@@ -0,0 +1,241 @@
+/*
+Copyright (C) 2025 QuantumNous
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+For commercial licensing, please contact support@quantumnous.com
+*/
+import React, { useEffect, useState, useRef } from 'react';
+import { Button, Col, Form, Popconfirm, Row, Spin } from '@douyinfe/semi-ui';
+import {
+  compareObjects,
+  API,
+  showError,
+  showSuccess,
+  showWarning,
+  verifyJSON,
+} from '../../../helpers';
+import { useTranslation } from 'react-i18next';
+
+export default function SettingsResponseCache(props) {
+  const { t } = useTranslation();
+  const [loading, setLoading] = useState(false);
+  const [inputs, setInputs] = useState({
+    'response_cache_setting.enabled': false,
+    'response_cache_setting.ttl_seconds': 3600,
+    'response_cache_setting.hit_ratio': 0.1,
+    'response_cache_setting.max_entries': 10000,
+    'response_cache_setting.max_body_kb': 4096,
+    'response_cache_setting.disk_spill_kb': 256,
+    'response_cache_setting.models': '[]',
+  });
+  const refForm = useRef();
+  const [inputsRow, setInputsRow] = useState(inputs);
+
+  function handleFieldChange(fieldName) {
+    return (value) => {
+      setInputs((inputs) => ({ ...inputs, [fieldName]: value }));
+    };
+  }
+
+  function onSubmit() {
+    const updateArray = compareObjects(inputs, inputsRow);
+    if (!updateArray.length) return showWarning(t('你似乎并没有修改什么'));
+    const requestQueue = updateArray.map((item) => {
+      let value = String(inputs[item.key]);
+      if (item.key === 'response_cache_setting.models' && value === '') {
+        value = '[]';
+      }
+      return API.put('/api/option/', {
+        key: item.key,
+        value,
+      });
+    });
+    setLoading(true);
+    Promise.all(requestQueue)
+      .then((res) => {
+        if (res.includes(undefined)) {
+          return showError(t('部分保存失败，请重试'));
+        }
+        for (let i = 0; i < res.length; i++) {
+          if (!res[i].data.success) {
+            return showError(res[i].data.message);
+          }
+        }
+        showSuccess(t('保存成功'));
+        props.refresh();
+      })
+      .catch(() => {
+        showError(t('保存失败，请重试'));
+      })
+      .finally(() => {
+        setLoading(false);
+      });
+  }
+
+  async function clearResponseCache() {
+    try {
+      const res = await API.delete('/api/performance/response_cache');
+      if (res.data.success) {
+        showSuccess(t('响应缓存已清空'));
+      } else {
+        showError(res.data.message || t('清理失败'));
+      }
+    } catch (error) {
+      showError(t('清理失败'));
+    }
+  }
+
+  useEffect(() => {
+    const currentInputs = {};
+    for (let key in props.options) {
+      if (Object.keys(inputs).includes(key)) {
+        currentInputs[key] = props.options[key];
+      }
+    }
+    setInputs(currentInputs);
+    setInputsRow(structuredClone(currentInputs));
+    refForm.current.setValues(currentInputs);
+  }, [props.options]);
+
+  return (
+    <>
+      <Spin spinning={loading}>
+        <Form
+          values={inputs}
+          getFormApi={(formAPI) => (refForm.current = formAPI)}
+          style={{ marginBottom: 15 }}
+        >
+          <Form.Section text={t('响应缓存')}>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Switch
+                  field={'response_cache_setting.enabled'}
+                  label={t('启用响应缓存')}
+                  extraText={t(
+                    '完全相同的对话补全请求直接返回缓存的响应，流式请求按 SSE 重放',
+                  )}
+                  size='default'
+                  checkedText='｜'
+                  uncheckedText='〇'
+                  onChange={handleFieldChange('response_cache_setting.enabled')}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  field={'response_cache_setting.ttl_seconds'}
+                  label={t('缓存有效期（秒）')}
+                  min={1}
+                  precision={0}
+                  onChange={handleFieldChange(
+                    'response_cache_setting.ttl_seconds',
+                  )}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  field={'response_cache_setting.hit_ratio'}
+                  label={t('缓存命中计费倍率')}
+                  extraText={t('在分组倍率基础上相乘，0 表示命中缓存时不计费')}
+                  min={0}
+                  step={0.1}
+                  onChange={handleFieldChange(
+                    'response_cache_setting.hit_ratio',
+                  )}
+                />
+              </Col>
+            </Row>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  field={'response_cache_setting.max_entries'}
+                  label={t('内存缓存最大条目数')}
+                  extraText={t('仅在未启用 Redis 时生效，修改后需重启生效')}
+                  min={1}
+                  precision={0}
+                  onChange={handleFieldChange(
+                    'response_cache_setting.max_entries',
+                  )}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  field={'response_cache_setting.max_body_kb'}
+                  label={t('最大缓存响应体 (KB)')}
+                  extraText={t('超过该大小的响应不缓存')}
+                  min={1}
+                  precision={0}
+                  onChange={handleFieldChange(
+                    'response_cache_setting.max_body_kb',
+                  )}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  field={'response_cache_setting.disk_spill_kb'}
+                  label={t('磁盘存储阈值 (KB)')}
+                  extraText={t(
+                    '超过该大小的响应体写入磁盘缓存目录，0 表示不写入磁盘',
+                  )}
+                  min={0}
+                  precision={0}
+                  onChange={handleFieldChange(
+                    'response_cache_setting.disk_spill_kb',
+                  )}
+                />
+              </Col>
+            </Row>
+            <Row>
+              <Col xs={24} sm={16}>
+                <Form.TextArea
+                  field={'response_cache_setting.models'}
+                  label={t('缓存模型列表')}
+                  placeholder={t(
+                    'JSON 数组，例如 ["gpt-4o-mini"]，为空时所有模型均可缓存',
+                  )}
+                  autosize={{ minRows: 2, maxRows: 8 }}
+                  trigger='blur'
+                  stopValidateWithError
+                  rules={[
+                    {
+                      validator: (rule, value) => verifyJSON(value),
+                      message: t('不是合法的 JSON 字符串'),
+                    },
+                  ]}
+                  extraText={t(
+                    '缓存不在用户之间共享；客户端可通过请求头 Cache-Control: no-cache 跳过缓存读取，no-store 完全跳过缓存',
+                  )}
+                  onChange={handleFieldChange('response_cache_setting.models')}
+                />
+              </Col>
+            </Row>
+            <Row>
+              <Button size='default' onClick={onSubmit}>
+                {t('保存响应缓存设置')}
+              </Button>
+              <Popconfirm
+                title={t('确认清空响应缓存？')}
+                onConfirm={clearResponseCache}
+              >
+                <Button type='danger' style={{ marginLeft: 8 }}>
+                  {t('清空响应缓存')}
+                </Button>
+              </Popconfirm>
+            </Row>
+          </Form.Section>
+        </Form>
+      </Spin>
+    </>
+  );
+}
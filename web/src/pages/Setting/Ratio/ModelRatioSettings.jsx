@@ -48,6 +48,7 @@ export default function ModelRatioSettings(props) {
     ImageRatio: '',
     AudioRatio: '',
     AudioCompletionRatio: '',
+    PricingTiers: '',
     ExposeRatioEnabled: false,
   });
   const refForm = useRef();
@@ -319,6 +320,30 @@ export default function ModelRatioSettings(props) {
             />
           </Col>
         </Row>
+        <Row gutter={16}>
+          <Col xs={24} sm={16}>
+            <Form.TextArea
+              label={t('分档计价')}
+              extraText={t(
+                '按提示长度、时段和用户当月累计用量调整模型价格，键为模型名称，值为按顺序匹配的规则列表，先命中者生效；未设置的价格沿用基础倍率，multiplier 在此基础上再乘以系数',
+              )}
+              placeholder={t(
+                '为一个 JSON 文本，例如：{"gemini-2.5-pro": [{"name": "long-context", "prompt_tokens_gt": 200000, "model_ratio": 1.25, "completion_ratio": 6}, {"name": "off-peak", "time_ranges": ["00:30-08:30"], "timezone": "UTC+8", "multiplier": 0.5}, {"name": "volume", "min_monthly_quota": 50000000, "multiplier": 0.9}]}',
+              )}
+              field={'PricingTiers'}
+              autosize={{ minRows: 6, maxRows: 12 }}
+              trigger='blur'
+              stopValidateWithError
+              rules={[
+                {
+                  validator: (rule, value) => verifyJSON(value),
+                  message: '不是合法的 JSON 字符串',
+                },
+              ]}
+              onChange={(value) => setInputs({ ...inputs, PricingTiers: value })}
+            />
+          </Col>
+        </Row>
         <Row gutter={16}>
           <Col span={16}>
             <Form.Switch
@@ -18,12 +18,25 @@ For commercial licensing, please contact support@quantumnous.com
 */
 
 import React from 'react';
+import { Tabs, TabPane } from '@douyinfe/semi-ui';
+import { useTranslation } from 'react-i18next';
 import TaskLogsTable from '../../components/table/task-logs';
+import BatchLogsTable from '../../components/table/batch-logs';
 
-const Task = () => (
-  <div className='mt-[60px] px-2'>
-    <TaskLogsTable />
-  </div>
-);
+const Task = () => {
+  const { t } = useTranslation();
+  return (
+    <div className='mt-[60px] px-2'>
+      <Tabs type='line' lazyRender keepDOM={false}>
+        <TabPane tab={t('任务日志')} itemKey='task'>
+          <TaskLogsTable />
+        </TabPane>
+        <TabPane tab={t('批处理')} itemKey='batch'>
+          <BatchLogsTable />
+        </TabPane>
+      </Tabs>
+    </div>
+  );
+};
 
 export default Task;